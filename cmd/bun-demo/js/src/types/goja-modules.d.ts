@@ -9,9 +9,15 @@ declare module "crypto" {
 declare module "database" {
   export function begin(): DatabaseTransaction;
   export function close(): void;
-  export function configure(driverName: string, dataSourceName: string): void;
+  export function configure(driverName: string, dataSourceName: string, options?: DatabasePoolOptions): void;
   export function exec(query: string, ...args: unknown[]): unknown;
+  export function iterate(query: string, ...args: unknown[]): DatabaseRowIterator;
+  export function migrate(migrations: string | DatabaseMigration[], options?: DatabaseMigrateOptions): DatabaseMigrationResult[];
+  export function prepare(query: string): DatabaseStatement;
   export function query(query: string, ...args: unknown[]): unknown;
+  export function queryOne(query: string, ...args: unknown[]): unknown;
+  export function queryValue(query: string, ...args: unknown[]): unknown;
+  export function stats(): DatabasePoolStats | null;
   interface DatabaseExecResult {
   success: boolean;
   rowsAffected?: number;
@@ -20,17 +26,76 @@ declare module "database" {
   }
   interface DatabaseTransaction {
   query(query: string, ...args: unknown[]): Array<Record<string, unknown>>;
+  queryOne(query: string, ...args: unknown[]): Record<string, unknown> | null;
+  queryValue(query: string, ...args: unknown[]): unknown;
   exec(query: string, ...args: unknown[]): DatabaseExecResult;
   commit(): { success: boolean; error?: string };
   rollback(): { success: boolean; error?: string };
   }
+  interface DatabaseRowIterator {
+  next(): Promise<{ value: Record<string, unknown> | undefined; done: boolean }>;
+  return(value?: unknown): Promise<{ value: unknown; done: true }>;
+  }
+  interface DatabasePoolOptions {
+  maxOpenConns?: number;
+  maxIdleConns?: number;
+  connMaxLifetime?: number | string;
+  connMaxIdleTime?: number | string;
+  }
+  interface DatabasePoolStats {
+  maxOpenConnections: number;
+  openConnections: number;
+  inUse: number;
+  idle: number;
+  waitCount: number;
+  waitDurationMs: number;
+  maxIdleClosed: number;
+  maxIdleTimeClosed: number;
+  maxLifetimeClosed: number;
+  }
+  interface DatabaseMigration {
+  version: number;
+  name: string;
+  sql: string | string[];
+  }
+  interface DatabaseMigrationResult {
+  version: number;
+  name: string;
+  checksum: string;
+  status: 'applied' | 'migrated' | 'pending';
+  appliedAt: string;
+  }
+  interface DatabaseMigrateOptions {
+  dryRun?: boolean;
+  table?: string;
+  }
+  interface DatabaseStatement {
+  readonly source: string;
+  all(...args: unknown[]): Array<Record<string, unknown>>;
+  get(...args: unknown[]): Record<string, unknown> | null;
+  value(...args: unknown[]): unknown;
+  run(...args: unknown[]): DatabaseExecResult;
+  iterate(...args: unknown[]): DatabaseRowIterator;
+  close(): void;
+  }
 }
 
 declare module "events" {
   type EventName = string | symbol;
   type Listener = (...args: any[]) => void;
+  interface EventEmitterOptions { captureRejections?: boolean; }
+  interface Abortable { signal?: AbortSignal; }
+  interface OnOptions extends Abortable { close?: EventName[]; }
+  interface EventIterator<T> {
+  next(): Promise<IteratorResult<T>>;
+  return(value?: any): Promise<IteratorResult<T>>;
+  }
   class EventEmitter {
-  constructor();
+  constructor(options?: EventEmitterOptions);
+  static captureRejections: boolean;
+  static readonly captureRejectionSymbol: unique symbol;
+  static once(emitter: EventEmitter | EventTarget, name: EventName, options?: Abortable): Promise<any[]>;
+  static on(emitter: EventEmitter | EventTarget, name: EventName, options?: OnOptions): EventIterator<any[]>;
   on(name: EventName, listener: Listener): this;
   addListener(name: EventName, listener: Listener): this;
   once(name: EventName, listener: Listener): this;
@@ -55,26 +120,54 @@ declare module "fs" {
   export function appendFile(path: string, data: string | Buffer | Uint8Array | DataView, encoding?: string | {  }): Promise<void>;
   export function appendFileSync(path: string, data: string | Buffer | Uint8Array | DataView, encoding?: string | {  }): void;
   export function capabilities(): FSCapabilities;
+  export function chmod(path: string, mode: number | string): Promise<void>;
+  export function chmodSync(path: string, mode: number | string): void;
+  export function closeSync(fd: number): void;
   export function copyFile(src: string, dst: string): Promise<void>;
   export function copyFileSync(src: string, dst: string): void;
+  export function cp(src: string, dst: string, options?: { recursive?: boolean; force?: boolean; errorOnExist?: boolean }): Promise<void>;
+  export function cpSync(src: string, dst: string, options?: { recursive?: boolean; force?: boolean; errorOnExist?: boolean }): void;
+  export function createReadStream(path: string, options?: FileStreamOptions | string): any;
+  export function createWriteStream(path: string, options?: FileStreamOptions): any;
   export function exists(path: string): Promise<boolean>;
   export function existsSync(path: string): boolean;
+  export function fstatSync(fd: number): FileStats;
+  export function fsyncSync(fd: number): void;
+  export function ftruncateSync(fd: number, len?: number): void;
+  export function glob(pattern: string | string[], options?: { cwd?: string; exclude?: string[] }): Promise<string[]>;
+  export function globSync(pattern: string | string[], options?: { cwd?: string; exclude?: string[] }): string[];
+  export function lstat(path: string): Promise<FileStats>;
+  export function lstatSync(path: string): FileStats;
   export function mkdir(path: string, options?: {  }): Promise<void>;
   export function mkdirSync(path: string, options?: {  }): void;
+  export function mkdtemp(prefix: string): Promise<string>;
+  export function mkdtempSync(prefix: string): string;
+  export function open(path: string, flags?: string | number, mode?: number | string): Promise<FileHandle>;
+  export function openSync(path: string, flags?: string | number, mode?: number | string): number;
   export function readFile(path: string, encoding?: string | {  }): Promise<string | Buffer>;
   export function readFileSync(path: string, encoding?: string | {  }): string | Buffer;
-  export function readdir(path: string): Promise<string[]>;
-  export function readdirSync(path: string): string[];
+  export function readSync(fd: number, buffer: Buffer | Uint8Array, offset?: number | {  }, length?: number, position?: number | null): number;
+  export function readdir(path: string, options?: { withFileTypes?: boolean }): Promise<string[] | Dirent[]>;
+  export function readdirSync(path: string, options?: { withFileTypes?: boolean }): string[] | Dirent[];
+  export function readlink(path: string): Promise<string>;
+  export function readlinkSync(path: string): string;
+  export function realpath(path: string): Promise<string>;
+  export function realpathSync(path: string): string;
   export function rename(oldPath: string, newPath: string): Promise<void>;
   export function renameSync(oldPath: string, newPath: string): void;
   export function rm(path: string, options?: {  }): Promise<void>;
   export function rmSync(path: string, options?: {  }): void;
   export function stat(path: string): Promise<FileStats>;
   export function statSync(path: string): FileStats;
+  export function symlink(target: string, path: string): Promise<void>;
+  export function symlinkSync(target: string, path: string): void;
   export function unlink(path: string): Promise<void>;
   export function unlinkSync(path: string): void;
+  export function utimes(path: string, atime: number | string | Date, mtime: number | string | Date): Promise<void>;
+  export function utimesSync(path: string, atime: number | string | Date, mtime: number | string | Date): void;
   export function writeFile(path: string, data: string | Buffer | Uint8Array | DataView, encoding?: string | {  }): Promise<void>;
   export function writeFileSync(path: string, data: string | Buffer | Uint8Array | DataView, encoding?: string | {  }): void;
+  export function writeSync(fd: number, data: string | Buffer | Uint8Array, offsetOrPosition?: number | null, lengthOrEncoding?: number | string, position?: number | null): number;
   interface FileStats {
   name: string;
   size: number;
@@ -82,10 +175,57 @@ declare module "fs" {
   modTime: string;
   isDir: boolean;
   isFile: boolean;
+  isSymbolicLink: boolean;
+  }
+  interface Dirent {
+  name: string;
+  parentPath: string;
+  path: string;
+  isFile(): boolean;
+  isDirectory(): boolean;
+  isSymbolicLink(): boolean;
+  }
+  interface FileReadResult {
+  bytesRead: number;
+  buffer: Buffer | Uint8Array;
+  }
+  interface FileWriteResult {
+  bytesWritten: number;
+  buffer: string | Buffer | Uint8Array;
+  }
+  interface FileStreamOptions {
+  flags?: string | number;
+  mode?: number | string;
+  start?: number;
+  end?: number;
+  highWaterMark?: number;
+  encoding?: string;
+  }
+  interface FileHandle {
+  readonly fd: number;
+  read(buffer: Buffer | Uint8Array, offset?: number | object, length?: number, position?: number | null): Promise<FileReadResult>;
+  write(data: string | Buffer | Uint8Array, offsetOrPosition?: number | null, lengthOrEncoding?: number | string, position?: number | null): Promise<FileWriteResult>;
+  readFile(encoding?: string | object): Promise<string | Buffer>;
+  writeFile(data: string | Buffer | Uint8Array, encoding?: string | object): Promise<void>;
+  appendFile(data: string | Buffer | Uint8Array, encoding?: string | object): Promise<void>;
+  stat(): Promise<FileStats>;
+  truncate(len?: number): Promise<void>;
+  sync(): Promise<void>;
+  close(): Promise<void>;
+  createReadStream(options?: FileStreamOptions | string): any;
+  createWriteStream(options?: FileStreamOptions): any;
+  }
+  interface FSWatcher {
+  on(event: 'change', listener: (eventType: 'rename' | 'change', filename: string) => void): this;
+  on(event: 'error', listener: (err: Error) => void): this;
+  on(event: 'close', listener: () => void): this;
+  close(): this;
   }
   interface FSMountInfo {
   mount: string;
   root: string;
+  backend?: string;
+  readOnly?: boolean;
   }
   interface FSCapabilities {
   backend: string;
@@ -95,6 +235,16 @@ declare module "fs" {
   mounts?: FSMountInfo[];
   }
   export const isReadOnly: boolean;
+  export const constants: { O_RDONLY: number; O_WRONLY: number; O_RDWR: number; O_CREAT: number; O_EXCL: number; O_TRUNC: number; O_APPEND: number };
+  export function watch(filename: string, options?: { recursive?: boolean; signal?: AbortSignal; debounceMs?: number } | string, listener?: (eventType: 'rename' | 'change', filename: string) => void): FSWatcher;
+  export function watch(filename: string, listener: (eventType: 'rename' | 'change', filename: string) => void): FSWatcher;
+  export const promises: {
+  readFile: typeof readFile; writeFile: typeof writeFile; mkdir: typeof mkdir; readdir: typeof readdir;
+  stat: typeof stat; lstat: typeof lstat; unlink: typeof unlink; appendFile: typeof appendFile;
+  rename: typeof rename; copyFile: typeof copyFile; rm: typeof rm; realpath: typeof realpath;
+  readlink: typeof readlink; symlink: typeof symlink; chmod: typeof chmod; utimes: typeof utimes;
+  mkdtemp: typeof mkdtemp; cp: typeof cp; glob: typeof glob; open: typeof open; constants: typeof constants;
+  };
 }
 
 declare module "node:crypto" {
@@ -106,8 +256,19 @@ declare module "node:crypto" {
 declare module "node:events" {
   type EventName = string | symbol;
   type Listener = (...args: any[]) => void;
+  interface EventEmitterOptions { captureRejections?: boolean; }
+  interface Abortable { signal?: AbortSignal; }
+  interface OnOptions extends Abortable { close?: EventName[]; }
+  interface EventIterator<T> {
+  next(): Promise<IteratorResult<T>>;
+  return(value?: any): Promise<IteratorResult<T>>;
+  }
   class EventEmitter {
-  constructor();
+  constructor(options?: EventEmitterOptions);
+  static captureRejections: boolean;
+  static readonly captureRejectionSymbol: unique symbol;
+  static once(emitter: EventEmitter | EventTarget, name: EventName, options?: Abortable): Promise<any[]>;
+  static on(emitter: EventEmitter | EventTarget, name: EventName, options?: OnOptions): EventIterator<any[]>;
   on(name: EventName, listener: Listener): this;
   addListener(name: EventName, listener: Listener): this;
   once(name: EventName, listener: Listener): this;
@@ -128,26 +289,54 @@ declare module "node:fs" {
   export function appendFile(path: string, data: string | Buffer | Uint8Array | DataView, encoding?: string | {  }): Promise<void>;
   export function appendFileSync(path: string, data: string | Buffer | Uint8Array | DataView, encoding?: string | {  }): void;
   export function capabilities(): FSCapabilities;
+  export function chmod(path: string, mode: number | string): Promise<void>;
+  export function chmodSync(path: string, mode: number | string): void;
+  export function closeSync(fd: number): void;
   export function copyFile(src: string, dst: string): Promise<void>;
   export function copyFileSync(src: string, dst: string): void;
+  export function cp(src: string, dst: string, options?: { recursive?: boolean; force?: boolean; errorOnExist?: boolean }): Promise<void>;
+  export function cpSync(src: string, dst: string, options?: { recursive?: boolean; force?: boolean; errorOnExist?: boolean }): void;
+  export function createReadStream(path: string, options?: FileStreamOptions | string): any;
+  export function createWriteStream(path: string, options?: FileStreamOptions): any;
   export function exists(path: string): Promise<boolean>;
   export function existsSync(path: string): boolean;
+  export function fstatSync(fd: number): FileStats;
+  export function fsyncSync(fd: number): void;
+  export function ftruncateSync(fd: number, len?: number): void;
+  export function glob(pattern: string | string[], options?: { cwd?: string; exclude?: string[] }): Promise<string[]>;
+  export function globSync(pattern: string | string[], options?: { cwd?: string; exclude?: string[] }): string[];
+  export function lstat(path: string): Promise<FileStats>;
+  export function lstatSync(path: string): FileStats;
   export function mkdir(path: string, options?: {  }): Promise<void>;
   export function mkdirSync(path: string, options?: {  }): void;
+  export function mkdtemp(prefix: string): Promise<string>;
+  export function mkdtempSync(prefix: string): string;
+  export function open(path: string, flags?: string | number, mode?: number | string): Promise<FileHandle>;
+  export function openSync(path: string, flags?: string | number, mode?: number | string): number;
   export function readFile(path: string, encoding?: string | {  }): Promise<string | Buffer>;
   export function readFileSync(path: string, encoding?: string | {  }): string | Buffer;
-  export function readdir(path: string): Promise<string[]>;
-  export function readdirSync(path: string): string[];
+  export function readSync(fd: number, buffer: Buffer | Uint8Array, offset?: number | {  }, length?: number, position?: number | null): number;
+  export function readdir(path: string, options?: { withFileTypes?: boolean }): Promise<string[] | Dirent[]>;
+  export function readdirSync(path: string, options?: { withFileTypes?: boolean }): string[] | Dirent[];
+  export function readlink(path: string): Promise<string>;
+  export function readlinkSync(path: string): string;
+  export function realpath(path: string): Promise<string>;
+  export function realpathSync(path: string): string;
   export function rename(oldPath: string, newPath: string): Promise<void>;
   export function renameSync(oldPath: string, newPath: string): void;
   export function rm(path: string, options?: {  }): Promise<void>;
   export function rmSync(path: string, options?: {  }): void;
   export function stat(path: string): Promise<FileStats>;
   export function statSync(path: string): FileStats;
+  export function symlink(target: string, path: string): Promise<void>;
+  export function symlinkSync(target: string, path: string): void;
   export function unlink(path: string): Promise<void>;
   export function unlinkSync(path: string): void;
+  export function utimes(path: string, atime: number | string | Date, mtime: number | string | Date): Promise<void>;
+  export function utimesSync(path: string, atime: number | string | Date, mtime: number | string | Date): void;
   export function writeFile(path: string, data: string | Buffer | Uint8Array | DataView, encoding?: string | {  }): Promise<void>;
   export function writeFileSync(path: string, data: string | Buffer | Uint8Array | DataView, encoding?: string | {  }): void;
+  export function writeSync(fd: number, data: string | Buffer | Uint8Array, offsetOrPosition?: number | null, lengthOrEncoding?: number | string, position?: number | null): number;
   interface FileStats {
   name: string;
   size: number;
@@ -155,10 +344,57 @@ declare module "node:fs" {
   modTime: string;
   isDir: boolean;
   isFile: boolean;
+  isSymbolicLink: boolean;
+  }
+  interface Dirent {
+  name: string;
+  parentPath: string;
+  path: string;
+  isFile(): boolean;
+  isDirectory(): boolean;
+  isSymbolicLink(): boolean;
+  }
+  interface FileReadResult {
+  bytesRead: number;
+  buffer: Buffer | Uint8Array;
+  }
+  interface FileWriteResult {
+  bytesWritten: number;
+  buffer: string | Buffer | Uint8Array;
+  }
+  interface FileStreamOptions {
+  flags?: string | number;
+  mode?: number | string;
+  start?: number;
+  end?: number;
+  highWaterMark?: number;
+  encoding?: string;
+  }
+  interface FileHandle {
+  readonly fd: number;
+  read(buffer: Buffer | Uint8Array, offset?: number | object, length?: number, position?: number | null): Promise<FileReadResult>;
+  write(data: string | Buffer | Uint8Array, offsetOrPosition?: number | null, lengthOrEncoding?: number | string, position?: number | null): Promise<FileWriteResult>;
+  readFile(encoding?: string | object): Promise<string | Buffer>;
+  writeFile(data: string | Buffer | Uint8Array, encoding?: string | object): Promise<void>;
+  appendFile(data: string | Buffer | Uint8Array, encoding?: string | object): Promise<void>;
+  stat(): Promise<FileStats>;
+  truncate(len?: number): Promise<void>;
+  sync(): Promise<void>;
+  close(): Promise<void>;
+  createReadStream(options?: FileStreamOptions | string): any;
+  createWriteStream(options?: FileStreamOptions): any;
+  }
+  interface FSWatcher {
+  on(event: 'change', listener: (eventType: 'rename' | 'change', filename: string) => void): this;
+  on(event: 'error', listener: (err: Error) => void): this;
+  on(event: 'close', listener: () => void): this;
+  close(): this;
   }
   interface FSMountInfo {
   mount: string;
   root: string;
+  backend?: string;
+  readOnly?: boolean;
   }
   interface FSCapabilities {
   backend: string;
@@ -168,6 +404,16 @@ declare module "node:fs" {
   mounts?: FSMountInfo[];
   }
   export const isReadOnly: boolean;
+  export const constants: { O_RDONLY: number; O_WRONLY: number; O_RDWR: number; O_CREAT: number; O_EXCL: number; O_TRUNC: number; O_APPEND: number };
+  export function watch(filename: string, options?: { recursive?: boolean; signal?: AbortSignal; debounceMs?: number } | string, listener?: (eventType: 'rename' | 'change', filename: string) => void): FSWatcher;
+  export function watch(filename: string, listener: (eventType: 'rename' | 'change', filename: string) => void): FSWatcher;
+  export const promises: {
+  readFile: typeof readFile; writeFile: typeof writeFile; mkdir: typeof mkdir; readdir: typeof readdir;
+  stat: typeof stat; lstat: typeof lstat; unlink: typeof unlink; appendFile: typeof appendFile;
+  rename: typeof rename; copyFile: typeof copyFile; rm: typeof rm; realpath: typeof realpath;
+  readlink: typeof readlink; symlink: typeof symlink; chmod: typeof chmod; utimes: typeof utimes;
+  mkdtemp: typeof mkdtemp; cp: typeof cp; glob: typeof glob; open: typeof open; constants: typeof constants;
+  };
 }
 
 declare module "node:os" {
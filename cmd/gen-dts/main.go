@@ -13,6 +13,7 @@ import (
 	"strings"
 
 	"github.com/go-go-golems/go-go-goja/modules"
+	_ "github.com/go-go-golems/go-go-goja/modules/childprocess"
 	_ "github.com/go-go-golems/go-go-goja/modules/crypto"
 	_ "github.com/go-go-golems/go-go-goja/modules/database"
 	_ "github.com/go-go-golems/go-go-goja/modules/events"
@@ -20,6 +21,7 @@ import (
 	_ "github.com/go-go-golems/go-go-goja/modules/fs"
 	_ "github.com/go-go-golems/go-go-goja/modules/os"
 	_ "github.com/go-go-golems/go-go-goja/modules/path"
+	_ "github.com/go-go-golems/go-go-goja/modules/stream"
 	_ "github.com/go-go-golems/go-go-goja/modules/yaml"
 	"github.com/go-go-golems/go-go-goja/pkg/tsgen/render"
 	"github.com/go-go-golems/go-go-goja/pkg/tsgen/spec"
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/go-go-golems/glazed/pkg/cli"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/spf13/cobra"
+
+	// Blank imports register the native modules in modules.DefaultRegistry
+	// so completion and hover know their exports, matching pkg/engine.
+	_ "github.com/go-go-golems/go-go-goja/modules/childprocess"
+	_ "github.com/go-go-golems/go-go-goja/modules/crypto"
+	_ "github.com/go-go-golems/go-go-goja/modules/database"
+	_ "github.com/go-go-golems/go-go-goja/modules/events"
+	_ "github.com/go-go-golems/go-go-goja/modules/exec"
+	_ "github.com/go-go-golems/go-go-goja/modules/fs"
+	_ "github.com/go-go-golems/go-go-goja/modules/os"
+	_ "github.com/go-go-golems/go-go-goja/modules/path"
+	_ "github.com/go-go-golems/go-go-goja/modules/stream"
+	_ "github.com/go-go-golems/go-go-goja/modules/time"
+	_ "github.com/go-go-golems/go-go-goja/modules/timer"
+	_ "github.com/go-go-golems/go-go-goja/modules/yaml"
+)
+
+func main() {
+	root := &cobra.Command{
+		Use:   "goja-lsp",
+		Short: "Language server for go-go-goja JavaScript",
+		Long: `Language Server Protocol server for scripts run by go-go-goja.
+
+It speaks LSP over stdio and offers diagnostics, completion (including native
+module exports), hover with jsdoc __doc__ metadata, go-to-definition,
+find-references and rename across the workspace, and understands jsverbs
+__verb__ metadata.`,
+	}
+
+	serveCmd, err := newServeCommand()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	serveCobra, err := cli.BuildCobraCommand(serveCmd,
+		cli.WithParserConfig(cli.CobraParserConfig{
+			ShortHelpSections: []string{schema.DefaultSlug},
+			MiddlewaresFunc:   cli.CobraCommandDefaultMiddlewares,
+		}),
+	)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	root.AddCommand(serveCobra)
+
+	if err := root.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+
+	"github.com/go-go-golems/go-go-goja/pkg/lsp"
+)
+
+type serveCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.BareCommand = (*serveCommand)(nil)
+
+type serveSettings struct {
+	Root  string `glazed:"root"`
+	Stdio bool   `glazed:"stdio"`
+}
+
+func newServeCommand() (*serveCommand, error) {
+	desc := cmds.NewCommandDescription(
+		"serve",
+		cmds.WithShort("Serve LSP over stdin/stdout"),
+		cmds.WithLong(`Start the language server on stdin/stdout. Editors launch this command and
+talk to it directly; nothing else is written to stdout.
+
+The workspace root defaults to the rootUri sent by the editor in initialize.
+Every .js, .cjs and .mjs file below it (except node_modules and hidden
+directories) is indexed for cross-file references and rename.`),
+		cmds.WithFlags(
+			fields.New("root", fields.TypeString, fields.WithDefault(""), fields.WithHelp("Workspace root directory (defaults to the editor's rootUri)")),
+			fields.New("stdio", fields.TypeBool, fields.WithDefault(true), fields.WithHelp("Accepted for editor compatibility; stdio is the only transport")),
+		),
+	)
+	return &serveCommand{CommandDescription: desc}, nil
+}
+
+func (c *serveCommand) Run(ctx context.Context, vals *values.Values) error {
+	settings := serveSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, &settings); err != nil {
+		return err
+	}
+	server := lsp.NewServer(lsp.Options{RootDir: settings.Root})
+	return server.Serve(ctx, os.Stdin, os.Stdout)
+}
@@ -7,13 +7,15 @@ import (
 	"os"
 	"path/filepath"
 
-	"github.com/dop251/goja"
 	"github.com/go-go-golems/glazed/pkg/cmds"
 	"github.com/go-go-golems/glazed/pkg/cmds/fields"
 	"github.com/go-go-golems/glazed/pkg/cmds/schema"
 	"github.com/go-go-golems/glazed/pkg/cmds/values"
 	"github.com/go-go-golems/go-go-goja/pkg/engine"
 	"github.com/go-go-golems/go-go-goja/pkg/hashiplugin/host"
+	"github.com/go-go-golems/go-go-goja/pkg/jscover"
+	"github.com/go-go-golems/go-go-goja/pkg/jsdebug"
+	"github.com/go-go-golems/go-go-goja/pkg/jserrors"
 )
 
 type runCommand struct {
@@ -36,19 +38,34 @@ destroyed when the script completes.
 Examples:
   goja-repl --enable-module yaml run ./examples/goja-repl/scripts/yaml.js
   goja-repl --plugin-dir ./plugins run ./scripts/with-custom-modules.js
+  goja-repl run --debug-addr 127.0.0.1:4711 ./script.js
+  goja-repl run --coverage ./coverage ./script.js
+
+With --debug-addr the script waits for a Debug Adapter Protocol client (VS Code,
+nvim-dap) to attach and configure breakpoints before it starts.
+
+With --coverage the modules the script loads are instrumented, and lcov.info,
+cobertura.xml and index.html coverage reports are written to the directory
+when it ends.
 `),
 			cmds.WithArguments(
 				fields.New("file", fields.TypeString,
 					fields.WithRequired(true),
 					fields.WithHelp("Path to the JavaScript file to execute")),
 			),
+			cmds.WithFlags(
+				fields.New("debug-addr", fields.TypeString, fields.WithDefault(""), fields.WithHelp("Wait for a Debug Adapter Protocol client on this address before running")),
+				fields.New("coverage", fields.TypeString, fields.WithDefault(""), fields.WithHelp("Write coverage reports of the run to this directory")),
+			),
 		),
 		commandSupport: commandSupport{out: out, opts: opts},
 	}
 }
 
 type runSettings struct {
-	File string `glazed:"file"`
+	File      string `glazed:"file"`
+	DebugAddr string `glazed:"debug-addr"`
+	Coverage  string `glazed:"coverage"`
 }
 
 type runScriptOptions struct {
@@ -59,6 +76,9 @@ type runScriptOptions struct {
 	DisableModules     []string
 	SafeMode           bool
 	UseModuleRoots     bool
+	DebugAddr          string
+	CoverageDir        string
+	Stderr             io.Writer
 }
 
 func (c *runCommand) Run(ctx context.Context, vals *values.Values) error {
@@ -70,6 +90,9 @@ func (c *runCommand) Run(ctx context.Context, vals *values.Values) error {
 	opts := runScriptOptions{
 		File:           settings.File,
 		UseModuleRoots: true,
+		DebugAddr:      settings.DebugAddr,
+		CoverageDir:    settings.Coverage,
+		Stderr:         os.Stderr,
 	}
 	if c.opts != nil {
 		opts.PluginDirs = c.opts.PluginDirs
@@ -81,7 +104,7 @@ func (c *runCommand) Run(ctx context.Context, vals *values.Values) error {
 	return runScriptFile(ctx, opts)
 }
 
-func runScriptFile(ctx context.Context, opts runScriptOptions) error {
+func runScriptFile(ctx context.Context, opts runScriptOptions) (retErr error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -96,27 +119,49 @@ func runScriptFile(ctx context.Context, opts runScriptOptions) error {
 	if _, err := os.Stat(scriptPath); err != nil {
 		return fmt.Errorf("script file not found %q: %w", scriptPath, err)
 	}
+	if opts.DebugAddr != "" && opts.CoverageDir != "" {
+		return fmt.Errorf("--debug-addr and --coverage cannot be combined")
+	}
 
-	builder := engine.NewRuntimeFactoryBuilder()
-	if opts.SafeMode {
-		builder = builder.UseModuleMiddleware(engine.MiddlewareSafe())
-	} else if len(opts.EnableModules) > 0 {
-		builder = builder.UseModuleMiddleware(engine.MiddlewareOnly(opts.EnableModules...))
-	} else if len(opts.DisableModules) > 0 {
-		builder = builder.UseModuleMiddleware(engine.MiddlewareExclude(opts.DisableModules...))
+	builder, err := newScriptFactoryBuilder(scriptPath, opts)
+	if err != nil {
+		return err
 	}
-	if opts.UseModuleRoots {
-		requireOpt, err := engine.RequireOptionWithModuleRootsFromScript(scriptPath, engine.DefaultModuleRootsOptions())
+
+	if opts.DebugAddr != "" {
+		dbg := jsdebug.New()
+		listener, err := jsdebug.Listen(opts.DebugAddr, dbg)
 		if err != nil {
-			return fmt.Errorf("resolve module roots from script %q: %w", scriptPath, err)
+			return err
 		}
-		if requireOpt != nil {
-			builder = builder.WithRequireOptions(requireOpt)
+		if opts.Stderr != nil {
+			_, _ = fmt.Fprintf(opts.Stderr, "Waiting for a debug client on %s\n", listener.Addr())
 		}
+		if err := dbg.WaitReady(ctx); err != nil {
+			_ = listener.Close(1)
+			return err
+		}
+		builder = dbg.Attach(builder, nil)
+		defer func() {
+			exitCode := 0
+			if retErr != nil {
+				exitCode = 1
+			}
+			_ = listener.Close(exitCode)
+		}()
 	}
 
-	pluginSetup := host.NewRuntimeSetup(opts.PluginDirs, opts.AllowPluginModules)
-	builder = pluginSetup.WithBuilder(builder)
+	if opts.CoverageDir != "" {
+		collector := jscover.New()
+		builder = collector.Attach(builder, nil)
+		// Deferred before the runtime closes, so the report is written
+		// after the script stopped counting.
+		defer func() {
+			if err := writeCoverage(collector, opts.CoverageDir, opts.Stderr); err != nil && retErr == nil {
+				retErr = err
+			}
+		}()
+	}
 
 	factory, err := builder.Build()
 	if err != nil {
@@ -129,13 +174,50 @@ func runScriptFile(ctx context.Context, opts runScriptOptions) error {
 	}
 	defer func() { _ = rt.Close(ctx) }()
 
-	_, err = rt.Owner.Call(ctx, "goja-repl.run", func(_ context.Context, vm *goja.Runtime) (any, error) {
-		_ = vm
-		return rt.Require.Require(scriptPath)
-	})
+	// ImportModule runs CommonJS and ES module entry points alike and waits
+	// for top-level await to settle.
+	_, err = rt.ImportModule(ctx, scriptPath)
 	if err != nil {
-		return fmt.Errorf("run %s as module: %w", scriptPath, err)
+		return fmt.Errorf("run %s as module: %w", scriptPath, jserrors.Wrap(err))
+	}
+
+	return nil
+}
+
+// newScriptFactoryBuilder returns a builder with the module selection and
+// plugins of opts that resolves modules relative to scriptPath.
+func newScriptFactoryBuilder(scriptPath string, opts runScriptOptions) (*engine.RuntimeFactoryBuilder, error) {
+	builder := engine.NewRuntimeFactoryBuilder()
+	if opts.SafeMode {
+		builder = builder.UseModuleMiddleware(engine.MiddlewareSafe())
+	} else if len(opts.EnableModules) > 0 {
+		builder = builder.UseModuleMiddleware(engine.MiddlewareOnly(opts.EnableModules...))
+	} else if len(opts.DisableModules) > 0 {
+		builder = builder.UseModuleMiddleware(engine.MiddlewareExclude(opts.DisableModules...))
+	}
+	if opts.UseModuleRoots {
+		requireOpt, err := engine.RequireOptionWithModuleRootsFromScript(scriptPath, engine.DefaultModuleRootsOptions())
+		if err != nil {
+			return nil, fmt.Errorf("resolve module roots from script %q: %w", scriptPath, err)
+		}
+		if requireOpt != nil {
+			builder = builder.WithRequireOptions(requireOpt)
+		}
 	}
 
+	pluginSetup := host.NewRuntimeSetup(opts.PluginDirs, opts.AllowPluginModules)
+	return pluginSetup.WithBuilder(builder), nil
+}
+
+// writeCoverage writes the reports of collector to dir and prints a
+// summary.
+func writeCoverage(collector *jscover.Collector, dir string, stderr io.Writer) error {
+	report := collector.Report()
+	if err := report.WriteDir(dir); err != nil {
+		return fmt.Errorf("write coverage: %w", err)
+	}
+	if stderr != nil {
+		_, _ = fmt.Fprintf(stderr, "coverage: %s\ncoverage: reports written to %s\n", report.Summary(), dir)
+	}
 	return nil
 }
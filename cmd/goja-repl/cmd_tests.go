@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/go-go-goja/modules"
+	"github.com/go-go-golems/go-go-goja/pkg/engine"
+	"github.com/go-go-golems/go-go-goja/pkg/jstest"
+)
+
+type testCommand struct {
+	*cmds.CommandDescription
+	commandSupport
+}
+
+var _ cmds.BareCommand = (*testCommand)(nil)
+
+func newTestCommand(out io.Writer, opts *rootOptions) *testCommand {
+	return &testCommand{
+		CommandDescription: cmds.NewCommandDescription("test",
+			cmds.WithShort("Run JavaScript and TypeScript test files"),
+			cmds.WithLong(`
+Test runs *.test.js, *.test.mjs and *.test.ts files, each in a fresh runtime
+with the native modules and plugins of the other commands.
+
+Test files declare tests with describe, it, beforeEach and the other hooks,
+assert with expect, replace members of native modules with mock, and compare
+values with snapshots stored in __snapshots__ next to the file.
+
+Examples:
+  goja-repl test
+  goja-repl test ./src --filter "parser"
+  goja-repl test --reporter junit --output junit.xml ./tests
+  goja-repl test --update-snapshots ./tests/render.test.js
+
+The command fails when a test fails. With --output the report is written to
+the file and a spec report is printed as well.
+`),
+			cmds.WithArguments(
+				fields.New("paths", fields.TypeStringList,
+					fields.WithDefault([]string{"."}),
+					fields.WithHelp("Test files, or directories to find test files in")),
+			),
+			cmds.WithFlags(
+				fields.New("reporter", fields.TypeChoice, fields.WithChoices(jstest.FormatSpec, jstest.FormatTAP, jstest.FormatJUnit), fields.WithDefault(jstest.FormatSpec), fields.WithHelp("Report format")),
+				fields.New("output", fields.TypeString, fields.WithDefault(""), fields.WithHelp("Write the report to this file and print a spec report")),
+				fields.New("filter", fields.TypeString, fields.WithShortFlag("t"), fields.WithDefault(""), fields.WithHelp("Run only tests whose full name matches this regular expression")),
+				fields.New("timeout", fields.TypeString, fields.WithDefault(jstest.DefaultTimeout.String()), fields.WithHelp("Time each test may take")),
+				fields.New("update-snapshots", fields.TypeBool, fields.WithShortFlag("u"), fields.WithDefault(false), fields.WithHelp("Overwrite snapshots that do not match and remove unused ones")),
+				fields.New("ci", fields.TypeBool, fields.WithDefault(false), fields.WithHelp("Fail tests whose snapshots do not exist instead of writing them")),
+			),
+		),
+		commandSupport: commandSupport{out: out, opts: opts},
+	}
+}
+
+type testSettings struct {
+	Paths           []string `glazed:"paths"`
+	Reporter        string   `glazed:"reporter"`
+	Output          string   `glazed:"output"`
+	Filter          string   `glazed:"filter"`
+	Timeout         string   `glazed:"timeout"`
+	UpdateSnapshots bool     `glazed:"update-snapshots"`
+	CI              bool     `glazed:"ci"`
+}
+
+func (c *testCommand) Run(ctx context.Context, vals *values.Values) error {
+	settings := testSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, &settings); err != nil {
+		return err
+	}
+	timeout, err := time.ParseDuration(settings.Timeout)
+	if err != nil {
+		return fmt.Errorf("parse --timeout: %w", err)
+	}
+	opts := jstest.Options{
+		Timeout:         timeout,
+		UpdateSnapshots: settings.UpdateSnapshots,
+		CI:              settings.CI,
+		External:        []string{"plugin:*"},
+	}
+	if settings.Filter != "" {
+		if opts.Filter, err = regexp.Compile(settings.Filter); err != nil {
+			return fmt.Errorf("parse --filter: %w", err)
+		}
+	}
+	for _, module := range modules.ListDefaultModules() {
+		opts.External = append(opts.External, module.Name())
+	}
+	scriptOpts := runScriptOptions{UseModuleRoots: true}
+	if c.opts != nil {
+		scriptOpts.PluginDirs = c.opts.PluginDirs
+		scriptOpts.AllowPluginModules = c.opts.AllowPluginModules
+		scriptOpts.EnableModules = c.opts.EnableModules
+		scriptOpts.DisableModules = c.opts.DisableModules
+		scriptOpts.SafeMode = c.opts.SafeMode
+	}
+	opts.NewRuntime = func(ctx context.Context, file string) (*engine.Runtime, error) {
+		builder, err := newScriptFactoryBuilder(file, scriptOpts)
+		if err != nil {
+			return nil, err
+		}
+		factory, err := builder.Build()
+		if err != nil {
+			return nil, fmt.Errorf("build engine factory: %w", err)
+		}
+		return factory.NewRuntime(engine.WithStartupContext(ctx), engine.WithLifetimeContext(ctx))
+	}
+	return runTests(ctx, c.out, settings.Paths, settings.Reporter, settings.Output, opts)
+}
+
+// runTests runs the test files under paths and writes the report.
+func runTests(ctx context.Context, out io.Writer, paths []string, reporter, output string, opts jstest.Options) error {
+	files, err := jstest.Discover(paths)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no test files found in %v", paths)
+	}
+	report := jstest.Run(ctx, files, opts)
+	dir, _ := os.Getwd()
+	if err := report.WriteOutput(out, reporter, output, dir); err != nil {
+		return err
+	}
+	if report.Failed() {
+		return fmt.Errorf("tests failed: %s", report.Summary())
+	}
+	return nil
+}
@@ -67,6 +67,7 @@ func newRootCommand(out io.Writer) (*cobra.Command, error) {
 		newCreateCommand(out, opts),
 		newEvalCommand(out, opts),
 		newRunCommand(out, opts),
+		newTestCommand(out, opts),
 		newSnapshotCommand(out, opts),
 		newHistoryCommand(out, opts),
 		newBindingsCommand(out, opts),
@@ -21,6 +21,8 @@ import (
 	"github.com/spf13/cobra"
 
 	sharedoc "github.com/go-go-golems/go-go-goja/pkg/doc"
+	"github.com/go-go-golems/go-go-goja/pkg/jscover"
+	"github.com/go-go-golems/go-go-goja/pkg/jsdebug"
 	"github.com/go-go-golems/go-go-goja/pkg/jsverbs"
 )
 
@@ -69,6 +71,8 @@ func main() {
 		os.Exit(1)
 	}
 
+	var debugListener *jsdebug.Listener
+	var coverageDir string
 	root := &cobra.Command{
 		Use:   "jsverbs-example",
 		Short: "Expose scanned JavaScript functions as Glazed commands",
@@ -77,10 +81,44 @@ func main() {
 			registry.RootDir,
 		),
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			return logging.InitLoggerFromCobra(cmd)
+			if err := logging.InitLoggerFromCobra(cmd); err != nil {
+				return err
+			}
+			addr, err := cmd.Flags().GetString("debug-addr")
+			if err != nil {
+				return err
+			}
+			coverageDir, err = cmd.Flags().GetString("coverage")
+			if err != nil {
+				return err
+			}
+			if coverageDir != "" {
+				if addr != "" {
+					return fmt.Errorf("--debug-addr and --coverage cannot be combined")
+				}
+				registry.Coverage = jscover.New()
+			}
+			if addr == "" {
+				return nil
+			}
+			registry.Debugger = jsdebug.New()
+			debugListener, err = jsdebug.Listen(addr, registry.Debugger)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Waiting for a debug client on %s\n", debugListener.Addr())
+			return registry.Debugger.WaitReady(cmd.Context())
+		},
+		PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+			if debugListener != nil {
+				return debugListener.Close(0)
+			}
+			return writeCoverage(registry.Coverage, coverageDir)
 		},
 	}
 	root.PersistentFlags().StringP("dir", "d", dir, "Directory scanned before command registration")
+	root.PersistentFlags().String("debug-addr", "", "Wait for a Debug Adapter Protocol client on this address before running a verb")
+	root.PersistentFlags().String("coverage", "", "Write coverage reports of the verb run to this directory")
 	if err := logging.AddLoggingSectionToRootCommand(root, "jsverbs-example"); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
@@ -119,10 +157,30 @@ func main() {
 	help_cmd.SetupCobraRootCommand(helpSystem, root)
 
 	if err := root.Execute(); err != nil {
+		if debugListener != nil {
+			_ = debugListener.Close(1)
+		}
+		if err := writeCoverage(registry.Coverage, coverageDir); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
 		os.Exit(1)
 	}
 }
 
+// writeCoverage writes the reports of collector to dir, if coverage was
+// requested.
+func writeCoverage(collector *jscover.Collector, dir string) error {
+	if collector == nil {
+		return nil
+	}
+	report := collector.Report()
+	if err := report.WriteDir(dir); err != nil {
+		return fmt.Errorf("write coverage: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "coverage: %s\ncoverage: reports written to %s\n", report.Summary(), dir)
+	return nil
+}
+
 func discoverDirectory(args []string) string {
 	for i := 0; i < len(args); i++ {
 		arg := args[i]
@@ -187,7 +187,7 @@ func validateCommands(report *Report, commands []CommandSurfaceSpec, providers m
 			report.AddOK("command-id", path+".id", id)
 		}
 		switch strings.TrimSpace(command.Type) {
-		case "builtin.eval", "builtin.run", "builtin.repl", "builtin.jsverbs", "provider.command-set":
+		case "builtin.eval", "builtin.run", "builtin.repl", "builtin.test", "builtin.jsverbs", "provider.command-set":
 			report.AddOK("command-type", path+".type", command.Type)
 		default:
 			report.AddError("command-type", path+".type", fmt.Sprintf("unsupported command type %q", command.Type))
@@ -70,6 +70,9 @@ func newHandler() (http.Handler, error) {
 		HandleJSON(projectResponse); err != nil {
 		return nil, err
 	}
+	if err := host.Validate(); err != nil {
+		return nil, err
+	}
 
 	middlewareRoute, err := gojahttp.PlannedMiddleware(gojahttp.MiddlewareOptions{
 		Auth: gojahttp.AuthOptions{
@@ -41,6 +41,9 @@ func newHandler(verifier programauth.OAuthBearerAuthenticator, issuer string) (h
 	}); err != nil {
 		return nil, err
 	}
+	if err := host.Validate(); err != nil {
+		return nil, err
+	}
 	return host, nil
 }
 
@@ -61,6 +61,9 @@ func run(ctx context.Context, listen, script string, smoke bool) error {
 	}); err != nil {
 		return err
 	}
+	if err := host.Validate(); err != nil {
+		return err
+	}
 	mux := buildMux(host, kit)
 	if smoke {
 		return runSmoke(mux, kit)
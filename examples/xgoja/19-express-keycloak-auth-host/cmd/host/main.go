@@ -300,6 +300,9 @@ func run(ctx context.Context, cfg config) error {
 	}); err != nil {
 		return err
 	}
+	if err := host.Validate(); err != nil {
+		return err
+	}
 	mux := http.NewServeMux()
 	mux.Handle("GET /auth/login", oidcHandlers.LoginHandler())
 	mux.Handle("GET /auth/callback", oidcHandlers.CallbackHandler())
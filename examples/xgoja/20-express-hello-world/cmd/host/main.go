@@ -54,6 +54,9 @@ func run(ctx context.Context, listen, script string, smoke bool) error {
 	}); err != nil {
 		return err
 	}
+	if err := host.Validate(); err != nil {
+		return err
+	}
 	if smoke {
 		return runSmoke(host)
 	}
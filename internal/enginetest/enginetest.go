@@ -0,0 +1,83 @@
+// Package enginetest holds helpers shared by tests that run scripts on an
+// engine.Runtime.
+package enginetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/pkg/engine"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimeowner"
+)
+
+// Timeout bounds how long Await waits for a promise to settle.
+const Timeout = 5 * time.Second
+
+// RunAsync runs body as the body of an async function on rt and returns the
+// value it resolves to, exported to Go. See Await.
+func RunAsync(t testing.TB, rt *engine.Runtime, body string) any {
+	t.Helper()
+	return Await(t, rt, "(async () => {"+body+"\n})()")
+}
+
+// Await runs src on rt's owner and, when its completion value is a promise,
+// waits for it with runtimeowner.AwaitPromise. It fails t when src throws, the
+// promise rejects, or the promise does not settle within Timeout, and returns
+// the settled value exported to Go.
+func Await(t testing.TB, rt *engine.Runtime, src string) any {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	ret, err := rt.Owner.Call(ctx, "enginetest.run", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		return vm.RunString(src)
+	})
+	if err != nil {
+		t.Fatalf("run script: %v", err)
+	}
+	value := ret.(goja.Value)
+	promise, ok := value.Export().(*goja.Promise)
+	if !ok {
+		return export(t, rt, value)
+	}
+	settlement, err := runtimeowner.AwaitPromise(ctx, rt.Owner, "enginetest.await", promise)
+	if err != nil {
+		t.Fatalf("await script: %v", err)
+	}
+	if settlement.Rejected() {
+		t.Fatalf("script rejected: %s", describe(t, rt, settlement.Value))
+	}
+	return export(t, rt, settlement.Value)
+}
+
+func export(t testing.TB, rt *engine.Runtime, value goja.Value) any {
+	t.Helper()
+	ret, err := rt.Owner.Call(context.Background(), "enginetest.export", func(context.Context, *goja.Runtime) (any, error) {
+		if value == nil {
+			return nil, nil
+		}
+		return value.Export(), nil
+	})
+	if err != nil {
+		t.Fatalf("export result: %v", err)
+	}
+	return ret
+}
+
+// describe formats a rejection reason, preferring the stack of Error values.
+func describe(t testing.TB, rt *engine.Runtime, reason goja.Value) string {
+	t.Helper()
+	ret, err := rt.Owner.Call(context.Background(), "enginetest.describe", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		if obj, ok := reason.(*goja.Object); ok {
+			if stack := obj.Get("stack"); stack != nil && !goja.IsUndefined(stack) {
+				return stack.String(), nil
+			}
+		}
+		return reason.String(), nil
+	})
+	if err != nil {
+		return err.Error()
+	}
+	return ret.(string)
+}
@@ -0,0 +1,367 @@
+package childprocessmod
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/buffer"
+	"github.com/go-go-golems/go-go-goja/modules"
+	"github.com/go-go-golems/go-go-goja/modules/events"
+	"github.com/go-go-golems/go-go-goja/modules/stream"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimebridge"
+	"github.com/go-go-golems/go-go-goja/pkg/sandbox"
+	"github.com/go-go-golems/go-go-goja/pkg/tsgen/spec"
+)
+
+// m implements a Node-style child_process module on top of os/exec.
+type m struct{ name string }
+
+var _ modules.NativeModule = (*m)(nil)
+var _ modules.TypeScriptDeclarer = (*m)(nil)
+
+func (m m) Name() string {
+	if m.name != "" {
+		return m.name
+	}
+	return "child_process"
+}
+
+// Doc returns the documentation for the module.
+func (m m) Doc() string {
+	return `The child_process module runs external commands without blocking the runtime.
+
+spawn(command, args?, options?) returns an EventEmitter child whose stdout and
+stderr are stream.Readable and whose stdin is a stream.Writable, with
+kill(signal?) and "spawn", "exit", "close" and "error" events.
+exec(command, options?) runs a shell command and execFile(file, args?,
+options?) runs a file directly; both return a Promise of { stdout, stderr, exitCode, signal } and reject with an error
+carrying the same fields when the command fails.
+
+Options: cwd, env, input, timeout (ms), killSignal, encoding, maxBuffer and
+shell. Children are killed when the runtime closes.`
+}
+
+func (m m) TypeScriptModule() *spec.Module {
+	return &spec.Module{
+		Name: m.Name(),
+		RawDTS: []string{
+			"import { Readable, Writable } from \"stream\";",
+			"export function spawn(command: string, args?: string[], options?: SpawnOptions): ChildProcess;",
+			"export function exec(command: string, options?: ExecOptions): Promise<ExecResult>;",
+			"export function execFile(file: string, args?: string[], options?: ExecOptions): Promise<ExecResult>;",
+			"export type Signal = string | number;",
+			"export interface SpawnOptions {",
+			"  cwd?: string;",
+			"  env?: Record<string, string>;",
+			"  input?: string | Uint8Array;",
+			"  timeout?: number;",
+			"  killSignal?: Signal;",
+			"  shell?: boolean | string;",
+			"}",
+			"export interface ExecOptions extends SpawnOptions {",
+			"  encoding?: string;",
+			"  maxBuffer?: number;",
+			"}",
+			"export interface ExecResult {",
+			"  stdout: string | Uint8Array;",
+			"  stderr: string | Uint8Array;",
+			"  exitCode: number | null;",
+			"  signal: string | null;",
+			"}",
+			"export interface ExecError extends Error, ExecResult {",
+			"  code: number | string | null;",
+			"  killed: boolean;",
+			"  timedOut: boolean;",
+			"  cmd: string;",
+			"}",
+			"export interface ChildProcess {",
+			"  readonly pid: number | null;",
+			"  readonly stdin: Writable | null;",
+			"  readonly stdout: Readable;",
+			"  readonly stderr: Readable;",
+			"  readonly exitCode: number | null;",
+			"  readonly signalCode: string | null;",
+			"  readonly killed: boolean;",
+			"  kill(signal?: Signal): boolean;",
+			"  on(event: \"spawn\", listener: () => void): this;",
+			"  on(event: \"exit\" | \"close\", listener: (code: number | null, signal: string | null) => void): this;",
+			"  on(event: \"error\", listener: (err: Error) => void): this;",
+			"}",
+		},
+	}
+}
+
+// Loader attaches spawn, exec and execFile to the JS module.exports object.
+func (mod m) Loader(vm *goja.Runtime, moduleObj *goja.Object) {
+	runtimeServices, ok := runtimebridge.Lookup(vm)
+	if !ok || runtimeServices.Owner == nil {
+		panic(vm.NewGoError(fmt.Errorf("%s module requires runtime services", mod.Name())))
+	}
+	exports := moduleObj.Get("exports").(*goja.Object)
+
+	modules.SetExport(exports, mod.Name(), "spawn", func(call goja.FunctionCall) goja.Value {
+		file := call.Argument(0).String()
+		args, optsValue := splitArgs(vm, call.Argument(1), call.Argument(2))
+		opts := parseOptions(vm, optsValue, runtimeServices.Sandbox)
+		if opts.shell != "" {
+			file, args = shellCommand(opts.shell, strings.Join(append([]string{file}, args...), " "))
+		}
+		if err := checkSandbox(runtimeServices.Sandbox, file, args, opts); err != nil {
+			panic(sandbox.JSError(vm, err))
+		}
+		return spawn(vm, runtimeServices, file, args, opts)
+	})
+	modules.SetExport(exports, mod.Name(), "exec", func(command string, optsValue goja.Value) goja.Value {
+		opts := parseOptions(vm, optsValue, runtimeServices.Sandbox)
+		if opts.shell == "" {
+			opts.shell = defaultShell()
+		}
+		file, args := shellCommand(opts.shell, command)
+		return execute(vm, runtimeServices, file, args, command, opts)
+	})
+	modules.SetExport(exports, mod.Name(), "execFile", func(call goja.FunctionCall) goja.Value {
+		file := call.Argument(0).String()
+		args, optsValue := splitArgs(vm, call.Argument(1), call.Argument(2))
+		opts := parseOptions(vm, optsValue, runtimeServices.Sandbox)
+		display := strings.Join(append([]string{file}, args...), " ")
+		if opts.shell != "" {
+			file, args = shellCommand(opts.shell, display)
+		}
+		return execute(vm, runtimeServices, file, args, display, opts)
+	})
+}
+
+// splitArgs accepts both (file, args, options) and (file, options).
+func splitArgs(vm *goja.Runtime, argsValue, optsValue goja.Value) ([]string, goja.Value) {
+	if !modules.Present(argsValue) {
+		return nil, optsValue
+	}
+	obj, ok := argsValue.(*goja.Object)
+	if !ok || obj.ClassName() != "Array" {
+		if ok && !modules.Present(optsValue) {
+			return nil, argsValue
+		}
+		panic(vm.NewTypeError("args must be an array of strings"))
+	}
+	var args []string
+	if err := vm.ExportTo(argsValue, &args); err != nil {
+		panic(vm.NewTypeError("args must be an array of strings"))
+	}
+	return args, optsValue
+}
+
+func spawn(vm *goja.Runtime, services runtimebridge.RuntimeServices, file string, args []string, opts options) goja.Value {
+	p := newProcess(services, file, args, opts)
+	emitter, obj := events.NewObject(vm)
+	emit := func(name string, args ...goja.Value) {
+		if _, err := emitter.Emit(name, args...); err != nil {
+			log.Warn().Err(err).Str("event", name).Msg("child_process: listener error")
+		}
+	}
+
+	// stdout and stderr are read through os.Pipe rather than cmd.StdoutPipe so
+	// that Wait does not close them under a consumer that has not drained them
+	// yet; the stream adapters close the read ends at EOF.
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		panic(vm.NewGoError(err))
+	}
+	stderrR, stderrW, err := os.Pipe()
+	if err != nil {
+		_ = stdoutR.Close()
+		_ = stdoutW.Close()
+		panic(vm.NewGoError(err))
+	}
+	p.cmd.Stdout, p.cmd.Stderr = stdoutW, stderrW
+	stdout := stream.NewReadable(vm, stdoutR, stream.Options{})
+	stderr := stream.NewReadable(vm, stderrR, stream.Options{})
+
+	stdin := goja.Null()
+	if opts.hasInput {
+		p.cmd.Stdin = bytes.NewReader(opts.input)
+	} else {
+		pipe, err := p.cmd.StdinPipe()
+		if err != nil {
+			panic(vm.NewGoError(err))
+		}
+		stdin = stream.NewWritable(vm, pipe, stream.Options{})
+	}
+	_ = obj.Set("stdin", stdin)
+	_ = obj.Set("stdout", stdout)
+	_ = obj.Set("stderr", stderr)
+	_ = obj.Set("pid", goja.Null())
+	_ = obj.Set("exitCode", goja.Null())
+	_ = obj.Set("signalCode", goja.Null())
+	_ = obj.Set("killed", false)
+	_ = obj.Set("kill", func(call goja.FunctionCall) goja.Value {
+		signal, err := parseSignal(call.Argument(0))
+		if err != nil {
+			panic(vm.NewTypeError(err.Error()))
+		}
+		delivered := p.kill(signal)
+		if delivered {
+			_ = obj.Set("killed", true)
+		}
+		return vm.ToValue(delivered)
+	})
+
+	startErr := p.start()
+	// The child holds its own copies of the write ends; closing ours lets the
+	// readers see EOF once the child and anything it started exit.
+	_ = stdoutW.Close()
+	_ = stderrW.Close()
+	if startErr != nil {
+		p.post("child_process.error", func(vm *goja.Runtime) {
+			emit("error", spawnError(vm, startErr, file))
+		})
+		return obj
+	}
+	_ = obj.Set("pid", p.cmd.Process.Pid)
+	p.post("child_process.spawn", func(vm *goja.Runtime) {
+		emit("spawn")
+	})
+
+	// "close" follows "exit" once both output streams have closed.
+	pending := 3
+	closed := func() {
+		if pending--; pending == 0 {
+			emit("close", obj.Get("exitCode"), obj.Get("signalCode"))
+		}
+	}
+	for _, s := range []*goja.Object{stdout, stderr} {
+		callMethod(vm, s, "once", vm.ToValue("close"), vm.ToValue(func(goja.FunctionCall) goja.Value {
+			closed()
+			return goja.Undefined()
+		}))
+	}
+	go func() {
+		status := p.wait()
+		p.post("child_process.exit", func(vm *goja.Runtime) {
+			code, signal := exitValues(vm, status)
+			_ = obj.Set("exitCode", code)
+			_ = obj.Set("signalCode", signal)
+			_ = obj.Set("killed", status.killed)
+			emit("exit", code, signal)
+			// Like Node, drain output nobody is reading so "close" is not
+			// held back by an unconsumed stream.
+			for _, s := range []*goja.Object{stdout, stderr} {
+				if goja.IsNull(s.Get("readableFlowing")) {
+					callMethod(vm, s, "resume")
+				}
+			}
+			closed()
+		})
+	}()
+	return obj
+}
+
+// callMethod calls obj[name](args...) and rethrows a JS exception.
+func callMethod(vm *goja.Runtime, obj *goja.Object, name string, args ...goja.Value) {
+	fn, ok := goja.AssertFunction(obj.Get(name))
+	if !ok {
+		panic(vm.NewTypeError("%s is not a function", name))
+	}
+	if _, err := fn(obj, args...); err != nil {
+		panic(err)
+	}
+}
+
+func execute(vm *goja.Runtime, services runtimebridge.RuntimeServices, file string, args []string, display string, opts options) goja.Value {
+	promise, resolve, reject := vm.NewPromise()
+	if err := checkSandbox(services.Sandbox, file, args, opts); err != nil {
+		_ = reject(sandbox.JSError(vm, err))
+		return vm.ToValue(promise)
+	}
+	p := newProcess(services, file, args, opts)
+	stdout := &collector{p: p, limit: opts.maxBuffer}
+	stderr := &collector{p: p, limit: opts.maxBuffer}
+	p.cmd.Stdout, p.cmd.Stderr = stdout, stderr
+	if opts.hasInput {
+		p.cmd.Stdin = bytes.NewReader(opts.input)
+	}
+	if err := p.start(); err != nil {
+		_ = reject(spawnError(vm, err, display))
+		return vm.ToValue(promise)
+	}
+	go func() {
+		status := p.wait()
+		p.post("child_process.exec", func(vm *goja.Runtime) {
+			result := vm.NewObject()
+			if !status.ok() {
+				result = vm.NewGoError(fmt.Errorf("%s", failureMessage(display, status, stderr.buf.String())))
+				_ = result.Set("code", failureCode(vm, status))
+				_ = result.Set("killed", status.killed)
+				_ = result.Set("timedOut", status.timedOut)
+				_ = result.Set("cmd", display)
+			}
+			code, signal := exitValues(vm, status)
+			_ = result.Set("stdout", encodeOutput(vm, stdout.buf.Bytes(), opts.encoding))
+			_ = result.Set("stderr", encodeOutput(vm, stderr.buf.Bytes(), opts.encoding))
+			_ = result.Set("exitCode", code)
+			_ = result.Set("signal", signal)
+			if status.ok() {
+				_ = resolve(result)
+				return
+			}
+			_ = reject(result)
+		})
+	}()
+	return vm.ToValue(promise)
+}
+
+func exitValues(vm *goja.Runtime, status exitStatus) (goja.Value, goja.Value) {
+	if status.signal != "" {
+		return goja.Null(), vm.ToValue(status.signal)
+	}
+	if status.code < 0 {
+		return goja.Null(), goja.Null()
+	}
+	return vm.ToValue(status.code), goja.Null()
+}
+
+func failureMessage(display string, status exitStatus, stderr string) string {
+	switch {
+	case status.overflowed:
+		return "child_process: stdout or stderr maxBuffer exceeded: " + display
+	case status.timedOut:
+		return "Command timed out: " + display
+	case status.err != nil:
+		return fmt.Sprintf("Command failed: %s: %v", display, status.err)
+	}
+	return strings.TrimRight("Command failed: "+display+"\n"+stderr, "\n")
+}
+
+func failureCode(vm *goja.Runtime, status exitStatus) goja.Value {
+	if status.overflowed {
+		return vm.ToValue("ERR_CHILD_PROCESS_STDIO_MAXBUFFER")
+	}
+	if status.signal != "" || status.code < 0 {
+		return goja.Null()
+	}
+	return vm.ToValue(status.code)
+}
+
+func encodeOutput(vm *goja.Runtime, data []byte, encoding string) goja.Value {
+	if encoding == "buffer" {
+		return buffer.WrapBytes(vm, data)
+	}
+	if encoding == "" {
+		encoding = "utf8"
+	}
+	return buffer.EncodeBytes(vm, data, vm.ToValue(encoding))
+}
+
+func spawnError(vm *goja.Runtime, err error, file string) *goja.Object {
+	obj := vm.NewGoError(fmt.Errorf("spawn %s: %w", file, err))
+	_ = obj.Set("code", spawnErrorCode(err))
+	_ = obj.Set("path", file)
+	return obj
+}
+
+func init() {
+	modules.Register(&m{name: "child_process"})
+	modules.Register(&m{name: "node:child_process"})
+}
@@ -0,0 +1,243 @@
+package childprocessmod_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	_ "github.com/go-go-golems/go-go-goja/modules/childprocess"
+	"github.com/go-go-golems/go-go-goja/pkg/engine"
+	"github.com/go-go-golems/go-go-goja/pkg/sandbox"
+)
+
+func newRuntime(t *testing.T, lifetime context.Context, opts ...engine.Option) *engine.Runtime {
+	t.Helper()
+	factory, err := engine.NewRuntimeFactoryBuilder(opts...).UseModuleMiddleware(engine.MiddlewareOnly("child_process")).Build()
+	if err != nil {
+		t.Fatalf("build factory: %v", err)
+	}
+	rt, err := factory.NewRuntime(engine.WithStartupContext(context.Background()), engine.WithLifetimeContext(lifetime))
+	if err != nil {
+		t.Fatalf("new runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close(context.Background()) })
+	return rt
+}
+
+func run(t *testing.T, rt *engine.Runtime, script string) {
+	t.Helper()
+	_, err := rt.Owner.Call(context.Background(), "child_process.test", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		_, runErr := vm.RunString(`globalThis.__state = { done: false };` + script)
+		return nil, runErr
+	})
+	if err != nil {
+		t.Fatalf("run script: %v", err)
+	}
+}
+
+func readState(t *testing.T, rt *engine.Runtime) string {
+	t.Helper()
+	ret, err := rt.Owner.Call(context.Background(), "child_process.state", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		value, runErr := vm.RunString(`JSON.stringify(globalThis.__state)`)
+		if runErr != nil {
+			return nil, runErr
+		}
+		return value.String(), nil
+	})
+	if err != nil {
+		t.Fatalf("read state: %v", err)
+	}
+	return ret.(string)
+}
+
+func requireState(t *testing.T, rt *engine.Runtime, wants ...string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	state := readState(t, rt)
+	for !strings.Contains(state, `"done":true`) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		state = readState(t, rt)
+	}
+	for _, want := range wants {
+		if !strings.Contains(state, want) {
+			t.Fatalf("state missing %s: %s", want, state)
+		}
+	}
+}
+
+func TestExecResolvesAndRejectsWithExitDetails(t *testing.T) {
+	rt := newRuntime(t, context.Background())
+	dir := t.TempDir()
+	run(t, rt, `
+		(async () => {
+			const cp = require("child_process");
+			const ok = await cp.exec("cat; printf ' %s\\n' \"$GREETING\"; pwd", {
+				input: "piped", env: { GREETING: "hi" }, cwd: `+"`"+dir+"`"+`,
+			});
+			let failed;
+			try {
+				await cp.execFile("sh", ["-c", "echo oops >&2; exit 3"]);
+			} catch (e) {
+				failed = { code: e.code, exitCode: e.exitCode, stderr: e.stderr, signal: e.signal };
+			}
+			globalThis.__state = { done: true, ok: ok.stdout.trim(), exitCode: ok.exitCode, failed };
+		})().catch(e => { globalThis.__state = { done: true, error: String(e) }; });
+	`)
+	requireState(t, rt,
+		`"ok":"piped hi`+"\\n"+dir+`"`,
+		`"exitCode":0`,
+		`"failed":{"code":3,"exitCode":3,"stderr":"oops\n","signal":null}`,
+	)
+}
+
+func TestSpawnStreamsStdio(t *testing.T) {
+	rt := newRuntime(t, context.Background())
+	run(t, rt, `
+		const cp = require("child_process");
+		const child = cp.spawn("cat");
+		const events = [];
+		let out = "";
+		child.stdout.setEncoding("utf8");
+		child.stdout.on("data", chunk => { out += chunk; });
+		child.on("spawn", () => events.push("spawn"));
+		child.on("exit", (code, signal) => events.push("exit:" + code + ":" + signal));
+		child.on("close", code => {
+			events.push("close");
+			globalThis.__state = { done: true, out, events, pid: typeof child.pid, exitCode: child.exitCode };
+		});
+		child.stdin.write("hello ");
+		child.stdin.end(Buffer.from("world"));
+	`)
+	requireState(t, rt, `"out":"hello world"`, `"events":["spawn","exit:0:null","close"]`, `"pid":"number"`, `"exitCode":0`)
+}
+
+func TestSpawnStdioAreStreams(t *testing.T) {
+	rt := newRuntime(t, context.Background())
+	run(t, rt, `
+		const cp = require("child_process");
+		const { Readable, Writable, pipeline } = require("stream");
+		const child = cp.spawn("sh", ["-c", "head -c 100000 /dev/zero; echo unread >&2"]);
+		let bytes = 0;
+		const sink = new Writable({ write(chunk, encoding, callback) { bytes += chunk.length; callback(); } });
+		let piped;
+		pipeline(child.stdout, sink, err => { piped = err || null; });
+		child.on("close", code => {
+			globalThis.__state = {
+				done: true, err: piped, bytes, code,
+				streams: child.stdout instanceof Readable && child.stdin instanceof Writable,
+			};
+		});
+	`)
+	requireState(t, rt, `"err":null`, `"bytes":100000`, `"code":0`, `"streams":true`)
+}
+
+func TestTimeoutAndKillSignal(t *testing.T) {
+	rt := newRuntime(t, context.Background())
+	run(t, rt, `
+		(async () => {
+			const cp = require("child_process");
+			const started = Date.now();
+			let timedOut;
+			try {
+				await cp.execFile("sleep", ["5"], { timeout: 50 });
+			} catch (e) {
+				timedOut = { timedOut: e.timedOut, killed: e.killed, signal: e.signal, fast: Date.now() - started < 3000 };
+			}
+			const child = cp.spawn("sleep", ["5"]);
+			const killed = await new Promise(resolve => {
+				child.on("exit", (code, signal) => resolve({ code, signal, killed: child.killed }));
+				child.kill("SIGINT");
+			});
+			globalThis.__state = { done: true, timedOut, killed };
+		})().catch(e => { globalThis.__state = { done: true, error: String(e) }; });
+	`)
+	requireState(t, rt,
+		`"timedOut":{"timedOut":true,"killed":true,"signal":"SIGTERM","fast":true}`,
+		`"killed":{"code":null,"signal":"SIGINT","killed":true}`,
+	)
+}
+
+func TestChildrenAreKilledWhenLifetimeEnds(t *testing.T) {
+	lifetime, cancel := context.WithCancel(context.Background())
+	rt := newRuntime(t, lifetime)
+	ret, err := rt.Owner.Call(context.Background(), "child_process.spawn", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		value, runErr := vm.RunString(`require("child_process").spawn("sleep", ["30"]).pid`)
+		if runErr != nil {
+			return nil, runErr
+		}
+		return value.ToInteger(), nil
+	})
+	if err != nil {
+		t.Fatalf("spawn: %v", err)
+	}
+	pid := int(ret.(int64))
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		t.Fatalf("find process: %v", err)
+	}
+	for proc.Signal(syscall.Signal(0)) == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("child %d still running after the runtime lifetime ended", pid)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSandboxProfileGatesCommands(t *testing.T) {
+	profile, err := sandbox.ParseProfile([]byte("exec:\n  - command: echo\n"), "")
+	if err != nil {
+		t.Fatalf("parse profile: %v", err)
+	}
+	rt := newRuntime(t, context.Background(), engine.WithSandbox(profile))
+	run(t, rt, `
+		(async () => {
+			const cp = require("child_process");
+			const echoed = await cp.execFile("echo", ["allowed"]);
+			let denied, thrown;
+			try { await cp.exec("echo nope"); } catch (e) { denied = e.code + ":" + e.capability; }
+			try { cp.spawn("ls"); } catch (e) { thrown = e.code; }
+			globalThis.__state = { done: true, echoed: echoed.stdout, denied, thrown };
+		})().catch(e => { globalThis.__state = { done: true, error: String(e) }; });
+	`)
+	requireState(t, rt, `"echoed":"allowed\n"`, `"denied":"ERR_ACCESS_DENIED:exec"`, `"thrown":"ERR_ACCESS_DENIED"`)
+}
+
+func TestSandboxProfileChecksCwdAndChildEnv(t *testing.T) {
+	granted, other := t.TempDir(), t.TempDir()
+	for _, dir := range []string{granted, other} {
+		if err := os.Mkdir(filepath.Join(dir, "bin"), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		script := "#!/bin/sh\nprintf '%s|%s' \"$LD_PRELOAD\" \"$GREETING\"\n"
+		if err := os.WriteFile(filepath.Join(dir, "bin", "tool"), []byte(script), 0o755); err != nil {
+			t.Fatalf("write tool: %v", err)
+		}
+	}
+	profile, err := sandbox.ParseProfile([]byte(fmt.Sprintf(
+		"fs:\n  read: [%q]\nexec:\n  - command: %q\nenv: [GREETING]\n", granted, filepath.Join(granted, "bin", "tool"))), "")
+	if err != nil {
+		t.Fatalf("parse profile: %v", err)
+	}
+	rt := newRuntime(t, context.Background(), engine.WithSandbox(profile))
+	run(t, rt, `
+		(async () => {
+			const cp = require("child_process");
+			const ran = await cp.execFile("./bin/tool", [], {
+				cwd: `+"`"+granted+"`"+`, env: { GREETING: "hi", LD_PRELOAD: "/tmp/evil.so" },
+			});
+			let cwdDenied, thrown;
+			try { await cp.execFile("./bin/tool", [], { cwd: `+"`"+other+"`"+` }); } catch (e) { cwdDenied = e.code + ":" + e.capability; }
+			try { cp.spawn("./bin/tool", [], { cwd: "/" }); } catch (e) { thrown = e.code; }
+			globalThis.__state = { done: true, ran: ran.stdout, cwdDenied, thrown };
+		})().catch(e => { globalThis.__state = { done: true, error: String(e) }; });
+	`)
+	requireState(t, rt, `"ran":"|hi"`, `"cwdDenied":"ERR_ACCESS_DENIED:fs.read"`, `"thrown":"ERR_ACCESS_DENIED"`)
+}
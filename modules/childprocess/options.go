@@ -0,0 +1,204 @@
+package childprocessmod
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/buffer"
+	"github.com/go-go-golems/go-go-goja/modules"
+	"github.com/go-go-golems/go-go-goja/pkg/sandbox"
+)
+
+// defaultMaxBuffer bounds the output exec and execFile collect per stream,
+// matching Node's default.
+const defaultMaxBuffer = 1024 * 1024
+
+// killGrace is how long a process gets to exit after its kill signal before a
+// timeout or runtime shutdown escalates to SIGKILL.
+const killGrace = 2 * time.Second
+
+// options is the parsed form of the options object accepted by spawn, exec
+// and execFile.
+type options struct {
+	cwd        string
+	env        []string
+	input      []byte
+	hasInput   bool
+	timeout    time.Duration
+	killSignal syscall.Signal
+	encoding   string
+	maxBuffer  int
+	shell      string
+}
+
+func parseOptions(vm *goja.Runtime, value goja.Value, profile *sandbox.Profile) options {
+	opts := options{
+		killSignal: syscall.SIGTERM,
+		maxBuffer:  defaultMaxBuffer,
+	}
+	obj, ok := value.(*goja.Object)
+	if !ok || goja.IsUndefined(value) || goja.IsNull(value) {
+		opts.env = inheritedEnv(profile)
+		return opts
+	}
+	if v := obj.Get("cwd"); modules.Present(v) {
+		opts.cwd = v.String()
+	}
+	if v := obj.Get("env"); modules.Present(v) {
+		// An explicit env is limited like the inherited one, so that a
+		// script cannot hand a granted command LD_PRELOAD or its own PATH.
+		envObj := v.ToObject(vm)
+		keys := envObj.Keys()
+		sort.Strings(keys)
+		for _, key := range keys {
+			if item := envObj.Get(key); modules.Present(item) && profile.AllowsEnv(key) {
+				opts.env = append(opts.env, key+"="+item.String())
+			}
+		}
+	} else {
+		opts.env = inheritedEnv(profile)
+	}
+	if v := obj.Get("input"); modules.Present(v) {
+		opts.input = inputBytes(vm, v)
+		opts.hasInput = true
+	}
+	if v := obj.Get("timeout"); modules.Present(v) {
+		if ms := v.ToInteger(); ms > 0 {
+			opts.timeout = time.Duration(ms) * time.Millisecond
+		}
+	}
+	if v := obj.Get("killSignal"); modules.Present(v) {
+		signal, err := parseSignal(v)
+		if err != nil {
+			panic(vm.NewTypeError(err.Error()))
+		}
+		opts.killSignal = signal
+	}
+	if v := obj.Get("encoding"); modules.Present(v) {
+		opts.encoding = v.String()
+	}
+	if v := obj.Get("maxBuffer"); modules.Present(v) {
+		if n := v.ToInteger(); n > 0 {
+			opts.maxBuffer = int(n)
+		}
+	}
+	if v := obj.Get("shell"); modules.Present(v) {
+		switch exported := v.Export().(type) {
+		case bool:
+			if exported {
+				opts.shell = defaultShell()
+			}
+		default:
+			opts.shell = v.String()
+		}
+	}
+	return opts
+}
+
+// command returns the command the child runs for file. exec runs a relative
+// path that names a directory, such as ./bin/tool, relative to cwd.
+func (o options) command(file string) string {
+	if o.cwd == "" || filepath.IsAbs(file) || !strings.ContainsAny(file, `/`+string(filepath.Separator)) {
+		return file
+	}
+	return filepath.Join(o.cwd, file)
+}
+
+// checkSandbox reports whether profile grants running file with args in
+// opts.cwd, which must be readable. file is checked as command resolves it.
+func checkSandbox(profile *sandbox.Profile, file string, args []string, opts options) error {
+	if opts.cwd != "" {
+		if err := profile.CheckRead(opts.cwd); err != nil {
+			return err
+		}
+	}
+	return profile.CheckExec(opts.command(file), args)
+}
+
+// inheritedEnv is the host environment a child sees when the caller passes no
+// env option. Like process.env it is limited to the sandbox's allowed names.
+func inheritedEnv(profile *sandbox.Profile) []string {
+	environ := os.Environ()
+	if profile == nil {
+		return environ
+	}
+	out := make([]string, 0, len(environ))
+	for _, item := range environ {
+		if key, _, ok := strings.Cut(item, "="); ok && profile.AllowsEnv(key) {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func inputBytes(vm *goja.Runtime, v goja.Value) []byte {
+	if s, ok := v.Export().(string); ok {
+		return []byte(s)
+	}
+	return buffer.DecodeBytes(vm, v, goja.Undefined())
+}
+
+func defaultShell() string {
+	if runtime.GOOS == "windows" {
+		return "cmd.exe"
+	}
+	return "/bin/sh"
+}
+
+// shellCommand returns the argv that runs command through shell.
+func shellCommand(shell, command string) (string, []string) {
+	if strings.EqualFold(shell, "cmd.exe") || strings.EqualFold(shell, "cmd") {
+		return shell, []string{"/d", "/s", "/c", command}
+	}
+	return shell, []string{"-c", command}
+}
+
+// signals lists the signal names scripts may pass to kill and killSignal.
+// Numeric signals are accepted as well.
+var signals = map[string]syscall.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGABRT": syscall.SIGABRT,
+	"SIGKILL": syscall.SIGKILL,
+	"SIGPIPE": syscall.SIGPIPE,
+	"SIGALRM": syscall.SIGALRM,
+	"SIGTERM": syscall.SIGTERM,
+}
+
+func parseSignal(v goja.Value) (syscall.Signal, error) {
+	if !modules.Present(v) {
+		return syscall.SIGTERM, nil
+	}
+	if n, ok := v.Export().(int64); ok {
+		if n <= 0 {
+			return 0, fmt.Errorf("invalid signal %d", n)
+		}
+		return syscall.Signal(n), nil
+	}
+	name := strings.ToUpper(v.String())
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	signal, ok := signals[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown signal %q", v.String())
+	}
+	return signal, nil
+}
+
+func signalName(signal syscall.Signal) string {
+	for name, candidate := range signals {
+		if candidate == signal {
+			return name
+		}
+	}
+	return fmt.Sprintf("SIG%d", int(signal))
+}
@@ -0,0 +1,155 @@
+package childprocessmod
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os/exec"
+	"sync"
+	"syscall"
+
+	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimebridge"
+)
+
+// process is one child started by spawn, exec or execFile. It is bound to the
+// runtime's lifetime context: when the runtime closes, the child receives its
+// kill signal and, after killGrace, SIGKILL.
+type process struct {
+	services runtimebridge.RuntimeServices
+	cmd      *exec.Cmd
+	ctx      context.Context
+	cancel   context.CancelFunc
+	opts     options
+
+	mu         sync.Mutex
+	exited     bool
+	killed     bool
+	overflowed bool
+}
+
+// exitStatus describes how a child ended. code is -1 when the child was
+// terminated by a signal.
+type exitStatus struct {
+	code       int
+	signal     string
+	killed     bool
+	timedOut   bool
+	overflowed bool
+	err        error
+}
+
+func (s exitStatus) ok() bool {
+	return s.err == nil && s.code == 0 && s.signal == "" && !s.overflowed
+}
+
+func newProcess(services runtimebridge.RuntimeServices, file string, args []string, opts options) *process {
+	ctx, cancel := context.WithCancel(services.Lifetime())
+	if opts.timeout > 0 {
+		ctx, cancel = context.WithTimeout(services.Lifetime(), opts.timeout)
+	}
+	// #nosec G204 -- child_process exists to run caller-selected commands; the sandbox profile gates them.
+	cmd := exec.CommandContext(ctx, opts.command(file), args...)
+	cmd.Dir = opts.cwd
+	cmd.Env = opts.env
+	cmd.WaitDelay = killGrace
+	p := &process{services: services, cmd: cmd, ctx: ctx, cancel: cancel, opts: opts}
+	cmd.Cancel = func() error {
+		p.mu.Lock()
+		p.killed = true
+		p.mu.Unlock()
+		return cmd.Process.Signal(opts.killSignal)
+	}
+	return p
+}
+
+func (p *process) start() error {
+	if err := p.cmd.Start(); err != nil {
+		p.cancel()
+		return err
+	}
+	return nil
+}
+
+// wait blocks until the child has exited and its output has been copied.
+func (p *process) wait() exitStatus {
+	err := p.cmd.Wait()
+	timedOut := p.opts.timeout > 0 && errors.Is(p.ctx.Err(), context.DeadlineExceeded)
+	p.cancel()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.exited = true
+	status := exitStatus{code: -1, killed: p.killed, timedOut: timedOut, overflowed: p.overflowed}
+	state := p.cmd.ProcessState
+	if state == nil {
+		status.err = err
+		return status
+	}
+	status.code = state.ExitCode()
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		status.signal = signalName(ws.Signal())
+	}
+	return status
+}
+
+// kill sends signal to a running child and reports whether it was delivered.
+func (p *process) kill(signal syscall.Signal) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.exited || p.cmd.Process == nil {
+		return false
+	}
+	if err := p.cmd.Process.Signal(signal); err != nil {
+		return false
+	}
+	p.killed = true
+	return true
+}
+
+// overflow stops a child whose collected output exceeded maxBuffer.
+func (p *process) overflow() {
+	p.mu.Lock()
+	p.overflowed = true
+	p.mu.Unlock()
+	p.cancel()
+}
+
+func (p *process) post(op string, fn func(*goja.Runtime)) {
+	err := p.services.PostWithLifetimeContext(op, func(_ context.Context, vm *goja.Runtime) {
+		fn(vm)
+	})
+	if err != nil {
+		log.Debug().Err(err).Str("op", op).Msg("child_process: dispatch dropped")
+	}
+}
+
+// collector buffers one output stream for exec and execFile up to maxBuffer.
+// It is written by os/exec's copy goroutine and read after Wait returns.
+type collector struct {
+	p     *process
+	buf   bytes.Buffer
+	limit int
+}
+
+func (c *collector) Write(b []byte) (int, error) {
+	if room := c.limit - c.buf.Len(); len(b) > room {
+		c.buf.Write(b[:max(room, 0)])
+		c.p.overflow()
+		return len(b), nil
+	}
+	return c.buf.Write(b)
+}
+
+// spawnErrorCode maps a start failure to the errno-style code Node reports.
+func spawnErrorCode(err error) string {
+	switch {
+	case errors.Is(err, exec.ErrNotFound), errors.Is(err, fs.ErrNotExist):
+		return "ENOENT"
+	case errors.Is(err, fs.ErrPermission):
+		return "EACCES"
+	default:
+		return "ESPAWN"
+	}
+}
@@ -0,0 +1,246 @@
+package databasemod
+
+import (
+	"database/sql"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/buffer"
+)
+
+// maxSafeInteger is Number.MAX_SAFE_INTEGER. Integers beyond it become
+// BigInts so they survive the trip to JavaScript intact.
+const maxSafeInteger = 1<<53 - 1
+
+type columnKind int
+
+const (
+	columnOther columnKind = iota
+	columnBlob
+	columnInt
+	columnFloat
+	columnDecimal
+	columnTime
+	columnBool
+)
+
+// column describes one result column. kind comes from the driver's
+// database type name and decides how values that drivers hand back as raw
+// bytes or strings are decoded.
+type column struct {
+	name string
+	kind columnKind
+}
+
+func resultColumns(rows *sql.Rows) ([]column, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	cols := make([]column, len(types))
+	for i, typ := range types {
+		cols[i] = column{name: typ.Name(), kind: kindOf(typ.DatabaseTypeName())}
+	}
+	return cols, nil
+}
+
+// kindOf classifies the type names used by sqlite3, Postgres and MySQL.
+func kindOf(typeName string) columnKind {
+	name := strings.ToUpper(typeName)
+	if i := strings.IndexByte(name, '('); i >= 0 {
+		name = name[:i]
+	}
+	switch {
+	case strings.Contains(name, "BLOB"), name == "BYTEA", strings.HasSuffix(name, "BINARY"):
+		return columnBlob
+	case strings.Contains(name, "DECIMAL"), strings.Contains(name, "NUMERIC"), name == "MONEY":
+		return columnDecimal
+	case strings.HasPrefix(name, "BOOL"):
+		return columnBool
+	case strings.Contains(name, "INT"), strings.HasPrefix(name, "SERIAL"), strings.HasPrefix(name, "BIGSERIAL"), name == "YEAR":
+		return columnInt
+	case strings.Contains(name, "FLOAT"), strings.Contains(name, "DOUBLE"), name == "REAL":
+		return columnFloat
+	case strings.Contains(name, "TIMESTAMP"), strings.Contains(name, "DATETIME"), name == "DATE":
+		return columnTime
+	}
+	return columnOther
+}
+
+func scanRow(rows *sql.Rows, n int) ([]any, error) {
+	vals := make([]any, n)
+	scan := make([]any, n)
+	for i := range vals {
+		scan[i] = &vals[i]
+	}
+	if err := rows.Scan(scan...); err != nil {
+		return nil, err
+	}
+	return vals, nil
+}
+
+// rowDecoder turns scanned values into JavaScript values. It must be used on
+// the owner goroutine.
+type rowDecoder struct {
+	vm      *goja.Runtime
+	columns []column
+	date    goja.Value
+}
+
+func newRowDecoder(vm *goja.Runtime, columns []column) *rowDecoder {
+	return &rowDecoder{vm: vm, columns: columns, date: vm.Get("Date")}
+}
+
+// record builds a plain object whose keys follow the column order.
+func (d *rowDecoder) record(vals []any) *goja.Object {
+	obj := d.vm.NewObject()
+	for i, col := range d.columns {
+		_ = obj.Set(col.name, d.value(col, vals[i]))
+	}
+	return obj
+}
+
+func (d *rowDecoder) value(col column, v any) goja.Value {
+	switch v := v.(type) {
+	case nil:
+		return goja.Null()
+	case []byte:
+		return d.bytes(col, v)
+	case string:
+		if col.kind == columnTime {
+			if t, ok := parseTime(v); ok {
+				return d.time(t)
+			}
+		}
+		return d.vm.ToValue(v)
+	case int64:
+		if col.kind == columnBool {
+			return d.vm.ToValue(v != 0)
+		}
+		return d.integer(v)
+	case uint64:
+		if v > maxSafeInteger {
+			return d.vm.ToValue(new(big.Int).SetUint64(v))
+		}
+		return d.vm.ToValue(int64(v))
+	case time.Time:
+		return d.time(v)
+	}
+	return d.vm.ToValue(v)
+}
+
+// bytes decodes the raw bytes drivers such as MySQL return for most column
+// types. Binary columns become Buffers; decimals stay strings so no digits
+// are lost.
+func (d *rowDecoder) bytes(col column, v []byte) goja.Value {
+	s := string(v)
+	switch col.kind {
+	case columnBlob, columnOther:
+		if col.kind == columnBlob || !isText(v) {
+			return buffer.WrapBytes(d.vm, append([]byte(nil), v...))
+		}
+	case columnInt:
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return d.integer(n)
+		}
+		if n, ok := new(big.Int).SetString(s, 10); ok {
+			return d.vm.ToValue(n)
+		}
+	case columnFloat:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return d.vm.ToValue(f)
+		}
+	case columnBool:
+		if b, err := strconv.ParseBool(s); err == nil {
+			return d.vm.ToValue(b)
+		}
+	case columnTime:
+		if t, ok := parseTime(s); ok {
+			return d.time(t)
+		}
+	}
+	return d.vm.ToValue(s)
+}
+
+func (d *rowDecoder) integer(v int64) goja.Value {
+	if v > maxSafeInteger || v < -maxSafeInteger {
+		return d.vm.ToValue(big.NewInt(v))
+	}
+	return d.vm.ToValue(v)
+}
+
+func (d *rowDecoder) time(t time.Time) goja.Value {
+	obj, err := d.vm.New(d.date, d.vm.ToValue(t.UnixMilli()))
+	if err != nil {
+		panic(err)
+	}
+	return obj
+}
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+func parseTime(s string) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// isText reports whether v looks like text rather than binary data.
+func isText(v []byte) bool {
+	for _, c := range v {
+		if c < 0x20 && c != '\t' && c != '\n' && c != '\r' {
+			return false
+		}
+	}
+	return utf8.Valid(v)
+}
+
+// bindArgs converts exported JavaScript arguments into driver arguments.
+// Arrays are spread, a plain object binds its keys as named parameters
+// (":id", "@id" or "$id" depending on the driver), and BigInts become
+// int64, or decimal strings when they do not fit.
+func bindArgs(args []any) []any {
+	var ret []any
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case []any:
+			ret = append(ret, bindArgs(arg)...)
+		case map[string]any:
+			names := make([]string, 0, len(arg))
+			for name := range arg {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				ret = append(ret, sql.Named(strings.TrimLeft(name, ":@$"), bindValue(arg[name])))
+			}
+		default:
+			ret = append(ret, bindValue(arg))
+		}
+	}
+	return ret
+}
+
+func bindValue(v any) any {
+	if n, ok := v.(*big.Int); ok {
+		if n.IsInt64() {
+			return n.Int64()
+		}
+		return n.String()
+	}
+	return v
+}
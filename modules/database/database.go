@@ -10,6 +10,7 @@ import (
 	"github.com/dop251/goja"
 	"github.com/go-go-golems/go-go-goja/modules"
 	"github.com/go-go-golems/go-go-goja/pkg/runtimebridge"
+	"github.com/go-go-golems/go-go-goja/pkg/sandbox"
 	"github.com/go-go-golems/go-go-goja/pkg/tsgen/spec"
 	_ "github.com/mattn/go-sqlite3" // Driver for sqlite3
 )
@@ -109,6 +110,9 @@ type DBModule struct {
 	queryExecer    QueryExecer
 	closeFn        func() error
 	allowConfigure bool
+	pool           PoolOptions
+	dialect        Dialect
+	fixedDialect   bool
 }
 
 var _ modules.NativeModule = (*DBModule)(nil)
@@ -124,6 +128,12 @@ func New(options ...Option) *DBModule {
 			option(ret)
 		}
 	}
+	if db, ok := ret.queryExecer.(*sql.DB); ok {
+		ret.pool.apply(db)
+		if !ret.fixedDialect {
+			ret.dialect = dialectOf(db)
+		}
+	}
 	return ret
 }
 
@@ -147,10 +157,58 @@ func (m *DBModule) TypeScriptModule() *spec.Module {
 			"}",
 			"interface DatabaseTransaction {",
 			"  query(query: string, ...args: unknown[]): Array<Record<string, unknown>>;",
+			"  queryOne(query: string, ...args: unknown[]): Record<string, unknown> | null;",
+			"  queryValue(query: string, ...args: unknown[]): unknown;",
 			"  exec(query: string, ...args: unknown[]): DatabaseExecResult;",
 			"  commit(): { success: boolean; error?: string };",
 			"  rollback(): { success: boolean; error?: string };",
 			"}",
+			"interface DatabaseRowIterator {",
+			"  next(): Promise<{ value: Record<string, unknown> | undefined; done: boolean }>;",
+			"  return(value?: unknown): Promise<{ value: unknown; done: true }>;",
+			"}",
+			"interface DatabasePoolOptions {",
+			"  maxOpenConns?: number;",
+			"  maxIdleConns?: number;",
+			"  connMaxLifetime?: number | string;",
+			"  connMaxIdleTime?: number | string;",
+			"}",
+			"interface DatabasePoolStats {",
+			"  maxOpenConnections: number;",
+			"  openConnections: number;",
+			"  inUse: number;",
+			"  idle: number;",
+			"  waitCount: number;",
+			"  waitDurationMs: number;",
+			"  maxIdleClosed: number;",
+			"  maxIdleTimeClosed: number;",
+			"  maxLifetimeClosed: number;",
+			"}",
+			"interface DatabaseMigration {",
+			"  version: number;",
+			"  name: string;",
+			"  sql: string | string[];",
+			"}",
+			"interface DatabaseMigrationResult {",
+			"  version: number;",
+			"  name: string;",
+			"  checksum: string;",
+			"  status: 'applied' | 'migrated' | 'pending';",
+			"  appliedAt: string;",
+			"}",
+			"interface DatabaseMigrateOptions {",
+			"  dryRun?: boolean;",
+			"  table?: string;",
+			"}",
+			"interface DatabaseStatement {",
+			"  readonly source: string;",
+			"  all(...args: unknown[]): Array<Record<string, unknown>>;",
+			"  get(...args: unknown[]): Record<string, unknown> | null;",
+			"  value(...args: unknown[]): unknown;",
+			"  run(...args: unknown[]): DatabaseExecResult;",
+			"  iterate(...args: unknown[]): DatabaseRowIterator;",
+			"  close(): void;",
+			"}",
 		},
 		Functions: []spec.Function{
 			{
@@ -158,6 +216,7 @@ func (m *DBModule) TypeScriptModule() *spec.Module {
 				Params: []spec.Param{
 					{Name: "driverName", Type: spec.String()},
 					{Name: "dataSourceName", Type: spec.String()},
+					{Name: "options", Type: spec.Named("DatabasePoolOptions"), Optional: true},
 				},
 				Returns: spec.Void(),
 			},
@@ -177,10 +236,53 @@ func (m *DBModule) TypeScriptModule() *spec.Module {
 				},
 				Returns: spec.Unknown(),
 			},
+			{
+				Name: "queryOne",
+				Params: []spec.Param{
+					{Name: "query", Type: spec.String()},
+					{Name: "args", Type: spec.Unknown(), Variadic: true},
+				},
+				Returns: spec.Unknown(),
+			},
+			{
+				Name: "queryValue",
+				Params: []spec.Param{
+					{Name: "query", Type: spec.String()},
+					{Name: "args", Type: spec.Unknown(), Variadic: true},
+				},
+				Returns: spec.Unknown(),
+			},
+			{
+				Name: "iterate",
+				Params: []spec.Param{
+					{Name: "query", Type: spec.String()},
+					{Name: "args", Type: spec.Unknown(), Variadic: true},
+				},
+				Returns: spec.Named("DatabaseRowIterator"),
+			},
+			{
+				Name: "prepare",
+				Params: []spec.Param{
+					{Name: "query", Type: spec.String()},
+				},
+				Returns: spec.Named("DatabaseStatement"),
+			},
+			{
+				Name: "migrate",
+				Params: []spec.Param{
+					{Name: "migrations", Type: spec.Union(spec.String(), spec.Array(spec.Named("DatabaseMigration")))},
+					{Name: "options", Type: spec.Named("DatabaseMigrateOptions"), Optional: true},
+				},
+				Returns: spec.Array(spec.Named("DatabaseMigrationResult")),
+			},
 			{
 				Name:    "begin",
 				Returns: spec.Named("DatabaseTransaction"),
 			},
+			{
+				Name:    "stats",
+				Returns: spec.Union(spec.Named("DatabasePoolStats"), spec.Named("null")),
+			},
 			{
 				Name:    "close",
 				Returns: spec.Void(),
@@ -197,15 +299,31 @@ Database module provides a simple SQL interface.
 Functions:
   query(sql, ...args): Executes a query and returns rows.
     Example: require('database').query('SELECT * FROM users WHERE id = ?', 1);
+  queryOne(sql, ...args): Returns the first row, or null when there is none.
+  queryValue(sql, ...args): Returns the first column of the first row, or undefined.
+    Example: require('database').queryValue('SELECT count(*) FROM users');
   exec(sql, ...args): Executes a statement and returns result summary.
     Example: require('database').exec('INSERT INTO users (name) VALUES (?)', 'John');
+  prepare(sql): Returns a reusable statement with all, get, value, run, iterate and close.
+    Example: const stmt = require('database').prepare('SELECT * FROM users WHERE id = :id'); stmt.get({ id: 1 });
+  iterate(sql, ...args): Returns an async iterator that streams rows one next() at a time.
+    Example: const it = require('database').iterate('SELECT * FROM users'); for (let r = await it.next(); !r.done; r = await it.next()) {}
+
+Arguments may be positional or a single object of named parameters (:name,
+@name or $name). Write ? placeholders for every driver; they are rewritten to
+$1, $2, ... for Postgres. BLOB columns come back as Buffers, timestamps as Dates and
+integers beyond Number.MAX_SAFE_INTEGER as BigInts.
   begin(): Starts a transaction. The returned object has query, exec, commit, and rollback.
     Example: const tx = require('database').begin(); tx.exec('INSERT INTO users(name) VALUES (?)', 'Ada'); tx.commit();
+  migrate(dirOrMigrations, options?): Applies pending schema migrations, each in its own transaction.
+    Example: require('database').migrate('./migrations', { dryRun: true });
+  stats(): Returns connection pool statistics, or null when the database is not a connection pool.
   close(): Closes the database connection if the module owns it.
 `
 	if m.allowConfigure {
 		doc += `
-  configure(driverName, dataSourceName): Configures the database connection.
+  configure(driverName, dataSourceName, options?): Configures the database connection.
+    options sets maxOpenConns, maxIdleConns, connMaxLifetime and connMaxIdleTime.
     Example: require('database').configure('sqlite3', ':memory:');
 `
 	} else {
@@ -220,9 +338,39 @@ This module is preconfigured by Go and does not allow configure().
 // Loader exposes the database functions to the JavaScript module.
 func (m *DBModule) Loader(vm *goja.Runtime, moduleObj *goja.Object) {
 	exports := moduleObj.Get("exports").(*goja.Object)
-	modules.SetExport(exports, m.Name(), "configure", m.Configure)
-	modules.SetExport(exports, m.Name(), "query", func(query string, args ...any) ([]map[string]any, error) {
-		return m.QueryContext(runtimebridge.CurrentOwnerContext(vm), query, args...)
+	modules.SetExport(exports, m.Name(), "configure", func(driverName, dataSourceName string, options goja.Value) error {
+		if err := runtimebridge.Sandbox(vm).CheckDatabase(driverName, dataSourceName); err != nil {
+			panic(sandbox.JSError(vm, err))
+		}
+		pool, err := poolOptionsArg(vm, options, m.pool)
+		if err != nil {
+			return err
+		}
+		m.pool = pool
+		return m.Configure(driverName, dataSourceName)
+	})
+	modules.SetExport(exports, m.Name(), "query", func(query string, args ...any) (goja.Value, error) {
+		return m.queryJS(vm, query, args, recordsValue)
+	})
+	modules.SetExport(exports, m.Name(), "queryOne", func(query string, args ...any) (goja.Value, error) {
+		return m.queryJS(vm, query, args, firstRecordValue)
+	})
+	modules.SetExport(exports, m.Name(), "queryValue", func(query string, args ...any) (goja.Value, error) {
+		return m.queryJS(vm, query, args, firstColumnValue)
+	})
+	modules.SetExport(exports, m.Name(), "iterate", func(query string, args ...any) (*goja.Object, error) {
+		open, err := m.openRows(query, args)
+		if err != nil {
+			return nil, err
+		}
+		return newRowIterator(vm, open), nil
+	})
+	modules.SetExport(exports, m.Name(), "prepare", func(query string) (*goja.Object, error) {
+		stmt, err := m.PrepareContext(runtimebridge.CurrentOwnerContext(vm), query)
+		if err != nil {
+			return nil, err
+		}
+		return stmt.ToObject(vm), nil
 	})
 	modules.SetExport(exports, m.Name(), "exec", func(query string, args ...any) (map[string]any, error) {
 		return m.ExecContext(runtimebridge.CurrentOwnerContext(vm), query, args...)
@@ -234,6 +382,10 @@ func (m *DBModule) Loader(vm *goja.Runtime, moduleObj *goja.Object) {
 		}
 		return tx.ToObject(vm), nil
 	})
+	modules.SetExport(exports, m.Name(), "migrate", func(source, options goja.Value) ([]map[string]any, error) {
+		return m.migrate(vm, source, options)
+	})
+	modules.SetExport(exports, m.Name(), "stats", m.statsValue)
 	modules.SetExport(exports, m.Name(), "close", m.Close)
 }
 
@@ -251,6 +403,10 @@ func (m *DBModule) Configure(driverName, dataSourceName string) error {
 		log.Error().Str("dsn", dataSourceName).Err(err).Msg("database: failed to open connection")
 		return err
 	}
+	m.pool.apply(db)
+	if !m.fixedDialect {
+		m.dialect = DialectForDriver(driverName)
+	}
 	m.queryExecer = db
 	m.closeFn = db.Close
 	log.Debug().Str("driver", driverName).Str("module", m.Name()).Msg("database: configured")
@@ -287,7 +443,11 @@ func (m *DBModule) QueryContext(ctx context.Context, query string, args ...any)
 	startTime := time.Now()
 	log.Debug().Str("module", m.Name()).Str("query", query).Msg("database: executing query")
 
-	rows, err := queryRows(ctx, m.queryExecer, query, flattenArgs(args)...)
+	query, bound, err := m.dialect.bind(query, args)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := queryRows(ctx, m.queryExecer, query, bound...)
 	if err != nil {
 		log.Error().Str("module", m.Name()).Str("query", query).Err(err).Msg("database: query error")
 		return nil, err
@@ -313,6 +473,42 @@ func (m *DBModule) QueryContext(ctx context.Context, query string, args ...any)
 	return result, nil
 }
 
+// openRows captures the configured database and returns a rowsFunc running
+// query on it. Rows are decoded by the caller, see queryJS.
+func (m *DBModule) openRows(query string, args []any) (rowsFunc, error) {
+	if m == nil || m.queryExecer == nil {
+		return nil, fmt.Errorf("database not configured, call require('%s').configure(...) first", m.Name())
+	}
+	qe, name := m.queryExecer, m.Name()
+	query, bound, err := m.dialect.bind(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context) (*sql.Rows, error) {
+		log.Debug().Str("module", name).Str("query", query).Msg("database: executing query")
+		rows, err := queryRows(ctx, qe, query, bound...)
+		if err != nil {
+			log.Error().Str("module", name).Str("query", query).Err(err).Msg("database: query error")
+		}
+		return rows, err
+	}, nil
+}
+
+// queryJS runs query on the owner goroutine and shapes the typed rows for
+// JavaScript.
+func (m *DBModule) queryJS(vm *goja.Runtime, query string, args []any, shape resultShape) (goja.Value, error) {
+	open, err := m.openRows(query, args)
+	if err != nil {
+		return nil, err
+	}
+	startTime := time.Now()
+	ret, err := queryJS(vm, open, shape)
+	if err == nil {
+		log.Debug().Str("module", m.Name()).Dur("duration", time.Since(startTime)).Msg("database: query completed")
+	}
+	return ret, err
+}
+
 // Exec executes a SQL statement without returning rows.
 func (m *DBModule) Exec(query string, args ...any) (map[string]any, error) {
 	return m.ExecContext(context.Background(), query, args...)
@@ -330,7 +526,11 @@ func (m *DBModule) ExecContext(ctx context.Context, query string, args ...any) (
 	startTime := time.Now()
 	log.Debug().Str("module", m.Name()).Str("query", query).Msg("database: executing exec")
 
-	result, err := execResult(ctx, m.queryExecer, query, flattenArgs(args)...)
+	var result sql.Result
+	query, bound, err := m.dialect.bind(query, args)
+	if err == nil {
+		result, err = execResult(ctx, m.queryExecer, query, bound...)
+	}
 	if err != nil {
 		log.Error().Str("module", m.Name()).Str("query", query).Err(err).Msg("database: exec error")
 		return map[string]any{
@@ -370,13 +570,14 @@ func (m *DBModule) BeginContext(ctx context.Context) (*TransactionHandle, error)
 	if err != nil {
 		return nil, err
 	}
-	return &TransactionHandle{moduleName: m.Name(), tx: tx}, nil
+	return &TransactionHandle{moduleName: m.Name(), tx: tx, dialect: m.dialect}, nil
 }
 
 // TransactionHandle is the JavaScript-facing database transaction handle.
 type TransactionHandle struct {
 	moduleName string
 	tx         Transaction
+	dialect    Dialect
 	closed     bool
 	mu         sync.Mutex
 }
@@ -384,8 +585,14 @@ type TransactionHandle struct {
 // ToObject creates the JavaScript transaction object exported by begin().
 func (h *TransactionHandle) ToObject(vm *goja.Runtime) *goja.Object {
 	obj := vm.NewObject()
-	modules.SetExport(obj, h.moduleName, "query", func(query string, args ...any) ([]map[string]any, error) {
-		return h.QueryContext(runtimebridge.CurrentOwnerContext(vm), query, args...)
+	modules.SetExport(obj, h.moduleName, "query", func(query string, args ...any) (goja.Value, error) {
+		return h.queryJS(vm, query, args, recordsValue)
+	})
+	modules.SetExport(obj, h.moduleName, "queryOne", func(query string, args ...any) (goja.Value, error) {
+		return h.queryJS(vm, query, args, firstRecordValue)
+	})
+	modules.SetExport(obj, h.moduleName, "queryValue", func(query string, args ...any) (goja.Value, error) {
+		return h.queryJS(vm, query, args, firstColumnValue)
 	})
 	modules.SetExport(obj, h.moduleName, "exec", func(query string, args ...any) (map[string]any, error) {
 		return h.ExecContext(runtimebridge.CurrentOwnerContext(vm), query, args...)
@@ -405,7 +612,11 @@ func (h *TransactionHandle) QueryContext(ctx context.Context, query string, args
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	rows, err := queryRows(ctx, h.tx, query, flattenArgs(args)...)
+	query, bound, err := h.dialect.bind(query, args)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := queryRows(ctx, h.tx, query, bound...)
 	if err != nil {
 		return nil, err
 	}
@@ -417,6 +628,23 @@ func (h *TransactionHandle) QueryContext(ctx context.Context, query string, args
 	return rowsToRecords(h.moduleName, rows)
 }
 
+// queryJS runs query inside the transaction and shapes the typed rows. The
+// handle stays locked until every row has been read.
+func (h *TransactionHandle) queryJS(vm *goja.Runtime, query string, args []any, shape resultShape) (goja.Value, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed || h.tx == nil {
+		return nil, fmt.Errorf("database transaction is closed")
+	}
+	query, bound, err := h.dialect.bind(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return queryJS(vm, func(ctx context.Context) (*sql.Rows, error) {
+		return queryRows(ctx, h.tx, query, bound...)
+	}, shape)
+}
+
 // ExecContext executes a statement inside the transaction.
 func (h *TransactionHandle) ExecContext(ctx context.Context, query string, args ...any) (map[string]any, error) {
 	h.mu.Lock()
@@ -427,7 +655,11 @@ func (h *TransactionHandle) ExecContext(ctx context.Context, query string, args
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	result, err := execResult(ctx, h.tx, query, flattenArgs(args)...)
+	query, bound, err := h.dialect.bind(query, args)
+	if err != nil {
+		return map[string]any{"error": err.Error(), "success": false}, err
+	}
+	result, err := execResult(ctx, h.tx, query, bound...)
 	if err != nil {
 		return map[string]any{"error": err.Error(), "success": false}, err
 	}
@@ -553,18 +785,6 @@ func execResult(ctx context.Context, qe QueryExecer, query string, args ...any)
 	return qe.Exec(query, args...)
 }
 
-func flattenArgs(args []any) []any {
-	var flatArgs []any
-	for _, arg := range args {
-		if slice, ok := arg.([]any); ok {
-			flatArgs = append(flatArgs, slice...)
-		} else {
-			flatArgs = append(flatArgs, arg)
-		}
-	}
-	return flatArgs
-}
-
 func init() {
 	modules.Register(New())
 	modules.Register(New(WithName("db")))
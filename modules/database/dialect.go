@@ -0,0 +1,275 @@
+package databasemod
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Dialect describes how a driver spells bind parameters. Scripts write "?"
+// and ":name" everywhere; queries are rewritten for dialects that need
+// something else.
+type Dialect string
+
+const (
+	// DialectSQLite passes queries through: sqlite3 understands "?",
+	// ":name", "@name" and "$name" natively.
+	DialectSQLite Dialect = "sqlite"
+	// DialectPostgres rewrites "?", ":name", "@name" and "$name" to $1, $2,
+	// ... and binds named arguments by position, which pgx and lib/pq
+	// require.
+	DialectPostgres Dialect = "postgres"
+	// DialectMySQL rewrites ":name" to "?" and binds named arguments by
+	// position. "@name" stays a MySQL user variable.
+	DialectMySQL Dialect = "mysql"
+)
+
+// DialectForDriver returns the dialect of a database/sql driver name.
+// Unknown drivers are passed through like sqlite3.
+func DialectForDriver(driverName string) Dialect {
+	switch strings.ToLower(driverName) {
+	case "pgx", "pgx/v5", "postgres", "postgresql", "cloudsqlpostgres":
+		return DialectPostgres
+	case "mysql":
+		return DialectMySQL
+	}
+	return DialectSQLite
+}
+
+// dialectOf guesses the dialect of a preconfigured *sql.DB from the package
+// of its driver.
+func dialectOf(db *sql.DB) Dialect {
+	t := reflect.TypeOf(db.Driver())
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	pkg := t.PkgPath()
+	switch {
+	case strings.Contains(pkg, "jackc/pgx"), strings.Contains(pkg, "lib/pq"):
+		return DialectPostgres
+	case strings.Contains(pkg, "go-sql-driver/mysql"):
+		return DialectMySQL
+	}
+	return DialectSQLite
+}
+
+// bind converts JavaScript arguments with bindArgs and rewrites query for
+// the dialect.
+func (d Dialect) bind(query string, args []any) (string, []any, error) {
+	p := d.placeholders(query)
+	bound, err := p.args(bindArgs(args))
+	return p.text, bound, err
+}
+
+// placeholders is a query rewritten for a dialect. refs lists the parameter
+// behind each bind position: "" for a positional "?", otherwise the name.
+type placeholders struct {
+	text      string
+	refs      []string
+	rewritten bool
+}
+
+// placeholders scans query for parameters outside string literals, quoted
+// identifiers and comments. "??" stands for a literal "?", such as the
+// Postgres jsonb operators ?, ?| and ?&. A ":" inside brackets is an array
+// slice, not a parameter. Queries with no "?" or named parameters, and
+// Postgres queries already written with $1, are returned unchanged.
+//
+// The escape is Postgres and MySQL only: SQLite queries are never rewritten,
+// so "??" there is two bind parameters, as sqlite3 reads it.
+func (d Dialect) placeholders(query string) placeholders {
+	if d != DialectPostgres && d != DialectMySQL {
+		return placeholders{text: query}
+	}
+	var out strings.Builder
+	var refs []string
+	index := map[string]int{}
+	emit := func(ref string) {
+		if d == DialectMySQL {
+			out.WriteByte('?')
+			refs = append(refs, ref)
+			return
+		}
+		// Postgres can reuse one position for a repeated name.
+		if i, ok := index[ref]; ok && ref != "" {
+			out.WriteString("$" + strconv.Itoa(i+1))
+			return
+		}
+		refs = append(refs, ref)
+		if ref != "" {
+			index[ref] = len(refs) - 1
+		}
+		out.WriteString("$" + strconv.Itoa(len(refs)))
+	}
+
+	rewritten, escaped := false, false
+	brackets := 0
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || (c == '`' && d == DialectMySQL):
+			end := skipQuoted(query, i, c, d == DialectMySQL)
+			out.WriteString(query[i:end])
+			i = end
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			out.WriteString(query[i : i+end])
+			i += end
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i
+			} else {
+				end += 4
+			}
+			out.WriteString(query[i : i+end])
+			i += end
+		case c == '$' && d == DialectPostgres && dollarTag(query[i:]) != "":
+			tag := dollarTag(query[i:])
+			end := strings.Index(query[i+len(tag):], tag)
+			if end < 0 {
+				end = len(query) - i
+			} else {
+				end += 2 * len(tag)
+			}
+			out.WriteString(query[i : i+end])
+			i += end
+		case c == '$' && d == DialectPostgres && i+1 < len(query) && query[i+1] >= '0' && query[i+1] <= '9':
+			// Already written for Postgres.
+			return placeholders{text: query}
+		case c == '?' && strings.HasPrefix(query[i:], "??"):
+			out.WriteByte('?')
+			escaped = true
+			i += 2
+		case c == '?':
+			emit("")
+			rewritten = true
+			i++
+		case c == ':' && strings.HasPrefix(query[i:], "::"):
+			// A Postgres cast.
+			out.WriteString("::")
+			i += 2
+		case ((c == ':' && brackets == 0) || (d == DialectPostgres && (c == '@' || c == '$'))) && i+1 < len(query) && isNameStart(query[i+1]):
+			end := i + 1
+			for end < len(query) && isNamePart(query[end]) {
+				end++
+			}
+			emit(query[i+1 : end])
+			rewritten = true
+			i = end
+		default:
+			switch c {
+			case '[':
+				brackets++
+			case ']':
+				brackets = max(brackets-1, 0)
+			}
+			out.WriteByte(c)
+			i++
+		}
+	}
+	if !rewritten {
+		if escaped {
+			return placeholders{text: out.String()}
+		}
+		return placeholders{text: query}
+	}
+	return placeholders{text: out.String(), refs: refs, rewritten: true}
+}
+
+// args orders bound arguments for the rewritten query: positional values
+// fill "?" in order and named values fill their names.
+func (p placeholders) args(args []any) ([]any, error) {
+	if !p.rewritten {
+		return args, nil
+	}
+	named := map[string]any{}
+	var positional []any
+	for _, arg := range args {
+		if n, ok := arg.(sql.NamedArg); ok {
+			named[n.Name] = n.Value
+			continue
+		}
+		positional = append(positional, arg)
+	}
+	ret := make([]any, len(p.refs))
+	used := map[string]bool{}
+	next := 0
+	for i, ref := range p.refs {
+		if ref == "" {
+			if next >= len(positional) {
+				return nil, fmt.Errorf("query has more ? placeholders than the %d positional arguments given", len(positional))
+			}
+			ret[i] = positional[next]
+			next++
+			continue
+		}
+		v, ok := named[ref]
+		if !ok {
+			return nil, fmt.Errorf("query parameter :%s is not bound", ref)
+		}
+		ret[i] = v
+		used[ref] = true
+	}
+	if next < len(positional) {
+		return nil, fmt.Errorf("query has %d ? placeholders but %d positional arguments were given", next, len(positional))
+	}
+	for name := range named {
+		if !used[name] {
+			return nil, fmt.Errorf("named argument %q is not used by the query", name)
+		}
+	}
+	return ret, nil
+}
+
+// skipQuoted returns the index after the literal or identifier starting at
+// i. Doubled quotes escape; MySQL also escapes with backslashes.
+func skipQuoted(query string, i int, quote byte, backslash bool) int {
+	for j := i + 1; j < len(query); j++ {
+		switch query[j] {
+		case '\\':
+			if backslash {
+				j++
+			}
+		case quote:
+			if j+1 < len(query) && query[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(query)
+}
+
+// dollarTag returns the opening tag of a Postgres dollar-quoted string such
+// as $$ or $body$, or "" when s does not start with one.
+func dollarTag(s string) string {
+	if strings.HasPrefix(s, "$$") {
+		return "$$"
+	}
+	if len(s) < 2 || !isNameStart(s[1]) {
+		return ""
+	}
+	end := 1
+	for end < len(s) && isNamePart(s[end]) {
+		end++
+	}
+	if end < len(s) && s[end] == '$' {
+		return s[:end+1]
+	}
+	return ""
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNamePart(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,85 @@
+package databasemod
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlaceholdersRewriteForDialect(t *testing.T) {
+	for _, tc := range []struct {
+		dialect Dialect
+		query   string
+		want    string
+		refs    []string
+	}{
+		{DialectSQLite, "SELECT * FROM t WHERE a = ? AND b = :b", "SELECT * FROM t WHERE a = ? AND b = :b", nil},
+		{DialectSQLite, "SELECT ?? , ?", "SELECT ?? , ?", nil},
+		{DialectPostgres, "SELECT * FROM t WHERE a = ? AND b = ?", "SELECT * FROM t WHERE a = $1 AND b = $2", []string{"", ""}},
+		{DialectPostgres, "SELECT * FROM t WHERE a = :id OR b = @id OR c = $id", "SELECT * FROM t WHERE a = $1 OR b = $1 OR c = $1", []string{"id"}},
+		{DialectPostgres, "SELECT '?', \"a?\", x::text, $$ ? :x $$, $tag$ ? $tag$ FROM t -- ?\nWHERE /* :y */ a = ?", "SELECT '?', \"a?\", x::text, $$ ? :x $$, $tag$ ? $tag$ FROM t -- ?\nWHERE /* :y */ a = $1", []string{""}},
+		{DialectPostgres, "SELECT * FROM t WHERE a = $1", "SELECT * FROM t WHERE a = $1", nil},
+		{DialectPostgres, "SELECT 'it''s ?' , ?", "SELECT 'it''s ?' , $1", []string{""}},
+		{DialectPostgres, "SELECT * FROM t WHERE doc ?? 'a' AND doc ??| ? AND doc ??& :keys", "SELECT * FROM t WHERE doc ? 'a' AND doc ?| $1 AND doc ?& $2", []string{"", "keys"}},
+		{DialectPostgres, "SELECT doc ?? 'a' FROM t", "SELECT doc ? 'a' FROM t", nil},
+		{DialectPostgres, "SELECT * FROM t WHERE doc ? 'a' AND id = $1", "SELECT * FROM t WHERE doc ? 'a' AND id = $1", nil},
+		{DialectPostgres, "SELECT arr[lo:hi], arr[:n], m[1][2:3] FROM t WHERE id = :id", "SELECT arr[lo:hi], arr[:n], m[1][2:3] FROM t WHERE id = $1", []string{"id"}},
+		{DialectMySQL, "SELECT ?? , ?", "SELECT ? , ?", []string{""}},
+		{DialectMySQL, "SELECT * FROM t WHERE a = :id AND b = ? AND c = :id AND d = @var", "SELECT * FROM t WHERE a = ? AND b = ? AND c = ? AND d = @var", []string{"id", "", "id"}},
+		{DialectMySQL, "SELECT 'a\\' ?', `c?` FROM t WHERE x = ?", "SELECT 'a\\' ?', `c?` FROM t WHERE x = ?", []string{""}},
+	} {
+		p := tc.dialect.placeholders(tc.query)
+		if p.text != tc.want || !reflect.DeepEqual(p.refs, tc.refs) {
+			t.Errorf("%s %q:\n got %q %q\nwant %q %q", tc.dialect, tc.query, p.text, p.refs, tc.want, tc.refs)
+		}
+	}
+}
+
+func TestPlaceholderArgsOrderNamedAndPositional(t *testing.T) {
+	query, args, err := DialectMySQL.bind("UPDATE t SET a = :a, b = ? WHERE id = :id AND a <> :a", []any{
+		"b-value", map[string]any{":id": 7, "a": "a-value"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != "UPDATE t SET a = ?, b = ? WHERE id = ? AND a <> ?" ||
+		!reflect.DeepEqual(args, []any{"a-value", "b-value", 7, "a-value"}) {
+		t.Fatalf("got %q %v", query, args)
+	}
+
+	for _, tc := range []struct {
+		query string
+		args  []any
+		want  string
+	}{
+		{"SELECT ?, ?", []any{1}, "more ? placeholders"},
+		{"SELECT ?", []any{1, 2}, "1 ? placeholders but 2"},
+		{"SELECT :a", []any{map[string]any{"b": 1}}, ":a is not bound"},
+		{"SELECT :a", []any{map[string]any{"a": 1, "b": 2}}, `"b" is not used`},
+	} {
+		if _, _, err := DialectPostgres.bind(tc.query, tc.args); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%q %v: got %v, want %q", tc.query, tc.args, err, tc.want)
+		}
+	}
+
+	// Queries without placeholders pass arguments through untouched.
+	_, args, err = DialectPostgres.bind("SELECT $1", []any{1})
+	if err != nil || !reflect.DeepEqual(args, []any{1}) {
+		t.Fatalf("passthrough: %v %v", args, err)
+	}
+	_, args, _ = DialectSQLite.bind("SELECT :a", []any{map[string]any{"a": 1}})
+	if !reflect.DeepEqual(args, []any{sql.Named("a", 1)}) {
+		t.Fatalf("sqlite named: %v", args)
+	}
+}
+
+func TestDialectForDriver(t *testing.T) {
+	for driver, want := range map[string]Dialect{
+		"pgx": DialectPostgres, "postgres": DialectPostgres, "mysql": DialectMySQL, "sqlite3": DialectSQLite, "custom": DialectSQLite,
+	} {
+		if got := DialectForDriver(driver); got != want {
+			t.Errorf("DialectForDriver(%q) = %q, want %q", driver, got, want)
+		}
+	}
+}
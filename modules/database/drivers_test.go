@@ -0,0 +1,101 @@
+package databasemod_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	databasemod "github.com/go-go-golems/go-go-goja/modules/database"
+	gggengine "github.com/go-go-golems/go-go-goja/pkg/engine"
+	"github.com/stretchr/testify/require"
+)
+
+// SQLite numbers $1, $2 ... parameters by first appearance, so it stands in
+// for Postgres when checking that rewritten queries bind the right values.
+func TestPostgresDialectRewritesPlaceholdersEndToEnd(t *testing.T) {
+	db := openSQLiteDB(t)
+	_, err := db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, team TEXT)`)
+	require.NoError(t, err)
+	rt := newModuleRuntime(t, databasemod.New(
+		databasemod.WithName("site-db"),
+		databasemod.WithPreconfiguredDB(db),
+		databasemod.WithDialect(databasemod.DialectPostgres),
+	))
+
+	ret := runDatabaseScript(t, rt, `
+		const db = require("site-db");
+		const insert = db.prepare("INSERT INTO users(name, team) VALUES (:name, :team)");
+		insert.run({ name: "Ada", team: "core" });
+		insert.run({ name: "Grace", team: "core" });
+		db.exec("INSERT INTO users(name, team) VALUES (?, ?)", "Linus", "kernel");
+		const tx = db.begin();
+		tx.exec("UPDATE users SET team = ? WHERE name = :name", "ops", { name: "Grace" });
+		tx.commit();
+		let unbound = "";
+		try { db.query("SELECT * FROM users WHERE name = :name"); } catch (e) { unbound = String(e); }
+		const it = db.iterate("SELECT name FROM users WHERE team = ? ORDER BY id", "core");
+		const first = await it.next();
+		await it.return();
+		return {
+			core: db.query("SELECT name FROM users WHERE team = :team OR name = :team ORDER BY id", { team: "core" }).map((r) => r.name),
+			grace: db.queryValue("SELECT team FROM users WHERE name = ?", "Grace"),
+			literal: db.queryValue("SELECT '?' || ? AS v", "x"),
+			unbound: unbound.includes(":name is not bound"),
+			first: first.value.name,
+		};
+	`)
+	require.Equal(t, map[string]any{
+		"core":    []any{"Ada"},
+		"grace":   "ops",
+		"literal": "?x",
+		"unbound": true,
+		"first":   "Ada",
+	}, ret)
+}
+
+func TestPoolOptionsApplyToPreconfiguredAndConfiguredDatabases(t *testing.T) {
+	db := openSQLiteDB(t)
+	module := databasemod.New(
+		databasemod.WithPreconfiguredDB(db),
+		databasemod.WithPool(databasemod.PoolOptions{MaxOpenConns: 3, ConnMaxLifetime: time.Minute}),
+	)
+	stats, ok := module.Stats()
+	require.True(t, ok)
+	require.Equal(t, 3, stats.MaxOpenConnections)
+
+	rt := newModuleRuntime(t, databasemod.New(databasemod.WithName("site-db")))
+	ret := runDatabaseScript(t, rt, `
+		const db = require("site-db");
+		const before = db.stats();
+		db.configure("sqlite3", ":memory:", { maxOpenConns: 1, connMaxIdleTime: "5m" });
+		db.queryValue("SELECT 1");
+		let invalid = "";
+		try { db.configure("sqlite3", ":memory:", { connMaxLifetime: "soon" }); } catch (e) { invalid = String(e); }
+		const stats = db.stats();
+		return { before, max: stats.maxOpenConnections, open: stats.openConnections, invalid: invalid.includes("connMaxLifetime") };
+	`)
+	require.Equal(t, map[string]any{"before": nil, "max": int64(1), "open": int64(1), "invalid": true}, ret)
+}
+
+func newModuleRuntime(t *testing.T, module *databasemod.DBModule) *gggengine.Runtime {
+	t.Helper()
+	factory, err := gggengine.NewRuntimeFactoryBuilder().
+		WithModules(gggengine.NativeModuleRegistrar{
+			ModuleID:   "test-" + module.Name(),
+			ModuleName: module.Name(),
+			Loader:     module.Loader,
+		}).
+		Build()
+	require.NoError(t, err)
+
+	rt, err := factory.NewRuntime(gggengine.WithStartupContext(context.Background()), gggengine.WithLifetimeContext(context.Background()))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = rt.Owner.Call(context.Background(), "database.close", func(context.Context, *goja.Runtime) (any, error) {
+			return nil, module.Close()
+		})
+		require.NoError(t, rt.Close(context.Background()))
+	})
+	return rt
+}
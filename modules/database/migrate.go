@@ -0,0 +1,158 @@
+package databasemod
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/modules"
+	"github.com/go-go-golems/go-go-goja/pkg/dbmigrate"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimebridge"
+	"github.com/go-go-golems/go-go-goja/pkg/sandbox"
+)
+
+// MigrateContext applies migrations to the configured database. Wrappers
+// keep their policy: statements go through the QueryExecer and each
+// migration runs in a transaction started the same way begin() starts one.
+func (m *DBModule) MigrateContext(ctx context.Context, migrations []dbmigrate.Migration, opts dbmigrate.Options) ([]dbmigrate.Result, error) {
+	if m == nil || m.queryExecer == nil {
+		return nil, fmt.Errorf("database not configured, call require('%s').configure(...) first", m.Name())
+	}
+	if opts.Dialect == "" {
+		opts.Dialect = string(m.dialect)
+	}
+	results, err := dbmigrate.Apply(ctx, migrationDB{m.queryExecer}, migrations, opts)
+	if err != nil {
+		log.Error().Str("module", m.Name()).Err(err).Msg("database: migration failed")
+	}
+	return results, err
+}
+
+// migrate implements migrate(dir | migrations, options?).
+func (m *DBModule) migrate(vm *goja.Runtime, source goja.Value, optionsValue goja.Value) ([]map[string]any, error) {
+	migrations, err := migrationsArg(vm, source)
+	if err != nil {
+		return nil, err
+	}
+	var opts dbmigrate.Options
+	if modules.Present(optionsValue) {
+		obj := optionsValue.ToObject(vm)
+		if v := obj.Get("dryRun"); modules.Present(v) {
+			opts.DryRun = v.ToBoolean()
+		}
+		if v := obj.Get("table"); modules.Present(v) {
+			opts.Table = v.String()
+		}
+	}
+	results, err := m.MigrateContext(runtimebridge.CurrentOwnerContext(vm), migrations, opts)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]map[string]any, len(results))
+	for i, result := range results {
+		ret[i] = map[string]any{
+			"version":   result.Version,
+			"name":      result.Name,
+			"checksum":  result.Checksum,
+			"status":    result.Status,
+			"appliedAt": result.AppliedAt,
+		}
+	}
+	return ret, nil
+}
+
+// migrationsArg reads a directory of NNNN_name.sql files or an array of
+// { version, name, sql } objects, where sql is a string or an array of
+// statements.
+func migrationsArg(vm *goja.Runtime, source goja.Value) ([]dbmigrate.Migration, error) {
+	if !modules.Present(source) {
+		panic(vm.NewTypeError("migrate: expected a directory or an array of migrations"))
+	}
+	if dir, ok := source.Export().(string); ok {
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		if err := runtimebridge.Sandbox(vm).CheckRead(dir); err != nil {
+			panic(sandbox.JSError(vm, err))
+		}
+		return dbmigrate.LoadDir(os.DirFS(dir), ".")
+	}
+	var items []map[string]any
+	if err := vm.ExportTo(source, &items); err != nil {
+		panic(vm.NewTypeError("migrate: expected a directory or an array of migrations"))
+	}
+	ret := make([]dbmigrate.Migration, len(items))
+	for i, item := range items {
+		version, ok := migrationVersion(item["version"])
+		if !ok {
+			panic(vm.NewTypeError("migrate: migration %d needs an integer version", i))
+		}
+		name, _ := item["name"].(string)
+		var statements []string
+		switch sqlValue := item["sql"].(type) {
+		case string:
+			statements = []string{sqlValue}
+		case []any:
+			for _, statement := range sqlValue {
+				s, ok := statement.(string)
+				if !ok {
+					panic(vm.NewTypeError("migrate: migration %d has a statement that is not a string", version))
+				}
+				statements = append(statements, s)
+			}
+		default:
+			panic(vm.NewTypeError("migrate: migration %d needs sql as a string or an array of strings", version))
+		}
+		ret[i] = dbmigrate.Migration{Version: version, Name: name, Statements: statements}
+	}
+	return ret, nil
+}
+
+func migrationVersion(v any) (int64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return v, true
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) <= maxSafeInteger {
+			return int64(v), true
+		}
+	}
+	return 0, false
+}
+
+// migrationDB adapts a QueryExecer to dbmigrate.Database.
+type migrationDB struct {
+	qe QueryExecer
+}
+
+func (db migrationDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return queryRows(ctx, db.qe, query, args...)
+}
+
+func (db migrationDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return execResult(ctx, db.qe, query, args...)
+}
+
+func (db migrationDB) Begin(ctx context.Context) (dbmigrate.Tx, error) {
+	tx, err := beginTransaction(ctx, db.qe)
+	if err != nil {
+		return nil, err
+	}
+	return migrationTx{tx}, nil
+}
+
+type migrationTx struct {
+	Transaction
+}
+
+func (tx migrationTx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return queryRows(ctx, tx.Transaction, query, args...)
+}
+
+func (tx migrationTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return execResult(ctx, tx.Transaction, query, args...)
+}
@@ -0,0 +1,49 @@
+package databasemod_test
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateAppliesDirectoryAndArrayMigrations(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0001_users.sql"), []byte(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0002_seed.sql"), []byte(`INSERT INTO users(name) VALUES ('Ada')`), 0o644))
+	db := openSQLiteDB(t)
+	rt := newSQLiteRuntime(t, db)
+
+	ret := runDatabaseScript(t, rt, `
+		const db = require("site-db");
+		const dir = `+strconv.Quote(dir)+`;
+		const planned = db.migrate(dir, { dryRun: true }).map((r) => r.status);
+		const first = db.migrate(dir).map((r) => r.version + ":" + r.status);
+		const again = db.migrate(dir).map((r) => r.status);
+		const inline = db.migrate([
+			{ version: 1, name: "posts", sql: "CREATE TABLE posts (id INTEGER)" },
+			{ version: 2, name: "tags", sql: ["CREATE TABLE tags (id INTEGER)", "CREATE TABLE post_tags (id INTEGER)"] },
+		], { table: "inline_migrations" }).map((r) => r.status);
+		let drift = "";
+		try {
+			db.migrate([{ version: 1, name: "posts", sql: "CREATE TABLE posts (id INTEGER, title TEXT)" }], { table: "inline_migrations" });
+		} catch (e) { drift = String(e); }
+		return {
+			planned, first, again, inline,
+			drift: drift.includes("checksum"),
+			users: db.queryValue("SELECT count(*) FROM users"),
+			tags: db.queryValue("SELECT count(*) FROM sqlite_master WHERE name = 'post_tags'"),
+		};
+	`)
+	require.Equal(t, map[string]any{
+		"planned": []any{"pending", "pending"},
+		"first":   []any{"1:migrated", "2:migrated"},
+		"again":   []any{"applied", "applied"},
+		"inline":  []any{"migrated", "migrated"},
+		"drift":   true,
+		"users":   int64(1),
+		"tags":    int64(1),
+	}, ret)
+}
@@ -0,0 +1,119 @@
+package databasemod
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/modules"
+)
+
+// PoolOptions are the database/sql connection pool settings applied to
+// databases the module opens, or to a preconfigured *sql.DB. Zero values
+// keep the database/sql defaults.
+type PoolOptions struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// WithPool sets the connection pool options.
+func WithPool(pool PoolOptions) Option {
+	return func(m *DBModule) {
+		if m == nil {
+			return
+		}
+		m.pool = pool
+	}
+}
+
+// WithDialect fixes the placeholder dialect instead of deriving it from the
+// driver. Use it for drivers registered under custom names.
+func WithDialect(dialect Dialect) Option {
+	return func(m *DBModule) {
+		if m == nil || dialect == "" {
+			return
+		}
+		m.dialect = dialect
+		m.fixedDialect = true
+	}
+}
+
+func (p PoolOptions) apply(db *sql.DB) {
+	if p.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(p.MaxOpenConns)
+	}
+	if p.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(p.MaxIdleConns)
+	}
+	if p.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(p.ConnMaxLifetime)
+	}
+	if p.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+	}
+}
+
+// Stats reports the connection pool statistics when the configured database
+// is a *sql.DB.
+func (m *DBModule) Stats() (sql.DBStats, bool) {
+	if m == nil {
+		return sql.DBStats{}, false
+	}
+	db, ok := m.queryExecer.(*sql.DB)
+	if !ok {
+		return sql.DBStats{}, false
+	}
+	return db.Stats(), true
+}
+
+func (m *DBModule) statsValue() map[string]any {
+	stats, ok := m.Stats()
+	if !ok {
+		return nil
+	}
+	return map[string]any{
+		"maxOpenConnections": stats.MaxOpenConnections,
+		"openConnections":    stats.OpenConnections,
+		"inUse":              stats.InUse,
+		"idle":               stats.Idle,
+		"waitCount":          stats.WaitCount,
+		"waitDurationMs":     stats.WaitDuration.Milliseconds(),
+		"maxIdleClosed":      stats.MaxIdleClosed,
+		"maxIdleTimeClosed":  stats.MaxIdleTimeClosed,
+		"maxLifetimeClosed":  stats.MaxLifetimeClosed,
+	}
+}
+
+// poolOptionsArg reads configure()'s options object on top of the module's
+// pool options.
+func poolOptionsArg(vm *goja.Runtime, value goja.Value, pool PoolOptions) (PoolOptions, error) {
+	if !modules.Present(value) {
+		return pool, nil
+	}
+	obj := value.ToObject(vm)
+	if v := obj.Get("maxOpenConns"); modules.Present(v) {
+		pool.MaxOpenConns = int(v.ToInteger())
+	}
+	if v := obj.Get("maxIdleConns"); modules.Present(v) {
+		pool.MaxIdleConns = int(v.ToInteger())
+	}
+	for _, field := range []struct {
+		name   string
+		target *time.Duration
+	}{
+		{"connMaxLifetime", &pool.ConnMaxLifetime},
+		{"connMaxIdleTime", &pool.ConnMaxIdleTime},
+	} {
+		if v := obj.Get(field.name); modules.Present(v) {
+			d, err := modules.DurationValue(v)
+			if err != nil {
+				return pool, fmt.Errorf("configure %s: %w", field.name, err)
+			}
+			*field.target = d
+		}
+	}
+	return pool, nil
+}
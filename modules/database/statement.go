@@ -0,0 +1,320 @@
+package databasemod
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/modules"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimebridge"
+)
+
+type sqlStatementPreparerContext interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// PreparedStatement is the JavaScript-facing handle returned by prepare().
+// When the database can prepare statements it wraps a *sql.Stmt; otherwise,
+// as for policy wrappers that only implement QueryExecer, it re-runs the SQL
+// text through the wrapper on every call.
+type PreparedStatement struct {
+	moduleName string
+	query      string
+	params     placeholders
+	qe         QueryExecer
+	stmt       *sql.Stmt
+	closed     bool
+	mu         sync.Mutex
+}
+
+// PrepareContext prepares query on the configured database.
+func (m *DBModule) PrepareContext(ctx context.Context, query string) (*PreparedStatement, error) {
+	if m == nil || m.queryExecer == nil {
+		return nil, fmt.Errorf("database not configured, call require('%s').configure(...) first", m.Name())
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	s := &PreparedStatement{moduleName: m.Name(), query: query, params: m.dialect.placeholders(query), qe: m.queryExecer}
+	if preparer, ok := m.queryExecer.(sqlStatementPreparerContext); ok {
+		stmt, err := preparer.PrepareContext(ctx, s.params.text)
+		if err != nil {
+			return nil, err
+		}
+		s.stmt = stmt
+	}
+	return s, nil
+}
+
+// QueryContext runs the statement and returns its rows.
+func (s *PreparedStatement) QueryContext(ctx context.Context, args ...any) (*sql.Rows, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, fmt.Errorf("database statement is closed")
+	}
+	bound, err := s.params.args(bindArgs(args))
+	if err != nil {
+		return nil, err
+	}
+	if s.stmt != nil {
+		return s.stmt.QueryContext(ctx, bound...)
+	}
+	return queryRows(ctx, s.qe, s.params.text, bound...)
+}
+
+// ExecContext runs the statement without returning rows.
+func (s *PreparedStatement) ExecContext(ctx context.Context, args ...any) (sql.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, fmt.Errorf("database statement is closed")
+	}
+	bound, err := s.params.args(bindArgs(args))
+	if err != nil {
+		return nil, err
+	}
+	if s.stmt != nil {
+		return s.stmt.ExecContext(ctx, bound...)
+	}
+	return execResult(ctx, s.qe, s.params.text, bound...)
+}
+
+// Close releases the prepared statement. Closing twice is a no-op.
+func (s *PreparedStatement) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if s.stmt != nil {
+		return s.stmt.Close()
+	}
+	return nil
+}
+
+// ToObject creates the JavaScript statement object exported by prepare().
+func (s *PreparedStatement) ToObject(vm *goja.Runtime) *goja.Object {
+	obj := vm.NewObject()
+	open := func(args []any) rowsFunc {
+		return func(ctx context.Context) (*sql.Rows, error) { return s.QueryContext(ctx, args...) }
+	}
+	_ = obj.Set("source", s.query)
+	modules.SetExport(obj, s.moduleName, "all", func(args ...any) (goja.Value, error) {
+		return queryJS(vm, open(args), recordsValue)
+	})
+	modules.SetExport(obj, s.moduleName, "get", func(args ...any) (goja.Value, error) {
+		return queryJS(vm, open(args), firstRecordValue)
+	})
+	modules.SetExport(obj, s.moduleName, "value", func(args ...any) (goja.Value, error) {
+		return queryJS(vm, open(args), firstColumnValue)
+	})
+	modules.SetExport(obj, s.moduleName, "run", func(args ...any) (map[string]any, error) {
+		result, err := s.ExecContext(runtimebridge.CurrentOwnerContext(vm), args...)
+		if err != nil {
+			return map[string]any{"error": err.Error(), "success": false}, err
+		}
+		return resultToMap(result), nil
+	})
+	modules.SetExport(obj, s.moduleName, "iterate", func(args ...any) *goja.Object {
+		return newRowIterator(vm, open(args))
+	})
+	modules.SetExport(obj, s.moduleName, "close", s.Close)
+	if services, ok := runtimebridge.Lookup(vm); ok {
+		context.AfterFunc(services.Lifetime(), func() { _ = s.Close() })
+	}
+	return obj
+}
+
+// rowsFunc opens a result set with the context of the JavaScript call.
+type rowsFunc func(ctx context.Context) (*sql.Rows, error)
+
+// resultShape turns the rows read by readRows into the value a JavaScript
+// call returns.
+type resultShape func(vm *goja.Runtime, columns []column, rows [][]any) goja.Value
+
+// queryJS runs open with the current owner context, reads the whole result
+// and shapes it. It runs on the owner goroutine, like query() always has.
+func queryJS(vm *goja.Runtime, open rowsFunc, shape resultShape) (goja.Value, error) {
+	rows, err := open(runtimebridge.CurrentOwnerContext(vm))
+	if err != nil {
+		return nil, err
+	}
+	columns, vals, err := readRows(rows)
+	if err != nil {
+		return nil, err
+	}
+	return shape(vm, columns, vals), nil
+}
+
+// readRows reads and closes rows.
+func readRows(rows *sql.Rows) ([]column, [][]any, error) {
+	defer func() { _ = rows.Close() }()
+	columns, err := resultColumns(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	var ret [][]any
+	for rows.Next() {
+		vals, err := scanRow(rows, len(columns))
+		if err != nil {
+			return nil, nil, err
+		}
+		ret = append(ret, vals)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	return columns, ret, rows.Close()
+}
+
+func recordsValue(vm *goja.Runtime, columns []column, rows [][]any) goja.Value {
+	decoder := newRowDecoder(vm, columns)
+	records := make([]any, len(rows))
+	for i, vals := range rows {
+		records[i] = decoder.record(vals)
+	}
+	return vm.NewArray(records...)
+}
+
+func firstRecordValue(vm *goja.Runtime, columns []column, rows [][]any) goja.Value {
+	if len(rows) == 0 {
+		return goja.Null()
+	}
+	return newRowDecoder(vm, columns).record(rows[0])
+}
+
+func firstColumnValue(vm *goja.Runtime, columns []column, rows [][]any) goja.Value {
+	if len(rows) == 0 || len(columns) == 0 {
+		return goja.Undefined()
+	}
+	return newRowDecoder(vm, columns).value(columns[0], rows[0][0])
+}
+
+// rowIterator streams a result set to JavaScript as an async iterator. Rows
+// are read on background goroutines one next() call at a time, so only the
+// current row is held in memory; each call waits for the previous one, which
+// keeps results in order when next() is called without awaiting.
+type rowIterator struct {
+	vm       *goja.Runtime
+	services runtimebridge.RuntimeServices
+	ctx      context.Context
+	open     rowsFunc
+
+	// tail is closed when the most recently queued call finishes. It is only
+	// touched on the owner goroutine.
+	tail chan struct{}
+
+	mu      sync.Mutex
+	rows    *sql.Rows
+	columns []column
+	done    bool
+}
+
+func newRowIterator(vm *goja.Runtime, open rowsFunc) *goja.Object {
+	services, ok := runtimebridge.Lookup(vm)
+	if !ok || services.Owner == nil {
+		panic(vm.NewGoError(fmt.Errorf("database iterate requires runtime services")))
+	}
+	tail := make(chan struct{})
+	close(tail)
+	it := &rowIterator{vm: vm, services: services, ctx: runtimebridge.CurrentOwnerContext(vm), open: open, tail: tail}
+	stop := context.AfterFunc(services.Lifetime(), func() { _ = it.finish() })
+
+	obj := vm.NewObject()
+	_ = obj.Set("next", func() goja.Value {
+		return it.queue("database.iterate.next", func() (func() goja.Value, error) {
+			columns, vals, err := it.next()
+			if err != nil || vals == nil {
+				stop()
+				if closeErr := it.finish(); err == nil {
+					err = closeErr
+				}
+				return func() goja.Value { return it.result(goja.Undefined(), true) }, err
+			}
+			return func() goja.Value { return it.result(newRowDecoder(vm, columns).record(vals), false) }, nil
+		})
+	})
+	_ = obj.Set("return", func(value goja.Value) goja.Value {
+		return it.queue("database.iterate.return", func() (func() goja.Value, error) {
+			stop()
+			err := it.finish()
+			return func() goja.Value { return it.result(value, true) }, err
+		})
+	})
+	modules.SetAsyncIterator(vm, obj)
+	return obj
+}
+
+// queue runs fn after every earlier call and settles the returned Promise on
+// the owner goroutine.
+func (it *rowIterator) queue(op string, fn func() (func() goja.Value, error)) goja.Value {
+	promise, resolve, reject := it.vm.NewPromise()
+	prev, done := it.tail, make(chan struct{})
+	it.tail = done
+	go func() {
+		defer close(done)
+		<-prev
+		build, err := fn()
+		_ = it.services.PostWithCustomContext(it.ctx, op, func(context.Context, *goja.Runtime) {
+			if err != nil {
+				_ = reject(it.vm.NewGoError(err))
+				return
+			}
+			_ = resolve(build())
+		})
+	}()
+	return it.vm.ToValue(promise)
+}
+
+// next opens the result set on first use and scans the following row. It
+// returns nil values at the end.
+func (it *rowIterator) next() ([]column, []any, error) {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	if it.done {
+		return nil, nil, nil
+	}
+	if it.rows == nil {
+		rows, err := it.open(it.ctx)
+		if err != nil {
+			it.done = true
+			return nil, nil, err
+		}
+		columns, err := resultColumns(rows)
+		if err != nil {
+			it.done = true
+			_ = rows.Close()
+			return nil, nil, err
+		}
+		it.rows, it.columns = rows, columns
+	}
+	if !it.rows.Next() {
+		return nil, nil, it.rows.Err()
+	}
+	vals, err := scanRow(it.rows, len(it.columns))
+	return it.columns, vals, err
+}
+
+// finish closes the result set. Later next() calls report done.
+func (it *rowIterator) finish() error {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	it.done = true
+	if it.rows == nil {
+		return nil
+	}
+	rows := it.rows
+	it.rows = nil
+	return rows.Close()
+}
+
+func (it *rowIterator) result(value goja.Value, done bool) goja.Value {
+	obj := it.vm.NewObject()
+	_ = obj.Set("value", value)
+	_ = obj.Set("done", done)
+	return obj
+}
@@ -0,0 +1,182 @@
+package databasemod_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/internal/enginetest"
+	databasemod "github.com/go-go-golems/go-go-goja/modules/database"
+	gggengine "github.com/go-go-golems/go-go-goja/pkg/engine"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryDecodesColumnTypes(t *testing.T) {
+	db := openSQLiteDB(t)
+	_, err := db.Exec(`CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT, data BLOB, created DATETIME, big INTEGER, price DECIMAL(10,2))`)
+	require.NoError(t, err)
+	rt := newSQLiteRuntime(t, db)
+
+	ret := runDatabaseScript(t, rt, `
+		const db = require("site-db");
+		db.exec("INSERT INTO items(name, data, created, big, price) VALUES (:name, :data, :created, :big, :price)", {
+			name: "Ada", data: Buffer.from([0, 1, 2]), created: "2024-05-06 07:08:09", big: 9007199254740993n, price: "12.50",
+		});
+		db.exec("INSERT INTO items(name) VALUES (?)", ["Grace"]);
+		const row = db.queryOne("SELECT * FROM items WHERE id = @id", { "@id": 1 });
+		return {
+			buffer: row.data instanceof Buffer && row.data.toString("hex"),
+			date: row.created instanceof Date && row.created.toISOString(),
+			big: typeof row.big === "bigint" && row.big === 9007199254740993n,
+			name: row.name,
+			nullData: db.queryOne("SELECT data FROM items WHERE name = ?", "Grace").data,
+			missingRow: db.queryOne("SELECT * FROM items WHERE id = ?", 99),
+			count: db.queryValue("SELECT count(*) FROM items"),
+			missingValue: db.queryValue("SELECT id FROM items WHERE id = ?", 99) === undefined,
+			names: db.query("SELECT name FROM items ORDER BY id").map((r) => r.name),
+		};
+	`)
+	require.Equal(t, map[string]any{
+		"buffer":       "000102",
+		"date":         "2024-05-06T07:08:09.000Z",
+		"big":          true,
+		"name":         "Ada",
+		"nullData":     nil,
+		"missingRow":   nil,
+		"count":        int64(2),
+		"missingValue": true,
+		"names":        []any{"Ada", "Grace"},
+	}, ret)
+}
+
+func TestPreparedStatementRunsRepeatedly(t *testing.T) {
+	db := openSQLiteDB(t)
+	_, err := db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)`)
+	require.NoError(t, err)
+	rt := newSQLiteRuntime(t, db)
+
+	ret := runDatabaseScript(t, rt, `
+		const db = require("site-db");
+		const insert = db.prepare("INSERT INTO users(name) VALUES (?)");
+		const ids = ["Ada", "Grace", "Linus"].map((name) => insert.run(name).lastInsertId);
+		insert.close();
+		insert.close();
+		let closed = "";
+		try { insert.run("Ken"); } catch (e) { closed = String(e); }
+
+		const byName = db.prepare("SELECT id, name FROM users WHERE name = :name");
+		const select = db.prepare("SELECT name FROM users ORDER BY id");
+		const tx = db.begin();
+		tx.exec("INSERT INTO users(name) VALUES (?)", "Barbara");
+		const inTx = tx.queryValue("SELECT count(*) FROM users");
+		tx.rollback();
+		return {
+			ids,
+			source: byName.source,
+			grace: byName.get({ name: "Grace" }).id,
+			none: byName.get({ name: "Nobody" }),
+			value: byName.value({ name: "Linus" }),
+			all: select.all().map((r) => r.name),
+			closed: closed.includes("statement is closed"),
+			inTx,
+		};
+	`)
+	require.Equal(t, map[string]any{
+		"ids":    []any{int64(1), int64(2), int64(3)},
+		"source": "SELECT id, name FROM users WHERE name = :name",
+		"grace":  int64(2),
+		"none":   nil,
+		"value":  int64(3),
+		"all":    []any{"Ada", "Grace", "Linus"},
+		"closed": true,
+		"inTx":   int64(4),
+	}, ret)
+}
+
+func TestIterateStreamsRowsInOrder(t *testing.T) {
+	db := openSQLiteDB(t)
+	_, err := db.Exec(`CREATE TABLE events (id INTEGER PRIMARY KEY, kind TEXT)`)
+	require.NoError(t, err)
+	for i := 1; i <= 5; i++ {
+		_, err = db.Exec(`INSERT INTO events(kind) VALUES (?)`, fmt.Sprintf("kind-%d", i))
+		require.NoError(t, err)
+	}
+	rt := newSQLiteRuntime(t, db)
+
+	ret := runDatabaseScript(t, rt, `
+		const db = require("site-db");
+		const seen = [];
+		const it = db.iterate("SELECT id, kind FROM events WHERE id > ? ORDER BY id", 1);
+		for (let r = await it.next(); !r.done; r = await it.next()) {
+			seen.push(r.value.id);
+		}
+		const after = await it.next();
+
+		// Calls that are not awaited still settle in order.
+		const eager = db.prepare("SELECT kind FROM events ORDER BY id").iterate();
+		const [a, b] = await Promise.all([eager.next(), eager.next()]);
+		const stopped = await eager.return("stop");
+		const afterReturn = await eager.next();
+
+		let failed = "";
+		try { await db.iterate("SELECT * FROM missing").next(); } catch (e) { failed = String(e); }
+		return {
+			seen,
+			afterDone: after.done,
+			eager: [a.value.kind, b.value.kind],
+			stopped: [stopped.value, stopped.done],
+			afterReturn: afterReturn.done,
+			failed: failed.includes("no such table"),
+		};
+	`)
+	require.Equal(t, map[string]any{
+		"seen":        []any{int64(2), int64(3), int64(4), int64(5)},
+		"afterDone":   true,
+		"eager":       []any{"kind-1", "kind-2"},
+		"stopped":     []any{"stop", true},
+		"afterReturn": true,
+		"failed":      true,
+	}, ret)
+}
+
+func TestIterateSupportsForAwaitInESModules(t *testing.T) {
+	db := openSQLiteDB(t)
+	_, err := db.Exec(`CREATE TABLE events (id INTEGER PRIMARY KEY, kind TEXT); INSERT INTO events(kind) VALUES ('a'), ('b')`)
+	require.NoError(t, err)
+	rt := newSQLiteRuntime(t, db)
+	entry := filepath.Join(t.TempDir(), "main.mjs")
+	require.NoError(t, os.WriteFile(entry, []byte(`
+import db from "site-db";
+const kinds = [];
+for await (const row of db.iterate("SELECT kind FROM events ORDER BY id")) {
+  kinds.push(row.kind);
+}
+export default kinds;
+`), 0o600))
+
+	ns, err := rt.ImportModule(context.Background(), entry)
+	require.NoError(t, err)
+	got, err := rt.Owner.Call(context.Background(), "database.esm", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		return ns.ToObject(vm).Get("default").Export(), nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []any{"a", "b"}, got)
+}
+
+func newSQLiteRuntime(t *testing.T, db databasemod.QueryExecer) *gggengine.Runtime {
+	t.Helper()
+	return newModuleRuntime(t, databasemod.New(
+		databasemod.WithName("site-db"),
+		databasemod.WithPreconfiguredDB(db),
+	))
+}
+
+// runDatabaseScript runs body inside an async function and returns the
+// exported result once the promise settles.
+func runDatabaseScript(t *testing.T, rt *gggengine.Runtime, body string) any {
+	t.Helper()
+	return enginetest.RunAsync(t, rt, body)
+}
@@ -0,0 +1,295 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimebridge"
+)
+
+// AbortSignal is the Go side of a JavaScript AbortSignal. Native modules that
+// start work on behalf of JavaScript register OnAbort hooks to cancel it, and
+// Go code can abort a signal it handed to JavaScript.
+//
+// It is not goroutine-safe. All methods must be called on the owning goja
+// runtime goroutine.
+type AbortSignal struct {
+	vm      *goja.Runtime
+	object  *goja.Object
+	target  *EventTarget
+	aborted bool
+	reason  goja.Value
+	hooks   []*abortHook
+}
+
+type abortHook struct {
+	fn func(reason goja.Value)
+}
+
+// classes holds the per-runtime AbortController, AbortSignal, EventTarget and
+// Event constructors.
+type classes struct {
+	controller *goja.Object
+	signal     *goja.Object
+	target     *goja.Object
+	event      *goja.Object
+}
+
+var (
+	classesKey = goja.NewSymbol("go-go-goja.events.classes")
+	signalKey  = goja.NewSymbol("go-go-goja.events.signal")
+)
+
+// EnableAbortController installs the AbortController, AbortSignal,
+// EventTarget and Event globals. The engine calls it for every runtime.
+func EnableAbortController(vm *goja.Runtime) {
+	c := classesFor(vm)
+	mustSet(vm, vm.GlobalObject(), "AbortController", c.controller)
+	mustSet(vm, vm.GlobalObject(), "AbortSignal", c.signal)
+	mustSet(vm, vm.GlobalObject(), "EventTarget", c.target)
+	mustSet(vm, vm.GlobalObject(), "Event", c.event)
+}
+
+// NewAbortSignal creates a signal that is aborted only through Abort. It must
+// be called on the owning runtime goroutine.
+func NewAbortSignal(vm *goja.Runtime) *AbortSignal {
+	c := classesFor(vm)
+	s := &AbortSignal{vm: vm, object: vm.NewObject()}
+	if err := s.object.SetPrototype(c.signal.Get("prototype").(*goja.Object)); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("events: set signal prototype: %w", err)))
+	}
+	if err := s.object.DefineDataPropertySymbol(signalKey, vm.ToValue(s), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("events: attach signal: %w", err)))
+	}
+	s.target = attachEventTarget(vm, s.object)
+	mustSet(vm, s.object, "onabort", goja.Null())
+	return s
+}
+
+// SignalOf unwraps a JavaScript AbortSignal.
+func SignalOf(value goja.Value) (*AbortSignal, bool) {
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return nil, false
+	}
+	inner, ok := obj.GetSymbol(signalKey).(*goja.Object)
+	if !ok {
+		return nil, false
+	}
+	s, ok := inner.Export().(*AbortSignal)
+	return s, ok && s != nil
+}
+
+// Object returns the JavaScript AbortSignal object.
+func (s *AbortSignal) Object() *goja.Object { return s.object }
+
+// Aborted reports whether the signal has been aborted.
+func (s *AbortSignal) Aborted() bool { return s.aborted }
+
+// Reason returns the abort reason, or undefined while the signal is not
+// aborted.
+func (s *AbortSignal) Reason() goja.Value {
+	if s.reason == nil {
+		return goja.Undefined()
+	}
+	return s.reason
+}
+
+// OnAbort registers fn to run when the signal is aborted, before the "abort"
+// event is dispatched to JavaScript listeners. It returns a function that
+// unregisters fn. If the signal is already aborted, fn runs immediately.
+func (s *AbortSignal) OnAbort(fn func(reason goja.Value)) (remove func()) {
+	if s.aborted {
+		fn(s.reason)
+		return func() {}
+	}
+	hook := &abortHook{fn: fn}
+	s.hooks = append(s.hooks, hook)
+	return func() {
+		for i, h := range s.hooks {
+			if h == hook {
+				s.hooks = append(s.hooks[:i], s.hooks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Abort aborts the signal with reason, or with an AbortError when reason is
+// nil or undefined. Aborting twice has no effect.
+func (s *AbortSignal) Abort(reason goja.Value) {
+	if s.aborted {
+		return
+	}
+	if reason == nil || goja.IsUndefined(reason) {
+		reason = domException(s.vm, "AbortError", "This operation was aborted", 20)
+	}
+	s.aborted = true
+	s.reason = reason
+	hooks := s.hooks
+	s.hooks = nil
+	for _, hook := range hooks {
+		hook.fn(reason)
+	}
+
+	event := NewEvent(s.vm, "abort")
+	if onabort, ok := goja.AssertFunction(s.object.Get("onabort")); ok {
+		_ = event.Set("target", s.object)
+		_ = event.Set("currentTarget", s.object)
+		if _, err := onabort(s.object, event); err != nil {
+			log.Warn().Err(err).Msg("events: abort listener error")
+		}
+	}
+	s.target.Dispatch(event)
+	// A signal aborts once, so its listeners are never needed again.
+	for _, l := range s.target.listeners["abort"] {
+		if l.detach != nil {
+			l.detach()
+		}
+	}
+	delete(s.target.listeners, "abort")
+}
+
+func classesFor(vm *goja.Runtime) *classes {
+	global := vm.GlobalObject()
+	if existing, ok := global.GetSymbol(classesKey).(*goja.Object); ok {
+		if c, ok := existing.Export().(*classes); ok {
+			return c
+		}
+	}
+	c := &classes{}
+	installEventTarget(vm, c)
+
+	c.signal = vm.ToValue(func(goja.ConstructorCall) *goja.Object {
+		panic(vm.NewTypeError("Illegal constructor"))
+	}).(*goja.Object)
+	signalProto := vm.NewObject()
+	if err := signalProto.SetPrototype(c.target.Get("prototype").(*goja.Object)); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("events: set AbortSignal prototype: %w", err)))
+	}
+	defineGetter(vm, signalProto, "aborted", func(s *AbortSignal) goja.Value { return vm.ToValue(s.aborted) })
+	defineGetter(vm, signalProto, "reason", func(s *AbortSignal) goja.Value { return s.Reason() })
+	mustSet(vm, signalProto, "throwIfAborted", func(call goja.FunctionCall) goja.Value {
+		if s := mustSignal(vm, call.This); s.aborted {
+			panic(s.reason)
+		}
+		return goja.Undefined()
+	})
+	mustSet(vm, c.signal, "prototype", signalProto)
+	if err := signalProto.DefineDataProperty("constructor", c.signal, goja.FLAG_TRUE, goja.FLAG_FALSE, goja.FLAG_TRUE); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("events: define AbortSignal constructor: %w", err)))
+	}
+	mustSet(vm, c.signal, "abort", func(call goja.FunctionCall) goja.Value {
+		s := NewAbortSignal(vm)
+		s.Abort(call.Argument(0))
+		return s.object
+	})
+	mustSet(vm, c.signal, "timeout", func(call goja.FunctionCall) goja.Value {
+		return timeoutSignal(vm, call.Argument(0).ToInteger()).object
+	})
+	mustSet(vm, c.signal, "any", func(call goja.FunctionCall) goja.Value {
+		return anySignal(vm, call.Argument(0)).object
+	})
+
+	c.controller = vm.ToValue(func(call goja.ConstructorCall) *goja.Object {
+		s := NewAbortSignal(vm)
+		if err := call.This.DefineDataProperty("signal", s.object, goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE); err != nil {
+			panic(vm.NewGoError(fmt.Errorf("events: define signal: %w", err)))
+		}
+		return nil
+	}).(*goja.Object)
+	controllerProto := c.controller.Get("prototype").(*goja.Object)
+	mustSet(vm, controllerProto, "abort", func(call goja.FunctionCall) goja.Value {
+		s, ok := SignalOf(call.This.ToObject(vm).Get("signal"))
+		if !ok {
+			panic(vm.NewTypeError("Value of this must be an AbortController"))
+		}
+		s.Abort(call.Argument(0))
+		return goja.Undefined()
+	})
+
+	if err := global.DefineDataPropertySymbol(classesKey, vm.ToValue(c), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("events: cache classes: %w", err)))
+	}
+	return c
+}
+
+// timeoutSignal returns a signal that aborts with a TimeoutError after ms
+// milliseconds.
+func timeoutSignal(vm *goja.Runtime, ms int64) *AbortSignal {
+	s := NewAbortSignal(vm)
+	services, ok := runtimebridge.Lookup(vm)
+	if !ok || services.Owner == nil {
+		panic(vm.NewGoError(fmt.Errorf("AbortSignal.timeout requires runtime services")))
+	}
+	time.AfterFunc(time.Duration(max(ms, 0))*time.Millisecond, func() {
+		_ = services.PostWithLifetimeContext("events.abortTimeout", func(_ context.Context, vm *goja.Runtime) {
+			s.Abort(domException(vm, "TimeoutError", "The operation was aborted due to timeout", 23))
+		})
+	})
+	return s
+}
+
+// anySignal returns a signal that aborts with the reason of the first of
+// signals to abort.
+func anySignal(vm *goja.Runtime, signals goja.Value) *AbortSignal {
+	combined := NewAbortSignal(vm)
+	var sources []*AbortSignal
+	if obj, ok := signals.(*goja.Object); ok {
+		for _, key := range obj.Keys() {
+			source, ok := SignalOf(obj.Get(key))
+			if !ok {
+				panic(vm.NewTypeError("AbortSignal.any requires an array of AbortSignals"))
+			}
+			sources = append(sources, source)
+		}
+	}
+	for _, source := range sources {
+		if source.aborted {
+			combined.Abort(source.reason)
+			return combined
+		}
+	}
+	for _, source := range sources {
+		source.OnAbort(combined.Abort)
+	}
+	return combined
+}
+
+func mustSignal(vm *goja.Runtime, value goja.Value) *AbortSignal {
+	s, ok := SignalOf(value)
+	if !ok {
+		panic(vm.NewTypeError("Value of this must be an AbortSignal"))
+	}
+	return s
+}
+
+func defineGetter(vm *goja.Runtime, proto *goja.Object, name string, get func(*AbortSignal) goja.Value) {
+	getter := vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		return get(mustSignal(vm, call.This))
+	})
+	if err := proto.DefineAccessorProperty(name, getter, nil, goja.FLAG_FALSE, goja.FLAG_TRUE); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("events: define %s: %w", name, err)))
+	}
+}
+
+// AbortError returns an abort reason shaped like the DOMException Node uses:
+// an Error whose name is "AbortError", with message.
+func AbortError(vm *goja.Runtime, message string) goja.Value {
+	return domException(vm, "AbortError", message, 20)
+}
+
+// domException builds an Error shaped like the DOMException Node uses for
+// abort reasons: err.name is "AbortError" or "TimeoutError".
+func domException(vm *goja.Runtime, name, message string, code int) goja.Value {
+	errorCtor, _ := vm.Get("Error").(*goja.Object)
+	err, newErr := vm.New(errorCtor, vm.ToValue(message))
+	if newErr != nil {
+		return vm.NewGoError(fmt.Errorf("%s", message))
+	}
+	_ = err.Set("name", name)
+	_ = err.Set("code", code)
+	return err
+}
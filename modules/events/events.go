@@ -26,6 +26,10 @@ type EventEmitter struct {
 	vm        *goja.Runtime
 	object    *goja.Object
 	listeners map[eventName][]listenerEntry
+	// captureRejections routes rejected Promises returned by listeners to
+	// the "error" event.
+	captureRejections bool
+	iterators         []*eventIterator
 }
 
 type listenerEntry struct {
@@ -86,6 +90,21 @@ Exports:
 Supported methods:
   on/addListener, once, off/removeListener, removeAllListeners, emit,
   listeners, rawListeners, listenerCount, eventNames.
+
+Helpers:
+  once(emitter, name, { signal }): Promise for the arguments of the next
+    event; an "error" event or an aborted signal rejects it.
+  on(emitter, name, { signal, close }): async iterator over the arguments of
+    each event. "error" rejects next(); close names events that end it.
+  Both accept EventEmitters and EventTargets.
+
+new EventEmitter({ captureRejections: true }), or setting
+EventEmitter.captureRejections before creating emitters, emits "error" when a
+listener returns a rejected Promise. A [captureRejectionSymbol] method on the
+emitter handles those rejections instead.
+
+EventTarget, Event, AbortController and AbortSignal are globals.
+addEventListener accepts { once, signal }.
 `
 }
 
@@ -95,8 +114,19 @@ func (m *module) TypeScriptModule() *spec.Module {
 		RawDTS: []string{
 			"type EventName = string | symbol;",
 			"type Listener = (...args: any[]) => void;",
+			"interface EventEmitterOptions { captureRejections?: boolean; }",
+			"interface Abortable { signal?: AbortSignal; }",
+			"interface OnOptions extends Abortable { close?: EventName[]; }",
+			"interface EventIterator<T> {",
+			"  next(): Promise<IteratorResult<T>>;",
+			"  return(value?: any): Promise<IteratorResult<T>>;",
+			"}",
 			"class EventEmitter {",
-			"  constructor();",
+			"  constructor(options?: EventEmitterOptions);",
+			"  static captureRejections: boolean;",
+			"  static readonly captureRejectionSymbol: unique symbol;",
+			"  static once(emitter: EventEmitter | EventTarget, name: EventName, options?: Abortable): Promise<any[]>;",
+			"  static on(emitter: EventEmitter | EventTarget, name: EventName, options?: OnOptions): EventIterator<any[]>;",
 			"  on(name: EventName, listener: Listener): this;",
 			"  addListener(name: EventName, listener: Listener): this;",
 			"  once(name: EventName, listener: Listener): this;",
@@ -116,8 +146,28 @@ func (m *module) TypeScriptModule() *spec.Module {
 }
 
 func (m *module) Loader(vm *goja.Runtime, moduleObj *goja.Object) {
-	constructor := vm.ToValue(func(call goja.ConstructorCall) *goja.Object {
+	if err := moduleObj.Set("exports", constructorFor(vm)); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("events: set exports: %w", err)))
+	}
+}
+
+// constructorKey caches the EventEmitter constructor on the runtime global
+// object so "events", "node:events", and Go callers of NewObject share one
+// prototype per runtime.
+var constructorKey = goja.NewSymbol("go-go-goja.events.EventEmitter")
+
+func constructorFor(vm *goja.Runtime) *goja.Object {
+	global := vm.GlobalObject()
+	if existing, ok := global.GetSymbol(constructorKey).(*goja.Object); ok {
+		return existing
+	}
+	var constructor *goja.Object
+	constructor = vm.ToValue(func(call goja.ConstructorCall) *goja.Object {
 		emitter := New(vm)
+		emitter.captureRejections = constructor.Get("captureRejections").ToBoolean()
+		if opts, ok := call.Argument(0).(*goja.Object); ok && modules.Present(opts.Get("captureRejections")) {
+			emitter.captureRejections = opts.Get("captureRejections").ToBoolean()
+		}
 		obj := vm.ToValue(emitter).(*goja.Object)
 		if err := obj.SetPrototype(call.This.Prototype()); err != nil {
 			panic(vm.NewGoError(fmt.Errorf("events: set emitter prototype: %w", err)))
@@ -190,10 +240,24 @@ func (m *module) Loader(vm *goja.Runtime, moduleObj *goja.Object) {
 	}
 	mustSet(vm, constructor, "EventEmitter", constructor)
 	mustSet(vm, constructor, "default", constructor)
+	mustSet(vm, constructor, "captureRejections", false)
+	if err := constructor.DefineDataProperty("captureRejectionSymbol", captureRejectionSymbol(vm), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("events: define captureRejectionSymbol: %w", err)))
+	}
+	mustSet(vm, constructor, "once", func(call goja.FunctionCall) goja.Value { return once(vm, call) })
+	mustSet(vm, constructor, "on", func(call goja.FunctionCall) goja.Value { return on(vm, call) })
 
-	if err := moduleObj.Set("exports", constructor); err != nil {
-		panic(vm.NewGoError(fmt.Errorf("events: set exports: %w", err)))
+	if err := global.DefineDataPropertySymbol(constructorKey, constructor, goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("events: cache constructor: %w", err)))
 	}
+	return constructor
+}
+
+// Constructor returns the EventEmitter constructor shared by require("events")
+// and NewObject. Native modules use its prototype as the base of their own
+// emitter classes.
+func Constructor(vm *goja.Runtime) *goja.Object {
+	return constructorFor(vm)
 }
 
 // New creates a Go-native EventEmitter backing value for vm. The caller is
@@ -205,14 +269,51 @@ func New(vm *goja.Runtime) *EventEmitter {
 	}
 }
 
+// NewObject creates an EventEmitter together with a plain JavaScript object
+// that inherits from the same prototype as instances created with
+// require("events"). Unlike the wrapped Go value, the returned object accepts
+// extra properties, so Go-owned emitters such as sockets can add their own
+// methods. It must be called on the owning runtime goroutine.
+func NewObject(vm *goja.Runtime) (*EventEmitter, *goja.Object) {
+	emitter := New(vm)
+	constructor := constructorFor(vm)
+	emitter.captureRejections = constructor.Get("captureRejections").ToBoolean()
+	obj := vm.NewObject()
+	if proto, ok := constructor.Get("prototype").(*goja.Object); ok {
+		if err := obj.SetPrototype(proto); err != nil {
+			panic(vm.NewGoError(fmt.Errorf("events: set emitter prototype: %w", err)))
+		}
+	}
+	if err := obj.DefineDataPropertySymbol(emitterKey, vm.ToValue(emitter), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("events: attach emitter: %w", err)))
+	}
+	emitter.object = obj
+	return emitter, obj
+}
+
+// emitterKey links objects created by NewObject to their Go emitter.
+var emitterKey = goja.NewSymbol("go-go-goja.events.emitter")
+
 // FromValue unwraps a JavaScript value created by the Go-native EventEmitter
-// constructor.
+// constructor or by NewObject.
 func FromValue(value goja.Value) (*EventEmitter, *goja.Object, bool) {
 	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
 		return nil, nil, false
 	}
 	if value.ExportType() != eventEmitterType {
-		return nil, nil, false
+		obj, ok := value.(*goja.Object)
+		if !ok {
+			return nil, nil, false
+		}
+		inner, ok := obj.GetSymbol(emitterKey).(*goja.Object)
+		if !ok || inner.ExportType() != eventEmitterType {
+			return nil, nil, false
+		}
+		emitter, ok := inner.Export().(*EventEmitter)
+		if !ok || emitter == nil || emitter.vm == nil {
+			return nil, nil, false
+		}
+		return emitter, emitter.object, true
 	}
 	emitter, ok := value.Export().(*EventEmitter)
 	if !ok || emitter == nil || emitter.vm == nil {
@@ -244,6 +345,14 @@ func (e *EventEmitter) AddGoListener(name string, fn func(goja.FunctionCall) goj
 	return e.AddListenerValue(name, e.vm.ToValue(fn))
 }
 
+// SetCaptureRejections controls whether rejected Promises returned by
+// listeners are emitted as "error" events.
+func (e *EventEmitter) SetCaptureRejections(capture bool) {
+	if e != nil {
+		e.captureRejections = capture
+	}
+}
+
 // Emit invokes all listeners for name synchronously on the owner goroutine.
 func (e *EventEmitter) Emit(name string, args ...goja.Value) (bool, error) {
 	if e == nil {
@@ -271,13 +380,72 @@ func (e *EventEmitter) emit(name eventName, args []goja.Value) (bool, error) {
 		if entry.once {
 			e.removeListenerEntry(name, entry.value)
 		}
-		if _, err := entry.callable(e.thisObject(), args...); err != nil {
+		ret, err := entry.callable(e.thisObject(), args...)
+		if err != nil {
 			return true, err
 		}
+		if e.captureRejections {
+			e.captureRejection(name, ret, args)
+		}
 	}
 	return true, nil
 }
 
+// captureRejection routes a rejection of the Promise a listener returned to
+// the emitter's [captureRejectionSymbol] method, or else to "error".
+func (e *EventEmitter) captureRejection(name eventName, ret goja.Value, args []goja.Value) {
+	thenable, ok := ret.(*goja.Object)
+	if !ok {
+		return
+	}
+	then, ok := goja.AssertFunction(thenable.Get("then"))
+	if !ok {
+		return
+	}
+	// args may alias the goja call stack, which is reused once emit returns.
+	args = append([]goja.Value(nil), args...)
+	onRejected := e.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		reason := call.Argument(0)
+		this := e.thisObject().ToObject(e.vm)
+		if handler, ok := goja.AssertFunction(this.GetSymbol(captureRejectionSymbol(e.vm))); ok {
+			if _, err := handler(this, append([]goja.Value{reason, name.value(e.vm)}, args...)...); err != nil {
+				log.Warn().Err(err).Msg("events: captureRejectionSymbol handler error")
+			}
+			return goja.Undefined()
+		}
+		// A rejected "error" listener would otherwise loop.
+		if name.isString("error") {
+			log.Warn().Str("reason", reason.String()).Msg("events: rejected error listener")
+			return goja.Undefined()
+		}
+		if _, err := e.emit(eventNameFromString("error"), []goja.Value{reason}); err != nil {
+			log.Warn().Err(err).Msg("events: captured rejection")
+		}
+		return goja.Undefined()
+	})
+	if _, err := then(thenable, goja.Undefined(), onRejected); err != nil {
+		log.Warn().Err(err).Msg("events: capture rejection")
+	}
+}
+
+// captureRejectionSymbol is Node's Symbol.for("nodejs.rejection").
+func captureRejectionSymbol(vm *goja.Runtime) *goja.Symbol {
+	return symbolFor(vm, "nodejs.rejection")
+}
+
+// symbolFor returns the registered symbol Symbol.for(key).
+func symbolFor(vm *goja.Runtime, key string) *goja.Symbol {
+	symbolFor, ok := goja.AssertFunction(vm.Get("Symbol").ToObject(vm).Get("for"))
+	if !ok {
+		panic(vm.NewGoError(fmt.Errorf("events: Symbol.for is not available")))
+	}
+	sym, err := symbolFor(goja.Undefined(), vm.ToValue(key))
+	if err != nil {
+		panic(vm.NewGoError(fmt.Errorf("events: Symbol.for(%q): %w", key, err)))
+	}
+	return sym.(*goja.Symbol)
+}
+
 func (e *EventEmitter) unhandledError(args []goja.Value) error {
 	if len(args) == 0 || goja.IsUndefined(args[0]) || goja.IsNull(args[0]) {
 		return fmt.Errorf("unhandled error event")
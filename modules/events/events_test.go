@@ -3,9 +3,13 @@ package events_test
 import (
 	"context"
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/internal/enginetest"
 	eventsmodule "github.com/go-go-golems/go-go-goja/modules/events"
 	gggengine "github.com/go-go-golems/go-go-goja/pkg/engine"
 	"github.com/stretchr/testify/require"
@@ -220,3 +224,269 @@ func runJSValue(t *testing.T, rt *gggengine.Runtime, code string) (any, error) {
 		return value.Export(), nil
 	})
 }
+
+func TestNewObjectSharesRequirePrototype(t *testing.T) {
+	rt := newRuntime(t)
+
+	ret, err := rt.Owner.Call(context.Background(), "events.test.new-object", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		emitter, obj := eventsmodule.NewObject(vm)
+		if err := vm.Set("goEmitter", obj); err != nil {
+			return nil, err
+		}
+		value, err := vm.RunString(`
+			globalThis.seen = [];
+			goEmitter.on("ping", v => seen.push(v));
+			JSON.stringify({
+				instance: goEmitter instanceof require("events"),
+				nodeInstance: goEmitter instanceof require("node:events").EventEmitter
+			});
+		`)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := emitter.Emit("ping", vm.ToValue("pong")); err != nil {
+			return nil, err
+		}
+		seen, err := vm.RunString(`seen.join(",")`)
+		if err != nil {
+			return nil, err
+		}
+		return value.String() + "|" + seen.String(), nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, `{"instance":true,"nodeInstance":true}|pong`, ret)
+}
+
+func TestAbortControllerGlobals(t *testing.T) {
+	rt := newRuntime(t)
+
+	got := runJS(t, rt, `
+		const controller = new AbortController();
+		const { signal } = controller;
+		const seen = [];
+		signal.onabort = e => seen.push("onabort:" + e.type);
+		const listener = () => seen.push("listener");
+		signal.addEventListener("abort", listener);
+		signal.addEventListener("abort", listener);
+		signal.addEventListener("abort", { handleEvent: e => seen.push("handleEvent:" + (e.target === signal)) });
+		const before = signal.aborted;
+		controller.abort();
+		controller.abort("again");
+		let thrown = "";
+		try { signal.throwIfAborted(); } catch (e) { thrown = e.name; }
+		const custom = AbortSignal.abort("done");
+		const combined = AbortSignal.any([new AbortController().signal, custom]);
+		let illegal = "";
+		try { new AbortSignal(); } catch (e) { illegal = e.name; }
+		JSON.stringify({
+			before, after: signal.aborted, seen, thrown,
+			reason: signal.reason.name, message: signal.reason.message,
+			custom: custom.reason, combined: combined.reason, illegal,
+			instance: signal instanceof AbortSignal,
+		});
+	`)
+	require.JSONEq(t, `{"before":false,"after":true,"seen":["onabort:abort","listener","handleEvent:true"],"thrown":"AbortError","reason":"AbortError","message":"This operation was aborted","custom":"done","combined":"done","illegal":"TypeError","instance":true}`, got)
+}
+
+func TestGoCanObserveAndAbortSignals(t *testing.T) {
+	rt := newRuntime(t)
+
+	ret, err := rt.Owner.Call(context.Background(), "events.test.abort", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		signal := eventsmodule.NewAbortSignal(vm)
+		var reasons []string
+		remove := signal.OnAbort(func(reason goja.Value) { reasons = append(reasons, "removed") })
+		remove()
+		signal.OnAbort(func(reason goja.Value) { reasons = append(reasons, reason.String()) })
+		if err := vm.Set("goSignal", signal.Object()); err != nil {
+			return nil, err
+		}
+		if _, err := vm.RunString(`goSignal.addEventListener("abort", () => { globalThis.jsSaw = goSignal.reason; });`); err != nil {
+			return nil, err
+		}
+		signal.Abort(vm.ToValue("shutdown"))
+		unwrapped, ok := eventsmodule.SignalOf(vm.Get("goSignal"))
+		return map[string]any{"reasons": reasons, "js": vm.Get("jsSaw").String(), "unwrapped": ok && unwrapped == signal}, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"reasons": []string{"shutdown"}, "js": "shutdown", "unwrapped": true}, ret)
+}
+
+func TestAbortSignalTimeout(t *testing.T) {
+	rt := newRuntime(t)
+
+	_, err := runJSValue(t, rt, `
+		globalThis.timeoutSignal = AbortSignal.timeout(10);
+		timeoutSignal.onabort = () => { globalThis.timedOut = timeoutSignal.reason.name; };
+	`)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		value, err := runJSValue(t, rt, `String(globalThis.timedOut)`)
+		return err == nil && value == "TimeoutError"
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestEventsOnceResolvesRejectsAndAborts(t *testing.T) {
+	rt := newRuntime(t)
+
+	got := runAsyncJS(t, rt, `
+		const EventEmitter = require("events");
+		const ee = new EventEmitter();
+		const first = EventEmitter.once(ee, "ready");
+		ee.emit("ready", 1, "two");
+		const args = await first;
+
+		const failing = EventEmitter.once(ee, "ready");
+		ee.emit("error", new Error("broken"));
+		const error = await failing.then(() => "resolved", (e) => e.message);
+
+		const controller = new AbortController();
+		const aborted = EventEmitter.once(ee, "ready", { signal: controller.signal });
+		controller.abort("stop");
+		const abort = await aborted.then(() => "resolved", (e) => e.name + ":" + e.cause);
+
+		const target = new EventTarget();
+		const fromTarget = EventEmitter.once(target, "ping");
+		target.dispatchEvent(new Event("ping"));
+		const event = (await fromTarget)[0];
+
+		return { args, error, abort, listeners: ee.eventNames(), eventType: event.type, sameTarget: event.target === target };
+	`)
+	require.JSONEq(t, `{"args":[1,"two"],"error":"broken","abort":"AbortError:stop","listeners":[],"eventType":"ping","sameTarget":true}`, got)
+}
+
+func TestEventsOnIteratesBufferedEventsUntilClose(t *testing.T) {
+	rt := newRuntime(t)
+
+	got := runAsyncJS(t, rt, `
+		const { EventEmitter, on } = require("events");
+		const ee = new EventEmitter();
+		const it = on(ee, "data", { close: ["end"] });
+		ee.emit("data", "a");
+		ee.emit("data", "b", 2);
+		const pending = it.next();
+		ee.emit("data", "c");
+		ee.emit("end");
+		ee.emit("data", "ignored");
+		const seen = [];
+		seen.push((await pending).value, (await it.next()).value, (await it.next()).value);
+		const done = (await it.next()).done;
+
+		const failing = on(ee, "data");
+		ee.emit("data", "kept");
+		ee.emit("error", new Error("boom"));
+		const kept = (await failing.next()).value;
+		const error = await failing.next().then(() => "resolved", (e) => e.message);
+		const afterError = (await failing.next()).done;
+
+		const returned = on(ee, "data");
+		await returned.return();
+		return { seen, done, kept, error, afterError, returnedDone: (await returned.next()).done, listeners: ee.eventNames() };
+	`)
+	require.JSONEq(t, `{"seen":[["a"],["b",2],["c"]],"done":true,"kept":["kept"],"error":"boom","afterError":true,"returnedDone":true,"listeners":[]}`, got)
+}
+
+func TestEventsOnSupportsForAwaitInESModules(t *testing.T) {
+	rt := newRuntime(t)
+	entry := filepath.Join(t.TempDir(), "main.mjs")
+	require.NoError(t, os.WriteFile(entry, []byte(`
+import { EventEmitter, on } from "events";
+const ee = new EventEmitter();
+Promise.resolve().then(() => { ee.emit("n", 1); ee.emit("n", 2); ee.emit("end"); });
+const seen = [];
+for await (const [n] of on(ee, "n", { close: ["end"] })) {
+  seen.push(n);
+}
+export default seen;
+`), 0o600))
+
+	ns, err := rt.ImportModule(context.Background(), entry)
+	require.NoError(t, err)
+	got, err := rt.Owner.Call(context.Background(), "events.test.esm", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		return ns.ToObject(vm).Get("default").Export(), nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []any{int64(1), int64(2)}, got)
+}
+
+func TestEventsOnStopsWhenSignalAborts(t *testing.T) {
+	rt := newRuntime(t)
+
+	got := runAsyncJS(t, rt, `
+		const { EventEmitter, on } = require("events");
+		const ee = new EventEmitter();
+		const controller = new AbortController();
+		const it = on(ee, "tick", { signal: controller.signal });
+		const pending = it.next();
+		controller.abort();
+		const error = await pending.then(() => "resolved", (e) => e.name);
+		let thrown = "";
+		try { on(ee, "tick", { signal: controller.signal }); } catch (e) { thrown = e.name; }
+		return { error, thrown, done: (await it.next()).done, count: ee.listenerCount("tick") };
+	`)
+	require.JSONEq(t, `{"error":"AbortError","thrown":"AbortError","done":true,"count":0}`, got)
+}
+
+func TestEventTargetListenerOptions(t *testing.T) {
+	rt := newRuntime(t)
+
+	got := runJS(t, rt, `
+		const target = new EventTarget();
+		const seen = [];
+		const controller = new AbortController();
+		target.addEventListener("x", () => seen.push("once"), { once: true });
+		target.addEventListener("x", () => seen.push("signal"), { signal: controller.signal });
+		target.addEventListener("x", (e) => { seen.push("cancel"); e.preventDefault(); });
+		const first = target.dispatchEvent(new Event("x", { cancelable: true }));
+		controller.abort();
+		const second = target.dispatchEvent(new Event("x"));
+		target.addEventListener("y", (e) => { seen.push("y1"); e.stopImmediatePropagation(); });
+		target.addEventListener("y", () => seen.push("y2"));
+		target.dispatchEvent(new Event("y"));
+		JSON.stringify({
+			seen, first, second,
+			signalIsTarget: controller.signal instanceof EventTarget,
+		});
+	`)
+	require.JSONEq(t, `{"seen":["once","signal","cancel","cancel","y1"],"first":false,"second":true,"signalIsTarget":true}`, got)
+}
+
+func TestCaptureRejectionsRoutesToErrorEvent(t *testing.T) {
+	rt := newRuntime(t)
+
+	got := runAsyncJS(t, rt, `
+		const EventEmitter = require("events");
+		const errors = [];
+		const captured = new EventEmitter({ captureRejections: true });
+		captured.on("error", (e) => errors.push("error:" + e.message));
+		captured.on("job", async () => { throw new Error("async failure"); });
+		captured.emit("job");
+
+		const custom = new EventEmitter({ captureRejections: true });
+		custom[EventEmitter.captureRejectionSymbol] = (err, name, arg) => errors.push("symbol:" + name + ":" + arg + ":" + err.message);
+		custom.on("job", async (arg) => { throw new Error("custom failure"); });
+		custom.emit("job", 7);
+
+		EventEmitter.captureRejections = true;
+		const byDefault = new EventEmitter();
+		EventEmitter.captureRejections = false;
+		byDefault.on("error", (e) => errors.push("default:" + e.message));
+		byDefault.on("job", () => Promise.reject(new Error("default failure")));
+		byDefault.emit("job");
+
+		const off = new EventEmitter();
+		off.on("job", async () => { throw new Error("ignored"); });
+		off.emit("job");
+
+		for (let i = 0; i < 5; i++) await null;
+		return { errors, symbol: EventEmitter.captureRejectionSymbol === Symbol.for("nodejs.rejection") };
+	`)
+	require.JSONEq(t, `{"errors":["error:async failure","symbol:job:7:custom failure","default:default failure"],"symbol":true}`, got)
+}
+
+// runAsyncJS runs body inside an async function and returns the JSON of its
+// result once the returned Promise settles.
+func runAsyncJS(t *testing.T, rt *gggengine.Runtime, body string) string {
+	t.Helper()
+	got, _ := enginetest.RunAsync(t, rt, `return JSON.stringify(await (async () => {`+body+`})());`).(string)
+	return got
+}
@@ -0,0 +1,290 @@
+package events
+
+import (
+	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/modules"
+)
+
+// subscribe adds listener for name on an EventEmitter-like object (on and
+// removeListener) or an EventTarget-like one (addEventListener), and returns
+// a function that removes it again. Like Node's events.once and events.on it
+// only relies on those methods, so JavaScript-implemented emitters work too.
+func subscribe(vm *goja.Runtime, emitter goja.Value, name goja.Value, listener goja.Value) func() {
+	obj, ok := emitter.(*goja.Object)
+	if ok {
+		on, hasOn := goja.AssertFunction(obj.Get("on"))
+		off, hasOff := goja.AssertFunction(obj.Get("removeListener"))
+		if hasOn && hasOff {
+			if _, err := on(obj, name, listener); err != nil {
+				panic(err)
+			}
+			return func() { _, _ = off(obj, name, listener) }
+		}
+		add, hasAdd := goja.AssertFunction(obj.Get("addEventListener"))
+		remove, hasRemove := goja.AssertFunction(obj.Get("removeEventListener"))
+		if hasAdd && hasRemove {
+			if _, err := add(obj, name, listener); err != nil {
+				panic(err)
+			}
+			return func() { _, _ = remove(obj, name, listener) }
+		}
+	}
+	panic(vm.NewTypeError("The \"emitter\" argument must be an EventEmitter or EventTarget"))
+}
+
+// isEventTarget reports whether emitter is subscribed to with
+// addEventListener, whose listeners receive one Event instead of the
+// emit() arguments and which has no "error" event.
+func isEventTarget(emitter goja.Value) bool {
+	obj, ok := emitter.(*goja.Object)
+	if !ok {
+		return false
+	}
+	_, hasOn := goja.AssertFunction(obj.Get("on"))
+	return !hasOn
+}
+
+// signalOption reads the { signal } option shared by once() and on().
+func signalOption(vm *goja.Runtime, options goja.Value) *AbortSignal {
+	opts, ok := options.(*goja.Object)
+	if !ok {
+		return nil
+	}
+	value := opts.Get("signal")
+	if !modules.Present(value) {
+		return nil
+	}
+	signal, ok := SignalOf(value)
+	if !ok {
+		panic(vm.NewTypeError("The \"options.signal\" property must be an AbortSignal"))
+	}
+	return signal
+}
+
+// abortError is the error once() and on() reject with when their signal
+// aborts. Like Node, the signal's reason becomes its cause.
+func abortError(vm *goja.Runtime, signal *AbortSignal) goja.Value {
+	err := domException(vm, "AbortError", "The operation was aborted", 20)
+	if obj, ok := err.(*goja.Object); ok {
+		_ = obj.Set("cause", signal.Reason())
+	}
+	return err
+}
+
+// once implements events.once(emitter, name, { signal }): a Promise for the
+// arguments of the next name event. An "error" event rejects it first.
+func once(vm *goja.Runtime, call goja.FunctionCall) goja.Value {
+	emitter, name := call.Argument(0), call.Argument(1)
+	signal := signalOption(vm, call.Argument(2))
+	promise, resolve, reject := vm.NewPromise()
+	if signal != nil && signal.aborted {
+		_ = reject(abortError(vm, signal))
+		return vm.ToValue(promise)
+	}
+
+	var cleanups []func()
+	settle := func() {
+		for _, cleanup := range cleanups {
+			cleanup()
+		}
+		cleanups = nil
+	}
+	cleanups = append(cleanups, subscribe(vm, emitter, name, vm.ToValue(func(c goja.FunctionCall) goja.Value {
+		settle()
+		_ = resolve(vm.NewArray(valuesToAny(c.Arguments)...))
+		return goja.Undefined()
+	})))
+	if !isEventTarget(emitter) && name.String() != "error" {
+		cleanups = append(cleanups, subscribe(vm, emitter, vm.ToValue("error"), vm.ToValue(func(c goja.FunctionCall) goja.Value {
+			settle()
+			_ = reject(c.Argument(0))
+			return goja.Undefined()
+		})))
+	}
+	if signal != nil {
+		cleanups = append(cleanups, signal.OnAbort(func(goja.Value) {
+			settle()
+			_ = reject(abortError(vm, signal))
+		}))
+	}
+	return vm.ToValue(promise)
+}
+
+// eventIterator is the async iterator returned by events.on(). Events that
+// arrive while no next() call is waiting are buffered.
+//
+// It is not goroutine-safe; listeners and next() both run on the owner
+// goroutine.
+type eventIterator struct {
+	vm       *goja.Runtime
+	emitter  *EventEmitter
+	queue    []goja.Value
+	waiting  []pendingNext
+	err      goja.Value
+	finished bool
+	cleanups []func()
+}
+
+type pendingNext struct {
+	resolve func(any) error
+	reject  func(any) error
+}
+
+// on implements events.on(emitter, name, { signal, close }).
+func on(vm *goja.Runtime, call goja.FunctionCall) goja.Value {
+	emitter, name := call.Argument(0), call.Argument(1)
+	signal := signalOption(vm, call.Argument(2))
+	if signal != nil && signal.aborted {
+		panic(abortError(vm, signal))
+	}
+	it := &eventIterator{vm: vm}
+	it.cleanups = append(it.cleanups, subscribe(vm, emitter, name, vm.ToValue(func(c goja.FunctionCall) goja.Value {
+		it.push(vm.NewArray(valuesToAny(c.Arguments)...))
+		return goja.Undefined()
+	})))
+	if !isEventTarget(emitter) && name.String() != "error" {
+		it.cleanups = append(it.cleanups, subscribe(vm, emitter, vm.ToValue("error"), vm.ToValue(func(c goja.FunctionCall) goja.Value {
+			it.fail(c.Argument(0))
+			return goja.Undefined()
+		})))
+	}
+	if opts, ok := call.Argument(2).(*goja.Object); ok && modules.Present(opts.Get("close")) {
+		closeNames := opts.Get("close").ToObject(vm)
+		for _, key := range closeNames.Keys() {
+			it.cleanups = append(it.cleanups, subscribe(vm, emitter, closeNames.Get(key), vm.ToValue(func(goja.FunctionCall) goja.Value {
+				it.finish()
+				return goja.Undefined()
+			})))
+		}
+	}
+	if signal != nil {
+		it.cleanups = append(it.cleanups, signal.OnAbort(func(goja.Value) {
+			it.fail(abortError(vm, signal))
+		}))
+	}
+	if e, _, ok := FromValue(emitter); ok {
+		it.emitter = e
+		e.iterators = append(e.iterators, it)
+	}
+	return it.object()
+}
+
+func (it *eventIterator) object() *goja.Object {
+	vm := it.vm
+	obj := vm.NewObject()
+	mustSet(vm, obj, "next", func() goja.Value {
+		promise, resolve, reject := vm.NewPromise()
+		switch {
+		case len(it.queue) > 0:
+			value := it.queue[0]
+			it.queue = it.queue[1:]
+			_ = resolve(it.result(value, false))
+		case it.err != nil:
+			err := it.err
+			it.err = nil
+			_ = reject(err)
+		case it.finished:
+			_ = resolve(it.result(goja.Undefined(), true))
+		default:
+			it.waiting = append(it.waiting, pendingNext{resolve: resolve, reject: reject})
+		}
+		return vm.ToValue(promise)
+	})
+	mustSet(vm, obj, "return", func(value goja.Value) goja.Value {
+		it.finish()
+		it.queue = nil
+		it.err = nil
+		promise, resolve, _ := vm.NewPromise()
+		_ = resolve(it.result(value, true))
+		return vm.ToValue(promise)
+	})
+	modules.SetAsyncIterator(vm, obj)
+	return obj
+}
+
+func (it *eventIterator) push(value goja.Value) {
+	if it.finished {
+		return
+	}
+	if len(it.waiting) > 0 {
+		next := it.waiting[0]
+		it.waiting = it.waiting[1:]
+		_ = next.resolve(it.result(value, false))
+		return
+	}
+	it.queue = append(it.queue, value)
+}
+
+// fail rejects the oldest waiting next() call with err, or the next one made,
+// and ends the iteration.
+func (it *eventIterator) fail(err goja.Value) {
+	if it.finished {
+		return
+	}
+	if len(it.waiting) > 0 {
+		next := it.waiting[0]
+		it.waiting = it.waiting[1:]
+		_ = next.reject(err)
+	} else {
+		it.err = err
+	}
+	it.finish()
+}
+
+// finish removes the listeners. Buffered events are still returned before
+// next() reports done.
+func (it *eventIterator) finish() {
+	if it.finished {
+		return
+	}
+	it.finished = true
+	for _, cleanup := range it.cleanups {
+		cleanup()
+	}
+	it.cleanups = nil
+	if it.emitter != nil {
+		it.emitter.dropIterator(it)
+	}
+	waiting := it.waiting
+	it.waiting = nil
+	for _, next := range waiting {
+		_ = next.resolve(it.result(goja.Undefined(), true))
+	}
+}
+
+func (it *eventIterator) result(value goja.Value, done bool) goja.Value {
+	obj := it.vm.NewObject()
+	_ = obj.Set("value", value)
+	_ = obj.Set("done", done)
+	return obj
+}
+
+// EndIterators finishes every events.on() iterator reading from e, as if a
+// close event had fired. Go code that owns the resource behind an emitter
+// calls it when the resource goes away so awaiting loops end instead of
+// waiting forever.
+func (e *EventEmitter) EndIterators() {
+	if e == nil {
+		return
+	}
+	for _, it := range append([]*eventIterator(nil), e.iterators...) {
+		it.finish()
+	}
+}
+
+func (e *EventEmitter) dropIterator(it *eventIterator) {
+	for i, existing := range e.iterators {
+		if existing == it {
+			e.iterators = append(e.iterators[:i], e.iterators[i+1:]...)
+			return
+		}
+	}
+}
+
+func valuesToAny(values []goja.Value) []any {
+	out := make([]any, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
@@ -0,0 +1,244 @@
+package events
+
+import (
+	"fmt"
+
+	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/modules"
+)
+
+// EventTarget is the Go side of a JavaScript EventTarget. AbortSignal is one;
+// scripts create others with new EventTarget().
+//
+// It is not goroutine-safe. All methods must be called on the owning goja
+// runtime goroutine.
+type EventTarget struct {
+	vm        *goja.Runtime
+	object    *goja.Object
+	listeners map[string][]*targetListener
+}
+
+type targetListener struct {
+	value    goja.Value
+	callable goja.Callable
+	once     bool
+	// detach unregisters the hook on the listener's { signal } option.
+	detach func()
+}
+
+var (
+	targetKey    = goja.NewSymbol("go-go-goja.events.target")
+	stopEventKey = goja.NewSymbol("go-go-goja.events.stopImmediatePropagation")
+)
+
+// NewEventTarget creates an EventTarget backed by a new JavaScript object. It
+// must be called on the owning runtime goroutine.
+func NewEventTarget(vm *goja.Runtime) *EventTarget {
+	obj := vm.NewObject()
+	if err := obj.SetPrototype(classesFor(vm).target.Get("prototype").(*goja.Object)); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("events: set event target prototype: %w", err)))
+	}
+	return attachEventTarget(vm, obj)
+}
+
+func attachEventTarget(vm *goja.Runtime, obj *goja.Object) *EventTarget {
+	t := &EventTarget{vm: vm, object: obj, listeners: map[string][]*targetListener{}}
+	if err := obj.DefineDataPropertySymbol(targetKey, vm.ToValue(t), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("events: attach event target: %w", err)))
+	}
+	return t
+}
+
+// EventTargetOf unwraps a JavaScript EventTarget, including AbortSignals.
+func EventTargetOf(value goja.Value) (*EventTarget, bool) {
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return nil, false
+	}
+	inner, ok := obj.GetSymbol(targetKey).(*goja.Object)
+	if !ok {
+		return nil, false
+	}
+	t, ok := inner.Export().(*EventTarget)
+	return t, ok && t != nil
+}
+
+// Object returns the JavaScript EventTarget object.
+func (t *EventTarget) Object() *goja.Object { return t.object }
+
+// NewEvent creates a JavaScript Event of the given type.
+func NewEvent(vm *goja.Runtime, typ string) *goja.Object {
+	event, err := vm.New(classesFor(vm).event, vm.ToValue(typ))
+	if err != nil {
+		panic(vm.NewGoError(fmt.Errorf("events: create event: %w", err)))
+	}
+	return event
+}
+
+// Dispatch delivers event to the listeners registered for its type and
+// reports whether no listener called preventDefault(). Listener errors are
+// logged rather than returned, as in browsers and Node.js.
+func (t *EventTarget) Dispatch(event *goja.Object) bool {
+	typ := event.Get("type").String()
+	_ = event.Set("target", t.object)
+	_ = event.Set("currentTarget", t.object)
+	for _, l := range append([]*targetListener(nil), t.listeners[typ]...) {
+		// Listeners removed by an earlier listener are skipped.
+		if !t.registered(typ, l) {
+			continue
+		}
+		if l.once {
+			t.remove(typ, l.value)
+		}
+		if _, err := l.callable(t.object, event); err != nil {
+			log.Warn().Err(err).Str("event", typ).Msg("events: event listener error")
+		}
+		if stop := event.GetSymbol(stopEventKey); stop != nil && stop.ToBoolean() {
+			break
+		}
+	}
+	_ = event.Set("currentTarget", goja.Null())
+	return !event.Get("defaultPrevented").ToBoolean()
+}
+
+// addEventListener registers listener with the DOM options once and signal.
+// A listener already registered for typ is ignored.
+func (t *EventTarget) addEventListener(typ string, listener goja.Value, options goja.Value) {
+	callable, ok := listenerCallable(listener)
+	if !ok {
+		return
+	}
+	for _, l := range t.listeners[typ] {
+		if l.value.SameAs(listener) {
+			return
+		}
+	}
+	l := &targetListener{value: listener, callable: callable}
+	if opts, isObj := options.(*goja.Object); isObj {
+		l.once = opts.Get("once") != nil && opts.Get("once").ToBoolean()
+		if signalValue := opts.Get("signal"); modules.Present(signalValue) {
+			signal, isSignal := SignalOf(signalValue)
+			if !isSignal {
+				panic(t.vm.NewTypeError("The \"options.signal\" property must be an AbortSignal"))
+			}
+			if signal.aborted {
+				return
+			}
+			l.detach = signal.OnAbort(func(goja.Value) { t.remove(typ, listener) })
+		}
+	}
+	t.listeners[typ] = append(t.listeners[typ], l)
+}
+
+func (t *EventTarget) remove(typ string, listener goja.Value) {
+	list := t.listeners[typ]
+	for i, l := range list {
+		if !l.value.SameAs(listener) {
+			continue
+		}
+		if l.detach != nil {
+			l.detach()
+		}
+		list = append(list[:i:i], list[i+1:]...)
+		if len(list) == 0 {
+			delete(t.listeners, typ)
+		} else {
+			t.listeners[typ] = list
+		}
+		return
+	}
+}
+
+func (t *EventTarget) registered(typ string, listener *targetListener) bool {
+	for _, l := range t.listeners[typ] {
+		if l == listener {
+			return true
+		}
+	}
+	return false
+}
+
+// listenerCallable accepts functions and objects with a handleEvent method,
+// which are both valid EventTarget listeners.
+func listenerCallable(value goja.Value) (goja.Callable, bool) {
+	if callable, ok := goja.AssertFunction(value); ok {
+		return callable, true
+	}
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return nil, false
+	}
+	handle, ok := goja.AssertFunction(obj.Get("handleEvent"))
+	if !ok {
+		return nil, false
+	}
+	return func(_ goja.Value, args ...goja.Value) (goja.Value, error) {
+		return handle(obj, args...)
+	}, true
+}
+
+// installEventTarget builds the EventTarget and Event constructors.
+func installEventTarget(vm *goja.Runtime, c *classes) {
+	c.target = vm.ToValue(func(call goja.ConstructorCall) *goja.Object {
+		attachEventTarget(vm, call.This)
+		return nil
+	}).(*goja.Object)
+	targetProto := c.target.Get("prototype").(*goja.Object)
+	mustSet(vm, targetProto, "addEventListener", func(call goja.FunctionCall) goja.Value {
+		mustTarget(vm, call.This).addEventListener(call.Argument(0).String(), call.Argument(1), call.Argument(2))
+		return goja.Undefined()
+	})
+	mustSet(vm, targetProto, "removeEventListener", func(call goja.FunctionCall) goja.Value {
+		mustTarget(vm, call.This).remove(call.Argument(0).String(), call.Argument(1))
+		return goja.Undefined()
+	})
+	mustSet(vm, targetProto, "dispatchEvent", func(call goja.FunctionCall) goja.Value {
+		event, ok := call.Argument(0).(*goja.Object)
+		if !ok || !modules.Present(event.Get("type")) {
+			panic(vm.NewTypeError("The \"event\" argument must be an instance of Event"))
+		}
+		return vm.ToValue(mustTarget(vm, call.This).Dispatch(event))
+	})
+
+	c.event = vm.ToValue(func(call goja.ConstructorCall) *goja.Object {
+		if len(call.Arguments) == 0 {
+			panic(vm.NewTypeError("The \"type\" argument must be specified"))
+		}
+		init, _ := call.Argument(1).(*goja.Object)
+		option := func(name string) bool {
+			return init != nil && init.Get(name) != nil && init.Get(name).ToBoolean()
+		}
+		mustSet(vm, call.This, "type", call.Argument(0).String())
+		mustSet(vm, call.This, "bubbles", option("bubbles"))
+		mustSet(vm, call.This, "cancelable", option("cancelable"))
+		mustSet(vm, call.This, "defaultPrevented", false)
+		mustSet(vm, call.This, "target", goja.Null())
+		mustSet(vm, call.This, "currentTarget", goja.Null())
+		return nil
+	}).(*goja.Object)
+	eventProto := c.event.Get("prototype").(*goja.Object)
+	mustSet(vm, eventProto, "preventDefault", func(call goja.FunctionCall) goja.Value {
+		this := call.This.ToObject(vm)
+		if this.Get("cancelable").ToBoolean() {
+			mustSet(vm, this, "defaultPrevented", true)
+		}
+		return goja.Undefined()
+	})
+	mustSet(vm, eventProto, "stopImmediatePropagation", func(call goja.FunctionCall) goja.Value {
+		_ = call.This.ToObject(vm).SetSymbol(stopEventKey, true)
+		return goja.Undefined()
+	})
+	// Events do not propagate through a tree here, so this is a no-op kept
+	// for DOM compatibility.
+	mustSet(vm, eventProto, "stopPropagation", func(goja.FunctionCall) goja.Value {
+		return goja.Undefined()
+	})
+}
+
+func mustTarget(vm *goja.Runtime, value goja.Value) *EventTarget {
+	t, ok := EventTargetOf(value)
+	if !ok {
+		panic(vm.NewTypeError("Value of this must be an EventTarget"))
+	}
+	return t
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/dop251/goja"
 	"github.com/go-go-golems/go-go-goja/modules"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimebridge"
+	"github.com/go-go-golems/go-go-goja/pkg/sandbox"
 	"github.com/go-go-golems/go-go-goja/pkg/tsgen/spec"
 )
 
@@ -43,6 +45,9 @@ func (mod m) Loader(vm *goja.Runtime, moduleObj *goja.Object) {
 
 	// run(cmd, args[]) -> string
 	modules.SetExport(exports, mod.Name(), "run", func(cmd string, args []string) (string, error) {
+		if err := runtimebridge.Sandbox(vm).CheckExec(cmd, args); err != nil {
+			panic(sandbox.JSError(vm, err))
+		}
 		// #nosec G204 -- this module exists specifically to run caller-selected commands in trusted runtimes.
 		out, err := exec.Command(cmd, args...).CombinedOutput()
 		return string(out), err
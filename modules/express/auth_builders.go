@@ -2,6 +2,8 @@ package express
 
 import (
 	"fmt"
+	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -269,14 +271,20 @@ func (s *builderStore) resourceSpec(vm *goja.Runtime, value goja.Value) (gojahtt
 }
 
 type routeBuilder struct {
-	registrar *Registrar
+	routes    *gojahttp.RuntimeRoutes
 	store     *builderStore
 	vm        *goja.Runtime
 	plan      gojahttp.RoutePlan
+	websocket bool
 }
 
-func newRouteBuilder(vm *goja.Runtime, registrar *Registrar, store *builderStore, method, pattern string) goja.Value {
-	b := &routeBuilder{registrar: registrar, store: store, vm: vm, plan: gojahttp.RoutePlan{Method: method, Pattern: pattern}}
+func newRouteBuilder(vm *goja.Runtime, routes *gojahttp.RuntimeRoutes, store *builderStore, method, pattern string) goja.Value {
+	b := &routeBuilder{routes: routes, store: store, vm: vm, plan: gojahttp.RoutePlan{Method: method, Pattern: pattern}}
+	return b.needsSecurityObject()
+}
+
+func newWebSocketRouteBuilder(vm *goja.Runtime, routes *gojahttp.RuntimeRoutes, store *builderStore, pattern string) goja.Value {
+	b := &routeBuilder{routes: routes, store: store, vm: vm, plan: gojahttp.RoutePlan{Method: http.MethodGet, Pattern: pattern}, websocket: true}
 	return b.needsSecurityObject()
 }
 
@@ -314,6 +322,7 @@ func (b *routeBuilder) needsPolicyObject() goja.Value {
 	b.attachCSRFMethod(obj)
 	b.attachAuditMethod(obj)
 	b.attachRateLimitMethod(obj)
+	b.attachBodyMethods(obj)
 	_ = obj.Set("allow", func(action string) (goja.Value, error) {
 		action = strings.TrimSpace(action)
 		if action == "" {
@@ -330,12 +339,19 @@ func (b *routeBuilder) needsHandlerObject() goja.Value {
 	b.attachCSRFMethod(obj)
 	b.attachAuditMethod(obj)
 	b.attachRateLimitMethod(obj)
+	b.attachBodyMethods(obj)
 	_ = obj.Set("handle", func(handler goja.Value) error {
 		fn, ok := goja.AssertFunction(handler)
 		if !ok {
 			return fmt.Errorf("planned route .handle(...) requires a function")
 		}
-		return b.registrar.host.RegisterPlanned(b.plan, fn)
+		if b.routes == nil {
+			return fmt.Errorf("express module is not bound to a host")
+		}
+		if b.websocket {
+			return b.routes.RegisterPlannedWebSocket(b.plan, fn)
+		}
+		return b.routes.RegisterPlanned(b.plan, fn)
 	})
 	return obj
 }
@@ -362,6 +378,54 @@ func (b *routeBuilder) attachRateLimitMethod(obj *goja.Object) {
 	})
 }
 
+func (b *routeBuilder) attachBodyMethods(obj *goja.Object) {
+	_ = obj.Set("bodyLimit", func(value goja.Value) (goja.Value, error) {
+		limit, err := parseByteSize(value)
+		if err != nil {
+			return nil, fmt.Errorf(".bodyLimit(limit): %w", err)
+		}
+		b.plan.Body.Limit = limit
+		return obj, nil
+	})
+	_ = obj.Set("streamBody", func() goja.Value {
+		b.plan.Body.Stream = true
+		return obj
+	})
+}
+
+// parseByteSize accepts a positive byte count or a string such as "512kb" or
+// "10mb". Units are binary multiples.
+func parseByteSize(value goja.Value) (int64, error) {
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return 0, fmt.Errorf("requires a size")
+	}
+	if n, ok := value.Export().(int64); ok {
+		if n <= 0 {
+			return 0, fmt.Errorf("size must be positive, got %d", n)
+		}
+		return n, nil
+	}
+	text := strings.ToLower(strings.TrimSpace(value.String()))
+	number := strings.TrimRight(text, "bkmg")
+	multiplier := int64(1)
+	switch strings.TrimSpace(text[len(number):]) {
+	case "", "b":
+	case "kb", "k":
+		multiplier = 1 << 10
+	case "mb", "m":
+		multiplier = 1 << 20
+	case "gb", "g":
+		multiplier = 1 << 30
+	default:
+		return 0, fmt.Errorf("invalid size %q", text)
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(number), 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", text)
+	}
+	return n * multiplier, nil
+}
+
 func (b *routeBuilder) attachAuditMethod(obj *goja.Object) {
 	_ = obj.Set("audit", func(event string) (goja.Value, error) {
 		event = strings.TrimSpace(event)
@@ -0,0 +1,141 @@
+package express
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/modules/uidsl"
+	"github.com/go-go-golems/go-go-goja/pkg/gojahttp"
+)
+
+func TestExpressPlannedRouteReceivesUploadedFiles(t *testing.T) {
+	host := gojahttp.NewHost(gojahttp.HostOptions{Dev: true, Renderer: uidsl.RenderAny})
+	rt := newExpressAuthRuntime(t, host)
+	runExpressAuthScript(t, rt, `
+		const express = require("express");
+		const app = express.app();
+		app.post("/upload")
+		  .public()
+		  .bodyLimit("1kb")
+		  .handle((ctx, res) => {
+		    const file = ctx.request.files[0];
+		    res.json({
+		      title: ctx.body.title,
+		      field: file.field,
+		      filename: file.filename,
+		      size: file.size,
+		      text: file.text(),
+		      first: file.bytes()[0],
+		    });
+		  });
+	`)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	_ = writer.WriteField("title", "Trail notes")
+	part, err := writer.CreateFormFile("attachment", "note.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = part.Write([]byte("hello"))
+	_ = writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader(body.Bytes()))
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rr := httptest.NewRecorder()
+	host.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", rr.Code, rr.Body.String())
+	}
+	for _, want := range []string{`"title":"Trail notes"`, `"field":"attachment"`, `"filename":"note.txt"`, `"size":5`, `"text":"hello"`, `"first":104`} {
+		if !strings.Contains(rr.Body.String(), want) {
+			t.Fatalf("body=%s, missing %s", rr.Body.String(), want)
+		}
+	}
+
+	big := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(strings.Repeat("x", 2048)))
+	big.Header.Set("Content-Type", "text/plain")
+	rr = httptest.NewRecorder()
+	host.ServeHTTP(rr, big)
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized status=%d body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestExpressPlannedRouteStreamsBody(t *testing.T) {
+	host := gojahttp.NewHost(gojahttp.HostOptions{Dev: true, Renderer: uidsl.RenderAny})
+	rt := newExpressAuthRuntime(t, host)
+	runExpressAuthScript(t, rt, `
+		const express = require("express");
+		const app = express.app();
+		app.put("/blobs/:id")
+		  .public()
+		  .streamBody()
+		  .bodyLimit(64)
+		  .handle(async (ctx, res) => {
+		    const stream = ctx.request.stream();
+		    let total = 0, chunks = 0;
+		    for (let chunk = await stream.read(8); chunk !== null; chunk = await stream.read(8)) {
+		      total += chunk.length;
+		      chunks++;
+		    }
+		    res.json({ id: ctx.params.id, total, chunks, rawBody: ctx.request.rawBody });
+		  });
+		app.post("/echo")
+		  .public()
+		  .handle(async (ctx, res) => res.send(await ctx.request.stream().text()));
+	`)
+
+	rr := httptest.NewRecorder()
+	host.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/blobs/a1", strings.NewReader(strings.Repeat("z", 20))))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", rr.Code, rr.Body.String())
+	}
+	for _, want := range []string{`"id":"a1"`, `"total":20`, `"chunks":3`, `"rawBody":""`} {
+		if !strings.Contains(rr.Body.String(), want) {
+			t.Fatalf("body=%s, missing %s", rr.Body.String(), want)
+		}
+	}
+
+	rr = httptest.NewRecorder()
+	host.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/blobs/a2", strings.NewReader(strings.Repeat("z", 65))))
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("oversized stream status=%d body=%s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	host.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("buffered")))
+	if rr.Code != http.StatusOK || strings.TrimSpace(rr.Body.String()) != "buffered" {
+		t.Fatalf("echo status=%d body=%q", rr.Code, rr.Body.String())
+	}
+}
+
+func TestExpressBodyBuilderValidation(t *testing.T) {
+	host := gojahttp.NewHost(gojahttp.HostOptions{Dev: true})
+	rt := newExpressAuthRuntime(t, host)
+	for name, script := range map[string]string{
+		"invalid size": `require("express").app().post("/a").public().bodyLimit("lots")`,
+		"zero size":    `require("express").app().post("/a").public().bodyLimit(0)`,
+		"streamed body rate limit key": `
+			const express = require("express");
+			express.app().post("/b").public()
+			  .rateLimit(express.rateLimit("uploads").perMinute(5).byBodyField("tenant"))
+			  .streamBody()
+			  .handle(() => {});
+		`,
+	} {
+		_, err := rt.Owner.Call(context.Background(), "load-test", func(_ context.Context, vm *goja.Runtime) (any, error) {
+			_, err := vm.RunString(script)
+			return nil, err
+		})
+		if err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
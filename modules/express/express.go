@@ -45,27 +45,65 @@ func (r *Registrar) RegisterRuntimeModule(ctx *engine.RuntimeModuleRegistrationC
 	if r.host == nil {
 		return fmt.Errorf("express registrar requires host")
 	}
-	r.host.SetRuntime(ctx.Owner)
+	routes, err := r.attach(ctx.Owner, ctx.AddCloser)
+	if err != nil {
+		return err
+	}
 	name := r.name
 	if name == "" {
 		name = "express"
 	}
-	reg.RegisterNativeModule(name, r.loader)
+	reg.RegisterNativeModule(name, func(vm *goja.Runtime, moduleObj *goja.Object) {
+		r.loader(vm, moduleObj, routes)
+	})
 	return nil
 }
 
+// NewLoader returns a loader that registers routes into host for the runtime
+// that requires the module. Routes of engine runtimes are keyed by the
+// runtime's owner, so host.SetRuntimePool can dispatch to them, and are
+// forgotten when the runtime closes.
 func NewLoader(host *gojahttp.Host, opts ...Option) require.ModuleLoader {
 	registrar := NewRegistrar(host, opts...)
 	return func(vm *goja.Runtime, moduleObj *goja.Object) {
+		var routes *gojahttp.RuntimeRoutes
 		if host != nil {
+			var owner runtimeowner.RuntimeOwner
+			var addCloser func(func(context.Context) error) error
 			if runtimeServices, ok := runtimebridge.Lookup(vm); ok && runtimeServices.Owner != nil {
-				host.SetRuntime(runtimebridgeOwnerAdapter{owner: runtimeServices.Owner})
+				owner = runtimeowner.Unbridge(runtimeServices.Owner)
+				if owner == nil {
+					owner = runtimebridgeOwnerAdapter{owner: runtimeServices.Owner}
+				}
+				addCloser = runtimeServices.AddCloser
+			}
+			var err error
+			if routes, err = registrar.attach(owner, addCloser); err != nil {
+				panic(vm.NewGoError(err))
 			}
 		}
-		registrar.loader(vm, moduleObj)
+		registrar.loader(vm, moduleObj, routes)
 	}
 }
 
+// attach returns the routes of the runtime owner and attaches it to the
+// host until the runtime closes.
+func (r *Registrar) attach(owner runtimeowner.RuntimeOwner, addCloser func(func(context.Context) error) error) (*gojahttp.RuntimeRoutes, error) {
+	if owner != nil {
+		r.host.AttachRuntime(owner)
+		if addCloser != nil {
+			if err := addCloser(func(context.Context) error {
+				r.host.ForgetRuntime(owner)
+				return nil
+			}); err != nil {
+				r.host.ForgetRuntime(owner)
+				return nil, err
+			}
+		}
+	}
+	return r.host.ForRuntime(owner), nil
+}
+
 type runtimebridgeOwnerAdapter struct {
 	owner runtimebridge.RuntimeOwner
 }
@@ -86,10 +124,10 @@ func (a runtimebridgeOwnerAdapter) WaitIdle(context.Context) error { return nil
 func (a runtimebridgeOwnerAdapter) Shutdown(context.Context) error { return nil }
 func (a runtimebridgeOwnerAdapter) IsClosed() bool                 { return false }
 
-func (r *Registrar) loader(vm *goja.Runtime, moduleObj *goja.Object) {
+func (r *Registrar) loader(vm *goja.Runtime, moduleObj *goja.Object, routes *gojahttp.RuntimeRoutes) {
 	exports := moduleObj.Get("exports").(*goja.Object)
 	builders := newBuilderStore()
-	_ = exports.Set("app", func() goja.Value { return r.appObject(vm, builders) })
+	_ = exports.Set("app", func() goja.Value { return r.appObject(vm, builders, routes) })
 	_ = exports.Set("user", func() goja.Value { return builders.newUserBuilder(vm) })
 	_ = exports.Set("agent", func() goja.Value { return builders.newAgentBuilder(vm) })
 	_ = exports.Set("sessionUser", func() goja.Value { return builders.newSessionUserBuilder(vm) })
@@ -163,7 +201,7 @@ func mountOptionsFromValue(vm *goja.Runtime, value goja.Value) mountOptions {
 	return ret
 }
 
-func (r *Registrar) appObject(vm *goja.Runtime, builders *builderStore) goja.Value {
+func (r *Registrar) appObject(vm *goja.Runtime, builders *builderStore, routes *gojahttp.RuntimeRoutes) goja.Value {
 	obj := vm.NewObject()
 	mount := func(prefix string, handlerValue goja.Value, options goja.Value) error {
 		if prefix == "" {
@@ -190,11 +228,17 @@ func (r *Registrar) appObject(vm *goja.Runtime, builders *builderStore) goja.Val
 			if len(call.Arguments) > 1 && !goja.IsUndefined(call.Argument(1)) && !goja.IsNull(call.Argument(1)) {
 				panic(vm.NewTypeError("app.%s(pattern, handler) was removed; use app.%s(pattern).public().handle(handler) or app.%s(pattern).auth(...).allow(...).handle(handler)", method, method, method))
 			}
-			return newRouteBuilder(vm, r, builders, upperMethod, pattern)
+			return newRouteBuilder(vm, routes, builders, upperMethod, pattern)
 		})
 	}
+	_ = obj.Set("ws", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) == 0 || goja.IsUndefined(call.Argument(0)) || goja.IsNull(call.Argument(0)) {
+			panic(vm.NewTypeError("app.ws(pattern) requires a route pattern"))
+		}
+		return newWebSocketRouteBuilder(vm, routes, builders, call.Argument(0).String())
+	})
 	_ = obj.Set("route", func(method, pattern string) goja.Value {
-		return newRouteBuilder(vm, r, builders, method, pattern)
+		return newRouteBuilder(vm, routes, builders, method, pattern)
 	})
 	_ = obj.Set("static", func(prefix, dir string) error {
 		if prefix == "" || dir == "" {
@@ -5,14 +5,18 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"testing/fstest"
+	"time"
 
 	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/require"
 	fsmod "github.com/go-go-golems/go-go-goja/modules/fs"
 	"github.com/go-go-golems/go-go-goja/modules/uidsl"
 	"github.com/go-go-golems/go-go-goja/pkg/engine"
 	"github.com/go-go-golems/go-go-goja/pkg/gojahttp"
+	"github.com/gorilla/websocket"
 )
 
 func TestExpressRouteReturnsHTMLNode(t *testing.T) {
@@ -418,3 +422,186 @@ func TestExpressMountRejectsPlainObjects(t *testing.T) {
 		t.Fatalf("expected mount error, got %v", err)
 	}
 }
+
+func TestExpressWebSocketRoute(t *testing.T) {
+	host := gojahttp.NewHost(gojahttp.HostOptions{Dev: true})
+	factory, err := engine.NewRuntimeFactoryBuilder().WithModules(NewRegistrar(host)).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt, err := factory.NewRuntime(engine.WithStartupContext(context.Background()), engine.WithLifetimeContext(context.Background()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = rt.Close(context.Background()) }()
+	host.SetRuntime(rt.Owner)
+	_, err = rt.Owner.Call(context.Background(), "load-test", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		_, err := vm.RunString(`
+			const express = require("express");
+			const EventEmitter = require("events");
+			const app = express.app();
+			app.ws("/echo").public().handle((ctx, socket) => {
+				socket.send(String(socket instanceof EventEmitter));
+				socket.on("message", data => socket.send("echo:" + data));
+			});
+		`)
+		return nil, err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(host)
+	defer srv.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/echo", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, data, err := conn.ReadMessage(); err != nil || string(data) != "true" {
+		t.Fatalf("greeting=%q err=%v", data, err)
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("hi")); err != nil {
+		t.Fatal(err)
+	}
+	if _, data, err := conn.ReadMessage(); err != nil || string(data) != "echo:hi" {
+		t.Fatalf("echo=%q err=%v", data, err)
+	}
+}
+
+func TestExpressRoutesServedFromRuntimePool(t *testing.T) {
+	host := gojahttp.NewHost(gojahttp.HostOptions{Dev: true})
+	factory, err := engine.NewRuntimeFactoryBuilder().WithModules(NewRegistrar(host)).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nextID := 0
+	pool, err := engine.NewRuntimePool(context.Background(), factory, engine.RuntimePoolOptions{
+		Size:    2,
+		MaxSize: 2,
+		Prepare: func(ctx context.Context, rt *engine.Runtime) error {
+			nextID++
+			id := nextID
+			_, err := rt.Owner.Call(ctx, "load-test", func(_ context.Context, vm *goja.Runtime) (any, error) {
+				if err := vm.Set("runtimeId", id); err != nil {
+					return nil, err
+				}
+				_, err := vm.RunString(`
+					const express = require("express");
+					let served = 0;
+					express.app().get("/who").public().handle((ctx, res) => {
+						served++;
+						res.json({ runtime: runtimeId, served });
+					});
+				`)
+				return nil, err
+			})
+			return err
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = pool.Close(context.Background()) }()
+	host.SetRuntimePool(pool)
+	if routes := host.Routes(); len(routes) != 1 {
+		t.Fatalf("expected pooled runtimes to share one route, got %#v", routes)
+	}
+
+	who := func() string {
+		rr := httptest.NewRecorder()
+		host.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/who", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("status=%d body=%s", rr.Code, rr.Body.String())
+		}
+		return strings.TrimSpace(rr.Body.String())
+	}
+	// Holding one lease forces requests onto the other runtime.
+	held, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second := who(), who()
+	// Swap which runtime is held.
+	busy, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.Release(held)
+	other := who()
+	pool.Release(busy)
+
+	if !strings.Contains(second, `"served":2`) || strings.TrimSuffix(first, `"served":1}`) != strings.TrimSuffix(second, `"served":2}`) {
+		t.Fatalf("expected both requests on the same runtime: %s, %s", first, second)
+	}
+	if other == first || !strings.Contains(other, `"served":1`) {
+		t.Fatalf("expected a request on the second runtime, got %s after %s", other, first)
+	}
+	if stats := pool.Stats(); stats.Hits < 5 || stats.Misses != 0 {
+		t.Fatalf("pool stats=%+v", stats)
+	}
+}
+
+// loaderModule registers a require.ModuleLoader as a native module, as
+// xgoja providers do.
+type loaderModule struct {
+	name   string
+	loader require.ModuleLoader
+}
+
+func (m loaderModule) ID() string { return m.name }
+
+func (m loaderModule) RegisterRuntimeModule(_ *engine.RuntimeModuleRegistrationContext, reg *require.Registry) error {
+	reg.RegisterNativeModule(m.name, m.loader)
+	return nil
+}
+
+func TestExpressLoaderRoutesServedFromRuntimePool(t *testing.T) {
+	host := gojahttp.NewHost(gojahttp.HostOptions{Dev: true})
+	factory, err := engine.NewRuntimeFactoryBuilder().WithModules(loaderModule{name: "express", loader: NewLoader(host)}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nextID atomic.Int32
+	pool, err := engine.NewRuntimePool(context.Background(), factory, engine.RuntimePoolOptions{
+		Size:    2,
+		MaxSize: 2,
+		Reset:   engine.ResetDiscard,
+		Prepare: func(ctx context.Context, rt *engine.Runtime) error {
+			id := nextID.Add(1)
+			_, err := rt.Owner.Call(ctx, "load-test", func(_ context.Context, vm *goja.Runtime) (any, error) {
+				if err := vm.Set("runtimeId", id); err != nil {
+					return nil, err
+				}
+				_, err := vm.RunString(`
+					require("express").app().get("/who").public().handle((ctx, res) => res.json({ runtime: runtimeId }));
+				`)
+				return nil, err
+			})
+			return err
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = pool.Close(context.Background()) }()
+	host.SetRuntimePool(pool)
+
+	seen := map[string]bool{}
+	for i := 0; i < 4; i++ {
+		rr := httptest.NewRecorder()
+		host.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/who", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("request %d: status=%d body=%s", i, rr.Code, rr.Body.String())
+		}
+		seen[strings.TrimSpace(rr.Body.String())] = true
+	}
+	// ResetDiscard retires every runtime after one request, so each request
+	// runs on a fresh runtime whose handlers the loader registered.
+	if len(seen) != 4 {
+		t.Fatalf("expected every request on its own runtime, got %v", seen)
+	}
+	if stats := pool.Stats(); stats.Recycled != 4 {
+		t.Fatalf("pool stats=%+v", stats)
+	}
+}
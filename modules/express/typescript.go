@@ -35,6 +35,7 @@ func (r *Registrar) TypeScriptModule() *spec.Module {
 			"  patch(pattern: string): RouteNeedsSecurity;",
 			"  delete(pattern: string): RouteNeedsSecurity;",
 			"  all(pattern: string): RouteNeedsSecurity;",
+			"  ws(pattern: string): RouteNeedsSecurity<WebSocketHandler>;",
 			"  mount(prefix: string, handler: MountableHandler, options?: MountOptions): void;",
 			"  mountHandler(prefix: string, handler: MountableHandler, options?: MountOptions): void;",
 			"  static(prefix: string, directory: string): void;",
@@ -43,23 +44,27 @@ func (r *Registrar) TypeScriptModule() *spec.Module {
 			"}",
 			"export interface MountableHandler {}",
 			"export interface MountOptions { stripPrefix?: boolean; excludePrefixes?: string[]; }",
-			"export interface RouteNeedsSecurity {",
-			"  name(name: string): RouteNeedsSecurity;",
-			"  public(): RouteNeedsHandler;",
-			"  auth(spec: UserAuthSpec): RouteNeedsPolicy;",
+			"export interface RouteNeedsSecurity<H = PlannedHandler> {",
+			"  name(name: string): RouteNeedsSecurity<H>;",
+			"  public(): RouteNeedsHandler<H>;",
+			"  auth(spec: UserAuthSpec): RouteNeedsPolicy<H>;",
 			"}",
-			"export interface RouteNeedsPolicy {",
-			"  resource(spec: ResourceSpec): RouteNeedsPolicy;",
-			"  csrf(required?: boolean): RouteNeedsPolicy;",
-			"  audit(event: string): RouteNeedsPolicy;",
-			"  rateLimit(spec: RateLimitSpec): RouteNeedsPolicy;",
-			"  allow(action: string): RouteNeedsHandler;",
+			"export interface RouteNeedsPolicy<H = PlannedHandler> {",
+			"  resource(spec: ResourceSpec): RouteNeedsPolicy<H>;",
+			"  csrf(required?: boolean): RouteNeedsPolicy<H>;",
+			"  audit(event: string): RouteNeedsPolicy<H>;",
+			"  rateLimit(spec: RateLimitSpec): RouteNeedsPolicy<H>;",
+			"  bodyLimit(limit: number | string): RouteNeedsPolicy<H>;",
+			"  streamBody(): RouteNeedsPolicy<H>;",
+			"  allow(action: string): RouteNeedsHandler<H>;",
 			"}",
-			"export interface RouteNeedsHandler {",
-			"  csrf(required?: boolean): RouteNeedsHandler;",
-			"  audit(event: string): RouteNeedsHandler;",
-			"  rateLimit(spec: RateLimitSpec): RouteNeedsHandler;",
-			"  handle(handler: PlannedHandler): void;",
+			"export interface RouteNeedsHandler<H = PlannedHandler> {",
+			"  csrf(required?: boolean): RouteNeedsHandler<H>;",
+			"  audit(event: string): RouteNeedsHandler<H>;",
+			"  rateLimit(spec: RateLimitSpec): RouteNeedsHandler<H>;",
+			"  bodyLimit(limit: number | string): RouteNeedsHandler<H>;",
+			"  streamBody(): RouteNeedsHandler<H>;",
+			"  handle(handler: H): void;",
 			"}",
 			"export interface UserAuthBuilder {",
 			"  required(): UserAuthSpec;",
@@ -97,6 +102,20 @@ func (r *Registrar) TypeScriptModule() *spec.Module {
 			"export type RateLimitSpec = RateLimitBuilder;",
 			"export type PlannedHandler = (ctx: PlannedContext, res: Response) => unknown;",
 			"export type Handler = PlannedHandler;",
+			"export type WebSocketHandler = (ctx: Omit<PlannedContext, \"signal\">, socket: WebSocket) => unknown;",
+			"export interface WebSocket {",
+			"  readonly readyState: 1 | 2 | 3;",
+			"  readonly protocol: string;",
+			"  send(data: string | ArrayBuffer | Uint8Array): void;",
+			"  close(code?: number, reason?: string): void;",
+			"  on(event: \"message\", listener: (data: string | Uint8Array, isBinary: boolean) => void): this;",
+			"  on(event: \"close\", listener: (code: number, reason: string) => void): this;",
+			"  once(event: \"message\", listener: (data: string | Uint8Array, isBinary: boolean) => void): this;",
+			"  once(event: \"close\", listener: (code: number, reason: string) => void): this;",
+			"  off(event: string, listener: (...args: any[]) => void): this;",
+			"  removeAllListeners(event?: string): this;",
+			"  listenerCount(event: string): number;",
+			"}",
 			"export interface PlannedContext {",
 			"  request: Request;",
 			"  auth: AuthInfo;",
@@ -107,6 +126,7 @@ func (r *Registrar) TypeScriptModule() *spec.Module {
 			"  resource(name: string): ResourceRef | null;",
 			"  action: string;",
 			"  routeName: string;",
+			"  signal: RequestAbortSignal;",
 			"}",
 			"export interface AuthInfo { method: \"none\" | \"session\" | \"apiToken\" | \"accessToken\" | string; principalKind?: \"user\" | \"agent\" | \"service\" | string; principalId?: string; credentialId?: string; credentialHint?: string; scopes: string[]; }",
 			"export interface Actor { id: string; kind: string; tenantIds?: string[]; claims?: Record<string, unknown>; }",
@@ -123,6 +143,22 @@ func (r *Registrar) TypeScriptModule() *spec.Module {
 			"  ip: string;",
 			"  body: unknown;",
 			"  rawBody: string;",
+			"  files: UploadedFile[];",
+			"  stream(): BodyStream;",
+			"}",
+			"export interface UploadedFile {",
+			"  field: string;",
+			"  filename: string;",
+			"  contentType: string;",
+			"  size: number;",
+			"  path: string;",
+			"  bytes(): Uint8Array;",
+			"  text(): string;",
+			"}",
+			"export interface BodyStream {",
+			"  read(size?: number): Promise<Uint8Array | null>;",
+			"  text(): Promise<string>;",
+			"  cancel(): void;",
 			"}",
 			"export interface Session { id: string; isNew: boolean; cookieName: string; }",
 			"export interface Response {",
@@ -134,7 +170,28 @@ func (r *Registrar) TypeScriptModule() *spec.Module {
 			"  html(value: unknown): void;",
 			"  redirect(url: string): void;",
 			"  redirect(status: number, url: string): void;",
-			"  end(): void;",
+			"  end(chunk?: string | Uint8Array): void;",
+			"  write(chunk: string | Uint8Array): boolean;",
+			"  flush(): void;",
+			"  sse(): ServerSentEvents;",
+			"  readonly signal: RequestAbortSignal;",
+			"}",
+			"export interface ServerSentEventOptions { event?: string; id?: string; retry?: number; }",
+			"export interface ServerSentEvents {",
+			"  send(data: unknown, options?: ServerSentEventOptions): ServerSentEvents;",
+			"  event(name: string, data: unknown): ServerSentEvents;",
+			"  comment(text: string): ServerSentEvents;",
+			"  retry(ms: number): ServerSentEvents;",
+			"  close(): void;",
+			"  readonly signal: RequestAbortSignal;",
+			"}",
+			"export interface RequestAbortSignal {",
+			"  readonly aborted: boolean;",
+			"  readonly reason: string | undefined;",
+			"  onabort: ((event: { type: \"abort\" }) => void) | null;",
+			"  addEventListener(type: \"abort\", listener: (event: { type: \"abort\" }) => void): void;",
+			"  removeEventListener(type: \"abort\", listener: (event: { type: \"abort\" }) => void): void;",
+			"  throwIfAborted(): void;",
 			"}",
 		},
 	}
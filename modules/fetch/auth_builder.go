@@ -10,6 +10,8 @@ import (
 	"sync"
 
 	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/pkg/jserrors"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimebridge"
 )
 
 type builderStore struct {
@@ -30,14 +32,16 @@ func (noneCredential) redacted() string                           { return "none
 
 type bearerCredential struct {
 	policy   Policy
+	services runtimebridge.RuntimeServices
 	token    string
 	envName  string
 	filePath string
 	jsonPath string
+	provider goja.Callable
 }
 
-func (c *bearerCredential) apply(_ context.Context, req *http.Request) error {
-	token, err := c.resolve()
+func (c *bearerCredential) apply(ctx context.Context, req *http.Request) error {
+	token, err := c.resolve(ctx)
 	if err != nil {
 		return err
 	}
@@ -47,14 +51,19 @@ func (c *bearerCredential) apply(_ context.Context, req *http.Request) error {
 
 func (c *bearerCredential) redacted() string { return "bearer(<redacted>)" }
 
-func (c *bearerCredential) resolve() (string, error) {
+func (c *bearerCredential) resolve(ctx context.Context) (string, error) {
 	switch {
+	case c.provider != nil:
+		return c.resolveProvider(ctx)
 	case strings.TrimSpace(c.token) != "":
 		return strings.TrimSpace(c.token), nil
 	case strings.TrimSpace(c.envName) != "":
 		if err := c.policy.CheckCredentialEnv(c.envName); err != nil {
 			return "", err
 		}
+		if err := c.services.Sandbox.CheckEnv(c.envName); err != nil {
+			return "", err
+		}
 		value := strings.TrimSpace(os.Getenv(c.envName))
 		if value == "" {
 			return "", fmt.Errorf("credential env var %q is empty", c.envName)
@@ -64,6 +73,9 @@ func (c *bearerCredential) resolve() (string, error) {
 		if err := c.policy.CheckCredentialFile(c.filePath); err != nil {
 			return "", err
 		}
+		if err := c.services.Sandbox.CheckRead(c.filePath); err != nil {
+			return "", err
+		}
 		data, err := os.ReadFile(c.filePath)
 		if err != nil {
 			return "", fmt.Errorf("read credential file %q: %w", c.filePath, err)
@@ -85,32 +97,80 @@ func (c *bearerCredential) resolve() (string, error) {
 	}
 }
 
+// resolveProvider runs the JavaScript token provider on the owner loop. It is
+// called from the request goroutine, so an async provider is awaited through
+// promise reactions instead of blocking the owner.
+func (c *bearerCredential) resolveProvider(ctx context.Context) (string, error) {
+	ret, err := c.services.CallWithCustomContext(ctx, "fetch.auth.provider", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		value, err := c.provider(goja.Undefined())
+		if err != nil {
+			return nil, err
+		}
+		if promise, ok := value.Export().(*goja.Promise); ok {
+			return promise, nil
+		}
+		return value, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("bearer token provider: %w", err)
+	}
+	value, _ := ret.(goja.Value)
+	if promise, ok := ret.(*goja.Promise); ok {
+		settlement, err := runtimebridge.AwaitPromise(ctx, c.services.Owner, "fetch.auth.provider.await", promise)
+		if err != nil {
+			return "", fmt.Errorf("bearer token provider: %w", err)
+		}
+		if settlement.Rejected() {
+			return "", fmt.Errorf("bearer token provider: %w", jserrors.Rejected(settlement.Value))
+		}
+		value = settlement.Value
+	}
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) || strings.TrimSpace(value.String()) == "" {
+		return "", fmt.Errorf("bearer token provider returned an empty token")
+	}
+	return strings.TrimSpace(value.String()), nil
+}
+
 func (s *builderStore) newNoneAuth(vm *goja.Runtime) *goja.Object {
 	obj := vm.NewObject()
 	s.credentials.Store(obj, noneCredential{})
 	return obj
 }
 
-func (s *builderStore) newBearerAuth(vm *goja.Runtime, policy Policy) *goja.Object {
-	cred := &bearerCredential{policy: policy}
+func (s *builderStore) newBearerAuth(vm *goja.Runtime, policy Policy, services runtimebridge.RuntimeServices) *goja.Object {
+	cred := &bearerCredential{policy: policy, services: services}
 	obj := vm.NewObject()
 	s.credentials.Store(obj, cred)
 	_ = obj.Set("token", func(value string) *goja.Object {
 		cred.token = strings.TrimSpace(value)
 		cred.envName = ""
 		cred.filePath = ""
+		cred.provider = nil
 		return obj
 	})
 	_ = obj.Set("fromEnv", func(name string) *goja.Object {
 		cred.envName = strings.TrimSpace(name)
 		cred.token = ""
 		cred.filePath = ""
+		cred.provider = nil
 		return obj
 	})
 	_ = obj.Set("fromFile", func(path string) *goja.Object {
 		cred.filePath = strings.TrimSpace(path)
 		cred.token = ""
 		cred.envName = ""
+		cred.provider = nil
+		return obj
+	})
+	_ = obj.Set("fromProvider", func(value goja.Value) *goja.Object {
+		provider, ok := goja.AssertFunction(value)
+		if !ok {
+			panic(vm.NewTypeError("fetch.auth.bearer().fromProvider(fn) expects a function"))
+		}
+		cred.provider = provider
+		cred.token = ""
+		cred.envName = ""
+		cred.filePath = ""
 		return obj
 	})
 	_ = obj.Set("jsonPath", func(path string) *goja.Object {
@@ -0,0 +1,311 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/buffer"
+	"github.com/go-go-golems/go-go-goja/modules"
+	"github.com/go-go-golems/go-go-goja/modules/events"
+	"github.com/go-go-golems/go-go-goja/modules/stream"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimebridge"
+	"github.com/go-go-golems/go-go-goja/pkg/sandbox"
+)
+
+// body is the payload of a Request or Response. The data stays in reader
+// until it is consumed: text(), json(), arrayBuffer(), bytes() and blob()
+// read it into memory up to limit bytes, while the body property exposes it
+// as a stream.Readable without a size limit. It is not goroutine-safe.
+type body struct {
+	vm       *goja.Runtime
+	services runtimebridge.RuntimeServices
+	reader   io.ReadCloser
+	limit    int64
+	used     bool
+	stream   *goja.Object
+	// signal aborts reads of a fetched response; done detaches it once the
+	// body has been read or closed.
+	signal *events.AbortSignal
+	done   func()
+}
+
+// bodyFromValue converts a Request or Response body init: a string, Buffer,
+// ArrayBuffer, typed array, or stream.Readable. Readables are sent as they
+// are read instead of being buffered.
+func bodyFromValue(vm *goja.Runtime, value goja.Value) (io.ReadCloser, error) {
+	if !modules.Present(value) {
+		return nil, nil
+	}
+	if r, ok := stream.NewReader(vm, value); ok {
+		return r, nil
+	}
+	if _, ok := value.(*goja.Object); ok {
+		if _, isFn := goja.AssertFunction(value.ToObject(vm).Get("pipe")); isFn {
+			return nil, fmt.Errorf("fetch body streams must be stream.Readable instances")
+		}
+	}
+	return memoryBody{bytes.NewReader(buffer.DecodeBytes(vm, value, goja.Undefined()))}, nil
+}
+
+// memoryBody is a body that is already in memory, which lets requests send
+// it with a Content-Length instead of chunked encoding.
+type memoryBody struct {
+	*bytes.Reader
+}
+
+func (memoryBody) Close() error { return nil }
+
+func (b *body) finish() {
+	if b.done != nil {
+		b.done()
+		b.done = nil
+	}
+}
+
+// close releases the body without reading it.
+func (b *body) close() {
+	if b.reader != nil && b.stream == nil {
+		_ = b.reader.Close()
+	}
+	b.finish()
+}
+
+// streamValue returns the body as a stream.Readable, or null when there is
+// no body. The stream is created on first access.
+func (b *body) streamValue() goja.Value {
+	if b == nil || b.reader == nil {
+		return goja.Null()
+	}
+	if b.stream == nil {
+		b.stream = stream.NewReadable(b.vm, b.reader, stream.Options{})
+		if b.signal != nil {
+			remove := b.signal.OnAbort(func(reason goja.Value) {
+				if destroy, ok := goja.AssertFunction(b.stream.Get("destroy")); ok {
+					_, _ = destroy(b.stream, reason)
+				}
+			})
+			done := b.done
+			b.done = func() {
+				remove()
+				if done != nil {
+					done()
+				}
+			}
+		}
+		if on, ok := goja.AssertFunction(b.stream.Get("on")); ok {
+			_, _ = on(b.stream, b.vm.ToValue("close"), b.vm.ToValue(func(goja.FunctionCall) goja.Value {
+				b.finish()
+				return goja.Undefined()
+			}))
+		}
+	}
+	return b.stream
+}
+
+// consume reads the whole body and resolves with convert(data).
+func (b *body) consume(convert func([]byte) (goja.Value, error)) goja.Value {
+	vm := b.vm
+	if b.used {
+		return rejectedPromiseValue(vm, vm.NewTypeError("Body is unusable: Body has already been read"))
+	}
+	b.used = true
+	promise, resolve, reject := vm.NewPromise()
+	settle := func(data []byte, err error) {
+		b.finish()
+		if err != nil {
+			_ = reject(b.errorValue(err))
+			return
+		}
+		value, convertErr := convert(data)
+		if convertErr != nil {
+			_ = reject(vm.NewGoError(convertErr))
+			return
+		}
+		_ = resolve(value)
+	}
+	switch {
+	case b.reader == nil:
+		settle(nil, nil)
+	case b.stream != nil:
+		b.collectStream(settle)
+	default:
+		reader := b.reader
+		go func() {
+			data, err := readLimited(reader, b.limit)
+			_ = reader.Close()
+			_ = b.services.PostWithLifetimeContext("fetch.body", func(context.Context, *goja.Runtime) {
+				settle(data, err)
+			})
+		}()
+	}
+	return vm.ToValue(promise)
+}
+
+// collectStream reads a body whose stream was already handed out.
+func (b *body) collectStream(settle func([]byte, error)) {
+	vm := b.vm
+	var data []byte
+	settled := false
+	once := func(err error) {
+		if !settled {
+			settled = true
+			settle(data, err)
+		}
+	}
+	on, _ := goja.AssertFunction(b.stream.Get("on"))
+	_, _ = on(b.stream, vm.ToValue("data"), vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		data = append(data, buffer.DecodeBytes(vm, call.Argument(0), goja.Undefined())...)
+		if int64(len(data)) > b.limit {
+			once(limitError(b.limit))
+			if destroy, ok := goja.AssertFunction(b.stream.Get("destroy")); ok {
+				_, _ = destroy(b.stream)
+			}
+		}
+		return goja.Undefined()
+	}))
+	_, _ = on(b.stream, vm.ToValue("end"), vm.ToValue(func(goja.FunctionCall) goja.Value {
+		once(nil)
+		return goja.Undefined()
+	}))
+	_, _ = on(b.stream, vm.ToValue("error"), vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		once(&jsError{value: call.Argument(0)})
+		return goja.Undefined()
+	}))
+	if resume, ok := goja.AssertFunction(b.stream.Get("resume")); ok {
+		_, _ = resume(b.stream)
+	}
+}
+
+// errorValue maps a read error to the rejection reason; reads cut short by an
+// abort reject with the signal's reason.
+func (b *body) errorValue(err error) goja.Value {
+	if b.signal != nil && b.signal.Aborted() {
+		return b.signal.Reason()
+	}
+	if jsErr, ok := err.(*jsError); ok {
+		return jsErr.value
+	}
+	return sandbox.JSError(b.vm, err)
+}
+
+// tee splits the unread body into two bodies that each see all of its data,
+// for clone().
+func (b *body) tee() (*body, error) {
+	if b.used {
+		return nil, fmt.Errorf("body has already been consumed")
+	}
+	if b.stream != nil {
+		return nil, fmt.Errorf("cannot clone a body whose stream has been accessed")
+	}
+	clone := *b
+	clone.done = nil
+	if b.reader != nil {
+		b.reader, clone.reader = teeReader(b.reader)
+	}
+	return &clone, nil
+}
+
+// jsError carries a JavaScript rejection reason through Go error plumbing.
+type jsError struct {
+	value goja.Value
+}
+
+func (e *jsError) Error() string { return e.value.String() }
+
+func limitError(limit int64) error {
+	return fmt.Errorf("fetch response body exceeds configured limit of %d bytes", limit)
+}
+
+// readLimited reads r to the end, failing once more than limit bytes arrive.
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, limitError(limit)
+	}
+	return data, nil
+}
+
+// teeSource feeds the two branches returned by teeReader. Whichever branch
+// reads ahead pulls from the source; data is buffered for the other branch
+// until it catches up or is closed.
+type teeSource struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	src     io.ReadCloser
+	pending [2][]byte
+	closed  [2]bool
+	reading bool
+	err     error
+}
+
+type teeBranch struct {
+	t *teeSource
+	i int
+}
+
+func teeReader(src io.ReadCloser) (io.ReadCloser, io.ReadCloser) {
+	t := &teeSource{src: src}
+	t.cond = sync.NewCond(&t.mu)
+	return &teeBranch{t: t, i: 0}, &teeBranch{t: t, i: 1}
+}
+
+func (b *teeBranch) Read(p []byte) (int, error) {
+	t := b.t
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for {
+		if t.closed[b.i] {
+			return 0, io.ErrClosedPipe
+		}
+		if len(t.pending[b.i]) > 0 {
+			n := copy(p, t.pending[b.i])
+			t.pending[b.i] = t.pending[b.i][n:]
+			return n, nil
+		}
+		if t.err != nil {
+			return 0, t.err
+		}
+		if t.reading {
+			t.cond.Wait()
+			continue
+		}
+		t.reading = true
+		t.mu.Unlock()
+		buf := make([]byte, 32*1024)
+		n, err := t.src.Read(buf)
+		t.mu.Lock()
+		t.reading = false
+		for i := range t.pending {
+			if !t.closed[i] {
+				t.pending[i] = append(t.pending[i], buf[:n]...)
+			}
+		}
+		if err != nil {
+			t.err = err
+		}
+		t.cond.Broadcast()
+	}
+}
+
+func (b *teeBranch) Close() error {
+	t := b.t
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed[b.i] {
+		return nil
+	}
+	t.closed[b.i] = true
+	t.pending[b.i] = nil
+	t.cond.Broadcast()
+	if t.closed[0] && t.closed[1] {
+		return t.src.Close()
+	}
+	return nil
+}
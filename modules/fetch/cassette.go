@@ -0,0 +1,363 @@
+package fetch
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CassetteMode selects whether a Cassette records live traffic or replays a
+// recording.
+type CassetteMode string
+
+const (
+	CassetteRecord CassetteMode = "record"
+	CassetteReplay CassetteMode = "replay"
+)
+
+// ParseCassetteMode parses "record" or "replay".
+func ParseCassetteMode(raw string) (CassetteMode, error) {
+	switch mode := CassetteMode(strings.ToLower(strings.TrimSpace(raw))); mode {
+	case CassetteRecord, CassetteReplay:
+		return mode, nil
+	case "":
+		return CassetteReplay, nil
+	default:
+		return "", fmt.Errorf("unknown cassette mode %q (want record or replay)", raw)
+	}
+}
+
+// CassetteMatch selects the request fields compared when replaying.
+type CassetteMatch struct {
+	Method  bool
+	URL     bool
+	Body    bool
+	Headers []string
+}
+
+// DefaultCassetteMatch matches requests on method and URL.
+var DefaultCassetteMatch = CassetteMatch{Method: true, URL: true}
+
+// ParseCassetteMatch parses a comma-separated list such as
+// "method,url,body,header:X-Tenant".
+func ParseCassetteMatch(raw string) (CassetteMatch, error) {
+	if strings.TrimSpace(raw) == "" {
+		return DefaultCassetteMatch, nil
+	}
+	var match CassetteMatch
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		switch {
+		case part == "":
+		case strings.EqualFold(part, "method"):
+			match.Method = true
+		case strings.EqualFold(part, "url"):
+			match.URL = true
+		case strings.EqualFold(part, "body"):
+			match.Body = true
+		case strings.HasPrefix(strings.ToLower(part), "header:"):
+			match.Headers = append(match.Headers, strings.TrimSpace(part[len("header:"):]))
+		default:
+			return CassetteMatch{}, fmt.Errorf("unknown cassette match field %q (want method, url, body or header:<name>)", part)
+		}
+	}
+	return match, nil
+}
+
+// DefaultRedactedHeaders are replaced with "[REDACTED]" before an interaction
+// is written, so recordings do not leak credentials.
+var DefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+const redacted = "[REDACTED]"
+
+// Interaction is one recorded request and its response.
+type Interaction struct {
+	Request  RecordedRequest  `yaml:"request"`
+	Response RecordedResponse `yaml:"response"`
+}
+
+type RecordedRequest struct {
+	Method     string              `yaml:"method"`
+	URL        string              `yaml:"url"`
+	Headers    map[string][]string `yaml:"headers,omitempty"`
+	Body       string              `yaml:"body,omitempty"`
+	BodyBase64 string              `yaml:"bodyBase64,omitempty"`
+}
+
+type RecordedResponse struct {
+	Status     int                 `yaml:"status"`
+	StatusText string              `yaml:"statusText,omitempty"`
+	Headers    map[string][]string `yaml:"headers,omitempty"`
+	Body       string              `yaml:"body,omitempty"`
+	BodyBase64 string              `yaml:"bodyBase64,omitempty"`
+}
+
+type cassetteFile struct {
+	Version      int           `yaml:"version"`
+	Interactions []Interaction `yaml:"interactions"`
+}
+
+// Cassette is an http.RoundTripper that records traffic to a YAML file or
+// replays it. Plug it in with WithHTTPClient(cassette.Client()); the origin
+// policy and sandbox still check every request before it reaches the
+// cassette. In replay mode each interaction is served once, in recorded
+// order, and a request with no unplayed match fails.
+type Cassette struct {
+	path     string
+	mode     CassetteMode
+	match    CassetteMatch
+	next     http.RoundTripper
+	redacted []string
+	maxBody  int64
+
+	mu           sync.Mutex
+	interactions []Interaction
+	played       []bool
+}
+
+type CassetteOption func(*Cassette)
+
+// WithCassetteMatch sets the request fields compared during replay.
+func WithCassetteMatch(match CassetteMatch) CassetteOption {
+	return func(c *Cassette) { c.match = match }
+}
+
+// WithCassetteTransport sets the transport used to reach the network while
+// recording. It defaults to http.DefaultTransport.
+func WithCassetteTransport(next http.RoundTripper) CassetteOption {
+	return func(c *Cassette) { c.next = next }
+}
+
+// WithCassetteMaxBodyBytes caps the response bodies recorded from the
+// network. It defaults to DefaultMaxResponseBytes; pass the fetch module's
+// Policy.MaxResponseBytes when it differs.
+func WithCassetteMaxBodyBytes(limit int64) CassetteOption {
+	return func(c *Cassette) {
+		if limit > 0 {
+			c.maxBody = limit
+		}
+	}
+}
+
+// WithCassetteRedactedHeaders replaces DefaultRedactedHeaders.
+func WithCassetteRedactedHeaders(names ...string) CassetteOption {
+	return func(c *Cassette) { c.redacted = append([]string(nil), names...) }
+}
+
+// OpenCassette opens the cassette at path. Record mode starts a new recording
+// and rewrites the file after each interaction; replay mode loads the file,
+// which must exist.
+func OpenCassette(path string, mode CassetteMode, opts ...CassetteOption) (*Cassette, error) {
+	c := &Cassette{path: path, mode: mode, match: DefaultCassetteMatch, next: http.DefaultTransport, redacted: DefaultRedactedHeaders, maxBody: DefaultMaxResponseBytes}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(c)
+		}
+	}
+	switch mode {
+	case CassetteRecord:
+		if err := c.save(); err != nil {
+			return nil, err
+		}
+	case CassetteReplay:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read fetch cassette: %w", err)
+		}
+		var file cassetteFile
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("parse fetch cassette %s: %w", path, err)
+		}
+		c.interactions = file.Interactions
+		c.played = make([]bool, len(file.Interactions))
+	default:
+		return nil, fmt.Errorf("unknown cassette mode %q", mode)
+	}
+	return c, nil
+}
+
+// Client returns an http.Client that sends requests through the cassette.
+func (c *Cassette) Client() *http.Client {
+	return &http.Client{Transport: c}
+}
+
+// Unplayed returns the recorded interactions that replay has not served yet.
+func (c *Cassette) Unplayed() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []Interaction
+	for i, played := range c.played {
+		if !played {
+			out = append(out, c.interactions[i])
+		}
+	}
+	return out
+}
+
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recorded := RecordedRequest{Method: req.Method, URL: req.URL.String(), Headers: c.redact(req.Header)}
+	recorded.Body, recorded.BodyBase64 = encodeBody(body)
+	if c.mode == CassetteRecord {
+		return c.record(req, recorded, body)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, interaction := range c.interactions {
+		if !c.played[i] && c.matches(interaction.Request, recorded) {
+			c.played[i] = true
+			return replayResponse(req, interaction.Response)
+		}
+	}
+	return nil, fmt.Errorf("fetch cassette %s has no unplayed interaction matching %s %s", c.path, req.Method, req.URL)
+}
+
+func (c *Cassette) record(req *http.Request, recorded RecordedRequest, body []byte) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	out.Body, out.ContentLength = http.NoBody, 0
+	if len(body) > 0 {
+		out.Body, out.ContentLength = io.NopCloser(bytes.NewReader(body)), int64(len(body))
+	}
+	resp, err := c.next.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readLimited(resp.Body, c.maxBody)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	resp.ContentLength = int64(len(data))
+	interaction := Interaction{Request: recorded, Response: RecordedResponse{
+		Status:     resp.StatusCode,
+		StatusText: reasonPhrase(resp),
+		Headers:    c.redact(resp.Header),
+	}}
+	interaction.Response.Body, interaction.Response.BodyBase64 = encodeBody(data)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, interaction)
+	if err := c.save(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Cassette) matches(recorded, live RecordedRequest) bool {
+	if c.match.Method && !strings.EqualFold(recorded.Method, live.Method) {
+		return false
+	}
+	if c.match.URL && recorded.URL != live.URL {
+		return false
+	}
+	if c.match.Body && (recorded.Body != live.Body || recorded.BodyBase64 != live.BodyBase64) {
+		return false
+	}
+	for _, name := range c.match.Headers {
+		if strings.Join(http.Header(recorded.Headers).Values(name), ",") != strings.Join(http.Header(live.Headers).Values(name), ",") {
+			return false
+		}
+	}
+	return true
+}
+
+// redact copies header with the configured headers masked.
+func (c *Cassette) redact(header http.Header) map[string][]string {
+	if len(header) == 0 {
+		return nil
+	}
+	out := header.Clone()
+	for _, name := range c.redacted {
+		if _, ok := out[http.CanonicalHeaderKey(name)]; ok {
+			out.Set(name, redacted)
+		}
+	}
+	return out
+}
+
+// save writes the cassette through a temporary file so an interrupted
+// recording never leaves a truncated file behind.
+func (c *Cassette) save() error {
+	data, err := yaml.Marshal(cassetteFile{Version: 1, Interactions: c.interactions})
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(c.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("write fetch cassette: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".cassette-*")
+	if err != nil {
+		return fmt.Errorf("write fetch cassette: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write fetch cassette: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write fetch cassette: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("write fetch cassette: %w", err)
+	}
+	return nil
+}
+
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	defer req.Body.Close()
+	return io.ReadAll(req.Body)
+}
+
+func encodeBody(data []byte) (text, encoded string) {
+	if utf8.Valid(data) {
+		return string(data), ""
+	}
+	return "", base64.StdEncoding.EncodeToString(data)
+}
+
+func replayResponse(req *http.Request, recorded RecordedResponse) (*http.Response, error) {
+	data := []byte(recorded.Body)
+	if recorded.BodyBase64 != "" {
+		decoded, err := base64.StdEncoding.DecodeString(recorded.BodyBase64)
+		if err != nil {
+			return nil, fmt.Errorf("fetch cassette response body: %w", err)
+		}
+		data = decoded
+	}
+	header := http.Header(recorded.Headers).Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	statusText := recorded.StatusText
+	if statusText == "" {
+		statusText = http.StatusText(recorded.Status)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, statusText),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}, nil
+}
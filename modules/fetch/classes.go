@@ -0,0 +1,117 @@
+package fetch
+
+import (
+	"fmt"
+
+	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimebridge"
+)
+
+// classes holds the per-runtime Headers, Request and Response constructors,
+// so every module instance and the responses it creates share prototypes.
+type classes struct {
+	headers  *goja.Object
+	request  *goja.Object
+	response *goja.Object
+}
+
+var classesKey = goja.NewSymbol("go-go-goja.fetch.classes")
+
+// constructedBodyLimit bounds text()/json() reads of bodies built in
+// JavaScript with new Request or new Response. Policy.MaxResponseBytes only
+// guards data that comes from the network.
+const constructedBodyLimit = 1 << 40
+
+func classesFor(vm *goja.Runtime) *classes {
+	global := vm.GlobalObject()
+	if existing, ok := global.GetSymbol(classesKey).(*goja.Object); ok {
+		if c, ok := existing.Export().(*classes); ok {
+			return c
+		}
+	}
+	services, ok := runtimebridge.Lookup(vm)
+	if !ok || services.Owner == nil {
+		panic(vm.NewGoError(fmt.Errorf("fetch module requires runtime services")))
+	}
+	c := &classes{}
+	if err := global.DefineDataPropertySymbol(classesKey, vm.ToValue(c), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("fetch: cache classes: %w", err)))
+	}
+	c.headers = newHeadersClass(vm)
+	c.request = newRequestClass(vm, services)
+	c.response = newResponseClass(vm, services)
+	return c
+}
+
+// installBody adds the body mixin shared by Request and Response.
+func installBody(vm *goja.Runtime, proto *goja.Object, bodyOf func(goja.Value) *body) {
+	defineAccessor(vm, proto, "body", func(this goja.Value) goja.Value {
+		return bodyOf(this).streamValue()
+	})
+	defineAccessor(vm, proto, "bodyUsed", func(this goja.Value) goja.Value {
+		return vm.ToValue(bodyOf(this).used)
+	})
+	mustSet(vm, proto, "text", func(call goja.FunctionCall) goja.Value {
+		return bodyOf(call.This).consume(func(data []byte) (goja.Value, error) {
+			return vm.ToValue(string(data)), nil
+		})
+	})
+	mustSet(vm, proto, "json", func(call goja.FunctionCall) goja.Value {
+		return bodyOf(call.This).consume(func(data []byte) (goja.Value, error) {
+			return decodeJSON(vm, data)
+		})
+	})
+	mustSet(vm, proto, "arrayBuffer", func(call goja.FunctionCall) goja.Value {
+		return bodyOf(call.This).consume(func(data []byte) (goja.Value, error) {
+			return vm.ToValue(vm.NewArrayBuffer(data)), nil
+		})
+	})
+	mustSet(vm, proto, "bytes", func(call goja.FunctionCall) goja.Value {
+		return bodyOf(call.This).consume(func(data []byte) (goja.Value, error) {
+			return uint8Array(vm, data), nil
+		})
+	})
+	mustSet(vm, proto, "blob", func(call goja.FunctionCall) goja.Value {
+		contentType := ""
+		if h, ok := headersOf(call.This.ToObject(vm).Get("headers")); ok {
+			contentType, _ = h.get("content-type")
+		}
+		return bodyOf(call.This).consume(func(data []byte) (goja.Value, error) {
+			return newBlob(vm, data, contentType), nil
+		})
+	})
+}
+
+// newBlob returns a read-only Blob-like view of data: size, type, text(),
+// arrayBuffer() and bytes().
+func newBlob(vm *goja.Runtime, data []byte, contentType string) *goja.Object {
+	blob := vm.NewObject()
+	_ = blob.Set("size", len(data))
+	_ = blob.Set("type", contentType)
+	_ = blob.Set("text", func() goja.Value { return resolvedPromise(vm, string(data)) })
+	_ = blob.Set("arrayBuffer", func() goja.Value { return resolvedPromise(vm, vm.NewArrayBuffer(data)) })
+	_ = blob.Set("bytes", func() goja.Value { return resolvedPromiseValue(vm, uint8Array(vm, data)) })
+	return blob
+}
+
+func uint8Array(vm *goja.Runtime, data []byte) goja.Value {
+	ctor, _ := vm.Get("Uint8Array").(*goja.Object)
+	array, err := vm.New(ctor, vm.ToValue(vm.NewArrayBuffer(data)))
+	if err != nil {
+		panic(err)
+	}
+	return array
+}
+
+func defineAccessor(vm *goja.Runtime, proto *goja.Object, name string, get func(this goja.Value) goja.Value) {
+	getter := vm.ToValue(func(call goja.FunctionCall) goja.Value { return get(call.This) })
+	if err := proto.DefineAccessorProperty(name, getter, nil, goja.FLAG_FALSE, goja.FLAG_TRUE); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("fetch: define %s: %w", name, err)))
+	}
+}
+
+func mustSet(vm *goja.Runtime, obj *goja.Object, name string, value any) {
+	if err := obj.Set(name, value); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("fetch: set %s: %w", name, err)))
+	}
+}
@@ -9,7 +9,9 @@ import (
 
 	"github.com/dop251/goja"
 	"github.com/dop251/goja_nodejs/buffer"
+	"github.com/go-go-golems/go-go-goja/modules"
 	"github.com/go-go-golems/go-go-goja/pkg/runtimebridge"
+	"github.com/google/uuid"
 )
 
 type clientState struct {
@@ -18,6 +20,11 @@ type clientState struct {
 	headers     map[string]string
 	credential  credentialSource
 	expectation expectation
+	retry       *retryPolicy
+	breaker     *breakerPolicy
+	// idempotencyHeader is set by idempotencyKeys(); POST and PATCH requests
+	// then carry a generated key that stays the same across retries.
+	idempotencyHeader string
 }
 
 func (m Module) newClientBuilder(vm *goja.Runtime, runtimeServices runtimebridge.RuntimeServices, store *builderStore) *goja.Object {
@@ -27,14 +34,37 @@ func (m Module) newClientBuilder(vm *goja.Runtime, runtimeServices runtimebridge
 		state.baseURL = strings.TrimRight(strings.TrimSpace(raw), "/")
 		return obj
 	})
-	_ = obj.Set("timeout", func(raw string) goja.Value {
-		d, err := time.ParseDuration(strings.TrimSpace(raw))
+	_ = obj.Set("timeout", func(value goja.Value) goja.Value {
+		d, err := modules.DurationValue(value)
 		if err != nil {
-			panic(vm.NewGoError(fmt.Errorf("fetch.client().timeout(%q): %w", raw, err)))
+			panic(vm.NewGoError(fmt.Errorf("fetch.client().timeout(%q): %w", value.String(), err)))
 		}
 		state.timeout = d
 		return obj
 	})
+	_ = obj.Set("retry", func(value goja.Value) *goja.Object {
+		policy, err := retryPolicyFromValue(vm, value)
+		if err != nil {
+			panic(vm.NewGoError(fmt.Errorf("fetch.client().retry: %w", err)))
+		}
+		state.retry = policy
+		return obj
+	})
+	_ = obj.Set("circuitBreaker", func(value goja.Value) *goja.Object {
+		policy, err := breakerPolicyFromValue(vm, value)
+		if err != nil {
+			panic(vm.NewGoError(fmt.Errorf("fetch.client().circuitBreaker: %w", err)))
+		}
+		state.breaker = policy
+		return obj
+	})
+	_ = obj.Set("idempotencyKeys", func(header goja.Value) *goja.Object {
+		state.idempotencyHeader = IdempotencyKeyHeader
+		if modules.Present(header) {
+			state.idempotencyHeader = strings.TrimSpace(header.String())
+		}
+		return obj
+	})
 	_ = obj.Set("header", func(name, value string) *goja.Object {
 		state.headers[strings.TrimSpace(name)] = value
 		return obj
@@ -71,7 +101,16 @@ func (m Module) newClientBuilder(vm *goja.Runtime, runtimeServices runtimebridge
 }
 
 func (m Module) newRequestBuilder(vm *goja.Runtime, runtimeServices runtimebridge.RuntimeServices, client *clientState, method, path string) *goja.Object {
-	spec := requestSpec{Method: method, Headers: cloneStringMap(client.headers), Timeout: client.timeout, Expectation: client.expectation, Credential: client.credential}
+	spec := requestSpec{
+		Method:            method,
+		Headers:           cloneStringMap(client.headers),
+		Timeout:           client.timeout,
+		Expectation:       client.expectation,
+		Credential:        client.credential,
+		Retry:             client.retry,
+		Breaker:           client.breaker,
+		IdempotencyHeader: client.idempotencyHeader,
+	}
 	resolved, err := resolveURL(client.baseURL, path)
 	if err != nil {
 		// Defer the error to run() so construction stays chainable.
@@ -111,6 +150,22 @@ func (m Module) newRequestBuilder(vm *goja.Runtime, runtimeServices runtimebridg
 		spec.Body = buffer.DecodeBytes(vm, value, goja.Undefined())
 		return obj
 	})
+	_ = obj.Set("retry", func(value goja.Value) *goja.Object {
+		policy, err := retryPolicyFromValue(vm, value)
+		if err != nil {
+			constructionErr = err
+			return obj
+		}
+		spec.Retry = policy
+		return obj
+	})
+	_ = obj.Set("idempotencyKey", func(key string) *goja.Object {
+		if spec.IdempotencyHeader == "" {
+			spec.IdempotencyHeader = IdempotencyKeyHeader
+		}
+		spec.Headers[spec.IdempotencyHeader] = key
+		return obj
+	})
 	_ = obj.Set("expectJson", func() *goja.Object { spec.Expectation = expectationJSON; return obj })
 	_ = obj.Set("expectText", func() *goja.Object { spec.Expectation = expectationText; return obj })
 	_ = obj.Set("expectResponse", func() *goja.Object { spec.Expectation = expectationResponse; return obj })
@@ -118,7 +173,12 @@ func (m Module) newRequestBuilder(vm *goja.Runtime, runtimeServices runtimebridg
 		if constructionErr != nil {
 			return rejectedPromise(vm, constructionErr)
 		}
-		return m.asyncExecute(vm, runtimeServices, spec, spec.Expectation)
+		run := spec
+		if run.IdempotencyHeader != "" && (strings.EqualFold(run.Method, "POST") || strings.EqualFold(run.Method, "PATCH")) && !hasHeader(run.Headers, run.IdempotencyHeader) {
+			run.Headers = cloneStringMap(spec.Headers)
+			run.Headers[run.IdempotencyHeader] = uuid.NewString()
+		}
+		return m.asyncExecute(vm, runtimeServices, run, run.Expectation)
 	})
 	return obj
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,17 +13,23 @@ import (
 	"time"
 
 	"github.com/dop251/goja"
-	"github.com/dop251/goja_nodejs/buffer"
 	"github.com/go-go-golems/go-go-goja/modules"
+	"github.com/go-go-golems/go-go-goja/modules/events"
 	"github.com/go-go-golems/go-go-goja/pkg/runtimebridge"
+	"github.com/go-go-golems/go-go-goja/pkg/sandbox"
 )
 
 type Option func(*Module)
 
 type Module struct {
-	name   string
-	policy Policy
-	client *http.Client
+	name    string
+	policy  Policy
+	client  *http.Client
+	sandbox *sandbox.Profile
+	// observer and breakers are shared by the copies Loader makes, so
+	// circuit breaker state spans every runtime that loads this Module.
+	observer Observer
+	breakers *breakerSet
 }
 
 var _ modules.NativeModule = (*Module)(nil)
@@ -39,6 +46,7 @@ func New(opts ...Option) *Module {
 	if m.client == nil {
 		m.client = &http.Client{}
 	}
+	m.breakers = newBreakerSet()
 	return m
 }
 
@@ -62,7 +70,7 @@ func (m *Module) Name() string {
 }
 
 func (m *Module) Doc() string {
-	return `The fetch module provides guarded outbound HTTP and a fluent authenticated API client for xgoja JavaScript.`
+	return `The fetch module provides guarded outbound HTTP with Headers, Request and Response classes, streaming bodies and AbortSignal cancellation, plus a fluent authenticated API client for xgoja JavaScript.`
 }
 
 func (m *Module) Loader(vm *goja.Runtime, moduleObj *goja.Object) {
@@ -74,19 +82,30 @@ func (m *Module) Loader(vm *goja.Runtime, moduleObj *goja.Object) {
 	if module.client == nil {
 		module.client = &http.Client{}
 	}
+	if module.breakers == nil {
+		module.breakers = newBreakerSet()
+	}
 	runtimeServices, ok := runtimebridge.Lookup(vm)
 	if !ok || runtimeServices.Owner == nil {
 		panic(vm.NewGoError(fmt.Errorf("fetch module requires runtime services")))
 	}
+	if runtimeServices.Sandbox != nil {
+		module.sandbox = runtimeServices.Sandbox
+		module.client = sandboxedClient(module.client, module.sandbox)
+	}
 	exports := moduleObj.Get("exports").(*goja.Object)
 	store := newBuilderStore()
 	modules.SetExport(exports, module.Name(), "fetch", func(call goja.FunctionCall) goja.Value {
-		spec, err := module.requestSpecFromFetchCall(vm, call)
+		spec, err := module.requestSpecFromFetchCall(vm, runtimeServices, call)
 		if err != nil {
 			return rejectedPromise(vm, err)
 		}
 		return module.asyncExecute(vm, runtimeServices, spec, expectationResponse)
 	})
+	c := classesFor(vm)
+	modules.SetExport(exports, module.Name(), "Headers", c.headers)
+	modules.SetExport(exports, module.Name(), "Request", c.request)
+	modules.SetExport(exports, module.Name(), "Response", c.response)
 	modules.SetExport(exports, module.Name(), "client", func() *goja.Object {
 		return module.newClientBuilder(vm, runtimeServices, store)
 	})
@@ -95,19 +114,27 @@ func (m *Module) Loader(vm *goja.Runtime, moduleObj *goja.Object) {
 		return store.newNoneAuth(vm)
 	})
 	modules.SetExport(authObj, module.Name()+".auth", "bearer", func() *goja.Object {
-		return store.newBearerAuth(vm, module.policy)
+		return store.newBearerAuth(vm, module.policy, runtimeServices)
 	})
 	modules.SetExport(exports, module.Name(), "auth", authObj)
 }
 
 type requestSpec struct {
-	Method      string
-	URL         string
-	Headers     map[string]string
-	Body        []byte
+	Method  string
+	URL     string
+	Headers map[string]string
+	Body    []byte
+	// BodyReader streams the request body when it is not held in Body.
+	BodyReader  io.ReadCloser
+	Signal      *events.AbortSignal
 	Timeout     time.Duration
 	Expectation expectation
 	Credential  credentialSource
+	// Retry and Breaker are set by client() builders; IdempotencyHeader names
+	// the header that makes POST and PATCH safe to retry.
+	Retry             *retryPolicy
+	Breaker           *breakerPolicy
+	IdempotencyHeader string
 }
 
 type responseData struct {
@@ -126,49 +153,86 @@ const (
 	expectationText
 )
 
-func (m Module) requestSpecFromFetchCall(vm *goja.Runtime, call goja.FunctionCall) (requestSpec, error) {
-	if len(call.Arguments) == 0 || goja.IsUndefined(call.Argument(0)) || goja.IsNull(call.Argument(0)) {
+// requestSpecFromFetchCall accepts the standard fetch(input, init) arguments,
+// where input is a URL or a Request, plus the json and timeout extensions.
+func (m Module) requestSpecFromFetchCall(vm *goja.Runtime, services runtimebridge.RuntimeServices, call goja.FunctionCall) (requestSpec, error) {
+	if !modules.Present(call.Argument(0)) {
 		return requestSpec{}, fmt.Errorf("fetch.fetch(url, options) requires a URL")
 	}
-	spec := requestSpec{Method: http.MethodGet, URL: call.Argument(0).String(), Headers: map[string]string{}, Expectation: expectationResponse}
-	if len(call.Arguments) < 2 || goja.IsUndefined(call.Argument(1)) || goja.IsNull(call.Argument(1)) {
+	req, err := newRequest(vm, services, call.Argument(0), call.Argument(1))
+	if err != nil {
+		return requestSpec{}, err
+	}
+	h, _ := headersOf(req.headers)
+	spec := requestSpec{Method: req.method, URL: req.url, Headers: h.flatten(), Signal: req.signal, Expectation: expectationResponse}
+	req.body.used = true
+	if memory, ok := req.body.reader.(memoryBody); ok {
+		spec.Body = make([]byte, memory.Len())
+		_, _ = memory.Read(spec.Body)
+	} else {
+		spec.BodyReader = req.body.reader
+	}
+	options, ok := call.Argument(1).(*goja.Object)
+	if !ok {
 		return spec, nil
 	}
-	options := call.Argument(1).ToObject(vm)
-	if method := options.Get("method"); method != nil && !goja.IsUndefined(method) && !goja.IsNull(method) {
-		spec.Method = method.String()
-	}
-	if headers := options.Get("headers"); headers != nil && !goja.IsUndefined(headers) && !goja.IsNull(headers) {
-		spec.Headers = headersFromValue(vm, headers)
-	}
-	if timeout := options.Get("timeout"); timeout != nil && !goja.IsUndefined(timeout) && !goja.IsNull(timeout) {
+	if timeout := options.Get("timeout"); modules.Present(timeout) {
 		d, err := time.ParseDuration(timeout.String())
 		if err != nil {
+			spec.close()
 			return requestSpec{}, fmt.Errorf("fetch timeout %q: %w", timeout.String(), err)
 		}
 		spec.Timeout = d
 	}
-	if jsonValue := options.Get("json"); jsonValue != nil && !goja.IsUndefined(jsonValue) && !goja.IsNull(jsonValue) {
+	if jsonValue := options.Get("json"); modules.Present(jsonValue) {
 		body, err := json.Marshal(jsonValue.Export())
 		if err != nil {
+			spec.close()
 			return requestSpec{}, fmt.Errorf("fetch json body: %w", err)
 		}
+		spec.close()
 		spec.Body = body
 		setDefaultHeader(spec.Headers, "Content-Type", "application/json")
 		setDefaultHeader(spec.Headers, "Accept", "application/json")
-		return spec, nil
-	}
-	if body := options.Get("body"); body != nil && !goja.IsUndefined(body) && !goja.IsNull(body) {
-		spec.Body = buffer.DecodeBytes(vm, body, goja.Undefined())
 	}
 	return spec, nil
 }
 
+// close releases a streaming body that will not be sent.
+func (spec *requestSpec) close() {
+	if spec.BodyReader != nil {
+		_ = spec.BodyReader.Close()
+		spec.BodyReader = nil
+	}
+}
+
+// execute sends the request and reads the whole response body, up to the
+// policy's MaxResponseBytes.
 func (m Module) execute(ctx context.Context, spec requestSpec) (responseData, error) {
+	resp, err := m.send(ctx, spec)
+	if err != nil {
+		return responseData{}, err
+	}
+	defer resp.Body.Close()
+	body, err := readLimited(resp.Body, m.policy.normalized().MaxResponseBytes)
+	if err != nil {
+		return responseData{}, err
+	}
+	return responseData{URL: resp.Request.URL.String(), Status: resp.StatusCode, StatusText: reasonPhrase(resp), Headers: cloneHeaders(resp.Header), Body: body}, nil
+}
+
+// open sends the request and returns the response with its body unread. The
+// request timeout covers reading the body too; closing the body releases it.
+func (m Module) open(ctx context.Context, spec requestSpec) (*http.Response, error) {
 	policy := m.policy.normalized()
 	u, err := policy.CheckURL(spec.URL)
 	if err != nil {
-		return responseData{}, err
+		spec.close()
+		return nil, err
+	}
+	if err := m.sandbox.CheckFetch(u); err != nil {
+		spec.close()
+		return nil, err
 	}
 	method := strings.ToUpper(strings.TrimSpace(spec.Method))
 	if method == "" {
@@ -178,15 +242,19 @@ func (m Module) execute(ctx context.Context, spec requestSpec) (responseData, er
 	if timeout <= 0 {
 		timeout = policy.Timeout
 	}
-	reqCtx := ctx
-	var cancel context.CancelFunc
+	reqCtx, cancel := context.WithCancel(ctx)
 	if timeout > 0 {
 		reqCtx, cancel = context.WithTimeout(ctx, timeout)
-		defer cancel()
 	}
-	req, err := http.NewRequestWithContext(reqCtx, method, u.String(), bytes.NewReader(spec.Body))
+	var reqBody io.Reader = bytes.NewReader(spec.Body)
+	if spec.BodyReader != nil {
+		reqBody = spec.BodyReader
+	}
+	req, err := http.NewRequestWithContext(reqCtx, method, u.String(), reqBody)
 	if err != nil {
-		return responseData{}, err
+		cancel()
+		spec.close()
+		return nil, err
 	}
 	for name, value := range spec.Headers {
 		if strings.TrimSpace(name) == "" {
@@ -196,7 +264,9 @@ func (m Module) execute(ctx context.Context, spec requestSpec) (responseData, er
 	}
 	if spec.Credential != nil {
 		if err := spec.Credential.apply(reqCtx, req); err != nil {
-			return responseData{}, err
+			cancel()
+			spec.close()
+			return nil, err
 		}
 	}
 	client := m.client
@@ -205,62 +275,125 @@ func (m Module) execute(ctx context.Context, spec requestSpec) (responseData, er
 	}
 	resp, err := client.Do(req)
 	if err != nil {
-		return responseData{}, redactError(err)
+		cancel()
+		return nil, redactError(err)
 	}
-	defer resp.Body.Close()
-	limit := policy.MaxResponseBytes
-	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
-	if err != nil {
-		return responseData{}, err
-	}
-	if int64(len(body)) > limit {
-		return responseData{}, fmt.Errorf("fetch response body exceeds configured limit of %d bytes", limit)
-	}
-	return responseData{URL: resp.Request.URL.String(), Status: resp.StatusCode, StatusText: resp.Status, Headers: cloneHeaders(resp.Header), Body: body}, nil
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: cancel}
+	return resp, nil
+}
+
+// releasingBody calls release once the body is closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
 }
 
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// asyncExecute runs spec off the owner goroutine and returns a Promise of the
+// result. Aborting spec.Signal cancels the request and rejects with the
+// signal's reason, including while a streamed response body is being read.
 func (m Module) asyncExecute(vm *goja.Runtime, runtimeServices runtimebridge.RuntimeServices, spec requestSpec, expect expectation) goja.Value {
+	if spec.Signal != nil && spec.Signal.Aborted() {
+		spec.close()
+		return rejectedPromiseValue(vm, spec.Signal.Reason())
+	}
 	promise, resolve, reject := vm.NewPromise()
 	callCtx := runtimebridge.CurrentOwnerContext(vm)
-	runtimeCtx := runtimeServices.Lifetime()
+	ctx, cancel := context.WithCancel(callCtx)
+	stopOnShutdown := context.AfterFunc(runtimeServices.Lifetime(), cancel)
+	release := func() {
+		stopOnShutdown()
+		cancel()
+	}
+	settled := false
+	detach := func() {}
+	if spec.Signal != nil {
+		detach = spec.Signal.OnAbort(func(reason goja.Value) {
+			cancel()
+			if !settled {
+				settled = true
+				_ = reject(reason)
+			}
+		})
+	}
 	go func() {
-		select {
-		case <-callCtx.Done():
-			return
-		case <-runtimeCtx.Done():
-			return
-		default:
-		}
-		data, err := m.execute(callCtx, spec)
-		if err != nil {
-			_ = runtimeServices.PostWithCustomContext(callCtx, "fetch.reject", func(context.Context, *goja.Runtime) {
-				_ = reject(vm.NewGoError(err))
+		if expect != expectationResponse {
+			data, err := m.execute(ctx, spec)
+			_ = runtimeServices.PostWithCustomContext(callCtx, "fetch.settle", func(context.Context, *goja.Runtime) {
+				release()
+				detach()
+				if settled {
+					return
+				}
+				settled = true
+				if err != nil {
+					_ = reject(errorValue(vm, err))
+					return
+				}
+				value, valueErr := responseValue(vm, data, expect)
+				if valueErr != nil {
+					_ = reject(valueErr)
+					return
+				}
+				_ = resolve(value)
 			})
 			return
 		}
-		_ = runtimeServices.PostWithCustomContext(callCtx, "fetch.resolve", func(context.Context, *goja.Runtime) {
-			value, valueErr := responseValue(vm, data, expect)
-			if valueErr != nil {
-				_ = reject(valueErr)
+		resp, err := m.send(ctx, spec)
+		posted := runtimeServices.PostWithCustomContext(callCtx, "fetch.settle", func(context.Context, *goja.Runtime) {
+			if err != nil || settled {
+				release()
+				detach()
+				if resp != nil {
+					_ = resp.Body.Close()
+				}
+				if !settled {
+					settled = true
+					_ = reject(errorValue(vm, err))
+				}
 				return
 			}
-			_ = resolve(value)
+			settled = true
+			resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+			obj := newFetchedResponse(vm, runtimeServices, resp, spec.URL, m.policy.normalized().MaxResponseBytes)
+			r := mustResponse(vm, obj)
+			r.body.signal = spec.Signal
+			r.body.done = detach
+			_ = resolve(obj)
 		})
+		if posted != nil {
+			release()
+			if resp != nil {
+				_ = resp.Body.Close()
+			}
+		}
 	}()
 	return vm.ToValue(promise)
 }
 
-func headersFromValue(vm *goja.Runtime, value goja.Value) map[string]string {
-	out := map[string]string{}
-	obj := value.ToObject(vm)
-	for _, key := range obj.Keys() {
-		v := obj.Get(key)
-		if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
-			continue
+// sandboxedClient copies client so redirects are checked against the sandbox
+// profile as well as the client's own redirect policy.
+func sandboxedClient(client *http.Client, profile *sandbox.Profile) *http.Client {
+	copied := *client
+	next := client.CheckRedirect
+	copied.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := profile.CheckFetch(req.URL); err != nil {
+			return err
+		}
+		if next != nil {
+			return next(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
 		}
-		out[key] = v.String()
+		return nil
 	}
-	return out
+	return &copied
 }
 
 func setDefaultHeader(headers map[string]string, name, value string) {
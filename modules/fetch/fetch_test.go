@@ -3,18 +3,23 @@ package fetch_test
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/dop251/goja"
-	_ "github.com/go-go-golems/go-go-goja/modules/fetch"
+	"github.com/go-go-golems/go-go-goja/internal/enginetest"
+	"github.com/go-go-golems/go-go-goja/modules/fetch"
 	"github.com/go-go-golems/go-go-goja/pkg/engine"
+	"github.com/go-go-golems/go-go-goja/pkg/sandbox"
 )
 
 func TestLowLevelFetchJSON(t *testing.T) {
@@ -29,22 +34,15 @@ func TestLowLevelFetchJSON(t *testing.T) {
 
 	rt := newRuntime(t)
 	url := strconv.Quote(server.URL + "/status?name=goja")
-	_, err := rt.Owner.Call(context.Background(), "fetch.low-level.setup", func(_ context.Context, vm *goja.Runtime) (any, error) {
-		_, runErr := vm.RunString(`
+	state := runFetchScript(t, rt, `
 			globalThis.__fetchSmoke = { done: false };
 			(async () => {
 				const fetch = require("fetch");
-				const response = await fetch.fetch(` + url + `, { headers: { Accept: "application/json" } });
+				const response = await fetch.fetch(`+url+`, { headers: { Accept: "application/json" } });
 				const body = await response.json();
 				globalThis.__fetchSmoke = { done: true, error: "", status: response.status, ok: response.ok, name: body.name };
 			})().catch(e => { globalThis.__fetchSmoke = { done: true, error: String(e) }; });
 		`)
-		return nil, runErr
-	})
-	if err != nil {
-		t.Fatalf("setup: %v", err)
-	}
-	state := requireFetchState(t, rt)
 	for _, want := range []string{`"error":""`, `"status":200`, `"ok":true`, `"name":"goja"`} {
 		if !strings.Contains(state, want) {
 			t.Fatalf("state missing %s: %s", want, state)
@@ -86,14 +84,7 @@ func TestClientBearerFromFile(t *testing.T) {
 			globalThis.__fetchSmoke = { done: true, error: "", reportId: body.reportId, auth: body.auth };
 		})().catch(e => { globalThis.__fetchSmoke = { done: true, error: String(e), status: e.status || 0 }; });
 	`, strconv.Quote(server.URL), strconv.Quote(tokenFile))
-	_, err := rt.Owner.Call(context.Background(), "fetch.client.setup", func(_ context.Context, vm *goja.Runtime) (any, error) {
-		_, runErr := vm.RunString(script)
-		return nil, runErr
-	})
-	if err != nil {
-		t.Fatalf("setup: %v", err)
-	}
-	state := requireFetchState(t, rt)
+	state := runFetchScript(t, rt, script)
 	for _, want := range []string{`"error":""`, `"reportId":"daily"`, `"auth":"apiToken"`} {
 		if !strings.Contains(state, want) {
 			t.Fatalf("state missing %s: %s", want, state)
@@ -127,9 +118,43 @@ func TestClientAuthRejectsPlainObject(t *testing.T) {
 	}
 }
 
-func newRuntime(t *testing.T) *engine.Runtime {
+func TestFetchSandboxChecksOriginsAndRedirects(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("sandbox let a request through to %s", r.URL)
+	}))
+	defer other.Close()
+	allowed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, other.URL+"/leak", http.StatusFound)
+	}))
+	defer allowed.Close()
+
+	profile := &sandbox.Profile{Name: "test", Fetch: sandbox.FetchGrant{Origins: []string{allowed.URL}}}
+	rt := newRuntime(t, engine.WithSandbox(profile))
+	state := runFetchScript(t, rt, `
+			globalThis.__fetchSmoke = { done: false };
+			(async () => {
+				const fetch = require("fetch");
+				const denied = async (url) => {
+					try { await fetch.fetch(url); return "allowed"; } catch (e) { return [e.name, e.code, e.capability].join("|"); }
+				};
+				globalThis.__fetchSmoke = {
+					done: true,
+					error: "",
+					direct: await denied(`+strconv.Quote(other.URL)+`),
+					redirect: await denied(`+strconv.Quote(allowed.URL)+`),
+				};
+			})().catch(e => { globalThis.__fetchSmoke = { done: true, error: String(e) }; });
+		`)
+	for _, want := range []string{`"error":""`, `"direct":"PermissionError|ERR_ACCESS_DENIED|fetch"`, `"redirect":"PermissionError|ERR_ACCESS_DENIED|fetch"`} {
+		if !strings.Contains(state, want) {
+			t.Fatalf("state missing %s: %s", want, state)
+		}
+	}
+}
+
+func newRuntime(t *testing.T, opts ...engine.Option) *engine.Runtime {
 	t.Helper()
-	factory, err := engine.NewRuntimeFactoryBuilder().UseModuleMiddleware(engine.MiddlewareOnly("fetch")).Build()
+	factory, err := engine.NewRuntimeFactoryBuilder(opts...).UseModuleMiddleware(engine.MiddlewareOnly("fetch")).Build()
 	if err != nil {
 		t.Fatalf("build factory: %v", err)
 	}
@@ -141,20 +166,6 @@ func newRuntime(t *testing.T) *engine.Runtime {
 	return rt
 }
 
-func requireFetchState(t *testing.T, rt *engine.Runtime) string {
-	t.Helper()
-	deadline := time.Now().Add(2 * time.Second)
-	for time.Now().Before(deadline) {
-		state := readFetchState(t, rt)
-		if strings.Contains(state, `"done":true`) {
-			return state
-		}
-		time.Sleep(10 * time.Millisecond)
-	}
-	t.Fatalf("fetch state not done: %s", readFetchState(t, rt))
-	return ""
-}
-
 func readFetchState(t *testing.T, rt *engine.Runtime) string {
 	t.Helper()
 	ret, err := rt.Owner.Call(context.Background(), "fetch.state", func(_ context.Context, vm *goja.Runtime) (any, error) {
@@ -169,3 +180,502 @@ func readFetchState(t *testing.T, rt *engine.Runtime) string {
 	}
 	return ret.(string)
 }
+
+func TestClientBearerFromAsyncProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer refreshed-token" {
+			t.Fatalf("Authorization = %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	rt := newRuntime(t)
+	script := fmt.Sprintf(`
+		globalThis.__fetchSmoke = { done: false };
+		(async () => {
+			const fetch = require("fetch");
+			let calls = 0;
+			const client = fetch.client()
+				.baseUrl(%s)
+				.auth(fetch.auth.bearer().fromProvider(async () => { calls++; await Promise.resolve(); return "refreshed-token"; }))
+				.expectJson();
+			const body = await client.get("/status").run();
+			globalThis.__fetchSmoke = { done: true, error: "", ok: body.ok, calls };
+		})().catch(e => { globalThis.__fetchSmoke = { done: true, error: String(e) }; });
+	`, strconv.Quote(server.URL))
+	state := runFetchScript(t, rt, script)
+	for _, want := range []string{`"error":""`, `"ok":true`, `"calls":1`} {
+		if !strings.Contains(state, want) {
+			t.Fatalf("state missing %s: %s", want, state)
+		}
+	}
+}
+
+func TestClientBearerProviderRejectionKeepsReason(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("request sent without a token")
+	}))
+	defer server.Close()
+
+	rt := newRuntime(t)
+	script := fmt.Sprintf(`
+		globalThis.__fetchSmoke = { done: false };
+		(async () => {
+			const fetch = require("fetch");
+			const client = fetch.client()
+				.baseUrl(%s)
+				.auth(fetch.auth.bearer().fromProvider(async () => { throw new Error("vault is sealed"); }));
+			await client.get("/status").run();
+			globalThis.__fetchSmoke = { done: true, error: "" };
+		})().catch(e => { globalThis.__fetchSmoke = { done: true, error: String(e) }; });
+	`, strconv.Quote(server.URL))
+	state := runFetchScript(t, rt, script)
+	if !strings.Contains(state, "bearer token provider: promise rejected: Error: vault is sealed") {
+		t.Fatalf("rejection reason missing: %s", state)
+	}
+}
+
+func TestFetchAPIClasses(t *testing.T) {
+	rt := newRuntime(t)
+	state := runFetchScript(t, rt, `
+			globalThis.__fetchSmoke = { done: false };
+			(async () => {
+				const { Headers, Request, Response } = require("fetch");
+				const headers = new Headers({ "X-One": "1" });
+				headers.append("x-one", "2");
+				headers.append("Set-Cookie", "a=1");
+				headers.append("Set-Cookie", "b=2");
+				const entries = [...headers];
+
+				const request = new Request("https://api.example.test/items", { method: "post", headers, body: "payload" });
+				const copy = request.clone();
+				const requestText = await request.text();
+				let reused = "";
+				try { await request.text(); } catch (e) { reused = e.name; }
+				let getBody = "";
+				try { new Request("https://api.example.test", { body: "x" }); } catch (e) { getBody = e.name; }
+
+				const response = Response.json({ ok: true }, { status: 201, headers: { "X-Trace": "t" } });
+				const twin = response.clone();
+				const parsed = await response.json();
+				const bytes = await twin.bytes();
+				const blob = await new Response("hello").blob();
+				let immutable = "";
+				globalThis.__fetchSmoke = {
+					done: true, error: "",
+					entries, combined: headers.get("X-ONE"), cookies: headers.getSetCookie(), missing: headers.get("nope"),
+					method: request.method, requestText, copyText: await copy.text(), contentType: request.headers.get("content-type"),
+					reused, getBody, signal: request.signal instanceof AbortSignal,
+					status: response.status, responseType: response.headers.get("content-type"), parsed, used: response.bodyUsed,
+					bytes: bytes.length, isBytes: bytes instanceof Uint8Array, blob: [blob.size, blob.type, await blob.text()],
+					instance: response instanceof Response, nullBody: new Response(null).body,
+				};
+			})().catch(e => { globalThis.__fetchSmoke = { done: true, error: String(e) }; });
+		`)
+	for _, want := range []string{
+		`"error":""`,
+		`"entries":[["set-cookie","a=1"],["set-cookie","b=2"],["x-one","1, 2"]]`,
+		`"combined":"1, 2"`, `"cookies":["a=1","b=2"]`, `"missing":null`,
+		`"method":"POST"`, `"requestText":"payload"`, `"copyText":"payload"`, `"contentType":"text/plain;charset=UTF-8"`,
+		`"reused":"TypeError"`, `"getBody":"TypeError"`, `"signal":true`,
+		`"status":201`, `"responseType":"application/json"`, `"parsed":{"ok":true}`, `"used":true`,
+		`"bytes":11`, `"isBytes":true`, `"blob":[5,"text/plain;charset=UTF-8","hello"]`,
+		`"instance":true`, `"nullBody":null`,
+	} {
+		if !strings.Contains(state, want) {
+			t.Fatalf("state missing %s: %s", want, state)
+		}
+	}
+}
+
+func TestFetchStreamsResponseAndRequestBodies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/upload" {
+			data, _ := io.ReadAll(r.Body)
+			_, _ = fmt.Fprintf(w, "%s:%d:%s", r.Method, len(data), r.TransferEncoding)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("X-Stream", "yes")
+		for i := 0; i < 3; i++ {
+			_, _ = fmt.Fprintf(w, "data: %d\n\n", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(5 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	rt := newRuntime(t)
+	script := fmt.Sprintf(`
+		globalThis.__fetchSmoke = { done: false };
+		(async () => {
+			const fetch = require("fetch");
+			const { Readable, promises } = require("stream");
+			const res = await fetch.fetch(%[1]s + "/events");
+			const clone = res.clone();
+			const chunks = [];
+			res.body.setEncoding("utf8");
+			res.body.on("data", chunk => chunks.push(chunk));
+			await promises.finished(res.body);
+			const buffer = await clone.arrayBuffer();
+
+			const upload = await fetch.fetch(%[1]s + "/upload", {
+				method: "PUT",
+				body: Readable.from(["a".repeat(1000), "b".repeat(24)]),
+			});
+			globalThis.__fetchSmoke = {
+				done: true, error: "",
+				events: chunks.join("").split("\n\n").filter(Boolean),
+				header: res.headers.get("x-stream"), statusText: res.statusText,
+				bytes: buffer.byteLength, upload: await upload.text(),
+			};
+		})().catch(e => { globalThis.__fetchSmoke = { done: true, error: String(e) }; });
+	`, strconv.Quote(server.URL))
+	state := runFetchScript(t, rt, script)
+	for _, want := range []string{`"error":""`, `"events":["data: 0","data: 1","data: 2"]`, `"header":"yes"`, `"statusText":"OK"`, `"bytes":27`, `"upload":"PUT:1024:[chunked]"`} {
+		if !strings.Contains(state, want) {
+			t.Fatalf("state missing %s: %s", want, state)
+		}
+	}
+}
+
+func TestAbortControllerCancelsFetch(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/partial" {
+			_, _ = w.Write([]byte("first"))
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	rt := newRuntime(t)
+	script := fmt.Sprintf(`
+		globalThis.__fetchSmoke = { done: false };
+		(async () => {
+			const fetch = require("fetch");
+			const { sleep } = require("timer");
+			const outcome = async (promise) => {
+				try { await promise; return "resolved"; } catch (e) { return e.name + ":" + (e.message || e); }
+			};
+
+			const pre = await outcome(fetch.fetch(%[1]s, { signal: AbortSignal.abort() }));
+
+			const controller = new AbortController();
+			const pending = fetch.fetch(%[1]s + "/hang", { signal: controller.signal });
+			await sleep(20);
+			controller.abort();
+			const inFlight = await outcome(pending);
+
+			const streaming = new AbortController();
+			const res = await fetch.fetch(%[1]s + "/partial", { signal: streaming.signal });
+			const first = await new Promise(resolve => res.body.once("data", chunk => resolve(String(chunk))));
+			const bodyError = new Promise(resolve => res.body.on("error", e => resolve(e.name)));
+			streaming.abort(new TypeError("stop streaming"));
+			const timedOut = await outcome(fetch.fetch(%[1]s + "/hang", { signal: AbortSignal.timeout(20) }));
+
+			globalThis.__fetchSmoke = { done: true, error: "", pre, inFlight, first, bodyError: await bodyError, timedOut };
+		})().catch(e => { globalThis.__fetchSmoke = { done: true, error: String(e) }; });
+	`, strconv.Quote(server.URL))
+	state := runFetchScript(t, rt, script)
+	for _, want := range []string{
+		`"error":""`,
+		`"pre":"AbortError:This operation was aborted"`,
+		`"inFlight":"AbortError:This operation was aborted"`,
+		`"first":"first"`,
+		`"bodyError":"TypeError"`,
+		`"timedOut":"TimeoutError:The operation was aborted due to timeout"`,
+	} {
+		if !strings.Contains(state, want) {
+			t.Fatalf("state missing %s: %s", want, state)
+		}
+	}
+}
+
+// observedRuntime loads a fetch module that records its retry and breaker
+// events.
+func observedRuntime(t *testing.T, opts ...engine.Option) (*engine.Runtime, func() []fetch.Event) {
+	t.Helper()
+	var mu sync.Mutex
+	var events []fetch.Event
+	module := fetch.New(fetch.WithObserver(func(e fetch.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	}))
+	factory, err := engine.NewRuntimeFactoryBuilder(opts...).
+		WithModules(engine.NativeModuleRegistrar{ModuleID: "test-observed-fetch", ModuleName: module.Name(), Loader: module.Loader}).
+		Build()
+	if err != nil {
+		t.Fatalf("build factory: %v", err)
+	}
+	rt, err := factory.NewRuntime(engine.WithStartupContext(context.Background()), engine.WithLifetimeContext(context.Background()))
+	if err != nil {
+		t.Fatalf("new runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close(context.Background()) })
+	return rt, func() []fetch.Event {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]fetch.Event(nil), events...)
+	}
+}
+
+func runFetchScript(t *testing.T, rt *engine.Runtime, script string) string {
+	t.Helper()
+	enginetest.Await(t, rt, `globalThis.__fetchSmoke = { done: false };`+script)
+	return readFetchState(t, rt)
+}
+
+func TestClientRetriesWithBackoffAndIdempotencyKeys(t *testing.T) {
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	keys := map[string][]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts[r.URL.Path]++
+		n := attempts[r.URL.Path]
+		keys[r.URL.Path] = append(keys[r.URL.Path], r.Header.Get("Idempotency-Key"))
+		mu.Unlock()
+		if n < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"attempt":` + strconv.Itoa(n) + `}`))
+	}))
+	defer server.Close()
+
+	rt, events := observedRuntime(t)
+	state := runFetchScript(t, rt, fmt.Sprintf(`
+		(async () => {
+			const fetch = require("fetch");
+			const client = fetch.client().baseUrl(%s).retry({ attempts: 3, backoff: 1 }).expectJson();
+			const outcome = (p) => p.then(v => v.attempt, e => e.name + ":" + e.status);
+			globalThis.__fetchSmoke = {
+				done: true,
+				error: "",
+				get: await outcome(client.get("/get").run()),
+				post: await outcome(client.post("/post").json({}).run()),
+				keyed: await outcome(client.idempotencyKeys().post("/keyed").json({}).run()),
+			};
+		})().catch(e => { globalThis.__fetchSmoke = { done: true, error: String(e) }; });
+	`, strconv.Quote(server.URL)))
+	for _, want := range []string{`"error":""`, `"get":3`, `"post":"HTTPError:503"`, `"keyed":3`} {
+		if !strings.Contains(state, want) {
+			t.Fatalf("state missing %s: %s", want, state)
+		}
+	}
+	mu.Lock()
+	keyed := keys["/keyed"]
+	mu.Unlock()
+	if len(keyed) != 3 || keyed[0] == "" || keyed[0] != keyed[1] || keyed[1] != keyed[2] {
+		t.Fatalf("idempotency keys across attempts = %q", keyed)
+	}
+	var retries []fetch.Event
+	for _, e := range events() {
+		if e.Kind == fetch.EventRetry {
+			retries = append(retries, e)
+		}
+	}
+	if len(retries) != 4 || retries[0].Status != http.StatusServiceUnavailable || retries[0].Attempt != 1 || retries[1].Attempt != 2 || retries[0].Method != "GET" {
+		t.Fatalf("retry events = %+v", retries)
+	}
+}
+
+func TestClientCircuitBreakerOpensPerOrigin(t *testing.T) {
+	var healthy atomic.Bool
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	rt, events := observedRuntime(t)
+	script := fmt.Sprintf(`
+		(async () => {
+			const fetch = require("fetch");
+			const client = fetch.client().baseUrl(%s).circuitBreaker({ failures: 2, resetAfter: 50 }).expectText();
+			const outcome = (p) => p.then(v => v, e => [e.name, e.code || e.status].join("|"));
+			const results = [];
+			for (let i = 0; i < 3; i++) results.push(await outcome(client.get("/work").run()));
+			globalThis.__fetchSmoke = { done: true, error: "", results };
+		})().catch(e => { globalThis.__fetchSmoke = { done: true, error: String(e) }; });
+	`, strconv.Quote(server.URL))
+	state := runFetchScript(t, rt, script)
+	if want := `"results":["HTTPError|500","HTTPError|500","CircuitOpenError|ERR_CIRCUIT_OPEN"]`; !strings.Contains(state, want) {
+		t.Fatalf("state missing %s: %s", want, state)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("open breaker let %d requests through", calls.Load())
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	healthy.Store(true)
+	state = runFetchScript(t, rt, script)
+	if want := `"results":["ok","ok","ok"]`; !strings.Contains(state, want) {
+		t.Fatalf("state missing %s: %s", want, state)
+	}
+	var kinds []string
+	for _, e := range events() {
+		if e.Origin != server.URL {
+			t.Fatalf("event origin = %q, want %q", e.Origin, server.URL)
+		}
+		kinds = append(kinds, string(e.Kind))
+	}
+	if got, want := strings.Join(kinds, ","), "breaker-open,breaker-reject,breaker-half-open,breaker-close"; got != want {
+		t.Fatalf("breaker events = %s, want %s", got, want)
+	}
+}
+
+func TestClientRetryAndBreakerIgnoreSandboxDenials(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("sandbox let a request through to %s", r.URL)
+	}))
+	defer other.Close()
+	var redirects atomic.Int32
+	allowed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirects.Add(1)
+		http.Redirect(w, r, other.URL+"/leak", http.StatusFound)
+	}))
+	defer allowed.Close()
+
+	profile := &sandbox.Profile{Name: "test", Fetch: sandbox.FetchGrant{Origins: []string{allowed.URL}}}
+	rt, events := observedRuntime(t, engine.WithSandbox(profile))
+	state := runFetchScript(t, rt, fmt.Sprintf(`
+		(async () => {
+			const fetch = require("fetch");
+			const guarded = (base) => fetch.client().baseUrl(base).retry({ attempts: 3, backoff: 1 }).circuitBreaker({ failures: 1 }).expectText();
+			const outcome = (p) => p.then(v => v, e => e.name);
+			const results = [];
+			for (const base of [%s, %s]) {
+				const client = guarded(base);
+				for (let i = 0; i < 2; i++) results.push(await outcome(client.get("/work").run()));
+			}
+			globalThis.__fetchSmoke = { done: true, error: "", results };
+		})().catch(e => { globalThis.__fetchSmoke = { done: true, error: String(e) }; });
+	`, strconv.Quote(other.URL), strconv.Quote(allowed.URL)))
+	if want := `"results":["PermissionError","PermissionError","PermissionError","PermissionError"]`; !strings.Contains(state, want) {
+		t.Fatalf("state missing %s: %s", want, state)
+	}
+	if got := redirects.Load(); got != 2 {
+		t.Fatalf("denied redirects took %d attempts, want one per request", got)
+	}
+	if got := events(); len(got) != 0 {
+		t.Fatalf("sandbox denials produced retry or breaker events: %+v", got)
+	}
+}
+
+func TestCassetteRecordsAndReplaysFetchTraffic(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"path":%q,"sent":%q}`, r.URL.Path, body)
+	}))
+	serverURL := server.URL
+	path := filepath.Join(t.TempDir(), "cassettes", "api.yaml")
+	script := fmt.Sprintf(`
+		(async () => {
+			const fetch = require("fetch");
+			const client = fetch.client().baseUrl(%s).header("Authorization", "Bearer secret-token").expectJson();
+			const first = await client.get("/items").run();
+			const second = await client.post("/items").json({ name: "a" }).run();
+			globalThis.__fetchSmoke = { done: true, error: "", first: first.path, second: second.sent };
+		})().catch(e => { globalThis.__fetchSmoke = { done: true, error: String(e) }; });
+	`, strconv.Quote(serverURL))
+	runWithCassette := func(mode fetch.CassetteMode, opts ...fetch.CassetteOption) (*fetch.Cassette, string) {
+		cassette, err := fetch.OpenCassette(path, mode, opts...)
+		if err != nil {
+			t.Fatalf("open cassette: %v", err)
+		}
+		module := fetch.New(fetch.WithHTTPClient(cassette.Client()))
+		factory, err := engine.NewRuntimeFactoryBuilder().
+			WithModules(engine.NativeModuleRegistrar{ModuleID: "test-cassette-fetch", ModuleName: module.Name(), Loader: module.Loader}).
+			Build()
+		if err != nil {
+			t.Fatalf("build factory: %v", err)
+		}
+		rt, err := factory.NewRuntime(engine.WithStartupContext(context.Background()), engine.WithLifetimeContext(context.Background()))
+		if err != nil {
+			t.Fatalf("new runtime: %v", err)
+		}
+		defer func() { _ = rt.Close(context.Background()) }()
+		return cassette, runFetchScript(t, rt, script)
+	}
+
+	_, recorded := runWithCassette(fetch.CassetteRecord)
+	server.Close()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read cassette: %v", err)
+	}
+	if strings.Contains(string(data), "secret-token") || !strings.Contains(string(data), "[REDACTED]") {
+		t.Fatalf("cassette did not redact credentials:\n%s", data)
+	}
+
+	cassette, replayed := runWithCassette(fetch.CassetteReplay, fetch.WithCassetteMatch(fetch.CassetteMatch{Method: true, URL: true, Body: true}))
+	for _, state := range []string{recorded, replayed} {
+		for _, want := range []string{`"error":""`, `"first":"/items"`, `"second":"{\"name\":\"a\"}"`} {
+			if !strings.Contains(state, want) {
+				t.Fatalf("state missing %s: %s", want, state)
+			}
+		}
+	}
+	if unplayed := cassette.Unplayed(); len(unplayed) != 0 {
+		t.Fatalf("unplayed interactions: %+v", unplayed)
+	}
+
+	_, unmatched := runWithCassette(fetch.CassetteReplay, fetch.WithCassetteMatch(fetch.CassetteMatch{Method: true, URL: true, Headers: []string{"X-Tenant"}}))
+	if !strings.Contains(unmatched, `"error":""`) {
+		t.Fatalf("header match without the header in either request should replay: %s", unmatched)
+	}
+}
+
+func TestCassetteRecordingCapsResponseBodies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("x", 64)))
+	}))
+	defer server.Close()
+	cassette, err := fetch.OpenCassette(filepath.Join(t.TempDir(), "big.yaml"), fetch.CassetteRecord, fetch.WithCassetteMaxBodyBytes(16))
+	if err != nil {
+		t.Fatalf("open cassette: %v", err)
+	}
+	_, err = cassette.Client().Get(server.URL)
+	if err == nil || !strings.Contains(err.Error(), "exceeds configured limit of 16 bytes") {
+		t.Fatalf("oversized recording error = %v", err)
+	}
+}
+
+func TestCassetteFailsOnUnmatchedRequests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.yaml")
+	if err := os.WriteFile(path, []byte("version: 1\ninteractions: []\n"), 0o600); err != nil {
+		t.Fatalf("write cassette: %v", err)
+	}
+	cassette, err := fetch.OpenCassette(path, fetch.CassetteReplay)
+	if err != nil {
+		t.Fatalf("open cassette: %v", err)
+	}
+	_, err = cassette.Client().Get("http://api.example.test/missing")
+	if err == nil || !strings.Contains(err.Error(), "no unplayed interaction matching GET http://api.example.test/missing") {
+		t.Fatalf("unmatched request error = %v", err)
+	}
+	if _, err := fetch.OpenCassette(filepath.Join(t.TempDir(), "absent.yaml"), fetch.CassetteReplay); err == nil {
+		t.Fatalf("replaying a missing cassette should fail")
+	}
+	if _, err := fetch.ParseCassetteMatch("method,query"); err == nil {
+		t.Fatalf("unknown match field should fail")
+	}
+}
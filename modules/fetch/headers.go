@@ -0,0 +1,244 @@
+package fetch
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/modules"
+)
+
+// headerList backs a Headers object. Names are stored lower-cased, as the
+// Fetch standard exposes them.
+type headerList struct {
+	values    map[string][]string
+	immutable bool
+}
+
+var headersKey = goja.NewSymbol("go-go-goja.fetch.headers")
+
+func newHeaderList() *headerList {
+	return &headerList{values: map[string][]string{}}
+}
+
+func headerListFromHTTP(header http.Header) *headerList {
+	h := newHeaderList()
+	for name, values := range header {
+		for _, value := range values {
+			h.append(name, value)
+		}
+	}
+	return h
+}
+
+func (h *headerList) append(name, value string) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	h.values[key] = append(h.values[key], strings.TrimSpace(value))
+}
+
+func (h *headerList) set(name, value string) {
+	h.values[strings.ToLower(strings.TrimSpace(name))] = []string{strings.TrimSpace(value)}
+}
+
+func (h *headerList) get(name string) (string, bool) {
+	values, ok := h.values[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return "", false
+	}
+	return strings.Join(values, ", "), true
+}
+
+func (h *headerList) clone() *headerList {
+	out := newHeaderList()
+	for name, values := range h.values {
+		out.values[name] = append([]string(nil), values...)
+	}
+	return out
+}
+
+// entries returns the sorted name/value pairs a Headers iterator yields.
+// Set-Cookie values are kept separate; other repeated headers are combined.
+func (h *headerList) entries() [][2]string {
+	names := make([]string, 0, len(h.values))
+	for name := range h.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([][2]string, 0, len(names))
+	for _, name := range names {
+		if name == "set-cookie" {
+			for _, value := range h.values[name] {
+				out = append(out, [2]string{name, value})
+			}
+			continue
+		}
+		out = append(out, [2]string{name, strings.Join(h.values[name], ", ")})
+	}
+	return out
+}
+
+// flatten returns the headers in the form requestSpec sends them.
+func (h *headerList) flatten() map[string]string {
+	out := map[string]string{}
+	for name, values := range h.values {
+		out[name] = strings.Join(values, ", ")
+	}
+	return out
+}
+
+// fill adds the headers described by init: a Headers object, an array of
+// [name, value] pairs, or a plain object.
+func (h *headerList) fill(vm *goja.Runtime, init goja.Value) {
+	if !modules.Present(init) {
+		return
+	}
+	if other, ok := headersOf(init); ok {
+		for name, values := range other.values {
+			h.values[name] = append(h.values[name], values...)
+		}
+		return
+	}
+	obj := init.ToObject(vm)
+	if isArray(vm, obj) {
+		for _, key := range obj.Keys() {
+			pair := obj.Get(key).ToObject(vm)
+			if pair.Get("length").ToInteger() != 2 {
+				panic(vm.NewTypeError("Headers init pairs must be [name, value]"))
+			}
+			h.append(pair.Get("0").String(), pair.Get("1").String())
+		}
+		return
+	}
+	for _, key := range obj.Keys() {
+		value := obj.Get(key)
+		if !modules.Present(value) {
+			continue
+		}
+		h.append(key, value.String())
+	}
+}
+
+func headersOf(value goja.Value) (*headerList, bool) {
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return nil, false
+	}
+	inner, ok := obj.GetSymbol(headersKey).(*goja.Object)
+	if !ok {
+		return nil, false
+	}
+	h, ok := inner.Export().(*headerList)
+	return h, ok && h != nil
+}
+
+func mustHeaders(vm *goja.Runtime, value goja.Value, write bool) *headerList {
+	h, ok := headersOf(value)
+	if !ok {
+		panic(vm.NewTypeError("Value of this must be Headers"))
+	}
+	if write && h.immutable {
+		panic(vm.NewTypeError("Headers are immutable"))
+	}
+	return h
+}
+
+func newHeadersClass(vm *goja.Runtime) *goja.Object {
+	constructor := vm.ToValue(func(call goja.ConstructorCall) *goja.Object {
+		h := newHeaderList()
+		h.fill(vm, call.Argument(0))
+		attachHeaders(vm, call.This, h)
+		return nil
+	}).(*goja.Object)
+	proto := constructor.Get("prototype").(*goja.Object)
+	mustSet(vm, proto, "append", func(call goja.FunctionCall) goja.Value {
+		mustHeaders(vm, call.This, true).append(call.Argument(0).String(), call.Argument(1).String())
+		return goja.Undefined()
+	})
+	mustSet(vm, proto, "set", func(call goja.FunctionCall) goja.Value {
+		mustHeaders(vm, call.This, true).set(call.Argument(0).String(), call.Argument(1).String())
+		return goja.Undefined()
+	})
+	mustSet(vm, proto, "delete", func(call goja.FunctionCall) goja.Value {
+		delete(mustHeaders(vm, call.This, true).values, strings.ToLower(strings.TrimSpace(call.Argument(0).String())))
+		return goja.Undefined()
+	})
+	mustSet(vm, proto, "get", func(call goja.FunctionCall) goja.Value {
+		value, ok := mustHeaders(vm, call.This, false).get(call.Argument(0).String())
+		if !ok {
+			return goja.Null()
+		}
+		return vm.ToValue(value)
+	})
+	mustSet(vm, proto, "has", func(call goja.FunctionCall) goja.Value {
+		_, ok := mustHeaders(vm, call.This, false).get(call.Argument(0).String())
+		return vm.ToValue(ok)
+	})
+	mustSet(vm, proto, "getSetCookie", func(call goja.FunctionCall) goja.Value {
+		values := mustHeaders(vm, call.This, false).values["set-cookie"]
+		return vm.ToValue(append([]string{}, values...))
+	})
+	mustSet(vm, proto, "forEach", func(call goja.FunctionCall) goja.Value {
+		h := mustHeaders(vm, call.This, false)
+		callback, ok := goja.AssertFunction(call.Argument(0))
+		if !ok {
+			panic(vm.NewTypeError("Headers.forEach requires a callback"))
+		}
+		for _, entry := range h.entries() {
+			if _, err := callback(call.Argument(1), vm.ToValue(entry[1]), vm.ToValue(entry[0]), call.This); err != nil {
+				panic(err)
+			}
+		}
+		return goja.Undefined()
+	})
+	iterator := func(pick func([2]string) any) func(goja.FunctionCall) goja.Value {
+		return func(call goja.FunctionCall) goja.Value {
+			entries := mustHeaders(vm, call.This, false).entries()
+			items := make([]any, 0, len(entries))
+			for _, entry := range entries {
+				items = append(items, pick(entry))
+			}
+			return arrayIterator(vm, vm.NewArray(items...))
+		}
+	}
+	entries := vm.ToValue(iterator(func(e [2]string) any { return vm.NewArray(e[0], e[1]) }))
+	mustSet(vm, proto, "entries", entries)
+	mustSet(vm, proto, "keys", iterator(func(e [2]string) any { return e[0] }))
+	mustSet(vm, proto, "values", iterator(func(e [2]string) any { return e[1] }))
+	if err := proto.SetSymbol(goja.SymIterator, entries); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("fetch: set Headers iterator: %w", err)))
+	}
+	return constructor
+}
+
+// newHeadersObject wraps h in a Headers instance.
+func newHeadersObject(vm *goja.Runtime, h *headerList) *goja.Object {
+	obj := vm.NewObject()
+	if err := obj.SetPrototype(classesFor(vm).headers.Get("prototype").(*goja.Object)); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("fetch: set Headers prototype: %w", err)))
+	}
+	attachHeaders(vm, obj, h)
+	return obj
+}
+
+func attachHeaders(vm *goja.Runtime, obj *goja.Object, h *headerList) {
+	if err := obj.DefineDataPropertySymbol(headersKey, vm.ToValue(h), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("fetch: attach headers: %w", err)))
+	}
+}
+
+func arrayIterator(vm *goja.Runtime, array *goja.Object) goja.Value {
+	values, _ := goja.AssertFunction(array.Get("values"))
+	it, err := values(array)
+	if err != nil {
+		panic(err)
+	}
+	return it
+}
+
+func isArray(vm *goja.Runtime, obj *goja.Object) bool {
+	isArrayFn, _ := goja.AssertFunction(vm.Get("Array").ToObject(vm).Get("isArray"))
+	ret, err := isArrayFn(goja.Undefined(), obj)
+	return err == nil && ret.ToBoolean()
+}
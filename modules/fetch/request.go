@@ -0,0 +1,156 @@
+package fetch
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/modules"
+	"github.com/go-go-golems/go-go-goja/modules/events"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimebridge"
+)
+
+// request backs a Request object.
+type request struct {
+	method  string
+	url     string
+	headers *goja.Object
+	signal  *events.AbortSignal
+	body    *body
+}
+
+var requestKey = goja.NewSymbol("go-go-goja.fetch.request")
+
+func newRequestClass(vm *goja.Runtime, services runtimebridge.RuntimeServices) *goja.Object {
+	constructor := vm.ToValue(func(call goja.ConstructorCall) *goja.Object {
+		r, err := newRequest(vm, services, call.Argument(0), call.Argument(1))
+		if err != nil {
+			panic(vm.NewTypeError(err.Error()))
+		}
+		attachRequest(vm, call.This, r)
+		return nil
+	}).(*goja.Object)
+	proto := constructor.Get("prototype").(*goja.Object)
+	field := func(name string, get func(*request) goja.Value) {
+		defineAccessor(vm, proto, name, func(this goja.Value) goja.Value { return get(mustRequest(vm, this)) })
+	}
+	field("method", func(r *request) goja.Value { return vm.ToValue(r.method) })
+	field("url", func(r *request) goja.Value { return vm.ToValue(r.url) })
+	field("headers", func(r *request) goja.Value { return r.headers })
+	field("signal", func(r *request) goja.Value { return r.signal.Object() })
+	installBody(vm, proto, func(this goja.Value) *body { return mustRequest(vm, this).body })
+	mustSet(vm, proto, "clone", func(call goja.FunctionCall) goja.Value {
+		r := mustRequest(vm, call.This)
+		cloned, err := r.body.tee()
+		if err != nil {
+			panic(vm.NewTypeError("Request.clone: " + err.Error()))
+		}
+		copied := *r
+		copied.body = cloned
+		h, _ := headersOf(r.headers)
+		copied.headers = newHeadersObject(vm, h.clone())
+		obj := vm.NewObject()
+		if err := obj.SetPrototype(proto); err != nil {
+			panic(vm.NewGoError(fmt.Errorf("fetch: set Request prototype: %w", err)))
+		}
+		attachRequest(vm, obj, &copied)
+		return obj
+	})
+	return constructor
+}
+
+// newRequest implements the Request(input, init) constructor. input is a URL
+// string or a Request, whose body moves to the new request; init may set
+// method, headers, body and signal.
+func newRequest(vm *goja.Runtime, services runtimebridge.RuntimeServices, input, initValue goja.Value) (*request, error) {
+	r := &request{method: http.MethodGet}
+	h := newHeaderList()
+	var inherited *body
+	if from, ok := requestOf(input); ok {
+		if from.body.used {
+			return nil, fmt.Errorf("cannot construct a Request from a Request whose body has been read")
+		}
+		r.method, r.url, r.signal = from.method, from.url, from.signal
+		fromHeaders, _ := headersOf(from.headers)
+		h = fromHeaders.clone()
+		inherited = from.body
+	} else if modules.Present(input) {
+		r.url = strings.TrimSpace(input.String())
+	} else {
+		return nil, fmt.Errorf("request requires a URL")
+	}
+
+	init, _ := initValue.(*goja.Object)
+	if init != nil {
+		if method := init.Get("method"); modules.Present(method) {
+			r.method = strings.ToUpper(strings.TrimSpace(method.String()))
+		}
+		if headers := init.Get("headers"); modules.Present(headers) {
+			h = newHeaderList()
+			h.fill(vm, headers)
+		}
+		if signal := init.Get("signal"); signal != nil && !goja.IsUndefined(signal) {
+			r.signal = nil
+			if modules.Present(signal) {
+				s, ok := events.SignalOf(signal)
+				if !ok {
+					return nil, fmt.Errorf("signal must be an AbortSignal")
+				}
+				r.signal = s
+			}
+		}
+	}
+	if r.signal == nil {
+		r.signal = events.NewAbortSignal(vm)
+	}
+
+	r.body = &body{vm: vm, services: services, limit: constructedBodyLimit}
+	if init != nil && modules.Present(init.Get("body")) {
+		reader, err := bodyFromValue(vm, init.Get("body"))
+		if err != nil {
+			return nil, err
+		}
+		r.body.reader = reader
+		if _, ok := h.get("content-type"); !ok && goja.IsString(init.Get("body")) {
+			h.set("content-type", "text/plain;charset=UTF-8")
+		}
+	} else if inherited != nil {
+		r.body.reader = inherited.reader
+		inherited.reader = nil
+		inherited.used = true
+	}
+	if r.body.reader != nil && (r.method == http.MethodGet || r.method == http.MethodHead) {
+		_ = r.body.reader.Close()
+		return nil, fmt.Errorf("request with %s method cannot have a body", r.method)
+	}
+	r.headers = newHeadersObject(vm, h)
+	return r, nil
+}
+
+func attachRequest(vm *goja.Runtime, obj *goja.Object, r *request) {
+	if err := obj.DefineDataPropertySymbol(requestKey, vm.ToValue(r), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("fetch: attach request: %w", err)))
+	}
+}
+
+func requestOf(value goja.Value) (*request, bool) {
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return nil, false
+	}
+	inner, ok := obj.GetSymbol(requestKey).(*goja.Object)
+	if !ok {
+		return nil, false
+	}
+	r, ok := inner.Export().(*request)
+	return r, ok && r != nil
+}
+
+func mustRequest(vm *goja.Runtime, value goja.Value) *request {
+	r, ok := requestOf(value)
+	if !ok {
+		panic(vm.NewTypeError("Value of this must be a Request"))
+	}
+	return r
+}
@@ -6,12 +6,25 @@ import (
 	"net/http"
 
 	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/modules"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimebridge"
 )
 
+// response backs a Response object.
+type response struct {
+	url        string
+	status     int
+	statusText string
+	redirected bool
+	kind       string
+	headers    *goja.Object
+	body       *body
+}
+
+var responseKey = goja.NewSymbol("go-go-goja.fetch.response")
+
 func responseValue(vm *goja.Runtime, data responseData, expect expectation) (goja.Value, goja.Value) {
 	switch expect {
-	case expectationResponse:
-		return newResponseObject(vm, data), nil
 	case expectationJSON:
 		if data.Status < 200 || data.Status > 299 {
 			return nil, httpErrorValue(vm, data)
@@ -31,26 +44,132 @@ func responseValue(vm *goja.Runtime, data responseData, expect expectation) (goj
 	}
 }
 
-func newResponseObject(vm *goja.Runtime, data responseData) goja.Value {
-	obj := vm.NewObject()
-	_ = obj.Set("url", data.URL)
-	_ = obj.Set("status", data.Status)
-	_ = obj.Set("statusText", data.StatusText)
-	_ = obj.Set("ok", data.Status >= 200 && data.Status <= 299)
-	_ = obj.Set("headers", data.Headers)
-	_ = obj.Set("text", func() goja.Value {
-		return resolvedPromise(vm, string(data.Body))
+func newResponseClass(vm *goja.Runtime, services runtimebridge.RuntimeServices) *goja.Object {
+	constructor := vm.ToValue(func(call goja.ConstructorCall) *goja.Object {
+		r := &response{status: http.StatusOK, kind: "default"}
+		reader, err := bodyFromValue(vm, call.Argument(0))
+		if err != nil {
+			panic(vm.NewTypeError(err.Error()))
+		}
+		r.body = &body{vm: vm, services: services, reader: reader, limit: constructedBodyLimit}
+		h := newHeaderList()
+		if init, ok := call.Argument(1).(*goja.Object); ok {
+			if status := init.Get("status"); modules.Present(status) {
+				r.status = int(status.ToInteger())
+				if r.status < 200 || r.status > 599 {
+					panic(vm.NewTypeError(fmt.Sprintf("Response status %d is outside the range [200, 599]", r.status)))
+				}
+			}
+			if statusText := init.Get("statusText"); modules.Present(statusText) {
+				r.statusText = statusText.String()
+			}
+			h.fill(vm, init.Get("headers"))
+		}
+		if _, ok := h.get("content-type"); !ok && reader != nil && goja.IsString(call.Argument(0)) {
+			h.set("content-type", "text/plain;charset=UTF-8")
+		}
+		r.headers = newHeadersObject(vm, h)
+		attachResponse(vm, call.This, r)
+		return nil
+	}).(*goja.Object)
+	proto := constructor.Get("prototype").(*goja.Object)
+	field := func(name string, get func(*response) goja.Value) {
+		defineAccessor(vm, proto, name, func(this goja.Value) goja.Value { return get(mustResponse(vm, this)) })
+	}
+	field("url", func(r *response) goja.Value { return vm.ToValue(r.url) })
+	field("status", func(r *response) goja.Value { return vm.ToValue(r.status) })
+	field("statusText", func(r *response) goja.Value { return vm.ToValue(r.statusText) })
+	field("ok", func(r *response) goja.Value { return vm.ToValue(r.status >= 200 && r.status <= 299) })
+	field("redirected", func(r *response) goja.Value { return vm.ToValue(r.redirected) })
+	field("type", func(r *response) goja.Value { return vm.ToValue(r.kind) })
+	field("headers", func(r *response) goja.Value { return r.headers })
+	installBody(vm, proto, func(this goja.Value) *body { return mustResponse(vm, this).body })
+	mustSet(vm, proto, "clone", func(call goja.FunctionCall) goja.Value {
+		r := mustResponse(vm, call.This)
+		cloned, err := r.body.tee()
+		if err != nil {
+			panic(vm.NewTypeError("Response.clone: " + err.Error()))
+		}
+		copied := *r
+		copied.body = cloned
+		h, _ := headersOf(r.headers)
+		copiedHeaders := h.clone()
+		copiedHeaders.immutable = h.immutable
+		copied.headers = newHeadersObject(vm, copiedHeaders)
+		return newResponseObject(vm, &copied)
 	})
-	_ = obj.Set("json", func() goja.Value {
-		value, err := decodeJSON(vm, data.Body)
+	mustSet(vm, constructor, "json", func(call goja.FunctionCall) goja.Value {
+		data, err := json.Marshal(call.Argument(0).Export())
+		if err != nil {
+			panic(vm.NewTypeError("Response.json: " + err.Error()))
+		}
+		// Passed as bytes so the constructor does not default to text/plain.
+		obj, err := vm.New(constructor, uint8Array(vm, data), call.Argument(1))
 		if err != nil {
-			return rejectedPromiseValue(vm, vm.NewGoError(err))
+			panic(err)
+		}
+		h, _ := headersOf(mustResponse(vm, obj).headers)
+		if _, ok := h.get("content-type"); !ok {
+			h.set("content-type", "application/json")
 		}
-		return resolvedPromiseValue(vm, value)
+		return obj
 	})
+	return constructor
+}
+
+// newFetchedResponse wraps a network response. The body is read lazily from
+// resp.Body, which is closed once it has been read, on abort, or when the
+// runtime shuts down.
+func newFetchedResponse(vm *goja.Runtime, services runtimebridge.RuntimeServices, resp *http.Response, requestURL string, limit int64) *goja.Object {
+	h := headerListFromHTTP(resp.Header)
+	h.immutable = true
+	r := &response{
+		url:        resp.Request.URL.String(),
+		status:     resp.StatusCode,
+		statusText: reasonPhrase(resp),
+		redirected: resp.Request.URL.String() != requestURL,
+		kind:       "basic",
+		headers:    newHeadersObject(vm, h),
+		body:       &body{vm: vm, services: services, reader: resp.Body, limit: limit},
+	}
+	return newResponseObject(vm, r)
+}
+
+func newResponseObject(vm *goja.Runtime, r *response) *goja.Object {
+	obj := vm.NewObject()
+	if err := obj.SetPrototype(classesFor(vm).response.Get("prototype").(*goja.Object)); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("fetch: set Response prototype: %w", err)))
+	}
+	attachResponse(vm, obj, r)
 	return obj
 }
 
+func attachResponse(vm *goja.Runtime, obj *goja.Object, r *response) {
+	if err := obj.DefineDataPropertySymbol(responseKey, vm.ToValue(r), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("fetch: attach response: %w", err)))
+	}
+}
+
+func mustResponse(vm *goja.Runtime, value goja.Value) *response {
+	if obj, ok := value.(*goja.Object); ok {
+		if inner, ok := obj.GetSymbol(responseKey).(*goja.Object); ok {
+			if r, ok := inner.Export().(*response); ok {
+				return r
+			}
+		}
+	}
+	panic(vm.NewTypeError("Value of this must be a Response"))
+}
+
+// reasonPhrase returns the status text the server sent, such as "Not Found".
+func reasonPhrase(resp *http.Response) string {
+	prefix := fmt.Sprintf("%d ", resp.StatusCode)
+	if len(resp.Status) > len(prefix) && resp.Status[:len(prefix)] == prefix {
+		return resp.Status[len(prefix):]
+	}
+	return http.StatusText(resp.StatusCode)
+}
+
 func decodeJSON(vm *goja.Runtime, body []byte) (goja.Value, error) {
 	var decoded any
 	if len(body) == 0 {
@@ -85,7 +204,7 @@ func resolvedPromiseValue(vm *goja.Runtime, value goja.Value) goja.Value {
 }
 
 func rejectedPromise(vm *goja.Runtime, err error) goja.Value {
-	return rejectedPromiseValue(vm, vm.NewGoError(err))
+	return rejectedPromiseValue(vm, errorValue(vm, err))
 }
 
 func rejectedPromiseValue(vm *goja.Runtime, value goja.Value) goja.Value {
@@ -0,0 +1,474 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/modules"
+	"github.com/go-go-golems/go-go-goja/pkg/sandbox"
+)
+
+// Default retry settings used by client().retry() when an option is omitted.
+const (
+	DefaultRetryAttempts     = 3
+	DefaultRetryInitialDelay = 100 * time.Millisecond
+	DefaultRetryMaxDelay     = 5 * time.Second
+	DefaultBreakerFailures   = 5
+	DefaultBreakerReset      = 30 * time.Second
+)
+
+// IdempotencyKeyHeader is the header client().idempotencyKeys() fills in for
+// POST and PATCH requests.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// EventKind names a retry or circuit breaker event.
+type EventKind string
+
+const (
+	// EventRetry is reported before a failed attempt is retried.
+	EventRetry EventKind = "retry"
+	// EventBreakerOpen is reported when a breaker opens after repeated
+	// failures, or again after a failed half-open trial.
+	EventBreakerOpen EventKind = "breaker-open"
+	// EventBreakerHalfOpen is reported when an open breaker lets one trial
+	// request through.
+	EventBreakerHalfOpen EventKind = "breaker-half-open"
+	// EventBreakerClose is reported when a trial request succeeds.
+	EventBreakerClose EventKind = "breaker-close"
+	// EventBreakerReject is reported for a request refused by an open
+	// breaker.
+	EventBreakerReject EventKind = "breaker-reject"
+)
+
+// Event describes one retry or circuit breaker transition.
+type Event struct {
+	Kind    EventKind
+	Origin  string
+	Method  string
+	URL     string
+	Attempt int
+	Status  int
+	Err     error
+	// Delay is the wait before the next attempt of an EventRetry.
+	Delay time.Duration
+}
+
+// Observer receives retry and breaker events, for example to export metrics.
+// It is called from request goroutines and must be safe for concurrent use.
+type Observer func(Event)
+
+// WithObserver reports retry and circuit breaker events to observer.
+func WithObserver(observer Observer) Option {
+	return func(m *Module) { m.observer = observer }
+}
+
+// retryPolicy is the JavaScript retry({ attempts, backoff, retryOn }) option.
+type retryPolicy struct {
+	attempts   int
+	initial    time.Duration
+	max        time.Duration
+	multiplier float64
+	jitter     bool
+	statuses   map[int]bool
+	network    bool
+	timeout    bool
+}
+
+// breakerPolicy is the JavaScript circuitBreaker({ failures, resetAfter })
+// option.
+type breakerPolicy struct {
+	failures   int
+	resetAfter time.Duration
+}
+
+func defaultRetryPolicy() *retryPolicy {
+	return &retryPolicy{
+		attempts:   DefaultRetryAttempts,
+		initial:    DefaultRetryInitialDelay,
+		max:        DefaultRetryMaxDelay,
+		multiplier: 2,
+		jitter:     true,
+		statuses:   map[int]bool{408: true, 429: true, 500: true, 502: true, 503: true, 504: true},
+		network:    true,
+		timeout:    true,
+	}
+}
+
+// retryPolicyFromValue parses retry options. backoff is a delay (milliseconds
+// or a Go duration string) or { initial, max, multiplier, jitter }; retryOn
+// lists status codes and the keywords "network" and "timeout".
+func retryPolicyFromValue(vm *goja.Runtime, value goja.Value) (*retryPolicy, error) {
+	p := defaultRetryPolicy()
+	if !modules.Present(value) {
+		return p, nil
+	}
+	options := value.ToObject(vm)
+	if attempts := options.Get("attempts"); modules.Present(attempts) {
+		p.attempts = int(attempts.ToInteger())
+		if p.attempts < 1 {
+			return nil, fmt.Errorf("retry attempts must be at least 1")
+		}
+	}
+	if backoff := options.Get("backoff"); modules.Present(backoff) {
+		if obj, ok := backoff.(*goja.Object); ok {
+			if initial := obj.Get("initial"); modules.Present(initial) {
+				d, err := modules.DurationValue(initial)
+				if err != nil {
+					return nil, fmt.Errorf("retry backoff.initial: %w", err)
+				}
+				p.initial = d
+			}
+			if maxDelay := obj.Get("max"); modules.Present(maxDelay) {
+				d, err := modules.DurationValue(maxDelay)
+				if err != nil {
+					return nil, fmt.Errorf("retry backoff.max: %w", err)
+				}
+				p.max = d
+			}
+			if multiplier := obj.Get("multiplier"); modules.Present(multiplier) {
+				p.multiplier = multiplier.ToFloat()
+			}
+			if jitter := obj.Get("jitter"); modules.Present(jitter) {
+				p.jitter = jitter.ToBoolean()
+			}
+		} else {
+			d, err := modules.DurationValue(backoff)
+			if err != nil {
+				return nil, fmt.Errorf("retry backoff: %w", err)
+			}
+			p.initial, p.max, p.multiplier, p.jitter = d, d, 1, false
+		}
+	}
+	if retryOn := options.Get("retryOn"); modules.Present(retryOn) {
+		p.statuses, p.network, p.timeout = map[int]bool{}, false, false
+		obj := retryOn.ToObject(vm)
+		for _, key := range obj.Keys() {
+			item := obj.Get(key)
+			switch {
+			case item.String() == "network":
+				p.network = true
+			case item.String() == "timeout":
+				p.timeout = true
+			default:
+				status, err := strconv.Atoi(item.String())
+				if err != nil {
+					return nil, fmt.Errorf("retryOn entry %q is not a status code, \"network\" or \"timeout\"", item.String())
+				}
+				p.statuses[status] = true
+			}
+		}
+	}
+	return p, nil
+}
+
+func breakerPolicyFromValue(vm *goja.Runtime, value goja.Value) (*breakerPolicy, error) {
+	p := &breakerPolicy{failures: DefaultBreakerFailures, resetAfter: DefaultBreakerReset}
+	if !modules.Present(value) {
+		return p, nil
+	}
+	options := value.ToObject(vm)
+	if failures := options.Get("failures"); modules.Present(failures) {
+		p.failures = int(failures.ToInteger())
+		if p.failures < 1 {
+			return nil, fmt.Errorf("circuitBreaker failures must be at least 1")
+		}
+	}
+	if reset := options.Get("resetAfter"); modules.Present(reset) {
+		d, err := modules.DurationValue(reset)
+		if err != nil {
+			return nil, fmt.Errorf("circuitBreaker resetAfter: %w", err)
+		}
+		p.resetAfter = d
+	}
+	return p, nil
+}
+
+// delay returns the backoff before retry number attempt (1-based).
+func (p *retryPolicy) delay(attempt int) time.Duration {
+	d := float64(p.initial) * math.Pow(p.multiplier, float64(attempt-1))
+	if p.max > 0 && d > float64(p.max) {
+		d = float64(p.max)
+	}
+	if p.jitter {
+		d = d/2 + rand.Float64()*d/2
+	}
+	return time.Duration(d)
+}
+
+// retryable reports whether an attempt that ended with resp or err should be
+// retried. Errors other than transport errors are returned at once.
+func (p *retryPolicy) retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		if !transportError(err) {
+			return false
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			return p.timeout
+		}
+		return p.network
+	}
+	return p.statuses[resp.StatusCode]
+}
+
+// idempotent reports whether method may be retried without an idempotency
+// key.
+func idempotent(method string) bool {
+	switch strings.ToUpper(method) {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// retryAfter returns the delay requested by a Retry-After header in seconds.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	seconds, err := strconv.Atoi(strings.TrimSpace(resp.Header.Get("Retry-After")))
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// CircuitOpenError is returned while a circuit breaker rejects requests to
+// an origin.
+type CircuitOpenError struct {
+	Origin string
+	Until  time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker for %s is open", e.Origin)
+}
+
+// errorValue converts a fetch failure to a JavaScript error. Circuit breaker
+// rejections get name CircuitOpenError, code ERR_CIRCUIT_OPEN and the origin.
+func errorValue(vm *goja.Runtime, err error) goja.Value {
+	obj := sandbox.JSError(vm, err)
+	var open *CircuitOpenError
+	if errors.As(err, &open) {
+		_ = obj.Set("name", "CircuitOpenError")
+		_ = obj.Set("code", "ERR_CIRCUIT_OPEN")
+		_ = obj.Set("origin", open.Origin)
+	}
+	return obj
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// breaker tracks consecutive failures for one origin.
+type breaker struct {
+	state    breakerState
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+// breakerSet holds the per-origin breakers of one Module.
+type breakerSet struct {
+	mu       sync.Mutex
+	breakers map[string]*breaker
+	now      func() time.Time
+}
+
+func newBreakerSet() *breakerSet {
+	return &breakerSet{breakers: map[string]*breaker{}, now: time.Now}
+}
+
+// allow reports whether a request to origin may proceed, moving an open
+// breaker to half-open once its reset delay has passed. The returned kind is
+// the transition to report, if any.
+func (s *breakerSet) allow(origin string, p *breakerPolicy) (EventKind, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.breakers[origin]
+	if b == nil {
+		return "", nil
+	}
+	switch b.state {
+	case breakerOpen:
+		if s.now().Sub(b.openedAt) < p.resetAfter {
+			return EventBreakerReject, &CircuitOpenError{Origin: origin, Until: b.openedAt.Add(p.resetAfter)}
+		}
+		b.state, b.trial = breakerHalfOpen, true
+		return EventBreakerHalfOpen, nil
+	case breakerHalfOpen:
+		if b.trial {
+			return EventBreakerReject, &CircuitOpenError{Origin: origin, Until: s.now()}
+		}
+		b.trial = true
+	}
+	return "", nil
+}
+
+// record stores the outcome of a request and returns the transition to
+// report, if any.
+func (s *breakerSet) record(origin string, p *breakerPolicy, failed bool) EventKind {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.breakers[origin]
+	if b == nil {
+		if !failed {
+			return ""
+		}
+		b = &breaker{}
+		s.breakers[origin] = b
+	}
+	if !failed {
+		wasOpen := b.state != breakerClosed
+		delete(s.breakers, origin)
+		if wasOpen {
+			return EventBreakerClose
+		}
+		return ""
+	}
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= p.failures {
+		b.state, b.openedAt, b.trial = breakerOpen, s.now(), false
+		return EventBreakerOpen
+	}
+	return ""
+}
+
+// abandon frees the half-open trial slot of a request that was cancelled
+// before its outcome was known.
+func (s *breakerSet) abandon(origin string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b := s.breakers[origin]; b != nil && b.state == breakerHalfOpen {
+		b.trial = false
+	}
+}
+
+// breakerFailure reports whether an attempt counts against the breaker:
+// transport errors and 5xx responses do.
+func breakerFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return transportError(err)
+	}
+	return resp.StatusCode >= 500
+}
+
+// transportError reports whether err came from sending the request, as
+// opposed to the origin policy, the sandbox or a credential refusing it.
+// Those fail the same way on every attempt and say nothing about the origin.
+func transportError(err error) bool {
+	if errors.Is(err, sandbox.ErrPermissionDenied) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	// url.Parse reports malformed URLs as *url.Error too, with Op "parse".
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) && urlErr.Op != "parse"
+}
+
+// send performs spec with its retry and circuit breaker policies. Every
+// attempt goes through open, so the origin policy and sandbox are checked
+// each time. Streaming request bodies cannot be replayed and are sent once.
+func (m Module) send(ctx context.Context, spec requestSpec) (*http.Response, error) {
+	if spec.Retry == nil && spec.Breaker == nil {
+		return m.open(ctx, spec)
+	}
+	origin := spec.URL
+	if u, err := url.Parse(spec.URL); err == nil {
+		origin = originOf(u)
+	}
+	attempts := 1
+	keyHeader := spec.IdempotencyHeader
+	if keyHeader == "" {
+		keyHeader = IdempotencyKeyHeader
+	}
+	if spec.Retry != nil && spec.BodyReader == nil && (idempotent(spec.Method) || hasHeader(spec.Headers, keyHeader)) {
+		attempts = spec.Retry.attempts
+	}
+	event := Event{Origin: origin, Method: spec.Method, URL: spec.URL}
+	for attempt := 1; ; attempt++ {
+		event.Attempt = attempt
+		if spec.Breaker != nil {
+			kind, err := m.breakers.allow(origin, spec.Breaker)
+			if kind != "" {
+				m.report(event, kind, 0, err)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		resp, err := m.open(ctx, spec)
+		if spec.Breaker != nil {
+			if ctx.Err() != nil {
+				m.breakers.abandon(origin)
+			} else if kind := m.breakers.record(origin, spec.Breaker, breakerFailure(resp, err)); kind != "" {
+				m.report(event, kind, statusOf(resp), err)
+			}
+		}
+		if attempt >= attempts || ctx.Err() != nil || !spec.Retry.retryable(resp, err) {
+			return resp, err
+		}
+		delay := spec.Retry.delay(attempt)
+		if after, ok := retryAfter(resp); ok {
+			delay = after
+			if spec.Retry.max > 0 && delay > spec.Retry.max {
+				delay = spec.Retry.max
+			}
+		}
+		retryEvent := event
+		retryEvent.Delay = delay
+		m.report(retryEvent, EventRetry, statusOf(resp), err)
+		if resp != nil {
+			_ = resp.Body.Close()
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (m Module) report(event Event, kind EventKind, status int, err error) {
+	if m.observer == nil {
+		return
+	}
+	event.Kind, event.Status, event.Err = kind, status, err
+	m.observer(event)
+}
+
+func statusOf(resp *http.Response) int {
+	if resp == nil {
+		return 0
+	}
+	return resp.StatusCode
+}
+
+func hasHeader(headers map[string]string, name string) bool {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) && strings.TrimSpace(value) != "" {
+			return true
+		}
+	}
+	return false
+}
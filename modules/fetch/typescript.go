@@ -10,31 +10,105 @@ func (m *Module) TypeScriptModule() *spec.Module {
 	return &spec.Module{
 		Name: name,
 		RawDTS: []string{
-			"export function fetch(url: string, options?: FetchOptions): Promise<FetchResponse>;",
+			"import { Readable } from \"stream\";",
+			"export function fetch(input: string | Request, options?: FetchOptions): Promise<Response>;",
 			"export function client(): FetchClientBuilder;",
 			"export namespace auth {",
 			"  function none(): AuthSpec;",
 			"  function bearer(): BearerAuthBuilder;",
 			"}",
-			"export interface FetchOptions {",
+			"export type HeadersInit = Headers | Record<string, string> | [string, string][];",
+			"export type BodyInit = string | Uint8Array | ArrayBuffer | Readable;",
+			"export interface RequestInit {",
 			"  method?: string;",
-			"  headers?: Record<string, string>;",
-			"  body?: string | Uint8Array;",
+			"  headers?: HeadersInit;",
+			"  body?: BodyInit | null;",
+			"  signal?: AbortSignal | null;",
+			"}",
+			"export interface FetchOptions extends RequestInit {",
 			"  json?: unknown;",
 			"  timeout?: string;",
 			"}",
-			"export interface FetchResponse {",
-			"  url: string;",
-			"  status: number;",
-			"  statusText: string;",
-			"  ok: boolean;",
-			"  headers: Record<string, string[]>;",
+			"export class Headers {",
+			"  constructor(init?: HeadersInit);",
+			"  append(name: string, value: string): void;",
+			"  set(name: string, value: string): void;",
+			"  delete(name: string): void;",
+			"  get(name: string): string | null;",
+			"  has(name: string): boolean;",
+			"  getSetCookie(): string[];",
+			"  forEach(callback: (value: string, name: string, headers: Headers) => void, thisArg?: unknown): void;",
+			"  entries(): IterableIterator<[string, string]>;",
+			"  keys(): IterableIterator<string>;",
+			"  values(): IterableIterator<string>;",
+			"  [Symbol.iterator](): IterableIterator<[string, string]>;",
+			"}",
+			"export interface Blob {",
+			"  readonly size: number;",
+			"  readonly type: string;",
+			"  text(): Promise<string>;",
+			"  arrayBuffer(): Promise<ArrayBuffer>;",
+			"  bytes(): Promise<Uint8Array>;",
+			"}",
+			"interface Body {",
+			"  readonly body: Readable | null;",
+			"  readonly bodyUsed: boolean;",
+			"  text(): Promise<string>;",
+			"  json(): Promise<unknown>;",
+			"  arrayBuffer(): Promise<ArrayBuffer>;",
+			"  bytes(): Promise<Uint8Array>;",
+			"  blob(): Promise<Blob>;",
+			"}",
+			"export class Request implements Body {",
+			"  constructor(input: string | Request, init?: RequestInit);",
+			"  readonly method: string;",
+			"  readonly url: string;",
+			"  readonly headers: Headers;",
+			"  readonly signal: AbortSignal;",
+			"  readonly body: Readable | null;",
+			"  readonly bodyUsed: boolean;",
+			"  text(): Promise<string>;",
+			"  json(): Promise<unknown>;",
+			"  arrayBuffer(): Promise<ArrayBuffer>;",
+			"  bytes(): Promise<Uint8Array>;",
+			"  blob(): Promise<Blob>;",
+			"  clone(): Request;",
+			"}",
+			"export class Response implements Body {",
+			"  constructor(body?: BodyInit | null, init?: { status?: number; statusText?: string; headers?: HeadersInit });",
+			"  static json(data: unknown, init?: { status?: number; statusText?: string; headers?: HeadersInit }): Response;",
+			"  readonly url: string;",
+			"  readonly status: number;",
+			"  readonly statusText: string;",
+			"  readonly ok: boolean;",
+			"  readonly redirected: boolean;",
+			"  readonly type: string;",
+			"  readonly headers: Headers;",
+			"  readonly body: Readable | null;",
+			"  readonly bodyUsed: boolean;",
 			"  text(): Promise<string>;",
 			"  json(): Promise<unknown>;",
+			"  arrayBuffer(): Promise<ArrayBuffer>;",
+			"  bytes(): Promise<Uint8Array>;",
+			"  blob(): Promise<Blob>;",
+			"  clone(): Response;",
+			"}",
+			"export type FetchResponse = Response;",
+			"export interface RetryOptions {",
+			"  attempts?: number;",
+			"  backoff?: number | string | { initial?: number | string; max?: number | string; multiplier?: number; jitter?: boolean };",
+			"  retryOn?: Array<number | \"network\" | \"timeout\">;",
+			"}",
+			"export interface CircuitBreakerOptions {",
+			"  failures?: number;",
+			"  resetAfter?: number | string;",
 			"}",
 			"export interface FetchClientBuilder {",
 			"  baseUrl(url: string): FetchClientBuilder;",
-			"  timeout(duration: string): FetchClientBuilder;",
+			"  timeout(duration: number | string): FetchClientBuilder;",
+			"  retry(options?: RetryOptions): FetchClientBuilder;",
+			"  circuitBreaker(options?: CircuitBreakerOptions): FetchClientBuilder;",
+			"  idempotencyKeys(header?: string): FetchClientBuilder;",
 			"  header(name: string, value: string): FetchClientBuilder;",
 			"  auth(spec: AuthSpec): FetchClientBuilder;",
 			"  acceptJson(): FetchClientBuilder;",
@@ -53,6 +127,8 @@ func (m *Module) TypeScriptModule() *spec.Module {
 			"  header(name: string, value: string): RequestBuilder;",
 			"  json(value: unknown): RequestBuilder;",
 			"  body(value: string | Uint8Array): RequestBuilder;",
+			"  retry(options?: RetryOptions): RequestBuilder;",
+			"  idempotencyKey(key: string): RequestBuilder;",
 			"  expectJson(): RequestBuilder;",
 			"  expectText(): RequestBuilder;",
 			"  expectResponse(): RequestBuilder;",
@@ -63,6 +139,7 @@ func (m *Module) TypeScriptModule() *spec.Module {
 			"  token(value: string): BearerAuthBuilder;",
 			"  fromEnv(name: string): BearerAuthBuilder;",
 			"  fromFile(path: string): BearerAuthBuilder;",
+			"  fromProvider(provider: () => string | Promise<string>): BearerAuthBuilder;",
 			"  jsonPath(path: string): BearerAuthBuilder;",
 			"}",
 		},
@@ -1,6 +1,12 @@
 package fs
 
-import "os"
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/go-go-golems/go-go-goja/pkg/fswatch"
+)
 
 type Backend interface {
 	ReadFile(path string) ([]byte, error)
@@ -16,9 +22,56 @@ type Backend interface {
 	RemoveAll(path string) error
 }
 
+// File is an open file returned by FileOpener. Async FileHandle methods call
+// it from background goroutines, so implementations must be safe for
+// concurrent use.
+type File interface {
+	ReadAt(p []byte, off int64) (int, error)
+	WriteAt(p []byte, off int64) (int, error)
+	Stat() (fileStats, error)
+	Truncate(size int64) error
+	Sync() error
+	Close() error
+}
+
+// The interfaces below are optional. The fs module checks for them with a type
+// assertion and falls back to the core Backend methods when a backend does not
+// implement one.
+
+// FileOpener opens files for positional reads and writes. Without it, open()
+// loads the whole file through ReadFile and writes it back through WriteFile on
+// sync and close. flag takes os.O_* values.
+type FileOpener interface {
+	OpenFile(path string, flag int, mode os.FileMode) (File, error)
+}
+
+// LinkBackend supports symbolic links. Without it, lstat behaves like stat,
+// readlink fails with EINVAL, symlink fails with ENOSYS, and realpath returns
+// the cleaned virtual path.
+type LinkBackend interface {
+	Symlink(target, path string) error
+	Readlink(path string) (string, error)
+	Lstat(path string) (fileStats, error)
+	Realpath(path string) (string, error)
+}
+
+// AttrBackend changes file modes and times. Without it, chmod and utimes fail
+// with ENOSYS.
+type AttrBackend interface {
+	Chmod(path string, mode os.FileMode) error
+	Chtimes(path string, atime, mtime time.Time) error
+}
+
+// WatchBackend reports changes to files. Without it, watch() fails with ENOSYS.
+type WatchBackend interface {
+	Watch(ctx context.Context, path string, opts fswatch.Options, handler fswatch.Handler) (*fswatch.Watch, error)
+}
+
 type MountInfo struct {
-	Mount string `json:"mount"`
-	Root  string `json:"root"`
+	Mount    string `json:"mount"`
+	Root     string `json:"root"`
+	Backend  string `json:"backend,omitempty"`
+	ReadOnly bool   `json:"readOnly,omitempty"`
 }
 
 type Capabilities struct {
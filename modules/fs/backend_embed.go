@@ -7,6 +7,7 @@ import (
 	"path"
 	"sort"
 	"strings"
+	"time"
 )
 
 var errReadOnlyFS = errors.New("read-only file system")
@@ -126,6 +127,17 @@ func (b *ReadOnlyFSBackend) RemoveAll(p string) error {
 	return b.mutationError(p, "rm")
 }
 
+func (b *ReadOnlyFSBackend) Chmod(p string, mode os.FileMode) error {
+	_ = mode
+	return b.mutationError(p, "chmod")
+}
+
+func (b *ReadOnlyFSBackend) Chtimes(p string, atime, mtime time.Time) error {
+	_ = atime
+	_ = mtime
+	return b.mutationError(p, "utime")
+}
+
 func (b *ReadOnlyFSBackend) stat(p string) (fs.FileInfo, string, error) {
 	fsys, subpath, ok := b.resolve(p)
 	if !ok {
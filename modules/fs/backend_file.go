@@ -0,0 +1,198 @@
+package fs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// openFile opens path on backend, falling back to a buffered handle when the
+// backend has no FileOpener.
+func openFile(backend Backend, path string, flag int, mode os.FileMode) (File, error) {
+	if opener, ok := backend.(FileOpener); ok {
+		return opener.OpenFile(path, flag, mode)
+	}
+	return openBufferedFile(backend, path, flag, mode)
+}
+
+// osFile adapts *os.File. Go refuses WriteAt on files opened with O_APPEND,
+// so appending handles write at the end instead, as pwrite(2) does on Linux.
+type osFile struct {
+	f      *os.File
+	path   string
+	append bool
+}
+
+func (f *osFile) ReadAt(p []byte, off int64) (int, error) {
+	n, err := f.f.ReadAt(p, off)
+	if err == io.EOF {
+		return n, err
+	}
+	return n, wrapFSError(err, f.path, "read")
+}
+
+func (f *osFile) WriteAt(p []byte, off int64) (int, error) {
+	if f.append {
+		n, err := f.f.Write(p)
+		return n, wrapFSError(err, f.path, "write")
+	}
+	n, err := f.f.WriteAt(p, off)
+	return n, wrapFSError(err, f.path, "write")
+}
+
+func (f *osFile) Stat() (fileStats, error) {
+	info, err := f.f.Stat()
+	if err != nil {
+		return nil, wrapFSError(err, f.path, "fstat")
+	}
+	return statMap(info), nil
+}
+
+func (f *osFile) Truncate(size int64) error {
+	return wrapFSError(f.f.Truncate(size), f.path, "ftruncate")
+}
+
+func (f *osFile) Sync() error {
+	return wrapFSError(f.f.Sync(), f.path, "fsync")
+}
+
+func (f *osFile) Close() error {
+	return wrapFSError(f.f.Close(), f.path, "close")
+}
+
+// bufferedFile is the handle for backends without a FileOpener. It holds the
+// whole file in memory and writes it back through the backend on Sync and
+// Close.
+type bufferedFile struct {
+	backend  Backend
+	path     string
+	mode     os.FileMode
+	writable bool
+	append   bool
+
+	mu     sync.Mutex
+	data   []byte
+	dirty  bool
+	closed bool
+}
+
+func openBufferedFile(backend Backend, path string, flag int, mode os.FileMode) (File, error) {
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	if writable && !CapabilitiesForBackend(backend).Write {
+		return nil, wrapFSError(errReadOnlyFS, path, "open")
+	}
+	f := &bufferedFile{backend: backend, path: path, mode: mode, writable: writable, append: flag&os.O_APPEND != 0}
+	exists := backend.Exists(path)
+	switch {
+	case exists && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, wrapFSError(fs.ErrExist, path, "open")
+	case !exists && flag&os.O_CREATE == 0:
+		return nil, wrapFSError(fs.ErrNotExist, path, "open")
+	case !exists || (writable && flag&os.O_TRUNC != 0):
+		if err := backend.WriteFile(path, nil, mode); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+	if stats, err := backend.Stat(path); err == nil && stats["isDir"] == true {
+		if writable {
+			return nil, wrapFSError(syscall.EISDIR, path, "open")
+		}
+		return f, nil
+	}
+	data, err := backend.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f.data = data
+	return f, nil
+}
+
+func (f *bufferedFile) ReadAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return 0, wrapFSError(fs.ErrClosed, f.path, "read")
+	}
+	if off >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *bufferedFile) WriteAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case f.closed:
+		return 0, wrapFSError(fs.ErrClosed, f.path, "write")
+	case !f.writable:
+		return 0, wrapFSError(syscall.EBADF, f.path, "write")
+	}
+	if f.append {
+		off = int64(len(f.data))
+	}
+	if end := off + int64(len(p)); end > int64(len(f.data)) {
+		f.data = append(f.data, make([]byte, end-int64(len(f.data)))...)
+	}
+	copy(f.data[off:], p)
+	f.dirty = true
+	return len(p), nil
+}
+
+func (f *bufferedFile) Stat() (fileStats, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.flush(); err != nil {
+		return nil, err
+	}
+	return f.backend.Stat(f.path)
+}
+
+func (f *bufferedFile) Truncate(size int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.writable {
+		return wrapFSError(syscall.EINVAL, f.path, "ftruncate")
+	}
+	if size < int64(len(f.data)) {
+		f.data = f.data[:size]
+	} else {
+		f.data = append(f.data, make([]byte, size-int64(len(f.data)))...)
+	}
+	f.dirty = true
+	return nil
+}
+
+func (f *bufferedFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.flush()
+}
+
+func (f *bufferedFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	return f.flush()
+}
+
+func (f *bufferedFile) flush() error {
+	if !f.dirty {
+		return nil
+	}
+	if err := f.backend.WriteFile(f.path, f.data, f.mode); err != nil {
+		return err
+	}
+	f.dirty = false
+	return nil
+}
@@ -0,0 +1,242 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-go-golems/go-go-goja/pkg/fswatch"
+)
+
+var errPathEscapes = fmt.Errorf("path escapes jail root: %w", fs.ErrPermission)
+
+// JailedOSBackend serves a host directory through os.Root. Paths are virtual:
+// "/" is the jail root, ".." cannot climb above it, and symlinks that resolve
+// outside it fail with EACCES instead of being followed.
+type JailedOSBackend struct {
+	root *os.Root
+	dir  string
+}
+
+// NewJailedOSBackend opens dir as the jail root. Close releases the directory
+// handle once no runtime uses the backend any more.
+func NewJailedOSBackend(dir string) (*JailedOSBackend, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	root, err := os.OpenRoot(abs)
+	if err != nil {
+		return nil, err
+	}
+	return &JailedOSBackend{root: root, dir: abs}, nil
+}
+
+func (b *JailedOSBackend) Close() error {
+	return b.root.Close()
+}
+
+func (b *JailedOSBackend) FSCapabilities() Capabilities {
+	return Capabilities{Backend: "jail", Read: true, Write: true, Mounts: []MountInfo{{Mount: "/", Root: b.dir}}}
+}
+
+func (b *JailedOSBackend) ReadFile(p string) ([]byte, error) {
+	data, err := b.root.ReadFile(jailPath(p))
+	return data, jailError(err, p, "open")
+}
+
+func (b *JailedOSBackend) WriteFile(p string, data []byte, mode os.FileMode) error {
+	return jailError(b.root.WriteFile(jailPath(p), data, mode), p, "open")
+}
+
+func (b *JailedOSBackend) Exists(p string) bool {
+	_, err := b.root.Stat(jailPath(p))
+	return err == nil
+}
+
+func (b *JailedOSBackend) Mkdir(p string, recursive bool, mode os.FileMode) error {
+	if recursive {
+		return jailError(b.root.MkdirAll(jailPath(p), mode), p, "mkdir")
+	}
+	return jailError(b.root.Mkdir(jailPath(p), mode), p, "mkdir")
+}
+
+func (b *JailedOSBackend) ReadDir(p string) ([]string, error) {
+	entries, err := fs.ReadDir(b.root.FS(), jailPath(p))
+	if err != nil {
+		return nil, jailError(err, p, "scandir")
+	}
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	return names, nil
+}
+
+func (b *JailedOSBackend) Stat(p string) (fileStats, error) {
+	info, err := b.root.Stat(jailPath(p))
+	if err != nil {
+		return nil, jailError(err, p, "stat")
+	}
+	return statMap(info), nil
+}
+
+func (b *JailedOSBackend) Remove(p string) error {
+	return jailError(b.root.Remove(jailPath(p)), p, "unlink")
+}
+
+func (b *JailedOSBackend) AppendFile(p string, data []byte, mode os.FileMode) error {
+	f, err := b.root.OpenFile(jailPath(p), os.O_APPEND|os.O_WRONLY|os.O_CREATE, mode)
+	if err != nil {
+		return jailError(err, p, "open")
+	}
+	defer func() { _ = f.Close() }()
+	_, err = f.Write(data)
+	return jailError(err, p, "write")
+}
+
+func (b *JailedOSBackend) Rename(oldPath, newPath string) error {
+	return jailError(b.root.Rename(jailPath(oldPath), jailPath(newPath)), oldPath, "rename")
+}
+
+func (b *JailedOSBackend) CopyFile(src, dst string) error {
+	data, err := b.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return b.WriteFile(dst, data, 0o644)
+}
+
+func (b *JailedOSBackend) RemoveAll(p string) error {
+	rel := jailPath(p)
+	if rel == "." {
+		// os.Root cannot remove itself; empty it instead.
+		names, err := b.ReadDir(p)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if err := jailError(b.root.RemoveAll(name), p, "rm"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return jailError(b.root.RemoveAll(rel), p, "rm")
+}
+
+// jailPath turns a virtual path into a path relative to the jail root.
+func jailPath(p string) string {
+	rel := strings.TrimPrefix(cleanVirtualPath(p), "/")
+	if rel == "" {
+		return "."
+	}
+	return rel
+}
+
+// jailError maps os.Root's escape error, which wraps no sentinel, to EACCES.
+func jailError(err error, p, syscall string) error {
+	if err == nil {
+		return nil
+	}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) && strings.Contains(pathErr.Err.Error(), "path escapes from parent") {
+		err = errPathEscapes
+	}
+	return wrapFSError(err, p, syscall)
+}
+
+func (b *JailedOSBackend) OpenFile(p string, flag int, mode os.FileMode) (File, error) {
+	f, err := b.root.OpenFile(jailPath(p), flag, mode)
+	if err != nil {
+		return nil, jailError(err, p, "open")
+	}
+	return &osFile{f: f, path: p, append: flag&os.O_APPEND != 0}, nil
+}
+
+func (b *JailedOSBackend) Symlink(target, p string) error {
+	return jailError(b.root.Symlink(target, jailPath(p)), p, "symlink")
+}
+
+func (b *JailedOSBackend) Readlink(p string) (string, error) {
+	target, err := b.root.Readlink(jailPath(p))
+	return target, jailError(err, p, "readlink")
+}
+
+func (b *JailedOSBackend) Lstat(p string) (fileStats, error) {
+	info, err := b.root.Lstat(jailPath(p))
+	if err != nil {
+		return nil, jailError(err, p, "lstat")
+	}
+	return statMap(info), nil
+}
+
+// Realpath resolves symlinks one component at a time, since os.Root has no
+// realpath, and returns a virtual path. Links that leave the jail fail the
+// same way os.Root fails to follow them.
+func (b *JailedOSBackend) Realpath(p string) (string, error) {
+	const maxLinks = 40
+	parts := strings.Split(jailPath(p), "/")
+	resolved, links := "/", 0
+	for len(parts) > 0 {
+		part := parts[0]
+		parts = parts[1:]
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if resolved == "/" {
+				return "", wrapFSError(errPathEscapes, p, "realpath")
+			}
+			resolved = path.Dir(resolved)
+			continue
+		}
+		next := path.Join(resolved, part)
+		info, err := b.root.Lstat(jailPath(next))
+		if err != nil {
+			return "", jailError(err, p, "realpath")
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+		if links++; links > maxLinks {
+			return "", wrapFSError(syscall.ELOOP, p, "realpath")
+		}
+		target, err := b.root.Readlink(jailPath(next))
+		if err != nil {
+			return "", jailError(err, p, "realpath")
+		}
+		if filepath.IsAbs(target) {
+			return "", wrapFSError(errPathEscapes, p, "realpath")
+		}
+		parts = append(strings.Split(filepath.ToSlash(target), "/"), parts...)
+	}
+	return resolved, nil
+}
+
+func (b *JailedOSBackend) Chmod(p string, mode os.FileMode) error {
+	return jailError(b.root.Chmod(jailPath(p), mode), p, "chmod")
+}
+
+func (b *JailedOSBackend) Chtimes(p string, atime, mtime time.Time) error {
+	return jailError(b.root.Chtimes(jailPath(p), atime, mtime), p, "utime")
+}
+
+// Watch checks p through the jail and then watches the host path it names.
+// Recursive watches do not descend into symlinked directories.
+func (b *JailedOSBackend) Watch(ctx context.Context, p string, opts fswatch.Options, handler fswatch.Handler) (*fswatch.Watch, error) {
+	resolved, err := b.Realpath(p)
+	if err != nil {
+		return nil, err
+	}
+	watch, err := fswatch.Start(ctx, filepath.Join(b.dir, filepath.FromSlash(jailPath(resolved))), opts, handler)
+	return watch, wrapFSError(err, p, "watch")
+}
@@ -0,0 +1,302 @@
+package fs
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// MemoryBackend keeps files in memory. Paths are virtual: relative paths
+// resolve against "/". It is safe for concurrent use, which lets the async
+// fs functions share it with sync ones, and is mainly meant for tests and as
+// the writable layer of an OverlayBackend.
+type MemoryBackend struct {
+	mu    sync.RWMutex
+	nodes map[string]*memNode
+	now   func() time.Time
+}
+
+type memNode struct {
+	dir     bool
+	data    []byte
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	b := &MemoryBackend{nodes: map[string]*memNode{}, now: time.Now}
+	b.nodes["/"] = &memNode{dir: true, mode: os.ModeDir | 0o755, modTime: b.now()}
+	return b
+}
+
+func (b *MemoryBackend) FSCapabilities() Capabilities {
+	return Capabilities{Backend: "memory", Read: true, Write: true}
+}
+
+func (b *MemoryBackend) ReadFile(p string) ([]byte, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	node, err := b.file(cleanVirtualPath(p))
+	if err != nil {
+		return nil, wrapFSError(err, p, "open")
+	}
+	return append([]byte(nil), node.data...), nil
+}
+
+func (b *MemoryBackend) WriteFile(p string, data []byte, mode os.FileMode) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return wrapFSError(b.put(cleanVirtualPath(p), append([]byte(nil), data...), mode), p, "open")
+}
+
+func (b *MemoryBackend) Exists(p string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	_, ok := b.nodes[cleanVirtualPath(p)]
+	return ok
+}
+
+func (b *MemoryBackend) Mkdir(p string, recursive bool, mode os.FileMode) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	clean := cleanVirtualPath(p)
+	if node, ok := b.nodes[clean]; ok {
+		if recursive && node.dir {
+			return nil
+		}
+		return wrapFSError(fs.ErrExist, p, "mkdir")
+	}
+	if recursive {
+		parent := path.Dir(clean)
+		for _, dir := range ancestors(parent) {
+			if node, ok := b.nodes[dir]; ok {
+				if !node.dir {
+					return wrapFSError(syscall.ENOTDIR, p, "mkdir")
+				}
+				continue
+			}
+			b.nodes[dir] = &memNode{dir: true, mode: os.ModeDir | mode.Perm(), modTime: b.now()}
+		}
+	} else if err := b.parentDir(clean); err != nil {
+		return wrapFSError(err, p, "mkdir")
+	}
+	b.nodes[clean] = &memNode{dir: true, mode: os.ModeDir | mode.Perm(), modTime: b.now()}
+	return nil
+}
+
+func (b *MemoryBackend) ReadDir(p string) ([]string, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	clean := cleanVirtualPath(p)
+	node, ok := b.nodes[clean]
+	if !ok {
+		return nil, wrapFSError(fs.ErrNotExist, p, "scandir")
+	}
+	if !node.dir {
+		return nil, wrapFSError(syscall.ENOTDIR, p, "scandir")
+	}
+	return b.children(clean), nil
+}
+
+func (b *MemoryBackend) Stat(p string) (fileStats, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	clean := cleanVirtualPath(p)
+	node, ok := b.nodes[clean]
+	if !ok {
+		return nil, wrapFSError(fs.ErrNotExist, p, "stat")
+	}
+	return statMap(memFileInfo{name: path.Base(clean), node: node}), nil
+}
+
+func (b *MemoryBackend) Remove(p string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	clean := cleanVirtualPath(p)
+	node, ok := b.nodes[clean]
+	switch {
+	case !ok:
+		return wrapFSError(fs.ErrNotExist, p, "unlink")
+	case clean == "/":
+		return wrapFSError(fs.ErrPermission, p, "unlink")
+	case node.dir && len(b.children(clean)) > 0:
+		return wrapFSError(syscall.ENOTEMPTY, p, "unlink")
+	}
+	delete(b.nodes, clean)
+	return nil
+}
+
+func (b *MemoryBackend) AppendFile(p string, data []byte, mode os.FileMode) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	clean := cleanVirtualPath(p)
+	if node, ok := b.nodes[clean]; ok {
+		if node.dir {
+			return wrapFSError(syscall.EISDIR, p, "open")
+		}
+		node.data = append(node.data, data...)
+		node.modTime = b.now()
+		return nil
+	}
+	return wrapFSError(b.put(clean, append([]byte(nil), data...), mode), p, "open")
+}
+
+func (b *MemoryBackend) Rename(oldPath, newPath string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	from, to := cleanVirtualPath(oldPath), cleanVirtualPath(newPath)
+	node, ok := b.nodes[from]
+	if !ok {
+		return wrapFSError(fs.ErrNotExist, oldPath, "rename")
+	}
+	if from == to {
+		return nil
+	}
+	if node.dir && strings.HasPrefix(to, from+"/") {
+		return wrapFSError(syscall.EINVAL, oldPath, "rename")
+	}
+	if err := b.parentDir(to); err != nil {
+		return wrapFSError(err, newPath, "rename")
+	}
+	if existing, ok := b.nodes[to]; ok {
+		if existing.dir != node.dir || (existing.dir && len(b.children(to)) > 0) {
+			return wrapFSError(fs.ErrExist, newPath, "rename")
+		}
+	}
+	moved := map[string]*memNode{to: node}
+	delete(b.nodes, from)
+	if node.dir {
+		for key, child := range b.nodes {
+			if strings.HasPrefix(key, from+"/") {
+				moved[to+strings.TrimPrefix(key, from)] = child
+				delete(b.nodes, key)
+			}
+		}
+	}
+	for key, child := range moved {
+		b.nodes[key] = child
+	}
+	return nil
+}
+
+func (b *MemoryBackend) CopyFile(src, dst string) error {
+	data, err := b.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return b.WriteFile(dst, data, 0o644)
+}
+
+func (b *MemoryBackend) RemoveAll(p string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	clean := cleanVirtualPath(p)
+	for key := range b.nodes {
+		if key != "/" && (key == clean || strings.HasPrefix(key, strings.TrimSuffix(clean, "/")+"/")) {
+			delete(b.nodes, key)
+		}
+	}
+	return nil
+}
+
+// file returns the regular file at clean.
+func (b *MemoryBackend) file(clean string) (*memNode, error) {
+	node, ok := b.nodes[clean]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	if node.dir {
+		return nil, syscall.EISDIR
+	}
+	return node, nil
+}
+
+// put creates or replaces the regular file at clean.
+func (b *MemoryBackend) put(clean string, data []byte, mode os.FileMode) error {
+	if node, ok := b.nodes[clean]; ok {
+		if node.dir {
+			return syscall.EISDIR
+		}
+		node.data, node.modTime = data, b.now()
+		return nil
+	}
+	if err := b.parentDir(clean); err != nil {
+		return err
+	}
+	b.nodes[clean] = &memNode{data: data, mode: mode.Perm(), modTime: b.now()}
+	return nil
+}
+
+func (b *MemoryBackend) parentDir(clean string) error {
+	parent, ok := b.nodes[path.Dir(clean)]
+	if !ok {
+		return fs.ErrNotExist
+	}
+	if !parent.dir {
+		return syscall.ENOTDIR
+	}
+	return nil
+}
+
+func (b *MemoryBackend) children(dir string) []string {
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	names := []string{}
+	for key := range b.nodes {
+		if key != "/" && strings.HasPrefix(key, prefix) && !strings.Contains(key[len(prefix):], "/") {
+			names = append(names, key[len(prefix):])
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ancestors returns clean and its parent directories, outermost first.
+func ancestors(clean string) []string {
+	var out []string
+	for dir := clean; ; dir = path.Dir(dir) {
+		out = append([]string{dir}, out...)
+		if dir == "/" {
+			return out
+		}
+	}
+}
+
+type memFileInfo struct {
+	name string
+	node *memNode
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return int64(len(i.node.data)) }
+func (i memFileInfo) Mode() os.FileMode  { return i.node.mode }
+func (i memFileInfo) ModTime() time.Time { return i.node.modTime }
+func (i memFileInfo) IsDir() bool        { return i.node.dir }
+func (i memFileInfo) Sys() any           { return nil }
+
+func (b *MemoryBackend) Chmod(p string, mode os.FileMode) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	node, ok := b.nodes[cleanVirtualPath(p)]
+	if !ok {
+		return wrapFSError(fs.ErrNotExist, p, "chmod")
+	}
+	node.mode = node.mode&^os.ModePerm | mode.Perm()
+	return nil
+}
+
+// Chtimes sets the modification time; memory files keep no access time.
+func (b *MemoryBackend) Chtimes(p string, _, mtime time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	node, ok := b.nodes[cleanVirtualPath(p)]
+	if !ok {
+		return wrapFSError(fs.ErrNotExist, p, "utime")
+	}
+	node.modTime = mtime
+	return nil
+}
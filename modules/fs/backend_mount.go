@@ -0,0 +1,365 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-go-golems/go-go-goja/pkg/fswatch"
+)
+
+// BackendMount attaches a backend at a virtual path prefix.
+type BackendMount struct {
+	Mount   string
+	Backend Backend
+}
+
+// MountBackend routes each path to the backend mounted at its longest
+// matching prefix, rebasing it so the mounted backend sees its own "/".
+// Directories above mount points are synthesized so readdir("/") lists them.
+// Mounted backends must use virtual paths (memory, embed, overlay, jail).
+type MountBackend struct {
+	mounts []BackendMount
+}
+
+func NewMountBackend(mounts ...BackendMount) *MountBackend {
+	out := &MountBackend{mounts: make([]BackendMount, 0, len(mounts))}
+	for _, mount := range mounts {
+		if mount.Backend == nil {
+			continue
+		}
+		mount.Mount = cleanVirtualMount(mount.Mount)
+		out.mounts = append(out.mounts, mount)
+	}
+	sort.SliceStable(out.mounts, func(i, j int) bool {
+		return len(out.mounts[i].Mount) > len(out.mounts[j].Mount)
+	})
+	return out
+}
+
+// FSCapabilities flattens the mounted backends into one mount list. Embedded
+// is only reported when every mount is embedded, so the sandbox still guards
+// writable mounts.
+func (b *MountBackend) FSCapabilities() Capabilities {
+	caps := Capabilities{Backend: "mount", Embedded: len(b.mounts) > 0}
+	for _, mount := range b.mounts {
+		inner := CapabilitiesForBackend(mount.Backend)
+		caps.Read = caps.Read || inner.Read
+		caps.Write = caps.Write || inner.Write
+		caps.Embedded = caps.Embedded && inner.Embedded
+		if len(inner.Mounts) == 0 {
+			caps.Mounts = append(caps.Mounts, MountInfo{Mount: mount.Mount, Backend: inner.Backend, ReadOnly: !inner.Write})
+			continue
+		}
+		for _, im := range inner.Mounts {
+			caps.Mounts = append(caps.Mounts, MountInfo{
+				Mount:    path.Join(mount.Mount, im.Mount),
+				Root:     im.Root,
+				Backend:  inner.Backend,
+				ReadOnly: !inner.Write,
+			})
+		}
+	}
+	sort.Slice(caps.Mounts, func(i, j int) bool { return caps.Mounts[i].Mount < caps.Mounts[j].Mount })
+	return caps
+}
+
+func (b *MountBackend) ReadFile(p string) ([]byte, error) {
+	backend, inner, ok := b.resolve(p)
+	if !ok {
+		return nil, b.missing(p, "open")
+	}
+	data, err := backend.ReadFile(inner)
+	return data, rebaseFSError(err, p)
+}
+
+func (b *MountBackend) WriteFile(p string, data []byte, mode os.FileMode) error {
+	backend, inner, ok := b.resolve(p)
+	if !ok {
+		return b.missing(p, "open")
+	}
+	return rebaseFSError(backend.WriteFile(inner, data, mode), p)
+}
+
+func (b *MountBackend) Exists(p string) bool {
+	if backend, inner, ok := b.resolve(p); ok && backend.Exists(inner) {
+		return true
+	}
+	return len(b.mountChildren(p)) > 0
+}
+
+func (b *MountBackend) Mkdir(p string, recursive bool, mode os.FileMode) error {
+	backend, inner, ok := b.resolve(p)
+	if !ok {
+		if len(b.mountChildren(p)) == 0 {
+			return wrapFSError(fs.ErrNotExist, p, "mkdir")
+		}
+		if recursive {
+			return nil
+		}
+		return wrapFSError(fs.ErrExist, p, "mkdir")
+	}
+	return rebaseFSError(backend.Mkdir(inner, recursive, mode), p)
+}
+
+func (b *MountBackend) ReadDir(p string) ([]string, error) {
+	seen := map[string]bool{}
+	for _, name := range b.mountChildren(p) {
+		seen[name] = true
+	}
+	backend, inner, ok := b.resolve(p)
+	if ok {
+		names, err := backend.ReadDir(inner)
+		if err != nil && len(seen) == 0 {
+			return nil, rebaseFSError(err, p)
+		}
+		for _, name := range names {
+			seen[name] = true
+		}
+	} else if len(seen) == 0 {
+		return nil, wrapFSError(fs.ErrNotExist, p, "scandir")
+	}
+	out := make([]string, 0, len(seen))
+	for name := range seen {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func (b *MountBackend) Stat(p string) (fileStats, error) {
+	backend, inner, ok := b.resolve(p)
+	if ok {
+		stats, err := backend.Stat(inner)
+		if err == nil || len(b.mountChildren(p)) == 0 {
+			return stats, rebaseFSError(err, p)
+		}
+	}
+	if len(b.mountChildren(p)) == 0 {
+		return nil, wrapFSError(fs.ErrNotExist, p, "stat")
+	}
+	node := &memNode{dir: true, mode: os.ModeDir | 0o555}
+	return statMap(memFileInfo{name: path.Base(cleanVirtualPath(p)), node: node}), nil
+}
+
+func (b *MountBackend) Remove(p string) error {
+	backend, inner, ok := b.resolve(p)
+	if !ok {
+		return b.missing(p, "unlink")
+	}
+	return rebaseFSError(backend.Remove(inner), p)
+}
+
+func (b *MountBackend) AppendFile(p string, data []byte, mode os.FileMode) error {
+	backend, inner, ok := b.resolve(p)
+	if !ok {
+		return b.missing(p, "open")
+	}
+	return rebaseFSError(backend.AppendFile(inner, data, mode), p)
+}
+
+// Rename moves files across mounts by copying and removing them. Moving a
+// directory across mounts fails with EXDEV, as rename(2) does.
+func (b *MountBackend) Rename(oldPath, newPath string) error {
+	from, fromInner, ok := b.resolve(oldPath)
+	if !ok {
+		return b.missing(oldPath, "rename")
+	}
+	to, toInner, ok := b.resolve(newPath)
+	if !ok {
+		return b.missing(newPath, "rename")
+	}
+	if b.sameMount(oldPath, newPath) {
+		return rebaseFSError(from.Rename(fromInner, toInner), oldPath)
+	}
+	stats, err := from.Stat(fromInner)
+	if err != nil {
+		return rebaseFSError(err, oldPath)
+	}
+	if stats["isDir"] == true {
+		return wrapFSError(syscall.EXDEV, oldPath, "rename")
+	}
+	data, err := from.ReadFile(fromInner)
+	if err != nil {
+		return rebaseFSError(err, oldPath)
+	}
+	if err := to.WriteFile(toInner, data, statPerm(stats)); err != nil {
+		return rebaseFSError(err, newPath)
+	}
+	return rebaseFSError(from.Remove(fromInner), oldPath)
+}
+
+func (b *MountBackend) CopyFile(src, dst string) error {
+	from, fromInner, ok := b.resolve(src)
+	if !ok {
+		return b.missing(src, "copyfile")
+	}
+	to, toInner, ok := b.resolve(dst)
+	if !ok {
+		return b.missing(dst, "copyfile")
+	}
+	if b.sameMount(src, dst) {
+		return rebaseFSError(from.CopyFile(fromInner, toInner), src)
+	}
+	data, err := from.ReadFile(fromInner)
+	if err != nil {
+		return rebaseFSError(err, src)
+	}
+	return rebaseFSError(to.WriteFile(toInner, data, 0o644), dst)
+}
+
+func (b *MountBackend) RemoveAll(p string) error {
+	backend, inner, ok := b.resolve(p)
+	if !ok {
+		if len(b.mountChildren(p)) > 0 {
+			return wrapFSError(errReadOnlyFS, p, "rm")
+		}
+		return nil
+	}
+	return rebaseFSError(backend.RemoveAll(inner), p)
+}
+
+func (b *MountBackend) OpenFile(p string, flag int, mode os.FileMode) (File, error) {
+	backend, inner, ok := b.resolve(p)
+	if !ok {
+		return nil, b.missing(p, "open")
+	}
+	f, err := openFile(backend, inner, flag, mode)
+	return f, rebaseFSError(err, p)
+}
+
+func (b *MountBackend) Symlink(target, p string) error {
+	backend, inner, ok := b.resolve(p)
+	if !ok {
+		return b.missing(p, "symlink")
+	}
+	return rebaseFSError(symlinkPath(backend, target, inner), p)
+}
+
+func (b *MountBackend) Readlink(p string) (string, error) {
+	backend, inner, ok := b.resolve(p)
+	if !ok {
+		return "", b.missing(p, "readlink")
+	}
+	target, err := readlinkPath(backend, inner)
+	return target, rebaseFSError(err, p)
+}
+
+func (b *MountBackend) Lstat(p string) (fileStats, error) {
+	backend, inner, ok := b.resolve(p)
+	if !ok || len(b.mountChildren(p)) > 0 {
+		return b.Stat(p)
+	}
+	stats, err := lstatPath(backend, inner)
+	return stats, rebaseFSError(err, p)
+}
+
+// Realpath resolves p inside its mount and maps the result back into the
+// mount table's namespace.
+func (b *MountBackend) Realpath(p string) (string, error) {
+	mount, ok := b.mountFor(p)
+	if !ok {
+		if len(b.mountChildren(p)) > 0 {
+			return cleanVirtualPath(p), nil
+		}
+		return "", wrapFSError(fs.ErrNotExist, p, "realpath")
+	}
+	_, inner, _ := b.resolve(p)
+	resolved, err := realpathPath(mount.Backend, inner)
+	if err != nil {
+		return "", rebaseFSError(err, p)
+	}
+	return path.Join(mount.Mount, resolved), nil
+}
+
+func (b *MountBackend) Chmod(p string, mode os.FileMode) error {
+	backend, inner, ok := b.resolve(p)
+	if !ok {
+		return b.missing(p, "chmod")
+	}
+	return rebaseFSError(chmodPath(backend, inner, mode), p)
+}
+
+func (b *MountBackend) Chtimes(p string, atime, mtime time.Time) error {
+	backend, inner, ok := b.resolve(p)
+	if !ok {
+		return b.missing(p, "utime")
+	}
+	return rebaseFSError(chtimesPath(backend, inner, atime, mtime), p)
+}
+
+func (b *MountBackend) Watch(ctx context.Context, p string, opts fswatch.Options, handler fswatch.Handler) (*fswatch.Watch, error) {
+	backend, inner, ok := b.resolve(p)
+	if !ok {
+		return nil, b.missing(p, "watch")
+	}
+	watch, err := watchPath(ctx, backend, inner, opts, handler)
+	return watch, rebaseFSError(err, p)
+}
+
+func (b *MountBackend) resolve(p string) (Backend, string, bool) {
+	mount, ok := b.mountFor(p)
+	if !ok {
+		return nil, "", false
+	}
+	clean := cleanVirtualPath(p)
+	if mount.Mount == "/" {
+		return mount.Backend, clean, true
+	}
+	return mount.Backend, cleanVirtualPath(strings.TrimPrefix(clean, mount.Mount)), true
+}
+
+func (b *MountBackend) mountFor(p string) (BackendMount, bool) {
+	clean := cleanVirtualPath(p)
+	for _, mount := range b.mounts {
+		if mount.Mount == "/" || clean == mount.Mount || strings.HasPrefix(clean, mount.Mount+"/") {
+			return mount, true
+		}
+	}
+	return BackendMount{}, false
+}
+
+func (b *MountBackend) sameMount(a, c string) bool {
+	ma, _ := b.mountFor(a)
+	mc, _ := b.mountFor(c)
+	return ma.Mount == mc.Mount
+}
+
+// mountChildren returns the names of mount points directly below p.
+func (b *MountBackend) mountChildren(p string) []string {
+	prefix := strings.TrimSuffix(cleanVirtualPath(p), "/") + "/"
+	var names []string
+	for _, mount := range b.mounts {
+		if mount.Mount == "/" || !strings.HasPrefix(mount.Mount, prefix) {
+			continue
+		}
+		name, _, _ := strings.Cut(mount.Mount[len(prefix):], "/")
+		names = append(names, name)
+	}
+	return names
+}
+
+// missing reports a path no backend serves. Synthesized directories above
+// mount points cannot be changed.
+func (b *MountBackend) missing(p, syscall string) error {
+	if len(b.mountChildren(p)) > 0 {
+		return wrapFSError(errReadOnlyFS, p, syscall)
+	}
+	return wrapFSError(fs.ErrNotExist, p, syscall)
+}
+
+// rebaseFSError reports err against the caller's path rather than the path
+// the mounted backend saw.
+func rebaseFSError(err error, p string) error {
+	var opErr *fsOpError
+	if errors.As(err, &opErr) {
+		return &fsOpError{err: opErr.err, path: p, syscall: opErr.syscall}
+	}
+	return err
+}
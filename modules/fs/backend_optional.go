@@ -0,0 +1,79 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/go-go-golems/go-go-goja/pkg/fswatch"
+)
+
+// These helpers call a backend's optional interface when it has one and fall
+// back as documented on the interface otherwise.
+
+func lstatPath(backend Backend, path string) (fileStats, error) {
+	if links, ok := backend.(LinkBackend); ok {
+		return links.Lstat(path)
+	}
+	return backend.Stat(path)
+}
+
+func readlinkPath(backend Backend, path string) (string, error) {
+	if links, ok := backend.(LinkBackend); ok {
+		return links.Readlink(path)
+	}
+	if !backend.Exists(path) {
+		return "", wrapFSError(fs.ErrNotExist, path, "readlink")
+	}
+	return "", wrapFSError(syscall.EINVAL, path, "readlink")
+}
+
+func symlinkPath(backend Backend, target, path string) error {
+	if links, ok := backend.(LinkBackend); ok {
+		return links.Symlink(target, path)
+	}
+	return unsupported(backend, path, "symlink")
+}
+
+func realpathPath(backend Backend, path string) (string, error) {
+	if links, ok := backend.(LinkBackend); ok {
+		return links.Realpath(path)
+	}
+	if !backend.Exists(path) {
+		return "", wrapFSError(fs.ErrNotExist, path, "realpath")
+	}
+	return cleanVirtualPath(path), nil
+}
+
+func chmodPath(backend Backend, path string, mode os.FileMode) error {
+	if attrs, ok := backend.(AttrBackend); ok {
+		return attrs.Chmod(path, mode)
+	}
+	return unsupported(backend, path, "chmod")
+}
+
+func chtimesPath(backend Backend, path string, atime, mtime time.Time) error {
+	if attrs, ok := backend.(AttrBackend); ok {
+		return attrs.Chtimes(path, atime, mtime)
+	}
+	return unsupported(backend, path, "utime")
+}
+
+func watchPath(ctx context.Context, backend Backend, path string, opts fswatch.Options, handler fswatch.Handler) (*fswatch.Watch, error) {
+	if watcher, ok := backend.(WatchBackend); ok {
+		return watcher.Watch(ctx, path, opts, handler)
+	}
+	return nil, wrapFSError(errors.ErrUnsupported, path, "watch")
+}
+
+// unsupported reports a mutation the backend cannot perform: EROFS on
+// read-only backends, ENOSYS otherwise.
+func unsupported(backend Backend, path, syscall string) error {
+	if !CapabilitiesForBackend(backend).Write {
+		return wrapFSError(errReadOnlyFS, path, syscall)
+	}
+	return wrapFSError(errors.ErrUnsupported, path, syscall)
+}
@@ -0,0 +1,422 @@
+package fs
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// OverlayBackend layers a writable upper backend over a lower one that is
+// never modified. Reads prefer the upper layer; writes copy files and their
+// parent directories up first; removing a lower path records a whiteout that
+// hides it until it is written again. Both layers are addressed with virtual
+// paths, so put a JailedOSBackend rather than OSBackend underneath to overlay a
+// host directory.
+type OverlayBackend struct {
+	lower Backend
+	upper Backend
+
+	mu sync.Mutex
+	// whiteouts hide lower paths and everything below them.
+	whiteouts map[string]bool
+	// opaque marks upper directories re-created over a whiteout; their
+	// lower contents stay hidden.
+	opaque map[string]bool
+}
+
+// NewOverlayBackend returns a copy-on-write view of lower. A nil upper uses a
+// fresh MemoryBackend, which keeps every change in memory.
+func NewOverlayBackend(lower, upper Backend) *OverlayBackend {
+	if upper == nil {
+		upper = NewMemoryBackend()
+	}
+	return &OverlayBackend{lower: lower, upper: upper, whiteouts: map[string]bool{}, opaque: map[string]bool{}}
+}
+
+func (o *OverlayBackend) FSCapabilities() Capabilities {
+	return Capabilities{Backend: "overlay", Read: true, Write: true, Mounts: CapabilitiesForBackend(o.lower).Mounts}
+}
+
+func (o *OverlayBackend) ReadFile(p string) ([]byte, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.readFile(cleanVirtualPath(p))
+}
+
+func (o *OverlayBackend) WriteFile(p string, data []byte, mode os.FileMode) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.writeFile(cleanVirtualPath(p), data, mode)
+}
+
+func (o *OverlayBackend) Exists(p string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.exists(cleanVirtualPath(p))
+}
+
+func (o *OverlayBackend) Mkdir(p string, recursive bool, mode os.FileMode) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	clean := cleanVirtualPath(p)
+	if o.exists(clean) {
+		if stats, err := o.stat(clean); err == nil && recursive && stats["isDir"] == true {
+			return nil
+		}
+		return wrapFSError(fs.ErrExist, p, "mkdir")
+	}
+	if err := o.copyUpParents(clean, "mkdir", recursive, mode); err != nil {
+		return err
+	}
+	return o.upperDir(clean, mode)
+}
+
+func (o *OverlayBackend) ReadDir(p string) ([]string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.readDir(cleanVirtualPath(p))
+}
+
+func (o *OverlayBackend) Stat(p string) (fileStats, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.stat(cleanVirtualPath(p))
+}
+
+func (o *OverlayBackend) Remove(p string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.remove(cleanVirtualPath(p))
+}
+
+func (o *OverlayBackend) AppendFile(p string, data []byte, mode os.FileMode) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	clean := cleanVirtualPath(p)
+	if err := o.copyUpParents(clean, "open", false, 0o755); err != nil {
+		return err
+	}
+	if err := o.copyUp(clean); err != nil {
+		return err
+	}
+	if err := o.upper.AppendFile(clean, data, mode); err != nil {
+		return err
+	}
+	o.unwhiteout(clean)
+	return nil
+}
+
+func (o *OverlayBackend) Rename(oldPath, newPath string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	from, to := cleanVirtualPath(oldPath), cleanVirtualPath(newPath)
+	stats, err := o.stat(from)
+	if err != nil {
+		return wrapFSError(fs.ErrNotExist, oldPath, "rename")
+	}
+	if from == to {
+		return nil
+	}
+	isDir := stats["isDir"] == true
+	if isDir && strings.HasPrefix(to, from+"/") {
+		return wrapFSError(syscall.EINVAL, oldPath, "rename")
+	}
+	if existing, err := o.stat(to); err == nil && (isDir || existing["isDir"] == true) {
+		return wrapFSError(fs.ErrExist, newPath, "rename")
+	}
+	if err := o.copyTree(from, to); err != nil {
+		return err
+	}
+	return o.removeAll(from)
+}
+
+func (o *OverlayBackend) CopyFile(src, dst string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	data, err := o.readFile(cleanVirtualPath(src))
+	if err != nil {
+		return err
+	}
+	return o.writeFile(cleanVirtualPath(dst), data, 0o644)
+}
+
+func (o *OverlayBackend) RemoveAll(p string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.removeAll(cleanVirtualPath(p))
+}
+
+func (o *OverlayBackend) readFile(clean string) ([]byte, error) {
+	if o.upper.Exists(clean) {
+		return o.upper.ReadFile(clean)
+	}
+	if o.lowerVisible(clean) {
+		return o.lower.ReadFile(clean)
+	}
+	return nil, wrapFSError(fs.ErrNotExist, clean, "open")
+}
+
+func (o *OverlayBackend) writeFile(clean string, data []byte, mode os.FileMode) error {
+	if err := o.copyUpParents(clean, "open", false, 0o755); err != nil {
+		return err
+	}
+	if err := o.upper.WriteFile(clean, data, mode); err != nil {
+		return err
+	}
+	o.unwhiteout(clean)
+	return nil
+}
+
+func (o *OverlayBackend) exists(clean string) bool {
+	return o.upper.Exists(clean) || o.lowerVisible(clean)
+}
+
+func (o *OverlayBackend) stat(clean string) (fileStats, error) {
+	if o.upper.Exists(clean) {
+		return o.upper.Stat(clean)
+	}
+	if o.lowerVisible(clean) {
+		return o.lower.Stat(clean)
+	}
+	return nil, wrapFSError(fs.ErrNotExist, clean, "stat")
+}
+
+func (o *OverlayBackend) readDir(clean string) ([]string, error) {
+	inUpper, inLower := o.upper.Exists(clean), o.lowerVisible(clean) && !o.opaque[clean]
+	if !inUpper && !inLower {
+		return nil, wrapFSError(fs.ErrNotExist, clean, "scandir")
+	}
+	seen := map[string]bool{}
+	if inUpper {
+		names, err := o.upper.ReadDir(clean)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			seen[name] = true
+		}
+	}
+	if inLower {
+		names, err := o.lower.ReadDir(clean)
+		if err != nil && !inUpper {
+			return nil, err
+		}
+		for _, name := range names {
+			if !o.whiteouts[path.Join(clean, name)] {
+				seen[name] = true
+			}
+		}
+	}
+	out := make([]string, 0, len(seen))
+	for name := range seen {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func (o *OverlayBackend) remove(clean string) error {
+	stats, err := o.stat(clean)
+	if err != nil {
+		return wrapFSError(fs.ErrNotExist, clean, "unlink")
+	}
+	if stats["isDir"] == true {
+		if names, err := o.readDir(clean); err == nil && len(names) > 0 {
+			return wrapFSError(syscall.ENOTEMPTY, clean, "unlink")
+		}
+	}
+	if o.upper.Exists(clean) {
+		if err := o.upper.Remove(clean); err != nil {
+			return err
+		}
+	}
+	o.whiteout(clean)
+	return nil
+}
+
+func (o *OverlayBackend) removeAll(clean string) error {
+	if o.upper.Exists(clean) {
+		if err := o.upper.RemoveAll(clean); err != nil {
+			return err
+		}
+	}
+	for key := range o.whiteouts {
+		if strings.HasPrefix(key, clean+"/") {
+			delete(o.whiteouts, key)
+		}
+	}
+	for key := range o.opaque {
+		if strings.HasPrefix(key, clean+"/") {
+			delete(o.opaque, key)
+		}
+	}
+	o.whiteout(clean)
+	return nil
+}
+
+// whiteout hides clean in the lower layer if it is visible there. The root
+// cannot be whited out, so it is made opaque instead.
+func (o *OverlayBackend) whiteout(clean string) {
+	if !o.lowerVisible(clean) {
+		return
+	}
+	if clean == "/" {
+		o.opaque[clean] = true
+		return
+	}
+	o.whiteouts[clean] = true
+	delete(o.opaque, clean)
+}
+
+// unwhiteout drops the whiteout on a path that now exists in the upper layer.
+// The path becomes opaque so that lower entries below it stay hidden.
+func (o *OverlayBackend) unwhiteout(clean string) {
+	if o.whiteouts[clean] {
+		delete(o.whiteouts, clean)
+		o.opaque[clean] = true
+	}
+}
+
+// lowerVisible reports whether clean exists in the lower layer and is not
+// hidden by a whiteout or an opaque directory above it.
+func (o *OverlayBackend) lowerVisible(clean string) bool {
+	for _, dir := range ancestors(clean) {
+		if o.whiteouts[dir] || (dir != clean && o.opaque[dir]) {
+			return false
+		}
+	}
+	return o.lower.Exists(clean)
+}
+
+// copyUpParents makes the parent directories of clean exist in the upper
+// layer. Missing parents are an error unless create is set.
+func (o *OverlayBackend) copyUpParents(clean, syscall string, create bool, mode os.FileMode) error {
+	dirs := ancestors(path.Dir(clean))
+	for _, dir := range dirs[1:] {
+		if !create && !o.exists(dir) {
+			return wrapFSError(fs.ErrNotExist, clean, syscall)
+		}
+		if err := o.upperDir(dir, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upperDir makes dir exist as a directory in the upper layer.
+func (o *OverlayBackend) upperDir(dir string, mode os.FileMode) error {
+	if stats, err := o.stat(dir); err == nil {
+		if stats["isDir"] != true {
+			return wrapFSError(syscall.ENOTDIR, dir, "mkdir")
+		}
+		if o.upper.Exists(dir) {
+			return nil
+		}
+	}
+	if err := o.upper.Mkdir(dir, false, mode); err != nil {
+		return err
+	}
+	o.unwhiteout(dir)
+	return nil
+}
+
+// copyUp copies a visible lower file into the upper layer.
+func (o *OverlayBackend) copyUp(clean string) error {
+	if o.upper.Exists(clean) || !o.lowerVisible(clean) {
+		return nil
+	}
+	stats, err := o.lower.Stat(clean)
+	if err != nil {
+		return err
+	}
+	if stats["isDir"] == true {
+		return wrapFSError(syscall.EISDIR, clean, "open")
+	}
+	data, err := o.lower.ReadFile(clean)
+	if err != nil {
+		return err
+	}
+	return o.upper.WriteFile(clean, data, statPerm(stats))
+}
+
+// copyTree copies from and everything below it to to, through both layers.
+func (o *OverlayBackend) copyTree(from, to string) error {
+	stats, err := o.stat(from)
+	if err != nil {
+		return err
+	}
+	if stats["isDir"] != true {
+		data, err := o.readFile(from)
+		if err != nil {
+			return err
+		}
+		return o.writeFile(to, data, statPerm(stats))
+	}
+	if err := o.copyUpParents(to, "rename", false, 0o755); err != nil {
+		return err
+	}
+	if err := o.upperDir(to, 0o755); err != nil {
+		return err
+	}
+	names, err := o.readDir(from)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := o.copyTree(path.Join(from, name), path.Join(to, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statPerm returns the permission bits recorded in stats, falling back to
+// 0o644 for backends such as embed.FS that report none.
+func statPerm(stats fileStats) os.FileMode {
+	mode, _ := stats["mode"].(int64)
+	if perm := os.FileMode(mode).Perm(); perm != 0 {
+		return perm
+	}
+	return 0o644
+}
+
+func (o *OverlayBackend) Chmod(p string, mode os.FileMode) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	clean := cleanVirtualPath(p)
+	if err := o.copyUpForAttrs(clean, "chmod"); err != nil {
+		return err
+	}
+	return chmodPath(o.upper, clean, mode)
+}
+
+func (o *OverlayBackend) Chtimes(p string, atime, mtime time.Time) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	clean := cleanVirtualPath(p)
+	if err := o.copyUpForAttrs(clean, "utime"); err != nil {
+		return err
+	}
+	return chtimesPath(o.upper, clean, atime, mtime)
+}
+
+// copyUpForAttrs copies clean into the upper layer so its attributes can
+// change without touching the lower layer.
+func (o *OverlayBackend) copyUpForAttrs(clean, syscall string) error {
+	stats, err := o.stat(clean)
+	if err != nil {
+		return wrapFSError(fs.ErrNotExist, clean, syscall)
+	}
+	if err := o.copyUpParents(clean, syscall, false, 0o755); err != nil {
+		return err
+	}
+	if stats["isDir"] == true {
+		return o.upperDir(clean, statPerm(stats))
+	}
+	return o.copyUp(clean)
+}
@@ -38,10 +38,57 @@ func (m m) TypeScriptModule() *spec.Module {
 			"  modTime: string;",
 			"  isDir: boolean;",
 			"  isFile: boolean;",
+			"  isSymbolicLink: boolean;",
+			"}",
+			"interface Dirent {",
+			"  name: string;",
+			"  parentPath: string;",
+			"  path: string;",
+			"  isFile(): boolean;",
+			"  isDirectory(): boolean;",
+			"  isSymbolicLink(): boolean;",
+			"}",
+			"interface FileReadResult {",
+			"  bytesRead: number;",
+			"  buffer: Buffer | Uint8Array;",
+			"}",
+			"interface FileWriteResult {",
+			"  bytesWritten: number;",
+			"  buffer: string | Buffer | Uint8Array;",
+			"}",
+			"interface FileStreamOptions {",
+			"  flags?: string | number;",
+			"  mode?: number | string;",
+			"  start?: number;",
+			"  end?: number;",
+			"  highWaterMark?: number;",
+			"  encoding?: string;",
+			"}",
+			"interface FileHandle {",
+			"  readonly fd: number;",
+			"  read(buffer: Buffer | Uint8Array, offset?: number | object, length?: number, position?: number | null): Promise<FileReadResult>;",
+			"  write(data: string | Buffer | Uint8Array, offsetOrPosition?: number | null, lengthOrEncoding?: number | string, position?: number | null): Promise<FileWriteResult>;",
+			"  readFile(encoding?: string | object): Promise<string | Buffer>;",
+			"  writeFile(data: string | Buffer | Uint8Array, encoding?: string | object): Promise<void>;",
+			"  appendFile(data: string | Buffer | Uint8Array, encoding?: string | object): Promise<void>;",
+			"  stat(): Promise<FileStats>;",
+			"  truncate(len?: number): Promise<void>;",
+			"  sync(): Promise<void>;",
+			"  close(): Promise<void>;",
+			"  createReadStream(options?: FileStreamOptions | string): any;",
+			"  createWriteStream(options?: FileStreamOptions): any;",
+			"}",
+			"interface FSWatcher {",
+			"  on(event: 'change', listener: (eventType: 'rename' | 'change', filename: string) => void): this;",
+			"  on(event: 'error', listener: (err: Error) => void): this;",
+			"  on(event: 'close', listener: () => void): this;",
+			"  close(): this;",
 			"}",
 			"interface FSMountInfo {",
 			"  mount: string;",
 			"  root: string;",
+			"  backend?: string;",
+			"  readOnly?: boolean;",
 			"}",
 			"interface FSCapabilities {",
 			"  backend: string;",
@@ -51,13 +98,23 @@ func (m m) TypeScriptModule() *spec.Module {
 			"  mounts?: FSMountInfo[];",
 			"}",
 			"export const isReadOnly: boolean;",
+			"export const constants: { O_RDONLY: number; O_WRONLY: number; O_RDWR: number; O_CREAT: number; O_EXCL: number; O_TRUNC: number; O_APPEND: number };",
+			"export function watch(filename: string, options?: { recursive?: boolean; signal?: AbortSignal; debounceMs?: number } | string, listener?: (eventType: 'rename' | 'change', filename: string) => void): FSWatcher;",
+			"export function watch(filename: string, listener: (eventType: 'rename' | 'change', filename: string) => void): FSWatcher;",
+			"export const promises: {",
+			"  readFile: typeof readFile; writeFile: typeof writeFile; mkdir: typeof mkdir; readdir: typeof readdir;",
+			"  stat: typeof stat; lstat: typeof lstat; unlink: typeof unlink; appendFile: typeof appendFile;",
+			"  rename: typeof rename; copyFile: typeof copyFile; rm: typeof rm; realpath: typeof realpath;",
+			"  readlink: typeof readlink; symlink: typeof symlink; chmod: typeof chmod; utimes: typeof utimes;",
+			"  mkdtemp: typeof mkdtemp; cp: typeof cp; glob: typeof glob; open: typeof open; constants: typeof constants;",
+			"};",
 		},
 		Functions: []spec.Function{
 			{Name: "readFile", Params: []spec.Param{{Name: "path", Type: spec.String()}, {Name: "encoding", Type: spec.Union(spec.String(), spec.Object()), Optional: true}}, Returns: spec.Named("Promise<string | Buffer>")},
 			{Name: "writeFile", Params: []spec.Param{{Name: "path", Type: spec.String()}, {Name: "data", Type: spec.Union(spec.String(), spec.Named("Buffer"), spec.Named("Uint8Array"), spec.Named("DataView"))}, {Name: "encoding", Type: spec.Union(spec.String(), spec.Object()), Optional: true}}, Returns: spec.Named("Promise<void>")},
 			{Name: "exists", Params: []spec.Param{{Name: "path", Type: spec.String()}}, Returns: spec.Named("Promise<boolean>")},
 			{Name: "mkdir", Params: []spec.Param{{Name: "path", Type: spec.String()}, {Name: "options", Type: spec.Object(), Optional: true}}, Returns: spec.Named("Promise<void>")},
-			{Name: "readdir", Params: []spec.Param{{Name: "path", Type: spec.String()}}, Returns: spec.Named("Promise<string[]>")},
+			{Name: "readdir", Params: []spec.Param{{Name: "path", Type: spec.String()}, {Name: "options", Type: spec.Named("{ withFileTypes?: boolean }"), Optional: true}}, Returns: spec.Named("Promise<string[] | Dirent[]>")},
 			{Name: "stat", Params: []spec.Param{{Name: "path", Type: spec.String()}}, Returns: spec.Named("Promise<FileStats>")},
 			{Name: "unlink", Params: []spec.Param{{Name: "path", Type: spec.String()}}, Returns: spec.Named("Promise<void>")},
 			{Name: "appendFile", Params: []spec.Param{{Name: "path", Type: spec.String()}, {Name: "data", Type: spec.Union(spec.String(), spec.Named("Buffer"), spec.Named("Uint8Array"), spec.Named("DataView"))}, {Name: "encoding", Type: spec.Union(spec.String(), spec.Object()), Optional: true}}, Returns: spec.Named("Promise<void>")},
@@ -68,13 +125,41 @@ func (m m) TypeScriptModule() *spec.Module {
 			{Name: "writeFileSync", Params: []spec.Param{{Name: "path", Type: spec.String()}, {Name: "data", Type: spec.Union(spec.String(), spec.Named("Buffer"), spec.Named("Uint8Array"), spec.Named("DataView"))}, {Name: "encoding", Type: spec.Union(spec.String(), spec.Object()), Optional: true}}, Returns: spec.Void()},
 			{Name: "existsSync", Params: []spec.Param{{Name: "path", Type: spec.String()}}, Returns: spec.Boolean()},
 			{Name: "mkdirSync", Params: []spec.Param{{Name: "path", Type: spec.String()}, {Name: "options", Type: spec.Object(), Optional: true}}, Returns: spec.Void()},
-			{Name: "readdirSync", Params: []spec.Param{{Name: "path", Type: spec.String()}}, Returns: spec.Array(spec.String())},
+			{Name: "readdirSync", Params: []spec.Param{{Name: "path", Type: spec.String()}, {Name: "options", Type: spec.Named("{ withFileTypes?: boolean }"), Optional: true}}, Returns: spec.Named("string[] | Dirent[]")},
 			{Name: "statSync", Params: []spec.Param{{Name: "path", Type: spec.String()}}, Returns: spec.Named("FileStats")},
 			{Name: "unlinkSync", Params: []spec.Param{{Name: "path", Type: spec.String()}}, Returns: spec.Void()},
 			{Name: "appendFileSync", Params: []spec.Param{{Name: "path", Type: spec.String()}, {Name: "data", Type: spec.Union(spec.String(), spec.Named("Buffer"), spec.Named("Uint8Array"), spec.Named("DataView"))}, {Name: "encoding", Type: spec.Union(spec.String(), spec.Object()), Optional: true}}, Returns: spec.Void()},
 			{Name: "renameSync", Params: []spec.Param{{Name: "oldPath", Type: spec.String()}, {Name: "newPath", Type: spec.String()}}, Returns: spec.Void()},
 			{Name: "copyFileSync", Params: []spec.Param{{Name: "src", Type: spec.String()}, {Name: "dst", Type: spec.String()}}, Returns: spec.Void()},
 			{Name: "rmSync", Params: []spec.Param{{Name: "path", Type: spec.String()}, {Name: "options", Type: spec.Object(), Optional: true}}, Returns: spec.Void()},
+			{Name: "lstat", Params: []spec.Param{{Name: "path", Type: spec.String()}}, Returns: spec.Named("Promise<FileStats>")},
+			{Name: "realpath", Params: []spec.Param{{Name: "path", Type: spec.String()}}, Returns: spec.Named("Promise<string>")},
+			{Name: "readlink", Params: []spec.Param{{Name: "path", Type: spec.String()}}, Returns: spec.Named("Promise<string>")},
+			{Name: "symlink", Params: []spec.Param{{Name: "target", Type: spec.String()}, {Name: "path", Type: spec.String()}}, Returns: spec.Named("Promise<void>")},
+			{Name: "chmod", Params: []spec.Param{{Name: "path", Type: spec.String()}, {Name: "mode", Type: spec.Union(spec.Number(), spec.String())}}, Returns: spec.Named("Promise<void>")},
+			{Name: "utimes", Params: []spec.Param{{Name: "path", Type: spec.String()}, {Name: "atime", Type: spec.Union(spec.Number(), spec.String(), spec.Named("Date"))}, {Name: "mtime", Type: spec.Union(spec.Number(), spec.String(), spec.Named("Date"))}}, Returns: spec.Named("Promise<void>")},
+			{Name: "mkdtemp", Params: []spec.Param{{Name: "prefix", Type: spec.String()}}, Returns: spec.Named("Promise<string>")},
+			{Name: "cp", Params: []spec.Param{{Name: "src", Type: spec.String()}, {Name: "dst", Type: spec.String()}, {Name: "options", Type: spec.Named("{ recursive?: boolean; force?: boolean; errorOnExist?: boolean }"), Optional: true}}, Returns: spec.Named("Promise<void>")},
+			{Name: "glob", Params: []spec.Param{{Name: "pattern", Type: spec.Union(spec.String(), spec.Array(spec.String()))}, {Name: "options", Type: spec.Named("{ cwd?: string; exclude?: string[] }"), Optional: true}}, Returns: spec.Named("Promise<string[]>")},
+			{Name: "open", Params: []spec.Param{{Name: "path", Type: spec.String()}, {Name: "flags", Type: spec.Union(spec.String(), spec.Number()), Optional: true}, {Name: "mode", Type: spec.Union(spec.Number(), spec.String()), Optional: true}}, Returns: spec.Named("Promise<FileHandle>")},
+			{Name: "createReadStream", Params: []spec.Param{{Name: "path", Type: spec.String()}, {Name: "options", Type: spec.Union(spec.Named("FileStreamOptions"), spec.String()), Optional: true}}, Returns: spec.Any()},
+			{Name: "createWriteStream", Params: []spec.Param{{Name: "path", Type: spec.String()}, {Name: "options", Type: spec.Named("FileStreamOptions"), Optional: true}}, Returns: spec.Any()},
+			{Name: "lstatSync", Params: []spec.Param{{Name: "path", Type: spec.String()}}, Returns: spec.Named("FileStats")},
+			{Name: "realpathSync", Params: []spec.Param{{Name: "path", Type: spec.String()}}, Returns: spec.String()},
+			{Name: "readlinkSync", Params: []spec.Param{{Name: "path", Type: spec.String()}}, Returns: spec.String()},
+			{Name: "symlinkSync", Params: []spec.Param{{Name: "target", Type: spec.String()}, {Name: "path", Type: spec.String()}}, Returns: spec.Void()},
+			{Name: "chmodSync", Params: []spec.Param{{Name: "path", Type: spec.String()}, {Name: "mode", Type: spec.Union(spec.Number(), spec.String())}}, Returns: spec.Void()},
+			{Name: "utimesSync", Params: []spec.Param{{Name: "path", Type: spec.String()}, {Name: "atime", Type: spec.Union(spec.Number(), spec.String(), spec.Named("Date"))}, {Name: "mtime", Type: spec.Union(spec.Number(), spec.String(), spec.Named("Date"))}}, Returns: spec.Void()},
+			{Name: "mkdtempSync", Params: []spec.Param{{Name: "prefix", Type: spec.String()}}, Returns: spec.String()},
+			{Name: "cpSync", Params: []spec.Param{{Name: "src", Type: spec.String()}, {Name: "dst", Type: spec.String()}, {Name: "options", Type: spec.Named("{ recursive?: boolean; force?: boolean; errorOnExist?: boolean }"), Optional: true}}, Returns: spec.Void()},
+			{Name: "globSync", Params: []spec.Param{{Name: "pattern", Type: spec.Union(spec.String(), spec.Array(spec.String()))}, {Name: "options", Type: spec.Named("{ cwd?: string; exclude?: string[] }"), Optional: true}}, Returns: spec.Array(spec.String())},
+			{Name: "openSync", Params: []spec.Param{{Name: "path", Type: spec.String()}, {Name: "flags", Type: spec.Union(spec.String(), spec.Number()), Optional: true}, {Name: "mode", Type: spec.Union(spec.Number(), spec.String()), Optional: true}}, Returns: spec.Number()},
+			{Name: "closeSync", Params: []spec.Param{{Name: "fd", Type: spec.Number()}}, Returns: spec.Void()},
+			{Name: "readSync", Params: []spec.Param{{Name: "fd", Type: spec.Number()}, {Name: "buffer", Type: spec.Union(spec.Named("Buffer"), spec.Named("Uint8Array"))}, {Name: "offset", Type: spec.Union(spec.Number(), spec.Object()), Optional: true}, {Name: "length", Type: spec.Number(), Optional: true}, {Name: "position", Type: spec.Named("number | null"), Optional: true}}, Returns: spec.Number()},
+			{Name: "writeSync", Params: []spec.Param{{Name: "fd", Type: spec.Number()}, {Name: "data", Type: spec.Union(spec.String(), spec.Named("Buffer"), spec.Named("Uint8Array"))}, {Name: "offsetOrPosition", Type: spec.Named("number | null"), Optional: true}, {Name: "lengthOrEncoding", Type: spec.Union(spec.Number(), spec.String()), Optional: true}, {Name: "position", Type: spec.Named("number | null"), Optional: true}}, Returns: spec.Number()},
+			{Name: "fstatSync", Params: []spec.Param{{Name: "fd", Type: spec.Number()}}, Returns: spec.Named("FileStats")},
+			{Name: "ftruncateSync", Params: []spec.Param{{Name: "fd", Type: spec.Number()}, {Name: "len", Type: spec.Number(), Optional: true}}, Returns: spec.Void()},
+			{Name: "fsyncSync", Params: []spec.Param{{Name: "fd", Type: spec.Number()}}, Returns: spec.Void()},
 			{Name: "capabilities", Returns: spec.Named("FSCapabilities")},
 		},
 	}
@@ -90,7 +175,12 @@ func capabilitiesObject(c Capabilities) map[string]any {
 	if len(c.Mounts) > 0 {
 		mounts := make([]map[string]any, 0, len(c.Mounts))
 		for _, mount := range c.Mounts {
-			mounts = append(mounts, map[string]any{"mount": mount.Mount, "root": mount.Root})
+			info := map[string]any{"mount": mount.Mount, "root": mount.Root}
+			if mount.Backend != "" {
+				info["backend"] = mount.Backend
+				info["readOnly"] = mount.ReadOnly
+			}
+			mounts = append(mounts, info)
 		}
 		ret["mounts"] = mounts
 	}
@@ -109,6 +199,17 @@ Sync functions block the JavaScript runtime: readFileSync, writeFileSync,
 existsSync, mkdirSync, readdirSync, statSync, unlinkSync, appendFileSync,
 renameSync, copyFileSync. writeFile/writeFileSync and appendFile/appendFileSync
 accept strings, Buffers, TypedArrays, and DataViews.
+
+lstat, realpath, readlink, symlink, chmod, utimes, mkdtemp, cp and glob come in
+both forms as well. readdir and readdirSync return Dirent objects with
+{ withFileTypes: true }.
+
+open returns a Promise for a FileHandle with positional read and write;
+openSync, readSync, writeSync, fstatSync, ftruncateSync, fsyncSync and
+closeSync work on integer descriptors. createReadStream and createWriteStream
+return stream Readables and Writables. watch returns an FSWatcher emitting
+'change' events. promises holds the Promise-returning functions, as
+node:fs/promises does.
 `
 }
 
@@ -119,8 +220,9 @@ func (mod m) Loader(vm *goja.Runtime, moduleObj *goja.Object) {
 		panic(vm.NewGoError(fmt.Errorf("fs module requires runtime services")))
 	}
 
-	backend := mod.fileSystem()
+	backend := withSandbox(mod.fileSystem(), runtimeServices.Sandbox)
 	capabilities := CapabilitiesForBackend(backend)
+	files := newFDTable(runtimeServices.Lifetime())
 	if err := exports.DefineDataProperty(backendExportKey, vm.ToValue(backend), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE); err != nil {
 		panic(vm.NewGoError(err))
 	}
@@ -147,7 +249,11 @@ func (mod m) Loader(vm *goja.Runtime, moduleObj *goja.Object) {
 		recursive, mode := mkdirOptions(vm, call.Argument(1))
 		return asyncMkdir(vm, runtimeServices, backend, path, recursive, mode)
 	})
-	modules.SetExport(exports, mod.Name(), "readdir", func(path string) goja.Value {
+	modules.SetExport(exports, mod.Name(), "readdir", func(call goja.FunctionCall) goja.Value {
+		path := call.Argument(0).String()
+		if withFileTypesOption(vm, call.Argument(1)) {
+			return asyncReaddirents(vm, runtimeServices, backend, path)
+		}
 		return asyncReaddir(vm, runtimeServices, backend, path)
 	})
 	modules.SetExport(exports, mod.Name(), "stat", func(path string) goja.Value {
@@ -192,10 +298,16 @@ func (mod m) Loader(vm *goja.Runtime, moduleObj *goja.Object) {
 		panicFSError(vm, backend.Mkdir(path, recursive, fileMode(mode)))
 		return goja.Undefined()
 	})
-	modules.SetExport(exports, mod.Name(), "readdirSync", func(path string) []string {
+	modules.SetExport(exports, mod.Name(), "readdirSync", func(call goja.FunctionCall) goja.Value {
+		path := call.Argument(0).String()
+		if withFileTypesOption(vm, call.Argument(1)) {
+			infos, err := readDirents(backend, path)
+			panicFSError(vm, err)
+			return direntsValue(vm, infos)
+		}
 		ret, err := backend.ReadDir(path)
 		panicFSError(vm, err)
-		return ret
+		return vm.ToValue(ret)
 	})
 	modules.SetExport(exports, mod.Name(), "statSync", func(path string) fileStats {
 		ret, err := backend.Stat(path)
@@ -235,6 +347,174 @@ func (mod m) Loader(vm *goja.Runtime, moduleObj *goja.Object) {
 		}
 		return goja.Undefined()
 	})
+
+	modules.SetExport(exports, mod.Name(), "lstat", func(path string) goja.Value {
+		return asyncValue(vm, runtimeServices, "fs.lstat", func() (any, error) { return lstatPath(backend, path) })
+	})
+	modules.SetExport(exports, mod.Name(), "realpath", func(path string) goja.Value {
+		return asyncValue(vm, runtimeServices, "fs.realpath", func() (any, error) { return realpathPath(backend, path) })
+	})
+	modules.SetExport(exports, mod.Name(), "readlink", func(path string) goja.Value {
+		return asyncValue(vm, runtimeServices, "fs.readlink", func() (any, error) { return readlinkPath(backend, path) })
+	})
+	modules.SetExport(exports, mod.Name(), "symlink", func(target, path string) goja.Value {
+		return asyncValue(vm, runtimeServices, "fs.symlink", func() (any, error) { return nil, symlinkPath(backend, target, path) })
+	})
+	modules.SetExport(exports, mod.Name(), "chmod", func(call goja.FunctionCall) goja.Value {
+		path, mode := call.Argument(0).String(), fileMode(modeArg(vm, call.Argument(1)))
+		return asyncValue(vm, runtimeServices, "fs.chmod", func() (any, error) { return nil, chmodPath(backend, path, mode) })
+	})
+	modules.SetExport(exports, mod.Name(), "utimes", func(call goja.FunctionCall) goja.Value {
+		path := call.Argument(0).String()
+		atime, mtime := timeArg(vm, call.Argument(1), "atime"), timeArg(vm, call.Argument(2), "mtime")
+		return asyncValue(vm, runtimeServices, "fs.utimes", func() (any, error) { return nil, chtimesPath(backend, path, atime, mtime) })
+	})
+	modules.SetExport(exports, mod.Name(), "mkdtemp", func(prefix string) goja.Value {
+		return asyncValue(vm, runtimeServices, "fs.mkdtemp", func() (any, error) { return makeTempDir(backend, prefix) })
+	})
+	modules.SetExport(exports, mod.Name(), "cp", func(call goja.FunctionCall) goja.Value {
+		src, dst := call.Argument(0).String(), call.Argument(1).String()
+		opts := parseCpOptions(vm, call.Argument(2))
+		return asyncValue(vm, runtimeServices, "fs.cp", func() (any, error) { return nil, copyTree(backend, src, dst, opts) })
+	})
+	modules.SetExport(exports, mod.Name(), "glob", func(call goja.FunctionCall) goja.Value {
+		patterns, opts := parseGlobArgs(vm, call.Argument(0), call.Argument(1))
+		return asyncValue(vm, runtimeServices, "fs.glob", func() (any, error) { return glob(backend, patterns, opts) })
+	})
+	modules.SetExport(exports, mod.Name(), "open", func(call goja.FunctionCall) goja.Value {
+		path := call.Argument(0).String()
+		flags, mode := parseOpenFlags(vm, call.Argument(1), "r"), openMode(vm, call.Argument(2))
+		return asyncResult(vm, runtimeServices, "fs.open", func() (func() goja.Value, error) {
+			h, err := files.open(backend, path, flags, mode)
+			if err != nil {
+				return nil, err
+			}
+			return func() goja.Value { return fileHandleObject(vm, runtimeServices, files, h) }, nil
+		})
+	})
+	modules.SetExport(exports, mod.Name(), "createReadStream", func(call goja.FunctionCall) goja.Value {
+		path := call.Argument(0).String()
+		opts := parseStreamOptions(vm, call.Argument(1))
+		h, err := files.open(backend, path, parseOpenFlags(vm, opts.flags, "r"), openMode(vm, opts.mode))
+		if err != nil {
+			return failedStream(vm, runtimeServices, true, err)
+		}
+		return readStream(vm, h.file, func() error { return files.close(h.fd) }, opts)
+	})
+	modules.SetExport(exports, mod.Name(), "createWriteStream", func(call goja.FunctionCall) goja.Value {
+		path := call.Argument(0).String()
+		opts := parseStreamOptions(vm, call.Argument(1))
+		h, err := files.open(backend, path, parseOpenFlags(vm, opts.flags, "w"), openMode(vm, opts.mode))
+		if err != nil {
+			return failedStream(vm, runtimeServices, false, err)
+		}
+		return writeStream(vm, h.file, func() error { return files.close(h.fd) }, opts)
+	})
+	modules.SetExport(exports, mod.Name(), "watch", func(call goja.FunctionCall) goja.Value {
+		return watchFile(vm, runtimeServices, backend, call)
+	})
+
+	modules.SetExport(exports, mod.Name(), "lstatSync", func(path string) fileStats {
+		ret, err := lstatPath(backend, path)
+		panicFSError(vm, err)
+		return ret
+	})
+	modules.SetExport(exports, mod.Name(), "realpathSync", func(path string) string {
+		ret, err := realpathPath(backend, path)
+		panicFSError(vm, err)
+		return ret
+	})
+	modules.SetExport(exports, mod.Name(), "readlinkSync", func(path string) string {
+		ret, err := readlinkPath(backend, path)
+		panicFSError(vm, err)
+		return ret
+	})
+	modules.SetExport(exports, mod.Name(), "symlinkSync", func(target, path string) {
+		panicFSError(vm, symlinkPath(backend, target, path))
+	})
+	modules.SetExport(exports, mod.Name(), "chmodSync", func(call goja.FunctionCall) goja.Value {
+		panicFSError(vm, chmodPath(backend, call.Argument(0).String(), fileMode(modeArg(vm, call.Argument(1)))))
+		return goja.Undefined()
+	})
+	modules.SetExport(exports, mod.Name(), "utimesSync", func(call goja.FunctionCall) goja.Value {
+		atime, mtime := timeArg(vm, call.Argument(1), "atime"), timeArg(vm, call.Argument(2), "mtime")
+		panicFSError(vm, chtimesPath(backend, call.Argument(0).String(), atime, mtime))
+		return goja.Undefined()
+	})
+	modules.SetExport(exports, mod.Name(), "mkdtempSync", func(prefix string) string {
+		ret, err := makeTempDir(backend, prefix)
+		panicFSError(vm, err)
+		return ret
+	})
+	modules.SetExport(exports, mod.Name(), "cpSync", func(call goja.FunctionCall) goja.Value {
+		opts := parseCpOptions(vm, call.Argument(2))
+		panicFSError(vm, copyTree(backend, call.Argument(0).String(), call.Argument(1).String(), opts))
+		return goja.Undefined()
+	})
+	modules.SetExport(exports, mod.Name(), "globSync", func(call goja.FunctionCall) goja.Value {
+		patterns, opts := parseGlobArgs(vm, call.Argument(0), call.Argument(1))
+		ret, err := glob(backend, patterns, opts)
+		panicFSError(vm, err)
+		return vm.ToValue(ret)
+	})
+	modules.SetExport(exports, mod.Name(), "openSync", func(call goja.FunctionCall) goja.Value {
+		flags, mode := parseOpenFlags(vm, call.Argument(1), "r"), openMode(vm, call.Argument(2))
+		h, err := files.open(backend, call.Argument(0).String(), flags, mode)
+		panicFSError(vm, err)
+		return vm.ToValue(h.fd)
+	})
+	modules.SetExport(exports, mod.Name(), "closeSync", func(fd int) {
+		panicFSError(vm, files.close(fd))
+	})
+	modules.SetExport(exports, mod.Name(), "readSync", func(call goja.FunctionCall) goja.Value {
+		h, err := files.get(int(call.Argument(0).ToInteger()), "read")
+		panicFSError(vm, err)
+		req := readRequest(vm, argumentsFrom(call.Arguments, 1))
+		n, err := h.read(req.data, req.position)
+		panicFSError(vm, err)
+		return vm.ToValue(n)
+	})
+	modules.SetExport(exports, mod.Name(), "writeSync", func(call goja.FunctionCall) goja.Value {
+		h, err := files.get(int(call.Argument(0).ToInteger()), "write")
+		panicFSError(vm, err)
+		req := writeRequest(vm, argumentsFrom(call.Arguments, 1))
+		n, err := h.write(req.data, req.position)
+		panicFSError(vm, err)
+		return vm.ToValue(n)
+	})
+	modules.SetExport(exports, mod.Name(), "fstatSync", func(fd int) fileStats {
+		h, err := files.get(fd, "fstat")
+		panicFSError(vm, err)
+		ret, err := h.file.Stat()
+		panicFSError(vm, err)
+		return ret
+	})
+	modules.SetExport(exports, mod.Name(), "ftruncateSync", func(call goja.FunctionCall) goja.Value {
+		h, err := files.get(int(call.Argument(0).ToInteger()), "ftruncate")
+		panicFSError(vm, err)
+		panicFSError(vm, h.truncate(call.Argument(1).ToInteger()))
+		return goja.Undefined()
+	})
+	modules.SetExport(exports, mod.Name(), "fsyncSync", func(fd int) {
+		h, err := files.get(fd, "fsync")
+		panicFSError(vm, err)
+		panicFSError(vm, h.file.Sync())
+	})
+	modules.SetExport(exports, mod.Name(), "constants", constantsObject())
+
+	// promises mirrors node:fs/promises with the Promise-returning exports.
+	promises := vm.NewObject()
+	for _, name := range promiseExports {
+		_ = promises.Set(name, exports.Get(name))
+	}
+	_ = promises.Set("constants", exports.Get("constants"))
+	modules.SetExport(exports, mod.Name(), "promises", promises)
+}
+
+var promiseExports = []string{
+	"readFile", "writeFile", "mkdir", "readdir", "stat", "lstat", "unlink", "appendFile",
+	"rename", "copyFile", "rm", "realpath", "readlink", "symlink", "chmod", "utimes",
+	"mkdtemp", "cp", "glob", "open",
 }
 
 func encodingOption(vm *goja.Runtime, value goja.Value) goja.Value {
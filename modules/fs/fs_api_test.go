@@ -0,0 +1,248 @@
+package fs_test
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-go-golems/go-go-goja/internal/enginetest"
+	fsmod "github.com/go-go-golems/go-go-goja/modules/fs"
+	gggengine "github.com/go-go-golems/go-go-goja/pkg/engine"
+)
+
+func TestFileHandlesOnOSAndMemoryBackends(t *testing.T) {
+	dir := t.TempDir()
+	for name, rt := range map[string]*gggengine.Runtime{
+		"os":     newBackendRuntime(t, fsmod.OSBackend{}),
+		"memory": newBackendRuntime(t, fsmod.NewMemoryBackend()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			root := "/"
+			if name == "os" {
+				root = filepath.ToSlash(dir) + "/"
+			}
+			state := runFSScript(t, rt, `
+				const fs = require("fs");
+				const file = `+strconv.Quote(root+"handle.txt")+`;
+				const fd = fs.openSync(file, "w+");
+				fs.writeSync(fd, "hello world");
+				fs.writeSync(fd, Buffer.from("HELLO"), 0, 5, 0);
+				const buf = Buffer.alloc(5);
+				const n = fs.readSync(fd, buf, 0, 5, 6);
+				fs.ftruncateSync(fd, 8);
+				const size = fs.fstatSync(fd).size;
+				fs.closeSync(fd);
+				let badFd = "";
+				try { fs.closeSync(fd); } catch (e) { badFd = e.code; }
+
+				const h = await fs.promises.open(file, "r+");
+				const out = Buffer.alloc(4);
+				const read = await h.read(out, 0, 4, 2);
+				await h.write("!", 7);
+				const all = await h.readFile("utf8");
+				await h.close();
+				await h.close();
+
+				let exclusive = "";
+				try { await fs.open(file, "wx"); } catch (e) { exclusive = e.code; }
+				const appender = await fs.open(file, "a");
+				await appender.write("+tail", 0);
+				await appender.close();
+				return {
+					n, slice: buf.toString(), size, badFd,
+					bytesRead: read.bytesRead, out: out.toString(), all, exclusive,
+					final: fs.readFileSync(file, "utf8"),
+				};
+			`)
+			for _, want := range []string{
+				`"n":5`, `"slice":"world"`, `"size":8`, `"badFd":"EBADF"`,
+				`"bytesRead":4`, `"out":"LLO "`, `"all":"HELLO w!"`, `"exclusive":"EEXIST"`,
+				`"final":"HELLO w!+tail"`,
+			} {
+				if !strings.Contains(state, want) {
+					t.Fatalf("handle state missing %s: %s", want, state)
+				}
+			}
+		})
+	}
+}
+
+func TestFileStreams(t *testing.T) {
+	rt := newBackendRuntime(t, fsmod.NewMemoryBackend())
+	state := runFSScript(t, rt, `
+		const fs = require("fs");
+		await new Promise((resolve, reject) => {
+			const w = fs.createWriteStream("/stream.txt");
+			w.on("error", reject);
+			w.write("abc");
+			w.write(Buffer.from("def"));
+			w.end("ghi", resolve);
+		});
+		const chunks = [];
+		await new Promise((resolve, reject) => {
+			const r = fs.createReadStream("/stream.txt", { start: 2, end: 6, encoding: "utf8" });
+			r.on("data", (chunk) => chunks.push(chunk));
+			r.on("end", resolve);
+			r.on("error", reject);
+		});
+		const missing = await new Promise((resolve) => {
+			fs.createReadStream("/missing.txt").on("error", (e) => resolve(e.code));
+		});
+		return { written: fs.readFileSync("/stream.txt", "utf8"), range: chunks.join(""), missing };
+	`)
+	for _, want := range []string{`"written":"abcdefghi"`, `"range":"cdefg"`, `"missing":"ENOENT"`} {
+		if !strings.Contains(state, want) {
+			t.Fatalf("stream state missing %s: %s", want, state)
+		}
+	}
+}
+
+func TestLinksAttributesAndDirents(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "real"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "real", "file.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	jail, err := fsmod.NewJailedOSBackend(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = jail.Close() })
+	rt := newBackendRuntime(t, jail)
+	state := runFSScript(t, rt, `
+		const fs = require("fs");
+		fs.symlinkSync("real", "/link");
+		await fs.symlink("/etc", "/escape");
+		const entries = fs.readdirSync("/", { withFileTypes: true })
+			.map((d) => d.name + ":" + (d.isSymbolicLink() ? "l" : d.isDirectory() ? "d" : "f"));
+		const asyncEntries = (await fs.readdir("/real", { withFileTypes: true })).map((d) => d.parentPath + "/" + d.name);
+		let escape = "";
+		try { fs.realpathSync("/escape"); } catch (e) { escape = e.code; }
+		fs.chmodSync("/real/file.txt", "600");
+		await fs.utimes("/real/file.txt", 1000, new Date(2000 * 1000));
+		return {
+			entries,
+			asyncEntries,
+			target: fs.readlinkSync("/link"),
+			real: await fs.realpath("/link/file.txt"),
+			linkStat: fs.lstatSync("/link").isSymbolicLink,
+			escape,
+			mode: fs.statSync("/real/file.txt").mode & 0o777,
+		};
+	`)
+	for _, want := range []string{
+		`"entries":["escape:l","link:l","real:d"]`, `"asyncEntries":["/real/file.txt"]`,
+		`"target":"real"`, `"real":"/real/file.txt"`, `"linkStat":true`, `"escape":"EACCES"`,
+		`"mode":384`,
+	} {
+		if !strings.Contains(state, want) {
+			t.Fatalf("links state missing %s: %s", want, state)
+		}
+	}
+	info, err := os.Stat(filepath.Join(dir, "real", "file.txt"))
+	if err != nil || info.ModTime().Unix() != 2000 {
+		t.Fatalf("mtime = %v, %v", info.ModTime(), err)
+	}
+}
+
+func TestMkdtempCpGlobAndPromises(t *testing.T) {
+	rt := newBackendRuntime(t, fsmod.NewMemoryBackend())
+	state := runFSScript(t, rt, `
+		const fs = require("fs");
+		fs.mkdirSync("/src/a/b", { recursive: true });
+		fs.writeFileSync("/src/top.js", "1");
+		fs.writeFileSync("/src/a/mid.js", "2");
+		fs.writeFileSync("/src/a/b/deep.js", "3");
+		fs.writeFileSync("/src/a/b/notes.md", "4");
+		const tmp = fs.mkdtempSync("/tmp-");
+		const tmp2 = await fs.promises.mkdtemp("/tmp-");
+
+		let notRecursive = "";
+		try { fs.cpSync("/src", "/copy"); } catch (e) { notRecursive = e.code; }
+		await fs.cp("/src", "/copy", { recursive: true });
+		fs.writeFileSync("/copy/top.js", "changed");
+		fs.cpSync("/src/top.js", "/copy/top.js", { force: false });
+		let exists = "";
+		try { fs.cpSync("/src/top.js", "/copy/top.js", { force: false, errorOnExist: true }); } catch (e) { exists = e.code; }
+		let intoSelf = "";
+		try { fs.cpSync("/src", "/src/a/inner", { recursive: true }); } catch (e) { intoSelf = e.code; }
+
+		return {
+			tmp: /^\/tmp-[A-Za-z0-9]{6}$/.test(tmp) && tmp !== tmp2 && fs.statSync(tmp).isDir,
+			notRecursive, exists, intoSelf,
+			copied: fs.readFileSync("/copy/a/b/deep.js", "utf8"),
+			kept: fs.readFileSync("/copy/top.js", "utf8"),
+			all: fs.globSync("**/*.js", { cwd: "/src" }),
+			shallow: await fs.glob(["*.js", "a/*.js"], { cwd: "/src" }),
+			absolute: fs.globSync("/src/a/**/*", { exclude: ["**/*.md"] }),
+			same: fs.promises.readFile === fs.readFile && typeof fs.promises.open === "function",
+		};
+	`)
+	for _, want := range []string{
+		`"tmp":true`, `"notRecursive":"EISDIR"`, `"exists":"EEXIST"`, `"intoSelf":"EINVAL"`,
+		`"copied":"3"`, `"kept":"changed"`,
+		`"all":["a/b/deep.js","a/mid.js","top.js"]`,
+		`"shallow":["a/mid.js","top.js"]`,
+		`"absolute":["/src/a/b","/src/a/b/deep.js","/src/a/mid.js"]`,
+		`"same":true`,
+	} {
+		if !strings.Contains(state, want) {
+			t.Fatalf("tree state missing %s: %s", want, state)
+		}
+	}
+}
+
+func TestWatchReportsChanges(t *testing.T) {
+	dir := t.TempDir()
+	rt := newBackendRuntime(t, fsmod.OSBackend{})
+	state := runFSScript(t, rt, `
+		const fs = require("fs");
+		const dir = `+strconv.Quote(filepath.ToSlash(dir))+`;
+		const controller = new AbortController();
+		let closed = false;
+		const changed = new Promise((resolve) => {
+			const watcher = fs.watch(dir, { signal: controller.signal }, (eventType, filename) => {
+				if (filename === "new.txt") resolve(eventType);
+			});
+			watcher.on("close", () => { closed = true; });
+		});
+		fs.writeFileSync(dir + "/new.txt", "x");
+		const eventType = await changed;
+		controller.abort();
+		let missing = "";
+		try { fs.watch(dir + "/missing"); } catch (e) { missing = e.code; }
+		return { eventType, closed, missing };
+	`)
+	for _, want := range []string{`"eventType":"rename"`, `"closed":true`, `"missing":"ENOENT"`} {
+		if !strings.Contains(state, want) {
+			t.Fatalf("watch state missing %s: %s", want, state)
+		}
+	}
+}
+
+func TestWatchIsUnsupportedOnMemoryBackend(t *testing.T) {
+	rt := newBackendRuntime(t, fsmod.NewMemoryBackend())
+	state := runFSScript(t, rt, `
+		const fs = require("fs");
+		try { fs.watch("/"); } catch (e) { return { code: e.code }; }
+		return { code: "" };
+	`)
+	if !strings.Contains(state, `"code":"ENOSYS"`) {
+		t.Fatalf("memory watch state: %s", state)
+	}
+}
+
+// runFSScript runs body inside an async function and returns the JSON of
+// its result once the promise settles.
+func runFSScript(t *testing.T, rt *gggengine.Runtime, body string) string {
+	t.Helper()
+	enginetest.RunAsync(t, rt, `
+		const value = await (async () => {`+body+`})();
+		globalThis.__fsSmoke = { done: true, error: "", value };
+	`)
+	return readState(t, rt)
+}
@@ -9,6 +9,24 @@ import (
 )
 
 func asyncValue(vm *goja.Runtime, runtimeServices runtimebridge.RuntimeServices, op string, fn func() (any, error)) goja.Value {
+	return asyncResult(vm, runtimeServices, op, func() (func() goja.Value, error) {
+		value, err := fn()
+		if err != nil {
+			return nil, err
+		}
+		return func() goja.Value {
+			if value == nil {
+				return goja.Undefined()
+			}
+			return vm.ToValue(value)
+		}, nil
+	})
+}
+
+// asyncResult is asyncValue for results that must be built on the owner
+// goroutine, such as Dirent objects or data copied into a caller's buffer. fn
+// runs in the background and returns the builder.
+func asyncResult(vm *goja.Runtime, runtimeServices runtimebridge.RuntimeServices, op string, fn func() (func() goja.Value, error)) goja.Value {
 	promise, resolve, reject := vm.NewPromise()
 	callCtx := runtimebridge.CurrentOwnerContext(vm)
 	runtimeCtx := bindingContext(runtimeServices)
@@ -21,7 +39,7 @@ func asyncValue(vm *goja.Runtime, runtimeServices runtimebridge.RuntimeServices,
 		default:
 		}
 
-		value, err := fn()
+		build, err := fn()
 		if err != nil {
 			_ = runtimeServices.PostWithCustomContext(callCtx, op+".reject", func(context.Context, *goja.Runtime) {
 				_ = reject(fsErrorValue(vm, err))
@@ -29,11 +47,7 @@ func asyncValue(vm *goja.Runtime, runtimeServices runtimebridge.RuntimeServices,
 			return
 		}
 		_ = runtimeServices.PostWithCustomContext(callCtx, op+".resolve", func(context.Context, *goja.Runtime) {
-			if value == nil {
-				_ = resolve(goja.Undefined())
-				return
-			}
-			_ = resolve(vm.ToValue(value))
+			_ = resolve(build())
 		})
 	}()
 	return vm.ToValue(promise)
@@ -94,6 +108,16 @@ func asyncReaddir(vm *goja.Runtime, runtimeServices runtimebridge.RuntimeService
 	})
 }
 
+func asyncReaddirents(vm *goja.Runtime, runtimeServices runtimebridge.RuntimeServices, backend Backend, path string) goja.Value {
+	return asyncResult(vm, runtimeServices, "fs.readdir", func() (func() goja.Value, error) {
+		infos, err := readDirents(backend, path)
+		if err != nil {
+			return nil, err
+		}
+		return func() goja.Value { return direntsValue(vm, infos) }, nil
+	})
+}
+
 func asyncStat(vm *goja.Runtime, runtimeServices runtimebridge.RuntimeServices, backend Backend, path string) goja.Value {
 	return asyncValue(vm, runtimeServices, "fs.stat", func() (any, error) {
 		return backend.Stat(path)
@@ -0,0 +1,301 @@
+package fs_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dop251/goja"
+	fsmod "github.com/go-go-golems/go-go-goja/modules/fs"
+	gggengine "github.com/go-go-golems/go-go-goja/pkg/engine"
+	"github.com/go-go-golems/go-go-goja/pkg/sandbox"
+)
+
+func TestMemoryBackend(t *testing.T) {
+	backend := fsmod.NewMemoryBackend()
+	if err := backend.Mkdir("/a/b", true, 0o755); err != nil {
+		t.Fatalf("mkdir -p: %v", err)
+	}
+	if err := backend.WriteFile("/a/b/one.txt", []byte("one"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := backend.AppendFile("a/b/one.txt", []byte("+"), 0o644); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if err := backend.WriteFile("/missing/x.txt", nil, 0o644); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("write without parent = %v", err)
+	}
+	if err := backend.Remove("/a"); err == nil {
+		t.Fatal("expected removing a non-empty directory to fail")
+	}
+	if err := backend.Rename("/a/b", "/c"); err != nil {
+		t.Fatalf("rename dir: %v", err)
+	}
+	data, err := backend.ReadFile("/c/one.txt")
+	if err != nil || string(data) != "one+" {
+		t.Fatalf("read renamed file = %q, %v", data, err)
+	}
+	entries, err := backend.ReadDir("/")
+	if err != nil || strings.Join(entries, ",") != "a,c" {
+		t.Fatalf("root entries = %#v, %v", entries, err)
+	}
+	stats, err := backend.Stat("/c")
+	if err != nil || stats["isDir"] != true {
+		t.Fatalf("stat dir = %#v, %v", stats, err)
+	}
+	if err := backend.RemoveAll("/c"); err != nil || backend.Exists("/c/one.txt") {
+		t.Fatalf("rm -r left files behind: %v", err)
+	}
+}
+
+func TestOverlayBackendCopyOnWrite(t *testing.T) {
+	lowerFS := fstest.MapFS{
+		"site/index.html":    &fstest.MapFile{Data: []byte("lower"), Mode: 0o644},
+		"site/css/main.css":  &fstest.MapFile{Data: []byte("body{}"), Mode: 0o644},
+		"site/css/print.css": &fstest.MapFile{Data: []byte("@media print{}"), Mode: 0o644},
+	}
+	lower := fsmod.NewReadOnlyFSBackend(fsmod.FSMount{FS: lowerFS, Root: "site", Mount: "/"})
+	overlay := fsmod.NewOverlayBackend(lower, nil)
+
+	if err := overlay.AppendFile("/index.html", []byte("+upper"), 0o644); err != nil {
+		t.Fatalf("append to lower file: %v", err)
+	}
+	if data, _ := overlay.ReadFile("/index.html"); string(data) != "lower+upper" {
+		t.Fatalf("overlay index = %q", data)
+	}
+	if data, _ := lower.ReadFile("/index.html"); string(data) != "lower" {
+		t.Fatalf("lower index changed to %q", data)
+	}
+	if err := overlay.Remove("/css/print.css"); err != nil {
+		t.Fatalf("remove lower file: %v", err)
+	}
+	if overlay.Exists("/css/print.css") {
+		t.Fatal("whited-out file is still visible")
+	}
+	if entries, _ := overlay.ReadDir("/css"); strings.Join(entries, ",") != "main.css" {
+		t.Fatalf("css entries = %#v", entries)
+	}
+	if err := overlay.WriteFile("/css/new.css", []byte("x"), 0o644); err != nil {
+		t.Fatalf("write into lower dir: %v", err)
+	}
+	if entries, _ := overlay.ReadDir("/css"); strings.Join(entries, ",") != "main.css,new.css" {
+		t.Fatalf("css entries after write = %#v", entries)
+	}
+
+	if err := overlay.RemoveAll("/css"); err != nil {
+		t.Fatalf("rm -r lower dir: %v", err)
+	}
+	if err := overlay.Mkdir("/css", false, 0o755); err != nil {
+		t.Fatalf("recreate dir: %v", err)
+	}
+	if entries, _ := overlay.ReadDir("/css"); len(entries) != 0 {
+		t.Fatalf("recreated dir shows lower entries %#v", entries)
+	}
+
+	if err := overlay.Rename("/index.html", "/home.html"); err != nil {
+		t.Fatalf("rename lower file: %v", err)
+	}
+	if entries, _ := overlay.ReadDir("/"); strings.Join(entries, ",") != "css,home.html" {
+		t.Fatalf("root entries after rename = %#v", entries)
+	}
+}
+
+func TestJailedOSBackendRejectsEscapes(t *testing.T) {
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "inside.txt"), []byte("inside"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "escape")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	if err := os.Symlink("inside.txt", filepath.Join(dir, "alias.txt")); err != nil {
+		t.Fatal(err)
+	}
+	backend, err := fsmod.NewJailedOSBackend(dir)
+	if err != nil {
+		t.Fatalf("open jail: %v", err)
+	}
+	t.Cleanup(func() { _ = backend.Close() })
+
+	if data, err := backend.ReadFile("/../../inside.txt"); err != nil || string(data) != "inside" {
+		t.Fatalf("dot-dot should stay at the jail root: %q, %v", data, err)
+	}
+	if data, err := backend.ReadFile("/alias.txt"); err != nil || string(data) != "inside" {
+		t.Fatalf("symlink inside the jail: %q, %v", data, err)
+	}
+	if _, err := backend.ReadFile("/escape/secret.txt"); !errors.Is(err, fs.ErrPermission) {
+		t.Fatalf("symlink escape error = %v", err)
+	}
+	if err := backend.WriteFile("/escape/planted.txt", []byte("x"), 0o644); !errors.Is(err, fs.ErrPermission) {
+		t.Fatalf("symlink escape write error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "planted.txt")); !os.IsNotExist(err) {
+		t.Fatalf("write escaped the jail: %v", err)
+	}
+	caps := fsmod.CapabilitiesForBackend(backend)
+	if caps.Backend != "jail" || len(caps.Mounts) != 1 || caps.Mounts[0].Root != dir {
+		t.Fatalf("jail capabilities = %#v", caps)
+	}
+}
+
+func TestMountBackendRoutesPrefixes(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "input.txt"), []byte("host"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	jail, err := fsmod.NewJailedOSBackend(dir)
+	if err != nil {
+		t.Fatalf("open jail: %v", err)
+	}
+	t.Cleanup(func() { _ = jail.Close() })
+	assets := fstest.MapFS{"web/logo.svg": &fstest.MapFile{Data: []byte("<svg/>")}}
+	backend := fsmod.NewMountBackend(
+		fsmod.BackendMount{Mount: "/tmp", Backend: fsmod.NewMemoryBackend()},
+		fsmod.BackendMount{Mount: "/assets", Backend: fsmod.NewReadOnlyFSBackend(fsmod.FSMount{FS: assets, Root: "web", Mount: "/"})},
+		fsmod.BackendMount{Mount: "/data", Backend: jail},
+	)
+
+	rt := newBackendRuntime(t, backend)
+	ret, err := rt.Owner.Call(context.Background(), "fs.mount.sync", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		value, runErr := vm.RunString(`
+			const fs = require("fs");
+			const caps = fs.capabilities();
+			let assetCode = "", dirCode = "";
+			try { fs.writeFileSync("/assets/logo.svg", "x"); } catch (e) { assetCode = e.code + ":" + e.path; }
+			fs.writeFileSync("/tmp/out.txt", fs.readFileSync("/data/input.txt", "utf8") + "+tmp");
+			fs.renameSync("/tmp/out.txt", "/data/out.txt");
+			fs.mkdirSync("/tmp/dir");
+			try { fs.renameSync("/tmp/dir", "/data/dir"); } catch (e) { dirCode = e.code; }
+			JSON.stringify({
+				root: fs.readdirSync("/"),
+				rootIsDir: fs.statSync("/").isDir,
+				logo: fs.readFileSync("/assets/logo.svg", "utf8"),
+				moved: fs.existsSync("/tmp/out.txt"),
+				data: fs.readdirSync("/data"),
+				assetCode, dirCode,
+				mounts: caps.mounts.map(m => m.mount + "=" + m.backend + (m.readOnly ? ":ro" : "")),
+				write: caps.write,
+			});
+		`)
+		if runErr != nil {
+			return nil, runErr
+		}
+		return value.String(), nil
+	})
+	if err != nil {
+		t.Fatalf("run mount script: %v", err)
+	}
+	state := ret.(string)
+	for _, want := range []string{
+		`"root":["assets","data","tmp"]`,
+		`"rootIsDir":true`,
+		`"logo":"<svg/>"`,
+		`"moved":false`,
+		`"data":["input.txt","out.txt"]`,
+		`"assetCode":"EROFS:/assets/logo.svg"`,
+		`"dirCode":"EXDEV"`,
+		`"mounts":["/assets=embedded:ro","/data=jail","/tmp=memory"]`,
+		`"write":true`,
+	} {
+		if !strings.Contains(state, want) {
+			t.Fatalf("mount state missing %s: %s", want, state)
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "out.txt")); err != nil || string(data) != "host+tmp" {
+		t.Fatalf("moved file on host = %q, %v", data, err)
+	}
+}
+
+func TestSandboxProfileChecksHostPathsBehindVirtualBackends(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"in", "secret"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name, "a.txt"), []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	jail, err := fsmod.NewJailedOSBackend(dir)
+	if err != nil {
+		t.Fatalf("open jail: %v", err)
+	}
+	t.Cleanup(func() { _ = jail.Close() })
+	profile, err := sandbox.ParseProfile([]byte("fs:\n  read: [in]\n"), dir)
+	if err != nil {
+		t.Fatalf("parse profile: %v", err)
+	}
+	backend := fsmod.NewMountBackend(
+		fsmod.BackendMount{Mount: "/host", Backend: jail},
+		fsmod.BackendMount{Mount: "/scratch", Backend: fsmod.NewMemoryBackend()},
+		fsmod.BackendMount{Mount: "/cow", Backend: fsmod.NewOverlayBackend(jail, nil)},
+	)
+	rt := newBackendRuntime(t, backend, gggengine.WithSandbox(profile))
+
+	ret, err := rt.Owner.Call(context.Background(), "fs.sandbox.virtual", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		value, runErr := vm.RunString(`
+			const fs = require("fs");
+			const denied = (fn) => { try { fn(); return "allowed"; } catch (e) { return [e.code, e.capability, e.path].join("|"); } };
+			fs.writeFileSync("/scratch/note.txt", "memory");
+			fs.writeFileSync("/cow/in/a.txt", "upper");
+			JSON.stringify({
+				read: fs.readFileSync("/host/in/a.txt", "utf8"),
+				scratch: fs.readFileSync("/scratch/note.txt", "utf8"),
+				cow: fs.readFileSync("/cow/in/a.txt", "utf8"),
+				secret: denied(() => fs.readFileSync("/host/secret/a.txt")),
+				cowSecret: denied(() => fs.readFileSync("/cow/secret/a.txt")),
+				write: denied(() => fs.writeFileSync("/host/in/a.txt", "x")),
+			});
+		`)
+		if runErr != nil {
+			return nil, runErr
+		}
+		return value.String(), nil
+	})
+	if err != nil {
+		t.Fatalf("run sandboxed mounts: %v", err)
+	}
+	state := ret.(string)
+	for _, want := range []string{
+		`"read":"in"`,
+		`"scratch":"memory"`,
+		`"cow":"upper"`,
+		`"secret":"ERR_ACCESS_DENIED|fs.read|/host/secret/a.txt"`,
+		`"cowSecret":"ERR_ACCESS_DENIED|fs.read|/cow/secret/a.txt"`,
+		`"write":"ERR_ACCESS_DENIED|fs.write|/host/in/a.txt"`,
+	} {
+		if !strings.Contains(state, want) {
+			t.Fatalf("sandboxed mount state missing %s: %s", want, state)
+		}
+	}
+	if strings.Contains(state, dir) {
+		t.Fatalf("denials reveal the jail directory: %s", state)
+	}
+}
+
+func newBackendRuntime(t *testing.T, backend fsmod.Backend, opts ...gggengine.Option) *gggengine.Runtime {
+	t.Helper()
+	mod := fsmod.New(fsmod.WithBackend(backend))
+	factory, err := gggengine.NewRuntimeFactoryBuilder(append([]gggengine.Option{
+		gggengine.WithImplicitDefaultRegistryModules(false),
+		gggengine.WithDataOnlyDefaultRegistryModules(false),
+	}, opts...)...).WithModules(gggengine.NativeModuleRegistrar{ModuleName: "fs", Loader: mod.Loader}).Build()
+	if err != nil {
+		t.Fatalf("build backend factory: %v", err)
+	}
+	rt, err := factory.NewRuntime(gggengine.WithStartupContext(context.Background()), gggengine.WithLifetimeContext(context.Background()))
+	if err != nil {
+		t.Fatalf("new backend runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close(context.Background()) })
+	return rt
+}
@@ -4,8 +4,10 @@ import (
 	"errors"
 	"io/fs"
 	"os"
+	"syscall"
 
 	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/pkg/sandbox"
 )
 
 type fsOpError struct {
@@ -26,6 +28,8 @@ func wrapFSError(err error, path, syscall string) error {
 
 func fsErrorCode(err error) string {
 	switch {
+	case errors.Is(err, sandbox.ErrPermissionDenied):
+		return sandbox.JSCode
 	case errors.Is(err, fs.ErrNotExist), os.IsNotExist(err):
 		return "ENOENT"
 	case errors.Is(err, fs.ErrPermission), os.IsPermission(err):
@@ -34,6 +38,22 @@ func fsErrorCode(err error) string {
 		return "EEXIST"
 	case errors.Is(err, errReadOnlyFS):
 		return "EROFS"
+	case errors.Is(err, syscall.ENOTDIR):
+		return "ENOTDIR"
+	case errors.Is(err, syscall.EISDIR):
+		return "EISDIR"
+	case errors.Is(err, syscall.ENOTEMPTY):
+		return "ENOTEMPTY"
+	case errors.Is(err, syscall.EXDEV):
+		return "EXDEV"
+	case errors.Is(err, syscall.ELOOP):
+		return "ELOOP"
+	case errors.Is(err, syscall.EINVAL):
+		return "EINVAL"
+	case errors.Is(err, syscall.EBADF), errors.Is(err, fs.ErrClosed):
+		return "EBADF"
+	case errors.Is(err, errors.ErrUnsupported):
+		return "ENOSYS"
 	default:
 		return "EIO"
 	}
@@ -50,7 +70,7 @@ func fsErrorValue(vm *goja.Runtime, err error) goja.Value {
 		path = opErr.path
 		syscall = opErr.syscall
 	}
-	obj := vm.NewGoError(err)
+	obj := sandbox.JSError(vm, err)
 	_ = obj.Set("code", fsErrorCode(err))
 	if path != "" {
 		_ = obj.Set("path", path)
@@ -0,0 +1,485 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"reflect"
+	"sync"
+	"syscall"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/buffer"
+	"github.com/go-go-golems/go-go-goja/modules"
+	"github.com/go-go-golems/go-go-goja/modules/stream"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimebridge"
+)
+
+// fdTable maps the integer descriptors handed to JavaScript to open files.
+// Descriptors start at 3, after the standard streams, and are not reused.
+// Every file still open when the runtime shuts down is closed.
+type fdTable struct {
+	mu     sync.Mutex
+	next   int
+	files  map[int]*fileHandle
+	closed bool
+}
+
+// fileHandle is one open file plus the position used by reads and writes
+// that do not pass one.
+type fileHandle struct {
+	fd   int
+	path string
+	file File
+
+	mu  sync.Mutex
+	pos int64
+}
+
+func newFDTable(lifetime context.Context) *fdTable {
+	t := &fdTable{next: 3, files: map[int]*fileHandle{}}
+	context.AfterFunc(lifetime, t.closeAll)
+	return t
+}
+
+func (t *fdTable) open(backend Backend, path string, flag int, mode os.FileMode) (*fileHandle, error) {
+	file, err := openFile(backend, path, flag, mode)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		_ = file.Close()
+		return nil, wrapFSError(fs.ErrClosed, path, "open")
+	}
+	h := &fileHandle{fd: t.next, path: path, file: file}
+	t.next++
+	t.files[h.fd] = h
+	return h, nil
+}
+
+func (t *fdTable) get(fd int, syscallName string) (*fileHandle, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h, ok := t.files[fd]
+	if !ok {
+		return nil, wrapFSError(syscall.EBADF, "", syscallName)
+	}
+	return h, nil
+}
+
+func (t *fdTable) close(fd int) error {
+	t.mu.Lock()
+	h, ok := t.files[fd]
+	delete(t.files, fd)
+	t.mu.Unlock()
+	if !ok {
+		return wrapFSError(syscall.EBADF, "", "close")
+	}
+	return h.file.Close()
+}
+
+func (t *fdTable) closeAll() {
+	t.mu.Lock()
+	files := t.files
+	t.files = map[int]*fileHandle{}
+	t.closed = true
+	t.mu.Unlock()
+	for _, h := range files {
+		_ = h.file.Close()
+	}
+}
+
+// read reads into p at position, or at the handle's position when position
+// is negative, advancing it. Reading at or past the end returns 0 bytes.
+func (h *fileHandle) read(p []byte, position int64) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	off := position
+	if off < 0 {
+		off = h.pos
+	}
+	n, err := h.file.ReadAt(p, off)
+	if position < 0 {
+		h.pos += int64(n)
+	}
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	return n, err
+}
+
+// write writes p like read reads.
+func (h *fileHandle) write(p []byte, position int64) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	off := position
+	if off < 0 {
+		off = h.pos
+	}
+	n, err := h.file.WriteAt(p, off)
+	if position < 0 {
+		h.pos += int64(n)
+	}
+	return n, err
+}
+
+// readAll reads from the handle's position to the end of the file.
+func (h *fileHandle) readAll() ([]byte, error) {
+	var data []byte
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := h.read(buf, -1)
+		data = append(data, buf[:n]...)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return data, nil
+		}
+	}
+}
+
+func (h *fileHandle) truncate(size int64) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.file.Truncate(size)
+}
+
+// parseOpenFlags converts a Node flags string such as "r+" or "wx", or a
+// numeric combination of fs.constants, into os.OpenFile flags.
+func parseOpenFlags(vm *goja.Runtime, value goja.Value, fallback string) int {
+	if !modules.Present(value) {
+		value = vm.ToValue(fallback)
+	}
+	if value.ExportType().Kind() != reflect.String {
+		flags := value.ToInteger()
+		if flags < 0 || flags > math.MaxInt32 {
+			panic(vm.NewTypeError("fs flags must be a string or a non-negative integer"))
+		}
+		return int(flags)
+	}
+	switch value.String() {
+	case "r", "rs", "sr":
+		return os.O_RDONLY
+	case "r+", "rs+", "sr+":
+		return os.O_RDWR
+	case "w":
+		return os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	case "wx", "xw":
+		return os.O_WRONLY | os.O_CREATE | os.O_TRUNC | os.O_EXCL
+	case "w+":
+		return os.O_RDWR | os.O_CREATE | os.O_TRUNC
+	case "wx+", "xw+":
+		return os.O_RDWR | os.O_CREATE | os.O_TRUNC | os.O_EXCL
+	case "a", "as", "sa":
+		return os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	case "ax", "xa":
+		return os.O_WRONLY | os.O_CREATE | os.O_APPEND | os.O_EXCL
+	case "a+", "as+", "sa+":
+		return os.O_RDWR | os.O_CREATE | os.O_APPEND
+	case "ax+", "xa+":
+		return os.O_RDWR | os.O_CREATE | os.O_APPEND | os.O_EXCL
+	}
+	panic(vm.NewTypeError("fs: unknown file open flag %q", value.String()))
+}
+
+func openMode(vm *goja.Runtime, value goja.Value) os.FileMode {
+	if !modules.Present(value) {
+		return 0o666
+	}
+	return fileMode(modeArg(vm, value))
+}
+
+func constantsObject() map[string]any {
+	return map[string]any{
+		"O_RDONLY": os.O_RDONLY,
+		"O_WRONLY": os.O_WRONLY,
+		"O_RDWR":   os.O_RDWR,
+		"O_CREAT":  os.O_CREATE,
+		"O_EXCL":   os.O_EXCL,
+		"O_TRUNC":  os.O_TRUNC,
+		"O_APPEND": os.O_APPEND,
+	}
+}
+
+// ioRequest is a decoded read/write call: the byte range of the caller's
+// buffer and the file position, negative for the handle's own.
+type ioRequest struct {
+	target   goja.Value
+	data     []byte
+	position int64
+}
+
+// readRequest decodes (buffer, offset?, length?, position?) or
+// (buffer, {offset, length, position}). data aliases the buffer.
+func readRequest(vm *goja.Runtime, args []goja.Value) ioRequest {
+	target := argument(args, 0)
+	var buf []byte
+	if !modules.Present(target) || vm.ExportTo(target, &buf) != nil {
+		panic(vm.NewTypeError("fs: buffer must be a Buffer or Uint8Array"))
+	}
+	offset, length, position := argument(args, 1), argument(args, 2), argument(args, 3)
+	if modules.Present(offset) && offset.ExportType().Kind() == reflect.Map {
+		opts := offset.ToObject(vm)
+		offset, length, position = opts.Get("offset"), opts.Get("length"), opts.Get("position")
+	}
+	return ioRequest{target: target, data: bufferRange(vm, buf, offset, length), position: positionArg(position)}
+}
+
+// writeRequest decodes (buffer, offset?, length?, position?) or
+// (string, position?, encoding?). data is a copy, so it can be written in
+// the background.
+func writeRequest(vm *goja.Runtime, args []goja.Value) ioRequest {
+	target := argument(args, 0)
+	if modules.Present(target) && target.ExportType().Kind() == reflect.String {
+		data := buffer.DecodeBytes(vm, target, argument(args, 2))
+		return ioRequest{target: target, data: data, position: positionArg(argument(args, 1))}
+	}
+	req := readRequest(vm, args)
+	req.data = append([]byte(nil), req.data...)
+	return req
+}
+
+func bufferRange(vm *goja.Runtime, buf []byte, offsetValue, lengthValue goja.Value) []byte {
+	offset := int64(0)
+	if modules.Present(offsetValue) {
+		offset = offsetValue.ToInteger()
+	}
+	if offset < 0 || offset > int64(len(buf)) {
+		panic(vm.NewTypeError("fs: offset %d is out of range", offset))
+	}
+	length := int64(len(buf)) - offset
+	if modules.Present(lengthValue) {
+		length = lengthValue.ToInteger()
+	}
+	if length < 0 || offset+length > int64(len(buf)) {
+		panic(vm.NewTypeError("fs: length %d is out of range", length))
+	}
+	return buf[offset : offset+length]
+}
+
+func positionArg(value goja.Value) int64 {
+	if !modules.Present(value) {
+		return -1
+	}
+	return value.ToInteger()
+}
+
+func argument(args []goja.Value, i int) goja.Value {
+	if i < len(args) {
+		return args[i]
+	}
+	return goja.Undefined()
+}
+
+func argumentsFrom(args []goja.Value, i int) []goja.Value {
+	if i < len(args) {
+		return args[i:]
+	}
+	return nil
+}
+
+// fileHandleObject builds the FileHandle returned by fs.open. Its methods
+// return Promises and operate on the handle's descriptor in table.
+func fileHandleObject(vm *goja.Runtime, runtimeServices runtimebridge.RuntimeServices, table *fdTable, h *fileHandle) *goja.Object {
+	obj := vm.NewObject()
+	handle := func(op string) *fileHandle {
+		current, err := table.get(h.fd, op)
+		panicFSError(vm, err)
+		return current
+	}
+	_ = obj.Set("fd", h.fd)
+	_ = obj.Set("read", func(call goja.FunctionCall) goja.Value {
+		h := handle("read")
+		req := readRequest(vm, call.Arguments)
+		return asyncResult(vm, runtimeServices, "fs.FileHandle.read", func() (func() goja.Value, error) {
+			tmp := make([]byte, len(req.data))
+			n, err := h.read(tmp, req.position)
+			if err != nil {
+				return nil, err
+			}
+			return func() goja.Value {
+				copy(req.data, tmp[:n])
+				return vm.ToValue(map[string]any{"bytesRead": n, "buffer": req.target})
+			}, nil
+		})
+	})
+	_ = obj.Set("write", func(call goja.FunctionCall) goja.Value {
+		h := handle("write")
+		req := writeRequest(vm, call.Arguments)
+		return asyncResult(vm, runtimeServices, "fs.FileHandle.write", func() (func() goja.Value, error) {
+			n, err := h.write(req.data, req.position)
+			if err != nil {
+				return nil, err
+			}
+			return func() goja.Value {
+				return vm.ToValue(map[string]any{"bytesWritten": n, "buffer": req.target})
+			}, nil
+		})
+	})
+	_ = obj.Set("readFile", func(call goja.FunctionCall) goja.Value {
+		h := handle("read")
+		enc := encodingOption(vm, call.Argument(0))
+		return asyncResult(vm, runtimeServices, "fs.FileHandle.readFile", func() (func() goja.Value, error) {
+			data, err := h.readAll()
+			if err != nil {
+				return nil, err
+			}
+			return func() goja.Value { return buffer.EncodeBytes(vm, data, enc) }, nil
+		})
+	})
+	writeAll := func(op string) func(goja.FunctionCall) goja.Value {
+		return func(call goja.FunctionCall) goja.Value {
+			h := handle("write")
+			enc, _ := writeOptions(vm, call.Argument(1))
+			data := buffer.DecodeBytes(vm, call.Argument(0), enc)
+			return asyncValue(vm, runtimeServices, op, func() (any, error) {
+				_, err := h.write(data, -1)
+				return nil, err
+			})
+		}
+	}
+	_ = obj.Set("writeFile", writeAll("fs.FileHandle.writeFile"))
+	_ = obj.Set("appendFile", writeAll("fs.FileHandle.appendFile"))
+	_ = obj.Set("stat", func() goja.Value {
+		h := handle("fstat")
+		return asyncValue(vm, runtimeServices, "fs.FileHandle.stat", func() (any, error) {
+			return h.file.Stat()
+		})
+	})
+	_ = obj.Set("truncate", func(call goja.FunctionCall) goja.Value {
+		h := handle("ftruncate")
+		size := call.Argument(0).ToInteger()
+		return asyncValue(vm, runtimeServices, "fs.FileHandle.truncate", func() (any, error) {
+			return nil, h.truncate(size)
+		})
+	})
+	_ = obj.Set("sync", func() goja.Value {
+		h := handle("fsync")
+		return asyncValue(vm, runtimeServices, "fs.FileHandle.sync", func() (any, error) {
+			return nil, h.file.Sync()
+		})
+	})
+	_ = obj.Set("close", func() goja.Value {
+		return asyncValue(vm, runtimeServices, "fs.FileHandle.close", func() (any, error) {
+			err := table.close(h.fd)
+			if errors.Is(err, syscall.EBADF) {
+				// Closing a FileHandle twice is a no-op, as in Node.
+				err = nil
+			}
+			return nil, err
+		})
+	})
+	_ = obj.Set("createReadStream", func(call goja.FunctionCall) goja.Value {
+		h := handle("read")
+		opts := parseStreamOptions(vm, call.Argument(0))
+		return readStream(vm, h.file, func() error { return table.close(h.fd) }, opts)
+	})
+	_ = obj.Set("createWriteStream", func(call goja.FunctionCall) goja.Value {
+		h := handle("write")
+		opts := parseStreamOptions(vm, call.Argument(0))
+		return writeStream(vm, h.file, func() error { return table.close(h.fd) }, opts)
+	})
+	return obj
+}
+
+// streamOptions are the createReadStream/createWriteStream options. end is
+// inclusive, as in Node, and negative when unset.
+type streamOptions struct {
+	flags         goja.Value
+	mode          goja.Value
+	start         int64
+	end           int64
+	highWaterMark int
+	encoding      string
+}
+
+func parseStreamOptions(vm *goja.Runtime, value goja.Value) streamOptions {
+	opts := streamOptions{flags: goja.Undefined(), mode: goja.Undefined(), end: -1}
+	if !modules.Present(value) {
+		return opts
+	}
+	if value.ExportType().Kind() == reflect.String {
+		opts.encoding = value.String()
+		return opts
+	}
+	obj := value.ToObject(vm)
+	opts.flags = obj.Get("flags")
+	opts.mode = obj.Get("mode")
+	if v := obj.Get("start"); modules.Present(v) {
+		opts.start = v.ToInteger()
+	}
+	if v := obj.Get("end"); modules.Present(v) {
+		opts.end = v.ToInteger()
+	}
+	if opts.start < 0 || (opts.end >= 0 && opts.end < opts.start) {
+		panic(vm.NewTypeError("fs: stream start and end must satisfy 0 <= start <= end"))
+	}
+	if v := obj.Get("highWaterMark"); modules.Present(v) {
+		opts.highWaterMark = int(v.ToInteger())
+	}
+	if v := obj.Get("encoding"); modules.Present(v) {
+		opts.encoding = v.String()
+	}
+	return opts
+}
+
+type sectionReadCloser struct {
+	*io.SectionReader
+	close func() error
+}
+
+func (r sectionReadCloser) Close() error { return r.close() }
+
+// fileWriter writes sequentially through WriteAt from a start offset.
+type fileWriter struct {
+	file  File
+	off   int64
+	close func() error
+}
+
+func (w *fileWriter) Write(p []byte) (int, error) {
+	n, err := w.file.WriteAt(p, w.off)
+	w.off += int64(n)
+	return n, err
+}
+
+func (w *fileWriter) Close() error { return w.close() }
+
+func readStream(vm *goja.Runtime, file File, closeFile func() error, opts streamOptions) *goja.Object {
+	size := int64(math.MaxInt64 - opts.start)
+	if opts.end >= 0 {
+		size = opts.end - opts.start + 1
+	}
+	r := sectionReadCloser{SectionReader: io.NewSectionReader(file, opts.start, size), close: closeFile}
+	return stream.NewReadable(vm, r, stream.Options{HighWaterMark: opts.highWaterMark, Encoding: opts.encoding})
+}
+
+func writeStream(vm *goja.Runtime, file File, closeFile func() error, opts streamOptions) *goja.Object {
+	w := &fileWriter{file: file, off: opts.start, close: closeFile}
+	return stream.NewWritable(vm, w, stream.Options{HighWaterMark: opts.highWaterMark})
+}
+
+// failedStream returns a stream that emits err once listeners had a chance
+// to attach, the way Node reports a failed open from createReadStream.
+func failedStream(vm *goja.Runtime, runtimeServices runtimebridge.RuntimeServices, readable bool, err error) *goja.Object {
+	// The pipe never delivers data; destroying the stream closes it.
+	pr, pw := io.Pipe()
+	var obj *goja.Object
+	if readable {
+		obj = stream.NewReadable(vm, pr, stream.Options{})
+	} else {
+		obj = stream.NewWritable(vm, pw, stream.Options{})
+	}
+	_ = runtimeServices.PostWithLifetimeContext("fs.stream.openError", func(context.Context, *goja.Runtime) {
+		if destroy, ok := goja.AssertFunction(obj.Get("destroy")); ok {
+			_, _ = destroy(obj, fsErrorValue(vm, err))
+		}
+	})
+	return obj
+}
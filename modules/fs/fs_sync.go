@@ -1,10 +1,14 @@
 package fs
 
 import (
+	"context"
 	"errors"
 	"io/fs"
 	"os"
+	"path/filepath"
 	"time"
+
+	"github.com/go-go-golems/go-go-goja/pkg/fswatch"
 )
 
 type fileStats map[string]any
@@ -23,6 +27,8 @@ func statMap(info os.FileInfo) fileStats {
 		"modTime": info.ModTime().Format(time.RFC3339),
 		"isDir":   info.IsDir(),
 		"isFile":  info.Mode().IsRegular(),
+		// isSymbolicLink is only ever true for lstat results.
+		"isSymbolicLink": info.Mode()&os.ModeSymlink != 0,
 	}
 }
 
@@ -100,3 +106,50 @@ func (OSBackend) RemoveAll(path string) error {
 func isNotExist(err error) bool {
 	return errors.Is(err, fs.ErrNotExist) || os.IsNotExist(err)
 }
+
+func (OSBackend) OpenFile(path string, flag int, mode os.FileMode) (File, error) {
+	f, err := os.OpenFile(path, flag, mode)
+	if err != nil {
+		return nil, wrapFSError(err, path, "open")
+	}
+	return &osFile{f: f, path: path, append: flag&os.O_APPEND != 0}, nil
+}
+
+func (OSBackend) Symlink(target, path string) error {
+	return wrapFSError(os.Symlink(target, path), path, "symlink")
+}
+
+func (OSBackend) Readlink(path string) (string, error) {
+	target, err := os.Readlink(path)
+	return target, wrapFSError(err, path, "readlink")
+}
+
+func (OSBackend) Lstat(path string) (fileStats, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, wrapFSError(err, path, "lstat")
+	}
+	return statMap(info), nil
+}
+
+func (OSBackend) Realpath(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", wrapFSError(err, path, "realpath")
+	}
+	abs, err := filepath.Abs(resolved)
+	return abs, wrapFSError(err, path, "realpath")
+}
+
+func (OSBackend) Chmod(path string, mode os.FileMode) error {
+	return wrapFSError(os.Chmod(path, mode), path, "chmod")
+}
+
+func (OSBackend) Chtimes(path string, atime, mtime time.Time) error {
+	return wrapFSError(os.Chtimes(path, atime, mtime), path, "utime")
+}
+
+func (OSBackend) Watch(ctx context.Context, path string, opts fswatch.Options, handler fswatch.Handler) (*fswatch.Watch, error) {
+	watch, err := fswatch.Start(ctx, path, opts, handler)
+	return watch, wrapFSError(err, path, "watch")
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/dop251/goja"
 	gggengine "github.com/go-go-golems/go-go-goja/pkg/engine"
+	"github.com/go-go-golems/go-go-goja/pkg/sandbox"
 )
 
 func TestHostFsCapabilities(t *testing.T) {
@@ -283,9 +284,61 @@ func TestFsAsyncErrorObjectSmoke(t *testing.T) {
 	}
 }
 
-func newRuntime(t *testing.T) *gggengine.Runtime {
+func TestFsSandboxProfileDeniesUngrantedPaths(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "in"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "in", "a.txt"), []byte("hello"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	profile, err := sandbox.ParseProfile([]byte("name: test\nfs:\n  read: [in]\n  write: [out]\n"), dir)
+	if err != nil {
+		t.Fatalf("parse profile: %v", err)
+	}
+	rt := newRuntime(t, gggengine.WithSandbox(profile))
+	quotedDir := strconv.Quote(filepath.ToSlash(dir))
+
+	ret, err := rt.Owner.Call(context.Background(), "fs.sandbox", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		value, runErr := vm.RunString(`
+			const fs = require("fs");
+			const root = ` + quotedDir + `;
+			const denied = (fn) => { try { fn(); return "allowed"; } catch (e) { return [e.name, e.code, e.capability, e.path].join("|"); } };
+			fs.mkdirSync(root + "/out", { recursive: true });
+			fs.copyFileSync(root + "/in/a.txt", root + "/out/b.txt");
+			JSON.stringify({
+				read: fs.readFileSync(root + "/in/a.txt", "utf8"),
+				copied: fs.readFileSync(root + "/out/b.txt", "utf8"),
+				write: denied(() => fs.writeFileSync(root + "/in/a.txt", "x")),
+				outside: denied(() => fs.readFileSync(root + "/secret.txt")),
+				exists: fs.existsSync(root),
+			});
+		`)
+		if runErr != nil {
+			return nil, runErr
+		}
+		return value.String(), nil
+	})
+	if err != nil {
+		t.Fatalf("run fs sandbox: %v", err)
+	}
+	state := ret.(string)
+	for _, want := range []string{
+		`"read":"hello"`,
+		`"copied":"hello"`,
+		`"write":"PermissionError|ERR_ACCESS_DENIED|fs.write|` + filepath.ToSlash(dir) + `/in/a.txt"`,
+		`"outside":"PermissionError|ERR_ACCESS_DENIED|fs.read|`,
+		`"exists":false`,
+	} {
+		if !strings.Contains(state, want) {
+			t.Fatalf("fs sandbox state missing %s: %s", want, state)
+		}
+	}
+}
+
+func newRuntime(t *testing.T, opts ...gggengine.Option) *gggengine.Runtime {
 	t.Helper()
-	factory, err := gggengine.NewRuntimeFactoryBuilder().UseModuleMiddleware(gggengine.MiddlewareOnly("fs")).Build()
+	factory, err := gggengine.NewRuntimeFactoryBuilder(opts...).UseModuleMiddleware(gggengine.MiddlewareOnly("fs")).Build()
 	if err != nil {
 		t.Fatalf("build factory: %v", err)
 	}
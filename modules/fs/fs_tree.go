@@ -0,0 +1,345 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"math/rand/v2"
+	"path"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/modules"
+	"github.com/go-go-golems/go-go-goja/pkg/fswatch"
+)
+
+// direntInfo is what readdir({withFileTypes: true}) learns about one entry
+// in the background; direntObject turns it into a Dirent on the owner
+// goroutine.
+type direntInfo struct {
+	name  string
+	dir   string
+	stats fileStats
+}
+
+func readDirents(backend Backend, dir string) ([]direntInfo, error) {
+	names, err := backend.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]direntInfo, 0, len(names))
+	for _, name := range names {
+		stats, err := lstatPath(backend, path.Join(dir, name))
+		if err != nil {
+			// The entry vanished between the listing and the lstat.
+			if isNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		ret = append(ret, direntInfo{name: name, dir: dir, stats: stats})
+	}
+	return ret, nil
+}
+
+func direntObject(vm *goja.Runtime, info direntInfo) *goja.Object {
+	obj := vm.NewObject()
+	_ = obj.Set("name", info.name)
+	_ = obj.Set("parentPath", info.dir)
+	_ = obj.Set("path", info.dir)
+	_ = obj.Set("isFile", func() bool { return info.stats["isFile"] == true })
+	_ = obj.Set("isDirectory", func() bool { return info.stats["isDir"] == true })
+	_ = obj.Set("isSymbolicLink", func() bool { return info.stats["isSymbolicLink"] == true })
+	return obj
+}
+
+func direntsValue(vm *goja.Runtime, infos []direntInfo) goja.Value {
+	values := make([]any, len(infos))
+	for i, info := range infos {
+		values[i] = direntObject(vm, info)
+	}
+	return vm.NewArray(values...)
+}
+
+func withFileTypesOption(vm *goja.Runtime, value goja.Value) bool {
+	if !modules.Present(value) || value.ExportType().Kind() == reflect.String {
+		return false
+	}
+	v := value.ToObject(vm).Get("withFileTypes")
+	return v != nil && v.ToBoolean()
+}
+
+// modeArg accepts a numeric mode or, as Node does, an octal string.
+func modeArg(vm *goja.Runtime, value goja.Value) uint32 {
+	if value.ExportType().Kind() == reflect.String {
+		mode, err := strconv.ParseUint(value.String(), 8, 32)
+		if err != nil {
+			panic(vm.NewTypeError("fs mode %q is not an octal number", value.String()))
+		}
+		return uint32(mode)
+	}
+	return fileModeOption(vm, value)
+}
+
+// timeArg accepts a Date, a number of seconds since the epoch, or a numeric
+// string, as utimes does in Node.
+func timeArg(vm *goja.Runtime, value goja.Value, name string) time.Time {
+	if t, ok := value.Export().(time.Time); ok {
+		return t
+	}
+	seconds := value.ToFloat()
+	if value.ExportType().Kind() == reflect.String {
+		parsed, err := strconv.ParseFloat(value.String(), 64)
+		if err != nil {
+			panic(vm.NewTypeError("fs: %s must be a Date or a number", name))
+		}
+		seconds = parsed
+	}
+	if math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		panic(vm.NewTypeError("fs: %s must be a finite time", name))
+	}
+	whole, frac := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(frac*1e9))
+}
+
+const tempNameChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// makeTempDir creates a directory named prefix plus six random characters,
+// retrying on collisions.
+func makeTempDir(backend Backend, prefix string) (string, error) {
+	var err error
+	for range 100 {
+		suffix := make([]byte, 6)
+		for i := range suffix {
+			suffix[i] = tempNameChars[rand.IntN(len(tempNameChars))]
+		}
+		dir := prefix + string(suffix)
+		err = backend.Mkdir(dir, false, 0o700)
+		if err == nil {
+			return dir, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return "", err
+		}
+	}
+	return "", err
+}
+
+type cpOptions struct {
+	recursive    bool
+	force        bool
+	errorOnExist bool
+}
+
+func parseCpOptions(vm *goja.Runtime, value goja.Value) cpOptions {
+	opts := cpOptions{force: true}
+	if !modules.Present(value) {
+		return opts
+	}
+	obj := value.ToObject(vm)
+	if v := obj.Get("recursive"); modules.Present(v) {
+		opts.recursive = v.ToBoolean()
+	}
+	if v := obj.Get("force"); modules.Present(v) {
+		opts.force = v.ToBoolean()
+	}
+	if v := obj.Get("errorOnExist"); modules.Present(v) {
+		opts.errorOnExist = v.ToBoolean()
+	}
+	return opts
+}
+
+// copyTree copies src to dst within backend. Directories need recursive,
+// symlinks are recreated rather than followed, and existing files are
+// overwritten only with force.
+func copyTree(backend Backend, src, dst string, opts cpOptions) error {
+	cleanSrc, cleanDst := path.Clean(src), path.Clean(dst)
+	if cleanSrc == cleanDst || strings.HasPrefix(cleanDst, strings.TrimSuffix(cleanSrc, "/")+"/") {
+		return wrapFSError(syscall.EINVAL, dst, "cp")
+	}
+	return copyEntry(backend, src, dst, opts)
+}
+
+func copyEntry(backend Backend, src, dst string, opts cpOptions) error {
+	stats, err := lstatPath(backend, src)
+	if err != nil {
+		return err
+	}
+	switch {
+	case stats["isDir"] == true:
+		if !opts.recursive {
+			return wrapFSError(syscall.EISDIR, src, "cp")
+		}
+		if err := backend.Mkdir(dst, true, statPerm(stats)|0o700); err != nil {
+			return err
+		}
+		names, err := backend.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if err := copyEntry(backend, path.Join(src, name), path.Join(dst, name), opts); err != nil {
+				return err
+			}
+		}
+		return nil
+	case backend.Exists(dst) || isSymlink(backend, dst):
+		if !opts.force {
+			if opts.errorOnExist {
+				return wrapFSError(fs.ErrExist, dst, "cp")
+			}
+			return nil
+		}
+		if stats["isSymbolicLink"] == true {
+			if err := backend.Remove(dst); err != nil {
+				return err
+			}
+		}
+	}
+	if stats["isSymbolicLink"] == true {
+		target, err := readlinkPath(backend, src)
+		if err != nil {
+			return err
+		}
+		return symlinkPath(backend, target, dst)
+	}
+	return backend.CopyFile(src, dst)
+}
+
+func isSymlink(backend Backend, p string) bool {
+	stats, err := lstatPath(backend, p)
+	return err == nil && stats["isSymbolicLink"] == true
+}
+
+// globOptions are the glob/globSync options. Results are relative to cwd
+// unless the pattern is absolute.
+type globOptions struct {
+	cwd     string
+	exclude []string
+}
+
+func parseGlobArgs(vm *goja.Runtime, patternValue, optionsValue goja.Value) ([]string, globOptions) {
+	var patterns []string
+	if modules.Present(patternValue) && patternValue.ExportType().Kind() == reflect.String {
+		patterns = []string{patternValue.String()}
+	} else if !modules.Present(patternValue) || vm.ExportTo(patternValue, &patterns) != nil {
+		panic(vm.NewTypeError("fs.glob: pattern must be a string or an array of strings"))
+	}
+	opts := globOptions{cwd: "."}
+	if modules.Present(optionsValue) {
+		obj := optionsValue.ToObject(vm)
+		if v := obj.Get("cwd"); modules.Present(v) {
+			opts.cwd = v.String()
+		}
+		if v := obj.Get("exclude"); modules.Present(v) {
+			if vm.ExportTo(v, &opts.exclude) != nil {
+				panic(vm.NewTypeError("fs.glob: exclude must be an array of strings"))
+			}
+		}
+	}
+	if err := fswatch.ValidatePatterns(append(slices.Clone(patterns), opts.exclude...), "pattern"); err != nil {
+		panic(vm.NewTypeError(fmt.Sprintf("fs.glob: %v", err)))
+	}
+	return patterns, opts
+}
+
+// glob walks backend for every pattern and returns the sorted, de-duplicated
+// matches. Walks do not descend into symlinked directories.
+func glob(backend Backend, patterns []string, opts globOptions) ([]string, error) {
+	seen := map[string]bool{}
+	var ret []string
+	for _, pattern := range patterns {
+		matches, err := globPattern(backend, pattern, opts.cwd)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			if seen[match] || globExcluded(match, opts.exclude) {
+				continue
+			}
+			seen[match] = true
+			ret = append(ret, match)
+		}
+	}
+	slices.Sort(ret)
+	return ret, nil
+}
+
+func globPattern(backend Backend, pattern, cwd string) ([]string, error) {
+	segments := strings.Split(path.Clean(pattern), "/")
+	absolute := strings.HasPrefix(pattern, "/")
+	// The literal prefix of the pattern is where the walk starts.
+	literal := 0
+	for literal < len(segments)-1 && !hasGlobMeta(segments[literal]) {
+		literal++
+	}
+	prefix := path.Join(segments[:literal]...)
+	if absolute {
+		prefix = "/" + prefix
+	}
+	base := prefix
+	if !absolute {
+		base = path.Join(cwd, prefix)
+	}
+	rest := path.Join(segments[literal:]...)
+	maxDepth := len(segments) - literal
+	if slices.Contains(segments[literal:], "**") {
+		maxDepth = math.MaxInt
+	}
+
+	var ret []string
+	var walk func(dir, rel string, depth int) error
+	walk = func(dir, rel string, depth int) error {
+		names, err := backend.ReadDir(dir)
+		if err != nil {
+			if depth == 0 && (isNotExist(err) || errors.Is(err, syscall.ENOTDIR)) {
+				return nil
+			}
+			return err
+		}
+		for _, name := range names {
+			childRel := path.Join(rel, name)
+			if fswatch.Match(rest, childRel) {
+				ret = append(ret, path.Join(prefix, childRel))
+			}
+			if depth+1 >= maxDepth {
+				continue
+			}
+			stats, err := lstatPath(backend, path.Join(dir, name))
+			if err != nil || stats["isDir"] != true {
+				continue
+			}
+			if err := walk(path.Join(dir, name), childRel, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if !hasGlobMeta(rest) {
+		// A pattern without wildcards matches the path itself.
+		if backend.Exists(path.Join(base, rest)) {
+			return []string{path.Join(prefix, rest)}, nil
+		}
+		return nil, nil
+	}
+	return ret, walk(base, "", 0)
+}
+
+func globExcluded(match string, exclude []string) bool {
+	for _, pattern := range exclude {
+		if fswatch.Match(pattern, match) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasGlobMeta(segment string) bool {
+	return strings.ContainsAny(segment, "*?[")
+}
@@ -0,0 +1,124 @@
+package fs
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-go-golems/go-go-goja/modules"
+	"github.com/go-go-golems/go-go-goja/modules/events"
+	"github.com/go-go-golems/go-go-goja/pkg/fswatch"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimebridge"
+)
+
+type watchOptions struct {
+	recursive bool
+	debounce  time.Duration
+	signal    *events.AbortSignal
+}
+
+func parseWatchOptions(vm *goja.Runtime, value goja.Value) watchOptions {
+	var opts watchOptions
+	if !modules.Present(value) || value.ExportType().Kind() == reflect.String {
+		return opts
+	}
+	obj := value.ToObject(vm)
+	if v := obj.Get("recursive"); modules.Present(v) {
+		opts.recursive = v.ToBoolean()
+	}
+	if v := obj.Get("debounceMs"); modules.Present(v) {
+		ms := v.ToFloat()
+		if math.IsNaN(ms) || math.IsInf(ms, 0) || ms < 0 {
+			panic(vm.NewTypeError("fs.watch: debounceMs must be a finite non-negative number"))
+		}
+		opts.debounce = time.Duration(ms * float64(time.Millisecond))
+	}
+	if v := obj.Get("signal"); modules.Present(v) {
+		signal, ok := events.SignalOf(v)
+		if !ok {
+			panic(vm.NewTypeError("fs.watch: signal must be an AbortSignal"))
+		}
+		opts.signal = signal
+	}
+	return opts
+}
+
+// watchFile implements fs.watch(filename, options?, listener?). It returns an
+// FSWatcher emitting 'change' (eventType, filename), 'error' and 'close'. The
+// watch stops on close(), when the signal aborts, or at runtime shutdown.
+func watchFile(vm *goja.Runtime, runtimeServices runtimebridge.RuntimeServices, backend Backend, call goja.FunctionCall) goja.Value {
+	filename := call.Argument(0).String()
+	optionsValue, listener := call.Argument(1), call.Argument(2)
+	if _, ok := goja.AssertFunction(optionsValue); ok {
+		optionsValue, listener = goja.Undefined(), optionsValue
+	}
+	opts := parseWatchOptions(vm, optionsValue)
+
+	emitter, obj := events.NewObject(vm)
+	if modules.Present(listener) {
+		if err := emitter.AddListenerValue("change", listener); err != nil {
+			panic(vm.NewTypeError("fs.watch: %v", err))
+		}
+	}
+
+	closed := false
+	// post runs fn on the owner goroutine unless the watcher closed first.
+	post := func(op string, fn func()) {
+		_ = runtimeServices.PostWithLifetimeContext(op, func(context.Context, *goja.Runtime) {
+			if !closed {
+				fn()
+			}
+		})
+	}
+	var watch *fswatch.Watch
+	closeWatcher := func() {
+		if closed {
+			return
+		}
+		closed = true
+		if watch != nil {
+			watch.Close()
+		}
+		_, _ = emitter.Emit("close")
+	}
+
+	watch, err := watchPath(runtimeServices.Lifetime(), backend, filename, fswatch.Options{
+		Recursive: opts.recursive,
+		Debounce:  opts.debounce,
+	}, fswatch.Handler{
+		Event: func(event fswatch.Event) {
+			eventType := "change"
+			if event.Has(fsnotify.Create) || event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+				eventType = "rename"
+			}
+			post("fs.watch.change", func() {
+				_, _ = emitter.Emit("change", vm.ToValue(eventType), vm.ToValue(event.RelativeName))
+			})
+		},
+		Error: func(err error) {
+			post("fs.watch.error", func() {
+				_, _ = emitter.Emit("error", fsErrorValue(vm, wrapFSError(err, filename, "watch")))
+			})
+		},
+		Closed: func() {
+			post("fs.watch.close", closeWatcher)
+		},
+	})
+	panicFSError(vm, err)
+
+	if opts.signal != nil {
+		if opts.signal.Aborted() {
+			closeWatcher()
+		} else {
+			opts.signal.OnAbort(func(goja.Value) { closeWatcher() })
+		}
+	}
+	_ = obj.Set("close", func() goja.Value {
+		closeWatcher()
+		return obj
+	})
+	return obj
+}
@@ -0,0 +1,234 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-go-golems/go-go-goja/pkg/fswatch"
+	"github.com/go-go-golems/go-go-goja/pkg/sandbox"
+)
+
+// sandboxedBackend checks every path against the runtime's sandbox profile
+// before handing it to the wrapped backend. Virtual paths are translated to
+// the host paths they reach first; see hostPaths.
+type sandboxedBackend struct {
+	backend Backend
+	profile *sandbox.Profile
+}
+
+// withSandbox confines backend to profile. Embedded backends are left alone:
+// they are read-only and expose only what the host mounted.
+func withSandbox(backend Backend, profile *sandbox.Profile) Backend {
+	if profile == nil || CapabilitiesForBackend(backend).Embedded {
+		return backend
+	}
+	return &sandboxedBackend{backend: backend, profile: profile}
+}
+
+// hostPaths returns the host paths an operation on p reaches through backend.
+// Jails join p to their directory, overlays read both layers and write the
+// upper one, and mounts defer to the mounted backend. Memory and embedded
+// backends reach no host path, so the profile has nothing to check. Backends
+// this package does not know are assumed to take host paths, like OSBackend.
+func hostPaths(backend Backend, p string, write bool) []string {
+	switch b := backend.(type) {
+	case *JailedOSBackend:
+		return []string{filepath.Join(b.dir, filepath.FromSlash(jailPath(p)))}
+	case *OverlayBackend:
+		if write {
+			return hostPaths(b.upper, p, true)
+		}
+		return append(hostPaths(b.lower, p, false), hostPaths(b.upper, p, false)...)
+	case *MountBackend:
+		inner, rel, ok := b.resolve(p)
+		if !ok {
+			return nil
+		}
+		return hostPaths(inner, rel, write)
+	case *MemoryBackend, *ReadOnlyFSBackend:
+		return nil
+	default:
+		return []string{p}
+	}
+}
+
+func (b *sandboxedBackend) FSCapabilities() Capabilities {
+	return CapabilitiesForBackend(b.backend)
+}
+
+func (b *sandboxedBackend) read(path, syscall string) error {
+	return b.check(path, syscall, false, b.profile.CheckRead)
+}
+
+func (b *sandboxedBackend) write(path, syscall string) error {
+	return b.check(path, syscall, true, b.profile.CheckWrite)
+}
+
+// check reports a denial against the script's path, so a jail does not reveal
+// the host directory behind it.
+func (b *sandboxedBackend) check(path, syscall string, write bool, allowed func(string) error) error {
+	for _, host := range hostPaths(b.backend, path, write) {
+		err := allowed(host)
+		var denied *sandbox.PermissionError
+		if errors.As(err, &denied) {
+			err = &sandbox.PermissionError{Profile: denied.Profile, Capability: denied.Capability, Resource: path}
+		}
+		if err != nil {
+			return wrapFSError(err, path, syscall)
+		}
+	}
+	return nil
+}
+
+func (b *sandboxedBackend) ReadFile(path string) ([]byte, error) {
+	if err := b.read(path, "open"); err != nil {
+		return nil, err
+	}
+	return b.backend.ReadFile(path)
+}
+
+func (b *sandboxedBackend) WriteFile(path string, data []byte, mode os.FileMode) error {
+	if err := b.write(path, "open"); err != nil {
+		return err
+	}
+	return b.backend.WriteFile(path, data, mode)
+}
+
+func (b *sandboxedBackend) Exists(path string) bool {
+	return b.read(path, "access") == nil && b.backend.Exists(path)
+}
+
+func (b *sandboxedBackend) Mkdir(path string, recursive bool, mode os.FileMode) error {
+	if err := b.write(path, "mkdir"); err != nil {
+		return err
+	}
+	return b.backend.Mkdir(path, recursive, mode)
+}
+
+func (b *sandboxedBackend) ReadDir(path string) ([]string, error) {
+	if err := b.read(path, "scandir"); err != nil {
+		return nil, err
+	}
+	return b.backend.ReadDir(path)
+}
+
+func (b *sandboxedBackend) Stat(path string) (fileStats, error) {
+	if err := b.read(path, "stat"); err != nil {
+		return nil, err
+	}
+	return b.backend.Stat(path)
+}
+
+func (b *sandboxedBackend) Remove(path string) error {
+	if err := b.write(path, "unlink"); err != nil {
+		return err
+	}
+	return b.backend.Remove(path)
+}
+
+func (b *sandboxedBackend) AppendFile(path string, data []byte, mode os.FileMode) error {
+	if err := b.write(path, "open"); err != nil {
+		return err
+	}
+	return b.backend.AppendFile(path, data, mode)
+}
+
+func (b *sandboxedBackend) Rename(oldPath, newPath string) error {
+	if err := b.write(oldPath, "rename"); err != nil {
+		return err
+	}
+	if err := b.write(newPath, "rename"); err != nil {
+		return err
+	}
+	return b.backend.Rename(oldPath, newPath)
+}
+
+func (b *sandboxedBackend) CopyFile(src, dst string) error {
+	if err := b.read(src, "copyfile"); err != nil {
+		return err
+	}
+	if err := b.write(dst, "copyfile"); err != nil {
+		return err
+	}
+	return b.backend.CopyFile(src, dst)
+}
+
+func (b *sandboxedBackend) RemoveAll(path string) error {
+	if err := b.write(path, "rm"); err != nil {
+		return err
+	}
+	return b.backend.RemoveAll(path)
+}
+
+func (b *sandboxedBackend) OpenFile(path string, flag int, mode os.FileMode) (File, error) {
+	if err := b.read(path, "open"); err != nil {
+		return nil, err
+	}
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		if err := b.write(path, "open"); err != nil {
+			return nil, err
+		}
+	}
+	return openFile(b.backend, path, flag, mode)
+}
+
+func (b *sandboxedBackend) Symlink(target, path string) error {
+	if err := b.write(path, "symlink"); err != nil {
+		return err
+	}
+	return symlinkPath(b.backend, target, path)
+}
+
+func (b *sandboxedBackend) Readlink(path string) (string, error) {
+	if err := b.read(path, "readlink"); err != nil {
+		return "", err
+	}
+	return readlinkPath(b.backend, path)
+}
+
+func (b *sandboxedBackend) Lstat(path string) (fileStats, error) {
+	if err := b.read(path, "lstat"); err != nil {
+		return nil, err
+	}
+	return lstatPath(b.backend, path)
+}
+
+// Realpath checks the resolved path as well, so a symlink cannot reveal where
+// an ungranted path leads.
+func (b *sandboxedBackend) Realpath(path string) (string, error) {
+	if err := b.read(path, "realpath"); err != nil {
+		return "", err
+	}
+	resolved, err := realpathPath(b.backend, path)
+	if err != nil {
+		return "", err
+	}
+	if err := b.read(resolved, "realpath"); err != nil {
+		return "", err
+	}
+	return resolved, nil
+}
+
+func (b *sandboxedBackend) Chmod(path string, mode os.FileMode) error {
+	if err := b.write(path, "chmod"); err != nil {
+		return err
+	}
+	return chmodPath(b.backend, path, mode)
+}
+
+func (b *sandboxedBackend) Chtimes(path string, atime, mtime time.Time) error {
+	if err := b.write(path, "utime"); err != nil {
+		return err
+	}
+	return chtimesPath(b.backend, path, atime, mtime)
+}
+
+func (b *sandboxedBackend) Watch(ctx context.Context, path string, opts fswatch.Options, handler fswatch.Handler) (*fswatch.Watch, error) {
+	if err := b.read(path, "watch"); err != nil {
+		return nil, err
+	}
+	return watchPath(ctx, b.backend, path, opts, handler)
+}
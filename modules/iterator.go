@@ -0,0 +1,25 @@
+package modules
+
+import "github.com/dop251/goja"
+
+// SetAsyncIterator makes obj its own async iterator so for await can loop
+// over it. goja has no Symbol.asyncIterator, and esbuild lowers for await
+// (ES modules are compiled for ES2017) to look it up under
+// Symbol.for("Symbol.asyncIterator") when the runtime lacks it, so obj gets
+// both keys.
+func SetAsyncIterator(vm *goja.Runtime, obj *goja.Object) {
+	self := func(call goja.FunctionCall) goja.Value { return call.This }
+	symbol := vm.Get("Symbol").ToObject(vm)
+	if sym, ok := symbol.Get("asyncIterator").(*goja.Symbol); ok {
+		_ = obj.SetSymbol(sym, self)
+	}
+	symbolFor, ok := goja.AssertFunction(symbol.Get("for"))
+	if !ok {
+		return
+	}
+	if sym, err := symbolFor(symbol, vm.ToValue("Symbol.asyncIterator")); err == nil {
+		if sym, ok := sym.(*goja.Symbol); ok {
+			_ = obj.SetSymbol(sym, self)
+		}
+	}
+}
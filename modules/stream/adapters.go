@@ -0,0 +1,152 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/dop251/goja"
+)
+
+// maxReadChunk caps how much a Go-backed Readable reads per _read call.
+const maxReadChunk = 64 * 1024
+
+// Options configures a stream created from Go with NewReadable or
+// NewWritable.
+type Options struct {
+	// HighWaterMark is the buffer size in bytes at which the stream applies
+	// back-pressure. Zero uses the 16 KiB default.
+	HighWaterMark int
+	// Encoding makes a Readable emit strings decoded with this encoding
+	// instead of Buffers.
+	Encoding string
+}
+
+func (o Options) highWaterMark() int {
+	if o.HighWaterMark > 0 {
+		return o.HighWaterMark
+	}
+	return -1
+}
+
+// NewReadable returns a Readable whose data comes from r. Reads run on a
+// background goroutine and only while the stream's buffer is below its
+// high-water mark, so a slow consumer stops reading from r. Reading starts
+// immediately. r is closed, if it is an io.Closer, when the stream ends, is
+// destroyed, or the runtime shuts down. It must be called on the owner
+// goroutine.
+func NewReadable(vm *goja.Runtime, r io.Reader, opts Options) *goja.Object {
+	c := classesFor(vm)
+	s := newState(vm, c.Readable.Get("prototype").(*goja.Object))
+	s.initReadable(nil, false, opts.highWaterMark())
+	if opts.Encoding != "" {
+		s.setEncoding(opts.Encoding)
+	}
+	closeReader := closeOnce(r)
+	s.release = func() { _ = closeReader() }
+	stop := context.AfterFunc(s.services.Lifetime(), s.release)
+	s.r.source = func(size int) {
+		buf := make([]byte, min(max(size, 1), maxReadChunk))
+		go func() {
+			n, err := r.Read(buf)
+			s.nextTick("stream.read", func() {
+				if s.destroyed {
+					return
+				}
+				if n > 0 {
+					s.pushBytes(buf[:n])
+				}
+				switch {
+				case errors.Is(err, io.EOF):
+					stop()
+					_ = closeReader()
+					s.pushEOF()
+				case err != nil:
+					stop()
+					s.fail(err)
+				case n == 0:
+					s.r.reading = false
+					s.scheduleFlow()
+				}
+			})
+		}()
+	}
+	s.scheduleFlow()
+	return s.obj
+}
+
+// NewWritable returns a Writable that writes each chunk to w on a background
+// goroutine, one chunk at a time, so a slow w produces back-pressure instead
+// of blocking the runtime. end() closes w if it is an io.Closer; destroy() and
+// runtime shutdown close it as well. It must be called on the owner goroutine.
+func NewWritable(vm *goja.Runtime, w io.Writer, opts Options) *goja.Object {
+	c := classesFor(vm)
+	s := newState(vm, c.Writable.Get("prototype").(*goja.Object))
+	s.initWritable(nil, false, opts.highWaterMark())
+	closeWriter := closeOnce(w)
+	s.release = func() { _ = closeWriter() }
+	stop := context.AfterFunc(s.services.Lifetime(), s.release)
+	s.w.sink = func(req *writeRequest, done func(goja.Value)) {
+		data := req.data
+		if data == nil {
+			data = []byte(req.value.String())
+		}
+		go func() {
+			_, err := w.Write(data)
+			s.nextTick("stream.write", func() { done(errorValue(vm, err)) })
+		}()
+	}
+	s.w.final = func(done func(goja.Value)) {
+		go func() {
+			stop()
+			err := closeWriter()
+			s.nextTick("stream.final", func() { done(errorValue(vm, err)) })
+		}()
+	}
+	return s.obj
+}
+
+// NewReader returns an io.ReadCloser over the data of the Readable value, for
+// Go code that consumes a JavaScript stream such as an upload body. The
+// Readable is piped into an io.Pipe, so it is only read as fast as the Go side
+// drains the reader. A stream error, or a close before the stream ended,
+// becomes the reader's error; closing the reader destroys the stream. ok is
+// false when value is not a Readable. It must be called on the owner
+// goroutine.
+func NewReader(vm *goja.Runtime, value goja.Value) (io.ReadCloser, bool) {
+	s, ok := stateOf(value)
+	if !ok || s.r == nil {
+		return nil, false
+	}
+	pr, pw := io.Pipe()
+	sink := NewWritable(vm, pw, Options{})
+	sinkState, _ := stateOf(sink)
+	_ = sinkState.emitter.AddGoListener("error", func(goja.FunctionCall) goja.Value {
+		s.destroy(nil)
+		return goja.Undefined()
+	})
+	_ = s.emitter.AddGoListener("error", func(call goja.FunctionCall) goja.Value {
+		_ = pw.CloseWithError(fmt.Errorf("%s", call.Argument(0).String()))
+		return goja.Undefined()
+	})
+	_ = s.emitter.AddGoListener("close", func(goja.FunctionCall) goja.Value {
+		if !s.r.endEmitted {
+			_ = pw.CloseWithError(&codeError{code: "ERR_STREAM_PREMATURE_CLOSE", message: "Premature close"})
+		}
+		return goja.Undefined()
+	})
+	s.pipeTo(sink, true)
+	return pr, true
+}
+
+// closeOnce returns a function that closes v at most once if it is an
+// io.Closer, reporting the error of the first call.
+func closeOnce(v any) func() error {
+	closer, ok := v.(io.Closer)
+	if !ok {
+		return func() error { return nil }
+	}
+	return sync.OnceValue(closer.Close)
+}
@@ -0,0 +1,330 @@
+package stream
+
+import (
+	"fmt"
+
+	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/modules"
+	"github.com/go-go-golems/go-go-goja/modules/events"
+)
+
+// classes holds the per-runtime stream constructors so require("stream"),
+// require("node:stream") and Go adapters share one set of prototypes.
+type classes struct {
+	Readable    *goja.Object
+	Writable    *goja.Object
+	Duplex      *goja.Object
+	Transform   *goja.Object
+	PassThrough *goja.Object
+}
+
+var classesKey = goja.NewSymbol("go-go-goja.stream.classes")
+
+type kind int
+
+const (
+	kindReadable kind = iota
+	kindWritable
+	kindDuplex
+	kindTransform
+)
+
+var optionMethods = map[kind]map[string]string{
+	kindReadable:  {"read": "_read", "destroy": "_destroy"},
+	kindWritable:  {"write": "_write", "final": "_final", "destroy": "_destroy"},
+	kindDuplex:    {"read": "_read", "write": "_write", "final": "_final", "destroy": "_destroy"},
+	kindTransform: {"transform": "_transform", "flush": "_flush", "final": "_final", "destroy": "_destroy"},
+}
+
+func classesFor(vm *goja.Runtime) *classes {
+	global := vm.GlobalObject()
+	if existing, ok := global.GetSymbol(classesKey).(*goja.Object); ok {
+		if c, ok := existing.Export().(*classes); ok {
+			return c
+		}
+	}
+	emitterProto := events.Constructor(vm).Get("prototype").(*goja.Object)
+
+	c := &classes{}
+	c.Readable = newClass(vm, kindReadable, emitterProto)
+	readableProto := c.Readable.Get("prototype").(*goja.Object)
+	installReadable(vm, readableProto, emitterProto)
+
+	c.Writable = newClass(vm, kindWritable, emitterProto)
+	writableProto := c.Writable.Get("prototype").(*goja.Object)
+	installWritable(vm, writableProto)
+	installCommon(vm, readableProto)
+	installCommon(vm, writableProto)
+
+	c.Duplex = newClass(vm, kindDuplex, readableProto)
+	installWritable(vm, c.Duplex.Get("prototype").(*goja.Object))
+
+	c.Transform = newClass(vm, kindTransform, c.Duplex.Get("prototype").(*goja.Object))
+
+	c.PassThrough = newClass(vm, kindTransform, c.Transform.Get("prototype").(*goja.Object))
+	mustSet(vm, c.PassThrough.Get("prototype").(*goja.Object), "_transform", func(call goja.FunctionCall) goja.Value {
+		callback, ok := goja.AssertFunction(call.Argument(2))
+		if ok {
+			_, _ = callback(goja.Undefined(), goja.Null(), call.Argument(0))
+		}
+		return goja.Undefined()
+	})
+
+	mustSet(vm, c.Readable, "from", func(call goja.FunctionCall) goja.Value {
+		return readableFrom(vm, c, call.Argument(0), call.Argument(1))
+	})
+
+	if err := global.DefineDataPropertySymbol(classesKey, vm.ToValue(c), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("stream: cache classes: %w", err)))
+	}
+	return c
+}
+
+func newClass(vm *goja.Runtime, k kind, parentProto *goja.Object) *goja.Object {
+	constructor := vm.ToValue(func(call goja.ConstructorCall) *goja.Object {
+		s := newState(vm, call.This.Prototype())
+		opts, _ := call.Argument(0).(*goja.Object)
+		construct(s, k, opts)
+		return s.obj
+	}).(*goja.Object)
+	proto := vm.NewObject()
+	if err := proto.SetPrototype(parentProto); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("stream: set class prototype: %w", err)))
+	}
+	if err := proto.DefineDataProperty("constructor", constructor, goja.FLAG_TRUE, goja.FLAG_FALSE, goja.FLAG_TRUE); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("stream: define constructor: %w", err)))
+	}
+	mustSet(vm, constructor, "prototype", proto)
+	return constructor
+}
+
+func construct(s *state, k kind, opts *goja.Object) {
+	switch k {
+	case kindReadable:
+		s.initReadable(opts, optionBool(opts, "objectMode", false), optionInt(opts, "highWaterMark", -1))
+	case kindWritable:
+		s.initWritable(opts, optionBool(opts, "objectMode", false), optionInt(opts, "highWaterMark", -1))
+	case kindDuplex:
+		s.initDuplex(opts)
+	case kindTransform:
+		s.initTransform(opts)
+	}
+	s.adoptMethods(opts, optionMethods[k])
+}
+
+func installCommon(vm *goja.Runtime, proto *goja.Object) {
+	mustSet(vm, proto, "destroy", func(call goja.FunctionCall) goja.Value {
+		s := mustState(vm, call.This)
+		err := call.Argument(0)
+		if !modules.Present(err) {
+			err = nil
+		}
+		s.destroy(err)
+		return call.This
+	})
+	accessor(vm, proto, "destroyed", func(s *state) any { return s.destroyed })
+	accessor(vm, proto, "closed", func(s *state) any { return s.closeEmitted })
+	accessor(vm, proto, "errored", func(s *state) any {
+		if s.errored == nil {
+			return goja.Null()
+		}
+		return s.errored
+	})
+}
+
+func installReadable(vm *goja.Runtime, proto, emitterProto *goja.Object) {
+	addListener, _ := goja.AssertFunction(emitterProto.Get("on"))
+	addOnce, _ := goja.AssertFunction(emitterProto.Get("once"))
+	// Attaching a "data" listener switches the stream into flowing mode
+	// unless it was paused explicitly; "readable" listeners start reads.
+	listen := func(add goja.Callable) func(goja.FunctionCall) goja.Value {
+		return func(call goja.FunctionCall) goja.Value {
+			ret, err := add(call.This, call.Arguments...)
+			if err != nil {
+				panic(errorValue(vm, err))
+			}
+			if s, ok := stateOf(call.This); ok && s.r != nil {
+				switch call.Argument(0).String() {
+				case "data":
+					if s.r.flowing != flowingOff {
+						s.resume()
+					}
+				case "readable":
+					s.scheduleFlow()
+				}
+			}
+			return ret
+		}
+	}
+	mustSet(vm, proto, "on", listen(addListener))
+	mustSet(vm, proto, "addListener", listen(addListener))
+	mustSet(vm, proto, "once", listen(addOnce))
+
+	mustSet(vm, proto, "push", func(call goja.FunctionCall) goja.Value {
+		return vm.ToValue(mustReadable(vm, call.This).push(call.Argument(0), call.Argument(1)))
+	})
+	mustSet(vm, proto, "unshift", func(call goja.FunctionCall) goja.Value {
+		s := mustReadable(vm, call.This)
+		value := call.Argument(0)
+		if !modules.Present(value) {
+			return goja.Undefined()
+		}
+		if s.r.objectMode {
+			s.appendChunk(chunk{value: value, size: 1}, true)
+		} else {
+			data := bytesOf(vm, value, call.Argument(1))
+			s.appendChunk(chunk{data: data, size: len(data)}, true)
+		}
+		return goja.Undefined()
+	})
+	mustSet(vm, proto, "read", func(call goja.FunctionCall) goja.Value {
+		s := mustReadable(vm, call.This)
+		n := -1
+		if arg := call.Argument(0); modules.Present(arg) {
+			n = int(arg.ToInteger())
+		}
+		return s.read(n)
+	})
+	mustSet(vm, proto, "pause", func(call goja.FunctionCall) goja.Value {
+		mustReadable(vm, call.This).pause()
+		return call.This
+	})
+	mustSet(vm, proto, "resume", func(call goja.FunctionCall) goja.Value {
+		mustReadable(vm, call.This).resume()
+		return call.This
+	})
+	mustSet(vm, proto, "isPaused", func(call goja.FunctionCall) goja.Value {
+		return vm.ToValue(mustReadable(vm, call.This).r.flowing == flowingOff)
+	})
+	mustSet(vm, proto, "setEncoding", func(call goja.FunctionCall) goja.Value {
+		mustReadable(vm, call.This).setEncoding(call.Argument(0).String())
+		return call.This
+	})
+	mustSet(vm, proto, "pipe", func(call goja.FunctionCall) goja.Value {
+		s := mustReadable(vm, call.This)
+		dest, ok := call.Argument(0).(*goja.Object)
+		if !ok {
+			panic(vm.NewTypeError("pipe destination must be a stream"))
+		}
+		end := true
+		if opts, ok := call.Argument(1).(*goja.Object); ok {
+			end = optionBool(opts, "end", true)
+		}
+		s.pipeTo(dest, end)
+		return dest
+	})
+	mustSet(vm, proto, "unpipe", func(call goja.FunctionCall) goja.Value {
+		mustReadable(vm, call.This).unpipe(call.Argument(0))
+		return call.This
+	})
+	accessor(vm, proto, "readable", func(s *state) any {
+		return s.r != nil && !s.destroyed && !s.r.endEmitted
+	})
+	accessor(vm, proto, "readableEnded", func(s *state) any { return s.r != nil && s.r.endEmitted })
+	accessor(vm, proto, "readableLength", func(s *state) any { return readableField(s, func(r *readableState) any { return r.length }) })
+	accessor(vm, proto, "readableHighWaterMark", func(s *state) any {
+		return readableField(s, func(r *readableState) any { return r.highWaterMark })
+	})
+	accessor(vm, proto, "readableObjectMode", func(s *state) any {
+		return readableField(s, func(r *readableState) any { return r.objectMode })
+	})
+	accessor(vm, proto, "readableFlowing", func(s *state) any {
+		return readableField(s, func(r *readableState) any {
+			switch r.flowing {
+			case flowingOn:
+				return true
+			case flowingOff:
+				return false
+			}
+			return goja.Null()
+		})
+	})
+	accessor(vm, proto, "readableEncoding", func(s *state) any {
+		return readableField(s, func(r *readableState) any {
+			if r.decoder == nil {
+				return goja.Null()
+			}
+			return r.decoder.name
+		})
+	})
+}
+
+func readableField(s *state, get func(*readableState) any) any {
+	if s.r == nil {
+		return goja.Undefined()
+	}
+	return get(s.r)
+}
+
+func writableField(s *state, get func(*writableState) any) any {
+	if s.w == nil {
+		return goja.Undefined()
+	}
+	return get(s.w)
+}
+
+func installWritable(vm *goja.Runtime, proto *goja.Object) {
+	mustSet(vm, proto, "write", func(call goja.FunctionCall) goja.Value {
+		s := mustWritable(vm, call.This)
+		value, encoding, callback := writeArgs(call)
+		return vm.ToValue(s.write(value, encoding, callback))
+	})
+	mustSet(vm, proto, "end", func(call goja.FunctionCall) goja.Value {
+		s := mustWritable(vm, call.This)
+		value, encoding, callback := writeArgs(call)
+		if modules.Present(value) {
+			s.write(value, encoding, nil)
+		}
+		s.end(callback)
+		return call.This
+	})
+	mustSet(vm, proto, "cork", func(call goja.FunctionCall) goja.Value {
+		mustWritable(vm, call.This).w.corked++
+		return goja.Undefined()
+	})
+	mustSet(vm, proto, "uncork", func(call goja.FunctionCall) goja.Value {
+		mustWritable(vm, call.This).uncork()
+		return goja.Undefined()
+	})
+	mustSet(vm, proto, "setDefaultEncoding", func(call goja.FunctionCall) goja.Value {
+		mustWritable(vm, call.This).w.defaultEncoding = call.Argument(0).String()
+		return call.This
+	})
+	accessor(vm, proto, "writable", func(s *state) any {
+		return s.w != nil && !s.destroyed && !s.w.ending
+	})
+	accessor(vm, proto, "writableEnded", func(s *state) any { return s.w != nil && s.w.ending })
+	accessor(vm, proto, "writableFinished", func(s *state) any { return s.w != nil && s.w.finished })
+	accessor(vm, proto, "writableLength", func(s *state) any { return writableField(s, func(w *writableState) any { return w.length }) })
+	accessor(vm, proto, "writableHighWaterMark", func(s *state) any {
+		return writableField(s, func(w *writableState) any { return w.highWaterMark })
+	})
+	accessor(vm, proto, "writableObjectMode", func(s *state) any {
+		return writableField(s, func(w *writableState) any { return w.objectMode })
+	})
+	accessor(vm, proto, "writableNeedDrain", func(s *state) any {
+		return writableField(s, func(w *writableState) any { return w.needDrain })
+	})
+	accessor(vm, proto, "writableCorked", func(s *state) any {
+		return writableField(s, func(w *writableState) any { return w.corked })
+	})
+}
+
+func accessor(vm *goja.Runtime, proto *goja.Object, name string, get func(*state) any) {
+	getter := vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		s, ok := stateOf(call.This)
+		if !ok {
+			return goja.Undefined()
+		}
+		return vm.ToValue(get(s))
+	})
+	if err := proto.DefineAccessorProperty(name, getter, nil, goja.FLAG_TRUE, goja.FLAG_FALSE); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("stream: define %s: %w", name, err)))
+	}
+}
+
+func mustSet(vm *goja.Runtime, obj *goja.Object, name string, value any) {
+	if err := obj.Set(name, value); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("stream: set %s: %w", name, err)))
+	}
+}
@@ -0,0 +1,219 @@
+package stream
+
+import (
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/buffer"
+	"github.com/go-go-golems/go-go-goja/modules"
+)
+
+// finished calls done once stream has ended (readable), finished (writable)
+// or both (duplex), or with the error that stopped it. A stream that closes
+// before that reports ERR_STREAM_PREMATURE_CLOSE.
+func finished(vm *goja.Runtime, value goja.Value, done func(goja.Value)) {
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		panic(vm.NewTypeError("finished() expects a stream"))
+	}
+	s, native := stateOf(obj)
+	called := false
+	once := func(err goja.Value) {
+		if !called {
+			called = true
+			done(err)
+		}
+	}
+	readable := native && s.r != nil || !native && modules.Present(obj.Get("read"))
+	writable := native && s.w != nil || !native && modules.Present(obj.Get("write"))
+	ended, finishedWriting := !readable, !writable
+	if native {
+		if s.errored != nil {
+			s.nextTick("stream.finished", func() { once(s.errored) })
+			return
+		}
+		ended = ended || s.r.endEmitted
+		finishedWriting = finishedWriting || s.w.finished
+		if ended && finishedWriting {
+			s.nextTick("stream.finished", func() { once(nil) })
+			return
+		}
+	}
+	check := func() {
+		if ended && finishedWriting {
+			once(nil)
+		}
+	}
+	on := func(name string, fn func(goja.FunctionCall)) {
+		callMethod(vm, obj, "on", vm.ToValue(name), vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			fn(call)
+			return goja.Undefined()
+		}))
+	}
+	on("end", func(goja.FunctionCall) { ended = true; check() })
+	on("finish", func(goja.FunctionCall) { finishedWriting = true; check() })
+	on("error", func(call goja.FunctionCall) { once(call.Argument(0)) })
+	on("close", func(goja.FunctionCall) {
+		if !ended || !finishedWriting {
+			once(errorValue(vm, &codeError{code: "ERR_STREAM_PREMATURE_CLOSE", message: "Premature close"}))
+		}
+	})
+}
+
+// pipeline pipes streams into each other, destroys all of them when one
+// fails, and calls done with the first error or once the last stream is done.
+func pipeline(vm *goja.Runtime, streams []goja.Value, done func(goja.Value)) goja.Value {
+	if len(streams) < 2 {
+		panic(vm.NewTypeError("pipeline() requires at least two streams"))
+	}
+	objects := make([]*goja.Object, len(streams))
+	for i, value := range streams {
+		obj, ok := value.(*goja.Object)
+		if !ok {
+			panic(vm.NewTypeError("pipeline() arguments must be streams"))
+		}
+		objects[i] = obj
+	}
+	settled := false
+	settle := func(err goja.Value) {
+		if settled {
+			return
+		}
+		settled = true
+		if modules.Present(err) {
+			for _, obj := range objects {
+				if s, ok := stateOf(obj); ok {
+					s.destroy(err)
+				} else {
+					callMethod(vm, obj, "destroy", err)
+				}
+			}
+		}
+		done(err)
+	}
+	for i, obj := range objects {
+		if i < len(objects)-1 {
+			// Errors from streams before the last one arrive via "error";
+			// premature closes on them are reported by the last stream.
+			callMethod(vm, obj, "on", vm.ToValue("error"), vm.ToValue(func(call goja.FunctionCall) goja.Value {
+				settle(call.Argument(0))
+				return goja.Undefined()
+			}))
+			callMethod(vm, obj, "pipe", objects[i+1])
+		}
+	}
+	finished(vm, objects[len(objects)-1], settle)
+	return objects[len(objects)-1]
+}
+
+// splitCallback separates a trailing callback from pipeline-style arguments
+// and flattens a single array argument.
+func splitCallback(args []goja.Value) ([]goja.Value, goja.Callable) {
+	var callback goja.Callable
+	if len(args) > 0 {
+		if fn, ok := goja.AssertFunction(args[len(args)-1]); ok {
+			callback = fn
+			args = args[:len(args)-1]
+		}
+	}
+	if len(args) == 1 {
+		if obj, ok := args[0].(*goja.Object); ok && obj.ClassName() == "Array" {
+			var values []goja.Value
+			for _, key := range obj.Keys() {
+				values = append(values, obj.Get(key))
+			}
+			args = values
+		}
+	}
+	return args, callback
+}
+
+// readableFrom implements Readable.from for arrays, iterables, strings and
+// Buffers. Iterated values that are promises are awaited before being pushed.
+func readableFrom(vm *goja.Runtime, c *classes, source, optsValue goja.Value) goja.Value {
+	opts, _ := optsValue.(*goja.Object)
+	s := newState(vm, c.Readable.Get("prototype").(*goja.Object))
+	s.initReadable(opts, optionBool(opts, "objectMode", true), optionInt(opts, "highWaterMark", -1))
+
+	if _, isString := source.Export().(string); isString || isBytes(source) {
+		sent := false
+		s.r.source = func(int) {
+			if !sent {
+				sent = true
+				s.push(source, goja.Undefined())
+			}
+			s.pushEOF()
+		}
+		return s.obj
+	}
+	obj, ok := source.(*goja.Object)
+	if !ok {
+		panic(vm.NewTypeError("Readable.from() expects an iterable"))
+	}
+	iterFn, ok := goja.AssertFunction(obj.GetSymbol(goja.SymIterator))
+	if !ok {
+		panic(vm.NewTypeError("Readable.from() expects an iterable"))
+	}
+	iterValue, err := iterFn(obj)
+	if err != nil {
+		panic(errorValue(vm, err))
+	}
+	iterator := iterValue.ToObject(vm)
+	next, ok := goja.AssertFunction(iterator.Get("next"))
+	if !ok {
+		panic(vm.NewTypeError("Readable.from() iterator has no next()"))
+	}
+	s.r.source = func(int) {
+		result, err := next(iterator)
+		if err != nil {
+			s.destroy(errorValue(vm, err))
+			return
+		}
+		res := result.ToObject(vm)
+		if res.Get("done").ToBoolean() {
+			s.pushEOF()
+			return
+		}
+		value := res.Get("value")
+		if then, ok := thenable(value); ok {
+			onValue := vm.ToValue(func(call goja.FunctionCall) goja.Value {
+				s.push(call.Argument(0), goja.Undefined())
+				return goja.Undefined()
+			})
+			onError := vm.ToValue(func(call goja.FunctionCall) goja.Value {
+				s.destroy(call.Argument(0))
+				return goja.Undefined()
+			})
+			if _, err := then(value, onValue, onError); err != nil {
+				s.destroy(errorValue(vm, err))
+			}
+			return
+		}
+		s.push(value, goja.Undefined())
+	}
+	s.release = func() {
+		if ret, ok := goja.AssertFunction(iterator.Get("return")); ok {
+			_, _ = ret(iterator)
+		}
+	}
+	return s.obj
+}
+
+func thenable(value goja.Value) (goja.Callable, bool) {
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return nil, false
+	}
+	return goja.AssertFunction(obj.Get("then"))
+}
+
+func isBytes(value goja.Value) bool {
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return false
+	}
+	_, ok = obj.Export().([]byte)
+	return ok
+}
+
+func bytesOf(vm *goja.Runtime, value, encoding goja.Value) []byte {
+	return buffer.DecodeBytes(vm, value, encoding)
+}
@@ -0,0 +1,436 @@
+package stream
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/buffer"
+	"github.com/go-go-golems/go-go-goja/modules"
+)
+
+// Flowing modes, mirroring readable.readableFlowing: null until a consumer
+// attaches, then true or false.
+const (
+	flowingUnset = iota
+	flowingOn
+	flowingOff
+)
+
+type readableState struct {
+	objectMode    bool
+	highWaterMark int
+	decoder       *decoder
+
+	buffer []chunk
+	length int
+
+	flowing       int
+	ended         bool
+	endEmitted    bool
+	reading       bool
+	pushes        int
+	flowScheduled bool
+	pipes         []*pipe
+
+	// source replaces this._read for streams fed from Go.
+	source func(size int)
+}
+
+// chunk is one buffered piece of data. Byte streams keep raw bytes (or a
+// decoded string once setEncoding is used); object streams keep the value.
+type chunk struct {
+	data  []byte
+	value goja.Value
+	size  int
+}
+
+func (s *state) initReadable(opts *goja.Object, objectMode bool, highWaterMark int) {
+	r := &readableState{objectMode: objectMode, highWaterMark: highWaterMark}
+	if r.highWaterMark < 0 {
+		r.highWaterMark = defaultHighWaterMark
+		if objectMode {
+			r.highWaterMark = defaultObjectHighWaterMark
+		}
+	}
+	s.r = r
+	if opts != nil {
+		if enc := opts.Get("encoding"); modules.Present(enc) {
+			s.setEncoding(enc.String())
+		}
+	}
+}
+
+func (s *state) chunkValue(c chunk) goja.Value {
+	if c.value != nil {
+		return c.value
+	}
+	return buffer.WrapBytes(s.vm, c.data)
+}
+
+// push adds value to the read buffer; null ends the stream. It reports whether
+// the producer may keep pushing before the buffer reaches its high-water mark.
+func (s *state) push(value, encoding goja.Value) bool {
+	r := s.r
+	if s.destroyed {
+		return false
+	}
+	if r.ended {
+		s.fail(&codeError{code: "ERR_STREAM_PUSH_AFTER_EOF", message: "stream.push() after EOF"})
+		return false
+	}
+	r.reading = false
+	if value == nil || goja.IsNull(value) {
+		s.pushEOF()
+		return false
+	}
+	if r.objectMode {
+		s.appendChunk(chunk{value: value, size: 1}, false)
+	} else {
+		s.pushBytes(buffer.DecodeBytes(s.vm, value, encoding))
+	}
+	return r.length < r.highWaterMark
+}
+
+func (s *state) pushBytes(data []byte) {
+	r := s.r
+	r.reading = false
+	if r.decoder != nil {
+		if text := r.decoder.write(data); text != "" {
+			s.appendChunk(chunk{value: s.vm.ToValue(text), size: len(text)}, false)
+		}
+		return
+	}
+	if len(data) > 0 {
+		s.appendChunk(chunk{data: data, size: len(data)}, false)
+	}
+}
+
+func (s *state) pushEOF() {
+	r := s.r
+	r.reading = false
+	if r.ended {
+		return
+	}
+	if r.decoder != nil {
+		if text := r.decoder.end(); text != "" {
+			s.appendChunk(chunk{value: s.vm.ToValue(text), size: len(text)}, false)
+		}
+	}
+	r.ended = true
+	s.scheduleFlow()
+}
+
+func (s *state) appendChunk(c chunk, front bool) {
+	r := s.r
+	if front {
+		r.buffer = append([]chunk{c}, r.buffer...)
+	} else {
+		r.buffer = append(r.buffer, c)
+		r.pushes++
+	}
+	r.length += c.size
+	s.scheduleFlow()
+}
+
+func (s *state) shift() chunk {
+	r := s.r
+	c := r.buffer[0]
+	r.buffer[0] = chunk{}
+	r.buffer = r.buffer[1:]
+	r.length -= c.size
+	return c
+}
+
+func (s *state) scheduleFlow() {
+	if s.r.flowScheduled || s.destroyed {
+		return
+	}
+	s.r.flowScheduled = true
+	s.nextTick("stream.flow", s.flow)
+}
+
+// flow delivers buffered chunks to "data" listeners while flowing, refills the
+// buffer through _read up to the high-water mark, and emits "end" once the
+// buffer drains after EOF.
+func (s *state) flow() {
+	r := s.r
+	r.flowScheduled = false
+	for !s.destroyed {
+		for r.flowing == flowingOn && len(r.buffer) > 0 && !s.destroyed {
+			s.emit("data", s.chunkValue(s.shift()))
+		}
+		if s.destroyed {
+			return
+		}
+		if r.ended {
+			if len(r.buffer) == 0 {
+				s.endReadable()
+			} else if r.flowing != flowingOn {
+				s.emitReadable()
+			}
+			return
+		}
+		if r.reading || r.length >= r.highWaterMark {
+			break
+		}
+		r.reading = true
+		before := r.pushes
+		s.callRead()
+		if r.pushes == before && !r.ended {
+			break
+		}
+	}
+	if r.flowing != flowingOn && len(r.buffer) > 0 {
+		s.emitReadable()
+	}
+}
+
+func (s *state) emitReadable() {
+	if s.emitter.ListenerCount("readable") > 0 {
+		s.emit("readable")
+	}
+}
+
+func (s *state) callRead() {
+	r := s.r
+	if r.source != nil {
+		r.source(r.highWaterMark)
+		return
+	}
+	impl, ok := s.method("_read")
+	if !ok {
+		s.fail(&codeError{code: "ERR_METHOD_NOT_IMPLEMENTED", message: "The _read() method is not implemented"})
+		return
+	}
+	if _, err := impl(s.obj, s.vm.ToValue(r.highWaterMark)); err != nil {
+		s.destroy(errorValue(s.vm, err))
+	}
+}
+
+func (s *state) endReadable() {
+	r := s.r
+	if r.endEmitted {
+		return
+	}
+	r.endEmitted = true
+	s.emit("end")
+	s.maybeAutoDestroy()
+}
+
+// read returns up to n bytes (all buffered data when n < 0), one object in
+// object mode, or null when not enough data is buffered yet.
+func (s *state) read(n int) goja.Value {
+	r := s.r
+	defer s.scheduleFlow()
+	if len(r.buffer) == 0 {
+		return goja.Null()
+	}
+	if r.objectMode {
+		return s.shift().value
+	}
+	if n > r.length && !r.ended {
+		return goja.Null()
+	}
+	if n < 0 || n >= r.length {
+		n = r.length
+	}
+	if r.decoder != nil {
+		var text strings.Builder
+		for text.Len() < n {
+			c := s.shift()
+			str := c.value.String()
+			if text.Len()+len(str) > n {
+				rest := str[n-text.Len():]
+				str = str[:n-text.Len()]
+				s.appendChunk(chunk{value: s.vm.ToValue(rest), size: len(rest)}, true)
+			}
+			text.WriteString(str)
+		}
+		return s.vm.ToValue(text.String())
+	}
+	var out bytes.Buffer
+	for out.Len() < n {
+		c := s.shift()
+		data := c.data
+		if out.Len()+len(data) > n {
+			rest := data[n-out.Len():]
+			data = data[:n-out.Len()]
+			s.appendChunk(chunk{data: rest, size: len(rest)}, true)
+		}
+		out.Write(data)
+	}
+	return buffer.WrapBytes(s.vm, out.Bytes())
+}
+
+func (s *state) resume() {
+	s.r.flowing = flowingOn
+	s.scheduleFlow()
+}
+
+func (s *state) pause() {
+	s.r.flowing = flowingOff
+}
+
+func (s *state) setEncoding(encoding string) {
+	d, err := newDecoder(s.vm, encoding)
+	if err != nil {
+		panic(s.vm.NewTypeError(err.Error()))
+	}
+	r := s.r
+	r.decoder = d
+	// Re-decode anything already buffered so every chunk has the same type.
+	buffered := r.buffer
+	r.buffer, r.length = nil, 0
+	for _, c := range buffered {
+		if c.data != nil {
+			if text := d.write(c.data); text != "" {
+				r.buffer = append(r.buffer, chunk{value: s.vm.ToValue(text), size: len(text)})
+				r.length += len(text)
+			}
+			continue
+		}
+		r.buffer = append(r.buffer, c)
+		r.length += c.size
+	}
+}
+
+// decoder turns byte chunks into strings without splitting multi-byte
+// characters or base64 groups across chunk boundaries.
+type decoder struct {
+	vm       *goja.Runtime
+	encoding goja.Value
+	name     string
+	pending  []byte
+}
+
+func newDecoder(vm *goja.Runtime, encoding string) (*decoder, error) {
+	name := strings.ToLower(encoding)
+	if name == "utf-8" {
+		name = "utf8"
+	}
+	if buffer.StringCodecByName(name) == nil {
+		return nil, &codeError{code: "ERR_UNKNOWN_ENCODING", message: "Unknown encoding: " + encoding}
+	}
+	return &decoder{vm: vm, encoding: vm.ToValue(name), name: name}, nil
+}
+
+func (d *decoder) write(data []byte) string {
+	data = append(d.pending, data...)
+	cut := len(data)
+	switch d.name {
+	case "utf8":
+		cut = completeUTF8(data)
+	case "base64", "base64url":
+		cut -= len(data) % 3
+	}
+	d.pending = append([]byte(nil), data[cut:]...)
+	if cut == 0 {
+		return ""
+	}
+	return buffer.EncodeBytes(d.vm, data[:cut], d.encoding).String()
+}
+
+func (d *decoder) end() string {
+	if len(d.pending) == 0 {
+		return ""
+	}
+	data := d.pending
+	d.pending = nil
+	return buffer.EncodeBytes(d.vm, data, d.encoding).String()
+}
+
+// completeUTF8 returns the length of the longest prefix of data that does not
+// end in the middle of a UTF-8 sequence.
+func completeUTF8(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if utf8.FullRune(data[i:]) {
+				return len(data)
+			}
+			return i
+		}
+	}
+	return len(data)
+}
+
+// pipe is one readable.pipe(destination) connection.
+type pipe struct {
+	dest    *goja.Object
+	onData  goja.Value
+	onDrain goja.Value
+	onEnd   goja.Value
+}
+
+func (s *state) pipeTo(dest *goja.Object, end bool) {
+	vm := s.vm
+	write, ok := goja.AssertFunction(dest.Get("write"))
+	if !ok {
+		panic(vm.NewTypeError("pipe destination must be writable"))
+	}
+	p := &pipe{dest: dest}
+	p.onData = vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		ret, err := write(dest, call.Argument(0))
+		if err != nil {
+			panic(errorValue(vm, err))
+		}
+		if ret != nil && !ret.ToBoolean() {
+			s.pause()
+		}
+		return goja.Undefined()
+	})
+	p.onDrain = vm.ToValue(func(goja.FunctionCall) goja.Value {
+		if len(s.r.pipes) > 0 {
+			s.resume()
+		}
+		return goja.Undefined()
+	})
+	_ = s.emitter.AddListenerValue("data", p.onData)
+	callMethod(vm, dest, "on", vm.ToValue("drain"), p.onDrain)
+	if end {
+		p.onEnd = vm.ToValue(func(goja.FunctionCall) goja.Value {
+			callMethod(vm, dest, "end")
+			return goja.Undefined()
+		})
+		_ = s.emitter.AddListenerValue("end", p.onEnd)
+	}
+	s.r.pipes = append(s.r.pipes, p)
+	callMethod(vm, dest, "emit", vm.ToValue("pipe"), s.obj)
+	s.resume()
+}
+
+func (s *state) unpipe(dest goja.Value) {
+	vm := s.vm
+	kept := s.r.pipes[:0]
+	for _, p := range s.r.pipes {
+		if modules.Present(dest) && !p.dest.SameAs(dest.ToObject(vm)) {
+			kept = append(kept, p)
+			continue
+		}
+		s.emitter.RemoveListener("data", p.onData)
+		if p.onEnd != nil {
+			s.emitter.RemoveListener("end", p.onEnd)
+		}
+		callMethod(vm, p.dest, "removeListener", vm.ToValue("drain"), p.onDrain)
+		callMethod(vm, p.dest, "emit", vm.ToValue("unpipe"), s.obj)
+	}
+	s.r.pipes = kept
+	if len(kept) == 0 && s.r.flowing == flowingOn {
+		s.pause()
+	}
+}
+
+// callMethod calls obj[name](args...) when it exists and rethrows JavaScript
+// exceptions.
+func callMethod(vm *goja.Runtime, obj *goja.Object, name string, args ...goja.Value) goja.Value {
+	fn, ok := goja.AssertFunction(obj.Get(name))
+	if !ok {
+		return goja.Undefined()
+	}
+	ret, err := fn(obj, args...)
+	if err != nil {
+		panic(errorValue(vm, err))
+	}
+	return ret
+}
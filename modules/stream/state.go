@@ -0,0 +1,251 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/modules"
+	"github.com/go-go-golems/go-go-goja/modules/events"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimebridge"
+)
+
+const (
+	defaultHighWaterMark       = 16 * 1024
+	defaultObjectHighWaterMark = 16
+)
+
+// stateKey links a stream object to its Go state.
+var stateKey = goja.NewSymbol("go-go-goja.stream.state")
+
+// state backs one Readable, Writable, Duplex or Transform object. Readable
+// streams have r set, Writable streams have w set, and Duplex streams have
+// both. It is not goroutine-safe: everything runs on the owner goroutine and
+// background work re-enters through post.
+type state struct {
+	vm       *goja.Runtime
+	obj      *goja.Object
+	emitter  *events.EventEmitter
+	services runtimebridge.RuntimeServices
+
+	r *readableState
+	w *writableState
+	t *transformState
+
+	destroyed    bool
+	closeEmitted bool
+	errored      goja.Value
+	// release frees Go resources held by adapters when the stream is
+	// destroyed.
+	release func()
+}
+
+func newState(vm *goja.Runtime, proto *goja.Object) *state {
+	services, ok := runtimebridge.Lookup(vm)
+	if !ok || services.Owner == nil {
+		panic(vm.NewGoError(fmt.Errorf("stream module requires runtime services")))
+	}
+	emitter, obj := events.NewObject(vm)
+	if err := obj.SetPrototype(proto); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("stream: set prototype: %w", err)))
+	}
+	s := &state{vm: vm, obj: obj, emitter: emitter, services: services}
+	if err := obj.DefineDataPropertySymbol(stateKey, vm.ToValue(s), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE); err != nil {
+		panic(vm.NewGoError(fmt.Errorf("stream: attach state: %w", err)))
+	}
+	return s
+}
+
+// stateOf returns the Go state behind a stream object created by this module.
+func stateOf(value goja.Value) (*state, bool) {
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return nil, false
+	}
+	inner, ok := obj.GetSymbol(stateKey).(*goja.Object)
+	if !ok {
+		return nil, false
+	}
+	s, ok := inner.Export().(*state)
+	return s, ok && s != nil
+}
+
+func mustState(vm *goja.Runtime, value goja.Value) *state {
+	s, ok := stateOf(value)
+	if !ok {
+		panic(vm.NewTypeError("Value of this must be a stream"))
+	}
+	return s
+}
+
+func mustReadable(vm *goja.Runtime, value goja.Value) *state {
+	s := mustState(vm, value)
+	if s.r == nil {
+		panic(vm.NewTypeError("Value of this must be a readable stream"))
+	}
+	return s
+}
+
+func mustWritable(vm *goja.Runtime, value goja.Value) *state {
+	s := mustState(vm, value)
+	if s.w == nil {
+		panic(vm.NewTypeError("Value of this must be a writable stream"))
+	}
+	return s
+}
+
+// nextTick runs fn on the owner goroutine after the current job, the way
+// Node defers stream events with process.nextTick.
+func (s *state) nextTick(op string, fn func()) {
+	err := s.services.PostWithLifetimeContext(op, func(context.Context, *goja.Runtime) {
+		fn()
+	})
+	if err != nil {
+		log.Debug().Err(err).Str("op", op).Msg("stream: dispatch dropped")
+	}
+}
+
+func (s *state) emit(name string, args ...goja.Value) {
+	if _, err := s.emitter.Emit(name, args...); err != nil {
+		log.Warn().Err(err).Str("event", name).Msg("stream: listener error")
+	}
+}
+
+// method returns this[name] when it is a function, so options such as
+// { read() {} } and subclass overrides are both honored.
+func (s *state) method(name string) (goja.Callable, bool) {
+	return goja.AssertFunction(s.obj.Get(name))
+}
+
+// destroy tears the stream down, emitting "error" when err is set and then
+// "close". A _destroy(err, callback) implementation may replace the error.
+func (s *state) destroy(err goja.Value) {
+	if s.destroyed {
+		return
+	}
+	s.destroyed = true
+	if modules.Present(err) {
+		s.errored = err
+	}
+	finish := func(err goja.Value) {
+		if s.release != nil {
+			s.release()
+		}
+		s.nextTick("stream.destroy", func() {
+			if modules.Present(err) {
+				s.errored = err
+				s.emit("error", err)
+			}
+			s.emitClose()
+		})
+	}
+	impl, ok := s.method("_destroy")
+	if !ok {
+		finish(err)
+		return
+	}
+	called := false
+	callback := s.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		if !called {
+			called = true
+			finish(call.Argument(0))
+		}
+		return goja.Undefined()
+	})
+	if err == nil {
+		err = goja.Null()
+	}
+	if _, callErr := impl(s.obj, err, callback); callErr != nil && !called {
+		called = true
+		finish(errorValue(s.vm, callErr))
+	}
+}
+
+func (s *state) emitClose() {
+	if s.closeEmitted {
+		return
+	}
+	s.closeEmitted = true
+	s.emit("close")
+}
+
+// maybeAutoDestroy closes a stream once every side it has is done.
+func (s *state) maybeAutoDestroy() {
+	if s.r != nil && !s.r.endEmitted {
+		return
+	}
+	if s.w != nil && !s.w.finished {
+		return
+	}
+	s.destroy(nil)
+}
+
+// fail destroys the stream with a Go error.
+func (s *state) fail(err error) {
+	s.destroy(errorValue(s.vm, err))
+}
+
+// codeError is a stream error with a Node-style code such as
+// ERR_STREAM_WRITE_AFTER_END.
+type codeError struct {
+	code    string
+	message string
+}
+
+func (e *codeError) Error() string { return e.message }
+
+// errorValue converts err to the JavaScript value to emit or reject with.
+// Exceptions thrown by JavaScript keep their original value.
+func errorValue(vm *goja.Runtime, err error) goja.Value {
+	if err == nil {
+		return nil
+	}
+	var exception *goja.Exception
+	if errors.As(err, &exception) {
+		return exception.Value()
+	}
+	obj := vm.NewGoError(err)
+	var coded *codeError
+	if errors.As(err, &coded) {
+		_ = obj.Set("code", coded.code)
+	}
+	return obj
+}
+
+func optionInt(opts *goja.Object, name string, fallback int) int {
+	if opts == nil {
+		return fallback
+	}
+	if v := opts.Get(name); modules.Present(v) {
+		if n := v.ToInteger(); n >= 0 {
+			return int(n)
+		}
+	}
+	return fallback
+}
+
+func optionBool(opts *goja.Object, name string, fallback bool) bool {
+	if opts == nil {
+		return fallback
+	}
+	if v := opts.Get(name); modules.Present(v) {
+		return v.ToBoolean()
+	}
+	return fallback
+}
+
+// adoptMethods copies implementation functions from the options object onto
+// the stream, as Node does for { read, write, transform, ... }.
+func (s *state) adoptMethods(opts *goja.Object, names map[string]string) {
+	if opts == nil {
+		return
+	}
+	for option, method := range names {
+		if fn := opts.Get(option); modules.Present(fn) {
+			if _, ok := goja.AssertFunction(fn); ok {
+				_ = s.obj.Set(method, fn)
+			}
+		}
+	}
+}
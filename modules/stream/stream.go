@@ -0,0 +1,205 @@
+// Package stream implements Node-style Readable, Writable, Duplex and
+// Transform streams on top of the events module, plus adapters that expose Go
+// io.Reader and io.Writer values as streams with back-pressure.
+package stream
+
+import (
+	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/modules"
+	"github.com/go-go-golems/go-go-goja/pkg/tsgen/spec"
+)
+
+type module struct {
+	name string
+}
+
+var _ modules.NativeModule = (*module)(nil)
+var _ modules.TypeScriptDeclarer = (*module)(nil)
+
+func (m *module) Name() string { return m.name }
+
+func (m *module) Doc() string {
+	return `The stream module provides Node-style streams built on EventEmitter.
+
+Readable streams buffer pushed chunks up to highWaterMark and emit "data" once
+a consumer attaches; push() returns false when the producer should wait for the
+next _read(). Writable streams queue chunks for _write() and return false from
+write() until "drain". Transform streams connect both sides and hold writes
+while their readable side is full. pipe() and pipeline() propagate
+back-pressure; pipeline() also destroys every stream when one fails.
+
+Classes: Readable, Writable, Duplex, Transform, PassThrough, Readable.from.
+Functions: pipeline(...streams, callback?), finished(stream, callback),
+promises.pipeline(...streams), promises.finished(stream).`
+}
+
+func (m *module) TypeScriptModule() *spec.Module {
+	return &spec.Module{
+		Name: m.name,
+		RawDTS: []string{
+			"import { EventEmitter } from \"events\";",
+			"export type Chunk = string | Uint8Array;",
+			"export interface ReadableOptions {",
+			"  highWaterMark?: number;",
+			"  objectMode?: boolean;",
+			"  encoding?: string;",
+			"  read?(this: Readable, size: number): void;",
+			"  destroy?(this: Readable, err: Error | null, callback: (err?: Error | null) => void): void;",
+			"}",
+			"export interface WritableOptions {",
+			"  highWaterMark?: number;",
+			"  objectMode?: boolean;",
+			"  decodeStrings?: boolean;",
+			"  defaultEncoding?: string;",
+			"  write?(this: Writable, chunk: any, encoding: string, callback: (err?: Error | null) => void): void;",
+			"  final?(this: Writable, callback: (err?: Error | null) => void): void;",
+			"  destroy?(this: Writable, err: Error | null, callback: (err?: Error | null) => void): void;",
+			"}",
+			"export interface DuplexOptions extends ReadableOptions, WritableOptions {",
+			"  readableObjectMode?: boolean;",
+			"  writableObjectMode?: boolean;",
+			"  readableHighWaterMark?: number;",
+			"  writableHighWaterMark?: number;",
+			"}",
+			"export interface TransformOptions extends DuplexOptions {",
+			"  transform?(this: Transform, chunk: any, encoding: string, callback: (err?: Error | null, data?: any) => void): void;",
+			"  flush?(this: Transform, callback: (err?: Error | null, data?: any) => void): void;",
+			"}",
+			"export class Readable extends EventEmitter {",
+			"  constructor(options?: ReadableOptions);",
+			"  static from(iterable: Iterable<any> | string | Uint8Array, options?: ReadableOptions): Readable;",
+			"  readonly readable: boolean;",
+			"  readonly readableEnded: boolean;",
+			"  readonly readableFlowing: boolean | null;",
+			"  readonly readableLength: number;",
+			"  readonly readableHighWaterMark: number;",
+			"  readonly readableObjectMode: boolean;",
+			"  readonly readableEncoding: string | null;",
+			"  readonly destroyed: boolean;",
+			"  readonly closed: boolean;",
+			"  readonly errored: Error | null;",
+			"  _read(size: number): void;",
+			"  push(chunk: any, encoding?: string): boolean;",
+			"  unshift(chunk: any, encoding?: string): void;",
+			"  read(size?: number): any;",
+			"  pause(): this;",
+			"  resume(): this;",
+			"  isPaused(): boolean;",
+			"  setEncoding(encoding: string): this;",
+			"  pipe<T extends Writable>(destination: T, options?: { end?: boolean }): T;",
+			"  unpipe(destination?: Writable): this;",
+			"  destroy(error?: Error): this;",
+			"}",
+			"export class Writable extends EventEmitter {",
+			"  constructor(options?: WritableOptions);",
+			"  readonly writable: boolean;",
+			"  readonly writableEnded: boolean;",
+			"  readonly writableFinished: boolean;",
+			"  readonly writableLength: number;",
+			"  readonly writableHighWaterMark: number;",
+			"  readonly writableObjectMode: boolean;",
+			"  readonly writableNeedDrain: boolean;",
+			"  readonly writableCorked: number;",
+			"  readonly destroyed: boolean;",
+			"  readonly closed: boolean;",
+			"  readonly errored: Error | null;",
+			"  _write(chunk: any, encoding: string, callback: (err?: Error | null) => void): void;",
+			"  write(chunk: any, encoding?: string, callback?: (err?: Error | null) => void): boolean;",
+			"  end(chunk?: any, encoding?: string, callback?: () => void): this;",
+			"  cork(): void;",
+			"  uncork(): void;",
+			"  setDefaultEncoding(encoding: string): this;",
+			"  destroy(error?: Error): this;",
+			"}",
+			"export class Duplex extends Readable {",
+			"  constructor(options?: DuplexOptions);",
+			"  readonly writable: boolean;",
+			"  readonly writableEnded: boolean;",
+			"  readonly writableFinished: boolean;",
+			"  readonly writableLength: number;",
+			"  write(chunk: any, encoding?: string, callback?: (err?: Error | null) => void): boolean;",
+			"  end(chunk?: any, encoding?: string, callback?: () => void): this;",
+			"  cork(): void;",
+			"  uncork(): void;",
+			"}",
+			"export class Transform extends Duplex {",
+			"  constructor(options?: TransformOptions);",
+			"  _transform(chunk: any, encoding: string, callback: (err?: Error | null, data?: any) => void): void;",
+			"  _flush(callback: (err?: Error | null, data?: any) => void): void;",
+			"}",
+			"export class PassThrough extends Transform {}",
+			"export type Stream = Readable | Writable;",
+			"export function pipeline<T extends Stream>(...streams: [...Stream[], T]): T;",
+			"export function pipeline<T extends Stream>(...args: [...Stream[], T, (err?: Error | null) => void]): T;",
+			"export function finished(stream: Stream, callback: (err?: Error | null) => void): void;",
+			"export namespace promises {",
+			"  function pipeline(...streams: Stream[]): Promise<void>;",
+			"  function finished(stream: Stream): Promise<void>;",
+			"}",
+		},
+	}
+}
+
+func (m *module) Loader(vm *goja.Runtime, moduleObj *goja.Object) {
+	c := classesFor(vm)
+	exports := moduleObj.Get("exports").(*goja.Object)
+	mustSet(vm, exports, "Readable", c.Readable)
+	mustSet(vm, exports, "Writable", c.Writable)
+	mustSet(vm, exports, "Duplex", c.Duplex)
+	mustSet(vm, exports, "Transform", c.Transform)
+	mustSet(vm, exports, "PassThrough", c.PassThrough)
+
+	modules.SetExport(exports, m.name, "pipeline", func(call goja.FunctionCall) goja.Value {
+		streams, callback := splitCallback(call.Arguments)
+		return pipeline(vm, streams, func(err goja.Value) {
+			if callback == nil {
+				return
+			}
+			if err == nil {
+				err = goja.Undefined()
+			}
+			if _, callErr := callback(goja.Undefined(), err); callErr != nil {
+				log.Warn().Err(callErr).Msg("stream: pipeline callback error")
+			}
+		})
+	})
+	modules.SetExport(exports, m.name, "finished", func(stream goja.Value, callback goja.Callable) {
+		finished(vm, stream, func(err goja.Value) {
+			if err == nil {
+				err = goja.Undefined()
+			}
+			if _, callErr := callback(goja.Undefined(), err); callErr != nil {
+				log.Warn().Err(callErr).Msg("stream: finished callback error")
+			}
+		})
+	})
+
+	promises := vm.NewObject()
+	modules.SetExport(promises, m.name+".promises", "pipeline", func(call goja.FunctionCall) goja.Value {
+		promise, resolve, reject := vm.NewPromise()
+		streams, _ := splitCallback(call.Arguments)
+		pipeline(vm, streams, settlePromise(resolve, reject))
+		return vm.ToValue(promise)
+	})
+	modules.SetExport(promises, m.name+".promises", "finished", func(stream goja.Value) goja.Value {
+		promise, resolve, reject := vm.NewPromise()
+		finished(vm, stream, settlePromise(resolve, reject))
+		return vm.ToValue(promise)
+	})
+	mustSet(vm, exports, "promises", promises)
+}
+
+func settlePromise(resolve, reject func(any) error) func(goja.Value) {
+	return func(err goja.Value) {
+		if modules.Present(err) {
+			_ = reject(err)
+			return
+		}
+		_ = resolve(goja.Undefined())
+	}
+}
+
+func init() {
+	modules.Register(&module{name: "stream"})
+	modules.Register(&module{name: "node:stream"})
+}
@@ -0,0 +1,217 @@
+package stream_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/internal/enginetest"
+	"github.com/go-go-golems/go-go-goja/modules/stream"
+	"github.com/go-go-golems/go-go-goja/pkg/engine"
+	"github.com/stretchr/testify/require"
+)
+
+func newRuntime(t *testing.T) *engine.Runtime {
+	t.Helper()
+	factory, err := engine.NewRuntimeFactoryBuilder().UseModuleMiddleware(engine.MiddlewareOnly("stream")).Build()
+	require.NoError(t, err)
+	rt, err := factory.NewRuntime(engine.WithStartupContext(context.Background()), engine.WithLifetimeContext(context.Background()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = rt.Close(context.Background()) })
+	return rt
+}
+
+// runAsync runs script, which must eventually call done(value), and returns
+// the JSON encoding of value.
+func runAsync(t *testing.T, rt *engine.Runtime, script string) string {
+	t.Helper()
+	got, ok := enginetest.RunAsync(t, rt, `
+		let result;
+		const done = value => { result = JSON.stringify(value); };
+		const { sleep } = require("timer");
+		`+script+`
+		return result;
+	`).(string)
+	if !ok {
+		t.Fatalf("script did not call done()")
+	}
+	return got
+}
+
+func TestPipelineThroughTransformSubclass(t *testing.T) {
+	rt := newRuntime(t)
+	got := runAsync(t, rt, `
+		const { Readable, Writable, Transform, promises } = require("node:stream");
+		class Upper extends Transform {
+			_transform(chunk, encoding, callback) {
+				callback(null, String(chunk).toUpperCase());
+			}
+			_flush(callback) {
+				callback(null, "!");
+			}
+		}
+		const out = [];
+		const events = [];
+		const sink = new Writable({
+			write(chunk, encoding, callback) { out.push(String(chunk)); callback(); },
+		});
+		sink.on("finish", () => events.push("finish"));
+		const upper = new Upper();
+		await promises.pipeline(Readable.from(["a", "b", "c"]), upper, sink);
+		done({ out, events, instance: upper instanceof Transform, ended: upper.readableEnded, finished: sink.writableFinished });
+	`)
+	require.JSONEq(t, `{"out":["A","B","C","!"],"events":["finish"],"instance":true,"ended":true,"finished":true}`, got)
+}
+
+func TestBackPressure(t *testing.T) {
+	rt := newRuntime(t)
+	got := runAsync(t, rt, `
+		const { Readable, Writable } = require("stream");
+		let produced = 0;
+		const pushes = [];
+		const source = new Readable({
+			highWaterMark: 4,
+			read() {
+				produced++;
+				if (produced > 6) { this.push(null); return; }
+				pushes.push(this.push("xx"));
+			},
+		});
+		// read(0) starts the source without consuming anything: it fills to
+		// its high-water mark and stops calling _read.
+		source.read(0);
+		await sleep(20);
+		const bufferedBeforeConsumer = source.readableLength;
+		const readsBeforeConsumer = produced;
+
+		const pending = [];
+		const writes = [];
+		const sink = new Writable({
+			highWaterMark: 2,
+			write(chunk, encoding, callback) { writes.push(String(chunk)); pending.push(callback); },
+		});
+		const first = sink.write("ab");
+		let drained = 0;
+		sink.on("drain", () => { drained++; });
+		while (pending.length) { pending.shift()(); await null; await null; }
+		await sleep(5);
+
+		const collected = [];
+		const slow = new Writable({
+			highWaterMark: 2,
+			write(chunk, encoding, callback) { collected.push(String(chunk)); sleep(1).then(() => callback()); },
+		});
+		await new Promise(resolve => { source.pipe(slow).on("finish", resolve); });
+		done({ bufferedBeforeConsumer, readsBeforeConsumer, pushes: pushes.slice(0, 2), first, drained, writes, collected: collected.join("") });
+	`)
+	require.JSONEq(t, `{"bufferedBeforeConsumer":4,"readsBeforeConsumer":2,"pushes":[true,false],"first":false,"drained":1,"writes":["ab"],"collected":"xxxxxxxxxxxx"}`, got)
+}
+
+func TestPipelineDestroysStreamsOnError(t *testing.T) {
+	rt := newRuntime(t)
+	got := runAsync(t, rt, `
+		const { Readable, Transform, PassThrough, pipeline } = require("stream");
+		const source = new Readable({ read() { this.push("chunk"); } });
+		const failing = new Transform({
+			transform(chunk, encoding, callback) { callback(new Error("boom")); },
+		});
+		const sink = new PassThrough();
+		sink.resume();
+		const err = await new Promise(resolve => pipeline(source, failing, sink, resolve));
+		await sleep(5);
+		done({ message: err.message, destroyed: [source.destroyed, failing.destroyed, sink.destroyed] });
+	`)
+	require.JSONEq(t, `{"message":"boom","destroyed":[true,true,true]}`, got)
+}
+
+func TestPausedModeReadAndEncoding(t *testing.T) {
+	rt := newRuntime(t)
+	got := runAsync(t, rt, `
+		const { Readable, Writable } = require("stream");
+		const euro = Buffer.from("€uro");
+		const source = new Readable({ read() {} });
+		source.setEncoding("utf8");
+		source.push(euro.subarray(0, 1));
+		source.push(euro.subarray(1));
+		source.push(null);
+		const reads = [];
+		await new Promise(resolve => {
+			source.on("readable", () => {
+				let chunk;
+				while ((chunk = source.read()) !== null) reads.push(chunk);
+			});
+			source.on("end", resolve);
+		});
+		let writeErr;
+		const w = new Writable({ write(c, e, cb) { cb(); } });
+		w.on("error", e => { writeErr = e.code; });
+		w.end("x");
+		w.write("late");
+		await sleep(5);
+		done({ reads, writeErr });
+	`)
+	require.JSONEq(t, `{"reads":["€uro"],"writeErr":"ERR_STREAM_WRITE_AFTER_END"}`, got)
+}
+
+// closeTracker is an io.ReadCloser/io.WriteCloser that records Close.
+type closeTracker struct {
+	mu     sync.Mutex
+	reader *strings.Reader
+	buf    bytes.Buffer
+	closed bool
+	reads  int
+}
+
+func (c *closeTracker) Read(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reads++
+	return c.reader.Read(p)
+}
+
+func (c *closeTracker) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.Write(p)
+}
+
+func (c *closeTracker) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func TestGoAdaptersStreamReadersAndWriters(t *testing.T) {
+	rt := newRuntime(t)
+	src := &closeTracker{reader: strings.NewReader(strings.Repeat("0123456789", 10))}
+	dst := &closeTracker{}
+	_, err := rt.Owner.Call(context.Background(), "stream.adapters", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		if err := vm.Set("goReader", stream.NewReadable(vm, src, stream.Options{HighWaterMark: 8, Encoding: "utf8"})); err != nil {
+			return nil, err
+		}
+		return nil, vm.Set("goWriter", stream.NewWritable(vm, dst, stream.Options{}))
+	})
+	require.NoError(t, err)
+
+	got := runAsync(t, rt, `
+		const { Readable, promises } = require("stream");
+		await sleep(20);
+		const buffered = goReader.readableLength;
+		const chunks = [];
+		goReader.on("data", chunk => chunks.push(typeof chunk));
+		await promises.pipeline(goReader, goWriter);
+		done({ buffered, readable: goReader instanceof Readable, allStrings: chunks.every(c => c === "string"), chunks: chunks.length });
+	`)
+	require.JSONEq(t, `{"buffered":8,"readable":true,"allStrings":true,"chunks":13}`, got)
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	dst.mu.Lock()
+	defer dst.mu.Unlock()
+	require.True(t, src.closed, "reader closed at EOF")
+	require.True(t, dst.closed, "writer closed by end()")
+	require.Equal(t, strings.Repeat("0123456789", 10), dst.buf.String())
+}
@@ -0,0 +1,97 @@
+package stream
+
+import (
+	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/modules"
+)
+
+// transformState holds the write callback a Transform defers while its
+// readable side is full, which is how back-pressure crosses the two sides.
+type transformState struct {
+	pending func()
+}
+
+func (s *state) initDuplex(opts *goja.Object) {
+	objectMode := optionBool(opts, "objectMode", false)
+	highWaterMark := optionInt(opts, "highWaterMark", -1)
+	s.initReadable(opts, optionBool(opts, "readableObjectMode", objectMode), optionInt(opts, "readableHighWaterMark", highWaterMark))
+	s.initWritable(opts, optionBool(opts, "writableObjectMode", objectMode), optionInt(opts, "writableHighWaterMark", highWaterMark))
+}
+
+func (s *state) initTransform(opts *goja.Object) {
+	s.initDuplex(opts)
+	t := &transformState{}
+	s.t = t
+	s.w.sink = s.transformWrite
+	s.w.final = s.transformFlush
+	s.r.source = func(int) {
+		if pending := t.pending; pending != nil {
+			t.pending = nil
+			pending()
+		}
+	}
+}
+
+// transformWrite hands a chunk to _transform and pushes what it produces.
+func (s *state) transformWrite(req *writeRequest, done func(goja.Value)) {
+	impl, ok := s.method("_transform")
+	if !ok {
+		done(errorValue(s.vm, &codeError{code: "ERR_METHOD_NOT_IMPLEMENTED", message: "The _transform() method is not implemented"}))
+		return
+	}
+	called := false
+	callback := s.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		if called {
+			return goja.Undefined()
+		}
+		called = true
+		if err := call.Argument(0); modules.Present(err) {
+			done(err)
+			return goja.Undefined()
+		}
+		if data := call.Argument(1); modules.Present(data) {
+			s.push(data, goja.Undefined())
+		}
+		if s.r.length < s.r.highWaterMark || s.destroyed {
+			done(nil)
+			return goja.Undefined()
+		}
+		s.t.pending = func() { done(nil) }
+		return goja.Undefined()
+	})
+	if _, err := impl(s.obj, req.chunk(s.vm), s.vm.ToValue(req.encoding), callback); err != nil && !called {
+		called = true
+		done(errorValue(s.vm, err))
+	}
+}
+
+// transformFlush runs _flush, pushes its output and ends the readable side.
+func (s *state) transformFlush(done func(goja.Value)) {
+	impl, ok := s.method("_flush")
+	if !ok {
+		s.pushEOF()
+		done(nil)
+		return
+	}
+	called := false
+	callback := s.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		if called {
+			return goja.Undefined()
+		}
+		called = true
+		if err := call.Argument(0); modules.Present(err) {
+			done(err)
+			return goja.Undefined()
+		}
+		if data := call.Argument(1); modules.Present(data) {
+			s.push(data, goja.Undefined())
+		}
+		s.pushEOF()
+		done(nil)
+		return goja.Undefined()
+	})
+	if _, err := impl(s.obj, callback); err != nil && !called {
+		called = true
+		done(errorValue(s.vm, err))
+	}
+}
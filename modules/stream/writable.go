@@ -0,0 +1,289 @@
+package stream
+
+import (
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/buffer"
+	"github.com/go-go-golems/go-go-goja/modules"
+)
+
+type writableState struct {
+	objectMode      bool
+	highWaterMark   int
+	decodeStrings   bool
+	defaultEncoding string
+
+	queue   []*writeRequest
+	length  int
+	corked  int
+	writing bool
+
+	ending      bool
+	finalCalled bool
+	finished    bool
+	needDrain   bool
+
+	// sink and final replace this._write and this._final for streams that
+	// are implemented in Go.
+	sink  func(req *writeRequest, done func(goja.Value))
+	final func(done func(goja.Value))
+}
+
+// writeRequest is one write() call waiting for, or handed to, _write.
+type writeRequest struct {
+	value    goja.Value
+	data     []byte
+	encoding string
+	size     int
+	callback goja.Callable
+}
+
+func (s *state) initWritable(opts *goja.Object, objectMode bool, highWaterMark int) {
+	w := &writableState{
+		objectMode:      objectMode,
+		highWaterMark:   highWaterMark,
+		decodeStrings:   optionBool(opts, "decodeStrings", true),
+		defaultEncoding: "utf8",
+	}
+	if w.highWaterMark < 0 {
+		w.highWaterMark = defaultHighWaterMark
+		if objectMode {
+			w.highWaterMark = defaultObjectHighWaterMark
+		}
+	}
+	if opts != nil {
+		if enc := opts.Get("defaultEncoding"); modules.Present(enc) {
+			w.defaultEncoding = enc.String()
+		}
+	}
+	s.w = w
+}
+
+// write queues value and reports whether the caller may keep writing before
+// waiting for "drain".
+func (s *state) write(value goja.Value, encoding string, callback goja.Callable) bool {
+	w := s.w
+	var failure error
+	switch {
+	case w.ending:
+		failure = &codeError{code: "ERR_STREAM_WRITE_AFTER_END", message: "write after end"}
+	case s.destroyed:
+		failure = &codeError{code: "ERR_STREAM_DESTROYED", message: "Cannot call write after a stream was destroyed"}
+	case value == nil || goja.IsNull(value):
+		panic(s.vm.NewTypeError("May not write null values to stream"))
+	}
+	if failure != nil {
+		errValue := errorValue(s.vm, failure)
+		s.nextTick("stream.write-error", func() {
+			if callback != nil {
+				_, _ = callback(goja.Undefined(), errValue)
+			}
+		})
+		s.destroy(errValue)
+		return false
+	}
+	if encoding == "" {
+		encoding = w.defaultEncoding
+	}
+	req := &writeRequest{value: value, encoding: encoding, callback: callback, size: 1}
+	if !w.objectMode {
+		if _, isString := value.Export().(string); !isString || w.decodeStrings {
+			req.data = buffer.DecodeBytes(s.vm, value, s.vm.ToValue(encoding))
+			req.value = nil
+			req.encoding = "buffer"
+		}
+		req.size = len(req.data)
+		if req.value != nil {
+			req.size = len(value.String())
+		}
+	}
+	w.length += req.size
+	ok := w.length < w.highWaterMark
+	if !ok {
+		w.needDrain = true
+	}
+	if w.writing || w.corked > 0 {
+		w.queue = append(w.queue, req)
+	} else {
+		s.doWrite(req)
+	}
+	return ok
+}
+
+func (req *writeRequest) chunk(vm *goja.Runtime) goja.Value {
+	if req.value != nil {
+		return req.value
+	}
+	return buffer.WrapBytes(vm, req.data)
+}
+
+func (s *state) doWrite(req *writeRequest) {
+	w := s.w
+	w.writing = true
+	sync, called := true, false
+	done := func(err goja.Value) {
+		if called {
+			return
+		}
+		called = true
+		if sync {
+			s.nextTick("stream.after-write", func() { s.afterWrite(req, err) })
+			return
+		}
+		s.afterWrite(req, err)
+	}
+	defer func() { sync = false }()
+	if w.sink != nil {
+		w.sink(req, done)
+		return
+	}
+	impl, ok := s.method("_write")
+	if !ok {
+		done(errorValue(s.vm, &codeError{code: "ERR_METHOD_NOT_IMPLEMENTED", message: "The _write() method is not implemented"}))
+		return
+	}
+	callback := s.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		done(call.Argument(0))
+		return goja.Undefined()
+	})
+	if _, err := impl(s.obj, req.chunk(s.vm), s.vm.ToValue(req.encoding), callback); err != nil {
+		done(errorValue(s.vm, err))
+	}
+}
+
+func (s *state) afterWrite(req *writeRequest, err goja.Value) {
+	w := s.w
+	w.length -= req.size
+	if modules.Present(err) {
+		w.writing = false
+		if req.callback != nil {
+			_, _ = req.callback(goja.Undefined(), err)
+		}
+		s.destroy(err)
+		return
+	}
+	if req.callback != nil {
+		if _, callErr := req.callback(goja.Undefined()); callErr != nil {
+			log.Warn().Err(callErr).Msg("stream: write callback error")
+		}
+	}
+	if s.destroyed {
+		w.writing = false
+		return
+	}
+	if len(w.queue) > 0 && w.corked == 0 {
+		next := w.queue[0]
+		w.queue = w.queue[1:]
+		s.doWrite(next)
+		return
+	}
+	w.writing = false
+	if w.needDrain && w.length == 0 && !w.ending {
+		w.needDrain = false
+		s.emit("drain")
+	}
+	s.maybeFinish()
+}
+
+func (s *state) uncork() {
+	w := s.w
+	if w.corked > 0 {
+		w.corked--
+	}
+	if w.corked == 0 && !w.writing && len(w.queue) > 0 {
+		next := w.queue[0]
+		w.queue = w.queue[1:]
+		s.doWrite(next)
+	}
+}
+
+func (s *state) end(callback goja.Callable) {
+	w := s.w
+	if callback != nil {
+		if w.finished {
+			s.nextTick("stream.end-callback", func() { _, _ = callback(goja.Undefined()) })
+		} else {
+			_ = s.emitter.AddListenerValue("finish", s.vm.ToValue(func(goja.FunctionCall) goja.Value {
+				_, _ = callback(goja.Undefined())
+				return goja.Undefined()
+			}))
+		}
+	}
+	if w.ending {
+		return
+	}
+	w.ending = true
+	w.corked = 0
+	if !w.writing && len(w.queue) > 0 {
+		next := w.queue[0]
+		w.queue = w.queue[1:]
+		s.doWrite(next)
+		return
+	}
+	s.maybeFinish()
+}
+
+// maybeFinish runs _final and emits "finish" once end() was called and every
+// queued write has completed.
+func (s *state) maybeFinish() {
+	w := s.w
+	if !w.ending || w.writing || len(w.queue) > 0 || w.finalCalled || s.destroyed {
+		return
+	}
+	w.finalCalled = true
+	called := false
+	done := func(err goja.Value) {
+		if called {
+			return
+		}
+		called = true
+		if modules.Present(err) {
+			s.destroy(err)
+			return
+		}
+		s.nextTick("stream.finish", func() {
+			if s.destroyed && s.errored != nil {
+				return
+			}
+			w.finished = true
+			s.emit("finish")
+			s.maybeAutoDestroy()
+		})
+	}
+	if w.final != nil {
+		w.final(done)
+		return
+	}
+	impl, ok := s.method("_final")
+	if !ok {
+		done(nil)
+		return
+	}
+	callback := s.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		done(call.Argument(0))
+		return goja.Undefined()
+	})
+	if _, err := impl(s.obj, callback); err != nil {
+		done(errorValue(s.vm, err))
+	}
+}
+
+// writeArgs splits write(chunk, encoding?, callback?) and
+// end(chunk?, encoding?, callback?) arguments.
+func writeArgs(call goja.FunctionCall) (goja.Value, string, goja.Callable) {
+	value := call.Argument(0)
+	if fn, ok := goja.AssertFunction(value); ok {
+		return nil, "", fn
+	}
+	encoding := ""
+	var callback goja.Callable
+	for _, arg := range call.Arguments[min(1, len(call.Arguments)):] {
+		if fn, ok := goja.AssertFunction(arg); ok {
+			callback = fn
+			break
+		}
+		if modules.Present(arg) && encoding == "" {
+			encoding = arg.String()
+		}
+	}
+	return value, encoding, callback
+}
@@ -0,0 +1,32 @@
+package modules
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// Present reports whether v is set to something other than undefined or null.
+func Present(v goja.Value) bool {
+	return v != nil && !goja.IsUndefined(v) && !goja.IsNull(v)
+}
+
+// DurationValue reads a duration given as milliseconds or as a Go duration
+// string such as "250ms" or "5m".
+func DurationValue(value goja.Value) (time.Duration, error) {
+	if s, ok := value.Export().(string); ok {
+		if ms, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			return time.Duration(ms * float64(time.Millisecond)), nil
+		}
+		return time.ParseDuration(strings.TrimSpace(s))
+	}
+	ms := value.ToFloat()
+	if math.IsNaN(ms) || ms < 0 {
+		return 0, fmt.Errorf("invalid duration %s", value.String())
+	}
+	return time.Duration(ms * float64(time.Millisecond)), nil
+}
@@ -0,0 +1,421 @@
+// Package dbmigrate applies versioned SQL migrations to any database/sql
+// connection. Applied versions are recorded with a checksum in a meta table,
+// each migration runs in its own transaction, and a migration whose SQL
+// changed after it was applied is reported as drift instead of being
+// silently skipped. The database module's migrate() and the "db migrate"
+// command of generated xgoja hosts both build on it.
+package dbmigrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTable is the meta table used when Options.Table is empty.
+const DefaultTable = "goja_schema_migrations"
+
+// DialectPostgres is the Options.Dialect of drivers that number bind
+// parameters $1, $2, ...; it matches the database module's dialect name.
+const DialectPostgres = "postgres"
+
+// Migration statuses reported in Result.
+const (
+	// StatusApplied marks a migration recorded before this run.
+	StatusApplied = "applied"
+	// StatusMigrated marks a migration applied by this run.
+	StatusMigrated = "migrated"
+	// StatusPending marks a migration a dry run would apply.
+	StatusPending = "pending"
+)
+
+var (
+	// ErrInvalidMigration identifies malformed or badly ordered migrations.
+	ErrInvalidMigration = errors.New("dbmigrate: invalid migration")
+	// ErrChecksumMismatch reports an applied migration whose SQL changed.
+	ErrChecksumMismatch = errors.New("dbmigrate: checksum mismatch")
+	// ErrUnknownVersion reports an applied version missing from the list,
+	// usually a database migrated by a newer release.
+	ErrUnknownVersion = errors.New("dbmigrate: unknown applied version")
+)
+
+// Migration is one schema step. Statements run in order inside one
+// transaction.
+type Migration struct {
+	Version    int64
+	Name       string
+	Statements []string
+}
+
+// Checksum is the hex SHA-256 of the migration's statements.
+func (m Migration) Checksum() string {
+	h := sha256.New()
+	for _, statement := range m.Statements {
+		h.Write([]byte(strings.TrimSpace(statement)))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// DriftError describes an applied migration whose checksum changed.
+type DriftError struct {
+	Version  int64
+	Name     string
+	Recorded string
+	Current  string
+}
+
+func (e *DriftError) Error() string {
+	return fmt.Sprintf("dbmigrate: migration %d (%s) changed after it was applied: recorded checksum %s, now %s",
+		e.Version, e.Name, short(e.Recorded), short(e.Current))
+}
+
+func (e *DriftError) Unwrap() error { return ErrChecksumMismatch }
+
+// Result reports one migration.
+type Result struct {
+	Version  int64  `json:"version"`
+	Name     string `json:"name"`
+	Checksum string `json:"checksum"`
+	Status   string `json:"status"`
+	// AppliedAt is the RFC 3339 time the migration was recorded. It is
+	// empty for pending migrations.
+	AppliedAt string `json:"appliedAt,omitempty"`
+}
+
+// Conn is the query surface shared by databases and transactions. *sql.DB,
+// *sql.Conn and *sql.Tx implement it.
+type Conn interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Tx is a transaction started by Database.Begin.
+type Tx interface {
+	Conn
+	Commit() error
+	Rollback() error
+}
+
+// Database is what Apply migrates. Use FromDB for a *sql.DB; wrappers that
+// enforce host policy can implement it directly.
+type Database interface {
+	Conn
+	Begin(ctx context.Context) (Tx, error)
+}
+
+// FromDB adapts a *sql.DB.
+func FromDB(db *sql.DB) Database {
+	return sqlDB{db}
+}
+
+type sqlDB struct{ *sql.DB }
+
+func (db sqlDB) Begin(ctx context.Context) (Tx, error) {
+	return db.BeginTx(ctx, nil)
+}
+
+// Options configures Apply.
+type Options struct {
+	// Table is the meta table name. It defaults to DefaultTable.
+	Table string
+	// DryRun validates the migrations against the database and reports the
+	// pending ones without creating the meta table or changing the schema.
+	DryRun bool
+	// Now stamps applied migrations. It defaults to time.Now.
+	Now func() time.Time
+	// Dialect selects how the statement recording a migration spells its
+	// bind parameters: DialectPostgres for $1, $2, ..., anything else for
+	// "?". The database module passes its connection's dialect.
+	Dialect string
+}
+
+var (
+	fileNamePattern  = regexp.MustCompile(`^(\d+)[_-](.+)\.sql$`)
+	tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// LoadDir reads the *.sql files in dir. File names start with the version,
+// for example 0001_create_users.sql or 20240501-add-index.sql; each file is
+// one migration and its whole content is executed as a single statement, so
+// files with several statements need a driver that accepts them (sqlite3
+// does; MySQL needs multiStatements=true). Other files are ignored.
+func LoadDir(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	var ret []Migration
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("%w: %s does not start with a version number", ErrInvalidMigration, entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidMigration, entry.Name(), err)
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, Migration{Version: version, Name: match[2], Statements: []string{string(data)}})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Version < ret[j].Version })
+	return ret, Validate(ret)
+}
+
+// Validate checks that versions are positive, strictly increasing and
+// unique, that names are printable, and that every migration has SQL.
+func Validate(migrations []Migration) error {
+	var last int64
+	for _, m := range migrations {
+		switch {
+		case m.Version <= 0:
+			return fmt.Errorf("%w: version %d must be positive", ErrInvalidMigration, m.Version)
+		case m.Version == last:
+			return fmt.Errorf("%w: duplicate version %d", ErrInvalidMigration, m.Version)
+		case m.Version < last:
+			return fmt.Errorf("%w: version %d follows %d", ErrInvalidMigration, m.Version, last)
+		case m.Name == "" || strings.ContainsAny(m.Name, "'\\") || strings.IndexFunc(m.Name, isControl) >= 0:
+			return fmt.Errorf("%w: migration %d needs a name without quotes, backslashes or control characters", ErrInvalidMigration, m.Version)
+		case !hasSQL(m.Statements):
+			return fmt.Errorf("%w: migration %d (%s) has no SQL", ErrInvalidMigration, m.Version, m.Name)
+		}
+		last = m.Version
+	}
+	return nil
+}
+
+// Apply brings db up to date with migrations, which must be sorted by
+// version. Already applied migrations must be unchanged; pending ones must
+// all be newer than the newest applied version. Each pending migration
+// runs and is recorded in its own transaction, so a failure leaves the
+// earlier ones applied. Whether anything of the failing one remains depends
+// on the database: SQLite and PostgreSQL roll its DDL back, while MySQL
+// commits each DDL statement as it runs. Results cover every migration in
+// order.
+func Apply(ctx context.Context, db Database, migrations []Migration, opts Options) ([]Result, error) {
+	if db == nil {
+		return nil, fmt.Errorf("dbmigrate: database is nil")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	opts = opts.withDefaults()
+	if !tableNamePattern.MatchString(opts.Table) {
+		return nil, fmt.Errorf("dbmigrate: invalid table name %q", opts.Table)
+	}
+	if err := Validate(migrations); err != nil {
+		return nil, err
+	}
+
+	exists, err := tableExists(ctx, db, opts.Table)
+	if err != nil {
+		return nil, err
+	}
+	if !exists && !opts.DryRun {
+		if _, err := db.ExecContext(ctx, createTableSQL(opts.Table)); err != nil {
+			return nil, fmt.Errorf("dbmigrate: create %s: %w", opts.Table, err)
+		}
+		exists = true
+	}
+	applied := map[int64]record{}
+	if exists {
+		if applied, err = readApplied(ctx, db, opts.Table); err != nil {
+			return nil, err
+		}
+	}
+	results, err := plan(migrations, applied)
+	if err != nil || opts.DryRun {
+		return results, err
+	}
+
+	for i, m := range migrations {
+		if results[i].Status != StatusPending {
+			continue
+		}
+		log.Debug().Int64("version", m.Version).Str("name", m.Name).Msg("dbmigrate: applying migration")
+		appliedAt, err := applyOne(ctx, db, m, opts)
+		if err != nil {
+			return results, err
+		}
+		results[i].Status = StatusMigrated
+		results[i].AppliedAt = appliedAt
+	}
+	return results, nil
+}
+
+func (o Options) withDefaults() Options {
+	if o.Table == "" {
+		o.Table = DefaultTable
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+	return o
+}
+
+type record struct {
+	name      string
+	checksum  string
+	appliedAt string
+}
+
+// plan compares migrations with the applied records without touching the
+// database.
+func plan(migrations []Migration, applied map[int64]record) ([]Result, error) {
+	known := map[int64]bool{}
+	var newestApplied int64
+	for version := range applied {
+		newestApplied = max(newestApplied, version)
+	}
+	results := make([]Result, len(migrations))
+	for i, m := range migrations {
+		known[m.Version] = true
+		checksum := m.Checksum()
+		results[i] = Result{Version: m.Version, Name: m.Name, Checksum: checksum, Status: StatusPending}
+		rec, ok := applied[m.Version]
+		if !ok {
+			if m.Version < newestApplied {
+				return results, fmt.Errorf("%w: migration %d (%s) is older than applied version %d", ErrInvalidMigration, m.Version, m.Name, newestApplied)
+			}
+			continue
+		}
+		if rec.checksum != checksum {
+			return results, &DriftError{Version: m.Version, Name: m.Name, Recorded: rec.checksum, Current: checksum}
+		}
+		results[i].Status = StatusApplied
+		results[i].AppliedAt = rec.appliedAt
+	}
+	for version, rec := range applied {
+		if !known[version] {
+			return results, fmt.Errorf("%w: %d (%s)", ErrUnknownVersion, version, rec.name)
+		}
+	}
+	return results, nil
+}
+
+func applyOne(ctx context.Context, db Database, m Migration, opts Options) (string, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("dbmigrate: migration %d: begin transaction: %w", m.Version, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Another process may have applied the migration while this one waited.
+	done, err := readApplied(ctx, tx, opts.Table)
+	if err != nil {
+		return "", err
+	}
+	if rec, ok := done[m.Version]; ok {
+		if rec.checksum != m.Checksum() {
+			return "", &DriftError{Version: m.Version, Name: m.Name, Recorded: rec.checksum, Current: m.Checksum()}
+		}
+		return rec.appliedAt, tx.Commit()
+	}
+
+	for index, statement := range m.Statements {
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return "", fmt.Errorf("dbmigrate: migration %d (%s), statement %d: %w", m.Version, m.Name, index+1, err)
+		}
+	}
+	appliedAt := opts.Now().UTC().Format(time.RFC3339)
+	if _, err := tx.ExecContext(ctx, opts.insertSQL(), m.Version, m.Name, m.Checksum(), appliedAt); err != nil {
+		return "", fmt.Errorf("dbmigrate: migration %d: record version: %w", m.Version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("dbmigrate: migration %d: commit transaction: %w", m.Version, err)
+	}
+	return appliedAt, nil
+}
+
+// insertSQL records one migration with bound values.
+func (o Options) insertSQL() string {
+	values := "?, ?, ?, ?"
+	if o.Dialect == DialectPostgres {
+		values = "$1, $2, $3, $4"
+	}
+	return fmt.Sprintf("INSERT INTO %s (version, name, checksum, applied_at) VALUES (%s)", o.Table, values)
+}
+
+func createTableSQL(table string) string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		version BIGINT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		checksum VARCHAR(64) NOT NULL,
+		applied_at VARCHAR(64) NOT NULL
+	)`, table)
+}
+
+// tableExists probes the meta table with a query every SQL dialect accepts.
+func tableExists(ctx context.Context, db Database, table string) (bool, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT version FROM %s WHERE 1 = 0", table))
+	if err == nil {
+		return true, rows.Close()
+	}
+	// Drivers report a missing table in their own words, so tell it apart
+	// from a broken connection with a query that cannot fail otherwise.
+	rows, pingErr := db.QueryContext(ctx, "SELECT 1")
+	if pingErr != nil {
+		return false, fmt.Errorf("dbmigrate: %w", pingErr)
+	}
+	return false, rows.Close()
+}
+
+func readApplied(ctx context.Context, conn Conn, table string) (map[int64]record, error) {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT version, name, checksum, applied_at FROM %s", table))
+	if err != nil {
+		return nil, fmt.Errorf("dbmigrate: read %s: %w", table, err)
+	}
+	defer func() { _ = rows.Close() }()
+	ret := map[int64]record{}
+	for rows.Next() {
+		var version int64
+		var rec record
+		if err := rows.Scan(&version, &rec.name, &rec.checksum, &rec.appliedAt); err != nil {
+			return nil, fmt.Errorf("dbmigrate: read %s: %w", table, err)
+		}
+		ret[version] = rec
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("dbmigrate: read %s: %w", table, err)
+	}
+	return ret, nil
+}
+
+func hasSQL(statements []string) bool {
+	for _, statement := range statements {
+		if strings.TrimSpace(statement) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func isControl(r rune) bool {
+	return r < 0x20 || r == 0x7f
+}
+
+func short(checksum string) string {
+	if len(checksum) > 12 {
+		return checksum[:12]
+	}
+	return checksum
+}
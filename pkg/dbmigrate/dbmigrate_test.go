@@ -0,0 +1,244 @@
+package dbmigrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var fixedNow = func() time.Time { return time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC) }
+
+func TestApplyRecordsVersionsAndIsIdempotent(t *testing.T) {
+	db := openTestDB(t)
+	migrations := []Migration{
+		{Version: 1, Name: "users", Statements: []string{`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)`}},
+		{Version: 20240501, Name: "email", Statements: []string{
+			`ALTER TABLE users ADD COLUMN email TEXT`,
+			`CREATE INDEX idx_users_email ON users(email)`,
+		}},
+	}
+
+	results, err := Apply(context.Background(), FromDB(db), migrations, Options{Now: fixedNow})
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	for _, result := range results {
+		if result.Status != StatusMigrated || result.AppliedAt != "2024-05-06T07:08:09Z" || len(result.Checksum) != 64 {
+			t.Fatalf("unexpected first result: %+v", result)
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO users(name, email) VALUES ('Ada', 'ada@example.com')`); err != nil {
+		t.Fatalf("schema not applied: %v", err)
+	}
+
+	results, err = Apply(context.Background(), FromDB(db), migrations, Options{})
+	if err != nil {
+		t.Fatalf("reapply: %v", err)
+	}
+	for _, result := range results {
+		if result.Status != StatusApplied || result.AppliedAt != "2024-05-06T07:08:09Z" {
+			t.Fatalf("unexpected second result: %+v", result)
+		}
+	}
+}
+
+func TestApplyDryRunLeavesDatabaseUntouched(t *testing.T) {
+	db := openTestDB(t)
+	migrations := []Migration{{Version: 1, Name: "users", Statements: []string{`CREATE TABLE users (id INTEGER)`}}}
+
+	results, err := Apply(context.Background(), FromDB(db), migrations, Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if len(results) != 1 || results[0].Status != StatusPending {
+		t.Fatalf("unexpected dry run results: %+v", results)
+	}
+	var count int
+	if err := db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table'`).Scan(&count); err != nil || count != 0 {
+		t.Fatalf("dry run created tables: count=%d err=%v", count, err)
+	}
+}
+
+func TestApplyDetectsChecksumDriftAndUnknownVersions(t *testing.T) {
+	db := openTestDB(t)
+	original := []Migration{
+		{Version: 1, Name: "users", Statements: []string{`CREATE TABLE users (id INTEGER)`}},
+		{Version: 2, Name: "posts", Statements: []string{`CREATE TABLE posts (id INTEGER)`}},
+	}
+	if _, err := Apply(context.Background(), FromDB(db), original, Options{Table: "app_migrations"}); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	changed := []Migration{
+		{Version: 1, Name: "users", Statements: []string{`CREATE TABLE users (id INTEGER, name TEXT)`}},
+		original[1],
+	}
+	_, err := Apply(context.Background(), FromDB(db), changed, Options{Table: "app_migrations", DryRun: true})
+	var drift *DriftError
+	if !errors.As(err, &drift) || !errors.Is(err, ErrChecksumMismatch) || drift.Version != 1 {
+		t.Fatalf("expected drift on v1, got %v", err)
+	}
+
+	_, err = Apply(context.Background(), FromDB(db), original[:1], Options{Table: "app_migrations"})
+	if !errors.Is(err, ErrUnknownVersion) {
+		t.Fatalf("expected unknown version error, got %v", err)
+	}
+
+	duplicate := []Migration{original[0], {Version: 1, Name: "dup", Statements: []string{`SELECT 1`}}}
+	if _, err := Apply(context.Background(), FromDB(db), duplicate, Options{}); !errors.Is(err, ErrInvalidMigration) {
+		t.Fatalf("expected duplicate version error, got %v", err)
+	}
+}
+
+func TestApplyRejectsPendingMigrationOlderThanApplied(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := Apply(context.Background(), FromDB(db), []Migration{
+		{Version: 5, Name: "five", Statements: []string{`CREATE TABLE five (id INTEGER)`}},
+	}, Options{}); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	_, err := Apply(context.Background(), FromDB(db), []Migration{
+		{Version: 3, Name: "three", Statements: []string{`CREATE TABLE three (id INTEGER)`}},
+		{Version: 5, Name: "five", Statements: []string{`CREATE TABLE five (id INTEGER)`}},
+	}, Options{})
+	if !errors.Is(err, ErrInvalidMigration) {
+		t.Fatalf("expected out-of-order error, got %v", err)
+	}
+}
+
+func TestFailedMigrationRollsBackAndKeepsEarlierOnes(t *testing.T) {
+	db := openTestDB(t)
+	migrations := []Migration{
+		{Version: 1, Name: "users", Statements: []string{`CREATE TABLE users (id INTEGER)`}},
+		{Version: 2, Name: "broken", Statements: []string{
+			`CREATE TABLE half (id INTEGER)`,
+			`INSERT INTO missing VALUES (1)`,
+		}},
+	}
+	results, err := Apply(context.Background(), FromDB(db), migrations, Options{})
+	if err == nil {
+		t.Fatalf("expected broken migration to fail")
+	}
+	if results[0].Status != StatusMigrated || results[1].Status != StatusPending {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	var count int
+	if err := db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE name = 'half'`).Scan(&count); err != nil || count != 0 {
+		t.Fatalf("failed migration left table behind: count=%d err=%v", count, err)
+	}
+	if err := db.QueryRow(`SELECT count(*) FROM goja_schema_migrations`).Scan(&count); err != nil || count != 1 {
+		t.Fatalf("expected one recorded version: count=%d err=%v", count, err)
+	}
+}
+
+func TestLoadDirReadsVersionedFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0002-add-index.sql":     {Data: []byte("CREATE INDEX i ON t(id);")},
+		"migrations/0001_create_table.sql":  {Data: []byte("CREATE TABLE t (id INTEGER);")},
+		"migrations/README.md":              {Data: []byte("ignored")},
+		"migrations/nested/0003_skip.sql":   {Data: []byte("ignored")},
+		"bad/create.sql":                    {Data: []byte("CREATE TABLE t (id INTEGER);")},
+		"duplicate/1_a.sql":                 {Data: []byte("SELECT 1;")},
+		"duplicate/0001_b.sql":              {Data: []byte("SELECT 2;")},
+		"empty/0001_nothing.sql":            {Data: []byte("  \n")},
+		"quoted/0001_it's.sql":              {Data: []byte("SELECT 1;")},
+		"migrations/nested/README.markdown": {Data: []byte("ignored")},
+	}
+	migrations, err := LoadDir(fsys, "migrations")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].Version != 1 || migrations[0].Name != "create_table" ||
+		migrations[1].Version != 2 || migrations[1].Name != "add-index" {
+		t.Fatalf("unexpected migrations: %+v", migrations)
+	}
+	for _, dir := range []string{"bad", "duplicate", "empty", "quoted"} {
+		if _, err := LoadDir(fsys, dir); !errors.Is(err, ErrInvalidMigration) {
+			t.Fatalf("%s: expected invalid migration, got %v", dir, err)
+		}
+	}
+}
+
+func TestApplyBindsRecordValuesForDialect(t *testing.T) {
+	migrations := []Migration{{Version: 3, Name: "users", Statements: []string{`CREATE TABLE users (id INTEGER)`}}}
+	for dialect, want := range map[string]string{
+		"":              "INSERT INTO goja_schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)",
+		"mysql":         "INSERT INTO goja_schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)",
+		DialectPostgres: "INSERT INTO goja_schema_migrations (version, name, checksum, applied_at) VALUES ($1, $2, $3, $4)",
+	} {
+		db := &recordingDB{}
+		if _, err := Apply(context.Background(), db, migrations, Options{Now: fixedNow, Dialect: dialect}); err != nil {
+			t.Fatalf("%q: apply: %v", dialect, err)
+		}
+		insert := db.execs[len(db.execs)-1]
+		if insert.query != want {
+			t.Errorf("%q: insert = %q, want %q", dialect, insert.query, want)
+		}
+		if len(insert.args) != 4 || insert.args[0] != int64(3) || insert.args[1] != "users" || insert.args[2] != migrations[0].Checksum() || insert.args[3] != "2024-05-06T07:08:09Z" {
+			t.Errorf("%q: insert args = %v", dialect, insert.args)
+		}
+	}
+}
+
+// recordingDB is a Database over an in-memory sqlite3 connection that
+// records every Exec. sqlite3 binds both "?" and "$1" by position.
+type recordingDB struct {
+	db    *sql.DB
+	execs []recordedExec
+}
+
+type recordedExec struct {
+	query string
+	args  []any
+}
+
+func (r *recordingDB) conn() *sql.DB {
+	if r.db == nil {
+		r.db, _ = sql.Open("sqlite3", ":memory:")
+		r.db.SetMaxOpenConns(1)
+	}
+	return r.db
+}
+
+func (r *recordingDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return r.conn().QueryContext(ctx, query, args...)
+}
+
+func (r *recordingDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	r.execs = append(r.execs, recordedExec{query: query, args: args})
+	return r.conn().ExecContext(ctx, query, args...)
+}
+
+func (r *recordingDB) Begin(context.Context) (Tx, error) {
+	tx, err := r.conn().Begin()
+	if err != nil {
+		return nil, err
+	}
+	return recordingTx{Tx: tx, db: r}, nil
+}
+
+type recordingTx struct {
+	*sql.Tx
+	db *recordingDB
+}
+
+func (t recordingTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	t.db.execs = append(t.db.execs, recordedExec{query: query, args: args})
+	return t.Tx.ExecContext(ctx, query, args...)
+}
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "migrate.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
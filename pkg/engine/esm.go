@@ -0,0 +1,553 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/require"
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/go-go-golems/go-go-goja/pkg/jserrors"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimeowner"
+)
+
+// esmTarget is the language level ES modules are lowered to. goja implements
+// async functions natively, so they are kept rather than rewritten into
+// generators.
+const esmTarget = api.ES2017
+
+// esmEvaluationKey names the non-enumerable property through which a module
+// with top-level await exposes the promise of its evaluation.
+const esmEvaluationKey = "__gojaEvaluation"
+
+// Names used by the bundle compileAsyncModule builds: the module and the
+// entry that evaluates it live in asyncModuleNamespace, every import in
+// asyncRequireNamespace.
+const (
+	asyncModuleNamespace  = "goja-module"
+	asyncRequireNamespace = "goja-require"
+	asyncModuleEntry      = "module"
+	asyncEvaluateEntry    = "evaluate"
+	asyncSettleFunction   = "__gojaSettle"
+)
+
+var (
+	esmTopLevelAwaitMessage = "Top-level await"
+	// esbuild compiles imports in .mjs files in Node compatibility mode, where
+	// the default import of a compiled ES module is its whole namespace. Every
+	// module here is compiled, so that flag is dropped to keep default
+	// imports consistent across .js and .mjs files.
+	nodeModeImportPattern = regexp.MustCompile(`(__toESM\(require\("(?:[^"\\]|\\.)*"\)), 1\)`)
+)
+
+// ESMSourceLoader wraps base so that ES modules are compiled to CommonJS
+// before goja_nodejs require runs them. Import specifiers become require()
+// calls, so they resolve against the same module roots and native module
+// registrations as require: `import fs from "fs"` binds the fs module's
+// exports as the default export. Dynamic import() becomes a Promise of the
+// module namespace. Modules with top-level await evaluate asynchronously; use
+// Runtime.ImportModule to wait for an entry point.
+//
+// .mjs files are always ES modules and .cjs files never. A .js file is one
+// when the nearest package.json, read through base, declares "type":
+// "module"; without a "type" field it is one when esbuild parses it as an ES
+// module or finds a dynamic import() in it.
+//
+// The engine installs it around require.DefaultSourceLoader unless WithESM
+// disables it. Callers that pass their own loader through
+// require.WithLoader should wrap it with ESMSourceLoader to keep ESM support.
+func ESMSourceLoader(base require.SourceLoader) require.SourceLoader {
+	if base == nil {
+		base = require.DefaultSourceLoader
+	}
+	packages := &packageTypes{load: base, byDir: map[string]string{}}
+	return func(path string) ([]byte, error) {
+		src, err := base(path)
+		if err != nil || !isModuleSource(path, src, packages.lookup) {
+			return src, err
+		}
+		code, err := compileModule(path, src)
+		if err != nil {
+			return nil, err
+		}
+		// goja maps stack traces through the inline source map; reports
+		// need it to show the module's source.
+		return jserrors.Retain(path, code), nil
+	}
+}
+
+// isModuleSource reports whether path needs the ESM transform. packageType
+// returns the "type" of the package.json that governs path, or "".
+func isModuleSource(path string, src []byte, packageType func(string) string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mjs":
+		return true
+	case ".js", "":
+		switch packageType(path) {
+		case "module":
+			return true
+		case "commonjs":
+			return false
+		}
+		return parsesAsModule(path, src)
+	default:
+		return false
+	}
+}
+
+// packageTypes looks up and caches the "type" field of package.json files.
+type packageTypes struct {
+	load  require.SourceLoader
+	mu    sync.Mutex
+	byDir map[string]string
+}
+
+// lookup returns the "type" of the package.json nearest to path, or "" when
+// there is none or it has no such field.
+func (p *packageTypes) lookup(path string) string {
+	dir := filepath.Dir(path)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lookupDir(dir)
+}
+
+func (p *packageTypes) lookupDir(dir string) string {
+	if typ, ok := p.byDir[dir]; ok {
+		return typ
+	}
+	typ := ""
+	if data, err := p.load(filepath.Join(dir, "package.json")); err == nil {
+		var pkg struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal(data, &pkg) == nil {
+			typ = pkg.Type
+		}
+	} else if parent := filepath.Dir(dir); parent != dir {
+		typ = p.lookupDir(parent)
+	}
+	p.byDir[dir] = typ
+	return typ
+}
+
+// parsesAsModule reports whether esbuild parses src as an ES module, or finds
+// a dynamic import() in it, which goja cannot run either. Only sources in
+// which hasModuleKeyword finds import or export are parsed.
+func parsesAsModule(path string, src []byte) bool {
+	if !hasModuleKeyword(src) {
+		return false
+	}
+	result := api.Build(api.BuildOptions{
+		Stdin: &api.StdinOptions{
+			Contents:   string(src),
+			Sourcefile: path,
+			Loader:     api.LoaderJS,
+		},
+		Bundle:   true,
+		Write:    false,
+		Metafile: true,
+		Format:   api.FormatESModule,
+		Target:   api.ESNext,
+		Platform: api.PlatformNeutral,
+		Plugins:  []api.Plugin{externalImports},
+		LogLevel: api.LogLevelSilent,
+	})
+	// Sources esbuild cannot parse are left to goja, which reports them.
+	if len(result.Errors) > 0 {
+		return false
+	}
+	var meta struct {
+		Inputs map[string]struct {
+			Format  string `json:"format"`
+			Imports []struct {
+				Kind string `json:"kind"`
+			} `json:"imports"`
+		} `json:"inputs"`
+	}
+	if err := json.Unmarshal([]byte(result.Metafile), &meta); err != nil {
+		return false
+	}
+	for _, input := range meta.Inputs {
+		if input.Format == "esm" {
+			return true
+		}
+		for _, imp := range input.Imports {
+			if imp.Kind == "dynamic-import" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasModuleKeyword reports whether src uses import or export as a keyword.
+// It skips comments, strings, template literals, regular expressions after
+// punctuation, and the property names of member accesses and object
+// literals, so that CommonJS code such as module.exports is not parsed. It
+// errs towards true, and parsesAsModule confirms what it finds.
+func hasModuleKeyword(src []byte) bool {
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '/' && i+1 < len(src) && src[i+1] == '/':
+			i = skipPast(src, i+2, "\n") - 1
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			i = skipPast(src, i+2, "*/") - 1
+		case c == '"' || c == '\'' || c == '`':
+			i = skipString(src, i)
+		case c == '/' && strings.IndexByte("(,=:[!&|?{};", prevByte(src, i)) >= 0:
+			i = skipRegExp(src, i)
+		case isIdentByte(c) && (c < '0' || c > '9'):
+			start := i
+			for i < len(src) && isIdentByte(src[i]) {
+				i++
+			}
+			if word := string(src[start:i]); (word == "import" || word == "export") &&
+				prevByte(src, start) != '.' && nextByte(src, i) != ':' {
+				return true
+			}
+			i--
+		case c >= '0' && c <= '9':
+			for i+1 < len(src) && isIdentByte(src[i+1]) {
+				i++
+			}
+		}
+	}
+	return false
+}
+
+// skipPast returns the offset just after the first end at or after i, or
+// len(src).
+func skipPast(src []byte, i int, end string) int {
+	if j := bytes.Index(src[i:], []byte(end)); j >= 0 {
+		return i + j + len(end)
+	}
+	return len(src)
+}
+
+// skipString returns the offset of the quote that closes the string or
+// template literal opening at i. A quoted string also ends at a line break,
+// so that a quote in a regular expression only hides the rest of its line.
+func skipString(src []byte, i int) int {
+	quote := src[i]
+	for i++; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case quote:
+			return i
+		case '\n':
+			if quote != '`' {
+				return i
+			}
+		}
+	}
+	return len(src)
+}
+
+// skipRegExp returns the offset of the slash that closes the regular
+// expression literal opening at i, or of the line break that ends it.
+func skipRegExp(src []byte, i int) int {
+	class := false
+	for i++; i < len(src); i++ {
+		switch c := src[i]; {
+		case c == '\\':
+			i++
+		case c == '\n':
+			return i
+		case c == '[':
+			class = true
+		case c == ']':
+			class = false
+		case c == '/' && !class:
+			return i
+		}
+	}
+	return len(src)
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 ||
+		c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// prevByte and nextByte return the byte before i and the byte at or after
+// i, skipping whitespace, or 0.
+func prevByte(src []byte, i int) byte {
+	for i--; i >= 0; i-- {
+		if !isSpace(src[i]) {
+			return src[i]
+		}
+	}
+	return 0
+}
+
+func nextByte(src []byte, i int) byte {
+	for ; i < len(src); i++ {
+		if !isSpace(src[i]) {
+			return src[i]
+		}
+	}
+	return 0
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// externalImports keeps every import out of a bundle.
+var externalImports = api.Plugin{
+	Name: "esm-external",
+	Setup: func(build api.PluginBuild) {
+		build.OnResolve(api.OnResolveOptions{Filter: ".*"}, func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+			return api.OnResolveResult{Path: args.Path, External: true}, nil
+		})
+	},
+}
+
+// compileModule rewrites one ES module as CommonJS.
+func compileModule(path string, src []byte) ([]byte, error) {
+	result := api.Transform(string(src), api.TransformOptions{
+		Format:     api.FormatCommonJS,
+		Target:     esmTarget,
+		Supported:  map[string]bool{"dynamic-import": false},
+		Sourcemap:  api.SourceMapInline,
+		Sourcefile: path,
+		Loader:     api.LoaderJS,
+		LogLevel:   api.LogLevelSilent,
+	})
+	if len(result.Errors) == 0 {
+		return nodeModeImportPattern.ReplaceAll(result.Code, []byte("$1)")), nil
+	}
+	for _, msg := range result.Errors {
+		if strings.Contains(msg.Text, esmTopLevelAwaitMessage) {
+			return compileAsyncModule(path, src)
+		}
+	}
+	return nil, esmError(path, result.Errors)
+}
+
+// compileAsyncModule compiles a module that uses top-level await. esbuild
+// cannot emit CommonJS for it, so it bundles the module instead, behind an
+// entry that imports its namespace and hands it to the banner's settle
+// function. Each import becomes a bundled CommonJS module that calls
+// require(), so the bundle has no import or export statement left and runs
+// as the body of an async function. The module's export names, which the
+// namespace needs before evaluation finishes, come from a second entry point:
+// the module itself, whose exports esbuild reports in the metafile.
+func compileAsyncModule(path string, src []byte) ([]byte, error) {
+	contents := string(src)
+	plugin := api.Plugin{
+		Name: "esm-async",
+		Setup: func(build api.PluginBuild) {
+			build.OnResolve(api.OnResolveOptions{Filter: ".*"}, func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+				switch {
+				case args.Kind == api.ResolveEntryPoint,
+					args.Namespace == asyncModuleNamespace && args.Importer == asyncEvaluateEntry:
+					return api.OnResolveResult{Path: args.Path, Namespace: asyncModuleNamespace}, nil
+				case args.Namespace == asyncRequireNamespace:
+					return api.OnResolveResult{Path: args.Path, External: true}, nil
+				default:
+					// The path carries no extension, so that esbuild
+					// neither takes a .mjs import for ESM nor compiles
+					// its import in Node mode.
+					return api.OnResolveResult{
+						Path:       "require(" + jsString(args.Path) + ")",
+						Namespace:  asyncRequireNamespace,
+						PluginData: args.Path,
+					}, nil
+				}
+			})
+			build.OnLoad(api.OnLoadOptions{Filter: ".*", Namespace: asyncModuleNamespace}, func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+				code := contents
+				if args.Path == asyncEvaluateEntry {
+					code = `import * as namespace from "` + asyncModuleEntry + `"; ` + asyncSettleFunction + `(namespace);`
+				}
+				return api.OnLoadResult{Contents: &code, Loader: api.LoaderJS}, nil
+			})
+			build.OnLoad(api.OnLoadOptions{Filter: ".*", Namespace: asyncRequireNamespace}, func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+				code := "module.exports = require(" + jsString(args.PluginData.(string)) + ");"
+				return api.OnLoadResult{Contents: &code, Loader: api.LoaderJS}, nil
+			})
+		},
+	}
+	result := api.Build(api.BuildOptions{
+		EntryPoints: []string{asyncModuleEntry, asyncEvaluateEntry},
+		Bundle:      true,
+		Write:       false,
+		Metafile:    true,
+		Outdir:      "out",
+		Format:      api.FormatESModule,
+		Target:      esmTarget,
+		Supported:   map[string]bool{"top-level-await": true, "dynamic-import": false},
+		Platform:    api.PlatformNeutral,
+		Sourcemap:   api.SourceMapExternal,
+		Banner:      map[string]string{"js": asyncModuleBanner},
+		Footer:      map[string]string{"js": asyncModuleFooter},
+		Plugins:     []api.Plugin{plugin},
+		LogLevel:    api.LogLevelSilent,
+	})
+	if len(result.Errors) > 0 {
+		return nil, esmError(path, result.Errors)
+	}
+	var meta struct {
+		Outputs map[string]struct {
+			EntryPoint string   `json:"entryPoint"`
+			Exports    []string `json:"exports"`
+		} `json:"outputs"`
+	}
+	if err := json.Unmarshal([]byte(result.Metafile), &meta); err != nil {
+		return nil, fmt.Errorf("compile module %s: %w", path, err)
+	}
+	var names []string
+	for _, output := range meta.Outputs {
+		if output.EntryPoint == asyncModuleNamespace+":"+asyncModuleEntry {
+			names = output.Exports
+		}
+	}
+	var code, sourceMap []byte
+	for _, file := range result.OutputFiles {
+		switch filepath.Base(file.Path) {
+		case asyncEvaluateEntry + ".js":
+			code = file.Contents
+		case asyncEvaluateEntry + ".js.map":
+			sourceMap = file.Contents
+		}
+	}
+	if code == nil || sourceMap == nil {
+		return nil, fmt.Errorf("compile module %s: no output", path)
+	}
+	sourceMap, err := renameMapSource(sourceMap, asyncModuleNamespace+":"+asyncModuleEntry, path)
+	if err != nil {
+		return nil, fmt.Errorf("compile module %s: %w", path, err)
+	}
+	code, _ = splitSourceMap(code)
+	exportNames, _ := json.Marshal(names)
+	if names == nil {
+		exportNames = []byte("[]")
+	}
+	var out bytes.Buffer
+	out.WriteString("var __gojaModule = " + jsString(path) + ", __gojaExportNames = ")
+	out.Write(exportNames)
+	// On the banner's line, so that the mapped lines keep their numbers.
+	out.WriteString("; ")
+	out.Write(code)
+	out.WriteString("//# sourceMappingURL=data:application/json;base64,")
+	out.WriteString(base64.StdEncoding.EncodeToString(sourceMap))
+	out.WriteString("\n")
+	return out.Bytes(), nil
+}
+
+// asyncModuleBanner and asyncModuleFooter wrap the bundle of a module with
+// top-level await. The namespace has every export esbuild reported from the
+// start; exports only known at run time, such as star re-exports of
+// CommonJS modules, are added when evaluation finishes. Until then reading
+// an export throws a ReferenceError: a module that imports this one
+// statically is evaluated without waiting for it, and would otherwise see
+// undefined.
+const asyncModuleBanner = `var __gojaValues, __gojaNamespace = {};
+function __gojaExport(name) {
+  Object.defineProperty(__gojaNamespace, name, { get: function () {
+    if (!__gojaValues) throw new ReferenceError("Cannot access '" + name + "' of " + __gojaModule + " before its top-level await has finished");
+    return __gojaValues[name];
+  }, enumerable: true });
+}
+function ` + asyncSettleFunction + `(values) {
+  __gojaValues = values;
+  Object.keys(values).forEach(function (name) {
+    if (!Object.prototype.hasOwnProperty.call(__gojaNamespace, name)) __gojaExport(name);
+  });
+}
+__gojaExportNames.forEach(__gojaExport);
+Object.defineProperty(__gojaNamespace, "__esModule", { value: true });
+module.exports = __gojaNamespace;
+Object.defineProperty(__gojaNamespace, "` + esmEvaluationKey + `", { value: (async () => {`
+
+const asyncModuleFooter = `})().then(function () { return __gojaNamespace; }) });`
+
+// splitSourceMap splits the trailing source map comment off code.
+func splitSourceMap(code []byte) ([]byte, []byte) {
+	i := bytes.LastIndex(code, []byte("\n//# sourceMappingURL="))
+	if i < 0 {
+		return code, nil
+	}
+	return code[:i+1], code[i+1:]
+}
+
+// renameMapSource replaces the source from with to in a source map.
+func renameMapSource(sourceMap []byte, from, to string) ([]byte, error) {
+	var sm map[string]any
+	if err := json.Unmarshal(sourceMap, &sm); err != nil {
+		return nil, err
+	}
+	sources, _ := sm["sources"].([]any)
+	for i, source := range sources {
+		if source == from {
+			sources[i] = to
+		}
+	}
+	return json.Marshal(sm)
+}
+
+// jsString quotes s as a JavaScript string literal.
+func jsString(s string) string {
+	out, _ := json.Marshal(s)
+	return string(out)
+}
+
+func esmError(path string, messages []api.Message) error {
+	parts := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		text := msg.Text
+		if msg.Location != nil {
+			text = fmt.Sprintf("%d:%d: %s", msg.Location.Line, msg.Location.Column, text)
+		}
+		parts = append(parts, text)
+	}
+	return fmt.Errorf("compile module %s: %s", path, strings.Join(parts, "; "))
+}
+
+// ImportModule loads specifier as a module entry point, with the same
+// resolution as require, and waits until it has finished evaluating,
+// including any top-level await. It returns the module's exports, which for
+// an ES module is its namespace object. It must not be called on the owner
+// goroutine.
+func (r *Runtime) ImportModule(ctx context.Context, specifier string) (goja.Value, error) {
+	if r == nil || r.Owner == nil || r.Require == nil {
+		return nil, fmt.Errorf("runtime is not initialized")
+	}
+	var evaluation *goja.Promise
+	ret, err := r.Owner.Call(ctx, "engine.import", func(_ context.Context, _ *goja.Runtime) (any, error) {
+		exports, err := r.Require.Require(specifier)
+		if err != nil {
+			return nil, err
+		}
+		if obj, ok := exports.(*goja.Object); ok {
+			if value := obj.Get(esmEvaluationKey); value != nil {
+				evaluation, _ = value.Export().(*goja.Promise)
+			}
+		}
+		return exports, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if evaluation == nil {
+		return ret.(goja.Value), nil
+	}
+	settlement, err := runtimeowner.AwaitPromise(ctx, r.Owner, "engine.import.await", evaluation)
+	if err != nil {
+		return nil, err
+	}
+	if settlement.Rejected() {
+		return nil, jserrors.Rejected(settlement.Value)
+	}
+	return settlement.Value, nil
+}
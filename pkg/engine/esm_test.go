@@ -0,0 +1,265 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/require"
+	"github.com/go-go-golems/go-go-goja/pkg/jserrors"
+)
+
+func writeModuleFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func newESMRuntime(t *testing.T, opts ...Option) *Runtime {
+	t.Helper()
+	factory, err := NewRuntimeFactoryBuilder(opts...).UseModuleMiddleware(MiddlewareOnly("path")).Build()
+	if err != nil {
+		t.Fatalf("build factory: %v", err)
+	}
+	rt, err := factory.NewRuntime(WithStartupContext(context.Background()), WithLifetimeContext(context.Background()))
+	if err != nil {
+		t.Fatalf("new runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close(context.Background()) })
+	return rt
+}
+
+func exportJSON(t *testing.T, rt *Runtime, value goja.Value) string {
+	t.Helper()
+	ret, err := rt.Owner.Call(context.Background(), "esm.json", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		stringify, _ := goja.AssertFunction(vm.Get("JSON").ToObject(vm).Get("stringify"))
+		out, err := stringify(goja.Undefined(), value)
+		if err != nil {
+			return nil, err
+		}
+		return out.String(), nil
+	})
+	if err != nil {
+		t.Fatalf("stringify: %v", err)
+	}
+	return ret.(string)
+}
+
+func TestImportModuleInteroperatesWithRequireAndNativeModules(t *testing.T) {
+	dir := writeModuleFiles(t, map[string]string{
+		"main.mjs": `
+import path, { join } from "node:path";
+import greet, { name } from "./lib/greet.js";
+import * as util from "./lib/util.mjs";
+const legacy = require("./lib/legacy.js");
+export const joined = join("a", "b") === path.join("a", "b");
+export default greet(name) + " " + util.twice(legacy.value);
+export { fromCJS } from "./lib/legacy.js";
+`,
+		"lib/greet.js": `
+export const name = "esm";
+export default function greet(who) { return "hello " + who; }
+`,
+		"lib/util.mjs": `export const twice = n => n * 2;`,
+		"lib/legacy.js": `
+module.exports = { value: 21, fromCJS: true };
+`,
+	})
+	rt := newESMRuntime(t)
+
+	ns, err := rt.ImportModule(context.Background(), filepath.Join(dir, "main.mjs"))
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if got, want := exportJSON(t, rt, ns), `{"default":"hello esm 42","fromCJS":true,"joined":true}`; got != want {
+		t.Fatalf("namespace = %s, want %s", got, want)
+	}
+
+	// CommonJS code sees ES modules through require() as their namespace.
+	ret, err := rt.Owner.Call(context.Background(), "esm.require", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		value, err := rt.Require.Require(filepath.Join(dir, "lib", "greet.js"))
+		if err != nil {
+			return nil, err
+		}
+		return value.ToObject(vm).Get("name").String(), nil
+	})
+	if err != nil || ret != "esm" {
+		t.Fatalf("require(esm) = %v, %v", ret, err)
+	}
+}
+
+func TestImportModuleAwaitsTopLevelAwaitAndDynamicImport(t *testing.T) {
+	dir := writeModuleFiles(t, map[string]string{
+		"entry.js": `
+import { base } from "./base.mjs";
+const lazy = await import("./lazy.mjs");
+const value = await Promise.resolve(base + lazy.default);
+export { value as total };
+export const kind = typeof lazy.later;
+`,
+		"base.mjs": `export const base = 40;`,
+		"lazy.mjs": `export default 2; export function later() {}`,
+	})
+	rt := newESMRuntime(t)
+
+	ns, err := rt.ImportModule(context.Background(), filepath.Join(dir, "entry.js"))
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if got, want := exportJSON(t, rt, ns), `{"kind":"function","total":42}`; got != want {
+		t.Fatalf("namespace = %s, want %s", got, want)
+	}
+}
+
+func TestImportModuleReportsRejectedTopLevelAwait(t *testing.T) {
+	dir := writeModuleFiles(t, map[string]string{
+		"fail.mjs": `await Promise.resolve(); throw new Error("entry failed");`,
+	})
+	rt := newESMRuntime(t)
+
+	_, err := rt.ImportModule(context.Background(), filepath.Join(dir, "fail.mjs"))
+	if err == nil || !strings.Contains(err.Error(), "entry failed") {
+		t.Fatalf("err = %v, want entry failed", err)
+	}
+}
+
+func TestESMCanBeDisabledAndWrapsCustomLoaders(t *testing.T) {
+	source := []byte(`export const answer = 42;`)
+	loader := func(path string) ([]byte, error) {
+		if filepath.Base(path) == "virtual.mjs" {
+			return source, nil
+		}
+		return nil, require.ModuleFileDoesNotExistError
+	}
+
+	rt := newESMRuntime(t, WithRequireOptions(require.WithLoader(ESMSourceLoader(loader))))
+	ns, err := rt.ImportModule(context.Background(), "./virtual.mjs")
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if got := exportJSON(t, rt, ns); got != `{"answer":42}` {
+		t.Fatalf("namespace = %s", got)
+	}
+
+	disabled := newESMRuntime(t, WithESM(false), WithRequireOptions(require.WithLoader(loader)))
+	if _, err := disabled.ImportModule(context.Background(), "./virtual.mjs"); err == nil {
+		t.Fatalf("expected a syntax error with ESM disabled")
+	}
+}
+
+func TestIsModuleSource(t *testing.T) {
+	cases := []struct {
+		path    string
+		pkgType string
+		src     string
+		want    bool
+	}{
+		{"a.mjs", "", "module.exports = 1", true},
+		{"a.cjs", "", "export default 1", false},
+		{"a.json", "", `{"import": 1}`, false},
+		{"a.js", "", "module.exports = require('x')", false},
+		{"a.js", "", "// notes about import\nmodule.exports = 1", false},
+		{"a.js", "", "const s = `\nimport x from 'y'`;\nmodule.exports = s", false},
+		{"a.js", "", "exports.exportAll = function importAll() {}", false},
+		{"a.js", "", "import x from 'y'", true},
+		{"a.js", "", "const a = 1;\n  export { a }", true},
+		{"a.js", "", "const m = import('./m.js')", true},
+		{"a.js", "", "await Promise.resolve(1)", false},
+		{"a.js", "", "export {}; await Promise.resolve(1)", true},
+		{"a.js", "module", "module.exports = 1", true},
+		{"a.js", "commonjs", "export default 1", false},
+	}
+	for _, tc := range cases {
+		packageType := func(string) string { return tc.pkgType }
+		if got := isModuleSource(tc.path, []byte(tc.src), packageType); got != tc.want {
+			t.Errorf("isModuleSource(%q, %q, type %q) = %v, want %v", tc.path, tc.src, tc.pkgType, got, tc.want)
+		}
+	}
+}
+
+func TestESMFollowsPackageType(t *testing.T) {
+	dir := writeModuleFiles(t, map[string]string{
+		"esm/package.json": `{"type": "module"}`,
+		"esm/lib/value.js": `const value = 42; export default value;`,
+		"esm/lib/plain.js": `globalThis.plainEvaluated = true; export {};`,
+		"cjs/package.json": `{"name": "legacy"}`,
+		"cjs/value.js":     `module.exports = { value: "import" + " and export" };`,
+	})
+	rt := newESMRuntime(t)
+
+	ns, err := rt.ImportModule(context.Background(), filepath.Join(dir, "esm", "lib", "value.js"))
+	if err != nil {
+		t.Fatalf("import esm: %v", err)
+	}
+	if got := exportJSON(t, rt, ns); got != `{"default":42}` {
+		t.Fatalf("esm namespace = %s", got)
+	}
+	exports, err := rt.ImportModule(context.Background(), filepath.Join(dir, "cjs", "value.js"))
+	if err != nil {
+		t.Fatalf("import cjs: %v", err)
+	}
+	if got := exportJSON(t, rt, exports); got != `{"value":"import and export"}` {
+		t.Fatalf("cjs exports = %s", got)
+	}
+}
+
+func TestTopLevelAwaitModuleKeepsSourcePositions(t *testing.T) {
+	dir := writeModuleFiles(t, map[string]string{
+		"fail.mjs": "import { base } from \"./base.mjs\";\nawait Promise.resolve(base);\n\nthrow new Error(\"line four\");\n",
+		"base.mjs": `export const base = 1;`,
+	})
+	rt := newESMRuntime(t)
+
+	_, err := rt.ImportModule(context.Background(), filepath.Join(dir, "fail.mjs"))
+	if err == nil || !strings.Contains(err.Error(), "line four") {
+		t.Fatalf("err = %v, want line four", err)
+	}
+	if report, want := jserrors.Report(err), "fail.mjs:4:"; !strings.Contains(report, want) {
+		t.Fatalf("report = %s, want a frame at %s", report, want)
+	}
+}
+
+func TestHasModuleKeyword(t *testing.T) {
+	cases := map[string]bool{
+		"module.exports = { value: 1 }":                false,
+		"exports.exportAll = function importAll() {}":  false,
+		"const o = { import: 1, export: 2 }; o.import": false,
+		"// import x from 'y'\n/* export */ 'import'":  false,
+		"const s = `\nexport default 1`;":              false,
+		"const re = /'/; export default re;":           true,
+		"import x from 'y'":                            true,
+		"export { a }":                                 true,
+		"load(import('./m.js'))":                       true,
+		"x\n  .import(1); import.meta":                 true,
+	}
+	for src, want := range cases {
+		if got := hasModuleKeyword([]byte(src)); got != want {
+			t.Errorf("hasModuleKeyword(%q) = %v, want %v", src, got, want)
+		}
+	}
+}
+
+func TestStaticImportOfPendingTopLevelAwaitModuleThrows(t *testing.T) {
+	dir := writeModuleFiles(t, map[string]string{
+		"main.mjs": `import { value } from "./slow.mjs"; export const seen = value;`,
+		"slow.mjs": `export const value = await Promise.resolve(1);`,
+	})
+	rt := newESMRuntime(t)
+
+	_, err := rt.ImportModule(context.Background(), filepath.Join(dir, "main.mjs"))
+	if err == nil || !strings.Contains(err.Error(), "ReferenceError: Cannot access 'value' of "+filepath.Join(dir, "slow.mjs")) {
+		t.Fatalf("err = %v, want a ReferenceError for value", err)
+	}
+}
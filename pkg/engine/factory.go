@@ -11,22 +11,11 @@ import (
 	"github.com/dop251/goja_nodejs/eventloop"
 	"github.com/dop251/goja_nodejs/require"
 	"github.com/dop251/goja_nodejs/url"
+	"github.com/go-go-golems/go-go-goja/modules/events"
 	"github.com/go-go-golems/go-go-goja/pkg/runtimebridge"
 	"github.com/go-go-golems/go-go-goja/pkg/runtimeowner"
 )
 
-type runtimebridgeOwner struct {
-	owner runtimeowner.RuntimeOwner
-}
-
-func (o runtimebridgeOwner) Call(ctx context.Context, op string, fn func(context.Context, *goja.Runtime) (any, error)) (any, error) {
-	return o.owner.Call(ctx, op, runtimeowner.CallFunc(fn))
-}
-
-func (o runtimebridgeOwner) Post(ctx context.Context, op string, fn func(context.Context, *goja.Runtime)) error {
-	return o.owner.Post(ctx, op, runtimeowner.PostFunc(fn))
-}
-
 // RuntimeFactoryBuilder composes explicit module and runtime initializer configuration
 // before producing an immutable RuntimeFactory.
 type RuntimeFactoryBuilder struct {
@@ -173,6 +162,8 @@ func (b *RuntimeFactoryBuilder) Build() (*RuntimeFactory, error) {
 			implicitDefaultRegistryModules: b.settings.implicitDefaultRegistryModules,
 			dataOnlyDefaultRegistryModules: b.settings.dataOnlyDefaultRegistryModules,
 			includePanicStack:              b.settings.includePanicStack,
+			esm:                            b.settings.esm,
+			sandbox:                        b.settings.sandbox,
 		},
 		modules:             append([]RuntimeModuleRegistrar(nil), modules_...),
 		runtimeInitializers: append([]RuntimeInitializer(nil), inits...),
@@ -215,6 +206,7 @@ func (f *RuntimeFactory) NewRuntime(opts ...RuntimeOption) (*Runtime, error) {
 		Name:              "go-go-goja-runtime",
 		RecoverPanics:     true,
 		IncludePanicStack: f.settings.includePanicStack,
+		Budget:            settings.budget,
 	})
 	// #nosec G118 -- the runtime owns this cancel func and calls it on close and on setup failures.
 	runtimeCtx, runtimeCtxCancel := context.WithCancel(lifetimeCtx)
@@ -232,10 +224,16 @@ func (f *RuntimeFactory) NewRuntime(opts ...RuntimeOption) (*Runtime, error) {
 	runtimebridge.Store(vm, runtimebridge.RuntimeServices{
 		LifetimeContext: runtimeCtx,
 		Loop:            loop,
-		Owner:           runtimebridgeOwner{owner: owner},
+		Owner:           runtimeowner.Bridge(owner),
+		Sandbox:         f.settings.sandbox,
+		AddCloser:       rt.AddCloser,
 	})
 
-	reg := require.NewRegistry(f.settings.requireOptions...)
+	requireOptions := f.settings.requireOptions
+	if f.settings.esm {
+		requireOptions = append([]require.Option{require.WithLoader(ESMSourceLoader(require.DefaultSourceLoader))}, requireOptions...)
+	}
+	reg := require.NewRegistry(requireOptions...)
 	moduleCtx := &RuntimeModuleRegistrationContext{
 		Context:   startupCtx,
 		VM:        vm,
@@ -261,6 +259,7 @@ func (f *RuntimeFactory) NewRuntime(opts ...RuntimeOption) (*Runtime, error) {
 	console.Enable(vm)
 	buffer.Enable(vm)
 	url.Enable(vm)
+	events.EnableAbortController(vm)
 	if err := installPerformanceGlobals(vm); err != nil {
 		_ = rt.Close(startupCtx)
 		return nil, err
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/pkg/sandbox"
 )
 
 func TestDataOnlyModulesAreEnabledByDefault(t *testing.T) {
@@ -168,3 +169,60 @@ func TestMiddlewareOnlyEnablesSelectedHostModules(t *testing.T) {
 		t.Fatalf("named module availability = %v", ret)
 	}
 }
+
+func TestSandboxProfileGatesHostModules(t *testing.T) {
+	t.Setenv("SANDBOX_VISIBLE", "yes")
+	t.Setenv("SANDBOX_HIDDEN", "no")
+	profile, err := sandbox.ParseProfile([]byte(`
+name: test
+env: [SANDBOX_VISIBLE]
+exec:
+  - command: echo
+    args: [hello]
+database:
+  - driver: sqlite3
+    dsn: ":memory:"
+`), t.TempDir())
+	if err != nil {
+		t.Fatalf("parse profile: %v", err)
+	}
+	factory, err := NewRuntimeFactoryBuilder(WithSandbox(profile)).
+		UseModuleMiddleware(MiddlewareOnly("exec", "database")).
+		WithRuntimeInitializers(ProcessEnv()).
+		Build()
+	if err != nil {
+		t.Fatalf("build factory: %v", err)
+	}
+	rt, err := factory.NewRuntime()
+	if err != nil {
+		t.Fatalf("new runtime: %v", err)
+	}
+	defer func() { _ = rt.Close(context.Background()) }()
+
+	ret, err := rt.Owner.Call(context.Background(), "sandbox-modules", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		value, runErr := vm.RunString(`
+			const denied = (fn) => { try { fn(); return "allowed"; } catch (e) { return [e.name, e.code, e.capability, e.resource].join("|"); } };
+			const exec = require("exec");
+			const db = require("database");
+			JSON.stringify({
+				visible: process.env.SANDBOX_VISIBLE,
+				hidden: process.env.SANDBOX_HIDDEN === undefined,
+				echo: exec.run("echo", ["hello"]).trim(),
+				exec: denied(() => exec.run("echo", ["bye"])),
+				memory: denied(() => db.configure("sqlite3", ":memory:")),
+				file: denied(() => db.configure("sqlite3", "/tmp/other.db")),
+			});
+		`)
+		if runErr != nil {
+			return nil, runErr
+		}
+		return value.String(), nil
+	})
+	if err != nil {
+		t.Fatalf("run sandbox smoke: %v", err)
+	}
+	want := `{"visible":"yes","hidden":true,"echo":"hello","exec":"PermissionError|ERR_ACCESS_DENIED|exec|echo bye","memory":"allowed","file":"PermissionError|ERR_ACCESS_DENIED|database|sqlite3"}`
+	if ret != want {
+		t.Fatalf("sandbox state = %v, want %v", ret, want)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/dop251/goja_nodejs/eventloop"
 	"github.com/dop251/goja_nodejs/require"
 	"github.com/go-go-golems/go-go-goja/modules"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimebridge"
 	"github.com/go-go-golems/go-go-goja/pkg/runtimeowner"
 )
 
@@ -107,13 +108,15 @@ func (s namedDefaultRegistryModulesSpec) RegisterRuntimeModule(_ *RuntimeModuleR
 }
 
 var defaultRegistryModuleAliases = map[string][]string{
-	"crypto":   {"node:crypto"},
-	"database": {"db"},
-	"db":       {"database"},
-	"events":   {"node:events"},
-	"fs":       {"node:fs"},
-	"os":       {"node:os"},
-	"path":     {"node:path"},
+	"child_process": {"node:child_process"},
+	"crypto":        {"node:crypto"},
+	"database":      {"db"},
+	"db":            {"database"},
+	"events":        {"node:events"},
+	"fs":            {"node:fs"},
+	"os":            {"node:os"},
+	"path":          {"node:path"},
+	"stream":        {"node:stream"},
 }
 
 func expandDefaultRegistryModuleNames(names []string) []string {
@@ -161,7 +164,7 @@ func defaultRegistryModulesNamed(names ...string) RuntimeModuleRegistrar {
 	}
 }
 
-var dataOnlyDefaultRegistryModuleNames = []string{"crypto", "node:crypto", "events", "node:events", "path", "node:path", "time", "timer"}
+var dataOnlyDefaultRegistryModuleNames = []string{"crypto", "node:crypto", "events", "node:events", "path", "node:path", "stream", "node:stream", "time", "timer"}
 
 func dataOnlyDefaultRegistryModules() RuntimeModuleRegistrar {
 	return defaultRegistryModulesNamed(dataOnlyDefaultRegistryModuleNames...)
@@ -176,9 +179,10 @@ func DataOnlyDefaultRegistryModuleNames() []string {
 func processObject(vm *goja.Runtime) *goja.Object {
 	process := vm.NewObject()
 	env := map[string]string{}
+	profile := runtimebridge.Sandbox(vm)
 	for _, item := range os.Environ() {
 		key, value, ok := strings.Cut(item, "=")
-		if !ok {
+		if !ok || !profile.AllowsEnv(key) {
 			continue
 		}
 		env[key] = value
@@ -4,11 +4,14 @@ import (
 	"context"
 
 	"github.com/dop251/goja_nodejs/require"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimeowner"
+	"github.com/go-go-golems/go-go-goja/pkg/sandbox"
 )
 
 type runtimeOptions struct {
 	startupContext  context.Context
 	lifetimeContext context.Context
+	budget          runtimeowner.Budget
 }
 
 // RuntimeOption configures creation of one runtime instance.
@@ -31,6 +34,20 @@ func WithLifetimeContext(ctx context.Context) RuntimeOption {
 	}
 }
 
+// WithBudget limits the execution time, heap growth and stack depth of every
+// top-level owner call on the runtime, asynchronous module callbacks
+// included. Calls that exceed it fail with *runtimeowner.BudgetExceededError;
+// runtimeowner.WithBudget tightens or loosens the limits for a single call.
+// Code scheduled with Runtime.Loop.RunOnLoop bypasses the owner and the
+// budget. The heap growth limit measures the whole process, so it is only
+// reliable when the process runs a single runtime; see
+// runtimeowner.Budget.MaxHeapGrowth.
+func WithBudget(budget runtimeowner.Budget) RuntimeOption {
+	return func(o *runtimeOptions) {
+		o.budget = budget
+	}
+}
+
 func defaultRuntimeOptions() runtimeOptions {
 	return runtimeOptions{
 		startupContext:  context.Background(),
@@ -43,12 +60,15 @@ type builderSettings struct {
 	implicitDefaultRegistryModules bool
 	dataOnlyDefaultRegistryModules bool
 	includePanicStack              bool
+	esm                            bool
+	sandbox                        *sandbox.Profile
 }
 
 func defaultBuilderSettings() builderSettings {
 	return builderSettings{
 		implicitDefaultRegistryModules: true,
 		dataOnlyDefaultRegistryModules: true,
+		esm:                            true,
 	}
 }
 
@@ -88,3 +108,23 @@ func WithRecoveredPanicStack(enabled bool) Option {
 		s.includePanicStack = enabled
 	}
 }
+
+// WithESM controls whether require() compiles files that use import/export
+// (and .mjs files) as ES modules; see ESMSourceLoader. It is enabled by
+// default. A loader passed with require.WithLoader replaces the ESM-aware
+// default loader.
+func WithESM(enabled bool) Option {
+	return func(s *builderSettings) {
+		s.esm = enabled
+	}
+}
+
+// WithSandbox confines every runtime from the factory to profile. Native
+// modules check it before touching files, commands, the network, environment
+// variables or databases, and throw a PermissionError for anything the
+// profile does not grant. A nil profile leaves runtimes unrestricted.
+func WithSandbox(profile *sandbox.Profile) Option {
+	return func(s *builderSettings) {
+		s.sandbox = profile
+	}
+}
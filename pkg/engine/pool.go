@@ -0,0 +1,323 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-go-golems/go-go-goja/pkg/runtimeowner"
+)
+
+// ErrRuntimePoolClosed is returned by Acquire after the pool has been closed.
+var ErrRuntimePoolClosed = errors.New("runtime pool is closed")
+
+// ResetStrategy decides what happens to a runtime when its lease is released.
+type ResetStrategy string
+
+const (
+	// ResetReuse returns released runtimes to the pool as they are. Globals
+	// and module state written during one lease are visible to the next.
+	ResetReuse ResetStrategy = "reuse"
+	// ResetDiscard closes every released runtime and warms a fresh one in its
+	// place, so no state is shared between leases.
+	ResetDiscard ResetStrategy = "discard"
+)
+
+// RuntimePoolOptions configures a RuntimePool.
+type RuntimePoolOptions struct {
+	// Size is the number of idle runtimes kept warm. Zero means one.
+	Size int
+	// MaxSize caps the number of live runtimes, leased or idle. When the cap
+	// is reached Acquire waits for a release. Zero means no cap; a miss then
+	// creates a runtime on demand.
+	MaxSize int
+	// MaxUses recycles a runtime after it has been leased this many times.
+	// Zero means runtimes are never recycled for age.
+	MaxUses int
+	// Reset selects the release behavior. The zero value is ResetReuse.
+	Reset ResetStrategy
+	// Prepare runs once on every new runtime before it is leased, for
+	// example to load the application script.
+	Prepare func(ctx context.Context, rt *Runtime) error
+	// New creates the pool's runtimes in place of the factory, for hosts
+	// that build runtimes through their own factory. The factory passed to
+	// NewRuntimePool may then be nil.
+	New func(ctx context.Context) (*Runtime, error)
+}
+
+// RuntimePoolStats is a snapshot of pool counters. Hits count leases served
+// from a warm runtime, Misses count leases that had to create one, and
+// Recycled counts runtimes closed on release.
+type RuntimePoolStats struct {
+	Hits     uint64
+	Misses   uint64
+	Created  uint64
+	Recycled uint64
+	Idle     int
+	Leased   int
+}
+
+// RuntimePool keeps warm runtimes built from one frozen RuntimeFactory, or by
+// RuntimePoolOptions.New, and leases them out one caller at a time.
+type RuntimePool struct {
+	factory *RuntimeFactory
+	opts    RuntimePoolOptions
+	ctx     context.Context
+
+	mu      sync.Mutex
+	idle    []*pooledRuntime
+	leased  map[*Runtime]*pooledRuntime
+	live    int
+	warming int
+	closed  bool
+	changed chan struct{}
+	wg      sync.WaitGroup
+
+	hits     atomic.Uint64
+	misses   atomic.Uint64
+	created  atomic.Uint64
+	recycled atomic.Uint64
+}
+
+type pooledRuntime struct {
+	rt   *Runtime
+	uses int
+}
+
+// NewRuntimePool creates a pool and warms opts.Size runtimes before
+// returning. ctx is the lifetime parent of every pooled runtime.
+func NewRuntimePool(ctx context.Context, factory *RuntimeFactory, opts RuntimePoolOptions) (*RuntimePool, error) {
+	if factory == nil && opts.New == nil {
+		return nil, fmt.Errorf("factory is nil")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if opts.Size <= 0 {
+		opts.Size = 1
+	}
+	if opts.MaxSize > 0 && opts.MaxSize < opts.Size {
+		return nil, fmt.Errorf("runtime pool max size %d is smaller than size %d", opts.MaxSize, opts.Size)
+	}
+	switch opts.Reset {
+	case "":
+		opts.Reset = ResetReuse
+	case ResetReuse, ResetDiscard:
+	default:
+		return nil, fmt.Errorf("unknown runtime pool reset strategy %q", opts.Reset)
+	}
+	p := &RuntimePool{factory: factory, opts: opts, ctx: ctx, leased: map[*Runtime]*pooledRuntime{}, changed: make(chan struct{})}
+	for i := 0; i < opts.Size; i++ {
+		rt, err := p.newRuntime()
+		if err != nil {
+			_ = p.Close(ctx)
+			return nil, err
+		}
+		p.mu.Lock()
+		p.live++
+		p.idle = append(p.idle, &pooledRuntime{rt: rt})
+		p.mu.Unlock()
+	}
+	return p, nil
+}
+
+// Acquire leases a runtime. The caller owns it until Release.
+func (p *RuntimePool) Acquire(ctx context.Context) (*Runtime, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, ErrRuntimePoolClosed
+		}
+		if n := len(p.idle); n > 0 {
+			entry := p.idle[n-1]
+			p.idle = p.idle[:n-1]
+			p.leased[entry.rt] = entry
+			p.mu.Unlock()
+			p.hits.Add(1)
+			return entry.rt, nil
+		}
+		if p.opts.MaxSize == 0 || p.live < p.opts.MaxSize {
+			p.live++
+			p.mu.Unlock()
+			p.misses.Add(1)
+			rt, err := p.newRuntime()
+			p.mu.Lock()
+			if err != nil {
+				p.live--
+				p.notifyLocked()
+				p.mu.Unlock()
+				return nil, err
+			}
+			p.leased[rt] = &pooledRuntime{rt: rt}
+			p.mu.Unlock()
+			return rt, nil
+		}
+		changed := p.changed
+		p.mu.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// AcquireOwner leases a runtime and returns only its owner together with the
+// function that releases the lease. Releasing more than once is a no-op.
+func (p *RuntimePool) AcquireOwner(ctx context.Context) (runtimeowner.RuntimeOwner, func(), error) {
+	rt, err := p.Acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	var once sync.Once
+	return rt.Owner, func() { once.Do(func() { p.Release(rt) }) }, nil
+}
+
+// Release returns a leased runtime. Depending on the reset strategy and
+// MaxUses the runtime goes back to the idle set or is closed and replaced.
+func (p *RuntimePool) Release(rt *Runtime) {
+	p.mu.Lock()
+	entry, ok := p.leased[rt]
+	if !ok {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.leased, rt)
+	entry.uses++
+	retire := p.closed ||
+		p.opts.Reset == ResetDiscard ||
+		(p.opts.MaxUses > 0 && entry.uses >= p.opts.MaxUses) ||
+		len(p.idle)+p.warming >= p.opts.Size
+	if !retire {
+		p.idle = append(p.idle, entry)
+		p.notifyLocked()
+		p.mu.Unlock()
+		return
+	}
+	p.live--
+	p.notifyLocked()
+	p.recycled.Add(1)
+	if p.closed {
+		p.mu.Unlock()
+		closeRetiredRuntime(p.ctx, rt)
+		return
+	}
+	p.wg.Add(1)
+	p.mu.Unlock()
+
+	go func() {
+		defer p.wg.Done()
+		closeRetiredRuntime(p.ctx, rt)
+		p.warm()
+	}()
+}
+
+func closeRetiredRuntime(ctx context.Context, rt *Runtime) {
+	if err := rt.Close(context.WithoutCancel(ctx)); err != nil {
+		log.Debug().Err(err).Msg("close retired pooled runtime")
+	}
+}
+
+// warm creates one idle runtime if the pool is below its warm size.
+func (p *RuntimePool) warm() {
+	p.mu.Lock()
+	if p.closed || len(p.idle)+p.warming >= p.opts.Size || (p.opts.MaxSize > 0 && p.live >= p.opts.MaxSize) {
+		p.mu.Unlock()
+		return
+	}
+	p.live++
+	p.warming++
+	p.mu.Unlock()
+
+	rt, err := p.newRuntime()
+
+	p.mu.Lock()
+	p.warming--
+	if err != nil {
+		p.live--
+		p.notifyLocked()
+		p.mu.Unlock()
+		log.Warn().Err(err).Msg("warm pooled runtime")
+		return
+	}
+	if p.closed {
+		p.live--
+		p.mu.Unlock()
+		closeRetiredRuntime(p.ctx, rt)
+		return
+	}
+	p.idle = append(p.idle, &pooledRuntime{rt: rt})
+	p.notifyLocked()
+	p.mu.Unlock()
+}
+
+func (p *RuntimePool) newRuntime() (*Runtime, error) {
+	var rt *Runtime
+	var err error
+	if p.opts.New != nil {
+		rt, err = p.opts.New(p.ctx)
+	} else {
+		rt, err = p.factory.NewRuntime(WithStartupContext(p.ctx), WithLifetimeContext(p.ctx))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.opts.Prepare != nil {
+		if err := p.opts.Prepare(p.ctx, rt); err != nil {
+			_ = rt.Close(p.ctx)
+			return nil, fmt.Errorf("prepare pooled runtime: %w", err)
+		}
+	}
+	p.created.Add(1)
+	return rt, nil
+}
+
+// notifyLocked wakes Acquire callers waiting for capacity.
+func (p *RuntimePool) notifyLocked() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// Stats returns a snapshot of the pool counters.
+func (p *RuntimePool) Stats() RuntimePoolStats {
+	p.mu.Lock()
+	idle, leased := len(p.idle), len(p.leased)
+	p.mu.Unlock()
+	return RuntimePoolStats{
+		Hits:     p.hits.Load(),
+		Misses:   p.misses.Load(),
+		Created:  p.created.Load(),
+		Recycled: p.recycled.Load(),
+		Idle:     idle,
+		Leased:   leased,
+	}
+}
+
+// Close closes the idle runtimes and stops warming replacements. Runtimes
+// that are still leased are closed when they are released.
+func (p *RuntimePool) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.live -= len(idle)
+	p.notifyLocked()
+	p.mu.Unlock()
+
+	var retErr error
+	for _, entry := range idle {
+		retErr = errors.Join(retErr, entry.rt.Close(ctx))
+	}
+	p.wg.Wait()
+	return retErr
+}
@@ -0,0 +1,196 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+func newTestPool(t *testing.T, opts RuntimePoolOptions) *RuntimePool {
+	t.Helper()
+	factory, err := NewRuntimeFactoryBuilder().Build()
+	if err != nil {
+		t.Fatalf("build factory: %v", err)
+	}
+	pool, err := NewRuntimePool(context.Background(), factory, opts)
+	if err != nil {
+		t.Fatalf("new pool: %v", err)
+	}
+	t.Cleanup(func() { _ = pool.Close(context.Background()) })
+	return pool
+}
+
+func setGlobal(t *testing.T, rt *Runtime, name string, value any) {
+	t.Helper()
+	if _, err := rt.Owner.Call(context.Background(), "pool-test.set", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		return nil, vm.Set(name, value)
+	}); err != nil {
+		t.Fatalf("set %s: %v", name, err)
+	}
+}
+
+func getGlobal(t *testing.T, rt *Runtime, name string) any {
+	t.Helper()
+	ret, err := rt.Owner.Call(context.Background(), "pool-test.get", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		value := vm.Get(name)
+		if value == nil {
+			return nil, nil
+		}
+		return value.Export(), nil
+	})
+	if err != nil {
+		t.Fatalf("get %s: %v", name, err)
+	}
+	return ret
+}
+
+func TestRuntimePoolReusesWarmRuntimes(t *testing.T) {
+	prepared := 0
+	pool := newTestPool(t, RuntimePoolOptions{Size: 2, Prepare: func(_ context.Context, rt *Runtime) error {
+		prepared++
+		setGlobal(t, rt, "prepared", true)
+		return nil
+	}})
+	if prepared != 2 {
+		t.Fatalf("prepared=%d, want 2 warm runtimes", prepared)
+	}
+
+	first, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if getGlobal(t, first, "prepared") != true {
+		t.Fatalf("runtime was not prepared before lease")
+	}
+	setGlobal(t, first, "marker", "kept")
+	pool.Release(first)
+
+	again, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire again: %v", err)
+	}
+	if again != first || getGlobal(t, again, "marker") != "kept" {
+		t.Fatalf("expected the reuse strategy to hand back the same runtime")
+	}
+	second, _ := pool.Acquire(context.Background())
+	third, _ := pool.Acquire(context.Background())
+	pool.Release(again)
+	pool.Release(second)
+	pool.Release(third)
+
+	stats := pool.Stats()
+	if stats.Hits != 3 || stats.Misses != 1 || stats.Created != 3 {
+		t.Fatalf("stats=%+v, want 3 hits, 1 miss, 3 created", stats)
+	}
+	if stats.Idle != 2 || stats.Leased != 0 || stats.Recycled != 1 {
+		t.Fatalf("stats=%+v, want overflow runtime recycled down to 2 idle", stats)
+	}
+}
+
+func TestRuntimePoolDiscardAndMaxUses(t *testing.T) {
+	for _, opts := range []RuntimePoolOptions{{Reset: ResetDiscard}, {MaxUses: 1}} {
+		pool := newTestPool(t, opts)
+		rt, err := pool.Acquire(context.Background())
+		if err != nil {
+			t.Fatalf("acquire: %v", err)
+		}
+		setGlobal(t, rt, "marker", "leaked")
+		pool.Release(rt)
+
+		next := acquireEventually(t, pool)
+		if next == rt {
+			t.Fatalf("%+v: expected a fresh runtime after release", opts)
+		}
+		if marker := getGlobal(t, next, "marker"); marker != nil {
+			t.Fatalf("%+v: state leaked into fresh runtime: %v", opts, marker)
+		}
+		pool.Release(next)
+		if stats := pool.Stats(); stats.Recycled == 0 {
+			t.Fatalf("%+v: stats=%+v, want recycled runtimes", opts, stats)
+		}
+	}
+}
+
+// acquireEventually waits for the background replacement to warm so the lease
+// counts as a hit.
+func acquireEventually(t *testing.T, pool *RuntimePool) *Runtime {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for pool.Stats().Idle == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	rt, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	return rt
+}
+
+func TestRuntimePoolMaxSizeWaitsForRelease(t *testing.T) {
+	pool := newTestPool(t, RuntimePoolOptions{Size: 1, MaxSize: 1})
+	rt, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected acquire to wait for capacity, got %v", err)
+	}
+
+	got := make(chan *Runtime, 1)
+	go func() {
+		next, _ := pool.Acquire(context.Background())
+		got <- next
+	}()
+	pool.Release(rt)
+	select {
+	case next := <-got:
+		if next != rt {
+			t.Fatalf("expected waiting caller to receive the released runtime")
+		}
+		pool.Release(next)
+	case <-time.After(2 * time.Second):
+		t.Fatalf("waiting acquire was not woken by release")
+	}
+}
+
+func TestRuntimePoolCloseAndOptions(t *testing.T) {
+	factory, err := NewRuntimeFactoryBuilder().Build()
+	if err != nil {
+		t.Fatalf("build factory: %v", err)
+	}
+	if _, err := NewRuntimePool(context.Background(), factory, RuntimePoolOptions{Size: 2, MaxSize: 1}); err == nil {
+		t.Fatalf("expected max size below size to fail")
+	}
+	if _, err := NewRuntimePool(context.Background(), factory, RuntimePoolOptions{Reset: "scrub"}); err == nil {
+		t.Fatalf("expected unknown reset strategy to fail")
+	}
+	if _, err := NewRuntimePool(context.Background(), factory, RuntimePoolOptions{Prepare: func(context.Context, *Runtime) error {
+		return errors.New("boom")
+	}}); err == nil {
+		t.Fatalf("expected prepare failure to fail pool creation")
+	}
+
+	pool, err := NewRuntimePool(context.Background(), factory, RuntimePoolOptions{})
+	if err != nil {
+		t.Fatalf("new pool: %v", err)
+	}
+	rt, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if err := pool.Close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if _, err := pool.Acquire(context.Background()); !errors.Is(err, ErrRuntimePoolClosed) {
+		t.Fatalf("expected ErrRuntimePoolClosed, got %v", err)
+	}
+	pool.Release(rt)
+	if !rt.Owner.IsClosed() {
+		t.Fatalf("expected runtime released after close to be closed")
+	}
+}
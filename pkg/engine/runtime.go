@@ -17,6 +17,7 @@ import (
 	// themselves in modules.DefaultRegistry. A plain NewRuntimeFactoryBuilder().Build() exposes
 	// this default registry; callers can restrict it with UseModuleMiddleware
 	// (e.g. MiddlewareSafe or MiddlewareOnly).
+	_ "github.com/go-go-golems/go-go-goja/modules/childprocess"
 	_ "github.com/go-go-golems/go-go-goja/modules/crypto"
 	_ "github.com/go-go-golems/go-go-goja/modules/database"
 	_ "github.com/go-go-golems/go-go-goja/modules/events"
@@ -24,6 +25,7 @@ import (
 	_ "github.com/go-go-golems/go-go-goja/modules/fs"
 	_ "github.com/go-go-golems/go-go-goja/modules/os"
 	_ "github.com/go-go-golems/go-go-goja/modules/path"
+	_ "github.com/go-go-golems/go-go-goja/modules/stream"
 	_ "github.com/go-go-golems/go-go-goja/modules/time"
 	_ "github.com/go-go-golems/go-go-goja/modules/timer"
 	_ "github.com/go-go-golems/go-go-goja/modules/yaml"
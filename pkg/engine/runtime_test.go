@@ -2,13 +2,16 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/dop251/goja"
 	"github.com/dop251/goja_nodejs/require"
 	"github.com/go-go-golems/go-go-goja/pkg/runtimebridge"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimeowner"
 )
 
 func TestBuilderWithRequireOptions(t *testing.T) {
@@ -180,3 +183,79 @@ func (i testRuntimeInitializer) InitRuntime(ctx *RuntimeInitializationContext) e
 	}
 	return i.fn(ctx)
 }
+
+func TestRuntimeBudgetStopsRunawayScript(t *testing.T) {
+	factory, err := NewRuntimeFactoryBuilder().Build()
+	if err != nil {
+		t.Fatalf("build factory: %v", err)
+	}
+	rt, err := factory.NewRuntime(WithBudget(runtimeowner.Budget{MaxExecutionTime: 20 * time.Millisecond, MaxStackDepth: 100}))
+	if err != nil {
+		t.Fatalf("new runtime: %v", err)
+	}
+	defer func() {
+		_ = rt.Close(context.Background())
+	}()
+
+	for _, src := range []string{"while (true) {}", "(function f() { f() })()"} {
+		_, err := rt.Owner.Call(context.Background(), "budget-test", func(_ context.Context, vm *goja.Runtime) (any, error) {
+			return vm.RunString(src)
+		})
+		var exceeded *runtimeowner.BudgetExceededError
+		if !errors.As(err, &exceeded) {
+			t.Fatalf("%s: expected BudgetExceededError, got %v", src, err)
+		}
+	}
+}
+
+// Modules run asynchronous callbacks, such as timer.sleep resolutions and
+// fetch responses, by posting them to the owner, so the runtime budget
+// interrupts them and the promise jobs they queue.
+func TestRuntimeBudgetStopsRunawayLoopCallbacks(t *testing.T) {
+	factory, err := NewRuntimeFactoryBuilder().Build()
+	if err != nil {
+		t.Fatalf("build factory: %v", err)
+	}
+	rt, err := factory.NewRuntime(WithBudget(runtimeowner.Budget{MaxExecutionTime: 20 * time.Millisecond}))
+	if err != nil {
+		t.Fatalf("new runtime: %v", err)
+	}
+	defer func() {
+		_ = rt.Close(context.Background())
+	}()
+
+	posted := make(chan string, 2)
+	_, err = rt.Owner.Call(context.Background(), "budget-loop.setup", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		err := vm.Set("later", func(name string, fn goja.Callable) {
+			go func() {
+				_ = rt.Owner.Post(context.Background(), "budget-loop."+name, func(context.Context, *goja.Runtime) {
+					_, _ = fn(goja.Undefined())
+					posted <- name
+				})
+			}()
+		})
+		if err != nil {
+			return nil, err
+		}
+		return vm.RunString(`
+			later("callback", () => { for (;;) {} });
+			later("promise-job", () => { Promise.resolve().then(() => { for (;;) {} }) });
+		`)
+	})
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	for range 2 {
+		select {
+		case <-posted:
+		case <-time.After(5 * time.Second):
+			t.Fatal("runaway loop callback was not interrupted")
+		}
+	}
+	ret, err := rt.Owner.Call(context.Background(), "budget-loop.after", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		return vm.RunString("1 + 1")
+	})
+	if err != nil || ret.(goja.Value).ToInteger() != 2 {
+		t.Fatalf("runtime unusable after interrupted callbacks: %v %v", ret, err)
+	}
+}
@@ -0,0 +1,490 @@
+// Package fswatch wraps fsnotify with recursive directory watching, glob
+// filters and per-path debouncing. It has no JavaScript dependencies; the
+// jsevents fswatch helper and the fs module's watch() both build on it.
+package fswatch
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Options configures one watch.
+type Options struct {
+	// Recursive watches every directory below a directory path, adding
+	// directories as they are created. It allocates one OS watch per
+	// directory.
+	Recursive bool
+	// Debounce coalesces events for the same path that arrive within this
+	// window into one event. Zero delivers every event.
+	Debounce time.Duration
+	// Include, when set, limits events to relative names matching one of
+	// these globs. "**" matches any number of path segments.
+	Include []string
+	// Exclude drops events for matching relative names and stops recursive
+	// traversal into matching directories.
+	Exclude []string
+	// IgnorePath excludes host paths from recursive traversal and event
+	// delivery.
+	IgnorePath func(path string) bool
+}
+
+// Event is one filesystem change.
+type Event struct {
+	WatchPath string
+	Name      string
+	// RelativeName is Name relative to WatchPath with forward slashes. For a
+	// watched file it is the file's base name.
+	RelativeName string
+	Op           fsnotify.Op
+	Recursive    bool
+	Debounced    bool
+	// Count is the number of raw events coalesced into this one.
+	Count int
+}
+
+// Has reports whether the event includes op.
+func (e Event) Has(op fsnotify.Op) bool { return e.Op.Has(op) }
+
+// Handler receives watch callbacks on background goroutines. Nil fields are
+// skipped.
+type Handler struct {
+	Event func(Event)
+	Error func(error)
+	// Closed runs when fsnotify shuts the watcher down on its own, not when
+	// Close is called.
+	Closed func()
+}
+
+// Watch is a running watch started by Start.
+type Watch struct {
+	state  *state
+	cancel context.CancelFunc
+}
+
+// Start watches path until ctx is done or Close is called.
+func Start(ctx context.Context, path string, opts Options, handler Handler) (*Watch, error) {
+	if err := ValidatePatterns(opts.Include, "include"); err != nil {
+		return nil, err
+	}
+	if err := ValidatePatterns(opts.Exclude, "exclude"); err != nil {
+		return nil, err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("fswatch: create watcher: %w", err)
+	}
+	s := newState(path, opts, watcher, handler)
+	if err := s.start(); err != nil {
+		_ = watcher.Close()
+		return nil, err
+	}
+	watchCtx, cancel := context.WithCancel(ctx)
+	go s.run(watchCtx)
+	return &Watch{state: s, cancel: cancel}, nil
+}
+
+// Close stops the watch. Pending debounced events are dropped.
+func (w *Watch) Close() {
+	w.state.stopDebounceTimers()
+	w.cancel()
+}
+
+// ValidatePatterns checks that every pattern is a valid glob. name labels the
+// option in the error.
+func ValidatePatterns(patterns []string, name string) error {
+	for i, pattern := range patterns {
+		for _, segment := range splitGlobPath(pattern) {
+			if segment == "**" {
+				continue
+			}
+			if _, err := pathpkg.Match(segment, ""); err != nil {
+				return fmt.Errorf("fswatch: invalid %s glob at index %d: %w", name, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Match reports whether the slash-separated relative path rel matches
+// pattern. Segments match with path.Match, and a "**" segment matches any
+// number of segments.
+func Match(pattern, rel string) bool {
+	return matchGlobSegments(splitGlobPath(pattern), splitGlobPath(rel))
+}
+
+type globMatcher struct {
+	include []string
+	exclude []string
+}
+
+type pendingEvent struct {
+	Event fsnotify.Event
+	Count int
+}
+
+type state struct {
+	watchPath  string
+	watchIsDir bool
+	opts       Options
+	matcher    globMatcher
+	watcher    *fsnotify.Watcher
+	handler    Handler
+
+	mu           sync.Mutex
+	watchedPaths map[string]struct{}
+
+	debounceMu         sync.Mutex
+	pending            map[string]pendingEvent
+	timers             map[string]*time.Timer
+	debounceGeneration map[string]uint64
+	debounceClosed     bool
+}
+
+func newState(watchPath string, opts Options, watcher *fsnotify.Watcher, handler Handler) *state {
+	return &state{
+		watchPath:          watchPath,
+		opts:               opts,
+		matcher:            globMatcher{include: opts.Include, exclude: opts.Exclude},
+		watcher:            watcher,
+		handler:            handler,
+		watchedPaths:       map[string]struct{}{},
+		pending:            map[string]pendingEvent{},
+		timers:             map[string]*time.Timer{},
+		debounceGeneration: map[string]uint64{},
+	}
+}
+
+func (s *state) start() error {
+	info, err := os.Lstat(s.watchPath)
+	if err != nil {
+		return fmt.Errorf("fswatch: stat %q: %w", s.watchPath, err)
+	}
+	if s.opts.IgnorePath != nil && s.opts.IgnorePath(s.watchPath) {
+		return fmt.Errorf("fswatch: path %q is ignored", s.watchPath)
+	}
+	s.watchIsDir = info.IsDir()
+	if s.opts.Recursive && info.IsDir() {
+		return s.addRecursive(s.watchPath)
+	}
+	return s.addWatchPath(s.watchPath)
+}
+
+func (s *state) run(ctx context.Context) {
+	defer func() {
+		s.stopDebounceTimers()
+		_ = s.watcher.Close()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-s.watcher.Events:
+			if !ok {
+				s.closed()
+				return
+			}
+			s.handleEvent(event)
+		case err, ok := <-s.watcher.Errors:
+			if !ok {
+				s.closed()
+				return
+			}
+			s.emitError(err)
+		}
+	}
+}
+
+func (s *state) closed() {
+	if s.handler.Closed != nil {
+		s.handler.Closed()
+	}
+}
+
+func (s *state) handleEvent(event fsnotify.Event) {
+	if s.opts.Recursive && event.Has(fsnotify.Create) {
+		s.maybeAddCreatedDirectory(event.Name)
+	}
+	if s.opts.Recursive && (event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)) {
+		s.removeWatchedPath(event.Name)
+	}
+	if !s.allowsEvent(event.Name) {
+		return
+	}
+	s.dispatch(event)
+}
+
+func (s *state) addRecursive(root string) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if entry.Type()&fs.ModeSymlink != 0 {
+			return filepath.SkipDir
+		}
+		if s.opts.IgnorePath != nil && s.opts.IgnorePath(path) {
+			return filepath.SkipDir
+		}
+		rel := s.relativeName(path)
+		if !s.matcher.ShouldDescend(rel) {
+			return filepath.SkipDir
+		}
+		return s.addWatchPath(path)
+	})
+}
+
+func (s *state) addWatchPath(rawPath string) error {
+	cleaned := filepath.Clean(rawPath)
+	s.mu.Lock()
+	if _, ok := s.watchedPaths[cleaned]; ok {
+		s.mu.Unlock()
+		return nil
+	}
+	s.mu.Unlock()
+
+	if err := s.watcher.Add(cleaned); err != nil {
+		return fmt.Errorf("fswatch: watch %q: %w", cleaned, err)
+	}
+
+	s.mu.Lock()
+	s.watchedPaths[cleaned] = struct{}{}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *state) maybeAddCreatedDirectory(rawPath string) {
+	info, err := os.Lstat(rawPath)
+	if err != nil || !info.IsDir() || info.Mode()&fs.ModeSymlink != 0 {
+		return
+	}
+	if s.opts.IgnorePath != nil && s.opts.IgnorePath(rawPath) {
+		return
+	}
+	if !s.matcher.ShouldDescend(s.relativeName(rawPath)) {
+		return
+	}
+	if err := s.addRecursive(rawPath); err != nil {
+		s.emitError(err)
+	}
+}
+
+func (s *state) removeWatchedPath(rawPath string) {
+	cleaned := filepath.Clean(rawPath)
+	s.mu.Lock()
+	_, ok := s.watchedPaths[cleaned]
+	if ok {
+		delete(s.watchedPaths, cleaned)
+	}
+	s.mu.Unlock()
+	if ok {
+		_ = s.watcher.Remove(cleaned)
+	}
+}
+
+func (s *state) allowsEvent(name string) bool {
+	if s.opts.IgnorePath != nil && s.opts.IgnorePath(name) {
+		return false
+	}
+	return s.matcher.Allows(s.relativeName(name))
+}
+
+func (s *state) dispatch(event fsnotify.Event) {
+	if s.opts.Debounce <= 0 {
+		s.emitEvent(event, 1, false)
+		return
+	}
+	s.dispatchDebounced(event)
+}
+
+func (s *state) dispatchDebounced(event fsnotify.Event) {
+	key := filepath.Clean(event.Name)
+	s.debounceMu.Lock()
+	if s.debounceClosed {
+		s.debounceMu.Unlock()
+		return
+	}
+	pending, ok := s.pending[key]
+	if ok {
+		pending.Event.Op |= event.Op
+		pending.Count++
+	} else {
+		pending = pendingEvent{Event: event, Count: 1}
+	}
+	s.pending[key] = pending
+	s.debounceGeneration[key]++
+	generation := s.debounceGeneration[key]
+	if timer, ok := s.timers[key]; ok {
+		timer.Stop()
+	}
+	s.timers[key] = time.AfterFunc(s.opts.Debounce, func() {
+		s.flushDebounced(key, generation)
+	})
+	s.debounceMu.Unlock()
+}
+
+func (s *state) flushDebounced(key string, generation uint64) {
+	s.debounceMu.Lock()
+	if s.debounceClosed || s.debounceGeneration[key] != generation {
+		s.debounceMu.Unlock()
+		return
+	}
+	pending, ok := s.pending[key]
+	if ok {
+		delete(s.pending, key)
+		delete(s.debounceGeneration, key)
+	}
+	if timer, ok := s.timers[key]; ok {
+		timer.Stop()
+		delete(s.timers, key)
+	}
+	s.debounceMu.Unlock()
+	if !ok {
+		return
+	}
+	s.emitEvent(pending.Event, pending.Count, true)
+}
+
+func (s *state) stopDebounceTimers() {
+	s.debounceMu.Lock()
+	defer s.debounceMu.Unlock()
+	s.debounceClosed = true
+	for key, timer := range s.timers {
+		timer.Stop()
+		delete(s.timers, key)
+	}
+	for key := range s.pending {
+		delete(s.pending, key)
+	}
+	for key := range s.debounceGeneration {
+		delete(s.debounceGeneration, key)
+	}
+}
+
+func (s *state) emitEvent(event fsnotify.Event, count int, debounced bool) {
+	if s.handler.Event == nil {
+		return
+	}
+	s.handler.Event(Event{
+		WatchPath:    s.watchPath,
+		Name:         event.Name,
+		RelativeName: s.relativeName(event.Name),
+		Op:           event.Op,
+		Recursive:    s.opts.Recursive,
+		Debounced:    debounced,
+		Count:        count,
+	})
+}
+
+func (s *state) emitError(err error) {
+	if s.handler.Error != nil {
+		s.handler.Error(err)
+	}
+}
+
+func (s *state) relativeName(name string) string {
+	rel, err := filepath.Rel(s.watchPath, name)
+	if err != nil {
+		return ""
+	}
+	if rel == "." {
+		if s.watchIsDir {
+			return ""
+		}
+		return filepath.ToSlash(filepath.Base(s.watchPath))
+	}
+	return filepath.ToSlash(rel)
+}
+
+func (m globMatcher) Allows(rel string) bool {
+	rel = normalizeGlobPath(rel)
+	if len(m.include) > 0 {
+		matched := false
+		for _, pattern := range m.include {
+			if Match(pattern, rel) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	for _, pattern := range m.exclude {
+		if Match(pattern, rel) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m globMatcher) ShouldDescend(rel string) bool {
+	rel = normalizeGlobPath(rel)
+	for _, pattern := range m.exclude {
+		if Match(pattern, rel) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchGlobSegments(patternSegments, relSegments []string) bool {
+	if len(patternSegments) == 0 {
+		return len(relSegments) == 0
+	}
+	if patternSegments[0] == "**" {
+		if matchGlobSegments(patternSegments[1:], relSegments) {
+			return true
+		}
+		for i := range relSegments {
+			if matchGlobSegments(patternSegments[1:], relSegments[i+1:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(relSegments) == 0 {
+		return false
+	}
+	matched, err := pathpkg.Match(patternSegments[0], relSegments[0])
+	if err != nil || !matched {
+		return false
+	}
+	return matchGlobSegments(patternSegments[1:], relSegments[1:])
+}
+
+func splitGlobPath(value string) []string {
+	value = normalizeGlobPath(value)
+	if value == "" {
+		return nil
+	}
+	parts := strings.Split(value, "/")
+	ret := parts[:0]
+	for _, part := range parts {
+		if part != "" && part != "." {
+			ret = append(ret, part)
+		}
+	}
+	return ret
+}
+
+func normalizeGlobPath(value string) string {
+	value = filepath.ToSlash(strings.TrimSpace(value))
+	value = strings.TrimPrefix(value, "./")
+	value = strings.Trim(value, "/")
+	if value == "." {
+		return ""
+	}
+	return value
+}
@@ -82,6 +82,18 @@ func (r *RouteNeedsPolicy) RateLimit(spec RateLimitSpec) *RouteNeedsPolicy {
 	return r
 }
 
+// BodyLimit caps the request body at limit bytes; larger bodies get 413.
+func (r *RouteNeedsPolicy) BodyLimit(limit int64) *RouteNeedsPolicy {
+	r.builder.plan.Body.Limit = limit
+	return r
+}
+
+// StreamBody leaves the request body unread so the handler can consume it.
+func (r *RouteNeedsPolicy) StreamBody() *RouteNeedsPolicy {
+	r.builder.plan.Body.Stream = true
+	return r
+}
+
 func (r *RouteNeedsPolicy) Allow(action string) *RouteNeedsHandler {
 	r.builder.plan.Action = strings.TrimSpace(action)
 	return &RouteNeedsHandler{builder: r.builder}
@@ -102,6 +114,16 @@ func (r *RouteNeedsHandler) RateLimit(spec RateLimitSpec) *RouteNeedsHandler {
 	return r
 }
 
+func (r *RouteNeedsHandler) BodyLimit(limit int64) *RouteNeedsHandler {
+	r.builder.plan.Body.Limit = limit
+	return r
+}
+
+func (r *RouteNeedsHandler) StreamBody() *RouteNeedsHandler {
+	r.builder.plan.Body.Stream = true
+	return r
+}
+
 // Handle validates the accumulated plan and registers handler as a planned Go
 // HTTP route on the backing host.
 func (r *RouteNeedsHandler) Handle(handler PlannedHTTPHandler) error {
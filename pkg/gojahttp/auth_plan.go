@@ -71,6 +71,7 @@ type RoutePlan struct {
 	CSRF       CSRFSpec
 	Audit      AuditSpec
 	RateLimits []RateLimitSpec
+	Body       BodySpec
 }
 
 // AuthRequirement constrains which authenticated principal families may enter
@@ -296,6 +297,9 @@ func ValidateRoutePlan(plan RoutePlan) (RoutePlan, error) {
 	if plan.Pattern == "" {
 		return RoutePlan{}, fmt.Errorf("planned route pattern is required")
 	}
+	if _, err := parsePattern(plan.Pattern); err != nil {
+		return RoutePlan{}, err
+	}
 
 	for i := range plan.RateLimits {
 		limit, err := normalizeRateLimitSpec(plan, plan.RateLimits[i])
@@ -330,6 +334,15 @@ func ValidateRoutePlan(plan RoutePlan) (RoutePlan, error) {
 		return RoutePlan{}, fmt.Errorf("planned route %s %s must declare .public() or .auth(...) before .handle(...)", plan.Method, plan.Pattern)
 	}
 
+	if plan.Body.Limit < 0 {
+		return RoutePlan{}, fmt.Errorf("planned route %s %s body limit must not be negative", plan.Method, plan.Pattern)
+	}
+	if plan.Body.Stream {
+		if err := validateStreamedBodyPlan(plan); err != nil {
+			return RoutePlan{}, err
+		}
+	}
+
 	pathParams := pathParamSet(plan.Pattern)
 	for i := range plan.Resources {
 		resource := &plan.Resources[i]
@@ -353,6 +366,24 @@ func ValidateRoutePlan(plan RoutePlan) (RoutePlan, error) {
 	return plan, nil
 }
 
+// validateStreamedBodyPlan rejects plans that read body values during
+// enforcement, because a streamed body is not read until the handler runs.
+func validateStreamedBodyPlan(plan RoutePlan) error {
+	for _, resource := range plan.Resources {
+		if resource.ID.Kind == ValueSourceBody || (resource.Tenant != nil && resource.Tenant.Kind == ValueSourceBody) {
+			return fmt.Errorf("planned route %s %s streams its body and cannot resolve resource %q from the body", plan.Method, plan.Pattern, resource.Name)
+		}
+	}
+	for _, limit := range plan.RateLimits {
+		for _, part := range limit.KeyParts {
+			if part.Kind == RateLimitKeyBodyField {
+				return fmt.Errorf("planned route %s %s streams its body and cannot key rate limit %q by a body field", plan.Method, plan.Pattern, limit.Policy)
+			}
+		}
+	}
+	return nil
+}
+
 func normalizeAuthRequirements(in []AuthRequirement) ([]AuthRequirement, error) {
 	if len(in) == 0 {
 		return nil, nil
@@ -465,12 +496,13 @@ func validateValueSource(source ValueSource, pathParams map[string]struct{}, lab
 
 func pathParamSet(pattern string) map[string]struct{} {
 	out := map[string]struct{}{}
-	for _, part := range splitPath(pattern) {
-		if strings.HasPrefix(part, ":") {
-			name := strings.TrimPrefix(part, ":")
-			if name != "" {
-				out[name] = struct{}{}
-			}
+	segments, err := parsePattern(pattern)
+	if err != nil {
+		return out
+	}
+	for _, name := range captureNames(segments) {
+		if name != "" {
+			out[name] = struct{}{}
 		}
 	}
 	return out
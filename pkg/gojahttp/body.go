@@ -1,11 +1,23 @@
 package gojahttp
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"os"
 	"strings"
+	"sync"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/buffer"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimeowner"
 )
 
 const (
@@ -13,51 +25,225 @@ const (
 	multipartFormMemoryLimit = 32 << 20
 )
 
-func parseBody(r *http.Request) (any, string, error) {
-	if r.Body == nil {
-		return nil, "", nil
+// ErrBodyTooLarge is returned when a request body exceeds the route's body
+// limit. The host answers such requests with 413.
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// BodySpec configures how the host reads a planned route's request body.
+// Limit caps the body size in bytes; zero uses the 64 MiB host default. When
+// Stream is set the body is left unread and handlers consume it through
+// req.stream(), so ctx.body and rawBody stay empty.
+type BodySpec struct {
+	Limit  int64
+	Stream bool
+}
+
+func (s BodySpec) limit() int64 {
+	if s.Limit > 0 {
+		return s.Limit
 	}
-	limited := &io.LimitedReader{R: r.Body, N: maxRequestBodyBytes + 1}
-	data, err := io.ReadAll(limited)
-	if err != nil {
-		return nil, "", err
+	return maxRequestBodyBytes
+}
+
+// UploadedFile is one file part of a multipart/form-data request. Files are
+// kept in memory until the request's multipart memory budget is spent; later
+// files are spooled to a temporary file at Path, which is removed when the
+// request finishes.
+type UploadedFile struct {
+	Field       string
+	Filename    string
+	ContentType string
+	Size        int64
+	Path        string
+
+	data []byte
+}
+
+// Open returns a reader over the file contents.
+func (f *UploadedFile) Open() (io.ReadCloser, error) {
+	if f.Path != "" {
+		return os.Open(f.Path)
+	}
+	return io.NopCloser(bytes.NewReader(f.data)), nil
+}
+
+// Bytes reads the whole file into memory.
+func (f *UploadedFile) Bytes() ([]byte, error) {
+	if f.Path != "" {
+		return os.ReadFile(f.Path)
+	}
+	return f.data, nil
+}
+
+func (f *UploadedFile) Map() map[string]any {
+	return map[string]any{
+		"field":       f.Field,
+		"filename":    f.Filename,
+		"contentType": f.ContentType,
+		"size":        f.Size,
+		"path":        f.Path,
+	}
+}
+
+// requestBody is the parsed form of a request body.
+type requestBody struct {
+	value  any
+	raw    string
+	files  []*UploadedFile
+	stream *bodyStream
+}
+
+func readRequestBody(r *http.Request, spec BodySpec) (*requestBody, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return &requestBody{stream: newBodyStream(io.NopCloser(bytes.NewReader(nil)))}, nil
+	}
+	limited := &limitedBody{r: r.Body, remaining: spec.limit(), limit: spec.limit()}
+	if spec.Stream {
+		// Go planned handlers read r.Body directly; keep the limit in force.
+		r.Body = limited
+		return &requestBody{stream: newBodyStream(limited)}, nil
 	}
-	if int64(len(data)) > maxRequestBodyBytes {
-		return nil, "", fmt.Errorf("request body exceeds %d bytes", maxRequestBodyBytes)
+	body, err := parseRequestBody(r, limited)
+	if err != nil && limited.exceeded() {
+		return nil, limited.err()
 	}
-	raw := string(data)
+	return body, err
+}
+
+func parseRequestBody(r *http.Request, limited *limitedBody) (*requestBody, error) {
 	ct := strings.ToLower(r.Header.Get("Content-Type"))
+	if strings.Contains(ct, "multipart/form-data") {
+		fields, files, err := parseMultipart(r, limited)
+		if err != nil {
+			return nil, err
+		}
+		return &requestBody{value: fields, files: files}, nil
+	}
+	data, err := io.ReadAll(limited)
+	if err != nil {
+		return nil, err
+	}
+	body := &requestBody{raw: string(data), stream: newBodyStream(io.NopCloser(bytes.NewReader(data)))}
 	if len(data) == 0 {
-		return nil, raw, nil
+		return body, nil
 	}
-	if strings.Contains(ct, "application/json") {
+	switch {
+	case strings.Contains(ct, "application/json"):
 		var v any
 		if err := json.Unmarshal(data, &v); err != nil {
-			return nil, raw, err
+			return nil, err
 		}
-		return v, raw, nil
-	}
-	if strings.Contains(ct, "application/x-www-form-urlencoded") {
-		r.Body = io.NopCloser(strings.NewReader(raw))
+		body.value = v
+	case strings.Contains(ct, "application/x-www-form-urlencoded"):
+		r.Body = io.NopCloser(bytes.NewReader(data))
 		if err := r.ParseForm(); err != nil {
-			return nil, raw, err
+			return nil, err
 		}
-		return postFormMap(r), raw, nil
+		body.value = postFormMap(r.PostForm)
+	default:
+		body.value = body.raw
 	}
-	if strings.Contains(ct, "multipart/form-data") {
-		r.Body = io.NopCloser(strings.NewReader(raw))
-		// Request body is capped above and ParseMultipartForm uses a bounded in-memory budget.
-		if err := r.ParseMultipartForm(multipartFormMemoryLimit); err != nil { // #nosec G120
-			return nil, raw, err
+	return body, nil
+}
+
+// parseMultipart reads form fields into a map and file parts into
+// UploadedFiles. Field values and in-memory files share the
+// multipartFormMemoryLimit budget.
+func parseMultipart(r *http.Request, body io.Reader) (map[string]any, []*UploadedFile, error) {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, nil, err
+	}
+	boundary := params["boundary"]
+	if boundary == "" {
+		return nil, nil, http.ErrMissingBoundary
+	}
+	reader := multipart.NewReader(body, boundary)
+	form := url.Values{}
+	var files []*UploadedFile
+	budget := int64(multipartFormMemoryLimit)
+	fail := func(err error) (map[string]any, []*UploadedFile, error) {
+		removeUploadedFiles(files)
+		return nil, nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fail(err)
+		}
+		name := part.FormName()
+		if name == "" {
+			_ = part.Close()
+			continue
+		}
+		if part.FileName() == "" {
+			var value bytes.Buffer
+			n, err := io.Copy(&value, io.LimitReader(part, budget+1))
+			_ = part.Close()
+			if err != nil {
+				return fail(err)
+			}
+			if n > budget {
+				return fail(fmt.Errorf("%w: multipart fields exceed %d bytes", ErrBodyTooLarge, multipartFormMemoryLimit))
+			}
+			budget -= n
+			form.Add(name, value.String())
+			continue
 		}
-		return postFormMap(r), raw, nil
+		file, err := spoolUpload(part, &budget)
+		_ = part.Close()
+		if err != nil {
+			return fail(err)
+		}
+		files = append(files, file)
+	}
+	return postFormMap(form), files, nil
+}
+
+func spoolUpload(part *multipart.Part, budget *int64) (*UploadedFile, error) {
+	file := &UploadedFile{Field: part.FormName(), Filename: part.FileName(), ContentType: part.Header.Get("Content-Type")}
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, io.LimitReader(part, *budget+1))
+	if err != nil {
+		return nil, err
+	}
+	if n <= *budget {
+		*budget -= n
+		file.data = buf.Bytes()
+		file.Size = n
+		return file, nil
+	}
+	tmp, err := os.CreateTemp("", "gojahttp-upload-*")
+	if err != nil {
+		return nil, err
+	}
+	file.Path = tmp.Name()
+	size, err := io.Copy(tmp, io.MultiReader(&buf, part))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(file.Path)
+		return nil, err
 	}
-	return raw, raw, nil
+	file.Size = size
+	return file, nil
 }
 
-func postFormMap(r *http.Request) map[string]any {
+func removeUploadedFiles(files []*UploadedFile) {
+	for _, file := range files {
+		if file.Path != "" {
+			_ = os.Remove(file.Path)
+		}
+	}
+}
+
+func postFormMap(form url.Values) map[string]any {
 	m := map[string]any{}
-	for k, vals := range r.PostForm {
+	for k, vals := range form {
 		if len(vals) == 1 {
 			m[k] = vals[0]
 		} else {
@@ -66,3 +252,125 @@ func postFormMap(r *http.Request) map[string]any {
 	}
 	return m
 }
+
+// limitedBody fails with ErrBodyTooLarge instead of silently truncating.
+type limitedBody struct {
+	r         io.ReadCloser
+	remaining int64
+	limit     int64
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.exceeded() {
+		return 0, l.err()
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.exceeded() {
+		return n + int(l.remaining), l.err()
+	}
+	return n, err
+}
+
+func (l *limitedBody) exceeded() bool { return l.remaining < 0 }
+
+func (l *limitedBody) err() error {
+	return fmt.Errorf("%w: limit is %d bytes", ErrBodyTooLarge, l.limit)
+}
+
+func (l *limitedBody) Close() error { return l.r.Close() }
+
+// bodyStream hands the request body to exactly one JavaScript reader.
+type bodyStream struct {
+	mu    sync.Mutex
+	r     io.ReadCloser
+	taken bool
+}
+
+func newBodyStream(r io.ReadCloser) *bodyStream {
+	return &bodyStream{r: r}
+}
+
+// take returns the body reader the first time it is called.
+func (s *bodyStream) take() (io.ReadCloser, error) {
+	if s == nil {
+		return nil, errors.New("request body was already consumed by the multipart parser")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.taken {
+		return nil, errors.New("request body stream already taken")
+	}
+	s.taken = true
+	return s.r, nil
+}
+
+const defaultBodyReadSize = 64 << 10
+
+// jsObject returns the reader handed out by req.stream(). Reads run on a
+// goroutine and settle their promises on the runtime owner, so a slow upload
+// never blocks the event loop.
+func (s *bodyStream) jsObject(ctx context.Context, vm *goja.Runtime, owner runtimeowner.RuntimeOwner) (*goja.Object, error) {
+	reader, err := s.take()
+	if err != nil {
+		return nil, err
+	}
+	ctx = context.WithoutCancel(ctx)
+	var readMu sync.Mutex
+	// read settles with the next chunk as a Buffer, or null at end of body.
+	read := func(size int) *goja.Promise {
+		promise, resolve, reject := vm.NewPromise()
+		go func() {
+			readMu.Lock()
+			buf := make([]byte, size)
+			var (
+				n   int
+				err error
+			)
+			for n == 0 && err == nil {
+				n, err = reader.Read(buf)
+			}
+			readMu.Unlock()
+			_ = owner.Post(ctx, "http-body.read", func(_ context.Context, vm *goja.Runtime) {
+				switch {
+				case n > 0:
+					_ = resolve(buffer.WrapBytes(vm, buf[:n]))
+				case errors.Is(err, io.EOF):
+					_ = resolve(goja.Null())
+				default:
+					_ = reject(vm.NewGoError(err))
+				}
+			})
+		}()
+		return promise
+	}
+	obj := vm.NewObject()
+	_ = obj.Set("read", func(call goja.FunctionCall) goja.Value {
+		size := defaultBodyReadSize
+		if arg := call.Argument(0); !goja.IsUndefined(arg) && !goja.IsNull(arg) && arg.ToInteger() > 0 {
+			size = int(arg.ToInteger())
+		}
+		return vm.ToValue(read(size))
+	})
+	_ = obj.Set("text", func() goja.Value {
+		promise, resolve, reject := vm.NewPromise()
+		go func() {
+			readMu.Lock()
+			data, err := io.ReadAll(reader)
+			readMu.Unlock()
+			_ = owner.Post(ctx, "http-body.text", func(_ context.Context, vm *goja.Runtime) {
+				if err != nil {
+					_ = reject(vm.NewGoError(err))
+					return
+				}
+				_ = resolve(vm.ToValue(string(data)))
+			})
+		}()
+		return vm.ToValue(promise)
+	})
+	_ = obj.Set("cancel", func() error { return reader.Close() })
+	return obj, nil
+}
@@ -2,8 +2,10 @@ package gojahttp
 
 import (
 	"bytes"
+	"errors"
 	"mime/multipart"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
 )
@@ -30,21 +32,78 @@ func TestParseBodyMultipartFormFields(t *testing.T) {
 
 	req := httptest.NewRequest("POST", "/upload", strings.NewReader(body.String()))
 	req.Header.Set("Content-Type", writer.FormDataContentType())
-	parsed, raw, err := parseBody(req)
+	parsed, err := readRequestBody(req, BodySpec{})
 	if err != nil {
-		t.Fatalf("parseBody() error = %v", err)
+		t.Fatalf("readRequestBody() error = %v", err)
 	}
-	if raw == "" {
-		t.Fatalf("expected raw multipart body")
-	}
-	fields, ok := parsed.(map[string]any)
+	fields, ok := parsed.value.(map[string]any)
 	if !ok {
-		t.Fatalf("parsed body type = %T, want map[string]any", parsed)
+		t.Fatalf("parsed body type = %T, want map[string]any", parsed.value)
 	}
 	if fields["title"] != "Trail notes" || fields["tag"] != "Planning" {
 		t.Fatalf("parsed fields = %#v", fields)
 	}
-	if req.MultipartForm == nil || len(req.MultipartForm.File["attachment"]) != 1 {
-		t.Fatalf("expected multipart file metadata, got %#v", req.MultipartForm)
+	if len(parsed.files) != 1 {
+		t.Fatalf("expected one uploaded file, got %#v", parsed.files)
+	}
+	upload := parsed.files[0]
+	if upload.Field != "attachment" || upload.Filename != "note.txt" || upload.Size != 5 || upload.Path != "" {
+		t.Fatalf("uploaded file = %#v", upload)
+	}
+	data, err := upload.Bytes()
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("uploaded bytes = %q, %v", data, err)
+	}
+}
+
+func TestParseBodyMultipartSpoolsLargeFiles(t *testing.T) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	file, err := writer.CreateFormFile("video", "clip.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := bytes.Repeat([]byte{7}, multipartFormMemoryLimit+1)
+	if _, err := file.Write(payload); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("POST", "/upload", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	dto, err := NewRequestDTOWithBody(req, nil, nil, BodySpec{})
+	if err != nil {
+		t.Fatalf("NewRequestDTOWithBody() error = %v", err)
+	}
+	if len(dto.Files) != 1 || dto.Files[0].Path == "" || dto.Files[0].Size != int64(len(payload)) {
+		t.Fatalf("expected spooled upload, got %#v", dto.Files)
+	}
+	path := dto.Files[0].Path
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("spooled file missing: %v", err)
+	}
+	dto.Close()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected spooled file to be removed, stat err = %v", err)
+	}
+}
+
+func TestReadRequestBodyEnforcesLimit(t *testing.T) {
+	req := httptest.NewRequest("POST", "/notes", strings.NewReader(`{"title":"too long"}`))
+	req.Header.Set("Content-Type", "application/json")
+	if _, err := readRequestBody(req, BodySpec{Limit: 8}); !errors.Is(err, ErrBodyTooLarge) {
+		t.Fatalf("expected ErrBodyTooLarge, got %v", err)
+	}
+
+	req = httptest.NewRequest("POST", "/notes", strings.NewReader(`{"a":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	body, err := readRequestBody(req, BodySpec{Limit: 7})
+	if err != nil {
+		t.Fatalf("body at the limit should parse: %v", err)
+	}
+	if body.raw != `{"a":1}` {
+		t.Fatalf("raw = %q", body.raw)
 	}
 }
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+
+	"github.com/go-go-golems/go-go-goja/pkg/jserrors"
 )
 
 // EnforcerOptions configures the reusable planned-auth enforcement pipeline.
@@ -49,7 +51,10 @@ func (e *Enforcer) Session(w http.ResponseWriter, r *http.Request) (*SessionDTO,
 	return e.sessions.Session(w, r)
 }
 
-// Request builds the planned-route request DTO for router-extracted params.
+// Request builds the planned-route request DTO for router-extracted params. It
+// reads the whole body with the default limits before returning; the DTO owns
+// any upload spooled to a temporary file, so callers must Close it once the
+// request is served.
 func (e *Enforcer) Request(w http.ResponseWriter, r *http.Request, params map[string]string) (*RequestDTO, error) {
 	session, err := e.Session(w, r)
 	if err != nil {
@@ -102,6 +107,10 @@ func (e *Enforcer) Enforce(ctx context.Context, httpReq *http.Request, req *Requ
 	default:
 		return sec, http.StatusInternalServerError, fmt.Errorf("unsupported planned route security mode %q", plan.Security.Mode)
 	}
+	if err := req.loadPendingBody(); err != nil {
+		return sec, statusForBodyError(err), err
+	}
+	sec.Body = req.Body
 
 	if plan.CSRF.Required && isUnsafeMethod(httpReq.Method) && shouldVerifyCSRF(plan.Security.Mode, sec.Auth) {
 		if e.auth.CSRF == nil {
@@ -306,7 +315,7 @@ func (e *Enforcer) writePlannedHTTPError(w http.ResponseWriter, loggingWriter *a
 	}
 	message := http.StatusText(status)
 	if e.dev && err != nil && status >= 500 {
-		message = err.Error()
+		message = jserrors.Report(err)
 	}
 	http.Error(w, message, status)
 }
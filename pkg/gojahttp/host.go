@@ -2,13 +2,17 @@ package gojahttp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/pkg/jserrors"
 	"github.com/go-go-golems/go-go-goja/pkg/runtimeowner"
+	"github.com/gorilla/websocket"
 )
 
 type HostOptions struct {
@@ -17,6 +21,7 @@ type HostOptions struct {
 	Sessions        SessionOptions
 	Auth            AuthOptions
 	RejectRawRoutes bool
+	WebSocket       WebSocketOptions
 }
 
 type StaticMount struct {
@@ -37,19 +42,29 @@ type Host struct {
 	registry        *Registry
 	dev             bool
 	renderer        Renderer
+	runtimeMu       sync.RWMutex
 	owner           runtimeowner.RuntimeOwner
+	attached        []runtimeowner.RuntimeOwner
+	pool            RuntimeLeaser
+	handlers        runtimeHandlers
 	sessions        *SessionManager
 	enforcer        *Enforcer
 	rejectRawRoutes bool
 	static          []StaticMount
+	webSocket       WebSocketOptions
+	upgrader        *websocket.Upgrader
 }
 
 func NewHost(opts HostOptions) *Host {
 	enforcer := NewEnforcer(EnforcerOptions{Dev: opts.Dev, Sessions: opts.Sessions, Auth: opts.Auth})
-	return &Host{registry: NewRegistry(), dev: opts.Dev, renderer: opts.Renderer, sessions: enforcer.sessions, enforcer: enforcer, rejectRawRoutes: opts.RejectRawRoutes}
+	return &Host{registry: NewRegistry(), dev: opts.Dev, renderer: opts.Renderer, sessions: enforcer.sessions, enforcer: enforcer, rejectRawRoutes: opts.RejectRawRoutes, webSocket: opts.WebSocket, upgrader: newWebSocketUpgrader(opts.WebSocket)}
 }
 
-func (h *Host) SetRuntime(owner runtimeowner.RuntimeOwner) { h.owner = owner }
+func (h *Host) SetRuntime(owner runtimeowner.RuntimeOwner) {
+	h.runtimeMu.Lock()
+	defer h.runtimeMu.Unlock()
+	h.owner = owner
+}
 
 // SetAuthOptions replaces the host-owned planned-auth services used by future
 // requests. It is intended for generated/runtime hosts whose auth services are
@@ -66,16 +81,13 @@ func (h *Host) SetAuthOptions(auth AuthOptions) {
 	h.enforcer.SetAuthOptions(auth)
 }
 
+// Register adds a raw Goja route. Invalid patterns and conflicts with an
+// existing route for the same method are reported by Validate.
 func (h *Host) Register(method, pattern string, handler goja.Callable) {
-	h.registry.Add(method, pattern, handler)
+	h.ForRuntime(nil).Register(method, pattern, handler)
 }
 func (h *Host) RegisterPlanned(plan RoutePlan, handler goja.Callable) error {
-	plan, err := ValidateRoutePlan(plan)
-	if err != nil {
-		return err
-	}
-	h.registry.AddPlanned(plan, handler)
-	return nil
+	return h.ForRuntime(nil).RegisterPlanned(plan, handler)
 }
 func (h *Host) RegisterPlannedHTTP(plan RoutePlan, handler PlannedHTTPHandler) error {
 	plan, err := ValidateRoutePlan(plan)
@@ -85,6 +97,36 @@ func (h *Host) RegisterPlannedHTTP(plan RoutePlan, handler PlannedHTTPHandler) e
 	h.registry.AddPlannedHTTP(plan, handler)
 	return nil
 }
+
+// RegisterPlannedWebSocket registers handler for WebSocket upgrades on the
+// planned route. The plan is enforced before the upgrade; the handler is then
+// called with the secure ctx and an EventEmitter-backed socket.
+func (h *Host) RegisterPlannedWebSocket(plan RoutePlan, handler goja.Callable) error {
+	return h.ForRuntime(nil).RegisterPlannedWebSocket(plan, handler)
+}
+
+func validateWebSocketPlan(plan RoutePlan) (RoutePlan, error) {
+	if plan.Method == "" {
+		plan.Method = http.MethodGet
+	}
+	if !strings.EqualFold(plan.Method, http.MethodGet) {
+		return RoutePlan{}, fmt.Errorf("websocket route %s must use GET, got %s", plan.Pattern, plan.Method)
+	}
+	return ValidateRoutePlan(plan)
+}
+
+// Validate reports the routes that could not be compiled into the route
+// tree: invalid patterns and registrations that are ambiguous with another
+// one for the same method. Call it once all routes are registered and before
+// serving. A host whose registry has errors answers every request with 500,
+// so an invalid or conflicting route cannot be silently dropped.
+func (h *Host) Validate() error {
+	if h == nil {
+		return nil
+	}
+	return h.registry.Err()
+}
+
 func (h *Host) Routes() []RouteDescriptor {
 	if h == nil || h.registry == nil {
 		return nil
@@ -162,6 +204,15 @@ func (h *Host) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	defer logRequestDone(logger, loggingWriter, started)
 	w = wrappedWriter
 
+	if err := h.Validate(); err != nil {
+		logger.Error().Err(err).Str("event", "http_route_registry_invalid").Msg("http route registry invalid")
+		message := http.StatusText(http.StatusInternalServerError)
+		if h.dev {
+			message = "route registration failed: " + err.Error()
+		}
+		http.Error(w, message, http.StatusInternalServerError)
+		return
+	}
 	for _, mount := range h.static {
 		if staticMountMatches(mount.Prefix, r.URL.Path) {
 			if staticMountExcluded(mount.ExcludePrefixes, r.URL.Path) {
@@ -179,6 +230,11 @@ func (h *Host) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	if !ok {
+		if allowed := h.registry.Allowed(r.URL.Path); len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
 		http.NotFound(w, r)
 		return
 	}
@@ -191,34 +247,42 @@ func (h *Host) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	req, err := NewRequestDTO(r, params, session)
+	req, err := newPlannedRequestDTO(r, params, session, route.Plan)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeRequestBodyError(w, err)
 		return
 	}
+	defer req.Close()
 	switch route.kind() {
 	case RouteKindPlannedHTTP:
 		h.servePlannedHTTP(w, r, route, req, loggingWriter)
 		return
-	case RouteKindPlannedGoja:
-		if h.owner == nil {
-			http.Error(w, "runtime not initialized", http.StatusInternalServerError)
-			return
-		}
-		h.servePlannedRoute(w, r, route, req)
-		return
-	case RouteKindRawGoja:
-		if h.owner == nil {
-			http.Error(w, "runtime not initialized", http.StatusInternalServerError)
-			return
-		}
+	case RouteKindPlannedGoja, RouteKindPlannedWebSocket, RouteKindRawGoja:
 	default:
 		http.Error(w, "unknown route kind", http.StatusInternalServerError)
 		return
 	}
+	owner, release, status, err := h.leaseRuntime(r.Context(), &route)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	defer release()
+	switch route.kind() {
+	case RouteKindPlannedGoja:
+		h.servePlannedRoute(w, r, route, req, owner)
+		return
+	case RouteKindPlannedWebSocket:
+		h.servePlannedWebSocket(w, r, route, req, owner, logger)
+		return
+	}
 	res := NewResponse(w, h.renderer)
-	ret, err := h.owner.Call(r.Context(), "http-handler", func(ctx context.Context, vm *goja.Runtime) (any, error) {
-		result, err := route.GojaHandler(goja.Undefined(), vm.ToValue(req.Map()), res.JSObject(vm))
+	res.bindRequest(r.Context(), owner)
+	ret, err := owner.Call(r.Context(), "http-handler", func(ctx context.Context, vm *goja.Runtime) (any, error) {
+		resObj := res.JSObject(vm)
+		reqMap := req.jsMap(r.Context(), vm, owner)
+		reqMap["signal"] = res.signalObject(vm)
+		result, err := route.GojaHandler(goja.Undefined(), vm.ToValue(reqMap), resObj)
 		if err != nil {
 			return nil, err
 		}
@@ -229,21 +293,33 @@ func (h *Host) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	})
 	if err == nil {
 		if promise, ok := ret.(*goja.Promise); ok {
-			err = h.awaitAndFinishPromise(r.Context(), res, promise)
+			err = h.awaitAndFinishPromise(r.Context(), owner, res, promise)
 		}
 	}
+	res.finish(r.Context(), err)
 	if err != nil {
 		logger.Error().Err(err).Str("event", "http_handler_error").Msg("http handler error")
 	}
 	if err != nil && !res.Sent() {
 		if h.dev {
-			http.Error(w, fmt.Sprintf("JavaScript handler error: %v", err), http.StatusInternalServerError)
+			http.Error(w, "JavaScript handler error: "+jserrors.Report(err), http.StatusInternalServerError)
 		} else {
 			http.Error(w, "internal server error", http.StatusInternalServerError)
 		}
 	}
 }
 
+func writeRequestBodyError(w http.ResponseWriter, err error) {
+	http.Error(w, err.Error(), statusForBodyError(err))
+}
+
+func statusForBodyError(err error) int {
+	if errors.Is(err, ErrBodyTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
 func (h *Host) writeRawRouteRejected(w http.ResponseWriter, route Route) {
 	message := "raw routes disabled"
 	if h.dev {
@@ -265,52 +341,26 @@ func (h *Host) finishHandlerResult(vm *goja.Runtime, res *Response, result goja.
 	return nil
 }
 
-func (h *Host) awaitAndFinishPromise(ctx context.Context, res *Response, promise *goja.Promise) error {
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
-
-		ret, err := h.owner.Call(ctx, "http-handler.promise-state", func(_ context.Context, vm *goja.Runtime) (any, error) {
-			snapshot := promiseSnapshot{State: promise.State(), Result: promise.Result()}
-			if snapshot.State == goja.PromiseStateFulfilled {
-				return snapshot, h.finishHandlerResult(vm, res, snapshot.Result)
-			}
-			return snapshot, nil
-		})
-		if err != nil {
-			return err
-		}
-		snapshot := ret.(promiseSnapshot)
-		switch snapshot.State {
-		case goja.PromiseStatePending:
-			time.Sleep(5 * time.Millisecond)
-		case goja.PromiseStateRejected:
-			return fmt.Errorf("promise rejected: %s", valueString(snapshot.Result))
-		case goja.PromiseStateFulfilled:
-			return nil
-		}
+func (h *Host) awaitAndFinishPromise(ctx context.Context, owner runtimeowner.RuntimeOwner, res *Response, promise *goja.Promise) error {
+	settlement, err := runtimeowner.AwaitPromise(ctx, owner, "http-handler.await", promise)
+	if err != nil {
+		return err
 	}
-}
-
-type promiseSnapshot struct {
-	State  goja.PromiseState
-	Result goja.Value
-}
-
-func valueString(value goja.Value) string {
-	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
-		return "undefined"
+	if settlement.Rejected() {
+		return jserrors.Rejected(settlement.Value)
 	}
-	return value.String()
+	_, err = owner.Call(ctx, "http-handler.finish", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		return nil, h.finishHandlerResult(vm, res, settlement.Value)
+	})
+	return err
 }
 
 type headResponseWriter struct {
 	http.ResponseWriter
 }
 
+func (w headResponseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
 func (w headResponseWriter) Write(b []byte) (int, error) {
 	return len(b), nil
 }
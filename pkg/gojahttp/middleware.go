@@ -38,11 +38,12 @@ func PlannedMiddleware(opts MiddlewareOptions, plan RoutePlan, next PlannedHTTPH
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		req, err := NewRequestDTO(r, params, session)
+		req, err := newPlannedRequestDTO(r, params, session, &plan)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeRequestBodyError(w, err)
 			return
 		}
+		defer req.Close()
 		loggingWriter, wrappedWriter := newAccessLogResponseWriter(w)
 		enforcer.servePlannedHTTP(wrappedWriter, r, &plan, req, next, loggingWriter)
 	}), nil
@@ -53,15 +54,16 @@ func middlewareParams(pattern string, r *http.Request, paramFunc func(*http.Requ
 		params, ok := matchPattern(pattern, r.URL.Path)
 		return params, ok
 	}
+	segments, err := parsePattern(pattern)
+	if err != nil {
+		return nil, false
+	}
 	params := map[string]string{}
-	for _, part := range splitPath(pattern) {
-		if !strings.HasPrefix(part, ":") {
+	for _, segment := range segments {
+		if segment.kind != segmentParam {
 			continue
 		}
-		name := strings.TrimPrefix(part, ":")
-		if name == "" {
-			return nil, false
-		}
+		name := segment.name
 		value := strings.TrimSpace(paramFunc(r, name))
 		if value == "" {
 			return nil, false
@@ -2,6 +2,8 @@ package gojahttp_test
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -65,3 +67,60 @@ func TestPlannedMiddlewareRejectsWrongMethod(t *testing.T) {
 		t.Fatalf("status=%d body=%s", rr.Code, rr.Body.String())
 	}
 }
+
+type countingReader struct {
+	r    io.Reader
+	read int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read += n
+	return n, err
+}
+
+func TestPlannedMiddlewareReadsBodyOnlyAfterAuthentication(t *testing.T) {
+	handler, err := gojahttp.PlannedMiddleware(gojahttp.MiddlewareOptions{
+		Auth: gojahttp.AuthOptions{
+			Authenticator: authenticatorFunc(func(_ context.Context, r *http.Request, _ *gojahttp.SessionDTO, _ gojahttp.SecuritySpec) (*gojahttp.Actor, error) {
+				if r.Header.Get("Authorization") == "" {
+					return nil, nil
+				}
+				return &gojahttp.Actor{ID: "u1", Kind: "user"}, nil
+			}),
+			Authorizer: authorizerFunc(func(context.Context, gojahttp.AuthorizationRequest) (gojahttp.AuthorizationDecision, error) {
+				return gojahttp.AuthorizationDecision{Allowed: true}, nil
+			}),
+		},
+	}, gojahttp.RoutePlan{
+		Method:   http.MethodPost,
+		Pattern:  "/uploads",
+		Security: gojahttp.SecuritySpec{Mode: gojahttp.SecurityModeUser},
+		Action:   "upload.create",
+	}, func(_ context.Context, sec *gojahttp.SecureContext, w http.ResponseWriter, _ *http.Request) error {
+		body, _ := sec.Body.(map[string]any)
+		_, _ = fmt.Fprint(w, body["name"])
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("PlannedMiddleware: %v", err)
+	}
+
+	anonymous := &countingReader{r: strings.NewReader(`{"name":"report"}`)}
+	req := httptest.NewRequest(http.MethodPost, "/uploads", anonymous)
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized || anonymous.read != 0 {
+		t.Fatalf("anonymous status=%d bytes read=%d, want 401 and no body read", rr.Code, anonymous.read)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/uploads", strings.NewReader(`{"name":"report"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer t")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || rr.Body.String() != "report" {
+		t.Fatalf("authenticated status=%d body=%q", rr.Code, rr.Body.String())
+	}
+}
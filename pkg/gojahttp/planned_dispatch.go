@@ -8,6 +8,8 @@ import (
 	"strconv"
 
 	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/pkg/jserrors"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimeowner"
 )
 
 // SecureContext is the Go-native result of planned-route enforcement. It is
@@ -32,7 +34,7 @@ type secureEnvelope struct {
 	*SecureContext
 }
 
-func (h *Host) servePlannedRoute(w http.ResponseWriter, r *http.Request, route Route, req *RequestDTO) {
+func (h *Host) servePlannedRoute(w http.ResponseWriter, r *http.Request, route Route, req *RequestDTO, owner runtimeowner.RuntimeOwner) {
 	res := NewResponse(w, h.renderer)
 	envelope, status, err := h.buildSecureEnvelope(r.Context(), r, req, route.Plan)
 	if err != nil {
@@ -42,8 +44,12 @@ func (h *Host) servePlannedRoute(w http.ResponseWriter, r *http.Request, route R
 	}
 	h.recordAudit(r.Context(), r, req, route.Plan, envelope, "allowed", 0, nil)
 	actorCtx := ContextWithActor(r.Context(), envelope.Actor)
-	ret, err := h.owner.Call(actorCtx, "http-planned-handler", func(ctx context.Context, vm *goja.Runtime) (any, error) {
-		result, err := route.GojaHandler(goja.Undefined(), envelope.JSObject(vm), res.JSObject(vm))
+	res.bindRequest(r.Context(), owner)
+	ret, err := owner.Call(actorCtx, "http-planned-handler", func(ctx context.Context, vm *goja.Runtime) (any, error) {
+		resObj := res.JSObject(vm)
+		ctxObj := envelope.JSObject(r.Context(), vm, owner)
+		_ = ctxObj.Set("signal", res.signalObject(vm))
+		result, err := route.GojaHandler(goja.Undefined(), ctxObj, resObj)
 		if err != nil {
 			return nil, err
 		}
@@ -54,14 +60,15 @@ func (h *Host) servePlannedRoute(w http.ResponseWriter, r *http.Request, route R
 	})
 	if err == nil {
 		if promise, ok := ret.(*goja.Promise); ok {
-			err = h.awaitAndFinishPromise(actorCtx, res, promise)
+			err = h.awaitAndFinishPromise(actorCtx, owner, res, promise)
 		}
 	}
+	res.finish(r.Context(), err)
 	if err != nil {
 		h.recordAudit(r.Context(), r, req, route.Plan, envelope, "failed", http.StatusInternalServerError, err)
 		if !res.Sent() {
 			if h.dev {
-				http.Error(w, fmt.Sprintf("JavaScript handler error: %v", err), http.StatusInternalServerError)
+				http.Error(w, "JavaScript handler error: "+jserrors.Report(err), http.StatusInternalServerError)
 			} else {
 				http.Error(w, "internal server error", http.StatusInternalServerError)
 			}
@@ -194,9 +201,9 @@ func cloneStringMap(in map[string]string) map[string]string {
 	return out
 }
 
-func (e *secureEnvelope) JSObject(vm *goja.Runtime) *goja.Object {
+func (e *secureEnvelope) JSObject(ctx context.Context, vm *goja.Runtime, owner runtimeowner.RuntimeOwner) *goja.Object {
 	obj := vm.NewObject()
-	_ = obj.Set("request", e.Request.Map())
+	_ = obj.Set("request", e.Request.jsMap(ctx, vm, owner))
 	_ = obj.Set("auth", authJSMap(e.Auth))
 	_ = obj.Set("actor", actorJSMap(e.Actor))
 	_ = obj.Set("body", e.Body)
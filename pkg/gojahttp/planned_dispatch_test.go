@@ -131,6 +131,26 @@ func TestPlannedPublicRouteDispatchesSecureContext(t *testing.T) {
 	}
 }
 
+func TestPlannedRouteDevErrorShowsJavaScriptStack(t *testing.T) {
+	host := gojahttp.NewHost(gojahttp.HostOptions{Dev: true})
+	handler := plannedTestRuntime(t, host, `(async function(ctx, res) {
+  await null;
+  throw new Error("nope");
+})`)
+	if err := host.RegisterPlanned(gojahttp.RoutePlan{Method: "GET", Pattern: "/fail", Security: gojahttp.SecuritySpec{Mode: gojahttp.SecurityModePublic}}, handler); err != nil {
+		t.Fatalf("RegisterPlanned: %v", err)
+	}
+	rr := httptest.NewRecorder()
+	host.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("status=%d body=%s", rr.Code, rr.Body.String())
+	}
+	body := rr.Body.String()
+	if !strings.HasPrefix(body, "JavaScript handler error: promise rejected: Error: nope\n") || !strings.Contains(body, "\n    at <eval>:3:9") {
+		t.Fatalf("body=%s", body)
+	}
+}
+
 func TestPlannedUserRouteAuthenticatesAndAuthorizes(t *testing.T) {
 	host := gojahttp.NewHost(gojahttp.HostOptions{Dev: true, Auth: gojahttp.AuthOptions{
 		Authenticator: authenticatorFunc(func(context.Context, *http.Request, *gojahttp.SessionDTO, gojahttp.SecuritySpec) (*gojahttp.Actor, error) {
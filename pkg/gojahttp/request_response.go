@@ -1,6 +1,7 @@
 package gojahttp
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -9,6 +10,9 @@ import (
 	"sync"
 
 	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/buffer"
+	"github.com/go-go-golems/go-go-goja/modules/events"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimeowner"
 )
 
 type Renderer func(*goja.Runtime, goja.Value) (string, error)
@@ -25,6 +29,17 @@ type RequestDTO struct {
 	IP      string
 	Body    any
 	RawBody string
+	Files   []*UploadedFile
+
+	body    *bodyStream
+	pending *pendingBody
+}
+
+// pendingBody is a request body whose parsing waits until the planned route
+// has authenticated the caller.
+type pendingBody struct {
+	r    *http.Request
+	spec BodySpec
 }
 
 func (r *RequestDTO) Map() map[string]any {
@@ -40,14 +55,113 @@ func (r *RequestDTO) Map() map[string]any {
 		"ip":      r.IP,
 		"body":    r.Body,
 		"rawBody": r.RawBody,
+		"files":   uploadedFileMaps(r.Files),
+	}
+}
+
+// jsMap extends Map with values that need the runtime: file handles with
+// bytes()/text() readers and the lazy stream() body reader.
+func (r *RequestDTO) jsMap(ctx context.Context, vm *goja.Runtime, owner runtimeowner.RuntimeOwner) map[string]any {
+	m := r.Map()
+	files := make([]any, 0, len(r.Files))
+	for _, file := range r.Files {
+		files = append(files, uploadedFileObject(vm, file))
+	}
+	m["files"] = files
+	m["stream"] = func() (*goja.Object, error) {
+		return r.body.jsObject(ctx, vm, owner)
+	}
+	return m
+}
+
+// Close removes temporary files spooled for multipart uploads.
+func (r *RequestDTO) Close() {
+	if r != nil {
+		removeUploadedFiles(r.Files)
+	}
+}
+
+func uploadedFileMaps(files []*UploadedFile) []map[string]any {
+	out := make([]map[string]any, 0, len(files))
+	for _, file := range files {
+		out = append(out, file.Map())
+	}
+	return out
+}
+
+func uploadedFileObject(vm *goja.Runtime, file *UploadedFile) *goja.Object {
+	obj := vm.NewObject()
+	for key, value := range file.Map() {
+		_ = obj.Set(key, value)
 	}
+	_ = obj.Set("bytes", func() (goja.Value, error) {
+		data, err := file.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		return buffer.WrapBytes(vm, data), nil
+	})
+	_ = obj.Set("text", func() (string, error) {
+		data, err := file.Bytes()
+		return string(data), err
+	})
+	return obj
 }
 
+// NewRequestDTO reads the request with the default body settings.
 func NewRequestDTO(r *http.Request, params map[string]string, session *SessionDTO) (*RequestDTO, error) {
-	body, raw, err := parseBody(r)
+	return NewRequestDTOWithBody(r, params, session, BodySpec{})
+}
+
+// NewRequestDTOWithBody reads the request body according to spec. Unless
+// spec.Stream is set, the whole body is read and parsed, up to the limit of
+// spec, before it returns. Multipart bodies are parsed part by part into Body
+// and Files and leave RawBody empty. The DTO owns spooled upload files:
+// callers must Close it once the request is served.
+func NewRequestDTOWithBody(r *http.Request, params map[string]string, session *SessionDTO, spec BodySpec) (*RequestDTO, error) {
+	body, err := readRequestBody(r, spec)
 	if err != nil {
 		return nil, err
 	}
+	req := newRequestDTO(r, params, session)
+	req.setBody(body)
+	return req, nil
+}
+
+// newPlannedRequestDTO builds the DTO for a planned route. Routes that require
+// a user leave the body unread until Enforce has authenticated the caller, so
+// anonymous clients cannot make the host buffer or spool uploads. The body is
+// still read up front when a pre-auth rate limit keys on a body field.
+func newPlannedRequestDTO(r *http.Request, params map[string]string, session *SessionDTO, plan *RoutePlan) (*RequestDTO, error) {
+	if plan == nil {
+		return NewRequestDTOWithBody(r, params, session, BodySpec{})
+	}
+	if !deferBodyUntilAuthenticated(plan) {
+		return NewRequestDTOWithBody(r, params, session, plan.Body)
+	}
+	req := newRequestDTO(r, params, session)
+	req.pending = &pendingBody{r: r, spec: plan.Body}
+	return req, nil
+}
+
+func deferBodyUntilAuthenticated(plan *RoutePlan) bool {
+	if plan.Security.Mode != SecurityModeUser || plan.Body.Stream {
+		return false
+	}
+	for _, spec := range plan.RateLimits {
+		if rateLimitStage(spec) != RateLimitStagePreAuth {
+			continue
+		}
+		for _, part := range spec.KeyParts {
+			if part.Kind == RateLimitKeyBodyField {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func newRequestDTO(r *http.Request, params map[string]string, session *SessionDTO) *RequestDTO {
 	query := map[string]any{}
 	for k, vals := range r.URL.Query() {
 		if len(vals) == 1 {
@@ -64,7 +178,30 @@ func NewRequestDTO(r *http.Request, params map[string]string, session *SessionDT
 	for _, c := range r.Cookies() {
 		cookies[c.Name] = c.Value
 	}
-	return &RequestDTO{Method: r.Method, URL: r.URL.String(), Path: r.URL.Path, Query: query, Params: params, Headers: headers, Cookies: cookies, Session: session, IP: RequestClientIP(r), Body: body, RawBody: raw}, nil
+	return &RequestDTO{Method: r.Method, URL: r.URL.String(), Path: r.URL.Path, Query: query, Params: params, Headers: headers, Cookies: cookies, Session: session, IP: RequestClientIP(r)}
+}
+
+func (r *RequestDTO) setBody(body *requestBody) {
+	r.Body = body.value
+	r.RawBody = body.raw
+	r.Files = body.files
+	r.body = body.stream
+}
+
+// loadPendingBody reads a body deferred by newPlannedRequestDTO. It is a no-op
+// for DTOs whose body was read when they were built.
+func (r *RequestDTO) loadPendingBody() error {
+	if r == nil || r.pending == nil {
+		return nil
+	}
+	pending := r.pending
+	r.pending = nil
+	body, err := readRequestBody(pending.r, pending.spec)
+	if err != nil {
+		return err
+	}
+	r.setBody(body)
+	return nil
 }
 
 type Response struct {
@@ -74,6 +211,12 @@ type Response struct {
 	status   int
 	headers  map[string]string
 	sent     bool
+	closed   bool
+
+	stream     *responseStream
+	abort      *events.AbortSignal
+	abortCause error
+	stopAbort  func() bool
 }
 
 func NewResponse(w http.ResponseWriter, renderer Renderer) *Response {
@@ -97,7 +240,15 @@ func (r *Response) JSObject(vm *goja.Runtime) *goja.Object {
 		}
 		return goja.Undefined()
 	})
-	_ = obj.Set("end", func() error { return r.End() })
+	_ = obj.Set("write", func(call goja.FunctionCall) goja.Value { return r.jsWrite(vm, call) })
+	_ = obj.Set("flush", func() {
+		if err := r.Flush(); err != nil {
+			panic(vm.NewGoError(err))
+		}
+	})
+	_ = obj.Set("sse", func() *goja.Object { return r.sseObject(vm) })
+	_ = obj.Set("end", func(call goja.FunctionCall) goja.Value { return r.jsEnd(vm, call) })
+	_ = obj.Set("signal", r.signalObject(vm))
 	return obj
 }
 
@@ -155,6 +306,9 @@ func (r *Response) JSON(vm *goja.Runtime, v goja.Value) error {
 
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	if r.closed {
+		return ErrResponseClosed
+	}
 	if r.sent {
 		return nil
 	}
@@ -168,6 +322,9 @@ func (r *Response) JSON(vm *goja.Runtime, v goja.Value) error {
 func (r *Response) writeString(s string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	if r.closed {
+		return ErrResponseClosed
+	}
 	if r.sent {
 		return nil
 	}
@@ -201,6 +358,9 @@ func (r *Response) Redirect(call goja.FunctionCall) error {
 	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	if r.closed {
+		return ErrResponseClosed
+	}
 	if r.sent {
 		return nil
 	}
@@ -210,9 +370,18 @@ func (r *Response) Redirect(call goja.FunctionCall) error {
 	return nil
 }
 
+// End completes the response. For streamed responses it closes the stream
+// after queued chunks are written.
 func (r *Response) End() error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	if r.stream != nil {
+		r.stream.end()
+		return nil
+	}
+	if r.closed {
+		return ErrResponseClosed
+	}
 	if r.sent {
 		return nil
 	}
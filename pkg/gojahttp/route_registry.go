@@ -2,6 +2,8 @@ package gojahttp
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"sync"
@@ -17,10 +19,17 @@ type PlannedHTTPHandler func(ctx context.Context, sec *SecureContext, w http.Res
 // RouteKind identifies which handler backend owns a matched route.
 type RouteKind string
 
+// ErrRouteConflict is reported by Registry.Err and Host.Validate when a route
+// is registered for a method and pattern that cannot be told apart from an
+// existing registration.
+var ErrRouteConflict = errors.New("route conflict")
+
 const (
 	RouteKindRawGoja     RouteKind = "raw-goja"
 	RouteKindPlannedGoja RouteKind = "planned-goja"
 	RouteKindPlannedHTTP RouteKind = "planned-http"
+
+	RouteKindPlannedWebSocket RouteKind = "planned-websocket"
 )
 
 type Route struct {
@@ -31,6 +40,9 @@ type Route struct {
 
 	GojaHandler goja.Callable
 	HTTPHandler PlannedHTTPHandler
+
+	captures []string
+	index    int
 }
 
 type RouteDescriptor struct {
@@ -46,33 +58,108 @@ type RouteDescriptor struct {
 	RateLimitPolicies string       `json:"rateLimitPolicies,omitempty"`
 }
 
+// Registry stores registered routes and the compiled route tree used for
+// lookup. Registration order is kept for Routes; matching precedence comes
+// from the tree. Routes that cannot be compiled are left out of the tree and
+// reported by Err once registration is done.
 type Registry struct {
 	mu     sync.RWMutex
 	routes []Route
+	root   *routeNode
+	errs   []error
 }
 
-func NewRegistry() *Registry { return &Registry{} }
+func NewRegistry() *Registry { return &Registry{root: newRouteNode(patternSegment{})} }
 
 func (r *Registry) Add(method, pattern string, handler goja.Callable) {
+	r.add(Route{Method: strings.ToUpper(method), Pattern: cleanPath(pattern), Kind: RouteKindRawGoja, GojaHandler: handler})
+}
+
+func (r *Registry) AddPlanned(plan RoutePlan, handler goja.Callable) {
+	r.add(plannedRoute(plan, RouteKindPlannedGoja, handler))
+}
+
+func (r *Registry) AddPlannedHTTP(plan RoutePlan, handler PlannedHTTPHandler) {
+	plan.Method = strings.ToUpper(plan.Method)
+	plan.Pattern = cleanPath(plan.Pattern)
+	r.add(Route{Method: plan.Method, Pattern: plan.Pattern, Kind: RouteKindPlannedHTTP, Plan: &plan, HTTPHandler: handler})
+}
+
+func (r *Registry) AddPlannedWebSocket(plan RoutePlan, handler goja.Callable) {
+	r.add(plannedRoute(plan, RouteKindPlannedWebSocket, handler))
+}
+
+func plannedRoute(plan RoutePlan, kind RouteKind, handler goja.Callable) Route {
+	plan.Method = strings.ToUpper(plan.Method)
+	plan.Pattern = cleanPath(plan.Pattern)
+	return Route{Method: plan.Method, Pattern: plan.Pattern, Kind: kind, Plan: &plan, GojaHandler: handler}
+}
+
+// add compiles route into the tree. Invalid patterns and routes that are
+// indistinguishable from an existing route for the same method are recorded
+// for Err.
+func (r *Registry) add(route Route) {
+	if index, shared, ok := r.addShared(route); ok && shared {
+		r.conflict(route, index)
+	}
+}
+
+// addShared is add for routes registered once per pooled runtime. When an
+// identical route already exists it reports that route's index with shared
+// set instead of failing; other clashes are recorded as conflicts and
+// reported as not ok.
+func (r *Registry) addShared(route Route) (int, bool, bool) {
+	segments, err := parsePattern(route.Pattern)
+	if err != nil {
+		r.fail(err)
+		return 0, false, false
+	}
+	route.captures = captureNames(segments)
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.routes = append(r.routes, Route{Method: strings.ToUpper(method), Pattern: cleanPath(pattern), Kind: RouteKindRawGoja, GojaHandler: handler})
+	if r.root == nil {
+		r.root = newRouteNode(patternSegment{})
+	}
+	route.index = len(r.routes)
+	if existing, ok := r.root.insert(segments, route.Method, route.index); !ok {
+		other := r.routes[existing]
+		if other.Pattern == route.Pattern && other.kind() == route.kind() {
+			return existing, true, true
+		}
+		r.errs = append(r.errs, conflictError(route, other))
+		return 0, false, false
+	}
+	r.routes = append(r.routes, route)
+	return route.index, false, true
 }
 
-func (r *Registry) AddPlanned(plan RoutePlan, handler goja.Callable) {
+// conflict records that route clashes with the route at index.
+func (r *Registry) conflict(route Route, index int) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	plan.Method = strings.ToUpper(plan.Method)
-	plan.Pattern = cleanPath(plan.Pattern)
-	r.routes = append(r.routes, Route{Method: plan.Method, Pattern: plan.Pattern, Kind: RouteKindPlannedGoja, Plan: &plan, GojaHandler: handler})
+	r.errs = append(r.errs, conflictError(route, r.routes[index]))
 }
 
-func (r *Registry) AddPlannedHTTP(plan RoutePlan, handler PlannedHTTPHandler) {
+func (r *Registry) fail(err error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	plan.Method = strings.ToUpper(plan.Method)
-	plan.Pattern = cleanPath(plan.Pattern)
-	r.routes = append(r.routes, Route{Method: plan.Method, Pattern: plan.Pattern, Kind: RouteKindPlannedHTTP, Plan: &plan, HTTPHandler: handler})
+	r.errs = append(r.errs, err)
+}
+
+func conflictError(route, other Route) error {
+	return fmt.Errorf("%w: %s %s is ambiguous with %s %s", ErrRouteConflict, route.Method, route.Pattern, other.Method, other.Pattern)
+}
+
+// Err reports every route that could not be added, joined into one error,
+// or nil. Conflicting routes are kept out of the tree; Host refuses to serve
+// while Err is non-nil.
+func (r *Registry) Err() error {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return errors.Join(r.errs...)
 }
 
 func (r *Registry) Routes() []RouteDescriptor {
@@ -84,8 +171,8 @@ func (r *Registry) Routes() []RouteDescriptor {
 	out := make([]RouteDescriptor, 0, len(r.routes))
 	for _, route := range r.routes {
 		descriptor := RouteDescriptor{Method: route.Method, Pattern: route.Pattern}
-		if route.kind() == RouteKindPlannedHTTP {
-			descriptor.Kind = RouteKindPlannedHTTP
+		if kind := route.kind(); kind == RouteKindPlannedHTTP || kind == RouteKindPlannedWebSocket {
+			descriptor.Kind = kind
 		}
 		if route.Plan != nil {
 			descriptor.Planned = true
@@ -107,21 +194,61 @@ func (r *Registry) Routes() []RouteDescriptor {
 	return out
 }
 
+// Match returns the highest-precedence route for path that accepts method.
+// At a given node an exact method registration wins over ALL.
 func (r *Registry) Match(method, path string) (Route, map[string]string, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
+	if r.root == nil {
+		return Route{}, nil, false
+	}
 	method = strings.ToUpper(method)
-	path = cleanPath(path)
-	for _, route := range r.routes {
-		if route.Method != method && route.Method != "ALL" {
-			continue
+	var (
+		found  Route
+		params map[string]string
+	)
+	ok := r.root.walk(splitPath(path), nil, func(node *routeNode, values []string) bool {
+		index, ok := node.methods[method]
+		if !ok {
+			index, ok = node.methods["ALL"]
+		}
+		if !ok {
+			return false
 		}
-		params, ok := matchPattern(route.Pattern, path)
-		if ok {
-			return route, params, true
+		found = r.routes[index]
+		params = make(map[string]string, len(values))
+		for i, name := range found.captures {
+			if name != "" && i < len(values) {
+				params[name] = values[i]
+			}
 		}
+		return true
+	})
+	if !ok {
+		return Route{}, nil, false
 	}
-	return Route{}, nil, false
+	return found, params, true
+}
+
+// Allowed lists the methods registered for routes matching path, for use in
+// the Allow header of a 405 response. It returns nil when no route matches.
+func (r *Registry) Allowed(path string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.root == nil {
+		return nil
+	}
+	methods := map[string]struct{}{}
+	r.root.walk(splitPath(path), nil, func(node *routeNode, _ []string) bool {
+		for method := range node.methods {
+			methods[method] = struct{}{}
+		}
+		return false
+	})
+	if len(methods) == 0 {
+		return nil
+	}
+	return allowedMethods(methods)
 }
 
 func (route Route) kind() RouteKind {
@@ -159,27 +286,9 @@ func splitPath(p string) []string {
 }
 
 func matchPattern(pattern, path string) (map[string]string, bool) {
-	pp := splitPath(pattern)
-	sp := splitPath(path)
-	params := map[string]string{}
-	for i := 0; i < len(pp); i++ {
-		if pp[i] == "*" {
-			return params, true
-		}
-		if i >= len(sp) {
-			return nil, false
-		}
-		if strings.HasPrefix(pp[i], ":") {
-			name := strings.TrimPrefix(pp[i], ":")
-			if name == "" {
-				return nil, false
-			}
-			params[name] = sp[i]
-			continue
-		}
-		if pp[i] != sp[i] {
-			return nil, false
-		}
+	segments, err := parsePattern(pattern)
+	if err != nil {
+		return nil, false
 	}
-	return params, len(pp) == len(sp)
+	return matchSegments(segments, path)
 }
@@ -1,7 +1,10 @@
 package gojahttp
 
 import (
+	"errors"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -34,3 +37,123 @@ func TestRouteRegistryWildcardMustBeSegment(t *testing.T) {
 		t.Fatalf("wildcard inside a segment should not match")
 	}
 }
+
+func TestRouteRegistryPrefersStaticOverParamOverWildcard(t *testing.T) {
+	registry := NewRegistry()
+	for _, pattern := range []string{"/files/*path", "/files/:name", "/files/readme", "/files/:id(\\d+)"} {
+		registry.Add(http.MethodGet, pattern, nil)
+	}
+	if err := registry.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	cases := []struct {
+		path    string
+		pattern string
+		params  map[string]string
+	}{
+		{"/files/readme", "/files/readme", map[string]string{}},
+		{"/files/42", "/files/:id(\\d+)", map[string]string{"id": "42"}},
+		{"/files/notes", "/files/:name", map[string]string{"name": "notes"}},
+		{"/files/a/b/c.txt", "/files/*path", map[string]string{"path": "a/b/c.txt"}},
+	}
+	for _, tc := range cases {
+		route, params, ok := registry.Match(http.MethodGet, tc.path)
+		if !ok || route.Pattern != tc.pattern {
+			t.Fatalf("Match(%q) = %q ok=%v, want %q", tc.path, route.Pattern, ok, tc.pattern)
+		}
+		if len(params) != len(tc.params) {
+			t.Fatalf("Match(%q) params = %#v, want %#v", tc.path, params, tc.params)
+		}
+		for key, value := range tc.params {
+			if params[key] != value {
+				t.Fatalf("Match(%q) params = %#v, want %#v", tc.path, params, tc.params)
+			}
+		}
+	}
+}
+
+func TestRouteRegistryBacktracksWhenStaticBranchFails(t *testing.T) {
+	registry := NewRegistry()
+	registry.Add(http.MethodGet, "/users/me/settings", nil)
+	registry.Add(http.MethodGet, "/users/:id/profile", nil)
+	route, params, ok := registry.Match(http.MethodGet, "/users/me/profile")
+	if !ok || route.Pattern != "/users/:id/profile" || params["id"] != "me" {
+		t.Fatalf("route=%q params=%#v ok=%v", route.Pattern, params, ok)
+	}
+}
+
+func TestRouteRegistryRejectsConflicts(t *testing.T) {
+	registry := NewRegistry()
+	registry.Add(http.MethodGet, "/users/:id", nil)
+	registry.Add(http.MethodPost, "/users/:name", nil)
+	registry.Add(http.MethodGet, "/users/:id(\\d+)", nil)
+	if err := registry.Err(); err != nil {
+		t.Fatalf("different methods and constrained params should not conflict: %v", err)
+	}
+	registry.Add(http.MethodGet, "/users/:name", nil)
+	err := registry.Err()
+	if !errors.Is(err, ErrRouteConflict) || !strings.Contains(err.Error(), "GET /users/:name is ambiguous with GET /users/:id") {
+		t.Fatalf("expected conflict, got %v", err)
+	}
+	if route, _, _ := registry.Match(http.MethodGet, "/users/u-1"); route.Pattern != "/users/:id" {
+		t.Fatalf("conflicting route replaced the first one: %q", route.Pattern)
+	}
+	for _, pattern := range []string{"/bad/:id(", "/bad/*rest/more", "/bad/:", "/bad/:id([)"} {
+		registry := NewRegistry()
+		registry.Add(http.MethodGet, pattern, nil)
+		if err := registry.Err(); err == nil || errors.Is(err, ErrRouteConflict) {
+			t.Fatalf("Add(%q) should fail as invalid, got %v", pattern, err)
+		}
+		if len(registry.Routes()) != 0 {
+			t.Fatalf("invalid route %q was added", pattern)
+		}
+	}
+}
+
+func TestRouteRegistryAllowedMethods(t *testing.T) {
+	registry := NewRegistry()
+	registry.Add(http.MethodGet, "/items/:id", nil)
+	registry.Add(http.MethodDelete, "/items/:id", nil)
+	if got := registry.Allowed("/items/1"); strings.Join(got, ",") != "DELETE,GET,HEAD" {
+		t.Fatalf("Allowed = %v", got)
+	}
+	registry.Add("ALL", "/any", nil)
+	if got := registry.Allowed("/any"); strings.Join(got, ",") != "DELETE,GET,HEAD,OPTIONS,PATCH,POST,PUT" {
+		t.Fatalf("Allowed(ALL) = %v", got)
+	}
+	if got := registry.Allowed("/missing"); got != nil {
+		t.Fatalf("Allowed(missing) = %v", got)
+	}
+}
+
+func TestHostRespondsMethodNotAllowed(t *testing.T) {
+	host := NewHost(HostOptions{})
+	host.Register(http.MethodGet, "/items/:id", nil)
+	rr := httptest.NewRecorder()
+	host.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/items/1", nil))
+	if rr.Code != http.StatusMethodNotAllowed || rr.Header().Get("Allow") != "GET, HEAD" {
+		t.Fatalf("status=%d allow=%q", rr.Code, rr.Header().Get("Allow"))
+	}
+	rr = httptest.NewRecorder()
+	host.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/other", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("status=%d", rr.Code)
+	}
+}
+
+func TestHostValidateReportsConflictsWhateverTheOrder(t *testing.T) {
+	for _, patterns := range [][]string{{"/users/:id", "/users/:name"}, {"/users/:name", "/users/:id"}} {
+		host := NewHost(HostOptions{})
+		for _, pattern := range patterns {
+			host.Register(http.MethodGet, pattern, nil)
+		}
+		if err := host.Validate(); !errors.Is(err, ErrRouteConflict) {
+			t.Fatalf("Validate(%v) = %v", patterns, err)
+		}
+	}
+	host := NewHost(HostOptions{})
+	host.Register(http.MethodGet, "/users/:id", nil)
+	if err := host.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+}
@@ -0,0 +1,260 @@
+package gojahttp
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Route patterns are slash-separated segments. Each segment is one of:
+//
+//	literal       exact text match
+//	:name         any single segment, captured as params[name]
+//	:name(regex)  a single segment matching regex in full
+//	*             the remainder of the path, not captured
+//	*name         the remainder of the path, captured as params[name]
+//
+// Wildcards must be the last segment. Lookup prefers literal segments over
+// regex-constrained params, constrained params over plain params, and params
+// over wildcards, independent of registration order.
+
+type segmentKind int
+
+const (
+	segmentStatic segmentKind = iota
+	segmentParam
+	segmentWildcard
+)
+
+type patternSegment struct {
+	kind  segmentKind
+	text  string // literal text for static segments
+	name  string // capture name for params and named wildcards
+	expr  string // regex source for constrained params
+	regex *regexp.Regexp
+}
+
+// key identifies structurally equivalent segments. Capture names do not
+// participate, so /users/:id and /users/:name conflict.
+func (s patternSegment) key() string {
+	switch s.kind {
+	case segmentParam:
+		return ":" + s.expr
+	case segmentWildcard:
+		return "*"
+	default:
+		return s.text
+	}
+}
+
+func parsePattern(pattern string) ([]patternSegment, error) {
+	parts := splitPath(pattern)
+	segments := make([]patternSegment, 0, len(parts))
+	for i, part := range parts {
+		switch {
+		case strings.HasPrefix(part, ":"):
+			name, expr := part[1:], ""
+			if open := strings.IndexByte(name, '('); open >= 0 {
+				if !strings.HasSuffix(name, ")") {
+					return nil, fmt.Errorf("route pattern %q: unterminated constraint in %q", pattern, part)
+				}
+				name, expr = name[:open], name[open+1:len(name)-1]
+				if expr == "" {
+					return nil, fmt.Errorf("route pattern %q: empty constraint in %q", pattern, part)
+				}
+			}
+			if !validCaptureName(name) {
+				return nil, fmt.Errorf("route pattern %q: invalid parameter name in %q", pattern, part)
+			}
+			segment := patternSegment{kind: segmentParam, name: name, expr: expr}
+			if expr != "" {
+				re, err := regexp.Compile("^(?:" + expr + ")$")
+				if err != nil {
+					return nil, fmt.Errorf("route pattern %q: parameter %q: %w", pattern, name, err)
+				}
+				segment.regex = re
+			}
+			segments = append(segments, segment)
+		case strings.HasPrefix(part, "*") && (part == "*" || validCaptureName(part[1:])):
+			if i != len(parts)-1 {
+				return nil, fmt.Errorf("route pattern %q: wildcard %q must be the last segment", pattern, part)
+			}
+			segments = append(segments, patternSegment{kind: segmentWildcard, name: part[1:]})
+		default:
+			segments = append(segments, patternSegment{kind: segmentStatic, text: part})
+		}
+	}
+	return segments, nil
+}
+
+func validCaptureName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r != '_' && r != '-' && (r < '0' || r > '9') && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// captureNames returns the params filled by segments, in path order. Unnamed
+// wildcards are recorded as "" so captured values stay aligned.
+func captureNames(segments []patternSegment) []string {
+	var names []string
+	for _, segment := range segments {
+		if segment.kind != segmentStatic {
+			names = append(names, segment.name)
+		}
+	}
+	return names
+}
+
+// matchSegments matches path against one parsed pattern. It is used where a
+// single pattern is checked outside the registry, such as route middleware.
+func matchSegments(segments []patternSegment, path string) (map[string]string, bool) {
+	parts := splitPath(path)
+	params := map[string]string{}
+	for i, segment := range segments {
+		if segment.kind == segmentWildcard {
+			if segment.name != "" {
+				params[segment.name] = strings.Join(parts[i:], "/")
+			}
+			return params, true
+		}
+		if i >= len(parts) {
+			return nil, false
+		}
+		switch segment.kind {
+		case segmentParam:
+			if segment.regex != nil && !segment.regex.MatchString(parts[i]) {
+				return nil, false
+			}
+			params[segment.name] = parts[i]
+		default:
+			if segment.text != parts[i] {
+				return nil, false
+			}
+		}
+	}
+	return params, len(segments) == len(parts)
+}
+
+// routeNode is one segment position in the compiled route tree. Terminal nodes
+// map methods to indexes in Registry.routes.
+type routeNode struct {
+	segment  patternSegment
+	static   map[string]*routeNode
+	params   []*routeNode
+	wildcard *routeNode
+	methods  map[string]int
+}
+
+func newRouteNode(segment patternSegment) *routeNode {
+	return &routeNode{segment: segment}
+}
+
+// insert adds the route at index to the tree. It reports the index of an
+// existing route registered for the same method and equivalent pattern.
+func (n *routeNode) insert(segments []patternSegment, method string, index int) (int, bool) {
+	node := n
+	for _, segment := range segments {
+		node = node.child(segment)
+	}
+	if existing, ok := node.methods[method]; ok {
+		return existing, false
+	}
+	if node.methods == nil {
+		node.methods = map[string]int{}
+	}
+	node.methods[method] = index
+	return 0, true
+}
+
+func (n *routeNode) child(segment patternSegment) *routeNode {
+	switch segment.kind {
+	case segmentWildcard:
+		if n.wildcard == nil {
+			n.wildcard = newRouteNode(segment)
+		}
+		return n.wildcard
+	case segmentParam:
+		for _, child := range n.params {
+			if child.segment.key() == segment.key() {
+				return child
+			}
+		}
+		child := newRouteNode(segment)
+		n.params = append(n.params, child)
+		// Constrained params are tried before the catch-all param; among
+		// constrained params, registration order is kept.
+		sort.SliceStable(n.params, func(i, j int) bool {
+			return n.params[i].segment.regex != nil && n.params[j].segment.regex == nil
+		})
+		return child
+	default:
+		if n.static == nil {
+			n.static = map[string]*routeNode{}
+		}
+		child := n.static[segment.text]
+		if child == nil {
+			child = newRouteNode(segment)
+			n.static[segment.text] = child
+		}
+		return child
+	}
+}
+
+// walk visits terminal nodes matching parts in priority order, passing the
+// captured values. It stops as soon as visit returns true.
+func (n *routeNode) walk(parts []string, values []string, visit func(*routeNode, []string) bool) bool {
+	if len(parts) == 0 {
+		if len(n.methods) > 0 && visit(n, values) {
+			return true
+		}
+	} else {
+		if child := n.static[parts[0]]; child != nil && child.walk(parts[1:], values, visit) {
+			return true
+		}
+		for _, child := range n.params {
+			if child.segment.regex != nil && !child.segment.regex.MatchString(parts[0]) {
+				continue
+			}
+			if child.walk(parts[1:], append(values, parts[0]), visit) {
+				return true
+			}
+		}
+	}
+	if n.wildcard != nil && len(n.wildcard.methods) > 0 {
+		return visit(n.wildcard, append(values, strings.Join(parts, "/")))
+	}
+	return false
+}
+
+// routableMethods are the methods an ALL route answers, listed in Allow
+// headers in place of ALL.
+var routableMethods = []string{
+	http.MethodDelete, http.MethodGet, http.MethodHead, http.MethodOptions,
+	http.MethodPatch, http.MethodPost, http.MethodPut,
+}
+
+func allowedMethods(methods map[string]struct{}) []string {
+	if _, ok := methods["ALL"]; ok {
+		delete(methods, "ALL")
+		for _, method := range routableMethods {
+			methods[method] = struct{}{}
+		}
+	}
+	if _, ok := methods[http.MethodGet]; ok {
+		methods[http.MethodHead] = struct{}{}
+	}
+	out := make([]string, 0, len(methods))
+	for method := range methods {
+		out = append(out, method)
+	}
+	sort.Strings(out)
+	return out
+}
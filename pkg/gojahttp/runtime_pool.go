@@ -0,0 +1,181 @@
+package gojahttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimeowner"
+)
+
+// RuntimeLeaser lends a runtime owner to one request at a time and returns a
+// function that ends the lease. engine.RuntimePool satisfies it.
+type RuntimeLeaser interface {
+	AcquireOwner(ctx context.Context) (runtimeowner.RuntimeOwner, func(), error)
+}
+
+// SetRuntimePool spreads JavaScript requests across the runtimes handed out by
+// pool instead of the single owner set with SetRuntime. Every pooled runtime
+// must register its routes through ForRuntime, so each lease finds its own
+// handler for the shared route table.
+func (h *Host) SetRuntimePool(pool RuntimeLeaser) { h.pool = pool }
+
+// RuntimeRoutes registers JavaScript handlers that belong to one runtime.
+// Registering a route that another runtime already registered shares the
+// route and records this runtime's handler for it; registering it twice from
+// the same runtime is a conflict, reported by Host.Validate.
+type RuntimeRoutes struct {
+	host  *Host
+	owner runtimeowner.RuntimeOwner
+}
+
+// ForRuntime returns the registration surface for handlers owned by owner.
+func (h *Host) ForRuntime(owner runtimeowner.RuntimeOwner) *RuntimeRoutes {
+	return &RuntimeRoutes{host: h, owner: owner}
+}
+
+// Register adds a raw Goja route owned by this runtime.
+func (r *RuntimeRoutes) Register(method, pattern string, handler goja.Callable) {
+	r.add(Route{Method: strings.ToUpper(method), Pattern: cleanPath(pattern), Kind: RouteKindRawGoja, GojaHandler: handler})
+}
+
+// RegisterPlanned adds a planned Goja route owned by this runtime.
+func (r *RuntimeRoutes) RegisterPlanned(plan RoutePlan, handler goja.Callable) error {
+	plan, err := ValidateRoutePlan(plan)
+	if err != nil {
+		return err
+	}
+	r.add(plannedRoute(plan, RouteKindPlannedGoja, handler))
+	return nil
+}
+
+// RegisterPlannedWebSocket adds a planned WebSocket route owned by this
+// runtime.
+func (r *RuntimeRoutes) RegisterPlannedWebSocket(plan RoutePlan, handler goja.Callable) error {
+	plan, err := validateWebSocketPlan(plan)
+	if err != nil {
+		return err
+	}
+	r.add(plannedRoute(plan, RouteKindPlannedWebSocket, handler))
+	return nil
+}
+
+func (r *RuntimeRoutes) add(route Route) {
+	index, shared, ok := r.host.registry.addShared(route)
+	if !ok {
+		return
+	}
+	if r.owner == nil {
+		if shared {
+			r.host.registry.conflict(route, index)
+		}
+		return
+	}
+	if !r.host.handlers.set(r.owner, index, route.GojaHandler) {
+		r.host.registry.conflict(route, index)
+	}
+}
+
+// AttachRuntime records owner as a runtime that serves this host. Requests
+// that are not served from a pool run on the earliest attached runtime that
+// has not been forgotten, unless SetRuntime chose one. Module loaders call it
+// for every runtime they load into, so the runtime that serves requests does
+// not depend on which one loaded last.
+func (h *Host) AttachRuntime(owner runtimeowner.RuntimeOwner) {
+	if owner == nil {
+		return
+	}
+	h.runtimeMu.Lock()
+	defer h.runtimeMu.Unlock()
+	h.attached = append(h.attached, owner)
+	if h.owner == nil {
+		h.owner = owner
+	}
+}
+
+// ForgetRuntime drops the handlers registered by owner and detaches it,
+// typically when a pooled runtime is closed. If owner served unpooled
+// requests, the next attached runtime takes over.
+func (h *Host) ForgetRuntime(owner runtimeowner.RuntimeOwner) {
+	h.handlers.forget(owner)
+	h.runtimeMu.Lock()
+	defer h.runtimeMu.Unlock()
+	for i, attached := range h.attached {
+		if attached == owner {
+			h.attached = append(h.attached[:i], h.attached[i+1:]...)
+			break
+		}
+	}
+	if h.owner == owner {
+		h.owner = nil
+		if len(h.attached) > 0 {
+			h.owner = h.attached[0]
+		}
+	}
+}
+
+// runtimeHandlers maps each runtime owner to the handlers it registered,
+// keyed by route index.
+type runtimeHandlers struct {
+	mu      sync.RWMutex
+	byOwner map[runtimeowner.RuntimeOwner]map[int]goja.Callable
+}
+
+func (t *runtimeHandlers) set(owner runtimeowner.RuntimeOwner, index int, handler goja.Callable) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.byOwner == nil {
+		t.byOwner = map[runtimeowner.RuntimeOwner]map[int]goja.Callable{}
+	}
+	handlers := t.byOwner[owner]
+	if handlers == nil {
+		handlers = map[int]goja.Callable{}
+		t.byOwner[owner] = handlers
+	}
+	if _, exists := handlers[index]; exists {
+		return false
+	}
+	handlers[index] = handler
+	return true
+}
+
+func (t *runtimeHandlers) get(owner runtimeowner.RuntimeOwner, index int) (goja.Callable, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	handler, ok := t.byOwner[owner][index]
+	return handler, ok
+}
+
+func (t *runtimeHandlers) forget(owner runtimeowner.RuntimeOwner) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.byOwner, owner)
+}
+
+// leaseRuntime picks the owner that runs route and points route at that
+// owner's handler. Without a pool every request uses the SetRuntime owner.
+func (h *Host) leaseRuntime(ctx context.Context, route *Route) (runtimeowner.RuntimeOwner, func(), int, error) {
+	h.runtimeMu.RLock()
+	owner, release := h.owner, func() {}
+	h.runtimeMu.RUnlock()
+	if h.pool != nil {
+		var err error
+		owner, release, err = h.pool.AcquireOwner(ctx)
+		if err != nil {
+			return nil, nil, http.StatusServiceUnavailable, fmt.Errorf("acquire runtime: %w", err)
+		}
+	}
+	if owner == nil {
+		return nil, nil, http.StatusInternalServerError, fmt.Errorf("runtime not initialized")
+	}
+	if handler, ok := h.handlers.get(owner, route.index); ok {
+		route.GojaHandler = handler
+	} else if h.pool != nil {
+		release()
+		return nil, nil, http.StatusInternalServerError, fmt.Errorf("route %s %s is not registered on the leased runtime", route.Method, route.Pattern)
+	}
+	return owner, release, 0, nil
+}
@@ -0,0 +1,126 @@
+package gojahttp_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/pkg/engine"
+	"github.com/go-go-golems/go-go-goja/pkg/gojahttp"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimeowner"
+)
+
+type fixedLeaser struct {
+	owner    runtimeowner.RuntimeOwner
+	released int
+}
+
+func (l *fixedLeaser) AcquireOwner(context.Context) (runtimeowner.RuntimeOwner, func(), error) {
+	return l.owner, func() { l.released++ }, nil
+}
+
+func newPoolTestRuntime(t *testing.T, script string) (*engine.Runtime, goja.Callable) {
+	t.Helper()
+	factory, err := engine.NewRuntimeFactoryBuilder().Build()
+	if err != nil {
+		t.Fatalf("build factory: %v", err)
+	}
+	rt, err := factory.NewRuntime(engine.WithStartupContext(context.Background()), engine.WithLifetimeContext(context.Background()))
+	if err != nil {
+		t.Fatalf("new runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close(context.Background()) })
+	ret, err := rt.Owner.Call(context.Background(), "load-pool-test", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		return vm.RunString(script)
+	})
+	if err != nil {
+		t.Fatalf("load script: %v", err)
+	}
+	fn, _ := goja.AssertFunction(ret.(goja.Value))
+	return rt, fn
+}
+
+func TestHostDispatchesToLeasedRuntimeHandler(t *testing.T) {
+	host := gojahttp.NewHost(gojahttp.HostOptions{Dev: true})
+	rt1, fn1 := newPoolTestRuntime(t, `(function(ctx, res) { res.send("one"); })`)
+	rt2, fn2 := newPoolTestRuntime(t, `(function(ctx, res) { res.send("two"); })`)
+	plan := gojahttp.RoutePlan{Method: "GET", Pattern: "/who", Security: gojahttp.SecuritySpec{Mode: gojahttp.SecurityModePublic}}
+	if err := host.ForRuntime(rt1.Owner).RegisterPlanned(plan, fn1); err != nil {
+		t.Fatalf("register on rt1: %v", err)
+	}
+	if err := host.ForRuntime(rt2.Owner).RegisterPlanned(plan, fn2); err != nil {
+		t.Fatalf("register on rt2: %v", err)
+	}
+	if err := host.Validate(); err != nil {
+		t.Fatalf("expected registrations from two runtimes to share the route, got %v", err)
+	}
+	leaser := &fixedLeaser{owner: rt2.Owner}
+	host.SetRuntimePool(leaser)
+	rr := httptest.NewRecorder()
+	host.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/who", nil))
+	if rr.Code != http.StatusOK || strings.TrimSpace(rr.Body.String()) != "two" || leaser.released != 1 {
+		t.Fatalf("status=%d body=%q released=%d", rr.Code, rr.Body.String(), leaser.released)
+	}
+
+	host.ForgetRuntime(rt2.Owner)
+	rr = httptest.NewRecorder()
+	host.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/who", nil))
+	if rr.Code != http.StatusInternalServerError || leaser.released != 2 {
+		t.Fatalf("forgotten runtime status=%d released=%d", rr.Code, leaser.released)
+	}
+
+	if err := host.ForRuntime(rt1.Owner).RegisterPlanned(plan, fn1); err != nil {
+		t.Fatalf("register twice on rt1: %v", err)
+	}
+	if err := host.ForRuntime(rt2.Owner).RegisterPlannedWebSocket(plan, fn2); err != nil {
+		t.Fatalf("register websocket on rt2: %v", err)
+	}
+	if err := host.RegisterPlanned(plan, fn1); err != nil {
+		t.Fatalf("register without runtime: %v", err)
+	}
+	err := host.Validate()
+	if !errors.Is(err, gojahttp.ErrRouteConflict) || strings.Count(err.Error(), "is ambiguous with") != 3 {
+		t.Fatalf("expected the duplicate registration, the different route kind and the runtime-less registration to conflict, got %v", err)
+	}
+	if len(host.Routes()) != 1 {
+		t.Fatalf("routes=%#v", host.Routes())
+	}
+
+	rr = httptest.NewRecorder()
+	host.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/who", nil))
+	if rr.Code != http.StatusInternalServerError || !strings.Contains(rr.Body.String(), "route registration failed") || leaser.released != 2 {
+		t.Fatalf("conflicting registry status=%d body=%q released=%d", rr.Code, rr.Body.String(), leaser.released)
+	}
+}
+
+func TestHostServesFromEarliestAttachedRuntime(t *testing.T) {
+	host := gojahttp.NewHost(gojahttp.HostOptions{Dev: true})
+	rt1, fn1 := newPoolTestRuntime(t, `(function(ctx, res) { res.send("one"); })`)
+	rt2, fn2 := newPoolTestRuntime(t, `(function(ctx, res) { res.send("two"); })`)
+	plan := gojahttp.RoutePlan{Method: "GET", Pattern: "/who", Security: gojahttp.SecuritySpec{Mode: gojahttp.SecurityModePublic}}
+	for _, rt := range []struct {
+		owner runtimeowner.RuntimeOwner
+		fn    goja.Callable
+	}{{rt1.Owner, fn1}, {rt2.Owner, fn2}} {
+		host.AttachRuntime(rt.owner)
+		if err := host.ForRuntime(rt.owner).RegisterPlanned(plan, rt.fn); err != nil {
+			t.Fatalf("register: %v", err)
+		}
+	}
+	who := func() string {
+		rr := httptest.NewRecorder()
+		host.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/who", nil))
+		return strings.TrimSpace(rr.Body.String())
+	}
+	if got := who(); got != "one" {
+		t.Fatalf("expected the first attached runtime, got %q", got)
+	}
+	host.ForgetRuntime(rt1.Owner)
+	if got := who(); got != "two" {
+		t.Fatalf("expected the next attached runtime after forgetting the first, got %q", got)
+	}
+}
@@ -0,0 +1,394 @@
+package gojahttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/buffer"
+	"github.com/go-go-golems/go-go-goja/modules/events"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimeowner"
+)
+
+// ErrResponseClosed is returned when JavaScript writes to a response after the
+// host finished serving the request, for example from a timer that outlived a
+// disconnected client.
+var ErrResponseClosed = errors.New("http response closed")
+
+// responseStream owns the http.ResponseWriter once a handler starts streaming.
+// JavaScript enqueues chunks from the owner loop; a single pump goroutine
+// performs the socket writes so slow clients never block the runtime owner.
+type responseStream struct {
+	w       http.ResponseWriter
+	status  int
+	headers map[string]string
+
+	mu       sync.Mutex
+	pending  []streamOp
+	ended    bool
+	canceled bool
+	err      error
+	wake     chan struct{}
+	done     chan struct{}
+}
+
+type streamOp struct {
+	data  []byte
+	flush bool
+}
+
+func newResponseStream(w http.ResponseWriter, status int, headers map[string]string) *responseStream {
+	s := &responseStream{w: w, status: status, headers: headers, wake: make(chan struct{}, 1), done: make(chan struct{})}
+	go s.pump()
+	return s
+}
+
+func (s *responseStream) enqueue(op streamOp) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended || s.canceled {
+		return ErrResponseClosed
+	}
+	if s.err != nil {
+		return s.err
+	}
+	s.pending = append(s.pending, op)
+	s.signal()
+	return nil
+}
+
+// end marks the stream complete; the pump drains queued chunks and exits.
+func (s *responseStream) end() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ended = true
+	s.signal()
+}
+
+// cancel drops queued chunks and stops the pump at the next write boundary.
+func (s *responseStream) cancel() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.canceled = true
+	s.pending = nil
+	s.signal()
+}
+
+func (s *responseStream) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *responseStream) pump() {
+	defer close(s.done)
+	for k, v := range s.headers {
+		s.w.Header().Set(k, v)
+	}
+	s.w.WriteHeader(s.status)
+	controller := http.NewResponseController(s.w)
+	for {
+		s.mu.Lock()
+		ops := s.pending
+		s.pending = nil
+		finished := s.canceled || (s.ended && len(ops) == 0)
+		s.mu.Unlock()
+		if finished {
+			return
+		}
+		for _, op := range ops {
+			if err := s.apply(controller, op); err != nil {
+				s.mu.Lock()
+				s.err = err
+				s.canceled = true
+				s.pending = nil
+				s.mu.Unlock()
+				return
+			}
+		}
+		if len(ops) == 0 {
+			<-s.wake
+		}
+	}
+}
+
+func (s *responseStream) apply(controller *http.ResponseController, op streamOp) error {
+	if len(op.data) > 0 {
+		if _, err := s.w.Write(op.data); err != nil {
+			return err
+		}
+	}
+	if op.flush {
+		if err := controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write appends a chunk to the streamed response body. The first write commits
+// the status and headers without a Content-Length.
+func (r *Response) Write(data []byte) error {
+	stream, err := r.ensureStream()
+	if err != nil {
+		return err
+	}
+	return stream.enqueue(streamOp{data: append([]byte(nil), data...)})
+}
+
+// Flush pushes buffered response bytes to the client.
+func (r *Response) Flush() error {
+	stream, err := r.ensureStream()
+	if err != nil {
+		return err
+	}
+	return stream.enqueue(streamOp{flush: true})
+}
+
+func (r *Response) ensureStream() (*responseStream, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil, ErrResponseClosed
+	}
+	if r.stream != nil {
+		return r.stream, nil
+	}
+	if r.sent {
+		return nil, fmt.Errorf("http response already sent")
+	}
+	headers := make(map[string]string, len(r.headers))
+	for k, v := range r.headers {
+		if strings.EqualFold(k, "Content-Length") {
+			continue
+		}
+		headers[k] = v
+	}
+	r.sent = true
+	r.stream = newResponseStream(r.w, r.status, headers)
+	return r.stream, nil
+}
+
+func (r *Response) streaming() *responseStream {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stream
+}
+
+// bindRequest ties the response to the request lifetime so that client
+// disconnects abort the JavaScript signal through the runtime owner.
+func (r *Response) bindRequest(ctx context.Context, owner runtimeowner.RuntimeOwner) {
+	if ctx == nil || owner == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopAbort = context.AfterFunc(ctx, func() {
+		_ = owner.Post(context.Background(), "http-response.abort", func(_ context.Context, vm *goja.Runtime) {
+			r.cancelRequest(vm, context.Cause(ctx))
+		})
+	})
+}
+
+// finish waits for a streamed response to end, then closes the response to
+// further JavaScript writes. handlerErr terminates an open stream immediately.
+func (r *Response) finish(ctx context.Context, handlerErr error) {
+	if stream := r.streaming(); stream != nil {
+		if handlerErr != nil {
+			stream.end()
+		}
+		select {
+		case <-stream.done:
+		case <-ctx.Done():
+			stream.cancel()
+			<-stream.done
+		}
+	}
+	r.mu.Lock()
+	r.closed = true
+	stop := r.stopAbort
+	r.stopAbort = nil
+	r.mu.Unlock()
+	if stop != nil {
+		stop()
+	}
+}
+
+func (r *Response) jsWrite(vm *goja.Runtime, call goja.FunctionCall) goja.Value {
+	chunk := call.Argument(0)
+	if goja.IsUndefined(chunk) || goja.IsNull(chunk) {
+		return vm.ToValue(true)
+	}
+	if err := r.Write(chunkBytes(vm, chunk)); err != nil {
+		panic(vm.NewGoError(err))
+	}
+	return vm.ToValue(true)
+}
+
+func (r *Response) jsEnd(vm *goja.Runtime, call goja.FunctionCall) goja.Value {
+	chunk := call.Argument(0)
+	if !goja.IsUndefined(chunk) && !goja.IsNull(chunk) {
+		var err error
+		if r.streaming() != nil {
+			err = r.Write(chunkBytes(vm, chunk))
+		} else {
+			err = r.writeString(string(chunkBytes(vm, chunk)))
+		}
+		if err != nil {
+			panic(vm.NewGoError(err))
+		}
+	}
+	if err := r.End(); err != nil {
+		panic(vm.NewGoError(err))
+	}
+	return goja.Undefined()
+}
+
+func chunkBytes(vm *goja.Runtime, value goja.Value) []byte {
+	if s, ok := value.Export().(string); ok {
+		return []byte(s)
+	}
+	return buffer.DecodeBytes(vm, value, goja.Undefined())
+}
+
+// sseObject starts a text/event-stream response and returns the JavaScript
+// helper used to emit Server-Sent Events frames.
+func (r *Response) sseObject(vm *goja.Runtime) *goja.Object {
+	r.setHeader("Content-Type", "text/event-stream; charset=utf-8")
+	r.setHeader("Cache-Control", "no-cache")
+	r.setHeader("X-Accel-Buffering", "no")
+	if err := r.Flush(); err != nil {
+		panic(vm.NewGoError(err))
+	}
+	emit := func(frame string) {
+		if err := r.Write([]byte(frame)); err != nil {
+			panic(vm.NewGoError(err))
+		}
+		if err := r.Flush(); err != nil {
+			panic(vm.NewGoError(err))
+		}
+	}
+	obj := vm.NewObject()
+	_ = obj.Set("send", func(call goja.FunctionCall) goja.Value {
+		frame, err := sseFrame(vm, call.Argument(0), call.Argument(1))
+		if err != nil {
+			panic(vm.NewGoError(err))
+		}
+		emit(frame)
+		return obj
+	})
+	_ = obj.Set("event", func(name string, data goja.Value) goja.Value {
+		opts := vm.NewObject()
+		_ = opts.Set("event", name)
+		frame, err := sseFrame(vm, data, opts)
+		if err != nil {
+			panic(vm.NewGoError(err))
+		}
+		emit(frame)
+		return obj
+	})
+	_ = obj.Set("comment", func(text string) goja.Value {
+		var b strings.Builder
+		for _, line := range strings.Split(text, "\n") {
+			b.WriteString(": " + line + "\n")
+		}
+		b.WriteString("\n")
+		emit(b.String())
+		return obj
+	})
+	_ = obj.Set("retry", func(ms int64) goja.Value {
+		emit("retry: " + strconv.FormatInt(ms, 10) + "\n\n")
+		return obj
+	})
+	_ = obj.Set("close", func() {
+		if stream := r.streaming(); stream != nil {
+			stream.end()
+		}
+	})
+	_ = obj.Set("signal", r.signalObject(vm))
+	return obj
+}
+
+func sseFrame(vm *goja.Runtime, data goja.Value, options goja.Value) (string, error) {
+	var b strings.Builder
+	if options != nil && !goja.IsUndefined(options) && !goja.IsNull(options) {
+		opts := options.ToObject(vm)
+		for _, field := range []string{"event", "id"} {
+			if v := opts.Get(field); v != nil && !goja.IsUndefined(v) && !goja.IsNull(v) {
+				value := v.String()
+				if strings.ContainsAny(value, "\r\n") {
+					return "", fmt.Errorf("sse %s must not contain newlines", field)
+				}
+				b.WriteString(field + ": " + value + "\n")
+			}
+		}
+		if v := opts.Get("retry"); v != nil && !goja.IsUndefined(v) && !goja.IsNull(v) {
+			b.WriteString("retry: " + strconv.FormatInt(v.ToInteger(), 10) + "\n")
+		}
+	}
+	payload := ""
+	if data != nil && !goja.IsUndefined(data) && !goja.IsNull(data) {
+		if s, ok := data.Export().(string); ok {
+			payload = s
+		} else {
+			encoded, err := json.Marshal(data.Export())
+			if err != nil {
+				return "", err
+			}
+			payload = string(encoded)
+		}
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(payload, "\r\n", "\n"), "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	return b.String(), nil
+}
+
+// signalObject returns the request's AbortSignal, an events.AbortSignal that
+// aborts when the request is cancelled, so that it can be passed to fetch and
+// the events helpers. It must be called on the runtime owner goroutine.
+func (r *Response) signalObject(vm *goja.Runtime) *goja.Object {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.abort == nil {
+		r.abort = events.NewAbortSignal(vm)
+		if r.abortCause != nil {
+			r.abort.Abort(abortReason(vm, r.abortCause))
+		}
+	}
+	return r.abort.Object()
+}
+
+// cancelRequest aborts the request's signal, or records cause for a signal
+// created later. It runs on the runtime owner goroutine.
+func (r *Response) cancelRequest(vm *goja.Runtime, cause error) {
+	r.mu.Lock()
+	if r.abortCause != nil {
+		r.mu.Unlock()
+		return
+	}
+	r.abortCause = cause
+	signal := r.abort
+	r.mu.Unlock()
+	if signal != nil {
+		signal.Abort(abortReason(vm, cause))
+	}
+}
+
+// abortReason is an AbortError for a cancelled request: "client
+// disconnected", or the cause the request context was cancelled with.
+func abortReason(vm *goja.Runtime, cause error) goja.Value {
+	message := "client disconnected"
+	if !errors.Is(cause, context.Canceled) {
+		message = cause.Error()
+	}
+	return events.AbortError(vm, message)
+}
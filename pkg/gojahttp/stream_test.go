@@ -0,0 +1,204 @@
+package gojahttp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	_ "github.com/go-go-golems/go-go-goja/modules/fetch"
+	"github.com/go-go-golems/go-go-goja/pkg/engine"
+	"github.com/go-go-golems/go-go-goja/pkg/gojahttp"
+)
+
+func TestRawRouteStreamsChunksUntilEnd(t *testing.T) {
+	host := gojahttp.NewHost(gojahttp.HostOptions{Dev: true})
+	handler := plannedTestRuntime(t, host, `(function(_req, res) {
+		const { sleep } = require("timer");
+		res.type("text/plain");
+		res.write("a");
+		res.flush();
+		sleep(10).then(() => { res.write("b"); res.end("c"); });
+	})`)
+	host.Register("GET", "/stream", handler)
+
+	rr := httptest.NewRecorder()
+	host.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	if rr.Code != http.StatusOK || rr.Body.String() != "abc" {
+		t.Fatalf("status=%d body=%q", rr.Code, rr.Body.String())
+	}
+	if rr.Header().Get("Content-Length") != "" {
+		t.Fatalf("streamed response must not set Content-Length: %q", rr.Header().Get("Content-Length"))
+	}
+	if !rr.Flushed {
+		t.Fatalf("expected response to be flushed")
+	}
+}
+
+func TestPlannedRouteWritesServerSentEvents(t *testing.T) {
+	host := gojahttp.NewHost(gojahttp.HostOptions{Dev: true})
+	handler := plannedTestRuntime(t, host, `(async function(_ctx, res) {
+		const sse = res.sse();
+		sse.event("progress", { n: 1 });
+		await Promise.resolve();
+		sse.send("line1\nline2", { id: "7" });
+		sse.close();
+	})`)
+	if err := host.RegisterPlanned(gojahttp.RoutePlan{Method: "GET", Pattern: "/events", Security: gojahttp.SecuritySpec{Mode: gojahttp.SecurityModePublic}}, handler); err != nil {
+		t.Fatalf("RegisterPlanned: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	host.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("content-type=%q", ct)
+	}
+	want := "event: progress\ndata: {\"n\":1}\n\nid: 7\ndata: line1\ndata: line2\n\n"
+	if rr.Body.String() != want {
+		t.Fatalf("body=%q want %q", rr.Body.String(), want)
+	}
+}
+
+func TestStreamingRouteAbortsSignalOnClientCancel(t *testing.T) {
+	host := gojahttp.NewHost(gojahttp.HostOptions{Dev: true})
+	factory, err := engine.NewRuntimeFactoryBuilder().Build()
+	if err != nil {
+		t.Fatalf("build factory: %v", err)
+	}
+	rt, err := factory.NewRuntime(engine.WithStartupContext(context.Background()), engine.WithLifetimeContext(context.Background()))
+	if err != nil {
+		t.Fatalf("new runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close(context.Background()) })
+	host.SetRuntime(rt.Owner)
+	ret, err := rt.Owner.Call(context.Background(), "load-stream-test", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		return vm.RunString(`(function(req, res) {
+			globalThis.__aborted = false;
+			req.signal.addEventListener("abort", () => { globalThis.__aborted = res.signal.aborted; });
+			res.write("tick");
+		})`)
+	})
+	if err != nil {
+		t.Fatalf("load script: %v", err)
+	}
+	handler, _ := goja.AssertFunction(ret.(goja.Value))
+	host.Register("GET", "/forever", handler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		host.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/forever", nil).WithContext(ctx))
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("ServeHTTP did not return after client cancellation")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		aborted, err := rt.Owner.Call(context.Background(), "read-abort", func(_ context.Context, vm *goja.Runtime) (any, error) {
+			return vm.Get("__aborted").ToBoolean(), nil
+		})
+		if err != nil {
+			t.Fatalf("read abort flag: %v", err)
+		}
+		if aborted.(bool) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("abort listener did not observe aborted signal")
+}
+
+func TestRequestSignalAbortsFetchAndEventIterators(t *testing.T) {
+	upstreamHit := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		close(upstreamHit)
+		<-r.Context().Done()
+	}))
+	defer upstream.Close()
+
+	host := gojahttp.NewHost(gojahttp.HostOptions{Dev: true})
+	factory, err := engine.NewRuntimeFactoryBuilder().Build()
+	if err != nil {
+		t.Fatalf("build factory: %v", err)
+	}
+	rt, err := factory.NewRuntime(engine.WithStartupContext(context.Background()), engine.WithLifetimeContext(context.Background()))
+	if err != nil {
+		t.Fatalf("new runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close(context.Background()) })
+	host.SetRuntime(rt.Owner)
+	ret, err := rt.Owner.Call(context.Background(), "load-signal-test", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		return vm.RunString(`globalThis.__outcome = "";
+		(async function(ctx, res) {
+		const { fetch } = require("fetch");
+		const { EventEmitter, on } = require("events");
+		const emitter = new EventEmitter();
+		const outcome = {};
+		const iterate = (async () => {
+			try {
+				const events = on(emitter, "tick", { signal: ctx.signal });
+				while (!(await events.next()).done) {}
+				outcome.on = "ended";
+			} catch (e) {
+				outcome.on = e.name;
+			}
+		})();
+		try {
+			await fetch(` + strconv.Quote(upstream.URL) + `, { signal: ctx.signal });
+			outcome.fetch = "resolved";
+		} catch (e) {
+			outcome.fetch = e.name + ": " + e.message;
+		}
+		await iterate;
+		globalThis.__outcome = JSON.stringify(outcome);
+	})`)
+	})
+	if err != nil {
+		t.Fatalf("load script: %v", err)
+	}
+	handler, _ := goja.AssertFunction(ret.(goja.Value))
+	if err := host.RegisterPlanned(gojahttp.RoutePlan{Method: "GET", Pattern: "/proxy", Security: gojahttp.SecuritySpec{Mode: gojahttp.SecurityModePublic}}, handler); err != nil {
+		t.Fatalf("RegisterPlanned: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		host.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/proxy", nil).WithContext(ctx))
+	}()
+	select {
+	case <-upstreamHit:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("fetch did not reach the upstream server")
+	}
+	cancel()
+	<-done
+
+	want := `{"on":"AbortError","fetch":"AbortError: client disconnected"}`
+	var outcome any
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		outcome, err = rt.Owner.Call(context.Background(), "read-outcome", func(_ context.Context, vm *goja.Runtime) (any, error) {
+			return vm.Get("__outcome").String(), nil
+		})
+		if err != nil {
+			t.Fatalf("read outcome: %v", err)
+		}
+		if outcome == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("outcome = %v, want %s", outcome, want)
+}
@@ -0,0 +1,317 @@
+package gojahttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/buffer"
+	"github.com/go-go-golems/go-go-goja/modules/events"
+	"github.com/go-go-golems/go-go-goja/pkg/jserrors"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimeowner"
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultWebSocketMaxMessageBytes = 1 << 20
+	defaultWebSocketWriteTimeout    = 10 * time.Second
+	webSocketCloseGrace             = 5 * time.Second
+)
+
+// WebSocketOptions configures planned WebSocket routes. The zero value accepts
+// same-origin upgrades only, limits inbound messages to 1 MiB, and gives each
+// outbound frame ten seconds to reach the client.
+type WebSocketOptions struct {
+	CheckOrigin     func(r *http.Request) bool
+	MaxMessageBytes int64
+	WriteTimeout    time.Duration
+}
+
+func newWebSocketUpgrader(opts WebSocketOptions) *websocket.Upgrader {
+	return &websocket.Upgrader{CheckOrigin: opts.CheckOrigin}
+}
+
+// servePlannedWebSocket enforces the route plan exactly like servePlannedRoute,
+// upgrades the connection, and then blocks until the socket closes. All
+// JavaScript callbacks run on the runtime owner; the serving goroutine only
+// reads frames and posts them to the socket emitter.
+func (h *Host) servePlannedWebSocket(w http.ResponseWriter, r *http.Request, route Route, req *RequestDTO, owner runtimeowner.RuntimeOwner, logger zerolog.Logger) {
+	res := NewResponse(w, h.renderer)
+	envelope, status, err := h.buildSecureEnvelope(r.Context(), r, req, route.Plan)
+	if err != nil {
+		h.recordAudit(r.Context(), r, req, route.Plan, envelope, "denied", status, err)
+		h.writePlannedError(w, res, status, err)
+		return
+	}
+	if !websocket.IsWebSocketUpgrade(r) {
+		err := errors.New("websocket upgrade required")
+		h.recordAudit(r.Context(), r, req, route.Plan, envelope, "denied", http.StatusUpgradeRequired, err)
+		w.Header().Set("Upgrade", "websocket")
+		http.Error(w, http.StatusText(http.StatusUpgradeRequired), http.StatusUpgradeRequired)
+		return
+	}
+	h.recordAudit(r.Context(), r, req, route.Plan, envelope, "allowed", 0, nil)
+
+	var header http.Header
+	if cookies := w.Header().Values("Set-Cookie"); len(cookies) > 0 {
+		header = http.Header{"Set-Cookie": cookies}
+	}
+	conn, err := h.upgrader.Upgrade(w, r, header)
+	if err != nil {
+		// The upgrader already answered the client.
+		h.recordAudit(r.Context(), r, req, route.Plan, envelope, "failed", http.StatusBadRequest, err)
+		return
+	}
+	limit := h.webSocket.MaxMessageBytes
+	if limit <= 0 {
+		limit = defaultWebSocketMaxMessageBytes
+	}
+	conn.SetReadLimit(limit)
+
+	actorCtx := context.WithoutCancel(ContextWithActor(r.Context(), envelope.Actor))
+	socket := newWebSocketConn(conn, owner, h.webSocket.WriteTimeout, logger)
+	ret, err := owner.Call(actorCtx, "http-websocket-handler", func(ctx context.Context, vm *goja.Runtime) (any, error) {
+		result, err := route.GojaHandler(goja.Undefined(), envelope.JSObject(r.Context(), vm, owner), socket.JSObject(vm))
+		if err != nil {
+			return nil, err
+		}
+		if promise, ok := result.Export().(*goja.Promise); ok {
+			return promise, nil
+		}
+		return nil, nil
+	})
+	if err != nil {
+		socket.closeWithError(err)
+	} else if promise, ok := ret.(*goja.Promise); ok {
+		go func() {
+			settlement, err := runtimeowner.AwaitPromise(actorCtx, owner, "http-websocket-handler.await", promise)
+			if err == nil && settlement.Rejected() {
+				err = jserrors.Rejected(settlement.Value)
+			}
+			if err != nil {
+				socket.closeWithError(err)
+			}
+		}()
+	}
+
+	code, reason := socket.readLoop(actorCtx)
+	if err != nil {
+		h.recordAudit(r.Context(), r, req, route.Plan, envelope, "failed", http.StatusInternalServerError, err)
+		return
+	}
+	logger.Debug().Int("closeCode", code).Str("closeReason", reason).Str("event", "websocket_closed").Msg("websocket closed")
+	h.recordAudit(r.Context(), r, req, route.Plan, envelope, "completed", http.StatusSwitchingProtocols, nil)
+}
+
+// webSocketConn adapts a gorilla connection to a JavaScript EventEmitter.
+// Outbound frames are queued from the owner loop and written by a single
+// writer goroutine, mirroring responseStream, so a slow peer never blocks the
+// runtime owner.
+type webSocketConn struct {
+	conn         *websocket.Conn
+	owner        runtimeowner.RuntimeOwner
+	writeTimeout time.Duration
+	logger       zerolog.Logger
+
+	// emitter is only touched on the owner goroutine.
+	emitter *events.EventEmitter
+
+	mu      sync.Mutex
+	pending []webSocketFrame
+	closing bool
+	closed  bool
+	wake    chan struct{}
+	done    chan struct{}
+}
+
+type webSocketFrame struct {
+	kind int
+	data []byte
+}
+
+func newWebSocketConn(conn *websocket.Conn, owner runtimeowner.RuntimeOwner, writeTimeout time.Duration, logger zerolog.Logger) *webSocketConn {
+	if writeTimeout <= 0 {
+		writeTimeout = defaultWebSocketWriteTimeout
+	}
+	s := &webSocketConn{conn: conn, owner: owner, writeTimeout: writeTimeout, logger: logger, wake: make(chan struct{}, 1), done: make(chan struct{})}
+	go s.writeLoop()
+	return s
+}
+
+// send queues a data frame. It fails once close has been requested.
+func (s *webSocketConn) send(kind int, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closing || s.closed {
+		return ErrResponseClosed
+	}
+	s.pending = append(s.pending, webSocketFrame{kind: kind, data: data})
+	s.signal()
+	return nil
+}
+
+// close queues a close frame after any pending data frames. The read loop
+// exits when the peer acknowledges, or after webSocketCloseGrace.
+func (s *webSocketConn) close(code int, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closing || s.closed {
+		return
+	}
+	s.closing = true
+	s.pending = append(s.pending, webSocketFrame{kind: websocket.CloseMessage, data: websocket.FormatCloseMessage(code, reason)})
+	s.signal()
+}
+
+func (s *webSocketConn) closeWithError(err error) {
+	if err != nil {
+		s.logger.Error().Err(err).Str("event", "websocket_handler_error").Msg("websocket handler error")
+	}
+	s.close(websocket.CloseInternalServerErr, "internal error")
+}
+
+func (s *webSocketConn) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *webSocketConn) writeLoop() {
+	defer close(s.done)
+	for {
+		s.mu.Lock()
+		frames := s.pending
+		s.pending = nil
+		closed := s.closed
+		s.mu.Unlock()
+		if closed && len(frames) == 0 {
+			return
+		}
+		for _, frame := range frames {
+			deadline := time.Now().Add(s.writeTimeout)
+			if frame.kind == websocket.CloseMessage {
+				_ = s.conn.WriteControl(websocket.CloseMessage, frame.data, deadline)
+				_ = s.conn.SetReadDeadline(time.Now().Add(webSocketCloseGrace))
+				continue
+			}
+			_ = s.conn.SetWriteDeadline(deadline)
+			if err := s.conn.WriteMessage(frame.kind, frame.data); err != nil {
+				// Closing the connection unblocks the read loop, which
+				// reports the close to JavaScript.
+				_ = s.conn.Close()
+				s.mu.Lock()
+				s.closed = true
+				s.pending = nil
+				s.mu.Unlock()
+				return
+			}
+		}
+		if len(frames) == 0 {
+			<-s.wake
+		}
+	}
+}
+
+// readLoop dispatches inbound messages until the connection closes, then
+// emits "close" and waits for the writer to drain.
+func (s *webSocketConn) readLoop(ctx context.Context) (int, string) {
+	code, reason := websocket.CloseAbnormalClosure, ""
+	for {
+		kind, data, err := s.conn.ReadMessage()
+		if err != nil {
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) {
+				code, reason = closeErr.Code, closeErr.Text
+			}
+			break
+		}
+		s.dispatch(ctx, "http-websocket.message", func(vm *goja.Runtime) []goja.Value {
+			if kind == websocket.BinaryMessage {
+				return []goja.Value{buffer.WrapBytes(vm, data), vm.ToValue(true)}
+			}
+			return []goja.Value{vm.ToValue(string(data)), vm.ToValue(false)}
+		}, "message")
+	}
+
+	s.mu.Lock()
+	s.closing = true
+	s.closed = true
+	s.signal()
+	s.mu.Unlock()
+	<-s.done
+	_ = s.conn.Close()
+
+	s.dispatch(ctx, "http-websocket.close", func(vm *goja.Runtime) []goja.Value {
+		return []goja.Value{vm.ToValue(code), vm.ToValue(reason)}
+	}, "close")
+	return code, reason
+}
+
+func (s *webSocketConn) dispatch(ctx context.Context, op string, args func(*goja.Runtime) []goja.Value, name string) {
+	err := s.owner.Post(ctx, op, func(_ context.Context, vm *goja.Runtime) {
+		if s.emitter == nil {
+			return
+		}
+		if _, err := s.emitter.Emit(name, args(vm)...); err != nil {
+			s.logger.Warn().Err(err).Str("event", "websocket_listener_error").Str("name", name).Msg("websocket listener error")
+		}
+	})
+	if err != nil {
+		s.logger.Warn().Err(err).Str("event", "websocket_dispatch_error").Str("name", name).Msg("websocket dispatch failed")
+	}
+}
+
+func (s *webSocketConn) readyState() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case s.closed:
+		return 3
+	case s.closing:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// JSObject returns the EventEmitter-backed socket handed to app.ws handlers.
+// It must be called on the owner goroutine.
+func (s *webSocketConn) JSObject(vm *goja.Runtime) *goja.Object {
+	emitter, obj := events.NewObject(vm)
+	s.emitter = emitter
+	_ = obj.Set("send", func(call goja.FunctionCall) goja.Value {
+		value := call.Argument(0)
+		var err error
+		if text, ok := value.Export().(string); ok {
+			err = s.send(websocket.TextMessage, []byte(text))
+		} else {
+			err = s.send(websocket.BinaryMessage, chunkBytes(vm, value))
+		}
+		if err != nil {
+			panic(vm.NewGoError(err))
+		}
+		return goja.Undefined()
+	})
+	_ = obj.Set("close", func(call goja.FunctionCall) goja.Value {
+		code := websocket.CloseNormalClosure
+		if arg := call.Argument(0); !goja.IsUndefined(arg) && !goja.IsNull(arg) {
+			code = int(arg.ToInteger())
+		}
+		reason := ""
+		if arg := call.Argument(1); !goja.IsUndefined(arg) && !goja.IsNull(arg) {
+			reason = arg.String()
+		}
+		s.close(code, reason)
+		return goja.Undefined()
+	})
+	_ = obj.DefineAccessorProperty("readyState", vm.ToValue(func(goja.FunctionCall) goja.Value {
+		return vm.ToValue(s.readyState())
+	}), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	_ = obj.Set("protocol", s.conn.Subprotocol())
+	return obj
+}
@@ -0,0 +1,164 @@
+package gojahttp_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/pkg/engine"
+	"github.com/go-go-golems/go-go-goja/pkg/gojahttp"
+	"github.com/gorilla/websocket"
+)
+
+func TestPlannedWebSocketEchoesAndEmitsClose(t *testing.T) {
+	host := gojahttp.NewHost(gojahttp.HostOptions{Dev: true})
+	factory, err := engine.NewRuntimeFactoryBuilder().Build()
+	if err != nil {
+		t.Fatalf("build factory: %v", err)
+	}
+	rt, err := factory.NewRuntime(engine.WithStartupContext(context.Background()), engine.WithLifetimeContext(context.Background()))
+	if err != nil {
+		t.Fatalf("new runtime: %v", err)
+	}
+	t.Cleanup(func() { _ = rt.Close(context.Background()) })
+	host.SetRuntime(rt.Owner)
+	closed := make(chan string, 1)
+	ret, err := rt.Owner.Call(context.Background(), "load-ws-test", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		if err := vm.Set("reportClose", func(code int, reason string) { closed <- fmt.Sprintf("%d:%s", code, reason) }); err != nil {
+			return nil, err
+		}
+		return vm.RunString(`(function(ctx, socket) {
+			socket.send("hello " + ctx.params.room);
+			socket.on("message", (data, isBinary) => {
+				socket.send(isBinary ? data : data.toUpperCase());
+			});
+			socket.on("close", (code, reason) => reportClose(code, reason));
+		})`)
+	})
+	if err != nil {
+		t.Fatalf("load script: %v", err)
+	}
+	handler, _ := goja.AssertFunction(ret.(goja.Value))
+	plan := gojahttp.RoutePlan{Pattern: "/rooms/:room", Security: gojahttp.SecuritySpec{Mode: gojahttp.SecurityModePublic}}
+	if err := host.RegisterPlannedWebSocket(plan, handler); err != nil {
+		t.Fatalf("RegisterPlannedWebSocket: %v", err)
+	}
+	srv := httptest.NewServer(host)
+	t.Cleanup(srv.Close)
+
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/rooms/lobby", nil)
+	if err != nil {
+		t.Fatalf("dial: %v (resp=%v)", err, resp)
+	}
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	expectMessage(t, conn, websocket.TextMessage, "hello lobby")
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("ping")); err != nil {
+		t.Fatalf("write text: %v", err)
+	}
+	expectMessage(t, conn, websocket.TextMessage, "PING")
+	if err := conn.WriteMessage(websocket.BinaryMessage, []byte{1, 2, 3}); err != nil {
+		t.Fatalf("write binary: %v", err)
+	}
+	expectMessage(t, conn, websocket.BinaryMessage, "\x01\x02\x03")
+
+	if err := conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "bye")); err != nil {
+		t.Fatalf("write close: %v", err)
+	}
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Fatalf("expected close acknowledgement, got %v", err)
+	}
+	select {
+	case got := <-closed:
+		if got != "1000:bye" {
+			t.Fatalf("close event=%q", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("close event not emitted")
+	}
+}
+
+func TestPlannedWebSocketServerClose(t *testing.T) {
+	host := gojahttp.NewHost(gojahttp.HostOptions{Dev: true})
+	handler := plannedTestRuntime(t, host, `(function(_ctx, socket) {
+		socket.on("message", () => socket.close(4000, "done"));
+	})`)
+	plan := gojahttp.RoutePlan{Method: "GET", Pattern: "/ws", Security: gojahttp.SecuritySpec{Mode: gojahttp.SecurityModePublic}}
+	if err := host.RegisterPlannedWebSocket(plan, handler); err != nil {
+		t.Fatalf("RegisterPlannedWebSocket: %v", err)
+	}
+	srv := httptest.NewServer(host)
+	t.Cleanup(srv.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("x")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, 4000) || !strings.Contains(err.Error(), "done") {
+		t.Fatalf("expected close 4000 done, got %v", err)
+	}
+}
+
+func TestPlannedWebSocketRequiresUpgradeAndEnforcesPlan(t *testing.T) {
+	host := gojahttp.NewHost(gojahttp.HostOptions{Dev: true})
+	handler := plannedTestRuntime(t, host, `(function(_ctx, socket) { socket.send("unexpected"); })`)
+	if err := host.RegisterPlannedWebSocket(gojahttp.RoutePlan{Pattern: "/open", Security: gojahttp.SecuritySpec{Mode: gojahttp.SecurityModePublic}}, handler); err != nil {
+		t.Fatalf("RegisterPlannedWebSocket public: %v", err)
+	}
+	authPlan := gojahttp.RoutePlan{Pattern: "/private", Security: gojahttp.SecuritySpec{Mode: gojahttp.SecurityModeUser}, Action: "private.read"}
+	if err := host.RegisterPlannedWebSocket(authPlan, handler); err != nil {
+		t.Fatalf("RegisterPlannedWebSocket auth: %v", err)
+	}
+	if err := host.RegisterPlannedWebSocket(gojahttp.RoutePlan{Method: "POST", Pattern: "/post", Security: gojahttp.SecuritySpec{Mode: gojahttp.SecurityModePublic}}, handler); err == nil {
+		t.Fatalf("expected non-GET websocket route to be rejected")
+	}
+
+	rr := httptest.NewRecorder()
+	host.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/open", nil))
+	if rr.Code != http.StatusUpgradeRequired || rr.Header().Get("Upgrade") != "websocket" {
+		t.Fatalf("plain GET status=%d upgrade=%q", rr.Code, rr.Header().Get("Upgrade"))
+	}
+
+	srv := httptest.NewServer(host)
+	t.Cleanup(srv.Close)
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/private", nil)
+	if err == nil {
+		t.Fatalf("expected unauthenticated upgrade to fail")
+	}
+	if resp == nil || resp.StatusCode == http.StatusSwitchingProtocols {
+		t.Fatalf("unexpected upgrade response: %v", resp)
+	}
+
+	var kind gojahttp.RouteKind
+	for _, route := range host.Routes() {
+		if route.Pattern == "/open" {
+			kind = route.Kind
+		}
+	}
+	if kind != gojahttp.RouteKindPlannedWebSocket {
+		t.Fatalf("route kind=%q", kind)
+	}
+}
+
+func expectMessage(t *testing.T, conn *websocket.Conn, wantKind int, want string) {
+	t.Helper()
+	kind, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if kind != wantKind || string(data) != want {
+		t.Fatalf("message kind=%d data=%q, want kind=%d data=%q", kind, data, wantKind, want)
+	}
+}
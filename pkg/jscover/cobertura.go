@@ -0,0 +1,193 @@
+package jscover
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type coberturaCoverage struct {
+	XMLName         xml.Name           `xml:"coverage"`
+	LineRate        float64            `xml:"line-rate,attr"`
+	BranchRate      float64            `xml:"branch-rate,attr"`
+	LinesCovered    int                `xml:"lines-covered,attr"`
+	LinesValid      int                `xml:"lines-valid,attr"`
+	BranchesCovered int                `xml:"branches-covered,attr"`
+	BranchesValid   int                `xml:"branches-valid,attr"`
+	Complexity      float64            `xml:"complexity,attr"`
+	Version         string             `xml:"version,attr"`
+	Timestamp       int64              `xml:"timestamp,attr"`
+	Sources         []string           `xml:"sources>source"`
+	Packages        []coberturaPackage `xml:"packages>package"`
+}
+
+type coberturaPackage struct {
+	Name       string           `xml:"name,attr"`
+	LineRate   float64          `xml:"line-rate,attr"`
+	BranchRate float64          `xml:"branch-rate,attr"`
+	Complexity float64          `xml:"complexity,attr"`
+	Classes    []coberturaClass `xml:"classes>class"`
+}
+
+type coberturaClass struct {
+	Name       string            `xml:"name,attr"`
+	Filename   string            `xml:"filename,attr"`
+	LineRate   float64           `xml:"line-rate,attr"`
+	BranchRate float64           `xml:"branch-rate,attr"`
+	Complexity float64           `xml:"complexity,attr"`
+	Methods    []coberturaMethod `xml:"methods>method"`
+	Lines      []coberturaLine   `xml:"lines>line"`
+}
+
+type coberturaMethod struct {
+	Name       string          `xml:"name,attr"`
+	Signature  string          `xml:"signature,attr"`
+	LineRate   float64         `xml:"line-rate,attr"`
+	BranchRate float64         `xml:"branch-rate,attr"`
+	Complexity float64         `xml:"complexity,attr"`
+	Lines      []coberturaLine `xml:"lines>line"`
+}
+
+type coberturaLine struct {
+	Number            int    `xml:"number,attr"`
+	Hits              int64  `xml:"hits,attr"`
+	Branch            bool   `xml:"branch,attr"`
+	ConditionCoverage string `xml:"condition-coverage,attr,omitempty"`
+}
+
+// WriteCobertura writes the report as Cobertura XML, which CI servers such
+// as Jenkins and GitLab read. Files are grouped into packages by directory,
+// relative to the directory all of them are in.
+func (r *Report) WriteCobertura(w io.Writer) error {
+	s := r.Summary()
+	root := commonDir(r.Files)
+	doc := coberturaCoverage{
+		LineRate:        rate(s.LinesHit, s.Lines),
+		BranchRate:      rate(s.BranchesHit, s.Branches),
+		LinesCovered:    s.LinesHit,
+		LinesValid:      s.Lines,
+		BranchesCovered: s.BranchesHit,
+		BranchesValid:   s.Branches,
+		Version:         "jscover",
+		Timestamp:       time.Now().UnixMilli(),
+		Sources:         []string{root},
+	}
+
+	packages := map[string]*coberturaPackage{}
+	var names []string
+	for _, f := range r.Files {
+		rel := filepath.ToSlash(relPath(root, f.Path))
+		dir := filepath.ToSlash(filepath.Dir(rel))
+		pkg := packages[dir]
+		if pkg == nil {
+			pkg = &coberturaPackage{Name: strings.ReplaceAll(dir, "/", ".")}
+			packages[dir] = pkg
+			names = append(names, dir)
+		}
+		pkg.Classes = append(pkg.Classes, coberturaFile(f, rel))
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pkg := packages[name]
+		var ps Summary
+		for _, f := range r.Files {
+			if dir := filepath.ToSlash(filepath.Dir(relPath(root, f.Path))); dir == name {
+				fs := f.Summary()
+				ps.Lines += fs.Lines
+				ps.LinesHit += fs.LinesHit
+				ps.Branches += fs.Branches
+				ps.BranchesHit += fs.BranchesHit
+			}
+		}
+		pkg.LineRate = rate(ps.LinesHit, ps.Lines)
+		pkg.BranchRate = rate(ps.BranchesHit, ps.Branches)
+		doc.Packages = append(doc.Packages, *pkg)
+	}
+
+	if _, err := io.WriteString(w, xml.Header+`<!DOCTYPE coverage SYSTEM "http://cobertura.sourceforge.net/xml/coverage-04.dtd">`+"\n"); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func coberturaFile(f *FileReport, rel string) coberturaClass {
+	s := f.Summary()
+	class := coberturaClass{
+		Name:       strings.ReplaceAll(strings.TrimSuffix(rel, filepath.Ext(rel)), "/", "."),
+		Filename:   rel,
+		LineRate:   rate(s.LinesHit, s.Lines),
+		BranchRate: rate(s.BranchesHit, s.Branches),
+	}
+
+	type arms struct{ covered, total int }
+	byLine := map[int]*arms{}
+	for _, b := range f.Branches {
+		a := byLine[b.Line]
+		if a == nil {
+			a = &arms{}
+			byLine[b.Line] = a
+		}
+		for _, hits := range b.Arms {
+			a.total++
+			if hits > 0 {
+				a.covered++
+			}
+		}
+	}
+	for _, l := range f.Lines() {
+		line := coberturaLine{Number: l.Line, Hits: l.Hits}
+		if a := byLine[l.Line]; a != nil {
+			line.Branch = true
+			line.ConditionCoverage = fmt.Sprintf("%d%% (%d/%d)", 100*a.covered/a.total, a.covered, a.total)
+		}
+		class.Lines = append(class.Lines, line)
+	}
+	for _, fn := range f.Functions {
+		hit := 0
+		if fn.Hits > 0 {
+			hit = 1
+		}
+		class.Methods = append(class.Methods, coberturaMethod{
+			Name:       fn.Name,
+			Signature:  "",
+			LineRate:   float64(hit),
+			BranchRate: 1,
+			Lines:      []coberturaLine{{Number: fn.Line, Hits: fn.Hits}},
+		})
+	}
+	return class
+}
+
+// commonDir returns the deepest directory that contains every file.
+func commonDir(files []*FileReport) string {
+	if len(files) == 0 {
+		return ""
+	}
+	dir := filepath.Dir(files[0].Path)
+	for _, f := range files[1:] {
+		for dir != filepath.Dir(dir) && !strings.HasPrefix(f.Path, dir+string(filepath.Separator)) {
+			dir = filepath.Dir(dir)
+		}
+	}
+	return dir
+}
+
+func relPath(root, path string) string {
+	if root == "" {
+		return path
+	}
+	if rel, err := filepath.Rel(root, path); err == nil {
+		return rel
+	}
+	return path
+}
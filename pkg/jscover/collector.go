@@ -0,0 +1,268 @@
+// Package jscover collects code coverage of scripts run in goja runtimes.
+//
+// A Collector instruments the sources that require loads: using jsparse it
+// inserts counter increments in front of every statement, at the start of
+// every function and in every arm of a branch, without adding line breaks.
+// Each runtime counts into counters of its own, which Report adds up once
+// the runtimes are done. Sources compiled with an inline source map, such
+// as ES modules and TypeScript from tsscript, are reported against the
+// files the map points to.
+//
+// Reports are written as lcov, Cobertura XML and HTML.
+package jscover
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/require"
+	"github.com/go-go-golems/go-go-goja/pkg/engine"
+)
+
+// Collector instruments the modules runtimes load and counts what they
+// run. One Collector can serve many runtimes, concurrently.
+type Collector struct {
+	mu sync.Mutex
+
+	// files holds instrumented sources by ID, loaded by module path.
+	files  []*file
+	loaded map[string]*file
+	// contents caches the text of original files for reports.
+	contents map[string]string
+	runtimes []*runtimeCounters
+}
+
+// file is an instrumented module source.
+type file struct {
+	id     int
+	module string
+	text   string
+	code   string
+
+	statements []*location
+	functions  []mappedFunction
+	branches   []mappedBranch
+	// counts of statements, functions and branch arms, the sizes of the
+	// counters runtimes allocate.
+	numStatements int
+	numFunctions  int
+	numArms       []int
+}
+
+type mappedFunction struct {
+	name string
+	at   *location
+}
+
+type mappedBranch struct {
+	kind string
+	at   *location
+	arms []*location
+}
+
+// runtimeCounters are the counters of one runtime, by file ID.
+type runtimeCounters struct {
+	files map[int]*counters
+}
+
+type counters struct {
+	s []int64
+	f []int64
+	b [][]int64
+}
+
+// New returns a Collector that has not counted anything yet.
+func New() *Collector {
+	return &Collector{loaded: map[string]*file{}, contents: map[string]string{}}
+}
+
+// Attach instruments the modules runtimes from builder load. base is the
+// source loader the runtimes would use otherwise; nil stands for the
+// engine's default loader, engine.ESMSourceLoader around
+// require.DefaultSourceLoader.
+func (c *Collector) Attach(builder *engine.RuntimeFactoryBuilder, base require.SourceLoader) *engine.RuntimeFactoryBuilder {
+	if builder == nil {
+		return nil
+	}
+	return builder.WithRequireOptions(c.RequireOption(base))
+}
+
+// RequireOption installs a loader that instruments what base loads, and the
+// native module instrumented code gets its counters from. Use it where
+// runtimes are created from require options rather than a builder. It
+// replaces any loader set before it, so pass that loader as base.
+func (c *Collector) RequireOption(base require.SourceLoader) require.Option {
+	return c.RequireOptionWithPaths(base, nil)
+}
+
+// RequireOptionWithPaths is RequireOption for loaders whose module paths are
+// not file paths, such as the virtual paths of a jsverbs registry. filePath
+// maps a module path to the file reports show; nil keeps module paths.
+func (c *Collector) RequireOptionWithPaths(base require.SourceLoader, filePath func(modulePath string) string) require.Option {
+	if base == nil {
+		base = engine.ESMSourceLoader(require.DefaultSourceLoader)
+	}
+	loader := c.sourceLoader(base, filePath)
+	return func(r *require.Registry) {
+		require.WithLoader(loader)(r)
+		r.RegisterNativeModule(counterModule, c.loadCounterModule)
+	}
+}
+
+// sourceLoader wraps base so that module sources are instrumented. Sources
+// that do not parse, or whose instrumented form does not, run unchanged and
+// are not reported.
+func (c *Collector) sourceLoader(base require.SourceLoader, filePath func(string) string) require.SourceLoader {
+	return func(path string) ([]byte, error) {
+		src, err := base(path)
+		if err != nil || !instrumentable(path) {
+			return src, err
+		}
+		file := path
+		if filePath != nil {
+			file = filepath.Clean(filePath(path))
+		}
+		return c.instrument(path, file, src), nil
+	}
+}
+
+// Instrument returns src, the code of the file path, instrumented to count
+// into the runtimes that use one of c's require options. It serves hosts
+// that run compiled code as a script rather than load it through require.
+// Code that cannot be instrumented comes back unchanged.
+func (c *Collector) Instrument(path string, src []byte) []byte {
+	return c.instrument(path, path, src)
+}
+
+func (c *Collector) instrument(module, path string, src []byte) []byte {
+	f, err := c.load(module, path, string(src))
+	if err != nil {
+		log.Warn().Err(err).Str("path", module).Msg("jscover: running module without instrumentation")
+		return src
+	}
+	return []byte(f.code)
+}
+
+// load returns the instrumented form of text, the module runtimes load as
+// module from the file path, reusing the previous one when it was loaded
+// with the same text before.
+func (c *Collector) load(module, path, text string) (*file, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if f := c.loaded[module]; f != nil && f.text == text {
+		return f, nil
+	}
+
+	loc, err := c.locator(path, text)
+	if err != nil {
+		return nil, err
+	}
+	id := len(c.files)
+	prog, err := instrument(module, text, id)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &file{
+		id:            id,
+		module:        module,
+		text:          text,
+		code:          prog.code,
+		numStatements: len(prog.statements),
+		numFunctions:  len(prog.functions),
+	}
+	seen := map[string]bool{}
+	at := func(p position) *location {
+		l, ok := loc.locate(p)
+		if !ok || excluded(l.path) {
+			return nil
+		}
+		if !seen[l.path] {
+			seen[l.path] = true
+			if content, ok := loc.content(l.path); ok {
+				c.contents[l.path] = content
+			}
+		}
+		// Code a loader appended to a compiled file, such as the jsverbs
+		// overlay of TypeScript verbs, maps past its end.
+		if content, ok := c.contents[l.path]; ok && l.line > strings.Count(content, "\n")+1 {
+			return nil
+		}
+		return &l
+	}
+	for _, p := range prog.statements {
+		f.statements = append(f.statements, at(p))
+	}
+	for _, fn := range prog.functions {
+		f.functions = append(f.functions, mappedFunction{name: fn.name, at: at(fn.at)})
+	}
+	for _, b := range prog.branches {
+		mb := mappedBranch{kind: b.kind, at: at(b.at)}
+		for _, arm := range b.arms {
+			mb.arms = append(mb.arms, at(arm))
+		}
+		f.branches = append(f.branches, mb)
+		f.numArms = append(f.numArms, len(b.arms))
+	}
+
+	c.files = append(c.files, f)
+	c.loaded[module] = f
+	return f, nil
+}
+
+// locator returns how positions in text, loaded from the file path, map to
+// original files: through its inline source map, or to the file itself.
+func (c *Collector) locator(path, text string) (locator, error) {
+	sm, err := inlineSourceMap(path, text)
+	if err != nil || sm != nil {
+		return sm, err
+	}
+	// A file that cannot be read is reported as loaded.
+	disk, _ := os.ReadFile(path)
+	return newFileLocator(path, text, string(disk)), nil
+}
+
+// excluded reports whether a file is left out of reports: dependencies
+// under node_modules.
+func excluded(path string) bool {
+	return strings.Contains(filepath.ToSlash(path), "/node_modules/")
+}
+
+// loadCounterModule registers a runtime. Its exports return the counters of
+// a file by ID, which instrumented code increments.
+func (c *Collector) loadCounterModule(vm *goja.Runtime, module *goja.Object) {
+	rc := &runtimeCounters{files: map[int]*counters{}}
+	c.mu.Lock()
+	c.runtimes = append(c.runtimes, rc)
+	c.mu.Unlock()
+
+	exports := map[int]goja.Value{}
+	_ = module.Set("exports", func(call goja.FunctionCall) goja.Value {
+		id := int(call.Argument(0).ToInteger())
+		if v, ok := exports[id]; ok {
+			return v
+		}
+		c.mu.Lock()
+		if id < 0 || id >= len(c.files) {
+			c.mu.Unlock()
+			panic(vm.NewTypeError("unknown coverage file %d", id))
+		}
+		f := c.files[id]
+		cnt := &counters{s: make([]int64, f.numStatements), f: make([]int64, f.numFunctions)}
+		for _, arms := range f.numArms {
+			cnt.b = append(cnt.b, make([]int64, arms))
+		}
+		rc.files[id] = cnt
+		c.mu.Unlock()
+
+		obj := vm.NewObject()
+		_ = obj.Set("s", cnt.s)
+		_ = obj.Set("f", cnt.f)
+		_ = obj.Set("b", cnt.b)
+		exports[id] = obj
+		return obj
+	})
+}
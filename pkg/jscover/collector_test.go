@@ -0,0 +1,130 @@
+package jscover
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/go-go-golems/go-go-goja/pkg/engine"
+	"github.com/go-go-golems/go-go-goja/pkg/tsscript"
+	"github.com/stretchr/testify/require"
+)
+
+const libScript = `function pick(x) {
+  if (x > 1) {
+    return "big";
+  }
+  return x ? "one" : "zero";
+}
+module.exports = { pick };
+`
+
+func runCovered(t *testing.T, c *Collector, script string) {
+	t.Helper()
+	factory, err := c.Attach(engine.NewRuntimeFactoryBuilder(), nil).Build()
+	require.NoError(t, err)
+	ctx := context.Background()
+	rt, err := factory.NewRuntime(engine.WithStartupContext(ctx), engine.WithLifetimeContext(ctx))
+	require.NoError(t, err)
+	defer func() { _ = rt.Close(ctx) }()
+	_, err = rt.ImportModule(ctx, script)
+	require.NoError(t, err)
+}
+
+func writeScript(t *testing.T, dir, name, text string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(text), 0o644))
+	return path
+}
+
+func fileReport(t *testing.T, r *Report, path string) *FileReport {
+	t.Helper()
+	for _, f := range r.Files {
+		if f.Path == path {
+			return f
+		}
+	}
+	require.Failf(t, "file not reported", "%s not in report", path)
+	return nil
+}
+
+func TestCollectorAddsUpRuntimes(t *testing.T) {
+	dir := t.TempDir()
+	lib := writeScript(t, dir, "lib.js", libScript)
+	first := writeScript(t, dir, "first.js", "require(\"./lib\").pick(2);\n")
+	second := writeScript(t, dir, "second.js", "const { pick } = require(\"./lib\");\npick(0);\npick(0);\n")
+
+	c := New()
+	runCovered(t, c, first)
+	runCovered(t, c, second)
+	report := c.Report()
+
+	require.Len(t, report.Files, 3)
+	f := fileReport(t, report, lib)
+	require.Equal(t, libScript, f.Source)
+	require.Equal(t, []FunctionCount{{Name: "pick", Line: 1, Column: 1, Hits: 3}}, f.Functions)
+	require.Equal(t, []BranchCount{
+		{Kind: BranchIf, Line: 2, Column: 3, Arms: []int64{1, 2}},
+		{Kind: BranchConditional, Line: 5, Column: 10, Arms: []int64{0, 2}},
+	}, f.Branches)
+	require.Equal(t, []LineCount{{Line: 2, Hits: 3}, {Line: 3, Hits: 1}, {Line: 5, Hits: 2}, {Line: 7, Hits: 2}}, f.Lines())
+	require.Equal(t, Summary{
+		Statements: 4, StatementsHit: 4,
+		Lines: 4, LinesHit: 4,
+		Functions: 1, FunctionsHit: 1,
+		Branches: 4, BranchesHit: 3,
+	}, f.Summary())
+}
+
+func TestCollectorMapsESModulesToTheirSource(t *testing.T) {
+	dir := t.TempDir()
+	lib := writeScript(t, dir, "lib.mjs", "export function twice(x) {\n  return x * 2;\n}\n\nexport function unused() {\n  return 0;\n}\n")
+	main := writeScript(t, dir, "main.mjs", "import { twice } from \"./lib.mjs\";\ntwice(2);\n")
+
+	c := New()
+	runCovered(t, c, main)
+	f := fileReport(t, c.Report(), lib)
+
+	require.Equal(t, []FunctionCount{
+		{Name: "twice", Line: 1, Column: 8, Hits: 1},
+		{Name: "unused", Line: 5, Column: 8, Hits: 0},
+	}, f.Functions)
+	require.Equal(t, []LineCount{{Line: 2, Hits: 1}, {Line: 6, Hits: 0}}, f.Lines())
+}
+
+func TestCollectorMapsTypeScriptBundlesToTheirSource(t *testing.T) {
+	dir := t.TempDir()
+	lib := writeScript(t, dir, "math.ts", "export function clamp(n: number, max: number): number {\n  if (n > max) {\n    return max;\n  }\n  return n;\n}\n")
+	entry := writeScript(t, dir, "main.ts", "import { clamp } from \"./math\";\nconst v: number = clamp(1, 5);\n")
+
+	artifact, err := tsscript.BundleEntry(entry, tsscript.Options{Sourcemap: api.SourceMapInline})
+	require.NoError(t, err)
+	bundle := writeScript(t, dir, "bundle.js", string(artifact.Code))
+
+	c := New()
+	runCovered(t, c, bundle)
+	report := c.Report()
+
+	f := fileReport(t, report, lib)
+	require.Equal(t, []FunctionCount{{Name: "clamp", Line: 1, Column: 8, Hits: 1}}, f.Functions)
+	require.Equal(t, []LineCount{{Line: 2, Hits: 1}, {Line: 3, Hits: 0}, {Line: 5, Hits: 1}}, f.Lines())
+	require.Len(t, f.Branches, 1)
+	require.Equal(t, []int64{0, 1}, f.Branches[0].Arms)
+	require.Equal(t, []LineCount{{Line: 2, Hits: 1}}, fileReport(t, report, entry).Lines())
+}
+
+func TestCollectorExcludesNodeModules(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "node_modules", "dep"), 0o755))
+	writeScript(t, dir, "node_modules/dep/index.js", "module.exports = 1;\n")
+	main := writeScript(t, dir, "main.js", "require(\"dep\");\n")
+
+	c := New()
+	runCovered(t, c, main)
+	report := c.Report()
+	require.Len(t, report.Files, 1)
+	require.Equal(t, main, report.Files[0].Path)
+}
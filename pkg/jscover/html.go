@@ -0,0 +1,118 @@
+package jscover
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+var htmlTemplate = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Coverage</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { padding: 2px 8px; text-align: left; }
+.summary td { border-top: 1px solid #ddd; }
+.source { font-family: monospace; white-space: pre; }
+.source td { padding: 0 8px; }
+.num, .hits { color: #888; text-align: right; }
+.hit { background: #e6ffec; }
+.miss { background: #ffebe9; }
+.partial { background: #fff8c5; }
+</style>
+</head>
+<body>
+<h1>Coverage</h1>
+<p>{{.Summary}}</p>
+<table class="summary">
+<tr><th>File</th><th>Statements</th><th>Branches</th><th>Functions</th><th>Lines</th></tr>
+{{range $i, $f := .Files}}<tr><td><a href="#file-{{$i}}">{{$f.Path}}</a></td><td>{{$f.Statements}}</td><td>{{$f.Branches}}</td><td>{{$f.Functions}}</td><td>{{$f.Lines}}</td></tr>
+{{end}}</table>
+{{range $i, $f := .Files}}<h2 id="file-{{$i}}">{{$f.Path}}</h2>
+{{if $f.Source}}<table class="source">
+{{range $f.Source}}<tr class="{{.Class}}"><td class="num">{{.Number}}</td><td class="hits">{{.Hits}}</td><td>{{.Text}}</td></tr>
+{{end}}</table>
+{{else}}<p>Source not available.</p>
+{{end}}{{end}}</body>
+</html>
+`))
+
+type htmlReport struct {
+	Summary Summary
+	Files   []htmlFile
+}
+
+type htmlFile struct {
+	Path       string
+	Statements string
+	Branches   string
+	Functions  string
+	Lines      string
+	Source     []htmlLine
+}
+
+type htmlLine struct {
+	Number int
+	Hits   string
+	Class  string
+	Text   string
+}
+
+// WriteHTML writes the report as one HTML page: a summary per file, then
+// the source of each file with its lines colored by whether they ran.
+// Lines with a branch arm that never ran are marked partial.
+func (r *Report) WriteHTML(w io.Writer) error {
+	page := htmlReport{Summary: r.Summary()}
+	for _, f := range r.Files {
+		s := f.Summary()
+		hf := htmlFile{
+			Path:       f.Path,
+			Statements: percent(s.StatementsHit, s.Statements),
+			Branches:   percent(s.BranchesHit, s.Branches),
+			Functions:  percent(s.FunctionsHit, s.Functions),
+			Lines:      percent(s.LinesHit, s.Lines),
+		}
+		if f.Source != "" {
+			hf.Source = htmlSource(f)
+		}
+		page.Files = append(page.Files, hf)
+	}
+	return htmlTemplate.Execute(w, page)
+}
+
+func htmlSource(f *FileReport) []htmlLine {
+	hits := map[int]int64{}
+	for _, l := range f.Lines() {
+		hits[l.Line] = l.Hits
+	}
+	partial := map[int]bool{}
+	for _, b := range f.Branches {
+		for _, arm := range b.Arms {
+			if arm == 0 {
+				partial[b.Line] = true
+			}
+		}
+	}
+
+	var lines []htmlLine
+	for i, text := range strings.Split(strings.TrimSuffix(f.Source, "\n"), "\n") {
+		line := htmlLine{Number: i + 1, Text: text}
+		if n, ok := hits[line.Number]; ok {
+			line.Hits = fmt.Sprint(n)
+			switch {
+			case n == 0:
+				line.Class = "miss"
+			case partial[line.Number]:
+				line.Class = "partial"
+			default:
+				line.Class = "hit"
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,319 @@
+package jscover
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-go-golems/go-go-goja/pkg/jsinstrument"
+	"github.com/go-go-golems/go-go-goja/pkg/jsparse"
+)
+
+const (
+	// counterModule is the native module instrumented sources require to get
+	// the counters of the runtime that loads them.
+	counterModule = "go-go-goja:coverage"
+	// counterName is the module-local binding instrumented code increments.
+	counterName = "__gojaCov__"
+)
+
+// Branch kinds, as reported in FileReport.Branches.
+const (
+	BranchIf          = "if"
+	BranchConditional = "cond-expr"
+	BranchLogical     = "binary-expr"
+	BranchSwitch      = "switch"
+)
+
+// position is a 1-based line and column in the source that was loaded.
+type position struct {
+	line   int
+	column int
+}
+
+type function struct {
+	name string
+	at   position
+}
+
+type branch struct {
+	kind string
+	at   position
+	arms []position
+}
+
+// program is what instrumenting a source found: the statements, functions
+// and branches its counters count, in counter order.
+type program struct {
+	code       string
+	statements []position
+	functions  []function
+	branches   []branch
+}
+
+// instrumentable reports whether the loader should instrument path. JSON
+// files are data.
+func instrumentable(path string) bool {
+	return strings.ToLower(filepath.Ext(path)) != ".json"
+}
+
+// instrument parses text with jsparse and inserts counter increments: in
+// front of every statement, at the start of every function, and in every
+// arm of if statements, conditional expressions, logical expressions and
+// switch statements. Increments never add line breaks, so line numbers in
+// the instrumented code match text. id is the file ID the counters are
+// requested with.
+func instrument(path, text string, id int) (*program, error) {
+	mod, err := jsinstrument.Parse(path, text)
+	if err != nil {
+		return nil, err
+	}
+	in := &instrumenter{mod: mod, prog: &program{}, wrapped: map[jsparse.NodeID]bool{}}
+
+	for _, st := range mod.Statements() {
+		if st.Body {
+			in.inserts = append(in.inserts, jsinstrument.WrapBody(st)...)
+			in.wrapped[st.Node.ID] = true
+		}
+		in.inserts = append(in.inserts, jsinstrument.Insertion{
+			Offset: st.Start,
+			Text:   fmt.Sprintf("%s.s[%d]++; ", counterName, len(in.prog.statements)),
+			Span:   st.End - st.Start,
+		})
+		in.prog.statements = append(in.prog.statements, position{line: st.Line, column: st.Column})
+	}
+	for _, n := range mod.Nodes() {
+		switch n.Kind {
+		case "FunctionLiteral", "ArrowFunctionLiteral":
+			in.function(n)
+		case "IfStatement":
+			in.ifStatement(n)
+		case "ConditionalExpression":
+			in.conditional(n)
+		case "BinaryExpression":
+			in.logical(n)
+		case "SwitchStatement":
+			in.switchStatement(n)
+		}
+	}
+	if len(in.inserts) == 0 {
+		in.prog.code = text
+		return in.prog, nil
+	}
+
+	in.inserts = append(in.inserts, jsinstrument.Insertion{
+		Offset: mod.PreludeOffset(),
+		Text:   fmt.Sprintf("var %s = require(%q)(%d); ", counterName, counterModule, id),
+		Span:   len(text) + 1,
+	})
+	rewritten, err := mod.Rewrite(in.inserts)
+	if err != nil {
+		return nil, err
+	}
+	in.prog.code = rewritten.Code
+	return in.prog, nil
+}
+
+type instrumenter struct {
+	mod     *jsinstrument.Module
+	prog    *program
+	inserts []jsinstrument.Insertion
+	// wrapped holds the statements already wrapped in a block.
+	wrapped   map[jsparse.NodeID]bool
+	anonymous int
+}
+
+func (in *instrumenter) position(n *jsparse.NodeRecord) position {
+	line, column := jsinstrument.LineColumn(in.mod.Text, in.mod.Start(n))
+	return position{line: line, column: column}
+}
+
+// count wraps expression n as (increment, n).
+func (in *instrumenter) count(n *jsparse.NodeRecord, increment string) {
+	start, end := in.mod.Start(n), in.mod.End(n)
+	in.inserts = append(in.inserts,
+		jsinstrument.Insertion{Offset: start, Text: "(" + increment + ", ", Span: end - start},
+		jsinstrument.Insertion{Offset: end, Text: ")", Close: true, Span: end - start},
+	)
+}
+
+// countStatement inserts increment in front of statement n, the consequent
+// or alternate of an if statement, inside a block.
+func (in *instrumenter) countStatement(n *jsparse.NodeRecord, increment string) {
+	if n.Kind == "BlockStatement" {
+		in.inserts = append(in.inserts, jsinstrument.Insertion{Offset: in.mod.Start(n) + 1, Text: increment + "; "})
+		return
+	}
+	st := in.mod.Statement(n)
+	if !in.wrapped[n.ID] {
+		in.inserts = append(in.inserts, jsinstrument.WrapBody(st)...)
+		in.wrapped[n.ID] = true
+	}
+	in.inserts = append(in.inserts, jsinstrument.Insertion{Offset: st.Start, Text: increment + "; ", Span: st.End - st.Start})
+}
+
+func (in *instrumenter) function(n *jsparse.NodeRecord) {
+	id := len(in.prog.functions)
+	in.prog.functions = append(in.prog.functions, function{name: in.functionName(n), at: in.position(n)})
+	increment := fmt.Sprintf("%s.f[%d]++", counterName, id)
+	for _, child := range in.mod.Children(n) {
+		switch child.Kind {
+		case "BlockStatement":
+			in.inserts = append(in.inserts, jsinstrument.Insertion{
+				Offset: in.mod.BodyOffset(child),
+				Text:   increment + "; ",
+				Span:   in.mod.End(child) - in.mod.Start(child),
+			})
+			return
+		case "ExpressionBody":
+			in.count(child, increment)
+			return
+		}
+	}
+}
+
+// functionName returns the name of a function: its own, that of the
+// method or property it defines, or that of the variable it initializes.
+func (in *instrumenter) functionName(n *jsparse.NodeRecord) string {
+	if name := unquote(n.Label); name != "" {
+		return name
+	}
+	if parent := in.mod.Parent(n); parent != nil {
+		switch parent.Kind {
+		case "MethodDefinition", "PropertyKeyed":
+			if key := firstChild(in.mod, parent, "StringLiteral"); key != nil {
+				return unquote(key.Label)
+			}
+		case "Binding":
+			if ident := firstChild(in.mod, parent, "Identifier"); ident != nil {
+				return unquote(ident.Label)
+			}
+		}
+	}
+	in.anonymous++
+	return fmt.Sprintf("(anonymous_%d)", in.anonymous)
+}
+
+func (in *instrumenter) ifStatement(n *jsparse.NodeRecord) {
+	children := in.mod.Children(n)
+	if len(children) < 2 {
+		return
+	}
+	id := len(in.prog.branches)
+	consequent := children[1]
+	b := branch{kind: BranchIf, at: in.position(n), arms: []position{in.position(consequent)}}
+	in.countStatement(consequent, fmt.Sprintf("%s.b[%d][0]++", counterName, id))
+	if len(children) > 2 {
+		b.arms = append(b.arms, in.position(children[2]))
+		in.countStatement(children[2], fmt.Sprintf("%s.b[%d][1]++", counterName, id))
+	} else {
+		// The else goes after the block the consequent may have been
+		// wrapped in, and before any block around the if statement.
+		st := in.mod.Statement(consequent)
+		if consequent.Kind == "BlockStatement" {
+			st.End = in.mod.End(consequent)
+		}
+		b.arms = append(b.arms, in.position(n))
+		in.inserts = append(in.inserts, jsinstrument.Insertion{
+			Offset: st.End,
+			Text:   fmt.Sprintf(" else { %s.b[%d][1]++; }", counterName, id),
+			Close:  true,
+			Span:   st.End - st.Start + 1,
+		})
+	}
+	in.prog.branches = append(in.prog.branches, b)
+}
+
+func (in *instrumenter) conditional(n *jsparse.NodeRecord) {
+	children := in.mod.Children(n)
+	if len(children) != 3 {
+		return
+	}
+	id := len(in.prog.branches)
+	b := branch{kind: BranchConditional, at: in.position(n)}
+	for i, arm := range children[1:] {
+		b.arms = append(b.arms, in.position(arm))
+		in.count(arm, fmt.Sprintf("%s.b[%d][%d]++", counterName, id, i))
+	}
+	in.prog.branches = append(in.prog.branches, b)
+}
+
+// logical counts the operands of a chain of &&, || or ?? expressions, such
+// as a || b || c, as the arms of one branch.
+func (in *instrumenter) logical(n *jsparse.NodeRecord) {
+	if !isLogical(n.Label) {
+		return
+	}
+	if parent := in.mod.Parent(n); parent != nil && parent.Kind == "BinaryExpression" && parent.Label == n.Label {
+		return
+	}
+	var operands []*jsparse.NodeRecord
+	var flatten func(*jsparse.NodeRecord)
+	flatten = func(e *jsparse.NodeRecord) {
+		if e.Kind == "BinaryExpression" && e.Label == n.Label {
+			for _, child := range in.mod.Children(e) {
+				flatten(child)
+			}
+			return
+		}
+		operands = append(operands, e)
+	}
+	flatten(n)
+
+	id := len(in.prog.branches)
+	b := branch{kind: BranchLogical, at: in.position(n)}
+	for i, operand := range operands {
+		b.arms = append(b.arms, in.position(operand))
+		in.count(operand, fmt.Sprintf("%s.b[%d][%d]++", counterName, id, i))
+	}
+	in.prog.branches = append(in.prog.branches, b)
+}
+
+// switchStatement counts the cases of a switch statement that have
+// statements. Cases without any fall through to the next one.
+func (in *instrumenter) switchStatement(n *jsparse.NodeRecord) {
+	id := len(in.prog.branches)
+	b := branch{kind: BranchSwitch, at: in.position(n)}
+	for _, c := range in.mod.Children(n) {
+		if c.Kind != "CaseStatement" {
+			continue
+		}
+		for _, st := range in.mod.Children(c) {
+			if !strings.HasSuffix(st.Kind, "Statement") && st.Kind != "LexicalDeclaration" && st.Kind != "ClassDeclaration" {
+				continue
+			}
+			in.inserts = append(in.inserts, jsinstrument.Insertion{
+				Offset: in.mod.Start(st),
+				Text:   fmt.Sprintf("%s.b[%d][%d]++; ", counterName, id, len(b.arms)),
+				Span:   in.mod.End(c) - in.mod.Start(c),
+			})
+			b.arms = append(b.arms, in.position(c))
+			break
+		}
+	}
+	if len(b.arms) > 0 {
+		in.prog.branches = append(in.prog.branches, b)
+	}
+}
+
+func isLogical(operator string) bool {
+	return operator == "&&" || operator == "||" || operator == "??"
+}
+
+func firstChild(mod *jsinstrument.Module, n *jsparse.NodeRecord, kind string) *jsparse.NodeRecord {
+	for _, child := range mod.Children(n) {
+		if child.Kind == kind {
+			return child
+		}
+	}
+	return nil
+}
+
+// unquote returns the name in a jsparse label, which quotes names.
+func unquote(label string) string {
+	if name, err := strconv.Unquote(label); err == nil {
+		return name
+	}
+	return label
+}
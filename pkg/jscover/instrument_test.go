@@ -0,0 +1,58 @@
+package jscover
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrumentKeepsLinesAndCountsStatements(t *testing.T) {
+	text := "\"use strict\";\nlet n = 0;\nif (n) n++;\nfor (;;) break;\n"
+	prog, err := instrument("/tmp/a.js", text, 3)
+	require.NoError(t, err)
+
+	require.Equal(t, strings.Count(text, "\n"), strings.Count(prog.code, "\n"))
+	require.True(t, strings.HasPrefix(prog.code, "\"use strict\";var __gojaCov__ = require(\"go-go-goja:coverage\")(3); "))
+	require.Contains(t, prog.code, "if (n) { __gojaCov__.s[2]++; __gojaCov__.b[0][0]++; n++; } else { __gojaCov__.b[0][1]++; }")
+	require.Contains(t, prog.code, "for (;;) { __gojaCov__.s[4]++; break; }")
+	require.Equal(t, []position{{2, 1}, {3, 1}, {3, 8}, {4, 1}, {4, 10}}, prog.statements)
+	require.Len(t, prog.branches, 1)
+	require.Equal(t, BranchIf, prog.branches[0].kind)
+}
+
+func TestInstrumentCountsBranchArms(t *testing.T) {
+	text := "const v = a ? b : c;\nconst w = a && b && c;\nswitch (v) {\ncase 1:\n  w;\ndefault:\n  v;\n}\n"
+	prog, err := instrument("/tmp/a.js", text, 0)
+	require.NoError(t, err)
+
+	var kinds []string
+	var arms []int
+	for _, b := range prog.branches {
+		kinds = append(kinds, b.kind)
+		arms = append(arms, len(b.arms))
+	}
+	require.Equal(t, []string{BranchConditional, BranchLogical, BranchSwitch}, kinds)
+	require.Equal(t, []int{2, 3, 2}, arms)
+	require.Contains(t, prog.code, "a ? (__gojaCov__.b[0][0]++, b) : (__gojaCov__.b[0][1]++, c)")
+	require.Contains(t, prog.code, "(__gojaCov__.b[1][0]++, a) && (__gojaCov__.b[1][1]++, b) && (__gojaCov__.b[1][2]++, c)")
+}
+
+func TestInstrumentNamesFunctions(t *testing.T) {
+	text := "function f() { return 1; }\nconst g = () => 2;\nconst o = { m() { return 3; } };\n[1].map(function () {});\n"
+	prog, err := instrument("/tmp/a.js", text, 0)
+	require.NoError(t, err)
+
+	var names []string
+	for _, fn := range prog.functions {
+		names = append(names, fn.name)
+	}
+	require.Equal(t, []string{"f", "g", "m", "(anonymous_1)"}, names)
+	require.Contains(t, prog.code, "() => (__gojaCov__.f[1]++, 2)")
+}
+
+func TestInstrumentableSkipsData(t *testing.T) {
+	require.False(t, instrumentable("/x/data.json"))
+	require.True(t, instrumentable("/x/main.js"))
+	require.True(t, instrumentable("/x/mod.mjs"))
+}
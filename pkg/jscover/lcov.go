@@ -0,0 +1,43 @@
+package jscover
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// WriteLCOV writes the report in the lcov tracefile format that genhtml,
+// Codecov and most editors read.
+func (r *Report) WriteLCOV(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range r.Files {
+		s := f.Summary()
+		_, _ = fmt.Fprintf(bw, "TN:\nSF:%s\n", f.Path)
+		for _, fn := range f.Functions {
+			_, _ = fmt.Fprintf(bw, "FN:%d,%s\n", fn.Line, fn.Name)
+		}
+		for _, fn := range f.Functions {
+			_, _ = fmt.Fprintf(bw, "FNDA:%d,%s\n", fn.Hits, fn.Name)
+		}
+		_, _ = fmt.Fprintf(bw, "FNF:%d\nFNH:%d\n", s.Functions, s.FunctionsHit)
+		for _, l := range f.Lines() {
+			_, _ = fmt.Fprintf(bw, "DA:%d,%d\n", l.Line, l.Hits)
+		}
+		_, _ = fmt.Fprintf(bw, "LF:%d\nLH:%d\n", s.Lines, s.LinesHit)
+		for i, b := range f.Branches {
+			reached := false
+			for _, hits := range b.Arms {
+				reached = reached || hits > 0
+			}
+			for j, hits := range b.Arms {
+				taken := "-"
+				if reached {
+					taken = fmt.Sprint(hits)
+				}
+				_, _ = fmt.Fprintf(bw, "BRDA:%d,%d,%d,%s\n", b.Line, i, j, taken)
+			}
+		}
+		_, _ = fmt.Fprintf(bw, "BRF:%d\nBRH:%d\nend_of_record\n", s.Branches, s.BranchesHit)
+	}
+	return bw.Flush()
+}
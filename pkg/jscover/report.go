@@ -0,0 +1,378 @@
+package jscover
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Files WriteDir writes into its directory.
+const (
+	LCOVFile      = "lcov.info"
+	CoberturaFile = "cobertura.xml"
+	HTMLFile      = "index.html"
+)
+
+// Report is the coverage of the files a Collector instrumented, summed
+// over all runtimes.
+type Report struct {
+	// Files are ordered by path.
+	Files []*FileReport
+}
+
+// FileReport is the coverage of one original file.
+type FileReport struct {
+	Path string
+	// Source is the text of the file, when it could be read.
+	Source string
+	// Statements, Functions and Branches are ordered by position.
+	Statements []StatementCount
+	Functions  []FunctionCount
+	Branches   []BranchCount
+}
+
+// StatementCount is how often a statement ran. Line and Column are 1-based.
+type StatementCount struct {
+	Line   int
+	Column int
+	Hits   int64
+}
+
+// FunctionCount is how often a function was called.
+type FunctionCount struct {
+	Name   string
+	Line   int
+	Column int
+	Hits   int64
+}
+
+// BranchCount is how often each arm of a branch ran. Kind is one of
+// BranchIf, BranchConditional, BranchLogical or BranchSwitch. An if
+// statement has two arms, the second one counting when the condition was
+// false even without an else. The arms of a logical expression are its
+// operands.
+type BranchCount struct {
+	Kind   string
+	Line   int
+	Column int
+	Arms   []int64
+}
+
+// LineCount is how often a line ran: the count of the statement on it that
+// ran most.
+type LineCount struct {
+	Line int
+	Hits int64
+}
+
+// Summary counts what was found and what ran at least once.
+type Summary struct {
+	Statements, StatementsHit int
+	Lines, LinesHit           int
+	Functions, FunctionsHit   int
+	Branches, BranchesHit     int
+}
+
+// Report adds up the counters of every runtime so far. Call it once the
+// runtimes have finished running: counters are read without synchronizing
+// with the runtimes that increment them.
+func (c *Collector) Report() *Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	totals := make([]*counters, len(c.files))
+	for i, f := range c.files {
+		t := &counters{s: make([]int64, f.numStatements), f: make([]int64, f.numFunctions)}
+		for _, arms := range f.numArms {
+			t.b = append(t.b, make([]int64, arms))
+		}
+		totals[i] = t
+	}
+	for _, rc := range c.runtimes {
+		for id, cnt := range rc.files {
+			t := totals[id]
+			addCounts(t.s, cnt.s)
+			addCounts(t.f, cnt.f)
+			for i := range cnt.b {
+				addCounts(t.b[i], cnt.b[i])
+			}
+		}
+	}
+
+	builders := map[string]*fileBuilder{}
+	builder := func(path string) *fileBuilder {
+		b := builders[path]
+		if b == nil {
+			b = newFileBuilder(path)
+			builders[path] = b
+		}
+		return b
+	}
+	for i, f := range c.files {
+		t := totals[i]
+		for j, at := range f.statements {
+			if at != nil {
+				builder(at.path).statement(at, t.s[j])
+			}
+		}
+		for j, fn := range f.functions {
+			if fn.at != nil {
+				builder(fn.at.path).function(fn.name, fn.at, t.f[j])
+			}
+		}
+		for j, b := range f.branches {
+			if b.at != nil {
+				builder(b.at.path).branch(b.kind, b.at, b.arms, t.b[j])
+			}
+		}
+	}
+
+	report := &Report{}
+	for path, b := range builders {
+		fr := b.build()
+		if content, ok := c.contents[path]; ok {
+			fr.Source = content
+		} else if data, err := os.ReadFile(path); err == nil {
+			fr.Source = string(data)
+		}
+		report.Files = append(report.Files, fr)
+	}
+	sort.Slice(report.Files, func(i, j int) bool { return report.Files[i].Path < report.Files[j].Path })
+	return report
+}
+
+func addCounts(dst, src []int64) {
+	for i := range src {
+		if i < len(dst) {
+			dst[i] += src[i]
+		}
+	}
+}
+
+// fileBuilder merges the counts of every source that maps to one file.
+// Sources loaded again with other text, or bundled into several others,
+// count the same statements more than once.
+type fileBuilder struct {
+	path       string
+	statements map[[2]int]int64
+	functions  map[functionKey]int64
+	branches   map[branchKey][]int64
+}
+
+type functionKey struct {
+	name         string
+	line, column int
+}
+
+// branchKey tells branches apart by their arms too: the chains of a || b
+// && c start at the same position.
+type branchKey struct {
+	kind         string
+	line, column int
+	arms         string
+}
+
+func newFileBuilder(path string) *fileBuilder {
+	return &fileBuilder{
+		path:       path,
+		statements: map[[2]int]int64{},
+		functions:  map[functionKey]int64{},
+		branches:   map[branchKey][]int64{},
+	}
+}
+
+func (b *fileBuilder) statement(at *location, hits int64) {
+	b.statements[[2]int{at.line, at.column}] += hits
+}
+
+func (b *fileBuilder) function(name string, at *location, hits int64) {
+	b.functions[functionKey{name: name, line: at.line, column: at.column}] += hits
+}
+
+func (b *fileBuilder) branch(kind string, at *location, armsAt []*location, arms []int64) {
+	key := branchKey{kind: kind, line: at.line, column: at.column}
+	for _, arm := range armsAt {
+		if arm != nil {
+			key.arms += fmt.Sprintf("%d:%d,", arm.line, arm.column)
+		}
+	}
+	total := b.branches[key]
+	for len(total) < len(arms) {
+		total = append(total, 0)
+	}
+	addCounts(total, arms)
+	b.branches[key] = total
+}
+
+func (b *fileBuilder) build() *FileReport {
+	fr := &FileReport{Path: b.path}
+	for at, hits := range b.statements {
+		fr.Statements = append(fr.Statements, StatementCount{Line: at[0], Column: at[1], Hits: hits})
+	}
+	sort.Slice(fr.Statements, func(i, j int) bool {
+		return before(fr.Statements[i].Line, fr.Statements[i].Column, fr.Statements[j].Line, fr.Statements[j].Column)
+	})
+	for key, hits := range b.functions {
+		fr.Functions = append(fr.Functions, FunctionCount{Name: key.name, Line: key.line, Column: key.column, Hits: hits})
+	}
+	sort.Slice(fr.Functions, func(i, j int) bool {
+		a, c := fr.Functions[i], fr.Functions[j]
+		if a.Line != c.Line || a.Column != c.Column {
+			return before(a.Line, a.Column, c.Line, c.Column)
+		}
+		return a.Name < c.Name
+	})
+	keys := make([]branchKey, 0, len(b.branches))
+	for key := range b.branches {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, c := keys[i], keys[j]
+		if a.line != c.line || a.column != c.column {
+			return before(a.line, a.column, c.line, c.column)
+		}
+		if a.kind != c.kind {
+			return a.kind < c.kind
+		}
+		return a.arms < c.arms
+	})
+	for _, key := range keys {
+		fr.Branches = append(fr.Branches, BranchCount{Kind: key.kind, Line: key.line, Column: key.column, Arms: b.branches[key]})
+	}
+	return fr
+}
+
+func before(line1, column1, line2, column2 int) bool {
+	if line1 != line2 {
+		return line1 < line2
+	}
+	return column1 < column2
+}
+
+// Lines returns the lines of the file that have statements, in order.
+func (f *FileReport) Lines() []LineCount {
+	var lines []LineCount
+	for _, st := range f.Statements {
+		if n := len(lines); n > 0 && lines[n-1].Line == st.Line {
+			if st.Hits > lines[n-1].Hits {
+				lines[n-1].Hits = st.Hits
+			}
+			continue
+		}
+		lines = append(lines, LineCount{Line: st.Line, Hits: st.Hits})
+	}
+	return lines
+}
+
+// Summary counts the statements, lines, functions and branch arms of the
+// file.
+func (f *FileReport) Summary() Summary {
+	var s Summary
+	for _, st := range f.Statements {
+		s.Statements++
+		if st.Hits > 0 {
+			s.StatementsHit++
+		}
+	}
+	for _, l := range f.Lines() {
+		s.Lines++
+		if l.Hits > 0 {
+			s.LinesHit++
+		}
+	}
+	for _, fn := range f.Functions {
+		s.Functions++
+		if fn.Hits > 0 {
+			s.FunctionsHit++
+		}
+	}
+	for _, b := range f.Branches {
+		for _, hits := range b.Arms {
+			s.Branches++
+			if hits > 0 {
+				s.BranchesHit++
+			}
+		}
+	}
+	return s
+}
+
+// Summary adds up the summaries of all files.
+func (r *Report) Summary() Summary {
+	var s Summary
+	for _, f := range r.Files {
+		fs := f.Summary()
+		s.Statements += fs.Statements
+		s.StatementsHit += fs.StatementsHit
+		s.Lines += fs.Lines
+		s.LinesHit += fs.LinesHit
+		s.Functions += fs.Functions
+		s.FunctionsHit += fs.FunctionsHit
+		s.Branches += fs.Branches
+		s.BranchesHit += fs.BranchesHit
+	}
+	return s
+}
+
+// String formats the summary as one line of percentages.
+func (s Summary) String() string {
+	return fmt.Sprintf("statements %s, branches %s, functions %s, lines %s",
+		percent(s.StatementsHit, s.Statements),
+		percent(s.BranchesHit, s.Branches),
+		percent(s.FunctionsHit, s.Functions),
+		percent(s.LinesHit, s.Lines))
+}
+
+func percent(hit, total int) string {
+	return fmt.Sprintf("%.1f%% (%d/%d)", 100*rate(hit, total), hit, total)
+}
+
+// rate returns hit/total, or 1 when there is nothing to cover.
+func rate(hit, total int) float64 {
+	if total == 0 {
+		return 1
+	}
+	return float64(hit) / float64(total)
+}
+
+// WriteDir writes the lcov, Cobertura and HTML reports into dir, creating
+// it if needed.
+func (r *Report) WriteDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return errors.Wrap(err, "create coverage directory")
+	}
+	writers := []struct {
+		name  string
+		write func(io.Writer) error
+	}{
+		{LCOVFile, r.WriteLCOV},
+		{CoberturaFile, r.WriteCobertura},
+		{HTMLFile, r.WriteHTML},
+	}
+	for _, wr := range writers {
+		if err := writeFile(filepath.Join(dir, wr.name), wr.write); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFile(path string, write func(io.Writer) error) (retErr error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrapf(err, "create %s", path)
+	}
+	defer func() {
+		if err := f.Close(); err != nil && retErr == nil {
+			retErr = errors.Wrapf(err, "close %s", path)
+		}
+	}()
+	if err := write(f); err != nil {
+		return errors.Wrapf(err, "write %s", path)
+	}
+	return nil
+}
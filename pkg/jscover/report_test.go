@@ -0,0 +1,123 @@
+package jscover
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func sampleReport() *Report {
+	return &Report{Files: []*FileReport{
+		{
+			Path:   "/src/app/lib/math.js",
+			Source: "function add(a, b) {\n  return a && b;\n}\nfunction unused() {}\n",
+			Statements: []StatementCount{
+				{Line: 2, Column: 3, Hits: 2},
+			},
+			Functions: []FunctionCount{
+				{Name: "add", Line: 1, Column: 1, Hits: 2},
+				{Name: "unused", Line: 4, Column: 1, Hits: 0},
+			},
+			Branches: []BranchCount{
+				{Kind: BranchLogical, Line: 2, Column: 10, Arms: []int64{2, 0}},
+				{Kind: BranchIf, Line: 4, Column: 1, Arms: []int64{0, 0}},
+			},
+		},
+		{
+			Path:   "/src/app/main.js",
+			Source: "add(1, 2);\n",
+			Statements: []StatementCount{
+				{Line: 1, Column: 1, Hits: 1},
+				{Line: 1, Column: 5, Hits: 0},
+			},
+		},
+	}}
+}
+
+func TestWriteLCOV(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, sampleReport().WriteLCOV(&out))
+	require.Equal(t, `TN:
+SF:/src/app/lib/math.js
+FN:1,add
+FN:4,unused
+FNDA:2,add
+FNDA:0,unused
+FNF:2
+FNH:1
+DA:2,2
+LF:1
+LH:1
+BRDA:2,0,0,2
+BRDA:2,0,1,0
+BRDA:4,1,0,-
+BRDA:4,1,1,-
+BRF:4
+BRH:1
+end_of_record
+TN:
+SF:/src/app/main.js
+FNF:0
+FNH:0
+DA:1,1
+LF:1
+LH:1
+BRF:0
+BRH:0
+end_of_record
+`, out.String())
+}
+
+func TestWriteCobertura(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, sampleReport().WriteCobertura(&out))
+
+	var doc coberturaCoverage
+	require.NoError(t, xml.Unmarshal(out.Bytes(), &doc))
+	require.Equal(t, []string{"/src/app"}, doc.Sources)
+	require.Equal(t, 2, doc.LinesValid)
+	require.Equal(t, 1, doc.BranchesCovered)
+	require.Len(t, doc.Packages, 2)
+	require.Equal(t, ".", doc.Packages[0].Name)
+	require.Equal(t, "main", doc.Packages[0].Classes[0].Name)
+
+	lib := doc.Packages[1]
+	require.Equal(t, "lib", lib.Name)
+	class := lib.Classes[0]
+	require.Equal(t, "lib.math", class.Name)
+	require.Equal(t, "lib/math.js", class.Filename)
+	require.Equal(t, []coberturaLine{{Number: 2, Hits: 2, Branch: true, ConditionCoverage: "50% (1/2)"}}, class.Lines)
+	require.Len(t, class.Methods, 2)
+	require.Equal(t, "unused", class.Methods[1].Name)
+	require.Equal(t, float64(0), class.Methods[1].LineRate)
+}
+
+func TestWriteHTMLMarksLines(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, sampleReport().WriteHTML(&out))
+	html := out.String()
+	require.Contains(t, html, `<tr class="partial"><td class="num">2</td><td class="hits">2</td><td>  return a &amp;&amp; b;</td></tr>`)
+	require.Contains(t, html, `<tr class=""><td class="num">4</td><td class="hits"></td><td>function unused() {}</td></tr>`)
+	require.Contains(t, html, `<tr class="hit"><td class="num">1</td><td class="hits">1</td><td>add(1, 2);</td></tr>`)
+}
+
+func TestWriteDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "coverage")
+	require.NoError(t, sampleReport().WriteDir(dir))
+	for _, name := range []string{LCOVFile, CoberturaFile, HTMLFile} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		require.NotEmpty(t, strings.TrimSpace(string(data)))
+	}
+}
+
+func TestSummaryString(t *testing.T) {
+	require.Equal(t,
+		"statements 66.7% (2/3), branches 25.0% (1/4), functions 50.0% (1/2), lines 100.0% (2/2)",
+		sampleReport().Summary().String())
+}
@@ -0,0 +1,185 @@
+package jscover
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-go-golems/go-go-goja/pkg/jserrors"
+	"github.com/go-sourcemap/sourcemap"
+	"github.com/pkg/errors"
+)
+
+// location is a position in an original source file.
+type location struct {
+	path   string
+	line   int
+	column int
+}
+
+// locator maps a position in a loaded source to the original file it was
+// compiled from. ok is false for code that belongs to no file, such as
+// helpers a compiler added.
+type locator interface {
+	locate(p position) (location, bool)
+	// content returns the text of an original file, if the locator knows
+	// it.
+	content(path string) (string, bool)
+}
+
+// fileLocator maps a loaded source to the file it was read from. Loaders
+// may add code of their own to a file, as jsverbs adds a prelude after its
+// directives and registrations at its end: loaded[split:split+inserted] and
+// whatever follows the file are not reported.
+type fileLocator struct {
+	path     string
+	loaded   string
+	text     string
+	split    int
+	inserted int
+}
+
+// newFileLocator returns a locator for loaded, the source of the file path
+// whose text on disk is disk.
+func newFileLocator(path, loaded, disk string) *fileLocator {
+	l := &fileLocator{path: path, loaded: loaded, text: loaded, split: len(loaded)}
+	if disk == "" || disk == loaded {
+		return l
+	}
+	split := 0
+	for split < len(loaded) && split < len(disk) && loaded[split] == disk[split] {
+		split++
+	}
+	if i := strings.Index(loaded[split:], disk[split:]); i >= 0 {
+		l.text, l.split, l.inserted = disk, split, i
+	}
+	return l
+}
+
+func (l *fileLocator) locate(p position) (location, bool) {
+	offset := offsetOf(l.loaded, p)
+	switch {
+	case offset < l.split:
+	case offset < l.split+l.inserted:
+		return location{}, false
+	default:
+		offset -= l.inserted
+	}
+	if offset < 0 || offset >= len(l.text) {
+		return location{}, false
+	}
+	line := 1 + strings.Count(l.text[:offset], "\n")
+	column := offset - strings.LastIndex(l.text[:offset], "\n")
+	return location{path: l.path, line: line, column: column}, true
+}
+
+func (l *fileLocator) content(path string) (string, bool) {
+	return l.text, path == l.path
+}
+
+// offsetOf converts a 1-based position into a 0-based offset in text.
+func offsetOf(text string, p position) int {
+	offset := 0
+	for line := 1; line < p.line; line++ {
+		i := strings.IndexByte(text[offset:], '\n')
+		if i < 0 {
+			return len(text)
+		}
+		offset += i + 1
+	}
+	return offset + p.column - 1
+}
+
+// sourceMapLocator maps a compiled source through the source map inlined
+// at its end, as engine.ESMSourceLoader and tsscript emit them.
+type sourceMapLocator struct {
+	consumer *sourcemap.Consumer
+	// dir resolves relative source paths.
+	dir      string
+	contents map[string]string
+}
+
+// inlineSourceMap returns a locator for the source map inlined in text, or
+// nil when there is none.
+func inlineSourceMap(path, text string) (*sourceMapLocator, error) {
+	raw, err := jserrors.InlineSourceMap([]byte(text))
+	if err != nil || raw == nil {
+		return nil, err
+	}
+	consumer, err := sourcemap.Parse("", raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse source map")
+	}
+	return &sourceMapLocator{consumer: consumer, dir: filepath.Dir(path), contents: map[string]string{}}, nil
+}
+
+func (l *sourceMapLocator) locate(p position) (location, bool) {
+	source, _, line, column, ok := l.consumer.Source(p.line, p.column-1)
+	if !ok || source == "" {
+		return location{}, false
+	}
+	path := l.resolve(source)
+	if _, known := l.contents[path]; !known {
+		l.contents[path] = l.consumer.SourceContent(source)
+	}
+	at := location{path: path, line: line, column: column + 1}
+	if isModuleGlue(l.contents[path], at) {
+		return location{}, false
+	}
+	return at, true
+}
+
+// isModuleGlue reports whether at is an import or export keyword. What a
+// compiler maps there, such as the require calls and export getters
+// esbuild emits for ES modules, does not run code of the file.
+func isModuleGlue(content string, at location) bool {
+	if content == "" {
+		return false
+	}
+	offset := offsetOf(content, position{line: at.line, column: at.column})
+	if offset < 0 || offset > len(content) {
+		return false
+	}
+	rest := content[offset:]
+	for _, keyword := range []string{"import", "export"} {
+		if strings.HasPrefix(rest, keyword) && (len(rest) == len(keyword) || !isIdentifierByte(rest[len(keyword)])) {
+			return true
+		}
+	}
+	return false
+}
+
+func isIdentifierByte(b byte) bool {
+	return b == '_' || b == '$' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= 0x80
+}
+
+func (l *sourceMapLocator) content(path string) (string, bool) {
+	text := l.contents[path]
+	return text, text != ""
+}
+
+// resolve turns a source of the map into a file path. Relative sources are
+// relative to the compiled file or, for bundles esbuild wrote without an
+// output directory, to the working directory. The first one that exists
+// wins.
+func (l *sourceMapLocator) resolve(source string) string {
+	source = strings.TrimPrefix(source, "file://")
+	if filepath.IsAbs(source) {
+		return filepath.Clean(source)
+	}
+	path := filepath.Join(l.dir, filepath.FromSlash(source))
+	if fileExists(path) {
+		return path
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if p := filepath.Join(wd, filepath.FromSlash(source)); fileExists(p) {
+			return p
+		}
+	}
+	return path
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
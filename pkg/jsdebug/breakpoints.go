@@ -0,0 +1,137 @@
+package jsdebug
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/dop251/goja"
+)
+
+// breakpoint is a line breakpoint requested by the client.
+type breakpoint struct {
+	id        int
+	path      string
+	line      int
+	condition string
+	// site is where the breakpoint stops once the source is known: the
+	// first statement on line or after it.
+	site    *site
+	message string
+}
+
+// breakpointSpec is one requested breakpoint of a setBreakpoints request.
+type breakpointSpec struct {
+	line      int
+	condition string
+}
+
+// setBreakpoints replaces the breakpoints of path. Sources that no runtime
+// has loaded yet are read from disk to verify the lines; they are resolved
+// again when a runtime loads them.
+func (d *Debugger) setBreakpoints(path string, specs []breakpointSpec) []Breakpoint {
+	path = filepath.Clean(path)
+	d.mu.Lock()
+	_, loaded := d.sources[path]
+	d.mu.Unlock()
+	if !loaded {
+		if data, err := os.ReadFile(path); err == nil && instrumentable(path, data) {
+			if _, err := d.load(path, path, string(data)); err != nil {
+				log.Debug().Err(err).Str("path", path).Msg("jsdebug: cannot verify breakpoints")
+			}
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, bp := range d.breakpoints[path] {
+		if bp.site != nil {
+			delete(d.bySite, bp.site.id)
+		}
+	}
+	bps := make([]*breakpoint, 0, len(specs))
+	for _, spec := range specs {
+		d.nextBreakpoint++
+		bps = append(bps, &breakpoint{id: d.nextBreakpoint, path: path, line: spec.line, condition: spec.condition})
+	}
+	d.breakpoints[path] = bps
+	d.resolveBreakpointsLocked(path)
+	return breakpointInfos(bps)
+}
+
+// resolveBreakpointsLocked binds the breakpoints of path to the sites of
+// its current source and returns them.
+func (d *Debugger) resolveBreakpointsLocked(path string) []*breakpoint {
+	bps := d.breakpoints[path]
+	s := d.sources[path]
+	if len(bps) == 0 || s == nil {
+		return nil
+	}
+	for _, bp := range bps {
+		if bp.site != nil && d.bySite[bp.site.id] == bp {
+			delete(d.bySite, bp.site.id)
+		}
+		bp.site = s.siteForLine(bp.line)
+		bp.message = ""
+		if bp.site == nil {
+			bp.message = "No statement at or after this line"
+			continue
+		}
+		if _, taken := d.bySite[bp.site.id]; taken {
+			// Two requested lines resolved to the same statement; the
+			// first one stops there.
+			continue
+		}
+		d.bySite[bp.site.id] = bp
+	}
+	return bps
+}
+
+func notifyBreakpoints(client eventSink, infos []Breakpoint) {
+	if client == nil {
+		return
+	}
+	for _, info := range infos {
+		client.event("breakpoint", BreakpointEvent{Reason: "changed", Breakpoint: info})
+	}
+}
+
+// breakpointInfos describes breakpoints for the client. Call it with d.mu
+// held.
+func breakpointInfos(bps []*breakpoint) []Breakpoint {
+	out := make([]Breakpoint, 0, len(bps))
+	for _, bp := range bps {
+		info := Breakpoint{ID: bp.id, Verified: bp.site != nil, Line: bp.line, Message: bp.message, Source: &Source{Name: filepath.Base(bp.path), Path: bp.path}}
+		if bp.site != nil {
+			info.Line = bp.site.line
+			info.Column = bp.site.column
+		}
+		out = append(out, info)
+	}
+	return out
+}
+
+// breakpointHits evaluates the breakpoint's condition in the scope of the
+// statement. A condition that throws stops, so the mistake is visible.
+func (d *Debugger) breakpointHits(t *thread, bp *breakpoint, eval goja.Callable) bool {
+	if bp.condition == "" || eval == nil {
+		return true
+	}
+	t.inspecting = true
+	defer func() { t.inspecting = false }()
+	v, err := eval(goja.Undefined(), t.vm.ToValue(bp.condition))
+	if err != nil {
+		d.output("stderr", "breakpoint condition "+bp.condition+" failed: "+err.Error()+"\n")
+		return true
+	}
+	return v.ToBoolean()
+}
+
+// output sends text to the client's debug console.
+func (d *Debugger) output(category, text string) {
+	d.mu.Lock()
+	client := d.client
+	d.mu.Unlock()
+	if client != nil {
+		client.event("output", OutputEvent{Category: category, Output: text})
+	}
+}
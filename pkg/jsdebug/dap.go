@@ -0,0 +1,384 @@
+package jsdebug
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// session is one attached Debug Adapter Protocol client.
+type session struct {
+	d *Debugger
+	r *bufio.Reader
+
+	mu  sync.Mutex
+	w   io.Writer
+	seq int
+
+	// lineBase and columnBase are 1 unless the client counts from 0.
+	lineBase   int
+	columnBase int
+	launch     LaunchArguments
+}
+
+// Serve speaks the Debug Adapter Protocol on r and w until the client
+// disconnects, the stream ends or ctx is done. Only one client is served at
+// a time.
+func (d *Debugger) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s := &session{d: d, r: bufio.NewReader(r), w: w, lineBase: 1, columnBase: 1}
+	if err := d.attach(s); err != nil {
+		return err
+	}
+	defer d.detach(s)
+
+	requests := make(chan *Request)
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			req, err := s.read()
+			if err != nil {
+				readErr <- err
+				return
+			}
+			requests <- req
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-readErr:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case req := <-requests:
+			if s.handle(req) {
+				return nil
+			}
+		}
+	}
+}
+
+// read returns the next request. It returns io.EOF once the client closes
+// the stream between messages.
+func (s *session) read() (*Request, error) {
+	length := -1
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && line == "" && length < 0 {
+				return nil, io.EOF
+			}
+			return nil, errors.Wrap(err, "read header")
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || length < 0 {
+				return nil, errors.Errorf("invalid Content-Length %q", value)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, errors.New("missing Content-Length header")
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.r, body); err != nil {
+		return nil, errors.Wrap(err, "read body")
+	}
+	req := &Request{}
+	if err := json.Unmarshal(body, req); err != nil {
+		return nil, errors.Wrap(err, "decode request")
+	}
+	return req, nil
+}
+
+func (s *session) write(msg func(seq int) any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	body, err := json.Marshal(msg(s.seq))
+	if err != nil {
+		log.Error().Err(err).Msg("jsdebug: encode message")
+		return
+	}
+	if _, err := fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		log.Debug().Err(err).Msg("jsdebug: write message")
+	}
+}
+
+// event implements eventSink.
+func (s *session) event(name string, body any) {
+	if b, ok := body.(BreakpointEvent); ok {
+		b.Breakpoint = s.breakpointOut(b.Breakpoint)
+		body = b
+	}
+	s.write(func(seq int) any {
+		return Event{ProtocolMessage: ProtocolMessage{Seq: seq, Type: "event"}, Event: name, Body: body}
+	})
+}
+
+func (s *session) respond(req *Request, body any, err error) {
+	s.write(func(seq int) any {
+		resp := Response{
+			ProtocolMessage: ProtocolMessage{Seq: seq, Type: "response"},
+			RequestSeq:      req.Seq,
+			Command:         req.Command,
+			Success:         err == nil,
+			Body:            body,
+		}
+		if err != nil {
+			resp.Message = err.Error()
+			resp.Body = nil
+		}
+		return resp
+	})
+}
+
+// handle answers one request and reports whether the session ends.
+func (s *session) handle(req *Request) bool {
+	switch req.Command {
+	case "initialize":
+		var args InitializeArguments
+		if err := decodeArguments(req, &args); err != nil {
+			s.respond(req, nil, err)
+			return false
+		}
+		if args.LinesStartAt1 != nil && !*args.LinesStartAt1 {
+			s.lineBase = 0
+		}
+		if args.ColumnsStartAt1 != nil && !*args.ColumnsStartAt1 {
+			s.columnBase = 0
+		}
+		s.respond(req, Capabilities{
+			SupportsConfigurationDoneRequest: true,
+			SupportsConditionalBreakpoints:   true,
+			SupportsEvaluateForHovers:        true,
+		}, nil)
+		s.event("initialized", nil)
+	case "launch", "attach":
+		err := decodeArguments(req, &s.launch)
+		s.respond(req, nil, err)
+	case "setBreakpoints":
+		var args SetBreakpointsArguments
+		if err := decodeArguments(req, &args); err != nil {
+			s.respond(req, nil, err)
+			return false
+		}
+		if args.Source.Path == "" {
+			s.respond(req, nil, errors.New("setBreakpoints needs a source path"))
+			return false
+		}
+		specs := make([]breakpointSpec, 0, len(args.Breakpoints))
+		for _, bp := range args.Breakpoints {
+			specs = append(specs, breakpointSpec{line: bp.Line + 1 - s.lineBase, condition: bp.Condition})
+		}
+		infos := s.d.setBreakpoints(args.Source.Path, specs)
+		for i := range infos {
+			infos[i] = s.breakpointOut(infos[i])
+		}
+		s.respond(req, map[string]any{"breakpoints": infos}, nil)
+	case "setExceptionBreakpoints":
+		s.respond(req, map[string]any{"breakpoints": []Breakpoint{}}, nil)
+	case "configurationDone":
+		s.respond(req, nil, nil)
+		s.d.configured(s.launch.StopOnEntry)
+	case "threads":
+		s.respond(req, map[string]any{"threads": s.d.threadList()}, nil)
+	case "stackTrace":
+		var args StackTraceArguments
+		if err := decodeArguments(req, &args); err != nil {
+			s.respond(req, nil, err)
+			return false
+		}
+		frames, err := s.d.stackTrace(args.ThreadID)
+		if err != nil {
+			s.respond(req, nil, err)
+			return false
+		}
+		total := len(frames)
+		if args.StartFrame > 0 && args.StartFrame < len(frames) {
+			frames = frames[args.StartFrame:]
+		} else if args.StartFrame >= len(frames) {
+			frames = nil
+		}
+		if args.Levels > 0 && args.Levels < len(frames) {
+			frames = frames[:args.Levels]
+		}
+		for i := range frames {
+			frames[i].Line += s.lineBase - 1
+			frames[i].Column += s.columnBase - 1
+		}
+		if frames == nil {
+			frames = []StackFrame{}
+		}
+		s.respond(req, map[string]any{"stackFrames": frames, "totalFrames": total}, nil)
+	case "scopes":
+		var args ScopesArguments
+		if err := decodeArguments(req, &args); err != nil {
+			s.respond(req, nil, err)
+			return false
+		}
+		scopes, err := s.d.scopes(args.FrameID)
+		s.respond(req, map[string]any{"scopes": scopes}, err)
+	case "variables":
+		var args VariablesArguments
+		if err := decodeArguments(req, &args); err != nil {
+			s.respond(req, nil, err)
+			return false
+		}
+		vars, err := s.d.variables(args.VariablesReference)
+		s.respond(req, map[string]any{"variables": vars}, err)
+	case "evaluate":
+		var args EvaluateArguments
+		if err := decodeArguments(req, &args); err != nil {
+			s.respond(req, nil, err)
+			return false
+		}
+		v, err := s.d.evaluate(args.Expression, args.FrameID)
+		s.respond(req, EvaluateResponseBody{Result: v.Value, Type: v.Type, VariablesReference: v.VariablesReference}, err)
+	case "continue", "next", "stepIn", "stepOut":
+		var args ThreadArguments
+		if err := decodeArguments(req, &args); err != nil {
+			s.respond(req, nil, err)
+			return false
+		}
+		mode := map[string]stepMode{"continue": stepNone, "next": stepOver, "stepIn": stepIn, "stepOut": stepOut}[req.Command]
+		run, err := s.d.resume(args.ThreadID, mode)
+		if req.Command == "continue" && err == nil {
+			s.respond(req, map[string]any{"allThreadsContinued": false}, nil)
+		} else {
+			s.respond(req, nil, err)
+		}
+		if run != nil {
+			run()
+		}
+	case "pause":
+		var args ThreadArguments
+		if err := decodeArguments(req, &args); err != nil {
+			s.respond(req, nil, err)
+			return false
+		}
+		s.respond(req, nil, s.d.requestPause(args.ThreadID))
+	case "disconnect":
+		s.respond(req, nil, nil)
+		return true
+	default:
+		s.respond(req, nil, errors.Errorf("unsupported request %q", req.Command))
+	}
+	return false
+}
+
+func (s *session) breakpointOut(bp Breakpoint) Breakpoint {
+	if bp.Line > 0 {
+		bp.Line += s.lineBase - 1
+	}
+	if bp.Column > 0 {
+		bp.Column += s.columnBase - 1
+	}
+	return bp
+}
+
+func decodeArguments(req *Request, v any) error {
+	if len(req.Arguments) == 0 || string(req.Arguments) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(req.Arguments, v); err != nil {
+		return errors.Wrapf(err, "decode %s arguments", req.Command)
+	}
+	return nil
+}
+
+// terminate tells the attached client that the program ended.
+func (d *Debugger) terminate(exitCode int) {
+	d.mu.Lock()
+	client := d.client
+	d.mu.Unlock()
+	if client == nil {
+		return
+	}
+	client.event("exited", ExitedEvent{ExitCode: exitCode})
+	client.event("terminated", nil)
+}
+
+// Listener accepts Debug Adapter Protocol clients on a TCP address, one at a
+// time, for editors that attach to a running program (VS Code's
+// "debugServer" setting, nvim-dap's "server" adapters).
+type Listener struct {
+	d      *Debugger
+	ln     net.Listener
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// Listen starts accepting clients for d on addr, such as "127.0.0.1:4711".
+func Listen(addr string, d *Debugger) (*Listener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "listen for debug clients on %s", addr)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &Listener{d: d, ln: ln, cancel: cancel, done: make(chan struct{})}
+	go l.accept(ctx)
+	return l, nil
+}
+
+// Addr returns the address clients connect to.
+func (l *Listener) Addr() net.Addr {
+	return l.ln.Addr()
+}
+
+func (l *Listener) accept(ctx context.Context) {
+	defer close(l.done)
+	for {
+		conn, err := l.ln.Accept()
+		if err != nil {
+			return
+		}
+		l.mu.Lock()
+		l.conn = conn
+		l.mu.Unlock()
+		if err := l.d.Serve(ctx, conn, conn); err != nil && !errors.Is(err, context.Canceled) {
+			log.Warn().Err(err).Str("remote", conn.RemoteAddr().String()).Msg("jsdebug: debug session ended")
+		}
+		_ = conn.Close()
+		l.mu.Lock()
+		l.conn = nil
+		l.mu.Unlock()
+	}
+}
+
+// Close reports exitCode to the attached client, disconnects it and stops
+// listening.
+func (l *Listener) Close(exitCode int) error {
+	l.d.terminate(exitCode)
+	err := l.ln.Close()
+	l.cancel()
+	l.mu.Lock()
+	if l.conn != nil {
+		_ = l.conn.Close()
+	}
+	l.mu.Unlock()
+	<-l.done
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,312 @@
+package jsdebug
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-go-golems/go-go-goja/pkg/engine"
+)
+
+const debugScript = `function add(a, b) {
+  const sum = a + b;
+  return sum;
+}
+let total = 0;
+for (let i = 0; i < 3; i++) {
+  total = add(total, i);
+}
+const info = { total, items: [1, 2] };
+debugger;
+module.exports = info;
+`
+
+// dapMessage is a response or an event as the client sees it.
+type dapMessage struct {
+	Seq        int             `json:"seq"`
+	Type       string          `json:"type"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Command    string          `json:"command"`
+	Message    string          `json:"message"`
+	Event      string          `json:"event"`
+	Body       json.RawMessage `json:"body"`
+}
+
+type dapClient struct {
+	t        *testing.T
+	w        io.Writer
+	messages chan dapMessage
+	events   []dapMessage
+	seq      int
+}
+
+func newDAPClient(t *testing.T, d *Debugger) *dapClient {
+	t.Helper()
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- d.Serve(ctx, serverIn, serverOut) }()
+
+	c := &dapClient{t: t, w: clientOut, messages: make(chan dapMessage, 64)}
+	go func() {
+		r := bufio.NewReader(clientIn)
+		for {
+			var length int
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					close(c.messages)
+					return
+				}
+				line = strings.TrimSpace(line)
+				if line == "" {
+					break
+				}
+				if v, ok := strings.CutPrefix(line, "Content-Length: "); ok {
+					length, _ = strconv.Atoi(v)
+				}
+			}
+			body := make([]byte, length)
+			if _, err := io.ReadFull(r, body); err != nil {
+				close(c.messages)
+				return
+			}
+			var msg dapMessage
+			if err := json.Unmarshal(body, &msg); err == nil {
+				c.messages <- msg
+			}
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		_ = clientOut.Close()
+		_ = serverOut.Close()
+		<-served
+	})
+	return c
+}
+
+func (c *dapClient) next() dapMessage {
+	c.t.Helper()
+	select {
+	case msg, ok := <-c.messages:
+		require.True(c.t, ok, "debug session closed")
+		return msg
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("timed out waiting for the debugger")
+		return dapMessage{}
+	}
+}
+
+func (c *dapClient) request(command string, args any, body any) dapMessage {
+	c.t.Helper()
+	c.seq++
+	payload, err := json.Marshal(map[string]any{"seq": c.seq, "type": "request", "command": command, "arguments": args})
+	require.NoError(c.t, err)
+	_, err = fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(payload), payload)
+	require.NoError(c.t, err)
+	for {
+		msg := c.next()
+		if msg.Type == "event" {
+			c.events = append(c.events, msg)
+			continue
+		}
+		require.Equal(c.t, c.seq, msg.RequestSeq)
+		if body != nil && msg.Success {
+			require.NoError(c.t, json.Unmarshal(msg.Body, body))
+		}
+		return msg
+	}
+}
+
+func (c *dapClient) waitEvent(name string, body any) {
+	c.t.Helper()
+	for i, msg := range c.events {
+		if msg.Event == name {
+			c.events = append(c.events[:i], c.events[i+1:]...)
+			if body != nil {
+				require.NoError(c.t, json.Unmarshal(msg.Body, body))
+			}
+			return
+		}
+	}
+	for {
+		msg := c.next()
+		if msg.Type == "event" && msg.Event == name {
+			if body != nil {
+				require.NoError(c.t, json.Unmarshal(msg.Body, body))
+			}
+			return
+		}
+		if msg.Type == "event" {
+			c.events = append(c.events, msg)
+		}
+	}
+}
+
+// stoppedAt waits for the next stop and returns the thread and the
+// innermost frame.
+func (c *dapClient) stoppedAt(reason string) (int, StackFrame, []StackFrame) {
+	c.t.Helper()
+	var stopped StoppedEvent
+	c.waitEvent("stopped", &stopped)
+	require.Equal(c.t, reason, stopped.Reason)
+	var trace struct {
+		StackFrames []StackFrame `json:"stackFrames"`
+	}
+	resp := c.request("stackTrace", StackTraceArguments{ThreadID: stopped.ThreadID}, &trace)
+	require.True(c.t, resp.Success, resp.Message)
+	require.NotEmpty(c.t, trace.StackFrames)
+	return stopped.ThreadID, trace.StackFrames[0], trace.StackFrames
+}
+
+func (c *dapClient) evaluate(expression string, frameID int) EvaluateResponseBody {
+	c.t.Helper()
+	var out EvaluateResponseBody
+	resp := c.request("evaluate", EvaluateArguments{Expression: expression, FrameID: frameID}, &out)
+	require.True(c.t, resp.Success, resp.Message)
+	return out
+}
+
+func (c *dapClient) variables(ref int) map[string]string {
+	c.t.Helper()
+	var out struct {
+		Variables []Variable `json:"variables"`
+	}
+	resp := c.request("variables", VariablesArguments{VariablesReference: ref}, &out)
+	require.True(c.t, resp.Success, resp.Message)
+	values := map[string]string{}
+	for _, v := range out.Variables {
+		values[v.Name] = v.Value
+	}
+	return values
+}
+
+func runDebugged(t *testing.T, d *Debugger, script string) <-chan error {
+	t.Helper()
+	factory, err := d.Attach(engine.NewRuntimeFactoryBuilder(), nil).Build()
+	require.NoError(t, err)
+	done := make(chan error, 1)
+	go func() {
+		ctx := context.Background()
+		rt, err := factory.NewRuntime(engine.WithStartupContext(ctx), engine.WithLifetimeContext(ctx))
+		if err != nil {
+			done <- err
+			return
+		}
+		defer func() { _ = rt.Close(ctx) }()
+		_, err = rt.ImportModule(ctx, script)
+		done <- err
+	}()
+	return done
+}
+
+func TestDebugSessionBreakpointsStepsAndInspection(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "main.js")
+	require.NoError(t, os.WriteFile(script, []byte(debugScript), 0o644))
+
+	d := New()
+	c := newDAPClient(t, d)
+
+	require.True(t, c.request("initialize", InitializeArguments{AdapterID: "goja"}, nil).Success)
+	c.waitEvent("initialized", nil)
+	require.True(t, c.request("attach", LaunchArguments{}, nil).Success)
+
+	var set struct {
+		Breakpoints []Breakpoint `json:"breakpoints"`
+	}
+	resp := c.request("setBreakpoints", SetBreakpointsArguments{
+		Source:      Source{Path: script},
+		Breakpoints: []SourceBreakpoint{{Line: 7, Condition: "i === 2"}, {Line: 4}},
+	}, &set)
+	require.True(t, resp.Success, resp.Message)
+	require.Len(t, set.Breakpoints, 2)
+	require.True(t, set.Breakpoints[0].Verified)
+	require.Equal(t, 7, set.Breakpoints[0].Line)
+	// Line 4 holds only a closing brace; the breakpoint moves to line 5.
+	require.True(t, set.Breakpoints[1].Verified)
+	require.Equal(t, 5, set.Breakpoints[1].Line)
+
+	require.True(t, c.request("configurationDone", nil, nil).Success)
+	require.NoError(t, d.WaitReady(context.Background()))
+	done := runDebugged(t, d, script)
+
+	thread, frame, _ := c.stoppedAt("breakpoint")
+	require.Equal(t, 5, frame.Line)
+	require.True(t, c.request("continue", ThreadArguments{ThreadID: thread}, nil).Success)
+
+	thread, frame, _ = c.stoppedAt("breakpoint")
+	require.Equal(t, 7, frame.Line)
+	require.Equal(t, "2", c.evaluate("i", frame.ID).Result)
+	require.Equal(t, "1", c.evaluate("total", frame.ID).Result)
+
+	require.True(t, c.request("stepIn", ThreadArguments{ThreadID: thread}, nil).Success)
+	thread, frame, frames := c.stoppedAt("step")
+	require.Equal(t, 2, frame.Line)
+	require.Equal(t, "add", frame.Name)
+	require.Len(t, frames, 2)
+	require.Equal(t, 7, frames[1].Line)
+
+	var scopes struct {
+		Scopes []Scope `json:"scopes"`
+	}
+	require.True(t, c.request("scopes", ScopesArguments{FrameID: frame.ID}, &scopes).Success)
+	require.Equal(t, "Local", scopes.Scopes[0].Name)
+	require.Equal(t, map[string]string{"a": "1", "b": "2", "sum": "<uninitialized>"}, c.variables(scopes.Scopes[0].VariablesReference))
+
+	require.True(t, c.request("next", ThreadArguments{ThreadID: thread}, nil).Success)
+	thread, frame, _ = c.stoppedAt("step")
+	require.Equal(t, 3, frame.Line)
+	require.Equal(t, "3", c.evaluate("sum", frame.ID).Result)
+
+	require.True(t, c.request("stepOut", ThreadArguments{ThreadID: thread}, nil).Success)
+	thread, frame, frames = c.stoppedAt("step")
+	require.Equal(t, 9, frame.Line)
+	require.Len(t, frames, 1)
+
+	require.True(t, c.request("continue", ThreadArguments{ThreadID: thread}, nil).Success)
+	thread, frame, _ = c.stoppedAt("breakpoint")
+	require.Equal(t, 10, frame.Line)
+	info := c.evaluate("info", frame.ID)
+	require.NotZero(t, info.VariablesReference)
+	require.Equal(t, map[string]string{"total": "3", "items": "Array(2)"}, c.variables(info.VariablesReference))
+
+	bad := c.request("evaluate", EvaluateArguments{Expression: "missing", FrameID: frame.ID}, nil)
+	require.False(t, bad.Success)
+	require.Contains(t, bad.Message, "missing")
+
+	require.True(t, c.request("continue", ThreadArguments{ThreadID: thread}, nil).Success)
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("script did not finish")
+	}
+	require.True(t, c.request("disconnect", nil, nil).Success)
+}
+
+func TestDebuggerWithoutClientRunsUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "main.js")
+	require.NoError(t, os.WriteFile(script, []byte(debugScript), 0o644))
+
+	select {
+	case err := <-runDebugged(t, New(), script):
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("script did not finish")
+	}
+}
@@ -0,0 +1,479 @@
+// Package jsdebug is a step debugger for goja runtimes.
+//
+// goja has no debugger hooks, so the debugger instruments the sources that
+// require loads: using jsparse it inserts a hook call in front of every
+// statement, without adding line breaks. The hook pauses the runtime's
+// goroutine at breakpoints and while stepping, and evaluates expressions in
+// the paused scope through a closure created next to each statement.
+//
+// Attach a Debugger to an engine.RuntimeFactoryBuilder and serve the Debug
+// Adapter Protocol with Listen (or Serve) so that editors such as VS Code can
+// set breakpoints, step, and inspect the call stack and variables.
+package jsdebug
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/require"
+	"github.com/go-go-golems/go-go-goja/pkg/engine"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimebridge"
+	"github.com/pkg/errors"
+)
+
+// Debugger pauses instrumented runtimes at breakpoints and steps through
+// them. One Debugger can serve many runtimes; each one that loads an
+// instrumented module becomes a thread.
+type Debugger struct {
+	mu sync.Mutex
+
+	// sources holds instrumented sources by file path, modules by the
+	// module path runtimes load them from.
+	sources map[string]*source
+	modules map[string]*source
+	sites   []*site
+
+	breakpoints    map[string][]*breakpoint
+	bySite         map[int]*breakpoint
+	nextBreakpoint int
+
+	threads    map[int]*thread
+	nextThread int
+
+	// client receives events while a DAP session is attached. Hooks do
+	// nothing without one.
+	client      eventSink
+	stopOnEntry bool
+	ready       chan struct{}
+	readyOnce   sync.Once
+
+	frames  map[int]frameRef
+	vars    map[int]variablesRef
+	nextRef int
+}
+
+type eventSink interface {
+	event(name string, body any)
+}
+
+type stepMode int
+
+const (
+	stepNone stepMode = iota
+	stepIn
+	stepOver
+	stepOut
+)
+
+// thread is one runtime that loaded instrumented code.
+type thread struct {
+	id   int
+	name string
+	vm   *goja.Runtime
+	done <-chan struct{}
+
+	// records holds, per JavaScript stack depth, the last site executed at
+	// that depth and the closure that evaluates in its scope.
+	records []frameRecord
+	stack   []goja.StackFrame
+
+	step      stepMode
+	stepDepth int
+	pauseNext bool
+	// inspecting is set while the debugger runs JavaScript on the paused
+	// thread, so that the code it calls does not stop again.
+	inspecting bool
+
+	paused   bool
+	commands chan func()
+	resume   chan resumeRequest
+}
+
+type frameRecord struct {
+	site *site
+	eval goja.Callable
+}
+
+type resumeRequest struct {
+	mode stepMode
+}
+
+// New returns a Debugger without breakpoints or a client.
+func New() *Debugger {
+	return &Debugger{
+		sources:     map[string]*source{},
+		modules:     map[string]*source{},
+		breakpoints: map[string][]*breakpoint{},
+		bySite:      map[int]*breakpoint{},
+		threads:     map[int]*thread{},
+		frames:      map[int]frameRef{},
+		vars:        map[int]variablesRef{},
+		ready:       make(chan struct{}),
+	}
+}
+
+// Attach instruments the modules runtimes from builder load. base is the
+// source loader the runtimes would use otherwise; nil stands for the
+// engine's default loader, engine.ESMSourceLoader around
+// require.DefaultSourceLoader.
+func (d *Debugger) Attach(builder *engine.RuntimeFactoryBuilder, base require.SourceLoader) *engine.RuntimeFactoryBuilder {
+	if builder == nil {
+		return nil
+	}
+	return builder.WithRequireOptions(d.RequireOption(base))
+}
+
+// RequireOption installs a loader that instruments what base loads, and the
+// native module instrumented code reports to. Use it where runtimes are
+// created from require options rather than a builder. It replaces any loader
+// set before it, so pass that loader as base.
+func (d *Debugger) RequireOption(base require.SourceLoader) require.Option {
+	return d.RequireOptionWithPaths(base, nil)
+}
+
+// RequireOptionWithPaths is RequireOption for loaders whose module paths are
+// not the file paths editors show, such as the virtual paths of a jsverbs
+// registry. filePath maps a module path to the file path breakpoints and
+// stack frames use; nil keeps module paths.
+func (d *Debugger) RequireOptionWithPaths(base require.SourceLoader, filePath func(modulePath string) string) require.Option {
+	if base == nil {
+		base = engine.ESMSourceLoader(require.DefaultSourceLoader)
+	}
+	loader := d.sourceLoader(base, filePath)
+	return func(r *require.Registry) {
+		require.WithLoader(loader)(r)
+		r.RegisterNativeModule(hookModule, d.loadHookModule)
+	}
+}
+
+// WaitReady blocks until a client has finished configuring breakpoints, so
+// that code started afterwards stops at them.
+func (d *Debugger) WaitReady(ctx context.Context) error {
+	select {
+	case <-d.ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sourceLoader wraps base so that module sources are instrumented. Sources
+// that do not parse, or whose instrumented form does not, run unchanged.
+func (d *Debugger) sourceLoader(base require.SourceLoader, filePath func(string) string) require.SourceLoader {
+	return func(path string) ([]byte, error) {
+		src, err := base(path)
+		if err != nil || !instrumentable(path, src) {
+			return src, err
+		}
+		file := path
+		if filePath != nil {
+			file = filepath.Clean(filePath(path))
+		}
+		s, err := d.load(path, file, string(src))
+		if err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("jsdebug: running module without instrumentation")
+			return src, nil
+		}
+		return []byte(s.code), nil
+	}
+}
+
+// load returns the instrumented form of text, the module runtimes load as
+// module from the file path, reusing the previous one when it was loaded
+// with the same text before.
+func (d *Debugger) load(module, path, text string) (*source, error) {
+	d.mu.Lock()
+	if s := d.sources[path]; s != nil && s.module == module && s.original == text {
+		d.mu.Unlock()
+		return s, nil
+	}
+	d.mu.Unlock()
+
+	s, err := instrument(module, text, d.newSiteID)
+	if err != nil {
+		return nil, err
+	}
+	s.path = path
+	d.mu.Lock()
+	for _, st := range s.sites {
+		d.sites[st.id] = st
+	}
+	d.sources[path] = s
+	d.modules[module] = s
+	changed := breakpointInfos(d.resolveBreakpointsLocked(path))
+	client := d.client
+	d.mu.Unlock()
+	notifyBreakpoints(client, changed)
+	return s, nil
+}
+
+func (d *Debugger) newSiteID() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sites = append(d.sites, nil)
+	return len(d.sites) - 1
+}
+
+// loadHookModule is the loader of hookModule. Its first require in a
+// runtime registers the runtime as a thread.
+func (d *Debugger) loadHookModule(vm *goja.Runtime, module *goja.Object) {
+	t := &thread{
+		vm:       vm,
+		commands: make(chan func()),
+		resume:   make(chan resumeRequest, 1),
+	}
+	var lifetime context.Context
+	if services, ok := runtimebridge.Lookup(vm); ok {
+		lifetime = services.Lifetime()
+		t.done = lifetime.Done()
+	}
+
+	d.mu.Lock()
+	d.nextThread++
+	t.id = d.nextThread
+	t.name = "runtime " + strconv.Itoa(t.id)
+	t.pauseNext = d.stopOnEntry
+	d.threads[t.id] = t
+	client := d.client
+	d.mu.Unlock()
+	if client != nil {
+		client.event("thread", ThreadEvent{Reason: "started", ThreadID: t.id})
+	}
+	if lifetime != nil {
+		go func() {
+			<-lifetime.Done()
+			d.removeThread(t)
+		}()
+	}
+
+	if err := module.Set("exports", vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		d.hook(t, call)
+		return goja.Undefined()
+	})); err != nil {
+		panic(vm.NewGoError(err))
+	}
+}
+
+func (d *Debugger) removeThread(t *thread) {
+	d.mu.Lock()
+	if d.threads[t.id] != t {
+		d.mu.Unlock()
+		return
+	}
+	delete(d.threads, t.id)
+	d.releaseRefsLocked(t)
+	client := d.client
+	d.mu.Unlock()
+	if client != nil {
+		client.event("thread", ThreadEvent{Reason: "exited", ThreadID: t.id})
+	}
+}
+
+// hook runs in front of every instrumented statement, on the thread's
+// goroutine.
+func (d *Debugger) hook(t *thread, call goja.FunctionCall) {
+	if t.inspecting {
+		return
+	}
+	id := int(call.Argument(0).ToInteger())
+	eval, _ := goja.AssertFunction(call.Argument(1))
+
+	d.mu.Lock()
+	if d.client == nil || id < 0 || id >= len(d.sites) || d.sites[id] == nil {
+		d.mu.Unlock()
+		return
+	}
+	s := d.sites[id]
+	bp := d.bySite[id]
+
+	// The innermost frame is the hook itself.
+	t.stack = t.vm.CaptureCallStack(0, t.stack[:0])
+	depth := len(t.stack) - 1
+	if depth < 1 {
+		d.mu.Unlock()
+		return
+	}
+	for len(t.records) < depth {
+		t.records = append(t.records, frameRecord{})
+	}
+	t.records = t.records[:depth]
+	t.records[depth-1] = frameRecord{site: s, eval: eval}
+
+	reason, description := "", ""
+	switch {
+	case t.pauseNext:
+		reason = "pause"
+		if d.stopOnEntry {
+			reason = "entry"
+		}
+	case s.kind == siteDebugger:
+		reason, description = "breakpoint", "Paused on debugger statement"
+	case t.step == stepIn,
+		t.step == stepOver && depth <= t.stepDepth,
+		t.step == stepOut && depth < t.stepDepth:
+		reason = "step"
+	}
+	d.mu.Unlock()
+
+	var hit []int
+	if reason == "" && bp != nil && d.breakpointHits(t, bp, eval) {
+		reason, hit = "breakpoint", []int{bp.id}
+	}
+	if reason == "" {
+		return
+	}
+	d.pause(t, depth, reason, description, hit)
+}
+
+// pause reports the stop to the client and serves its requests on the
+// thread's goroutine until it resumes the thread.
+func (d *Debugger) pause(t *thread, depth int, reason, description string, hit []int) {
+	d.mu.Lock()
+	t.paused = true
+	t.pauseNext = false
+	t.step = stepNone
+	d.stopOnEntry = false
+	client := d.client
+	d.mu.Unlock()
+	if client == nil {
+		return
+	}
+	client.event("stopped", StoppedEvent{
+		Reason: reason, Description: description, ThreadID: t.id, HitBreakpointIDs: hit,
+	})
+
+	for {
+		select {
+		case fn := <-t.commands:
+			fn()
+		case r := <-t.resume:
+			d.mu.Lock()
+			t.paused = false
+			t.step = r.mode
+			t.stepDepth = depth
+			d.releaseRefsLocked(t)
+			d.mu.Unlock()
+			return
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// onThread runs fn on the goroutine of a paused thread and waits for it.
+func (d *Debugger) onThread(id int, fn func(t *thread) error) error {
+	d.mu.Lock()
+	t := d.threads[id]
+	paused := t != nil && t.paused
+	d.mu.Unlock()
+	if !paused {
+		return errors.Errorf("thread %d is not paused", id)
+	}
+	result := make(chan error, 1)
+	run := func() {
+		t.inspecting = true
+		defer func() { t.inspecting = false }()
+		result <- fn(t)
+	}
+	select {
+	case t.commands <- run:
+		return <-result
+	case <-t.done:
+		return errors.Errorf("thread %d exited", id)
+	}
+}
+
+// resume marks a paused thread as running and returns the function that
+// lets it continue, stepping according to mode. The caller answers the
+// client before calling it, so that the response precedes the next stop.
+func (d *Debugger) resume(id int, mode stepMode) (func(), error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	t := d.threads[id]
+	if t == nil {
+		return nil, errors.Errorf("unknown thread %d", id)
+	}
+	if !t.paused {
+		return nil, errors.Errorf("thread %d is not paused", id)
+	}
+	t.paused = false
+	return func() { t.resume <- resumeRequest{mode: mode} }, nil
+}
+
+// requestPause stops the thread at its next statement.
+func (d *Debugger) requestPause(id int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	t := d.threads[id]
+	if t == nil {
+		return errors.Errorf("unknown thread %d", id)
+	}
+	t.pauseNext = true
+	return nil
+}
+
+// attach connects a client. Only one client is attached at a time.
+func (d *Debugger) attach(client eventSink) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.client != nil {
+		return errors.New("a debug client is already attached")
+	}
+	d.client = client
+	return nil
+}
+
+// detach disconnects client, drops its breakpoints and lets paused threads
+// run.
+func (d *Debugger) detach(client eventSink) {
+	d.mu.Lock()
+	if d.client != client {
+		d.mu.Unlock()
+		return
+	}
+	d.client = nil
+	d.stopOnEntry = false
+	d.breakpoints = map[string][]*breakpoint{}
+	d.bySite = map[int]*breakpoint{}
+	var paused []*thread
+	for _, t := range d.threads {
+		t.step = stepNone
+		t.pauseNext = false
+		if t.paused {
+			t.paused = false
+			paused = append(paused, t)
+		}
+	}
+	d.mu.Unlock()
+	for _, t := range paused {
+		t.resume <- resumeRequest{mode: stepNone}
+	}
+}
+
+// configured marks the end of the client's initial configuration.
+func (d *Debugger) configured(stopOnEntry bool) {
+	d.mu.Lock()
+	if stopOnEntry {
+		d.stopOnEntry = true
+		for _, t := range d.threads {
+			t.pauseNext = true
+		}
+	}
+	d.mu.Unlock()
+	d.readyOnce.Do(func() { close(d.ready) })
+}
+
+func (d *Debugger) threadList() []Thread {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]Thread, 0, len(d.threads))
+	for id := 1; id <= d.nextThread; id++ {
+		if t := d.threads[id]; t != nil {
+			out = append(out, Thread{ID: t.id, Name: t.name})
+		}
+	}
+	return out
+}
@@ -0,0 +1,321 @@
+package jsdebug
+
+import (
+	"math/big"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/pkg/jsparse"
+	"github.com/pkg/errors"
+)
+
+// frameRef is a stack frame of a paused thread, as handed to the client.
+type frameRef struct {
+	thread *thread
+	frame  goja.StackFrame
+	// record is the last statement the frame executed, when it is
+	// instrumented.
+	record *frameRecord
+}
+
+// variablesRef is an expandable value handed to the client: the names of a
+// scope, evaluated in a frame, or the properties of an object.
+type variablesRef struct {
+	thread *thread
+	record *frameRecord
+	names  []string
+	object *goja.Object
+}
+
+// stackTrace lists the JavaScript frames of a paused thread, innermost
+// first.
+func (d *Debugger) stackTrace(threadID int) ([]StackFrame, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	t := d.threads[threadID]
+	if t == nil || !t.paused {
+		return nil, errors.Errorf("thread %d is not paused", threadID)
+	}
+	// t.stack still holds the capture of the hook that paused, whose
+	// innermost frame is the hook itself.
+	depth := len(t.stack) - 1
+	var out []StackFrame
+	for k := 1; k < len(t.stack); k++ {
+		frame := t.stack[k]
+		module := frame.SrcName()
+		if module == "" || module == "<native>" {
+			continue
+		}
+		ref := frameRef{thread: t, frame: frame}
+		if i := depth - k; i >= 0 && i < len(t.records) {
+			if rec := t.records[i]; rec.site != nil && rec.site.source.module == module {
+				ref.record = &rec
+			}
+		}
+		d.nextRef++
+		d.frames[d.nextRef] = ref
+
+		pos := frame.Position()
+		path, column := module, pos.Column
+		if s := d.modules[module]; s != nil {
+			path, column = s.path, s.originalColumn(pos.Line, pos.Column)
+		}
+		name := frame.FuncName()
+		if name == "" || name == "<anonymous>" {
+			name = "(anonymous)"
+		}
+		out = append(out, StackFrame{
+			ID:     d.nextRef,
+			Name:   name,
+			Source: &Source{Name: filepath.Base(path), Path: path},
+			Line:   pos.Line,
+			Column: column,
+		})
+	}
+	return out, nil
+}
+
+// scopes lists the scopes visible from a frame: its function's locals, the
+// enclosing functions' bindings and the module's top-level bindings.
+func (d *Debugger) scopes(frameID int) ([]Scope, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ref, ok := d.frames[frameID]
+	if !ok {
+		return nil, errors.Errorf("unknown frame %d", frameID)
+	}
+	if ref.record == nil {
+		return []Scope{}, nil
+	}
+	var out []Scope
+	for _, group := range scopeGroups(ref.record.site) {
+		d.nextRef++
+		d.vars[d.nextRef] = variablesRef{thread: ref.thread, record: ref.record, names: group.names}
+		out = append(out, Scope{Name: group.name, VariablesReference: d.nextRef, Expensive: false})
+	}
+	return out, nil
+}
+
+type scopeGroup struct {
+	name  string
+	names []string
+}
+
+// scopeGroups walks the jsparse scopes enclosing a statement outwards.
+// Inner bindings shadow outer ones of the same name.
+func scopeGroups(s *site) []scopeGroup {
+	res := s.source.resolution
+	if res == nil {
+		return nil
+	}
+	var innermost *jsparse.ScopeRecord
+	for _, scope := range res.Scopes {
+		if s.offset < scope.Start || s.offset >= scope.End {
+			continue
+		}
+		if innermost == nil || scope.End-scope.Start < innermost.End-innermost.Start {
+			innermost = scope
+		}
+	}
+
+	seen := map[string]bool{}
+	var groups []scopeGroup
+	current := scopeGroup{name: "Local"}
+	flush := func(next string) {
+		if len(current.names) > 0 || current.name == "Local" {
+			sort.Strings(current.names)
+			groups = append(groups, current)
+		}
+		current = scopeGroup{name: next}
+	}
+	for scope := innermost; scope != nil && scope.ID != res.RootScopeID; scope = res.Scopes[scope.ParentID] {
+		// The function require wraps the module in is the module scope.
+		module := scope.Kind == jsparse.ScopeFunction && scope.ParentID == res.RootScopeID
+		if module {
+			if current.name == "Local" && len(groups) == 0 {
+				current.name = "Module"
+			} else {
+				flush("Module")
+			}
+		}
+		for name := range scope.Bindings {
+			if !seen[name] {
+				seen[name] = true
+				current.names = append(current.names, name)
+			}
+		}
+		if module {
+			break
+		}
+		if scope.Kind == jsparse.ScopeFunction && current.name == "Local" {
+			flush("Closure")
+		}
+	}
+	flush("")
+	return groups
+}
+
+// variables returns the children of a scope or object reference.
+func (d *Debugger) variables(ref int) ([]Variable, error) {
+	d.mu.Lock()
+	v, ok := d.vars[ref]
+	d.mu.Unlock()
+	if !ok {
+		return nil, errors.Errorf("unknown variables reference %d", ref)
+	}
+	var out []Variable
+	err := d.onThread(v.thread.id, func(t *thread) error {
+		if v.object != nil {
+			for _, key := range v.object.Keys() {
+				var value goja.Value
+				if ex := t.vm.Try(func() { value = v.object.Get(key) }); ex != nil {
+					out = append(out, Variable{Name: key, Value: ex.Error()})
+					continue
+				}
+				out = append(out, d.describe(t, key, value))
+			}
+			return nil
+		}
+		for _, name := range v.names {
+			value, err := v.record.eval(goja.Undefined(), t.vm.ToValue(name))
+			if err != nil {
+				out = append(out, Variable{Name: name, Value: "<uninitialized>"})
+				continue
+			}
+			out = append(out, d.describe(t, name, value))
+		}
+		return nil
+	})
+	if out == nil {
+		out = []Variable{}
+	}
+	return out, err
+}
+
+// evaluate runs expression in the scope of a frame. frameID 0 picks the
+// innermost frame of the first paused thread.
+func (d *Debugger) evaluate(expression string, frameID int) (Variable, error) {
+	d.mu.Lock()
+	ref, ok := d.frames[frameID]
+	if frameID == 0 {
+		ok = false
+		for id := 1; id <= d.nextThread; id++ {
+			if t := d.threads[id]; t != nil && t.paused && len(t.stack) > 1 {
+				depth := len(t.stack) - 1
+				if depth-1 < len(t.records) && t.records[depth-1].site != nil {
+					rec := t.records[depth-1]
+					ref, ok = frameRef{thread: t, record: &rec}, true
+				}
+				break
+			}
+		}
+	}
+	d.mu.Unlock()
+	if !ok {
+		return Variable{}, errors.New("evaluation needs a paused thread")
+	}
+	if ref.record == nil {
+		return Variable{}, errors.New("the frame is not instrumented")
+	}
+	var result Variable
+	err := d.onThread(ref.thread.id, func(t *thread) error {
+		value, err := ref.record.eval(goja.Undefined(), t.vm.ToValue(expression))
+		if err != nil {
+			return err
+		}
+		result = d.describe(t, "", value)
+		return nil
+	})
+	return result, err
+}
+
+// describe renders a value for the client. Objects get a reference that
+// expands to their own enumerable properties.
+func (d *Debugger) describe(t *thread, name string, value goja.Value) Variable {
+	v := Variable{Name: name}
+	switch {
+	case value == nil || goja.IsUndefined(value):
+		v.Value, v.Type = "undefined", "undefined"
+		return v
+	case goja.IsNull(value):
+		v.Value, v.Type = "null", "object"
+		return v
+	}
+	obj, isObject := value.(*goja.Object)
+	if !isObject {
+		switch exported := value.Export().(type) {
+		case string:
+			v.Value, v.Type = strconv.Quote(exported), "string"
+		case bool:
+			v.Value, v.Type = value.String(), "boolean"
+		case int64, float64:
+			v.Value, v.Type = value.String(), "number"
+		default:
+			v.Value, v.Type = value.String(), typeName(value)
+		}
+		return v
+	}
+
+	if _, callable := goja.AssertFunction(obj); callable {
+		fnName := obj.Get("name")
+		label := "function"
+		if fnName != nil && fnName.String() != "" {
+			label += " " + fnName.String()
+		}
+		v.Value, v.Type = label+"()", "function"
+		return v
+	}
+	v.Type = "object"
+	switch obj.ClassName() {
+	case "Array":
+		v.Value = "Array(" + obj.Get("length").String() + ")"
+	case "Error", "Date", "RegExp":
+		v.Value = value.String()
+	default:
+		v.Value = constructorName(obj) + " {…}"
+	}
+	if len(obj.Keys()) > 0 {
+		d.mu.Lock()
+		d.nextRef++
+		d.vars[d.nextRef] = variablesRef{thread: t, object: obj}
+		v.VariablesReference = d.nextRef
+		d.mu.Unlock()
+	}
+	return v
+}
+
+func constructorName(obj *goja.Object) string {
+	ctor, ok := obj.Get("constructor").(*goja.Object)
+	if ok && ctor != nil {
+		if name := ctor.Get("name"); name != nil && name.String() != "" {
+			return name.String()
+		}
+	}
+	return "Object"
+}
+
+func typeName(value goja.Value) string {
+	if _, ok := value.(*goja.Symbol); ok {
+		return "symbol"
+	}
+	if _, ok := value.Export().(*big.Int); ok {
+		return "bigint"
+	}
+	return "unknown"
+}
+
+// releaseRefsLocked forgets the frames and variables handed out for t.
+func (d *Debugger) releaseRefsLocked(t *thread) {
+	for id, ref := range d.frames {
+		if ref.thread == t {
+			delete(d.frames, id)
+		}
+	}
+	for id, ref := range d.vars {
+		if ref.thread == t {
+			delete(d.vars, id)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package jsdebug
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-go-golems/go-go-goja/pkg/jsinstrument"
+	"github.com/go-go-golems/go-go-goja/pkg/jsparse"
+)
+
+const (
+	// hookModule is the native module instrumented sources require to reach
+	// the debugger of the runtime that loads them.
+	hookModule = "go-go-goja:debug"
+	// hookName is the module-local binding instrumented statements call.
+	hookName = "__gojaDebug__"
+)
+
+type siteKind int
+
+const (
+	siteStatement siteKind = iota
+	siteDebugger
+)
+
+// site is one instrumented statement: the place a breakpoint or a step can
+// stop at.
+type site struct {
+	id     int
+	source *source
+	kind   siteKind
+	// line and column are 1-based positions in the original source.
+	line   int
+	column int
+	// offset is the 1-based offset of the statement in the wrapped source
+	// the resolution was computed for.
+	offset int
+}
+
+// source is an instrumented module source.
+type source struct {
+	// module is the path runtimes load the source from; path is the file
+	// path the client knows it by.
+	module     string
+	path       string
+	original   string
+	code       string
+	sites      []*site
+	resolution *jsparse.Resolution
+	rewritten  *jsinstrument.Rewritten
+}
+
+// instrumentable reports whether the loader should instrument path. JSON
+// files are data. TypeScript files and sources that carry an inline source
+// map were compiled from another file (ES modules, see
+// engine.ESMSourceLoader) whose lines they do not preserve.
+func instrumentable(path string, src []byte) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json", ".ts", ".tsx", ".mts", ".cts":
+		return false
+	}
+	return !strings.Contains(string(src), "//# sourceMappingURL=data:")
+}
+
+// instrument parses text with jsparse and inserts a hook call in front of
+// every statement. Hooks never add line breaks, so line numbers in the
+// instrumented code match the original. newID allocates site IDs.
+func instrument(path, text string, newID func() int) (*source, error) {
+	mod, err := jsinstrument.Parse(path, text)
+	if err != nil {
+		return nil, err
+	}
+
+	src := &source{module: path, path: path, original: text, resolution: mod.Analysis.Resolution}
+	var inserts []jsinstrument.Insertion
+	for _, st := range mod.Statements() {
+		s := &site{id: newID(), source: src, offset: mod.WrappedOffset(st.Start), kind: siteStatement, line: st.Line, column: st.Column}
+		if st.Node.Kind == "DebuggerStatement" {
+			s.kind = siteDebugger
+		}
+		src.sites = append(src.sites, s)
+
+		if st.Body {
+			inserts = append(inserts, jsinstrument.WrapBody(st)...)
+		}
+		inserts = append(inserts, jsinstrument.Insertion{
+			Offset: st.Start,
+			Text:   fmt.Sprintf("%s(%d, (__gojaExpr__) => eval(__gojaExpr__)); ", hookName, s.id),
+			Span:   st.End - st.Start,
+		})
+	}
+	if len(src.sites) == 0 {
+		src.code = text
+		return src, nil
+	}
+
+	inserts = append(inserts, jsinstrument.Insertion{
+		Offset: mod.PreludeOffset(),
+		Text:   fmt.Sprintf("var %s = require(%q); ", hookName, hookModule),
+		Span:   len(text) + 1,
+	})
+	src.rewritten, err = mod.Rewrite(inserts)
+	if err != nil {
+		return nil, err
+	}
+	src.code = src.rewritten.Code
+	return src, nil
+}
+
+// originalColumn maps a 1-based column of the instrumented code back to the
+// original source.
+func (s *source) originalColumn(line, column int) int {
+	if s.rewritten == nil {
+		return column
+	}
+	return s.rewritten.OriginalColumn(line, column)
+}
+
+// siteForLine returns the first site on line or, when the line has none,
+// on the closest line after it.
+func (s *source) siteForLine(line int) *site {
+	var best *site
+	for _, st := range s.sites {
+		if st.line < line {
+			continue
+		}
+		if best == nil || st.line < best.line || (st.line == best.line && st.column < best.column) {
+			best = st
+		}
+	}
+	return best
+}
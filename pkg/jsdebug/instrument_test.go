@@ -0,0 +1,55 @@
+package jsdebug
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrumentKeepsLinesAndWrapsBodies(t *testing.T) {
+	text := "\"use strict\";\nlet n = 0;\nif (n) n++;\nfor (;;) break;\nfunction f() {\n  return n;\n}\n"
+	next := 0
+	src, err := instrument("/tmp/a.js", text, func() int { next++; return next })
+	require.NoError(t, err)
+
+	require.Equal(t, strings.Count(text, "\n"), strings.Count(src.code, "\n"))
+	require.True(t, strings.HasPrefix(src.code, "\"use strict\";var __gojaDebug__ = require(\"go-go-goja:debug\"); "))
+	require.Contains(t, src.code, "if (n) { __gojaDebug__(")
+	require.Contains(t, src.code, "n++; }")
+	require.Contains(t, src.code, "for (;;) { __gojaDebug__(")
+
+	var lines []int
+	for _, s := range src.sites {
+		lines = append(lines, s.line)
+	}
+	require.Equal(t, []int{2, 3, 3, 4, 4, 6}, lines)
+	require.Equal(t, 3, src.siteForLine(3).line)
+	require.Equal(t, 6, src.siteForLine(5).line)
+	require.Nil(t, src.siteForLine(7))
+}
+
+func TestOriginalColumnSkipsInsertedText(t *testing.T) {
+	next := 0
+	src, err := instrument("/tmp/a.js", "let a = 1; let b = 2;\n", func() int { next++; return next })
+	require.NoError(t, err)
+
+	line := strings.Split(src.code, "\n")[0]
+	col := strings.Index(line, "let b") + 1
+	require.Equal(t, 12, src.originalColumn(1, col))
+	require.Equal(t, 1, src.originalColumn(1, 1))
+}
+
+func TestInstrumentableSkipsDataAndCompiledSources(t *testing.T) {
+	require.False(t, instrumentable("/x/data.json", []byte("{}")))
+	require.False(t, instrumentable("/x/verbs.ts", []byte("x")))
+	require.False(t, instrumentable("/x/mod.mjs", []byte("x\n//# sourceMappingURL=data:application/json;base64,e30=")))
+	require.True(t, instrumentable("/x/main.js", []byte("x")))
+}
+
+func TestInstrumentPlacesHooksBeforeTaggedTemplates(t *testing.T) {
+	next := 0
+	src, err := instrument("/tmp/a.js", "doc`text`;\n", func() int { next++; return next })
+	require.NoError(t, err)
+	require.Contains(t, src.code, "; doc`text`;")
+}
@@ -0,0 +1,185 @@
+package jsdebug
+
+import "encoding/json"
+
+// The types below are the subset of the Debug Adapter Protocol the debugger
+// speaks. Field names follow the specification.
+
+// ProtocolMessage is the envelope shared by requests, responses and events.
+type ProtocolMessage struct {
+	Seq  int    `json:"seq"`
+	Type string `json:"type"`
+}
+
+// Request is a client request.
+type Request struct {
+	ProtocolMessage
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+// Response answers a request.
+type Response struct {
+	ProtocolMessage
+	RequestSeq int    `json:"request_seq"`
+	Success    bool   `json:"success"`
+	Command    string `json:"command"`
+	Message    string `json:"message,omitempty"`
+	Body       any    `json:"body,omitempty"`
+}
+
+// Event is sent by the debugger without a request.
+type Event struct {
+	ProtocolMessage
+	Event string `json:"event"`
+	Body  any    `json:"body,omitempty"`
+}
+
+// Capabilities lists the optional protocol features the debugger supports.
+type Capabilities struct {
+	SupportsConfigurationDoneRequest bool `json:"supportsConfigurationDoneRequest"`
+	SupportsConditionalBreakpoints   bool `json:"supportsConditionalBreakpoints"`
+	SupportsEvaluateForHovers        bool `json:"supportsEvaluateForHovers"`
+}
+
+// InitializeArguments are the arguments of the initialize request.
+type InitializeArguments struct {
+	ClientID        string `json:"clientID,omitempty"`
+	AdapterID       string `json:"adapterID"`
+	LinesStartAt1   *bool  `json:"linesStartAt1,omitempty"`
+	ColumnsStartAt1 *bool  `json:"columnsStartAt1,omitempty"`
+}
+
+// LaunchArguments are the arguments of launch and attach requests. The
+// program already runs in the host process, so both only configure the
+// session.
+type LaunchArguments struct {
+	StopOnEntry bool `json:"stopOnEntry,omitempty"`
+}
+
+// Source identifies a script.
+type Source struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path,omitempty"`
+}
+
+// SourceBreakpoint is a breakpoint requested by the client.
+type SourceBreakpoint struct {
+	Line      int    `json:"line"`
+	Column    int    `json:"column,omitempty"`
+	Condition string `json:"condition,omitempty"`
+}
+
+// SetBreakpointsArguments replace the breakpoints of one source.
+type SetBreakpointsArguments struct {
+	Source      Source             `json:"source"`
+	Breakpoints []SourceBreakpoint `json:"breakpoints"`
+}
+
+// Breakpoint reports where a requested breakpoint stops.
+type Breakpoint struct {
+	ID       int     `json:"id"`
+	Verified bool    `json:"verified"`
+	Message  string  `json:"message,omitempty"`
+	Source   *Source `json:"source,omitempty"`
+	Line     int     `json:"line,omitempty"`
+	Column   int     `json:"column,omitempty"`
+}
+
+// Thread is one runtime.
+type Thread struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// ThreadArguments address one thread.
+type ThreadArguments struct {
+	ThreadID int `json:"threadId"`
+}
+
+// StackTraceArguments are the arguments of the stackTrace request.
+type StackTraceArguments struct {
+	ThreadID   int `json:"threadId"`
+	StartFrame int `json:"startFrame,omitempty"`
+	Levels     int `json:"levels,omitempty"`
+}
+
+// StackFrame is one frame of a paused thread.
+type StackFrame struct {
+	ID     int     `json:"id"`
+	Name   string  `json:"name"`
+	Source *Source `json:"source,omitempty"`
+	Line   int     `json:"line"`
+	Column int     `json:"column"`
+}
+
+// ScopesArguments are the arguments of the scopes request.
+type ScopesArguments struct {
+	FrameID int `json:"frameId"`
+}
+
+// Scope is a named group of variables visible from a frame.
+type Scope struct {
+	Name               string `json:"name"`
+	VariablesReference int    `json:"variablesReference"`
+	Expensive          bool   `json:"expensive"`
+}
+
+// VariablesArguments are the arguments of the variables request.
+type VariablesArguments struct {
+	VariablesReference int `json:"variablesReference"`
+}
+
+// Variable is a named value. A non-zero VariablesReference expands it.
+type Variable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	Type               string `json:"type,omitempty"`
+	VariablesReference int    `json:"variablesReference"`
+}
+
+// EvaluateArguments are the arguments of the evaluate request.
+type EvaluateArguments struct {
+	Expression string `json:"expression"`
+	FrameID    int    `json:"frameId,omitempty"`
+	Context    string `json:"context,omitempty"`
+}
+
+// EvaluateResponseBody is the result of the evaluate request.
+type EvaluateResponseBody struct {
+	Result             string `json:"result"`
+	Type               string `json:"type,omitempty"`
+	VariablesReference int    `json:"variablesReference"`
+}
+
+// StoppedEvent reports that a thread paused.
+type StoppedEvent struct {
+	Reason            string `json:"reason"`
+	Description       string `json:"description,omitempty"`
+	ThreadID          int    `json:"threadId"`
+	AllThreadsStopped bool   `json:"allThreadsStopped"`
+	HitBreakpointIDs  []int  `json:"hitBreakpointIds,omitempty"`
+}
+
+// ThreadEvent reports that a runtime started or exited.
+type ThreadEvent struct {
+	Reason   string `json:"reason"`
+	ThreadID int    `json:"threadId"`
+}
+
+// BreakpointEvent reports that a breakpoint moved or got verified.
+type BreakpointEvent struct {
+	Reason     string     `json:"reason"`
+	Breakpoint Breakpoint `json:"breakpoint"`
+}
+
+// OutputEvent writes to the client's debug console.
+type OutputEvent struct {
+	Category string `json:"category,omitempty"`
+	Output   string `json:"output"`
+}
+
+// ExitedEvent reports the exit code of the debugged program.
+type ExitedEvent struct {
+	ExitCode int `json:"exitCode"`
+}
@@ -0,0 +1,57 @@
+package jserrors
+
+import (
+	"fmt"
+	"strings"
+)
+
+// codeFrameContext is the number of lines a code frame shows on either
+// side of the error.
+const codeFrameContext = 2
+
+// CodeFrame returns the lines of source around a 1-based line and column,
+// with the line marked and a caret under the column. It is empty when the
+// line is not in source.
+func CodeFrame(source string, line, column int) string {
+	lines := strings.Split(strings.TrimSuffix(source, "\n"), "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	first := max(line-codeFrameContext, 1)
+	last := min(line+codeFrameContext, len(lines))
+	width := len(fmt.Sprint(last))
+
+	var b strings.Builder
+	for n := first; n <= last; n++ {
+		text := strings.TrimRight(lines[n-1], "\r")
+		marker := " "
+		if n == line {
+			marker = ">"
+		}
+		gutter := fmt.Sprintf("%s %*d |", marker, width, n)
+		if text == "" {
+			b.WriteString(gutter + "\n")
+		} else {
+			b.WriteString(gutter + " " + text + "\n")
+		}
+		if n == line && column > 0 {
+			b.WriteString(fmt.Sprintf("  %*s | %s^\n", width, "", caretIndent(text, column)))
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// caretIndent returns the whitespace that puts a caret under a 1-based
+// byte column of text, keeping its tabs so that the caret lines up.
+func caretIndent(text string, column int) string {
+	prefix := text[:min(column-1, len(text))]
+	var b strings.Builder
+	for _, r := range prefix {
+		if r == '\t' {
+			b.WriteRune('\t')
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	return b.String()
+}
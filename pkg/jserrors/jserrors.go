@@ -0,0 +1,253 @@
+// Package jserrors reports JavaScript errors against the files their code
+// was written in.
+//
+// Compilers such as tsscript and engine.ESMSourceLoader inline a source map
+// at the end of the code they emit, and goja maps the positions of stack
+// frames through it. Retain records those maps as code is loaded, so that
+// the reports of this package correct the columns goja gets wrong and show
+// a code frame of the original source, even of files that exist only in a
+// map:
+//
+//	Error: boom
+//
+//	  2 |   const y = x + 1;
+//	> 3 |   throw new Error("boom");
+//	    |         ^
+//	  4 | }
+//
+//	    at boom (/src/lib.ts:3:9)
+//	    at /src/main.ts:3:1
+package jserrors
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// Frame is one frame of a JavaScript stack. Line and Column are 1-based and
+// zero for native frames.
+type Frame struct {
+	// Function is empty for anonymous functions and top-level code.
+	Function string
+	// File is "native" for frames of Go functions.
+	File   string
+	Line   int
+	Column int
+}
+
+// String formats the frame the way goja does, without the program counter.
+func (f Frame) String() string {
+	at := f.File
+	if f.Line > 0 {
+		at = fmt.Sprintf("%s:%d:%d", f.File, f.Line, f.Column)
+	}
+	if f.Function == "" {
+		return at
+	}
+	return f.Function + " (" + at + ")"
+}
+
+// Exception is a thrown JavaScript value and the stack it was thrown from.
+type Exception struct {
+	// Message is the string form of the value, such as "TypeError: x is
+	// not a function".
+	Message string
+	// Frames are ordered innermost first.
+	Frames []Frame
+	// CodeFrame shows the source around the innermost frame whose file can
+	// be read, or is empty.
+	CodeFrame string
+}
+
+// FromException returns the report of an exception goja raised.
+func FromException(ex *goja.Exception) *Exception {
+	x := &Exception{}
+	if v := ex.Value(); v != nil {
+		x.Message = v.String()
+	}
+	for _, sf := range ex.Stack() {
+		f := Frame{Function: sf.FuncName()}
+		if f.Function == "<anonymous>" {
+			f.Function = ""
+		}
+		pos := sf.Position()
+		if pos.Line == 0 {
+			if f.Function == "<native>" {
+				f.Function = ""
+			}
+			f.File = "native"
+		} else {
+			f.File, f.Line, f.Column = pos.Filename, pos.Line, pos.Column
+			if f.File == "" {
+				f.File = "<eval>"
+			}
+			f = corrected(f)
+		}
+		x.Frames = append(x.Frames, f)
+	}
+	x.CodeFrame = x.codeFrame()
+	return x
+}
+
+// FromValue returns the report of a value that was thrown or that a promise
+// was rejected with. The frames of Error objects come from their stack
+// property; other values have none.
+func FromValue(value goja.Value) *Exception {
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return &Exception{Message: "undefined"}
+	}
+	x := &Exception{Message: value.String()}
+	if obj, ok := value.(*goja.Object); ok {
+		if stack := obj.Get("stack"); stack != nil && !goja.IsUndefined(stack) && !goja.IsNull(stack) {
+			x.Frames = parseStack(stack.String())
+		}
+	}
+	x.CodeFrame = x.codeFrame()
+	return x
+}
+
+// String formats the message, the code frame and the stack.
+func (x *Exception) String() string {
+	var b strings.Builder
+	b.WriteString(x.Message)
+	if x.CodeFrame != "" {
+		b.WriteString("\n\n")
+		b.WriteString(x.CodeFrame)
+	}
+	if len(x.Frames) > 0 {
+		b.WriteString("\n")
+		for _, f := range x.Frames {
+			b.WriteString("\n    at ")
+			b.WriteString(f.String())
+		}
+	}
+	return b.String()
+}
+
+func (x *Exception) codeFrame() string {
+	for _, f := range x.Frames {
+		if f.Line == 0 {
+			continue
+		}
+		if text, ok := sourceText(f.File); ok {
+			return CodeFrame(text, f.Line, f.Column)
+		}
+	}
+	return ""
+}
+
+// corrected fixes the column of a frame goja mapped through a source map:
+// it looks the generated position up with a 1-based column and reports the
+// 0-based original one.
+func corrected(f Frame) Frame {
+	if f.Line > 0 && mapped(f.File) {
+		f.Column++
+	}
+	return f
+}
+
+// stackLineRe matches a line of a goja stack trace, such as
+// "at fn (file:1:2(3))" or "at file:1:2(3)".
+var stackLineRe = regexp.MustCompile(`^at (?:(.*) \()?(.+):(\d+):(\d+)(?:\(\d+\))?\)?$`)
+
+// nativeLineRe matches the line of a native frame, "at fn (native)" or
+// "at native".
+var nativeLineRe = regexp.MustCompile(`^at (?:(.*) \()?native\)?$`)
+
+// parseStack returns the frames of a stack property.
+func parseStack(stack string) []Frame {
+	var frames []Frame
+	for _, line := range strings.Split(stack, "\n") {
+		line = strings.TrimSpace(line)
+		if m := stackLineRe.FindStringSubmatch(line); m != nil {
+			l, _ := strconv.Atoi(m[3])
+			c, _ := strconv.Atoi(m[4])
+			frames = append(frames, corrected(Frame{Function: m[1], File: m[2], Line: l, Column: c}))
+			continue
+		}
+		if m := nativeLineRe.FindStringSubmatch(line); m != nil {
+			frames = append(frames, Frame{Function: m[1], File: "native"})
+		}
+	}
+	return frames
+}
+
+// Find returns the report of the JavaScript exception or promise rejection
+// in err's chain, or nil when there is none.
+func Find(err error) *Exception {
+	x, _ := find(err)
+	return x
+}
+
+// find also returns the part of err's message that the exception makes up.
+func find(err error) (*Exception, string) {
+	var rej *rejection
+	if errors.As(err, &rej) {
+		return rej.exception, rej.exception.Message
+	}
+	var ex *goja.Exception
+	if errors.As(err, &ex) {
+		return FromException(ex), ex.Error()
+	}
+	return nil, ""
+}
+
+// Report returns the message of err with the JavaScript exception in it, if
+// any, expanded into its message, code frame and source-mapped stack.
+func Report(err error) string {
+	if err == nil {
+		return ""
+	}
+	text := err.Error()
+	var r *reported
+	if errors.As(err, &r) {
+		return text
+	}
+	x, short := find(err)
+	if x == nil {
+		return text
+	}
+	if i := strings.Index(text, short); i >= 0 {
+		return text[:i] + x.String() + text[i+len(short):]
+	}
+	return text + "\n" + strings.TrimPrefix(x.String(), x.Message)
+}
+
+// Wrap returns an error whose message is Report(err) and which unwraps to
+// err. Errors without a JavaScript exception are returned unchanged.
+func Wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+	var r *reported
+	if errors.As(err, &r) || Find(err) == nil {
+		return err
+	}
+	return &reported{err: err, message: Report(err)}
+}
+
+type reported struct {
+	err     error
+	message string
+}
+
+func (r *reported) Error() string { return r.message }
+func (r *reported) Unwrap() error { return r.err }
+
+// Rejected returns the error of a promise rejected with value. Its message
+// is "promise rejected: " and the value; Report adds the stack of Error
+// objects.
+func Rejected(value goja.Value) error {
+	return &rejection{exception: FromValue(value)}
+}
+
+type rejection struct {
+	exception *Exception
+}
+
+func (r *rejection) Error() string { return "promise rejected: " + r.exception.Message }
@@ -0,0 +1,116 @@
+package jserrors_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/go-go-golems/go-go-goja/pkg/engine"
+	"github.com/go-go-golems/go-go-goja/pkg/jserrors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeFrameMarksLineAndColumn(t *testing.T) {
+	source := "a();\nb();\n\tthrow x;\nc();\nd();\ne();\n"
+	require.Equal(t, ""+
+		"  1 | a();\n"+
+		"  2 | b();\n"+
+		"> 3 | \tthrow x;\n"+
+		"    | \t      ^\n"+
+		"  4 | c();\n"+
+		"  5 | d();", jserrors.CodeFrame(source, 3, 8))
+	require.Equal(t, "", jserrors.CodeFrame(source, 9, 1))
+}
+
+func TestReportMapsModuleFramesToTheirSource(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib.mjs")
+	require.NoError(t, os.WriteFile(lib, []byte("export function boom(x) {\n  const y = x + 1;\n  throw new Error(\"boom \" + y);\n}\n"), 0o600))
+	main := filepath.Join(dir, "main.mjs")
+	require.NoError(t, os.WriteFile(main, []byte("import { boom } from \"./lib.mjs\";\n\nboom(1);\n"), 0o600))
+
+	factory, err := engine.NewRuntimeFactoryBuilder().Build()
+	require.NoError(t, err)
+	rt, err := factory.NewRuntime(engine.WithStartupContext(context.Background()), engine.WithLifetimeContext(context.Background()))
+	require.NoError(t, err)
+	defer func() { _ = rt.Close(context.Background()) }()
+
+	_, err = rt.ImportModule(context.Background(), main)
+	require.Error(t, err)
+
+	x := jserrors.Find(err)
+	require.NotNil(t, x)
+	require.Equal(t, "Error: boom 2", x.Message)
+	require.Equal(t, jserrors.Frame{Function: "boom", File: lib, Line: 3, Column: 9}, x.Frames[0])
+	require.Equal(t, main, x.Frames[1].File)
+	require.Equal(t, 3, x.Frames[1].Line)
+
+	report := jserrors.Report(fmt.Errorf("run main: %w", err))
+	require.True(t, strings.HasPrefix(report, "run main: Error: boom 2\n\n"), report)
+	require.Contains(t, report, "> 3 |   throw new Error(\"boom \" + y);\n    |         ^\n")
+	require.Contains(t, report, "\n    at boom ("+lib+":3:9)")
+
+	wrapped := jserrors.Wrap(err)
+	require.Equal(t, jserrors.Report(err), wrapped.Error())
+	require.ErrorIs(t, wrapped, err)
+	require.Equal(t, wrapped.Error(), jserrors.Report(fmt.Errorf("%w", wrapped)))
+}
+
+func TestRejectedKeepsTheStackOfErrors(t *testing.T) {
+	vm := goja.New()
+	value, err := vm.RunScript("reject.js", "function fail() {\n  return new TypeError(\"bad\");\n}\nfail();\n")
+	require.NoError(t, err)
+
+	rejected := jserrors.Rejected(value)
+	require.EqualError(t, rejected, "promise rejected: TypeError: bad")
+	x := jserrors.Find(rejected)
+	require.NotNil(t, x)
+	require.Equal(t, []jserrors.Frame{
+		{Function: "fail", File: "reject.js", Line: 2, Column: 10},
+		{File: "reject.js", Line: 4, Column: 5},
+	}, x.Frames)
+
+	require.EqualError(t, jserrors.Rejected(vm.ToValue(42)), "promise rejected: 42")
+	require.Nil(t, jserrors.Find(fmt.Errorf("plain")))
+	require.Equal(t, "plain", jserrors.Report(fmt.Errorf("plain")))
+}
+
+func TestInlineSourceMapDecodesDataURLs(t *testing.T) {
+	for name, code := range map[string]string{
+		"base64":  "x();\n//# sourceMappingURL=data:application/json;base64,eyJ2ZXJzaW9uIjozfQ==\n",
+		"escaped": "x();\n//# sourceMappingURL=data:application/json,%7B%22version%22%3A3%7D",
+	} {
+		raw, err := jserrors.InlineSourceMap([]byte(code))
+		require.NoError(t, err, name)
+		require.JSONEq(t, `{"version":3}`, string(raw), name)
+	}
+
+	raw, err := jserrors.InlineSourceMap([]byte("x();\n//# sourceMappingURL=x.js.map\n"))
+	require.NoError(t, err)
+	require.Nil(t, raw)
+	_, err = jserrors.InlineSourceMap([]byte("//# sourceMappingURL=data:application/json;base64,!"))
+	require.Error(t, err)
+}
+
+func TestRetainReplacesTheSourceOfAReloadedFile(t *testing.T) {
+	compile := func(source string) string {
+		result := api.Transform(source, api.TransformOptions{Sourcefile: "virtual.ts", Loader: api.LoaderTS, Sourcemap: api.SourceMapInline})
+		require.Empty(t, result.Errors)
+		return string(result.Code)
+	}
+	report := func(code string) string {
+		_, err := goja.New().RunScript("virtual.js", string(jserrors.Retain("virtual.js", []byte(code))))
+		require.Error(t, err)
+		return jserrors.Report(err)
+	}
+
+	require.Contains(t, report(compile("const first: number = 1;\nthrow new Error(\"first\");\n")), "> 2 | throw new Error(\"first\");")
+	second := report(compile("const second: number = 2;\n\nthrow new Error(\"second\");\n"))
+	require.Contains(t, second, "> 3 | throw new Error(\"second\");")
+	require.NotContains(t, second, "first")
+}
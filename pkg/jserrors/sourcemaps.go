@@ -0,0 +1,184 @@
+package jserrors
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/dop251/goja/file"
+	"github.com/dop251/goja_nodejs/require"
+	"github.com/pkg/errors"
+)
+
+const sourceMapPrefix = "//# sourceMappingURL="
+
+// retained records the original files of the source maps goja applied, by
+// the name goja reports them under. Texts are kept once per content hash, so
+// runtimes that load the same files share them, and a name that is retained
+// again with other content releases the text it had before.
+var retained = struct {
+	mu sync.RWMutex
+	// names holds the hash of the text of each original file.
+	names map[string]sourceHash
+	// texts holds each distinct text with the number of names that refer to
+	// it. The text is "" when a map does not carry it.
+	texts map[sourceHash]*retainedText
+}{names: map[string]sourceHash{}, texts: map[sourceHash]*retainedText{}}
+
+type sourceHash [sha256.Size]byte
+
+type retainedText struct {
+	text string
+	refs int
+}
+
+// Retain records the source map inlined at the end of code, which goja
+// compiles as the file name. goja applies such maps itself, so positions in
+// stack traces already point at the original files, but it reports their
+// columns 0-based. Retain lets reports correct those columns and show code
+// frames of files that only exist in the map. Code without an inline map is
+// ignored. Retain returns code so that loaders can wrap what they return.
+func Retain(name string, code []byte) []byte {
+	raw, err := InlineSourceMap(code)
+	if err != nil || raw == nil {
+		return code
+	}
+	var sm struct {
+		SourceRoot     string    `json:"sourceRoot"`
+		Sources        []string  `json:"sources"`
+		SourcesContent []*string `json:"sourcesContent"`
+	}
+	if err := json.Unmarshal(raw, &sm); err != nil {
+		return code
+	}
+
+	retained.mu.Lock()
+	defer retained.mu.Unlock()
+	for i, source := range sm.Sources {
+		content := ""
+		if i < len(sm.SourcesContent) && sm.SourcesContent[i] != nil {
+			content = *sm.SourcesContent[i]
+		}
+		retainLocked(sourceName(name, sm.SourceRoot, source), content)
+	}
+	return code
+}
+
+// retainLocked points name at content and releases the text name pointed at
+// before once nothing else refers to it.
+func retainLocked(name, content string) {
+	hash := sourceHash(sha256.Sum256([]byte(content)))
+	previous, ok := retained.names[name]
+	if ok && previous == hash {
+		return
+	}
+	if ok {
+		t := retained.texts[previous]
+		t.refs--
+		if t.refs == 0 {
+			delete(retained.texts, previous)
+		}
+	}
+	retained.names[name] = hash
+	if t, ok := retained.texts[hash]; ok {
+		t.refs++
+		return
+	}
+	retained.texts[hash] = &retainedText{text: content, refs: 1}
+}
+
+// SourceLoader wraps base so that the source maps of what it loads are
+// retained.
+func SourceLoader(base require.SourceLoader) require.SourceLoader {
+	return func(path string) ([]byte, error) {
+		code, err := base(path)
+		if err != nil {
+			return code, err
+		}
+		return Retain(path, code), nil
+	}
+}
+
+// InlineSourceMap returns the source map of the data URL in the
+// sourceMappingURL comment on the last line of code, as esbuild and tsscript
+// inline it, or nil when there is none. The map may be base64 or
+// percent-encoded.
+func InlineSourceMap(code []byte) ([]byte, error) {
+	code = bytes.TrimRight(code, " \t\r\n")
+	line := code[bytes.LastIndexByte(code, '\n')+1:]
+	if !bytes.HasPrefix(line, []byte(sourceMapPrefix+"data:")) {
+		return nil, nil
+	}
+	data := string(line[len(sourceMapPrefix+"data:"):])
+	comma := strings.IndexByte(data, ',')
+	if comma < 0 {
+		return nil, errors.New("malformed source map URL")
+	}
+	if strings.HasSuffix(data[:comma], ";base64") {
+		raw, err := base64.StdEncoding.DecodeString(data[comma+1:])
+		if err != nil {
+			return nil, errors.Wrap(err, "decode source map")
+		}
+		return raw, nil
+	}
+	unescaped, err := url.PathUnescape(data[comma+1:])
+	if err != nil {
+		return nil, errors.Wrap(err, "decode source map")
+	}
+	return []byte(unescaped), nil
+}
+
+// sourceName returns the name goja reports positions in source under: the
+// map's source resolved against its root and the compiled file.
+func sourceName(name, root, source string) string {
+	if root != "" && !path.IsAbs(source) {
+		if u, err := url.Parse(source); err != nil || !u.IsAbs() {
+			source = path.Join(root, source)
+		}
+	}
+	if u := file.ResolveSourcemapURL(name, source); u != nil {
+		return u.String()
+	}
+	return source
+}
+
+// mapped reports whether goja maps positions into the file name.
+func mapped(name string) bool {
+	retained.mu.RLock()
+	defer retained.mu.RUnlock()
+	_, ok := retained.names[name]
+	return ok
+}
+
+// sourceText returns the text of the file name: the content its source map
+// carries, or the file on disk.
+func sourceText(name string) (string, bool) {
+	retained.mu.RLock()
+	content := ""
+	if hash, ok := retained.names[name]; ok {
+		content = retained.texts[hash].text
+	}
+	retained.mu.RUnlock()
+	if content != "" {
+		return content, true
+	}
+	p := name
+	if u, err := url.Parse(name); err == nil && u.Scheme == "file" {
+		p = u.Path
+	}
+	if !filepath.IsAbs(p) {
+		return "", false
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
@@ -3,18 +3,15 @@ package jsevents
 import (
 	"context"
 	"fmt"
-	"io/fs"
 	"math"
-	"os"
-	pathpkg "path"
 	"path/filepath"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/dop251/goja"
 	"github.com/fsnotify/fsnotify"
 	"github.com/go-go-golems/go-go-goja/pkg/engine"
+	"github.com/go-go-golems/go-go-goja/pkg/fswatch"
 )
 
 // FSWatchOptions configures the opt-in JavaScript helper installed by
@@ -120,35 +117,6 @@ func (c fsWatchConnection) ToValue(vm *goja.Runtime) goja.Value {
 	return obj
 }
 
-type fsWatchGlobMatcher struct {
-	include []string
-	exclude []string
-}
-
-type pendingFSEvent struct {
-	Event fsnotify.Event
-	Count int
-}
-
-type fsWatchState struct {
-	watchPath  string
-	watchIsDir bool
-	opts       fsWatchCallOptions
-	matcher    fsWatchGlobMatcher
-	hostOpts   FSWatchOptions
-	watcher    *fsnotify.Watcher
-	ref        *EmitterRef
-
-	mu           sync.Mutex
-	watchedPaths map[string]struct{}
-
-	debounceMu         sync.Mutex
-	pending            map[string]pendingFSEvent
-	timers             map[string]*time.Timer
-	debounceGeneration map[string]uint64
-	debounceClosed     bool
-}
-
 // FSWatchHelper installs a JS-callable helper object with watch(path, emitter,
 // options?). It does not create any filesystem watchers until JavaScript calls
 // watch.
@@ -192,25 +160,35 @@ func (h *fsWatchHelper) InitRuntime(ctx *engine.RuntimeInitializationContext) er
 			panic(ctx.VM.NewGoError(err))
 		}
 
-		watcher, err := fsnotify.NewWatcher()
+		watch, err := fswatch.Start(ctx.Context, path, fswatch.Options{
+			Recursive:  callOpts.Recursive,
+			Debounce:   callOpts.Debounce,
+			Include:    callOpts.Include,
+			Exclude:    callOpts.Exclude,
+			IgnorePath: h.opts.IgnorePath,
+		}, fswatch.Handler{
+			Event: func(event fswatch.Event) {
+				payload := eventPayload(event)
+				_ = ref.EmitWithBuilder(context.Background(), "event", func(vm *goja.Runtime) ([]goja.Value, error) {
+					return []goja.Value{payload.ToValue(vm)}, nil
+				})
+			},
+			Error: func(err error) {
+				payload := fsWatchErrorPayload{Source: "fsnotify", Path: path, Message: err.Error()}
+				_ = ref.EmitWithBuilder(context.Background(), "error", func(vm *goja.Runtime) ([]goja.Value, error) {
+					return []goja.Value{payload.ToValue(vm)}, nil
+				})
+			},
+			Closed: func() {
+				_ = ref.Emit(context.Background(), "close")
+				_ = ref.Close(context.Background())
+			},
+		})
 		if err != nil {
-			_ = ref.Close(context.Background())
-			panic(ctx.VM.NewGoError(fmt.Errorf("fswatch: create watcher: %w", err)))
-		}
-
-		state := newFSWatchState(path, callOpts, h.opts, watcher, ref)
-		if err := state.start(); err != nil {
-			_ = watcher.Close()
 			_ = ref.Close(context.Background())
 			panic(ctx.VM.NewGoError(err))
 		}
-
-		watchCtx, cancel := context.WithCancel(ctx.Context)
-		ref.SetCancel(func() {
-			state.stopDebounceTimers()
-			cancel()
-		})
-		go state.run(watchCtx)
+		ref.SetCancel(watch.Close)
 
 		return fsWatchConnection{Ref: ref, Path: path, Options: callOpts}.ToValue(ctx.VM)
 	}); err != nil {
@@ -303,10 +281,10 @@ func decodeFSWatchCallOptions(vm *goja.Runtime, value goja.Value, hostOpts FSWat
 	}
 	ret.Include = include
 	ret.Exclude = exclude
-	if err := validateGlobPatterns(ret.Include, "include"); err != nil {
+	if err := fswatch.ValidatePatterns(ret.Include, "include"); err != nil {
 		return ret, err
 	}
-	if err := validateGlobPatterns(ret.Exclude, "exclude"); err != nil {
+	if err := fswatch.ValidatePatterns(ret.Exclude, "exclude"); err != nil {
 		return ret, err
 	}
 	return ret, nil
@@ -337,374 +315,20 @@ func stringArrayOption(vm *goja.Runtime, value goja.Value, name string) ([]strin
 	return ret, nil
 }
 
-func validateGlobPatterns(patterns []string, name string) error {
-	for i, pattern := range patterns {
-		for _, segment := range splitGlobPath(pattern) {
-			if segment == "**" {
-				continue
-			}
-			if _, err := pathpkg.Match(segment, ""); err != nil {
-				return fmt.Errorf("fswatch: invalid %s glob at index %d: %w", name, i, err)
-			}
-		}
-	}
-	return nil
-}
-
-func newFSWatchState(watchPath string, opts fsWatchCallOptions, hostOpts FSWatchOptions, watcher *fsnotify.Watcher, ref *EmitterRef) *fsWatchState {
-	return &fsWatchState{
-		watchPath:          watchPath,
-		opts:               opts,
-		matcher:            fsWatchGlobMatcher{include: opts.Include, exclude: opts.Exclude},
-		hostOpts:           hostOpts,
-		watcher:            watcher,
-		ref:                ref,
-		watchedPaths:       map[string]struct{}{},
-		pending:            map[string]pendingFSEvent{},
-		timers:             map[string]*time.Timer{},
-		debounceGeneration: map[string]uint64{},
-	}
-}
-
-func (s *fsWatchState) start() error {
-	info, err := os.Lstat(s.watchPath)
-	if err != nil {
-		return fmt.Errorf("fswatch: stat %q: %w", s.watchPath, err)
-	}
-	if s.hostOpts.IgnorePath != nil && s.hostOpts.IgnorePath(s.watchPath) {
-		return fmt.Errorf("fswatch: path %q is ignored", s.watchPath)
-	}
-	s.watchIsDir = info.IsDir()
-	if s.opts.Recursive && info.IsDir() {
-		return s.addRecursive(s.watchPath)
-	}
-	return s.addWatchPath(s.watchPath)
-}
-
-func (s *fsWatchState) run(ctx context.Context) {
-	defer func() {
-		s.stopDebounceTimers()
-		_ = s.watcher.Close()
-	}()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case event, ok := <-s.watcher.Events:
-			if !ok {
-				_ = s.ref.Emit(context.Background(), "close")
-				_ = s.ref.Close(context.Background())
-				return
-			}
-			s.handleEvent(ctx, event)
-		case err, ok := <-s.watcher.Errors:
-			if !ok {
-				_ = s.ref.Emit(context.Background(), "close")
-				_ = s.ref.Close(context.Background())
-				return
-			}
-			_ = s.emitError(ctx, err)
-		}
-	}
-}
-
-func (s *fsWatchState) handleEvent(ctx context.Context, event fsnotify.Event) {
-	if s.opts.Recursive && event.Has(fsnotify.Create) {
-		s.maybeAddCreatedDirectory(ctx, event.Name)
-	}
-	if s.opts.Recursive && (event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)) {
-		s.removeWatchedPath(event.Name)
-	}
-	if !s.allowsEvent(event.Name) {
-		return
-	}
-	s.dispatch(ctx, event)
-}
-
-func (s *fsWatchState) addRecursive(root string) error {
-	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if !entry.IsDir() {
-			return nil
-		}
-		if entry.Type()&fs.ModeSymlink != 0 {
-			return filepath.SkipDir
-		}
-		if s.hostOpts.IgnorePath != nil && s.hostOpts.IgnorePath(path) {
-			return filepath.SkipDir
-		}
-		rel := s.relativeName(path)
-		if !s.matcher.ShouldDescend(rel) {
-			return filepath.SkipDir
-		}
-		return s.addWatchPath(path)
-	})
-}
-
-func (s *fsWatchState) addWatchPath(rawPath string) error {
-	cleaned := filepath.Clean(rawPath)
-	s.mu.Lock()
-	if _, ok := s.watchedPaths[cleaned]; ok {
-		s.mu.Unlock()
-		return nil
-	}
-	s.mu.Unlock()
-
-	if err := s.watcher.Add(cleaned); err != nil {
-		return fmt.Errorf("fswatch: watch %q: %w", cleaned, err)
-	}
-
-	s.mu.Lock()
-	s.watchedPaths[cleaned] = struct{}{}
-	s.mu.Unlock()
-	return nil
-}
-
-func (s *fsWatchState) maybeAddCreatedDirectory(ctx context.Context, rawPath string) {
-	info, err := os.Lstat(rawPath)
-	if err != nil || !info.IsDir() || info.Mode()&fs.ModeSymlink != 0 {
-		return
-	}
-	if s.hostOpts.IgnorePath != nil && s.hostOpts.IgnorePath(rawPath) {
-		return
-	}
-	if !s.matcher.ShouldDescend(s.relativeName(rawPath)) {
-		return
-	}
-	if err := s.addRecursive(rawPath); err != nil {
-		_ = s.emitError(ctx, err)
-	}
-}
-
-func (s *fsWatchState) removeWatchedPath(rawPath string) {
-	cleaned := filepath.Clean(rawPath)
-	s.mu.Lock()
-	_, ok := s.watchedPaths[cleaned]
-	if ok {
-		delete(s.watchedPaths, cleaned)
-	}
-	s.mu.Unlock()
-	if ok {
-		_ = s.watcher.Remove(cleaned)
-	}
-}
-
-func (s *fsWatchState) allowsEvent(name string) bool {
-	if s.hostOpts.IgnorePath != nil && s.hostOpts.IgnorePath(name) {
-		return false
-	}
-	return s.matcher.Allows(s.relativeName(name))
-}
-
-func (s *fsWatchState) dispatch(ctx context.Context, event fsnotify.Event) {
-	if s.opts.Debounce <= 0 {
-		_ = s.emitEvent(ctx, event, 1, false)
-		return
-	}
-	s.dispatchDebounced(event)
-}
-
-func (s *fsWatchState) dispatchDebounced(event fsnotify.Event) {
-	key := filepath.Clean(event.Name)
-	s.debounceMu.Lock()
-	if s.debounceClosed {
-		s.debounceMu.Unlock()
-		return
-	}
-	pending, ok := s.pending[key]
-	if ok {
-		pending.Event.Op |= event.Op
-		pending.Count++
-	} else {
-		pending = pendingFSEvent{Event: event, Count: 1}
-	}
-	s.pending[key] = pending
-	s.debounceGeneration[key]++
-	generation := s.debounceGeneration[key]
-	if timer, ok := s.timers[key]; ok {
-		timer.Stop()
-	}
-	s.timers[key] = time.AfterFunc(s.opts.Debounce, func() {
-		s.flushDebounced(key, generation)
-	})
-	s.debounceMu.Unlock()
-}
-
-func (s *fsWatchState) flushDebounced(key string, generation uint64) {
-	s.debounceMu.Lock()
-	if s.debounceClosed || s.debounceGeneration[key] != generation {
-		s.debounceMu.Unlock()
-		return
-	}
-	pending, ok := s.pending[key]
-	if ok {
-		delete(s.pending, key)
-		delete(s.debounceGeneration, key)
-	}
-	if timer, ok := s.timers[key]; ok {
-		timer.Stop()
-		delete(s.timers, key)
-	}
-	s.debounceMu.Unlock()
-	if !ok {
-		return
-	}
-	_ = s.emitEvent(context.Background(), pending.Event, pending.Count, true)
-}
-
-func (s *fsWatchState) stopDebounceTimers() {
-	s.debounceMu.Lock()
-	defer s.debounceMu.Unlock()
-	s.debounceClosed = true
-	for key, timer := range s.timers {
-		timer.Stop()
-		delete(s.timers, key)
-	}
-	for key := range s.pending {
-		delete(s.pending, key)
-	}
-	for key := range s.debounceGeneration {
-		delete(s.debounceGeneration, key)
-	}
-}
-
-func (s *fsWatchState) emitEvent(ctx context.Context, event fsnotify.Event, count int, debounced bool) error {
-	payload := s.eventPayload(event, count, debounced)
-	return s.ref.EmitWithBuilder(ctx, "event", func(vm *goja.Runtime) ([]goja.Value, error) {
-		return []goja.Value{payload.ToValue(vm)}, nil
-	})
-}
-
-func (s *fsWatchState) emitError(ctx context.Context, err error) error {
-	payload := fsWatchErrorPayload{
-		Source:  "fsnotify",
-		Path:    s.watchPath,
-		Message: err.Error(),
-	}
-	return s.ref.EmitWithBuilder(ctx, "error", func(vm *goja.Runtime) ([]goja.Value, error) {
-		return []goja.Value{payload.ToValue(vm)}, nil
-	})
-}
-
-func (s *fsWatchState) eventPayload(event fsnotify.Event, count int, debounced bool) fsWatchEventPayload {
+func eventPayload(event fswatch.Event) fsWatchEventPayload {
 	return fsWatchEventPayload{
 		Source:       "fsnotify",
-		WatchPath:    s.watchPath,
+		WatchPath:    event.WatchPath,
 		Name:         event.Name,
-		RelativeName: s.relativeName(event.Name),
+		RelativeName: event.RelativeName,
 		Op:           event.Op.String(),
 		Create:       event.Has(fsnotify.Create),
 		Write:        event.Has(fsnotify.Write),
 		Remove:       event.Has(fsnotify.Remove),
 		Rename:       event.Has(fsnotify.Rename),
 		Chmod:        event.Has(fsnotify.Chmod),
-		Recursive:    s.opts.Recursive,
-		Debounced:    debounced,
-		Count:        count,
-	}
-}
-
-func (s *fsWatchState) relativeName(name string) string {
-	rel, err := filepath.Rel(s.watchPath, name)
-	if err != nil {
-		return ""
-	}
-	if rel == "." {
-		if s.watchIsDir {
-			return ""
-		}
-		return filepath.ToSlash(filepath.Base(s.watchPath))
-	}
-	return filepath.ToSlash(rel)
-}
-
-func (m fsWatchGlobMatcher) Allows(rel string) bool {
-	rel = normalizeGlobPath(rel)
-	if len(m.include) > 0 {
-		matched := false
-		for _, pattern := range m.include {
-			if matchGlob(pattern, rel) {
-				matched = true
-				break
-			}
-		}
-		if !matched {
-			return false
-		}
-	}
-	for _, pattern := range m.exclude {
-		if matchGlob(pattern, rel) {
-			return false
-		}
-	}
-	return true
-}
-
-func (m fsWatchGlobMatcher) ShouldDescend(rel string) bool {
-	rel = normalizeGlobPath(rel)
-	for _, pattern := range m.exclude {
-		if matchGlob(pattern, rel) {
-			return false
-		}
-	}
-	return true
-}
-
-func matchGlob(pattern, rel string) bool {
-	patternSegments := splitGlobPath(pattern)
-	relSegments := splitGlobPath(rel)
-	return matchGlobSegments(patternSegments, relSegments)
-}
-
-func matchGlobSegments(patternSegments, relSegments []string) bool {
-	if len(patternSegments) == 0 {
-		return len(relSegments) == 0
-	}
-	if patternSegments[0] == "**" {
-		if matchGlobSegments(patternSegments[1:], relSegments) {
-			return true
-		}
-		for i := range relSegments {
-			if matchGlobSegments(patternSegments[1:], relSegments[i+1:]) {
-				return true
-			}
-		}
-		return false
-	}
-	if len(relSegments) == 0 {
-		return false
-	}
-	matched, err := pathpkg.Match(patternSegments[0], relSegments[0])
-	if err != nil || !matched {
-		return false
-	}
-	return matchGlobSegments(patternSegments[1:], relSegments[1:])
-}
-
-func splitGlobPath(value string) []string {
-	value = normalizeGlobPath(value)
-	if value == "" {
-		return nil
-	}
-	parts := strings.Split(value, "/")
-	ret := parts[:0]
-	for _, part := range parts {
-		if part != "" && part != "." {
-			ret = append(ret, part)
-		}
-	}
-	return ret
-}
-
-func normalizeGlobPath(value string) string {
-	value = filepath.ToSlash(strings.TrimSpace(value))
-	value = strings.TrimPrefix(value, "./")
-	value = strings.Trim(value, "/")
-	if value == "." {
-		return ""
+		Recursive:    event.Recursive,
+		Debounced:    event.Debounced,
+		Count:        event.Count,
 	}
-	return value
 }
@@ -247,6 +247,8 @@ func (r *EmitterRef) EmitWithBuilderSync(ctx context.Context, name string, build
 }
 
 // Close cancels the Go-side resource and unregisters the connected emitter.
+// Async iterators created with events.on() for the emitter finish once they
+// have returned the events they already buffered.
 func (r *EmitterRef) Close(ctx context.Context) error {
 	if r == nil {
 		return nil
@@ -267,6 +269,11 @@ func (r *EmitterRef) Close(ctx context.Context) error {
 		r.manager.mu.Lock()
 		delete(r.manager.refs, r.id)
 		r.manager.mu.Unlock()
+		// End events.on() loops over this emitter. The runtime may be
+		// shutting down, in which case there is nothing left to end.
+		_ = r.manager.owner.Post(context.Background(), "jsevents.endIterators."+r.id, func(context.Context, *goja.Runtime) {
+			r.emitter.EndIterators()
+		})
 	})
 	return err
 }
@@ -90,6 +90,51 @@ func TestManagerAsyncEmitReportsListenerErrors(t *testing.T) {
 	}, time.Second, 10*time.Millisecond)
 }
 
+func TestEmitterRefCloseEndsAsyncIterators(t *testing.T) {
+	var ref *jsevents.EmitterRef
+	rt := newRuntime(t, jsevents.Install())
+	manager, ok := jsevents.FromRuntime(rt)
+	require.True(t, ok)
+
+	_, err := rt.Owner.Call(context.Background(), "jsevents.test.iterate", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		if err := vm.Set("adopt", func(value goja.Value) bool {
+			var adoptErr error
+			ref, adoptErr = manager.AdoptEmitterOnOwner(value)
+			return adoptErr == nil
+		}); err != nil {
+			return nil, err
+		}
+		_, err := vm.RunString(`
+			const { EventEmitter, on } = require("events");
+			const emitter = new EventEmitter();
+			if (!adopt(emitter)) throw new Error("adopt failed");
+			globalThis.received = [];
+			globalThis.finished = false;
+			(async () => {
+				const it = on(emitter, "message");
+				for (let r = await it.next(); !r.done; r = await it.next()) {
+					received.push(r.value[0]);
+				}
+				finished = true;
+			})();
+		`)
+		return nil, err
+	})
+	require.NoError(t, err)
+	require.NotNil(t, ref)
+
+	for _, msg := range []string{"first", "second"} {
+		_, err := ref.EmitSync(context.Background(), "message", msg)
+		require.NoError(t, err)
+	}
+	require.NoError(t, ref.Close(context.Background()))
+
+	require.Eventually(t, func() bool {
+		got, err := runJSTry(rt, `JSON.stringify({ received, finished })`)
+		return err == nil && got == `{"received":["first","second"],"finished":true}`
+	}, time.Second, 10*time.Millisecond)
+}
+
 func newRuntime(t *testing.T, inits ...gggengine.RuntimeInitializer) *gggengine.Runtime {
 	t.Helper()
 	factory, err := gggengine.NewRuntimeFactoryBuilder().WithRuntimeInitializers(inits...).Build()
@@ -0,0 +1,419 @@
+// Package jsinstrument rewrites CommonJS module sources by inserting code,
+// for tools that observe scripts while they run: the jsdebug debugger and
+// the jscover coverage collector.
+//
+// Sources are parsed with jsparse inside the same function wrapper that
+// goja_nodejs require compiles them in, so that top-level return statements
+// parse and scopes match. Insertions never add line breaks, so line numbers
+// of the rewritten code match the original source.
+package jsinstrument
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/dop251/goja/parser"
+	"github.com/go-go-golems/go-go-goja/pkg/jsparse"
+	"github.com/pkg/errors"
+)
+
+// ModuleWrapper is the prefix goja_nodejs require puts around every CommonJS
+// source.
+const ModuleWrapper = "(function(exports,require,module,__filename,__dirname){"
+
+// listContainers are the node kinds whose statement children run in order.
+var listContainers = map[string]bool{
+	"BlockStatement": true,
+	"CaseStatement":  true,
+}
+
+// bodyContainers are the node kinds that take a single statement as their
+// body.
+var bodyContainers = map[string]bool{
+	"IfStatement":      true,
+	"ForStatement":     true,
+	"ForInStatement":   true,
+	"ForOfStatement":   true,
+	"WhileStatement":   true,
+	"DoWhileStatement": true,
+	"WithStatement":    true,
+}
+
+// skippedStatements are not reported by Statements: declarations that are
+// hoisted, statements that only group others, and statements that do
+// nothing.
+var skippedStatements = map[string]bool{
+	"BlockStatement":      true,
+	"EmptyStatement":      true,
+	"FunctionDeclaration": true,
+	"BadStatement":        true,
+	"CaseStatement":       true,
+	"CatchStatement":      true,
+}
+
+// Module is a parsed module source.
+type Module struct {
+	Path     string
+	Text     string
+	Analysis *jsparse.AnalysisResult
+	Index    *jsparse.Index
+
+	wrapped string
+	starts  map[jsparse.NodeID]int
+	nodes   []*jsparse.NodeRecord
+}
+
+// Parse parses text, the source of the module at path.
+func Parse(path, text string) (*Module, error) {
+	wrapped := ModuleWrapper + text + "\n})"
+	analysis := jsparse.Analyze(path, wrapped, nil)
+	if analysis.ParseErr != nil {
+		return nil, errors.Wrap(analysis.ParseErr, "parse")
+	}
+	return &Module{
+		Path:     path,
+		Text:     text,
+		Analysis: analysis,
+		Index:    analysis.Index,
+		wrapped:  wrapped,
+		starts:   map[jsparse.NodeID]int{},
+	}, nil
+}
+
+// Offset converts a jsparse offset, 1-based in the wrapped source, into a
+// 0-based offset in Text. Offsets inside the wrapper are negative.
+func (m *Module) Offset(wrapped int) int {
+	return wrapped - 1 - len(ModuleWrapper)
+}
+
+// WrappedOffset is the inverse of Offset.
+func (m *Module) WrappedOffset(offset int) int {
+	return offset + 1 + len(ModuleWrapper)
+}
+
+// Start returns the 0-based offset in Text where node n starts. Some goja
+// nodes report a start after their first token: a tagged template starts at
+// its backquote rather than its tag, so the earliest descendant wins. The
+// parser also leaves the position of the if keyword unset, so if statements
+// start at the last "if" before their condition.
+func (m *Module) Start(n *jsparse.NodeRecord) int {
+	return m.Offset(m.start(n))
+}
+
+func (m *Module) start(n *jsparse.NodeRecord) int {
+	if start, ok := m.starts[n.ID]; ok {
+		return start
+	}
+	start := m.computeStart(n)
+	m.starts[n.ID] = start
+	return start
+}
+
+func (m *Module) computeStart(n *jsparse.NodeRecord) int {
+	if n.Kind == "IfStatement" && len(n.ChildIDs) > 0 {
+		test := m.Index.Nodes[n.ChildIDs[0]].Start - 1
+		if test >= 0 && test <= len(m.wrapped) {
+			if i := strings.LastIndex(m.wrapped[:test], "if"); i >= 0 {
+				return i + 1
+			}
+		}
+		return n.Start
+	}
+	start := n.Start
+	for _, id := range n.ChildIDs {
+		if child := m.Index.Nodes[id]; child != nil {
+			if s := m.start(child); s < start {
+				start = s
+			}
+		}
+	}
+	return start
+}
+
+// End returns the 0-based offset in Text just after node n.
+func (m *Module) End(n *jsparse.NodeRecord) int {
+	return m.Offset(n.End)
+}
+
+// Parent returns the parent of n, or nil for the root.
+func (m *Module) Parent(n *jsparse.NodeRecord) *jsparse.NodeRecord {
+	if n.ParentID < 0 {
+		return nil
+	}
+	return m.Index.Nodes[n.ParentID]
+}
+
+// Children returns the children of n ordered by start.
+func (m *Module) Children(n *jsparse.NodeRecord) []*jsparse.NodeRecord {
+	out := make([]*jsparse.NodeRecord, 0, len(n.ChildIDs))
+	for _, id := range n.ChildIDs {
+		if child := m.Index.Nodes[id]; child != nil && !isHoistedCopy(n, child) {
+			out = append(out, child)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return m.start(out[i]) < m.start(out[j]) })
+	return out
+}
+
+// isHoistedCopy reports whether child is an entry of the list of var
+// declarations goja keeps on functions. Those entries repeat the var
+// statements of the body, initializers included, so walking them would
+// visit the same code twice.
+func isHoistedCopy(parent, child *jsparse.NodeRecord) bool {
+	if child.Kind != "VariableDeclaration" {
+		return false
+	}
+	switch parent.Kind {
+	case "FunctionLiteral", "ArrowFunctionLiteral", "Program":
+		return true
+	}
+	return false
+}
+
+// Nodes returns the nodes inside the module, not those of the wrapper,
+// ordered by start.
+func (m *Module) Nodes() []*jsparse.NodeRecord {
+	if m.nodes != nil {
+		return m.nodes
+	}
+	var out []*jsparse.NodeRecord
+	var walk func(n *jsparse.NodeRecord)
+	walk = func(n *jsparse.NodeRecord) {
+		if start := m.Start(n); start >= 0 && start <= len(m.Text) && m.End(n) <= len(m.Text) {
+			out = append(out, n)
+		}
+		for _, child := range m.Children(n) {
+			walk(child)
+		}
+	}
+	if root := m.Index.Nodes[m.Index.RootID]; root != nil {
+		walk(root)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return m.Start(out[i]) < m.Start(out[j]) })
+	m.nodes = out
+	return out
+}
+
+// Statement is a statement code can be inserted in front of.
+type Statement struct {
+	Node *jsparse.NodeRecord
+	// Start and End are 0-based offsets in Text. End includes the trailing
+	// semicolon goja leaves out of the node.
+	Start int
+	End   int
+	// Line and Column are 1-based.
+	Line   int
+	Column int
+	// Body is set when the statement is the single-statement body of an
+	// if, for, while, do or with statement. Code inserted in front of it
+	// must be wrapped in a block together with it; see WrapBody.
+	Body bool
+}
+
+// Statements returns the statements of the module that run in order:
+// those of blocks, switch cases and single-statement bodies, without
+// hoisted declarations and directives. They are ordered by start.
+func (m *Module) Statements() []Statement {
+	var out []Statement
+	for _, n := range m.Nodes() {
+		if !isStatementKind(n.Kind) || skippedStatements[n.Kind] {
+			continue
+		}
+		parent := m.Parent(n)
+		if parent == nil || !listContainers[parent.Kind] && !bodyContainers[parent.Kind] {
+			continue
+		}
+		if n.Kind == "ExpressionStatement" && m.isDirective(n) {
+			continue
+		}
+		out = append(out, m.Statement(n))
+	}
+	return out
+}
+
+// Statement returns the statement n, which need not be one Statements
+// reports.
+func (m *Module) Statement(n *jsparse.NodeRecord) Statement {
+	st := Statement{Node: n, Start: statementStart(m.Text, m.Start(n)), End: statementEnd(m.Text, m.End(n))}
+	if parent := m.Parent(n); parent != nil && bodyContainers[parent.Kind] {
+		st.Body = true
+	}
+	st.Line, st.Column = LineColumn(m.Text, st.Start)
+	return st
+}
+
+// BodyOffset returns where code can go at the start of block, a block
+// statement or function body: after its opening brace and any directive
+// prologue.
+func (m *Module) BodyOffset(block *jsparse.NodeRecord) int {
+	offset := m.Start(block) + 1
+	for _, n := range m.Children(block) {
+		if n.Kind != "ExpressionStatement" || !m.isDirective(n) {
+			break
+		}
+		offset = statementEnd(m.Text, m.End(n))
+	}
+	return offset
+}
+
+// WrapBody returns the insertions that wrap a single-statement body in a
+// block. Insert code in front of the statement after them.
+func WrapBody(st Statement) []Insertion {
+	span := st.End - st.Start
+	return []Insertion{
+		{Offset: st.Start, Text: "{ ", Span: span},
+		{Offset: st.End, Text: " }", Close: true, Span: span},
+	}
+}
+
+// PreludeOffset returns where module-level declarations go: at the start of
+// the module, or after its directive prologue.
+func (m *Module) PreludeOffset() int {
+	offset := 0
+	for _, n := range m.Nodes() {
+		if parent := m.Parent(n); parent == nil || parent.Kind != "BlockStatement" {
+			continue
+		}
+		// The first statement inside the wrapper decides.
+		if n.Kind == "ExpressionStatement" && m.isDirective(n) {
+			offset = statementEnd(m.Text, m.End(n))
+			continue
+		}
+		break
+	}
+	return offset
+}
+
+// isDirective reports whether an expression statement is a bare string
+// such as "use strict". Nothing may precede a directive prologue.
+func (m *Module) isDirective(n *jsparse.NodeRecord) bool {
+	return len(n.ChildIDs) == 1 && m.Index.Nodes[n.ChildIDs[0]].Kind == "StringLiteral"
+}
+
+func isStatementKind(kind string) bool {
+	return strings.HasSuffix(kind, "Statement") || kind == "LexicalDeclaration" || kind == "ClassDeclaration"
+}
+
+// statementStart moves a statement's start before the parentheses goja
+// leaves out of it, as in (() => {})(). A statement cannot follow an
+// opening parenthesis, so any directly before it are its own.
+func statementStart(text string, start int) int {
+	for i := start - 1; i >= 0; i-- {
+		switch text[i] {
+		case ' ', '\t', '\r', '\n':
+		case '(':
+			start = i
+		default:
+			return start
+		}
+	}
+	return start
+}
+
+// statementEnd extends a statement's end over the semicolon goja leaves
+// out of its span.
+func statementEnd(text string, end int) int {
+	i := end
+	for i < len(text) && (text[i] == ' ' || text[i] == '\t') {
+		i++
+	}
+	if i < len(text) && text[i] == ';' {
+		return i + 1
+	}
+	return end
+}
+
+// LineColumn converts a 0-based offset into a 1-based line and column.
+func LineColumn(text string, offset int) (int, int) {
+	if offset > len(text) {
+		offset = len(text)
+	}
+	line := 1 + strings.Count(text[:offset], "\n")
+	return line, offset - strings.LastIndex(text[:offset], "\n")
+}
+
+// Insertion is text added at a 0-based offset of the original source.
+type Insertion struct {
+	Offset int
+	Text   string
+	// Close marks text that ends a construct, such as a closing brace or
+	// parenthesis. At the same offset closing text goes first, innermost
+	// first, then opening text, outermost first. Span, the length of the
+	// wrapped construct, decides which is which; insertions with the same
+	// span keep their order.
+	Close bool
+	Span  int
+}
+
+// Rewrite applies insertions to the module and checks that the result
+// still parses.
+func (m *Module) Rewrite(inserts []Insertion) (*Rewritten, error) {
+	inserts = append([]Insertion(nil), inserts...)
+	sort.SliceStable(inserts, func(i, j int) bool {
+		a, b := inserts[i], inserts[j]
+		if a.Offset != b.Offset {
+			return a.Offset < b.Offset
+		}
+		if a.Close != b.Close {
+			return a.Close
+		}
+		if a.Close {
+			return a.Span < b.Span
+		}
+		return a.Span > b.Span
+	})
+
+	var out strings.Builder
+	r := &Rewritten{shifts: map[int][]shift{}}
+	last := 0
+	for _, ins := range inserts {
+		if ins.Offset < last || ins.Offset > len(m.Text) {
+			return nil, errors.Errorf("insertion at offset %d is outside the source", ins.Offset)
+		}
+		out.WriteString(m.Text[last:ins.Offset])
+		out.WriteString(ins.Text)
+		last = ins.Offset
+		line, column := LineColumn(m.Text, ins.Offset)
+		r.shifts[line] = append(r.shifts[line], shift{column: column - 1, length: len(ins.Text)})
+	}
+	out.WriteString(m.Text[last:])
+	r.Code = out.String()
+
+	if _, err := parser.ParseFile(nil, m.Path, ModuleWrapper+r.Code+"\n})", 0); err != nil {
+		return nil, errors.Wrap(err, "instrumented source does not parse")
+	}
+	return r, nil
+}
+
+// Rewritten is a rewritten module source.
+type Rewritten struct {
+	Code string
+	// shifts holds, per 1-based line, the insertions that moved the
+	// columns of the rewritten code, ordered by original column.
+	shifts map[int][]shift
+}
+
+type shift struct {
+	column int // 0-based column in the original line
+	length int
+}
+
+// OriginalColumn maps a 1-based column of the rewritten code back to the
+// original source. Columns inside inserted text map to where it was
+// inserted.
+func (r *Rewritten) OriginalColumn(line, column int) int {
+	col := column - 1
+	moved := 0
+	for _, s := range r.shifts[line] {
+		start := s.column + moved
+		if col >= start+s.length {
+			moved += s.length
+			continue
+		}
+		if col >= start {
+			return s.column + 1
+		}
+		break
+	}
+	return col - moved + 1
+}
@@ -0,0 +1,50 @@
+package jsinstrument
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatementsSkipDirectivesAndMarkBodies(t *testing.T) {
+	m, err := Parse("/tmp/a.js", "\"use strict\";\nvar a = 1;\nwhile (a) a--;\n")
+	require.NoError(t, err)
+
+	var got []Statement
+	for _, st := range m.Statements() {
+		got = append(got, Statement{Start: st.Start, End: st.End, Line: st.Line, Column: st.Column, Body: st.Body})
+	}
+	require.Equal(t, []Statement{
+		{Start: 14, End: 24, Line: 2, Column: 1},
+		{Start: 25, End: 39, Line: 3, Column: 1},
+		{Start: 35, End: 39, Line: 3, Column: 11, Body: true},
+	}, got)
+	require.Equal(t, 13, m.PreludeOffset())
+}
+
+func TestRewriteNestsInsertionsAndMapsColumns(t *testing.T) {
+	m, err := Parse("/tmp/a.js", "if (x) y();\n")
+	require.NoError(t, err)
+
+	body := m.Statements()[1]
+	inserts := append(WrapBody(body), Insertion{Offset: body.Start, Text: "hook(); ", Span: body.End - body.Start})
+	r, err := m.Rewrite(inserts)
+	require.NoError(t, err)
+	require.Equal(t, "if (x) { hook(); y(); }\n", r.Code)
+	require.Equal(t, 8, r.OriginalColumn(1, 18))
+	require.Equal(t, 8, r.OriginalColumn(1, 10))
+
+	_, err = m.Rewrite([]Insertion{{Offset: 0, Text: "("}})
+	require.Error(t, err)
+}
+
+func TestStatementsStartBeforeParentheses(t *testing.T) {
+	m, err := Parse("/tmp/a.js", "x;\n(() => {})();\n")
+	require.NoError(t, err)
+
+	sts := m.Statements()
+	require.Len(t, sts, 2)
+	require.Equal(t, 3, sts[1].Start)
+	require.Equal(t, 2, sts[1].Line)
+	require.Equal(t, 1, sts[1].Column)
+}
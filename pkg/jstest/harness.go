@@ -0,0 +1,9 @@
+package jstest
+
+import _ "embed"
+
+// harnessName is the file name frames of the harness carry.
+const harnessName = "goja-test:harness.js"
+
+//go:embed harness.js
+var harnessSource string
@@ -0,0 +1,830 @@
+// The test harness of pkg/jstest. It is evaluated once per runtime, called
+// with the host functions of the runner, installs the globals test files use
+// and returns the functions the runner drives the tests with.
+(function (host) {
+  "use strict";
+
+  var global = Function("return this")();
+
+  // --- Suites and tests ---
+
+  function newSuite(name, parent, mode) {
+    return {
+      name: name,
+      parent: parent,
+      mode: mode,
+      beforeAll: [],
+      afterAll: [],
+      beforeEach: [],
+      afterEach: [],
+      planned: 0,
+      finished: 0,
+      started: false,
+      failure: undefined,
+    };
+  }
+
+  var root = newSuite("", null, "");
+  var current = root;
+  var tests = [];
+  var currentTest = null;
+  var collecting = true;
+
+  function checkName(kind, name, fn) {
+    if (typeof name !== "string") {
+      throw new TypeError(kind + "() expects a name as its first argument");
+    }
+    if (fn !== undefined && typeof fn !== "function") {
+      throw new TypeError(kind + "(" + JSON.stringify(name) + ") expects a function");
+    }
+  }
+
+  function checkCollecting(kind) {
+    if (!collecting) {
+      throw new Error(kind + "() cannot be called while tests run");
+    }
+  }
+
+  function defineSuite(mode) {
+    return function describe(name, fn) {
+      checkCollecting("describe");
+      checkName("describe", name, fn);
+      var suite = newSuite(name, current, mode);
+      var previous = current;
+      current = suite;
+      try {
+        var ret = fn();
+        if (ret && typeof ret.then === "function") {
+          throw new Error("describe(" + JSON.stringify(name) + ") callback must not be async; use beforeAll for async setup");
+        }
+      } finally {
+        current = previous;
+      }
+    };
+  }
+
+  function defineTest(mode) {
+    return function it(name, fn, timeout) {
+      checkCollecting("it");
+      checkName("it", name, fn);
+      if (fn === undefined && mode !== "todo") {
+        throw new TypeError("it(" + JSON.stringify(name) + ") expects a function; use it.todo for tests without one");
+      }
+      tests.push({ id: tests.length, name: name, fn: fn, suite: current, mode: mode, timeout: timeout || 0 });
+    };
+  }
+
+  function defineHook(kind) {
+    return function (fn) {
+      checkCollecting(kind);
+      if (typeof fn !== "function") {
+        throw new TypeError(kind + "() expects a function");
+      }
+      current[kind].push(fn);
+    };
+  }
+
+  var describe = defineSuite("");
+  describe.skip = defineSuite("skip");
+  describe.only = defineSuite("only");
+
+  var it = defineTest("");
+  it.skip = defineTest("skip");
+  it.only = defineTest("only");
+  it.todo = function (name) {
+    defineTest("todo")(name);
+  };
+
+  // suitePath lists suite and the suites it is in, starting at the root
+  // suite, which holds the hooks declared outside of describe.
+  function suitePath(suite) {
+    var path = [];
+    for (var s = suite; s; s = s.parent) {
+      path.unshift(s);
+    }
+    return path;
+  }
+
+  function suiteNames(suite) {
+    return suitePath(suite).slice(1).map(function (s) {
+      return s.name;
+    });
+  }
+
+  function fullName(test) {
+    return suiteNames(test.suite).concat([test.name]).join(" ");
+  }
+
+  function hasMode(test, mode) {
+    if (test.mode === mode) {
+      return true;
+    }
+    for (var s = test.suite; s; s = s.parent) {
+      if (s.mode === mode) {
+        return true;
+      }
+    }
+    return false;
+  }
+
+  // plan lists the tests of the file and decides which of them run: those
+  // not skipped, marked only when any test is, and matched by the runner's
+  // filter.
+  function plan() {
+    collecting = false;
+    var only = tests.some(function (test) {
+      return hasMode(test, "only");
+    });
+    return tests.map(function (test) {
+      var status = "run";
+      if (test.mode === "todo") {
+        status = "todo";
+      } else if (hasMode(test, "skip") || (only && !hasMode(test, "only")) || !host.match(fullName(test))) {
+        status = "skip";
+      }
+      test.planned = status === "run";
+      if (test.planned) {
+        for (var s = test.suite; s; s = s.parent) {
+          s.planned++;
+        }
+      }
+      return { id: test.id, suites: suiteNames(test.suite), name: test.name, status: status, timeout: test.timeout };
+    });
+  }
+
+  // invoke calls a test or hook function and returns a promise of its end.
+  // Functions that declare a parameter get a done callback.
+  function invoke(fn) {
+    if (fn.length > 0) {
+      return new Promise(function (resolve, reject) {
+        fn(function done(err) {
+          if (err) {
+            reject(err);
+          } else {
+            resolve();
+          }
+        });
+      });
+    }
+    return Promise.resolve().then(function () {
+      return fn();
+    });
+  }
+
+  async function runHooks(hooks, errors) {
+    for (var i = 0; i < hooks.length; i++) {
+      try {
+        await invoke(hooks[i]);
+      } catch (e) {
+        errors.push(e);
+      }
+    }
+  }
+
+  // run runs one planned test with its hooks. beforeAll hooks run before the
+  // first test of their suite and afterAll hooks after its last one. The
+  // promise resolves to the first error, if any.
+  async function run(id) {
+    var test = tests[id];
+    var path = suitePath(test.suite);
+    var errors = [];
+    currentTest = test;
+    snapshotCounts[id] = 0;
+
+    for (var i = 0; i < path.length; i++) {
+      var suite = path[i];
+      if (!suite.started) {
+        suite.started = true;
+        var setup = [];
+        await runHooks(suite.beforeAll, setup);
+        suite.failure = setup[0];
+      }
+      if (suite.failure !== undefined && errors.length === 0) {
+        errors.push(suite.failure);
+      }
+    }
+
+    if (errors.length === 0) {
+      mockScopes.push([]);
+      var each = [];
+      for (var j = 0; j < path.length; j++) {
+        await runHooks(path[j].beforeEach, each);
+      }
+      if (each.length === 0) {
+        try {
+          await invoke(test.fn);
+        } catch (e) {
+          errors.push(e);
+        }
+      }
+      errors = errors.concat(each);
+      for (var k = path.length - 1; k >= 0; k--) {
+        await runHooks(path[k].afterEach, errors);
+      }
+      restoreMocks(mockScopes.pop());
+    }
+
+    for (var m = path.length - 1; m >= 0; m--) {
+      var s = path[m];
+      s.finished++;
+      if (s.finished === s.planned) {
+        await runHooks(s.afterAll, errors);
+      }
+    }
+    currentTest = null;
+    return { error: errors[0] };
+  }
+
+  // --- Formatting ---
+
+  function typeName(value) {
+    var proto = Object.getPrototypeOf(value);
+    if (proto === null) {
+      return "Object";
+    }
+    var ctor = proto.constructor;
+    return (ctor && ctor.name) || "Object";
+  }
+
+  function isPlainObject(value) {
+    var proto = Object.getPrototypeOf(value);
+    return proto === null || proto === Object.prototype;
+  }
+
+  function ownKeys(value) {
+    return Object.keys(value).sort();
+  }
+
+  // serialize formats a value over several lines with sorted keys, the form
+  // both snapshots and failure messages use.
+  function serialize(value, indent, seen) {
+    indent = indent || "";
+    seen = seen || [];
+    switch (typeof value) {
+      case "undefined":
+        return "undefined";
+      case "string":
+        return JSON.stringify(value);
+      case "number":
+        return Object.is(value, -0) ? "-0" : String(value);
+      case "bigint":
+        return String(value) + "n";
+      case "boolean":
+        return String(value);
+      case "symbol":
+        return value.toString();
+      case "function":
+        return "[Function " + (value.name || "anonymous") + "]";
+    }
+    if (value === null) {
+      return "null";
+    }
+    if (seen.indexOf(value) >= 0) {
+      return "[Circular]";
+    }
+    if (value instanceof Date) {
+      return isNaN(value.getTime()) ? "Date { NaN }" : value.toISOString();
+    }
+    if (value instanceof RegExp) {
+      return String(value);
+    }
+    if (value instanceof Error) {
+      return "[" + value.name + ": " + value.message + "]";
+    }
+    if (typeof Promise !== "undefined" && value instanceof Promise) {
+      return "Promise {}";
+    }
+    var inner = indent + "  ";
+    var nested = seen.concat([value]);
+    var lines = [];
+    if (Array.isArray(value)) {
+      for (var i = 0; i < value.length; i++) {
+        lines.push(inner + serialize(value[i], inner, nested) + ",");
+      }
+      return block(value.constructor === Array ? "" : typeName(value) + " ", "[", lines, indent, "]");
+    }
+    if (value instanceof Map) {
+      value.forEach(function (v, k) {
+        lines.push(inner + serialize(k, inner, nested) + " => " + serialize(v, inner, nested) + ",");
+      });
+      return block("Map ", "{", lines, indent, "}");
+    }
+    if (value instanceof Set) {
+      value.forEach(function (v) {
+        lines.push(inner + serialize(v, inner, nested) + ",");
+      });
+      return block("Set ", "{", lines, indent, "}");
+    }
+    ownKeys(value).forEach(function (key) {
+      lines.push(inner + JSON.stringify(key) + ": " + serialize(value[key], inner, nested) + ",");
+    });
+    return block(isPlainObject(value) ? "" : typeName(value) + " ", "{", lines, indent, "}");
+  }
+
+  function block(prefix, open, lines, indent, close) {
+    if (lines.length === 0) {
+      return prefix + open + close;
+    }
+    return prefix + open + "\n" + lines.join("\n") + "\n" + indent + close;
+  }
+
+  // --- Equality ---
+
+  function equals(a, b, strict, seen) {
+    if (Object.is(a, b)) {
+      return true;
+    }
+    if (typeof a !== "object" || typeof b !== "object" || a === null || b === null) {
+      return false;
+    }
+    seen = seen || [];
+    for (var i = 0; i < seen.length; i++) {
+      if (seen[i][0] === a && seen[i][1] === b) {
+        return true;
+      }
+    }
+    seen = seen.concat([[a, b]]);
+    if (strict && Object.getPrototypeOf(a) !== Object.getPrototypeOf(b)) {
+      return false;
+    }
+    if (a instanceof Date || b instanceof Date) {
+      return a instanceof Date && b instanceof Date && a.getTime() === b.getTime();
+    }
+    if (a instanceof RegExp || b instanceof RegExp) {
+      return a instanceof RegExp && b instanceof RegExp && String(a) === String(b);
+    }
+    if (a instanceof Error || b instanceof Error) {
+      return a instanceof Error && b instanceof Error && a.name === b.name && a.message === b.message;
+    }
+    if (Array.isArray(a) !== Array.isArray(b)) {
+      return false;
+    }
+    if (a instanceof Map || b instanceof Map) {
+      if (!(a instanceof Map && b instanceof Map) || a.size !== b.size) {
+        return false;
+      }
+      var same = true;
+      a.forEach(function (v, k) {
+        if (same && (!b.has(k) || !equals(v, b.get(k), strict, seen))) {
+          same = false;
+        }
+      });
+      return same;
+    }
+    if (a instanceof Set || b instanceof Set) {
+      if (!(a instanceof Set && b instanceof Set) || a.size !== b.size) {
+        return false;
+      }
+      var found = true;
+      a.forEach(function (v) {
+        if (!found) {
+          return;
+        }
+        if (b.has(v)) {
+          return;
+        }
+        var match = false;
+        b.forEach(function (w) {
+          if (!match && equals(v, w, strict, seen)) {
+            match = true;
+          }
+        });
+        found = match;
+      });
+      return found;
+    }
+    if (Array.isArray(a) && a.length !== b.length) {
+      return false;
+    }
+    var keysA = equalityKeys(a, strict);
+    var keysB = equalityKeys(b, strict);
+    if (keysA.length !== keysB.length) {
+      return false;
+    }
+    for (var j = 0; j < keysA.length; j++) {
+      var key = keysA[j];
+      if (!Object.prototype.hasOwnProperty.call(b, key) && !(b[key] === undefined && !strict)) {
+        return false;
+      }
+      if (!equals(a[key], b[key], strict, seen)) {
+        return false;
+      }
+    }
+    return true;
+  }
+
+  // equalityKeys are the keys equals compares. toEqual ignores properties
+  // that are undefined.
+  function equalityKeys(value, strict) {
+    return Object.keys(value).filter(function (key) {
+      return strict || value[key] !== undefined;
+    });
+  }
+
+  // matchesObject reports whether actual has every property of expected,
+  // recursively.
+  function matchesObject(actual, expected) {
+    if (typeof expected !== "object" || expected === null || typeof actual !== "object" || actual === null) {
+      return equals(actual, expected, false);
+    }
+    if (Array.isArray(expected)) {
+      return (
+        Array.isArray(actual) &&
+        actual.length === expected.length &&
+        expected.every(function (item, i) {
+          return matchesObject(actual[i], item);
+        })
+      );
+    }
+    return Object.keys(expected).every(function (key) {
+      return key in Object(actual) && matchesObject(actual[key], expected[key]);
+    });
+  }
+
+  // --- Assertions ---
+
+  function AssertionError(message) {
+    var err = new Error(message);
+    err.name = "AssertionError";
+    Object.setPrototypeOf(err, AssertionError.prototype);
+    return err;
+  }
+  AssertionError.prototype = Object.create(Error.prototype, {
+    constructor: { value: AssertionError, writable: true, configurable: true },
+    name: { value: "AssertionError", writable: true, configurable: true },
+  });
+
+  function expected(negate, value) {
+    return "Expected: " + (negate ? "not " : "") + serialize(value);
+  }
+
+  function received(value) {
+    return "Received: " + serialize(value);
+  }
+
+  function isMock(fn) {
+    return typeof fn === "function" && fn._isMockFunction === true;
+  }
+
+  function requireMock(name, value) {
+    if (!isMock(value)) {
+      throw AssertionError(name + "() expects a mock function, made by mock.fn or mock.spyOn\n\n" + received(value));
+    }
+  }
+
+  function callList(fn) {
+    if (fn.mock.calls.length === 0) {
+      return "It was not called.";
+    }
+    return "Calls:\n" + fn.mock.calls
+      .map(function (args, i) {
+        return "  " + (i + 1) + ": " + args.map(function (a) { return serialize(a); }).join(", ");
+      })
+      .join("\n");
+  }
+
+  function thrownBy(fn) {
+    if (typeof fn !== "function") {
+      throw AssertionError("toThrow() expects a function\n\n" + received(fn));
+    }
+    try {
+      fn();
+    } catch (e) {
+      return { thrown: true, value: e };
+    }
+    return { thrown: false };
+  }
+
+  // throwMatches checks a thrown value against the argument of toThrow: a
+  // substring or pattern of the message, an error class, or an error.
+  function throwMatches(value, want) {
+    if (want === undefined) {
+      return true;
+    }
+    var message = value instanceof Error ? value.message : String(value);
+    if (typeof want === "string") {
+      return message.indexOf(want) >= 0;
+    }
+    if (want instanceof RegExp) {
+      return want.test(message);
+    }
+    if (typeof want === "function") {
+      return value instanceof want;
+    }
+    if (want instanceof Error) {
+      return message === want.message;
+    }
+    return equals(value, want, false);
+  }
+
+  var matchers = {
+    toBe: function (actual, want) {
+      return { pass: Object.is(actual, want), message: [expected(this.negate, want), received(actual)] };
+    },
+    toEqual: function (actual, want) {
+      return { pass: equals(actual, want, false), message: [expected(this.negate, want), received(actual)] };
+    },
+    toStrictEqual: function (actual, want) {
+      return { pass: equals(actual, want, true), message: [expected(this.negate, want), received(actual)] };
+    },
+    toMatchObject: function (actual, want) {
+      return { pass: matchesObject(actual, want), message: [expected(this.negate, want), received(actual)] };
+    },
+    toBeTruthy: function (actual) {
+      return { pass: !!actual, args: "", message: [received(actual)] };
+    },
+    toBeFalsy: function (actual) {
+      return { pass: !actual, args: "", message: [received(actual)] };
+    },
+    toBeNull: function (actual) {
+      return { pass: actual === null, args: "", message: [received(actual)] };
+    },
+    toBeUndefined: function (actual) {
+      return { pass: actual === undefined, args: "", message: [received(actual)] };
+    },
+    toBeDefined: function (actual) {
+      return { pass: actual !== undefined, args: "", message: [received(actual)] };
+    },
+    toBeNaN: function (actual) {
+      return { pass: typeof actual === "number" && isNaN(actual), args: "", message: [received(actual)] };
+    },
+    toBeGreaterThan: function (actual, want) {
+      return { pass: actual > want, message: [expected(this.negate, want), received(actual)] };
+    },
+    toBeGreaterThanOrEqual: function (actual, want) {
+      return { pass: actual >= want, message: [expected(this.negate, want), received(actual)] };
+    },
+    toBeLessThan: function (actual, want) {
+      return { pass: actual < want, message: [expected(this.negate, want), received(actual)] };
+    },
+    toBeLessThanOrEqual: function (actual, want) {
+      return { pass: actual <= want, message: [expected(this.negate, want), received(actual)] };
+    },
+    toBeCloseTo: function (actual, want, digits) {
+      digits = digits === undefined ? 2 : digits;
+      var pass = Math.abs(want - actual) < Math.pow(10, -digits) / 2;
+      return { pass: pass, message: [expected(this.negate, want), received(actual), "Precision: " + digits + " digits"] };
+    },
+    toBeInstanceOf: function (actual, want) {
+      return { pass: actual instanceof want, args: (want && want.name) || "expected", message: [expected(this.negate, want), received(actual)] };
+    },
+    toContain: function (actual, item) {
+      var pass = typeof actual === "string" ? actual.indexOf(item) >= 0 : actual != null && Array.from(actual).some(function (v) { return Object.is(v, item); });
+      return { pass: pass, args: "item", message: ["Item: " + serialize(item), received(actual)] };
+    },
+    toContainEqual: function (actual, item) {
+      var pass = actual != null && Array.from(actual).some(function (v) { return equals(v, item, false); });
+      return { pass: pass, args: "item", message: ["Item: " + serialize(item), received(actual)] };
+    },
+    toHaveLength: function (actual, length) {
+      var has = actual != null && typeof actual.length === "number";
+      return { pass: has && actual.length === length, args: "length", message: [expected(this.negate, length), "Received length: " + (has ? actual.length : serialize(undefined))] };
+    },
+    toHaveProperty: function (actual, path, value) {
+      var keys = Array.isArray(path) ? path : String(path).split(".");
+      var target = actual;
+      var has = true;
+      for (var i = 0; i < keys.length; i++) {
+        if (target == null || !(keys[i] in Object(target))) {
+          has = false;
+          break;
+        }
+        target = target[keys[i]];
+      }
+      var pass = has && (arguments.length < 3 || equals(target, value, false));
+      var message = ["Path: " + serialize(path)];
+      if (arguments.length >= 3) {
+        message.push(expected(this.negate, value));
+      }
+      message.push(has ? "Received value: " + serialize(target) : received(actual));
+      return { pass: pass, args: arguments.length >= 3 ? "path, value" : "path", message: message };
+    },
+    toMatch: function (actual, pattern) {
+      var pass = typeof actual === "string" && (typeof pattern === "string" ? actual.indexOf(pattern) >= 0 : pattern.test(actual));
+      return { pass: pass, args: "pattern", message: ["Pattern: " + serialize(pattern), received(actual)] };
+    },
+    toThrow: function (actual, want) {
+      var result = this.thrown || thrownBy(actual);
+      var pass = result.thrown && throwMatches(result.value, want);
+      var message = [];
+      if (want !== undefined) {
+        message.push(expected(this.negate, want));
+      }
+      message.push(result.thrown ? "Thrown: " + serialize(result.value) : "Received function did not throw");
+      return { pass: pass, args: want === undefined ? "" : "expected", message: message };
+    },
+    toHaveBeenCalled: function (actual) {
+      requireMock("toHaveBeenCalled", actual);
+      return { pass: actual.mock.calls.length > 0, args: "", message: [callList(actual)] };
+    },
+    toHaveBeenCalledTimes: function (actual, times) {
+      requireMock("toHaveBeenCalledTimes", actual);
+      return { pass: actual.mock.calls.length === times, args: "times", message: ["Expected calls: " + (this.negate ? "not " : "") + times, "Received calls: " + actual.mock.calls.length] };
+    },
+    toHaveBeenCalledWith: function (actual) {
+      requireMock("toHaveBeenCalledWith", actual);
+      var args = Array.prototype.slice.call(arguments, 1);
+      var pass = actual.mock.calls.some(function (call) { return equals(call, args, false); });
+      return { pass: pass, args: "...expected", message: ["Expected: " + (this.negate ? "not " : "") + args.map(function (a) { return serialize(a); }).join(", "), callList(actual)] };
+    },
+    toHaveBeenLastCalledWith: function (actual) {
+      requireMock("toHaveBeenLastCalledWith", actual);
+      var args = Array.prototype.slice.call(arguments, 1);
+      var calls = actual.mock.calls;
+      var pass = calls.length > 0 && equals(calls[calls.length - 1], args, false);
+      return { pass: pass, args: "...expected", message: ["Expected: " + (this.negate ? "not " : "") + args.map(function (a) { return serialize(a); }).join(", "), callList(actual)] };
+    },
+    toMatchSnapshot: function (actual, name) {
+      if (this.negate) {
+        throw AssertionError("toMatchSnapshot() cannot be used with .not");
+      }
+      if (!currentTest) {
+        throw AssertionError("toMatchSnapshot() can only be used inside a test");
+      }
+      var count = ++snapshotCounts[currentTest.id];
+      var key = fullName(currentTest) + (name ? ": " + name : "") + " " + count;
+      var result = host.snapshot(key, serialize(actual));
+      return { pass: result.pass, args: name === undefined ? "" : "name", message: [result.message] };
+    },
+  };
+
+  var snapshotCounts = {};
+
+  function makeExpectation(actual, negate, mode) {
+    var expectation = {};
+    Object.keys(matchers).forEach(function (name) {
+      var matcher = matchers[name];
+      expectation[name] = function () {
+        var args = Array.prototype.slice.call(arguments);
+        var check = function (value, thrown) {
+          var ctx = { negate: negate, thrown: thrown };
+          var result = matcher.apply(ctx, [value].concat(args));
+          if (result.pass === negate) {
+            var header = (mode ? "expect(received)." + mode : "expect(received)") + (negate ? ".not" : "") + "." + name + "(" + (result.args !== undefined ? result.args : "expected") + ")";
+            throw AssertionError(header + "\n\n" + result.message.join("\n"));
+          }
+        };
+        if (mode === "resolves") {
+          return Promise.resolve(actual).then(
+            function (value) { check(value); },
+            function (err) { throw AssertionError("expect(received).resolves." + name + "()\n\nReceived promise rejected instead of resolved\nRejected to value: " + serialize(err)); }
+          );
+        }
+        if (mode === "rejects") {
+          return Promise.resolve(actual).then(
+            function (value) { throw AssertionError("expect(received).rejects." + name + "()\n\nReceived promise resolved instead of rejected\nResolved to value: " + serialize(value)); },
+            function (err) { check(err, name === "toThrow" ? { thrown: true, value: err } : undefined); }
+          );
+        }
+        check(actual);
+      };
+    });
+    return expectation;
+  }
+
+  function expect(actual) {
+    var expectation = makeExpectation(actual, false, "");
+    expectation.not = makeExpectation(actual, true, "");
+    expectation.resolves = makeExpectation(actual, false, "resolves");
+    expectation.resolves.not = makeExpectation(actual, true, "resolves");
+    expectation.rejects = makeExpectation(actual, false, "rejects");
+    expectation.rejects.not = makeExpectation(actual, true, "rejects");
+    return expectation;
+  }
+
+  // --- Mocks ---
+
+  // mockScopes holds the replaced members to restore. The first scope lasts
+  // for the file; run pushes one for each test.
+  var mockScopes = [[]];
+
+  function fn(impl) {
+    var once = [];
+    var state = { calls: [], results: [], instances: [] };
+    var mockFn = function () {
+      var args = Array.prototype.slice.call(arguments);
+      state.calls.push(args);
+      state.instances.push(this);
+      var f = once.length > 0 ? once.shift() : mockFn._impl;
+      try {
+        var value = f ? f.apply(this, args) : undefined;
+        state.results.push({ type: "return", value: value });
+        return value;
+      } catch (e) {
+        state.results.push({ type: "throw", value: e });
+        throw e;
+      }
+    };
+    mockFn._isMockFunction = true;
+    mockFn._impl = impl;
+    mockFn.mock = state;
+    mockFn.mockImplementation = function (f) {
+      mockFn._impl = f;
+      return mockFn;
+    };
+    mockFn.mockImplementationOnce = function (f) {
+      once.push(f);
+      return mockFn;
+    };
+    mockFn.mockReturnValue = function (value) {
+      return mockFn.mockImplementation(function () { return value; });
+    };
+    mockFn.mockReturnValueOnce = function (value) {
+      return mockFn.mockImplementationOnce(function () { return value; });
+    };
+    mockFn.mockResolvedValue = function (value) {
+      return mockFn.mockImplementation(function () { return Promise.resolve(value); });
+    };
+    mockFn.mockRejectedValue = function (value) {
+      return mockFn.mockImplementation(function () { return Promise.reject(value); });
+    };
+    mockFn.mockClear = function () {
+      state.calls.length = 0;
+      state.results.length = 0;
+      state.instances.length = 0;
+      return mockFn;
+    };
+    mockFn.mockReset = function () {
+      mockFn.mockClear();
+      once.length = 0;
+      mockFn._impl = undefined;
+      return mockFn;
+    };
+    return mockFn;
+  }
+
+  function replace(target, key, value) {
+    var scope = mockScopes[mockScopes.length - 1];
+    scope.push({ target: target, key: key, had: Object.prototype.hasOwnProperty.call(target, key), value: target[key] });
+    target[key] = value;
+    if (target[key] !== value) {
+      throw new TypeError("cannot mock " + String(key) + ": the property is read-only");
+    }
+  }
+
+  function restoreMocks(scope) {
+    for (var i = scope.length - 1; i >= 0; i--) {
+      var entry = scope[i];
+      if (entry.had) {
+        entry.target[entry.key] = entry.value;
+      } else {
+        delete entry.target[entry.key];
+      }
+    }
+    scope.length = 0;
+  }
+
+  // mock replaces members of a module, named as for require, or of an
+  // object. Functions become mock functions that call them. Members are
+  // restored after the test that replaced them, or after the file when
+  // replaced outside of tests.
+  function mock(target, members) {
+    if (typeof target === "string") {
+      target = global.require(target);
+    }
+    if (target === null || (typeof target !== "object" && typeof target !== "function")) {
+      throw new TypeError("mock() expects a module name or an object");
+    }
+    Object.keys(members || {}).forEach(function (key) {
+      var value = members[key];
+      if (typeof value === "function" && !isMock(value)) {
+        value = fn(value);
+      }
+      replace(target, key, value);
+    });
+    return target;
+  }
+
+  mock.fn = fn;
+  mock.spyOn = function (target, key) {
+    if (typeof target === "string") {
+      target = global.require(target);
+    }
+    var original = target[key];
+    if (typeof original !== "function") {
+      throw new TypeError("mock.spyOn() expects " + String(key) + " to be a function");
+    }
+    var spy = fn(function () {
+      return original.apply(this, arguments);
+    });
+    replace(target, key, spy);
+    return spy;
+  };
+  mock.restoreAll = function () {
+    for (var i = mockScopes.length - 1; i >= 0; i--) {
+      restoreMocks(mockScopes[i]);
+    }
+  };
+
+  global.describe = describe;
+  global.it = it;
+  global.test = it;
+  global.beforeAll = defineHook("beforeAll");
+  global.afterAll = defineHook("afterAll");
+  global.beforeEach = defineHook("beforeEach");
+  global.afterEach = defineHook("afterEach");
+  global.expect = expect;
+  global.mock = mock;
+
+  return { plan: plan, run: run };
+});
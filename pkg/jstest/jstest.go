@@ -0,0 +1,435 @@
+// Package jstest runs JavaScript and TypeScript test files in go-go-goja
+// runtimes.
+//
+// Each file runs in a fresh runtime from the caller's factory, so tests see
+// the same native modules as production code. A harness installs the
+// globals test files use: describe, it (or test) with .skip, .only and
+// .todo, the beforeAll, afterAll, beforeEach and afterEach hooks, expect
+// with Jest's common matchers, .not, .resolves and .rejects, and mock,
+// which replaces members of native modules and objects for the rest of a
+// test:
+//
+//	const fs = require("fs");
+//	const { load } = require("./config");
+//
+//	describe("load", () => {
+//	  it("parses the file", async () => {
+//	    mock("fs", { readFileSync: () => "port: 80" });
+//	    expect(load("app.yaml")).toMatchSnapshot();
+//	    expect(fs.readFileSync).toHaveBeenCalledWith("app.yaml", "utf8");
+//	  });
+//	});
+//
+// Tests may return promises or take a done callback. Snapshots are stored
+// in a __snapshots__ directory next to each test file. Run returns a Report
+// that can be written as text, TAP or JUnit XML.
+package jstest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/go-go-golems/go-go-goja/pkg/engine"
+	"github.com/go-go-golems/go-go-goja/pkg/jserrors"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimeowner"
+	"github.com/go-go-golems/go-go-goja/pkg/tsscript"
+	"github.com/pkg/errors"
+)
+
+// DefaultTimeout is how long a test may run when Options.Timeout is zero.
+const DefaultTimeout = 5 * time.Second
+
+// testSuffixes are the file names Discover collects.
+var testSuffixes = []string{".test.js", ".test.mjs", ".test.cjs", ".test.ts", ".test.mts", ".test.tsx"}
+
+// Options configure a test run.
+type Options struct {
+	// NewRuntime creates the runtime a test file runs in. It is called once
+	// per file with the absolute path of the file, and the runtime is closed
+	// when the file is done. Factories usually add the directory of the file
+	// to the module roots, see engine.RequireOptionWithModuleRootsFromScript.
+	NewRuntime func(ctx context.Context, file string) (*engine.Runtime, error)
+	// External are the modules TypeScript test files import from the
+	// runtime instead of bundling them, such as the names of native
+	// modules.
+	External []string
+	// Instrument, when set, rewrites the bundles of TypeScript test files
+	// before they run, as jscover.Collector.Instrument does. Modules the
+	// tests require are loaded by the runtime's own source loader.
+	Instrument func(file string, code []byte) []byte
+	// Filter selects the tests to run by their full name, the names of
+	// their suites and their own joined by spaces. Nil runs all tests.
+	Filter *regexp.Regexp
+	// Timeout bounds each test, its hooks included, and the loading of
+	// each file. A test can pass its own timeout as the third argument of
+	// it.
+	Timeout time.Duration
+	// UpdateSnapshots overwrites snapshots that do not match and removes
+	// the snapshots no test of a fully run file uses.
+	UpdateSnapshots bool
+	// CI fails tests whose snapshots do not exist yet instead of writing
+	// them.
+	CI bool
+}
+
+func (o Options) timeout() time.Duration {
+	if o.Timeout > 0 {
+		return o.Timeout
+	}
+	return DefaultTimeout
+}
+
+// IsTestFile reports whether path is named like a test file.
+func IsTestFile(path string) bool {
+	name := filepath.Base(path)
+	for _, suffix := range testSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Discover returns the test files under paths, sorted. Files named in paths
+// are returned as they are; directories are walked for test files, skipping
+// node_modules and hidden directories.
+func Discover(paths []string) ([]string, error) {
+	seen := map[string]struct{}{}
+	var files []string
+	add := func(path string) error {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return errors.Wrapf(err, "resolve %s", path)
+		}
+		if _, ok := seen[abs]; !ok {
+			seen[abs] = struct{}{}
+			files = append(files, abs)
+		}
+		return nil
+	}
+	for _, root := range paths {
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, errors.Wrap(err, "find tests")
+		}
+		if !info.IsDir() {
+			if err := add(root); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		err = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				name := d.Name()
+				if path != root && (name == "node_modules" || strings.HasPrefix(name, ".")) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !IsTestFile(path) {
+				return nil
+			}
+			return add(path)
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "find tests in %s", root)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Run runs files one after the other, each in a fresh runtime.
+func Run(ctx context.Context, files []string, opts Options) *Report {
+	report := &Report{}
+	for _, file := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		report.Files = append(report.Files, RunFile(ctx, file, opts))
+	}
+	return report
+}
+
+// planned is a test as the harness lists it.
+type planned struct {
+	ID      int      `json:"id"`
+	Suites  []string `json:"suites"`
+	Name    string   `json:"name"`
+	Status  string   `json:"status"`
+	Timeout int      `json:"timeout"`
+}
+
+// harnessAPI are the functions the harness returns.
+type harnessAPI struct {
+	plan goja.Callable
+	run  goja.Callable
+}
+
+// RunFile runs the tests of one file in a fresh runtime.
+func RunFile(ctx context.Context, file string, opts Options) *FileResult {
+	start := time.Now()
+	result := &FileResult{Path: file}
+	defer func() { result.Duration = time.Since(start) }()
+
+	if opts.NewRuntime == nil {
+		result.Err = errors.New("jstest: Options.NewRuntime is required")
+		return result
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		result.Err = errors.Wrapf(err, "resolve %s", file)
+		return result
+	}
+	result.Path = abs
+
+	snapshots, err := loadSnapshots(abs, opts)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	rt, err := opts.NewRuntime(ctx, abs)
+	if err != nil {
+		result.Err = errors.Wrap(err, "create runtime")
+		return result
+	}
+	defer func() { _ = rt.Close(ctx) }()
+
+	harness, err := installHarness(ctx, rt, opts, snapshots)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if err := loadTestFile(ctx, rt, abs, opts); err != nil {
+		result.Err = err
+		return result
+	}
+	tests, err := planTests(ctx, rt, harness)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	complete := true
+	for _, test := range tests {
+		tr := &TestResult{Suites: test.Suites, Name: test.Name}
+		switch test.Status {
+		case "todo":
+			tr.Status = StatusTodo
+		case "skip":
+			tr.Status = StatusSkipped
+			complete = false
+		default:
+			timeout := opts.timeout()
+			if test.Timeout > 0 {
+				timeout = time.Duration(test.Timeout) * time.Millisecond
+			}
+			testStart := time.Now()
+			tr.Failure = runTest(ctx, rt, harness, test.ID, timeout)
+			tr.Duration = time.Since(testStart)
+			tr.Status = StatusPassed
+			if tr.Failure != nil {
+				tr.Status = StatusFailed
+			}
+		}
+		result.Tests = append(result.Tests, tr)
+	}
+
+	if err := snapshots.save(complete && opts.Filter == nil); err != nil {
+		result.Err = err
+	}
+	result.Snapshots = snapshots.stats
+	return result
+}
+
+// installHarness evaluates the harness in rt and connects it to the
+// snapshots and the filter of the run.
+func installHarness(ctx context.Context, rt *engine.Runtime, opts Options, snapshots *snapshotFile) (*harnessAPI, error) {
+	ret, err := rt.Owner.Call(ctx, "jstest.harness", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		value, err := vm.RunScript(harnessName, harnessSource)
+		if err != nil {
+			return nil, err
+		}
+		install, ok := goja.AssertFunction(value)
+		if !ok {
+			return nil, errors.New("harness did not evaluate to a function")
+		}
+		host := vm.NewObject()
+		if err := host.Set("match", func(name string) bool {
+			return opts.Filter == nil || opts.Filter.MatchString(name)
+		}); err != nil {
+			return nil, err
+		}
+		if err := host.Set("snapshot", func(key, received string) map[string]any {
+			pass, message := snapshots.check(key, received)
+			return map[string]any{"pass": pass, "message": message}
+		}); err != nil {
+			return nil, err
+		}
+		api, err := install(goja.Undefined(), host)
+		if err != nil {
+			return nil, err
+		}
+		obj := api.ToObject(vm)
+		plan, ok1 := goja.AssertFunction(obj.Get("plan"))
+		run, ok2 := goja.AssertFunction(obj.Get("run"))
+		if !ok1 || !ok2 {
+			return nil, errors.New("harness returned no plan or run function")
+		}
+		return &harnessAPI{plan: plan, run: run}, nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "install test harness")
+	}
+	return ret.(*harnessAPI), nil
+}
+
+// loadTestFile runs the top level of the test file, which declares its
+// tests. TypeScript files are bundled first.
+func loadTestFile(ctx context.Context, rt *engine.Runtime, file string, opts Options) error {
+	loadCtx, cancel := context.WithTimeout(ctx, opts.timeout())
+	defer cancel()
+	loadCtx = runtimeowner.WithBudget(loadCtx, runtimeowner.Budget{MaxExecutionTime: opts.timeout()})
+
+	if !tsscript.IsTypeScriptPath(file) {
+		// ImportModule runs CommonJS and ES module test files alike and waits
+		// for top-level await to settle.
+		if _, err := rt.ImportModule(loadCtx, file); err != nil {
+			return fmt.Errorf("load %s: %w", file, jserrors.Wrap(err))
+		}
+		return nil
+	}
+	artifact, err := tsscript.BundleEntry(file, tsscript.Options{
+		Target:    api.ES2015,
+		Format:    api.FormatIIFE,
+		Platform:  api.PlatformNeutral,
+		External:  append(append([]string(nil), opts.External...), "node:*"),
+		Sourcemap: api.SourceMapInline,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "compile TypeScript %s", file)
+	}
+	code := jserrors.Retain(file, artifact.Code)
+	if opts.Instrument != nil {
+		code = opts.Instrument(file, code)
+	}
+	_, err = rt.Owner.Call(loadCtx, "jstest.load.typescript", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		return vm.RunScript(file, string(code))
+	})
+	if err != nil {
+		return fmt.Errorf("load %s: %w", file, jserrors.Wrap(err))
+	}
+	return nil
+}
+
+// planTests ends the collection of tests and lists them.
+func planTests(ctx context.Context, rt *engine.Runtime, harness *harnessAPI) ([]planned, error) {
+	ret, err := rt.Owner.Call(ctx, "jstest.plan", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		value, err := harness.plan(goja.Undefined())
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(value.Export())
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "plan tests")
+	}
+	var tests []planned
+	if err := json.Unmarshal(ret.([]byte), &tests); err != nil {
+		return nil, errors.Wrap(err, "decode test plan")
+	}
+	return tests, nil
+}
+
+// runTest runs one test with its hooks and returns its failure, or nil.
+func runTest(ctx context.Context, rt *engine.Runtime, harness *harnessAPI, id int, timeout time.Duration) *jserrors.Exception {
+	testCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	budgetCtx := runtimeowner.WithBudget(testCtx, runtimeowner.Budget{MaxExecutionTime: timeout})
+
+	ret, err := rt.Owner.Call(budgetCtx, "jstest.run", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		value, err := harness.run(goja.Undefined(), vm.ToValue(id))
+		if err != nil {
+			return nil, err
+		}
+		promise, ok := value.Export().(*goja.Promise)
+		if !ok {
+			return nil, errors.New("harness run did not return a promise")
+		}
+		return promise, nil
+	})
+	if err != nil {
+		return failure(testCtx, err, timeout)
+	}
+	settlement, err := runtimeowner.AwaitPromise(testCtx, rt.Owner, "jstest.await", ret.(*goja.Promise))
+	if err != nil {
+		return failure(testCtx, err, timeout)
+	}
+	if settlement.Rejected() {
+		return failureFromValue(ctx, rt, settlement.Value)
+	}
+	ret, err = rt.Owner.Call(ctx, "jstest.result", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		obj, ok := settlement.Value.(*goja.Object)
+		if !ok {
+			return nil, nil
+		}
+		thrown := obj.Get("error")
+		if thrown == nil || goja.IsUndefined(thrown) {
+			return nil, nil
+		}
+		return withoutHarnessFrames(jserrors.FromValue(thrown)), nil
+	})
+	if err != nil {
+		return &jserrors.Exception{Message: err.Error()}
+	}
+	x, _ := ret.(*jserrors.Exception)
+	return x
+}
+
+// failure reports an error of the runtime owner, such as a timeout.
+func failure(testCtx context.Context, err error, timeout time.Duration) *jserrors.Exception {
+	if errors.Is(testCtx.Err(), context.DeadlineExceeded) || errors.Is(err, runtimeowner.ErrBudgetExceeded) {
+		return &jserrors.Exception{Message: fmt.Sprintf("test timed out after %s", timeout)}
+	}
+	if x := jserrors.Find(err); x != nil {
+		return withoutHarnessFrames(x)
+	}
+	return &jserrors.Exception{Message: err.Error()}
+}
+
+func failureFromValue(ctx context.Context, rt *engine.Runtime, value goja.Value) *jserrors.Exception {
+	ret, err := rt.Owner.Call(ctx, "jstest.failure", func(_ context.Context, _ *goja.Runtime) (any, error) {
+		return withoutHarnessFrames(jserrors.FromValue(value)), nil
+	})
+	if err != nil {
+		return &jserrors.Exception{Message: err.Error()}
+	}
+	return ret.(*jserrors.Exception)
+}
+
+// withoutHarnessFrames drops the frames of the harness from x, which
+// assertion errors are created in.
+func withoutHarnessFrames(x *jserrors.Exception) *jserrors.Exception {
+	frames := x.Frames[:0:0]
+	for _, f := range x.Frames {
+		if f.File == harnessName {
+			continue
+		}
+		frames = append(frames, f)
+	}
+	x.Frames = frames
+	return x
+}
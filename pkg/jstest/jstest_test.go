@@ -0,0 +1,293 @@
+package jstest_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-go-golems/go-go-goja/pkg/engine"
+	"github.com/go-go-golems/go-go-goja/pkg/jstest"
+	"github.com/stretchr/testify/require"
+)
+
+func newRuntime(ctx context.Context, file string) (*engine.Runtime, error) {
+	builder := engine.NewRuntimeFactoryBuilder()
+	requireOpt, err := engine.RequireOptionWithModuleRootsFromScript(file, engine.DefaultModuleRootsOptions())
+	if err != nil {
+		return nil, err
+	}
+	if requireOpt != nil {
+		builder = builder.WithRequireOptions(requireOpt)
+	}
+	factory, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+	return factory.NewRuntime(engine.WithStartupContext(ctx), engine.WithLifetimeContext(ctx))
+}
+
+func writeFile(t *testing.T, dir, name, text string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(text), 0o600))
+	return path
+}
+
+func runFile(t *testing.T, file string, opts jstest.Options) *jstest.FileResult {
+	t.Helper()
+	opts.NewRuntime = newRuntime
+	return jstest.RunFile(context.Background(), file, opts)
+}
+
+func statuses(f *jstest.FileResult) map[string]jstest.Status {
+	out := map[string]jstest.Status{}
+	for _, t := range f.Tests {
+		out[t.FullName()] = t.Status
+	}
+	return out
+}
+
+func TestRunFileRunsSuitesHooksAndAsyncTests(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, "math.test.js", `
+const events = [];
+let files = 0;
+beforeAll(() => { files++; });
+
+describe("math", () => {
+  beforeAll(() => events.push("beforeAll"));
+  beforeEach(() => events.push("beforeEach"));
+  afterEach(() => events.push("afterEach"));
+  afterAll(() => events.push("afterAll"));
+
+  it("adds", () => {
+    expect(1 + 1).toBe(2);
+    expect({ a: [1, { b: 2 }] }).toEqual({ a: [1, { b: 2 }] });
+  });
+
+  it("awaits", async () => {
+    const value = await Promise.resolve(3);
+    expect(value).toBeGreaterThan(2);
+    await expect(Promise.reject(new TypeError("nope"))).rejects.toThrow(TypeError);
+  });
+
+  it("calls back", (done) => {
+    Promise.resolve().then(() => done());
+  });
+
+  it("fails", () => {
+    expect([1, 2]).toContain(3);
+  });
+
+  it.skip("is skipped", () => {});
+  it.todo("is planned");
+});
+
+test("saw the hooks in order", () => {
+  expect(events).toEqual([
+    "beforeAll",
+    "beforeEach", "afterEach",
+    "beforeEach", "afterEach",
+    "beforeEach", "afterEach",
+    "beforeEach", "afterEach",
+    "afterAll",
+  ]);
+  expect(files).toBe(1);
+});
+`)
+	result := runFile(t, file, jstest.Options{})
+	require.NoError(t, result.Err)
+	require.Equal(t, map[string]jstest.Status{
+		"math adds":              jstest.StatusPassed,
+		"math awaits":            jstest.StatusPassed,
+		"math calls back":        jstest.StatusPassed,
+		"math fails":             jstest.StatusFailed,
+		"math is skipped":        jstest.StatusSkipped,
+		"math is planned":        jstest.StatusTodo,
+		"saw the hooks in order": jstest.StatusPassed,
+	}, statuses(result))
+
+	failure := result.Tests[3].Failure
+	require.NotNil(t, failure)
+	require.Equal(t, "AssertionError: expect(received).toContain(item)\n\nItem: 3\nReceived: [\n  1,\n  2,\n]", failure.Message)
+	require.Equal(t, file, failure.Frames[0].File)
+	require.Equal(t, 28, failure.Frames[0].Line)
+	require.Contains(t, failure.CodeFrame, "> 28 |     expect([1, 2]).toContain(3);")
+}
+
+func TestRunFileSelectsOnlyAndFilteredTests(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, "only.test.js", `
+describe("a", () => {
+  it.only("one", () => {});
+  it("two", () => {});
+});
+describe.only("b", () => {
+  it("three", () => {});
+  it("four", () => {});
+});
+`)
+	result := runFile(t, file, jstest.Options{Filter: regexp.MustCompile("^b th")})
+	require.NoError(t, result.Err)
+	require.Equal(t, map[string]jstest.Status{
+		"a one":   jstest.StatusSkipped,
+		"a two":   jstest.StatusSkipped,
+		"b three": jstest.StatusPassed,
+		"b four":  jstest.StatusSkipped,
+	}, statuses(result))
+}
+
+func TestRunFileTimesOutTests(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, "slow.test.js", `
+it("never settles", () => new Promise(() => {}));
+it("loops", () => { for (;;) {} });
+it("still runs", () => {});
+`)
+	result := runFile(t, file, jstest.Options{Timeout: 100 * time.Millisecond})
+	require.NoError(t, result.Err)
+	require.Equal(t, jstest.StatusFailed, result.Tests[0].Status)
+	require.Equal(t, "test timed out after 100ms", result.Tests[0].Failure.Message)
+	require.Equal(t, jstest.StatusFailed, result.Tests[1].Status)
+	require.Equal(t, "test timed out after 100ms", result.Tests[1].Failure.Message)
+	require.Equal(t, jstest.StatusPassed, result.Tests[2].Status)
+}
+
+func TestRunFileMocksNativeModules(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "config.js", `
+const fs = require("fs");
+exports.load = (path) => fs.readFileSync(path, "utf8").trim();
+`)
+	file := writeFile(t, dir, "config.test.js", `
+const fs = require("fs");
+const path = require("path");
+const { load } = require("./config");
+const original = fs.readFileSync;
+
+mock(path, { sep: "|" });
+
+describe("load", () => {
+  it("reads the mocked file", () => {
+    mock("fs", { readFileSync: () => " port: 80 " });
+    expect(load("app.yaml")).toBe("port: 80");
+    expect(fs.readFileSync).toHaveBeenCalledWith("app.yaml", "utf8");
+    expect(fs.readFileSync).toHaveBeenCalledTimes(1);
+  });
+
+  it("restores mocks after each test", () => {
+    expect(fs.readFileSync).toBe(original);
+    expect(path.sep).toBe("|");
+  });
+
+  it("spies", () => {
+    const spy = mock.spyOn(Math, "max");
+    expect(Math.max(1, 3)).toBe(3);
+    expect(spy).toHaveBeenLastCalledWith(1, 3);
+    const fn = mock.fn().mockReturnValueOnce(1).mockReturnValue(2);
+    expect([fn(), fn()]).toEqual([1, 2]);
+    expect(fn.mock.calls).toHaveLength(2);
+  });
+});
+`)
+	result := runFile(t, file, jstest.Options{})
+	require.NoError(t, result.Err)
+	for _, test := range result.Tests {
+		require.Equal(t, jstest.StatusPassed, test.Status, "%s: %v", test.FullName(), test.Failure)
+	}
+}
+
+func TestRunFileRunsTypeScript(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "greet.ts", "export function greet(name: string): string {\n  return `hello ${name}`;\n}\n")
+	file := writeFile(t, dir, "greet.test.ts", `import { greet } from "./greet";
+import * as path from "path";
+
+it("greets", () => {
+  const name: string = path.basename("/x/world");
+  expect(greet(name)).toBe("hello world");
+});
+
+it("fails in TypeScript", () => {
+  const n: number = 1;
+  expect(greet("x")).toHaveLength(n);
+});
+`)
+	result := runFile(t, file, jstest.Options{External: []string{"path"}})
+	require.NoError(t, result.Err)
+	require.Equal(t, jstest.StatusPassed, result.Tests[0].Status, "%v", result.Tests[0].Failure)
+	require.Equal(t, jstest.StatusFailed, result.Tests[1].Status)
+	require.Equal(t, file, result.Tests[1].Failure.Frames[0].File)
+	require.Equal(t, 11, result.Tests[1].Failure.Frames[0].Line)
+}
+
+func TestRunFileReportsLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, "broken.test.js", "it(\"a\", () => {});\nthrow new Error(\"cannot load\");\n")
+	result := runFile(t, file, jstest.Options{})
+	require.Error(t, result.Err)
+	require.Contains(t, result.Err.Error(), "Error: cannot load")
+	require.True(t, result.Failed())
+	require.Empty(t, result.Tests)
+}
+
+func TestDiscoverFindsTestFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "b.test.ts", "")
+	writeFile(t, dir, "a.test.js", "")
+	writeFile(t, dir, "lib.js", "")
+	writeFile(t, dir, "sub/c.test.mjs", "")
+	writeFile(t, dir, "node_modules/x/x.test.js", "")
+	writeFile(t, dir, ".cache/y.test.js", "")
+
+	files, err := jstest.Discover([]string{dir})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "a.test.js"),
+		filepath.Join(dir, "b.test.ts"),
+		filepath.Join(dir, "sub", "c.test.mjs"),
+	}, files)
+
+	files, err = jstest.Discover([]string{filepath.Join(dir, "lib.js"), dir})
+	require.NoError(t, err)
+	require.Len(t, files, 4)
+}
+
+func TestReportsWriteSpecTAPAndJUnit(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, "r.test.js", `
+describe("s", () => {
+  it("passes", () => {});
+  it("fails", () => { expect(1).toBe(2); });
+  it.skip("skips", () => {});
+});
+`)
+	report := jstest.Run(context.Background(), []string{file}, jstest.Options{NewRuntime: newRuntime})
+	require.True(t, report.Failed())
+	require.Equal(t, "1 failed, 1 skipped, 1 passed, 3 total", report.Summary().String())
+
+	var spec bytes.Buffer
+	require.NoError(t, report.WriteSpec(&spec, dir))
+	require.Contains(t, spec.String(), "FAIL r.test.js\n  s\n    ✓ passes")
+	require.Contains(t, spec.String(), "    ✗ fails\n    ○ skipped skips\n")
+	require.Contains(t, spec.String(), "  ● s › fails\n\n    AssertionError: expect(received).toBe(expected)\n\n    Expected: 2\n    Received: 1\n")
+	require.Contains(t, spec.String(), "Tests:      1 failed, 1 skipped, 1 passed, 3 total\n")
+
+	var tap bytes.Buffer
+	require.NoError(t, report.WriteTAP(&tap, dir))
+	require.True(t, strings.HasPrefix(tap.String(), "TAP version 13\nok 1 - r.test.js > s > passes\nnot ok 2 - r.test.js > s > fails\n  ---\n  message: \"AssertionError: expect(received).toBe(expected)\"\n"), tap.String())
+	require.Contains(t, tap.String(), "ok 3 - r.test.js > s > skips # SKIP\n1..3\n")
+
+	var junit bytes.Buffer
+	require.NoError(t, report.WriteJUnit(&junit, dir))
+	require.Contains(t, junit.String(), `<testsuites name="goja-test" tests="3" failures="1" errors="0" skipped="1"`)
+	require.Contains(t, junit.String(), `<testcase name="s › fails" classname="r.test.js"`)
+	require.Contains(t, junit.String(), `<failure message="AssertionError: expect(received).toBe(expected)" type="AssertionError">`)
+	require.Contains(t, junit.String(), "<skipped></skipped>")
+}
@@ -0,0 +1,126 @@
+package jstest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Body    string `xml:",cdata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// WriteJUnit writes the report as JUnit XML, which CI servers such as
+// Jenkins and GitLab read. Each file is a test suite and each test a test
+// case whose class name is the file and whose name is the full name of the
+// test. A file that failed to load is an error.
+func (r *Report) WriteJUnit(w io.Writer, dir string) error {
+	doc := junitTestSuites{Name: "goja-test"}
+	var total time.Duration
+	for _, f := range r.Files {
+		path := displayPath(dir, f.Path)
+		suite := junitTestSuite{Name: path, Time: seconds(f.Duration)}
+		for _, t := range f.Tests {
+			tc := junitTestCase{
+				Name:      strings.Join(t.names(), " › "),
+				Classname: path,
+				Time:      seconds(t.Duration),
+			}
+			switch t.Status {
+			case StatusFailed:
+				suite.Failures++
+				tc.Failure = &junitProblem{Type: "AssertionError"}
+				if t.Failure != nil {
+					tc.Failure.Message = firstLine(t.Failure.Message)
+					tc.Failure.Body = t.Failure.String()
+					if name, _, ok := strings.Cut(tc.Failure.Message, ":"); ok && !strings.Contains(name, " ") {
+						tc.Failure.Type = name
+					}
+				}
+			case StatusSkipped:
+				suite.Skipped++
+				tc.Skipped = &junitSkipped{}
+			case StatusTodo:
+				suite.Skipped++
+				tc.Skipped = &junitSkipped{Message: "todo"}
+			}
+			suite.Tests++
+			suite.Cases = append(suite.Cases, tc)
+		}
+		if f.Err != nil {
+			suite.Errors++
+			suite.Tests++
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      "load " + path,
+				Classname: path,
+				Time:      seconds(0),
+				Error:     &junitProblem{Message: firstLine(f.Err.Error()), Body: f.Err.Error()},
+			})
+		}
+		doc.Tests += suite.Tests
+		doc.Failures += suite.Failures
+		doc.Errors += suite.Errors
+		doc.Skipped += suite.Skipped
+		total += f.Duration
+		doc.Suites = append(doc.Suites, suite)
+	}
+	doc.Time = seconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
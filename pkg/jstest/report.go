@@ -0,0 +1,285 @@
+package jstest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/go-go-goja/pkg/jserrors"
+	"github.com/pkg/errors"
+)
+
+// Formats of Write.
+const (
+	FormatSpec  = "spec"
+	FormatTAP   = "tap"
+	FormatJUnit = "junit"
+)
+
+// Status is the outcome of a test.
+type Status string
+
+const (
+	StatusPassed  Status = "passed"
+	StatusFailed  Status = "failed"
+	StatusSkipped Status = "skipped"
+	StatusTodo    Status = "todo"
+)
+
+// Report is the outcome of a run.
+type Report struct {
+	// Files are in the order they ran.
+	Files []*FileResult
+}
+
+// FileResult is the outcome of one test file.
+type FileResult struct {
+	Path string
+	// Tests are in the order they are declared.
+	Tests    []*TestResult
+	Duration time.Duration
+	// Err is set when the file could not be loaded or its snapshots not be
+	// saved. Tests that ran are still listed.
+	Err       error
+	Snapshots SnapshotStats
+}
+
+// TestResult is the outcome of one test.
+type TestResult struct {
+	// Suites are the names of the describe blocks the test is in,
+	// outermost first.
+	Suites   []string
+	Name     string
+	Status   Status
+	Duration time.Duration
+	// Failure is the error the test or one of its hooks threw, for failed
+	// tests.
+	Failure *jserrors.Exception
+}
+
+// FullName joins the names of the suites and the test with spaces, the name
+// Options.Filter matches.
+func (t *TestResult) FullName() string {
+	return strings.Join(t.names(), " ")
+}
+
+func (t *TestResult) names() []string {
+	return append(append([]string(nil), t.Suites...), t.Name)
+}
+
+// Failed reports whether the file failed to run or any of its tests
+// failed.
+func (f *FileResult) Failed() bool {
+	if f.Err != nil {
+		return true
+	}
+	for _, t := range f.Tests {
+		if t.Status == StatusFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// Summary counts the tests of a report.
+type Summary struct {
+	Files       int
+	FailedFiles int
+	Tests       int
+	Passed      int
+	Failed      int
+	Skipped     int
+	Todo        int
+	Snapshots   SnapshotStats
+	Duration    time.Duration
+}
+
+// Summary counts the files and tests of the report.
+func (r *Report) Summary() Summary {
+	s := Summary{Files: len(r.Files)}
+	for _, f := range r.Files {
+		if f.Failed() {
+			s.FailedFiles++
+		}
+		s.Duration += f.Duration
+		s.Snapshots.Added += f.Snapshots.Added
+		s.Snapshots.Updated += f.Snapshots.Updated
+		s.Snapshots.Removed += f.Snapshots.Removed
+		s.Snapshots.Failed += f.Snapshots.Failed
+		for _, t := range f.Tests {
+			s.Tests++
+			switch t.Status {
+			case StatusPassed:
+				s.Passed++
+			case StatusFailed:
+				s.Failed++
+			case StatusSkipped:
+				s.Skipped++
+			case StatusTodo:
+				s.Todo++
+			}
+		}
+	}
+	return s
+}
+
+// Failed reports whether any file of the report failed.
+func (r *Report) Failed() bool {
+	for _, f := range r.Files {
+		if f.Failed() {
+			return true
+		}
+	}
+	return false
+}
+
+// String formats the counts on one line, such as "1 failed, 12 passed, 13
+// total".
+func (s Summary) String() string {
+	var parts []string
+	for _, c := range []struct {
+		n    int
+		name string
+	}{{s.Failed, "failed"}, {s.Skipped, "skipped"}, {s.Todo, "todo"}, {s.Passed, "passed"}} {
+		if c.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", c.n, c.name))
+		}
+	}
+	parts = append(parts, fmt.Sprintf("%d total", s.Tests))
+	return strings.Join(parts, ", ")
+}
+
+// Write writes the report in format, one of FormatSpec, FormatTAP and
+// FormatJUnit. Paths are shown relative to dir when they are below it.
+func (r *Report) Write(w io.Writer, format, dir string) error {
+	switch format {
+	case FormatSpec, "":
+		return r.WriteSpec(w, dir)
+	case FormatTAP:
+		return r.WriteTAP(w, dir)
+	case FormatJUnit:
+		return r.WriteJUnit(w, dir)
+	default:
+		return errors.Errorf("unknown report format %q (use spec, tap or junit)", format)
+	}
+}
+
+// WriteOutput writes the report in format to the file output, and a spec
+// report to w, or only the report in format to w when output is empty.
+func (r *Report) WriteOutput(w io.Writer, format, output, dir string) error {
+	if output == "" {
+		return r.Write(w, format, dir)
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return errors.Wrap(err, "create report")
+	}
+	if err := r.Write(f, format, dir); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "write report")
+	}
+	return r.WriteSpec(w, dir)
+}
+
+// WriteSpec writes the report for people: each file with its tests and
+// their failures, then the totals. Paths are shown relative to dir when
+// they are below it.
+func (r *Report) WriteSpec(w io.Writer, dir string) error {
+	p := &errWriter{w: w}
+	for _, f := range r.Files {
+		status := "PASS"
+		if f.Failed() {
+			status = "FAIL"
+		}
+		p.printf("%s %s\n", status, displayPath(dir, f.Path))
+		var suites []string
+		for _, t := range f.Tests {
+			common := 0
+			for common < len(suites) && common < len(t.Suites) && suites[common] == t.Suites[common] {
+				common++
+			}
+			for i := common; i < len(t.Suites); i++ {
+				p.printf("%s%s\n", strings.Repeat("  ", i+1), t.Suites[i])
+			}
+			suites = t.Suites
+			indent := strings.Repeat("  ", len(t.Suites)+1)
+			switch t.Status {
+			case StatusPassed:
+				if d := t.Duration.Round(time.Millisecond); d > 0 {
+					p.printf("%s✓ %s (%s)\n", indent, t.Name, d)
+				} else {
+					p.printf("%s✓ %s\n", indent, t.Name)
+				}
+			case StatusFailed:
+				p.printf("%s✗ %s\n", indent, t.Name)
+			case StatusSkipped:
+				p.printf("%s○ skipped %s\n", indent, t.Name)
+			case StatusTodo:
+				p.printf("%s✎ todo %s\n", indent, t.Name)
+			}
+		}
+		for _, t := range f.Tests {
+			if t.Failure == nil {
+				continue
+			}
+			p.printf("\n  ● %s\n\n%s\n", strings.Join(t.names(), " › "), indentLines(t.Failure.String(), "    "))
+		}
+		if f.Err != nil {
+			p.printf("\n  ● %s\n\n%s\n", "Test file failed", indentLines(f.Err.Error(), "    "))
+		}
+		p.printf("\n")
+	}
+	s := r.Summary()
+	files := fmt.Sprintf("%d total", s.Files)
+	if s.FailedFiles > 0 {
+		files = fmt.Sprintf("%d failed, %d passed, %d total", s.FailedFiles, s.Files-s.FailedFiles, s.Files)
+	}
+	p.printf("Test files: %s\n", files)
+	p.printf("Tests:      %s\n", s)
+	if sn := s.Snapshots; sn != (SnapshotStats{}) {
+		p.printf("Snapshots:  %d added, %d updated, %d removed, %d failed\n", sn.Added, sn.Updated, sn.Removed, sn.Failed)
+	}
+	p.printf("Time:       %s\n", s.Duration.Round(time.Millisecond))
+	return p.err
+}
+
+// displayPath returns path relative to dir when it is below it.
+func displayPath(dir, path string) string {
+	if dir == "" {
+		return path
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+func indentLines(s, indent string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = indent + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// errWriter keeps the first error of a series of writes.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (p *errWriter) printf(format string, args ...any) {
+	if p.err != nil {
+		return
+	}
+	_, p.err = fmt.Fprintf(p.w, format, args...)
+}
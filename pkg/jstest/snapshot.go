@@ -0,0 +1,212 @@
+package jstest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/pkg/errors"
+)
+
+// snapshotHeader starts every snapshot file.
+const snapshotHeader = "// goja-test snapshot v1"
+
+// SnapshotStats counts the changes a run made to the snapshots of a file.
+type SnapshotStats struct {
+	Added   int
+	Updated int
+	Removed int
+	// Failed counts snapshots that did not match, or that were missing in
+	// CI mode.
+	Failed int
+}
+
+// SnapshotPath returns the file the snapshots of a test file are stored in:
+// __snapshots__/<name>.snap next to it.
+func SnapshotPath(testFile string) string {
+	return filepath.Join(filepath.Dir(testFile), "__snapshots__", filepath.Base(testFile)+".snap")
+}
+
+// snapshotFile holds the snapshots of one test file while it runs.
+type snapshotFile struct {
+	path    string
+	entries map[string]string
+	used    map[string]bool
+	dirty   bool
+	update  bool
+	ci      bool
+	stats   SnapshotStats
+}
+
+func loadSnapshots(testFile string, opts Options) (*snapshotFile, error) {
+	s := &snapshotFile{
+		path:    SnapshotPath(testFile),
+		entries: map[string]string{},
+		used:    map[string]bool{},
+		update:  opts.UpdateSnapshots,
+		ci:      opts.CI,
+	}
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "read snapshots")
+	}
+	entries, err := parseSnapshots(string(data))
+	if err != nil {
+		return nil, errors.Wrapf(err, "read snapshots %s", s.path)
+	}
+	s.entries = entries
+	return s, nil
+}
+
+// parseSnapshots evaluates a snapshot file, which assigns each snapshot to
+// a property of exports, in a runtime of its own.
+func parseSnapshots(text string) (map[string]string, error) {
+	vm := goja.New()
+	exports := vm.NewObject()
+	if err := vm.Set("exports", exports); err != nil {
+		return nil, err
+	}
+	if _, err := vm.RunString(text); err != nil {
+		return nil, err
+	}
+	entries := map[string]string{}
+	for _, key := range exports.Keys() {
+		value := exports.Get(key).String()
+		if strings.HasPrefix(value, "\n") && strings.HasSuffix(value, "\n") && len(value) > 1 {
+			value = value[1 : len(value)-1]
+		}
+		entries[key] = value
+	}
+	return entries, nil
+}
+
+// check compares received with the snapshot stored under key, writing it
+// when there is none. It returns whether the assertion passes and the
+// message of its failure.
+func (s *snapshotFile) check(key, received string) (bool, string) {
+	s.used[key] = true
+	stored, ok := s.entries[key]
+	switch {
+	case ok && stored == received:
+		return true, ""
+	case ok && s.update:
+		s.entries[key] = received
+		s.dirty = true
+		s.stats.Updated++
+		return true, ""
+	case ok:
+		s.stats.Failed++
+		return false, fmt.Sprintf("Snapshot name: `%s`\n\n- Snapshot\n+ Received\n\n%s", key, lineDiff(stored, received))
+	case s.ci && !s.update:
+		s.stats.Failed++
+		return false, fmt.Sprintf("New snapshot was not written. Snapshots are not written in CI mode unless they are updated.\n\nSnapshot name: `%s`\n\nReceived: %s", key, received)
+	default:
+		s.entries[key] = received
+		s.dirty = true
+		s.stats.Added++
+		return true, ""
+	}
+}
+
+// save writes the snapshots if they changed. When complete is set, every
+// test of the file ran, and snapshots no test used are removed in update
+// mode.
+func (s *snapshotFile) save(complete bool) error {
+	if complete && s.update {
+		for key := range s.entries {
+			if !s.used[key] {
+				delete(s.entries, key)
+				s.dirty = true
+				s.stats.Removed++
+			}
+		}
+	}
+	if !s.dirty {
+		return nil
+	}
+	if len(s.entries) == 0 {
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "remove snapshots")
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return errors.Wrap(err, "write snapshots")
+	}
+	if err := os.WriteFile(s.path, []byte(formatSnapshots(s.entries)), 0o644); err != nil {
+		return errors.Wrap(err, "write snapshots")
+	}
+	return nil
+}
+
+// formatSnapshots renders entries sorted by key. Multi-line values start and
+// end on lines of their own.
+func formatSnapshots(entries map[string]string) string {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(snapshotHeader)
+	b.WriteString("\n")
+	for _, key := range keys {
+		value := entries[key]
+		if strings.Contains(value, "\n") {
+			value = "\n" + value + "\n"
+		}
+		fmt.Fprintf(&b, "\nexports[`%s`] = `%s`;\n", escapeTemplate(key), escapeTemplate(value))
+	}
+	return b.String()
+}
+
+var templateEscaper = strings.NewReplacer("\\", "\\\\", "`", "\\`", "${", "\\${")
+
+func escapeTemplate(s string) string {
+	return templateEscaper.Replace(s)
+}
+
+// lineDiff lists the lines of want and got, marking those only in want with
+// "-" and those only in got with "+".
+func lineDiff(want, got string) string {
+	a := strings.Split(want, "\n")
+	b := strings.Split(got, "\n")
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var out []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out = append(out, "  "+a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			out = append(out, "- "+a[i])
+			i++
+		default:
+			out = append(out, "+ "+b[j])
+			j++
+		}
+	}
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,61 @@
+package jstest_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-go-golems/go-go-goja/pkg/jstest"
+	"github.com/stretchr/testify/require"
+)
+
+const snapshotTest = `
+describe("render", () => {
+  it("matches", () => {
+    expect({ name: "a` + "`" + `b", items: [1, "${x}"], at: new Date(0) }).toMatchSnapshot();
+    expect(VALUE).toMatchSnapshot("value");
+  });
+});
+`
+
+func TestSnapshotsAreWrittenComparedAndUpdated(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, "render.test.js", "const VALUE = 1;\n"+snapshotTest)
+	snap := jstest.SnapshotPath(file)
+	require.Equal(t, filepath.Join(dir, "__snapshots__", "render.test.js.snap"), snap)
+
+	result := runFile(t, file, jstest.Options{CI: true})
+	require.Equal(t, jstest.StatusFailed, result.Tests[0].Status)
+	require.Contains(t, result.Tests[0].Failure.Message, "New snapshot was not written")
+	require.NoFileExists(t, snap)
+
+	result = runFile(t, file, jstest.Options{})
+	require.NoError(t, result.Err)
+	require.Equal(t, jstest.StatusPassed, result.Tests[0].Status, "%v", result.Tests[0].Failure)
+	require.Equal(t, jstest.SnapshotStats{Added: 2}, result.Snapshots)
+	data, err := os.ReadFile(snap)
+	require.NoError(t, err)
+	require.Equal(t, "// goja-test snapshot v1\n"+
+		"\nexports[`render matches 1`] = `\n{\n  \"at\": 1970-01-01T00:00:00.000Z,\n  \"items\": [\n    1,\n    \"\\${x}\",\n  ],\n  \"name\": \"a\\`b\",\n}\n`;\n"+
+		"\nexports[`render matches: value 2`] = `1`;\n", string(data))
+
+	result = runFile(t, file, jstest.Options{CI: true})
+	require.Equal(t, jstest.StatusPassed, result.Tests[0].Status, "%v", result.Tests[0].Failure)
+	require.Equal(t, jstest.SnapshotStats{}, result.Snapshots)
+
+	writeFile(t, dir, "render.test.js", "const VALUE = 2;\n"+snapshotTest)
+	result = runFile(t, file, jstest.Options{})
+	require.Equal(t, jstest.StatusFailed, result.Tests[0].Status)
+	require.Equal(t, "AssertionError: expect(received).toMatchSnapshot(name)\n\n"+
+		"Snapshot name: `render matches: value 2`\n\n- Snapshot\n+ Received\n\n- 1\n+ 2", result.Tests[0].Failure.Message)
+	require.Equal(t, jstest.SnapshotStats{Failed: 1}, result.Snapshots)
+
+	result = runFile(t, file, jstest.Options{UpdateSnapshots: true})
+	require.Equal(t, jstest.StatusPassed, result.Tests[0].Status, "%v", result.Tests[0].Failure)
+	require.Equal(t, jstest.SnapshotStats{Updated: 1}, result.Snapshots)
+
+	writeFile(t, dir, "render.test.js", "it(\"other\", () => {});\n")
+	result = runFile(t, file, jstest.Options{UpdateSnapshots: true})
+	require.Equal(t, jstest.SnapshotStats{Removed: 2}, result.Snapshots)
+	require.NoFileExists(t, snap)
+}
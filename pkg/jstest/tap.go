@@ -0,0 +1,54 @@
+package jstest
+
+import (
+	"io"
+	"strings"
+)
+
+// WriteTAP writes the report in the Test Anything Protocol, version 13.
+// Each test is one test point named by its file and full name. Failures
+// carry a YAML block with the message and stack, files that failed to load
+// are reported as a failed test point of their own, and skipped and todo
+// tests carry the SKIP and TODO directives.
+func (r *Report) WriteTAP(w io.Writer, dir string) error {
+	p := &errWriter{w: w}
+	p.printf("TAP version 13\n")
+	n := 0
+	for _, f := range r.Files {
+		path := displayPath(dir, f.Path)
+		for _, t := range f.Tests {
+			n++
+			name := tapEscape(path + " > " + strings.Join(t.names(), " > "))
+			switch t.Status {
+			case StatusPassed:
+				p.printf("ok %d - %s\n", n, name)
+			case StatusSkipped:
+				p.printf("ok %d - %s # SKIP\n", n, name)
+			case StatusTodo:
+				p.printf("not ok %d - %s # TODO\n", n, name)
+			case StatusFailed:
+				p.printf("not ok %d - %s\n", n, name)
+				if t.Failure != nil {
+					writeTAPDiagnostic(p, t.Failure.Message, t.Failure.String())
+				}
+			}
+		}
+		if f.Err != nil {
+			n++
+			p.printf("not ok %d - %s\n", n, tapEscape(path))
+			writeTAPDiagnostic(p, "test file failed", f.Err.Error())
+		}
+	}
+	p.printf("1..%d\n", n)
+	return p.err
+}
+
+func writeTAPDiagnostic(p *errWriter, message, details string) {
+	p.printf("  ---\n  message: %q\n  stack: |\n%s\n  ...\n", firstLine(message), indentLines(details, "    "))
+}
+
+// tapEscape escapes the characters that end the description of a test
+// point.
+func tapEscape(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "#", "\\#", "\n", " ").Replace(s)
+}
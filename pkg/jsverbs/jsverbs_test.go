@@ -947,6 +947,24 @@ __verb__("summarize", {
 	require.Contains(t, err.Error(), `unknown section "missing"`)
 }
 
+func TestSourceLoaderKeepsScannedLineNumbers(t *testing.T) {
+	registry := mustRegistry(t)
+	require.NotEmpty(t, registry.Files)
+	file := registry.Files[0]
+
+	loaded, err := registry.RequireLoader()(file.ModulePath)
+	require.NoError(t, err)
+	original := strings.Split(string(file.Source), "\n")
+	lines := strings.Split(string(loaded), "\n")
+	require.GreaterOrEqual(t, len(lines), len(original))
+	for i, line := range original {
+		require.True(t, strings.HasSuffix(lines[i], line), "line %d moved", i+1)
+	}
+
+	require.Equal(t, file.AbsPath, registry.FilePath(file.ModulePath))
+	require.Equal(t, "/missing.js", registry.FilePath("/missing.js"))
+}
+
 func mustRegistry(t *testing.T) *Registry {
 	t.Helper()
 	registry, err := ScanDir(filepath.Join(repoRoot(t), "examples", "jsverbs", "basic"))
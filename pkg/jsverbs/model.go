@@ -8,6 +8,8 @@ import (
 	"strings"
 
 	"github.com/go-go-golems/go-go-goja/pkg/engine"
+	"github.com/go-go-golems/go-go-goja/pkg/jscover"
+	"github.com/go-go-golems/go-go-goja/pkg/jsdebug"
 )
 
 type ParameterKind string
@@ -112,6 +114,12 @@ type Registry struct {
 	// ModuleMiddleware controls which native modules are available to jsverbs
 	// when the registry builds its own runtime. If nil, all modules are loaded.
 	ModuleMiddleware engine.ModuleMiddleware
+	// Debugger, when set, instruments the runtimes the registry builds so a
+	// Debug Adapter Protocol client can step through verbs.
+	Debugger *jsdebug.Debugger
+	// Coverage, when set, counts what the verbs run in the runtimes the
+	// registry builds. Debugger takes precedence over it.
+	Coverage *jscover.Collector
 }
 
 type FileSpec struct {
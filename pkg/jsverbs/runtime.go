@@ -6,18 +6,26 @@ import (
 	"reflect"
 	"sort"
 	"strings"
-	"time"
 
 	"github.com/dop251/goja"
 	"github.com/dop251/goja_nodejs/require"
 	"github.com/go-go-golems/glazed/pkg/cmds/fields"
 	"github.com/go-go-golems/glazed/pkg/cmds/values"
 	"github.com/go-go-golems/go-go-goja/pkg/engine"
+	"github.com/go-go-golems/go-go-goja/pkg/jserrors"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimeowner"
 )
 
 func (r *Registry) invoke(ctx context.Context, verb *VerbSpec, parsedValues *values.Values) (interface{}, error) {
-	builder := engine.NewRuntimeFactoryBuilder().
-		WithRequireOptions(require.WithLoader(r.sourceLoader))
+	builder := engine.NewRuntimeFactoryBuilder()
+	switch {
+	case r.Debugger != nil:
+		builder = builder.WithRequireOptions(r.Debugger.RequireOptionWithPaths(r.sourceLoader, r.FilePath))
+	case r.Coverage != nil:
+		builder = builder.WithRequireOptions(r.Coverage.RequireOptionWithPaths(r.sourceLoader, r.FilePath))
+	default:
+		builder = builder.WithRequireOptions(require.WithLoader(r.sourceLoader))
+	}
 	if r.ModuleMiddleware != nil {
 		builder = builder.UseModuleMiddleware(r.ModuleMiddleware)
 	}
@@ -43,6 +51,15 @@ func (r *Registry) RequireLoader() func(modulePath string) ([]byte, error) {
 	return r.sourceLoader
 }
 
+// FilePath returns the file a scanned module was read from, or modulePath
+// when the module has no file on disk.
+func (r *Registry) FilePath(modulePath string) string {
+	if file := r.filesByModule[modulePath]; file != nil && file.AbsPath != "" {
+		return file.AbsPath
+	}
+	return modulePath
+}
+
 // InvokeInRuntime invokes a verb inside an already-live caller-owned runtime.
 // Unlike the default Commands()/invoke() path, it does not create or close the runtime.
 func (r *Registry) InvokeInRuntime(ctx context.Context, runtime *engine.Runtime, verb *VerbSpec, parsedValues *values.Values) (interface{}, error) {
@@ -100,7 +117,7 @@ func (r *Registry) InvokeInRuntime(ctx context.Context, runtime *engine.Runtime,
 		return result.Export(), nil
 	})
 	if err != nil {
-		return nil, err
+		return nil, jserrors.Wrap(err)
 	}
 
 	if promise, ok := ret.(*goja.Promise); ok {
@@ -170,7 +187,7 @@ func (r *Registry) sourceLoader(modulePath string) ([]byte, error) {
 		if len(original) == 0 {
 			original = append([]byte(nil), source...)
 		}
-		return r.options.RuntimeTransform(RuntimeTransformInput{
+		out, err := r.options.RuntimeTransform(RuntimeTransformInput{
 			Path:           file.RelPath,
 			AbsPath:        file.AbsPath,
 			RelPath:        file.RelPath,
@@ -183,6 +200,10 @@ func (r *Registry) sourceLoader(modulePath string) ([]byte, error) {
 			Prelude:        prelude,
 			Overlay:        overlay,
 		})
+		if err != nil {
+			return nil, err
+		}
+		return jserrors.Retain(modulePath, out), nil
 	}
 	return []byte(injectPrelude(string(file.Source), overlayPrelude()) + r.overlay(modulePath, file)), nil
 }
@@ -218,6 +239,8 @@ func (r *Registry) overlay(moduleKey string, file *FileSpec) string {
 	return suffix.String()
 }
 
+// overlayPrelude stays on one line so that line numbers in errors and in the
+// debugger match the scanned file.
 func overlayPrelude() string {
 	return strings.Join([]string{
 		`globalThis.__glazedVerbRegistry = globalThis.__glazedVerbRegistry || {};`,
@@ -226,7 +249,7 @@ func overlayPrelude() string {
 		`globalThis.__verb__ = globalThis.__verb__ || function() {};`,
 		`globalThis.doc = globalThis.doc || function() { return ""; };`,
 		"",
-	}, "\n")
+	}, " ")
 }
 
 func injectPrelude(source, prelude string) string {
@@ -239,51 +262,18 @@ func injectPrelude(source, prelude string) string {
 	return prelude + source
 }
 
-// waitForPromise intentionally uses polling for v1. It is simple, explicit, and
-// good enough for the current prototype, but should not be mistaken for the final
-// async bridge design.
 func waitForPromise(ctx context.Context, runtime *engine.Runtime, promise *goja.Promise) (interface{}, error) {
-	for {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-		}
-
-		ret, err := runtime.Owner.Call(ctx, "jsverbs.promise-state", func(_ context.Context, vm *goja.Runtime) (interface{}, error) {
-			return promiseSnapshot{
-				State:  promise.State(),
-				Result: promise.Result(),
-			}, nil
-		})
-		if err != nil {
-			return nil, err
-		}
-		snapshot := ret.(promiseSnapshot)
-		switch snapshot.State {
-		case goja.PromiseStatePending:
-			time.Sleep(5 * time.Millisecond)
-		case goja.PromiseStateRejected:
-			return nil, fmt.Errorf("promise rejected: %s", valueString(snapshot.Result))
-		case goja.PromiseStateFulfilled:
-			if snapshot.Result == nil || goja.IsUndefined(snapshot.Result) || goja.IsNull(snapshot.Result) {
-				return nil, nil
-			}
-			return snapshot.Result.Export(), nil
-		}
+	settlement, err := runtimeowner.AwaitPromise(ctx, runtime.Owner, "jsverbs.await-promise", promise)
+	if err != nil {
+		return nil, err
 	}
-}
-
-type promiseSnapshot struct {
-	State  goja.PromiseState
-	Result goja.Value
-}
-
-func valueString(value goja.Value) string {
-	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
-		return "undefined"
+	if settlement.Rejected() {
+		return nil, jserrors.Wrap(jserrors.Rejected(settlement.Value))
+	}
+	if settlement.Value == nil || goja.IsUndefined(settlement.Value) || goja.IsNull(settlement.Value) {
+		return nil, nil
 	}
-	return value.String()
+	return settlement.Value.Export(), nil
 }
 
 func collectSectionValues(parsedValues *values.Values) map[string]map[string]interface{} {
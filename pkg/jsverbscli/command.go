@@ -69,11 +69,27 @@ func newCommandWithInvokerFactory(bootstrap jsverbrepos.Bootstrap, invokers Invo
 			if flag := cmd.Flag("allow-writes"); flag != nil {
 				settings.AllowWrites = flag.Value.String() == "true"
 			}
+			if flag := cmd.Flag("fetch-cassette"); flag != nil {
+				settings.FetchCassette = flag.Value.String()
+			}
+			if flag := cmd.Flag("fetch-cassette-mode"); flag != nil {
+				settings.FetchCassetteMode = flag.Value.String()
+			}
+			if flag := cmd.Flag("fetch-cassette-match"); flag != nil {
+				settings.FetchCassetteMatch = flag.Value.String()
+			}
+			if origins, err := cmd.Flags().GetStringSlice("fetch-cassette-origin"); err == nil {
+				settings.FetchCassetteOrigins = origins
+			}
 			return nil
 		}
 		root.PersistentFlags().StringVar(&settings.DBPath, "db", "", "SQLite database path exposed as require(\"database\") and require(\"db\")")
 		root.PersistentFlags().BoolVar(&settings.ReadOnly, "readonly", true, "Open the JavaScript database API in read-only mode")
 		root.PersistentFlags().BoolVar(&settings.AllowWrites, "allow-writes", false, "Allow db.exec writes when --readonly=false")
+		root.PersistentFlags().StringVar(&settings.FetchCassette, "fetch-cassette", "", "Cassette file that serves require(\"fetch\") traffic instead of the network")
+		root.PersistentFlags().StringVar(&settings.FetchCassetteMode, "fetch-cassette-mode", "replay", "Cassette mode: replay recorded traffic, or record live traffic into the cassette")
+		root.PersistentFlags().StringVar(&settings.FetchCassetteMatch, "fetch-cassette-match", "method,url", "Request fields replay compares: method, url, body and header:<name>")
+		root.PersistentFlags().StringSliceVar(&settings.FetchCassetteOrigins, "fetch-cassette-origin", nil, "Origin require(\"fetch\") may reach through the cassette; required to record")
 	}
 
 	repositories, err := ScanRepositories(bootstrap)
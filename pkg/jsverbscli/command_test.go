@@ -4,8 +4,12 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -179,3 +183,102 @@ __verb__("hello", { short: "duplicate" });
 	}
 	return dir
 }
+
+func TestFetchCassetteFlagReplaysRecordedTraffic(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "weather")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	script := `__package__({ name: "weather", parents: ["examples"] });
+async function today() {
+  const response = await require("fetch").fetch("http://api.example.test/today");
+  return { status: response.status, body: await response.text() };
+}
+__verb__("today", { short: "Fetch today's weather" });
+`
+	if err := os.WriteFile(filepath.Join(dir, "weather.js"), []byte(script), 0o644); err != nil {
+		t.Fatalf("write weather.js: %v", err)
+	}
+	cassette := filepath.Join(t.TempDir(), "weather.yaml")
+	recording := `version: 1
+interactions:
+  - request:
+      method: GET
+      url: http://api.example.test/today
+    response:
+      status: 200
+      body: sunny
+`
+	if err := os.WriteFile(cassette, []byte(recording), 0o644); err != nil {
+		t.Fatalf("write cassette: %v", err)
+	}
+
+	cmd, err := NewCommand(jsverbrepos.Bootstrap{Repositories: []jsverbrepos.Repository{{Name: "weather", Source: "test", RootDir: dir}}})
+	if err != nil {
+		t.Fatalf("NewCommand() error = %v", err)
+	}
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{"--fetch-cassette", cassette, "examples", "weather", "today", "--output", "json"})
+	stdout := captureStdout(t, func() {
+		if err := cmd.ExecuteContext(context.Background()); err != nil {
+			t.Fatalf("ExecuteContext() error = %v\noutput:\n%s", err, out.String())
+		}
+	})
+	if !strings.Contains(stdout, `"body": "sunny"`) || !strings.Contains(stdout, `"status": 200`) {
+		t.Fatalf("verb output did not come from the cassette: %q", stdout)
+	}
+}
+
+func TestFetchCassetteRecordsEveryVerbRunIntoOneFile(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		_, _ = fmt.Fprintf(w, "call %d", calls)
+	}))
+	defer server.Close()
+	dir := filepath.Join(t.TempDir(), "weather")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	script := `__package__({ name: "weather", parents: ["examples"] });
+async function today() {
+  const response = await require("fetch").fetch(` + strconv.Quote(server.URL+"/today") + `);
+  return { body: await response.text() };
+}
+__verb__("today", { short: "Fetch today's weather" });
+`
+	if err := os.WriteFile(filepath.Join(dir, "weather.js"), []byte(script), 0o644); err != nil {
+		t.Fatalf("write weather.js: %v", err)
+	}
+	cassette := filepath.Join(t.TempDir(), "weather.yaml")
+	bootstrap := jsverbrepos.Bootstrap{Repositories: []jsverbrepos.Repository{{Name: "weather", Source: "test", RootDir: dir}}}
+	if _, err := cassetteFetchModule(&RuntimeSettings{FetchCassette: cassette, FetchCassetteMode: "record"}); err == nil || !strings.Contains(err.Error(), "--fetch-cassette-origin") {
+		t.Fatalf("recording without origins error = %v", err)
+	}
+
+	settings := &RuntimeSettings{ReadOnly: true}
+	cmd, err := newCommandWithInvokerFactory(bootstrap, runtimeInvokerFactory(settings), settings)
+	if err != nil {
+		t.Fatalf("newCommandWithInvokerFactory() error = %v", err)
+	}
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	for range 2 {
+		cmd.SetArgs([]string{"--fetch-cassette", cassette, "--fetch-cassette-mode", "record", "--fetch-cassette-origin", server.URL, "examples", "weather", "today", "--output", "json"})
+		captureStdout(t, func() {
+			if err := cmd.ExecuteContext(context.Background()); err != nil {
+				t.Fatalf("ExecuteContext() error = %v\noutput:\n%s", err, out.String())
+			}
+		})
+	}
+	data, err := os.ReadFile(cassette)
+	if err != nil {
+		t.Fatalf("read cassette: %v", err)
+	}
+	if !strings.Contains(string(data), "call 1") || !strings.Contains(string(data), "call 2") {
+		t.Fatalf("cassette lost an earlier verb run:\n%s", data)
+	}
+}
@@ -5,10 +5,12 @@ import (
 	"database/sql"
 	"fmt"
 	"path/filepath"
+	"sync"
 
 	noderequire "github.com/dop251/goja_nodejs/require"
 	"github.com/go-go-golems/glazed/pkg/cmds/values"
 	databasemod "github.com/go-go-golems/go-go-goja/modules/database"
+	fetchmod "github.com/go-go-golems/go-go-goja/modules/fetch"
 	"github.com/go-go-golems/go-go-goja/modules/uidsl"
 	"github.com/go-go-golems/go-go-goja/pkg/engine"
 	"github.com/go-go-golems/go-go-goja/pkg/jsverbs"
@@ -19,6 +21,19 @@ type RuntimeSettings struct {
 	DBPath      string
 	ReadOnly    bool
 	AllowWrites bool
+	// FetchCassette exposes require("fetch") backed by a record/replay
+	// cassette, so verb suites can run offline. Recording reaches the
+	// network, so it requires FetchCassetteOrigins.
+	FetchCassette        string
+	FetchCassetteMode    string
+	FetchCassetteMatch   string
+	FetchCassetteOrigins []string
+
+	// The cassette is opened once per process: every verb invocation builds
+	// a new runtime factory, and reopening a recording would truncate it.
+	cassetteOnce sync.Once
+	cassette     *fetchmod.Cassette
+	cassetteErr  error
 }
 
 func runtimeInvokerFactory(settings *RuntimeSettings) InvokerFactory {
@@ -76,6 +91,14 @@ func newRuntimeFactory(repo ScannedRepository, settings *RuntimeSettings) (*engi
 		)
 		cleanup = func() { _ = db.Close() }
 	}
+	if settings.FetchCassette != "" {
+		fetchModule, err := cassetteFetchModule(settings)
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		moduleSpecs = append(moduleSpecs, engine.NativeModuleRegistrar{ModuleID: "fetch:cassette", ModuleName: fetchModule.Name(), Loader: fetchModule.Loader})
+	}
 
 	builder := engine.NewRuntimeFactoryBuilder(runtimeOptions(repo)...).
 		WithRequireOptions(noderequire.WithLoader(repo.Registry.RequireLoader())).
@@ -90,6 +113,48 @@ func newRuntimeFactory(repo ScannedRepository, settings *RuntimeSettings) (*engi
 	return factory, cleanup, nil
 }
 
+// cassetteFetchModule exposes fetch through the settings' cassette under a
+// policy of its own: requests may only reach FetchCassetteOrigins (any origin
+// while replaying, when none are given), responses are capped at the default
+// size, and scripts cannot read credentials from the environment or files.
+func cassetteFetchModule(settings *RuntimeSettings) (*fetchmod.Module, error) {
+	policy := fetchmod.Policy{
+		AllowedOrigins:   settings.FetchCassetteOrigins,
+		Timeout:          fetchmod.DefaultTimeout,
+		MaxResponseBytes: fetchmod.DefaultMaxResponseBytes,
+	}
+	cassette, err := settings.fetchCassette(policy)
+	if err != nil {
+		return nil, err
+	}
+	return fetchmod.New(fetchmod.WithPolicy(policy), fetchmod.WithHTTPClient(cassette.Client())), nil
+}
+
+func (s *RuntimeSettings) fetchCassette(policy fetchmod.Policy) (*fetchmod.Cassette, error) {
+	s.cassetteOnce.Do(func() {
+		s.cassette, s.cassetteErr = openFetchCassette(s, policy)
+	})
+	return s.cassette, s.cassetteErr
+}
+
+func openFetchCassette(settings *RuntimeSettings, policy fetchmod.Policy) (*fetchmod.Cassette, error) {
+	mode, err := fetchmod.ParseCassetteMode(settings.FetchCassetteMode)
+	if err != nil {
+		return nil, fmt.Errorf("--fetch-cassette-mode: %w", err)
+	}
+	if mode == fetchmod.CassetteRecord && len(settings.FetchCassetteOrigins) == 0 {
+		return nil, fmt.Errorf("--fetch-cassette-mode record needs --fetch-cassette-origin to name the origins it may reach")
+	}
+	match, err := fetchmod.ParseCassetteMatch(settings.FetchCassetteMatch)
+	if err != nil {
+		return nil, fmt.Errorf("--fetch-cassette-match: %w", err)
+	}
+	return fetchmod.OpenCassette(settings.FetchCassette, mode,
+		fetchmod.WithCassetteMatch(match),
+		fetchmod.WithCassetteMaxBodyBytes(policy.MaxResponseBytes),
+	)
+}
+
 func runtimeOptions(repo ScannedRepository) []engine.Option {
 	if repo.Repository.Embedded {
 		return nil
@@ -0,0 +1,209 @@
+package lsp
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-go-golems/go-go-goja/pkg/jsparse"
+)
+
+// requireStringPattern matches a cursor inside the string of a require()
+// call and captures what has been typed so far.
+var requireStringPattern = regexp.MustCompile(`require\(\s*['"]([^'"]*)$`)
+
+// memberPattern matches alias.partial at the end of the line prefix. It is
+// the fallback for a trailing dot that tree-sitter joined with the next line.
+var memberPattern = regexp.MustCompile(`([A-Za-z_$][\w$]*)\.([\w$]*)$`)
+
+// completion answers textDocument/completion: module names inside
+// require(""), exports after a module alias, and otherwise the jsparse
+// candidates plus the bindings in scope at the cursor.
+func (s *Server) completion(params TextDocumentPositionParams) *CompletionList {
+	d := s.workspace.get(params.TextDocument.URI)
+	if d == nil {
+		return &CompletionList{Items: []CompletionItem{}}
+	}
+	offset := d.offsetAt(params.Position)
+	prefix := d.linePrefix(offset)
+	if match := requireStringPattern.FindStringSubmatch(prefix); match != nil {
+		return &CompletionList{Items: s.moduleCompletions(d, match[1])}
+	}
+
+	tp, err := jsparse.NewTSParser()
+	if err != nil {
+		log.Warn().Err(err).Msg("lsp: create tree-sitter parser")
+		return &CompletionList{Items: []CompletionItem{}}
+	}
+	defer tp.Close()
+	root := tp.Parse([]byte(d.text))
+	ctx := jsparse.ExtractCompletionContext(root, []byte(d.text), params.Position.Line, len(prefix))
+	if ctx.Kind == jsparse.CompletionNone {
+		if match := memberPattern.FindStringSubmatch(prefix); match != nil {
+			ctx = jsparse.CompletionContext{Kind: jsparse.CompletionProperty, BaseExpr: match[1], PartialText: match[2]}
+		}
+	}
+
+	var items []CompletionItem
+	switch ctx.Kind {
+	case jsparse.CompletionProperty:
+		items = s.memberCompletions(d, ctx)
+	case jsparse.CompletionIdentifier:
+		items = s.identifierCompletions(d, ctx, root, offset)
+	case jsparse.CompletionArgument, jsparse.CompletionNone:
+	}
+	if items == nil {
+		items = []CompletionItem{}
+	}
+	return &CompletionList{Items: items}
+}
+
+// moduleCompletions lists native modules and workspace files for require().
+func (s *Server) moduleCompletions(d *document, typed string) []CompletionItem {
+	var items []CompletionItem
+	if !isRelativeModule(typed) && !strings.HasPrefix(typed, ".") {
+		for _, name := range s.natives.names() {
+			if !strings.HasPrefix(name, typed) {
+				continue
+			}
+			m := s.natives.module(name)
+			item := CompletionItem{Label: name, Kind: CompletionKindModule, Detail: "native module"}
+			if m.doc != "" {
+				item.Documentation = &MarkupContent{Kind: "markdown", Value: strings.TrimSpace(m.doc)}
+			}
+			items = append(items, item)
+		}
+	}
+	for _, other := range s.workspace.sorted() {
+		if other == d {
+			continue
+		}
+		spec := relativeSpec(d, other)
+		if spec != "" && strings.HasPrefix(spec, typed) {
+			items = append(items, CompletionItem{Label: spec, Kind: CompletionKindFile, Detail: other.rel})
+		}
+	}
+	return items
+}
+
+// memberCompletions completes alias.partial. Aliases of require() get the
+// module's exports; other bases fall back to jsparse.
+func (s *Server) memberCompletions(d *document, ctx jsparse.CompletionContext) []CompletionItem {
+	if module, ok := jsparse.ExtractRequireAliases(d.text)[ctx.BaseExpr]; ok {
+		if target := s.workspace.resolve(d, module); target != nil {
+			return s.workspaceExportCompletions(target, ctx.PartialText)
+		}
+		if m := s.natives.module(module); m != nil {
+			var items []CompletionItem
+			for _, e := range m.sortedExports() {
+				if !hasPrefixFold(e.name, ctx.PartialText) {
+					continue
+				}
+				kind := CompletionKindProperty
+				if e.function {
+					kind = CompletionKindFunction
+				}
+				items = append(items, CompletionItem{
+					Label: e.name, Kind: kind, Detail: firstLine(e.signature),
+					Documentation: &MarkupContent{Kind: "markdown", Value: e.markdown(m.name)},
+				})
+			}
+			return items
+		}
+	}
+	index := d.completionIndex()
+	return candidateItems(jsparse.ResolveCandidates(ctx, index))
+}
+
+func (s *Server) workspaceExportCompletions(target *document, partial string) []CompletionItem {
+	var items []CompletionItem
+	for _, name := range exportNames(target) {
+		if !hasPrefixFold(name, partial) {
+			continue
+		}
+		sym := topSymbol(target, name)
+		kind := CompletionKindProperty
+		if sym.binding != nil && sym.binding.Kind == jsparse.BindingFunction {
+			kind = CompletionKindFunction
+		}
+		items = append(items, CompletionItem{
+			Label: name, Kind: kind, Detail: target.rel,
+			Documentation: &MarkupContent{Kind: "markdown", Value: declarationHover(sym)},
+		})
+	}
+	return items
+}
+
+// identifierCompletions merges the jsparse candidates (top-level bindings,
+// well-known globals and declarations found by tree-sitter) with the
+// bindings of every scope enclosing the cursor.
+func (s *Server) identifierCompletions(d *document, ctx jsparse.CompletionContext, root *jsparse.TSNode, offset int) []CompletionItem {
+	index := d.completionIndex()
+	candidates := jsparse.ResolveCandidates(ctx, index, root)
+	if index != nil && index.Resolution != nil {
+		var scoped []jsparse.CompletionCandidate
+		for _, scope := range index.Resolution.Scopes {
+			// Scope offsets are 1-based.
+			if offset+1 < scope.Start || offset+1 > scope.End || scope.ID == index.Resolution.RootScopeID {
+				continue
+			}
+			for name, b := range scope.Bindings {
+				kind := jsparse.CandidateVariable
+				if b.Kind == jsparse.BindingFunction {
+					kind = jsparse.CandidateFunction
+				}
+				scoped = append(scoped, jsparse.CompletionCandidate{Label: name, Kind: kind, Detail: b.Kind.String()})
+			}
+		}
+		candidates = append(candidates, jsparse.FilterCandidatesByPrefix(scoped, ctx.PartialText)...)
+	}
+	return candidateItems(jsparse.DedupeAndSortCandidates(candidates))
+}
+
+// completionIndex returns the index of the last text that parsed, so
+// completion keeps working while the current text has a syntax error.
+func (d *document) completionIndex() *jsparse.Index {
+	if d.lastGood != nil {
+		return d.lastGood.Index
+	}
+	return nil
+}
+
+func candidateItems(candidates []jsparse.CompletionCandidate) []CompletionItem {
+	items := make([]CompletionItem, 0, len(candidates))
+	seen := map[string]bool{}
+	for _, c := range candidates {
+		if seen[c.Label] {
+			continue
+		}
+		seen[c.Label] = true
+		items = append(items, CompletionItem{Label: c.Label, Kind: candidateKind(c.Kind), Detail: c.Detail})
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+	return items
+}
+
+func candidateKind(kind jsparse.CandidateKind) CompletionItemKind {
+	switch kind {
+	case jsparse.CandidateProperty:
+		return CompletionKindProperty
+	case jsparse.CandidateMethod:
+		return CompletionKindMethod
+	case jsparse.CandidateFunction:
+		return CompletionKindFunction
+	case jsparse.CandidateKeyword:
+		return CompletionKindKeyword
+	case jsparse.CandidateVariable:
+		return CompletionKindVariable
+	}
+	return CompletionKindVariable
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return strings.HasPrefix(strings.ToLower(s), strings.ToLower(prefix))
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
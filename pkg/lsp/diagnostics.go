@@ -0,0 +1,61 @@
+package lsp
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/dop251/goja/parser"
+)
+
+// diagnostics collects the problems of d: syntax errors from the goja parser,
+// jsverbs metadata problems and require() calls that resolve to nothing.
+func (s *Server) diagnostics(d *document) []Diagnostic {
+	out := []Diagnostic{}
+	out = append(out, parseDiagnostics(d)...)
+	out = append(out, verbDiagnostics(d)...)
+	for _, imp := range d.imports {
+		if s.workspace.resolve(d, imp.module) != nil || s.natives.module(imp.module) != nil {
+			continue
+		}
+		message := "cannot find module " + imp.module
+		if !isRelativeModule(imp.module) {
+			message += " in the native module registry"
+		}
+		out = append(out, Diagnostic{
+			Range: d.nodeRange(imp.specNode), Severity: SeverityWarning, Source: "goja-lsp", Message: message,
+		})
+	}
+	return out
+}
+
+// parseDiagnostics converts goja parser errors. Their positions are 1-based
+// lines and byte columns.
+func parseDiagnostics(d *document) []Diagnostic {
+	err := d.analysis.ParseErr
+	if err == nil {
+		return nil
+	}
+	var list parser.ErrorList
+	if !errors.As(err, &list) {
+		return []Diagnostic{{Severity: SeverityError, Source: "goja", Message: err.Error()}}
+	}
+	out := make([]Diagnostic, 0, len(list))
+	for _, e := range list {
+		offset := len(d.text)
+		if line := e.Position.Line - 1; line >= 0 && line < len(d.lineStarts) {
+			offset = min(d.lineStarts[line]+e.Position.Column-1, len(d.text))
+		}
+		end := offset
+		// Underline the offending token, or one character.
+		for end < len(d.text) && !strings.ContainsRune(" \t\r\n;,(){}[]", rune(d.text[end])) {
+			end++
+		}
+		if end == offset && end < len(d.text) && d.text[end] != '\n' {
+			end++
+		}
+		out = append(out, Diagnostic{
+			Range: d.spanRange(offset, end), Severity: SeverityError, Source: "goja", Message: e.Message,
+		})
+	}
+	return out
+}
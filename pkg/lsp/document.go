@@ -0,0 +1,156 @@
+package lsp
+
+import (
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/go-go-golems/go-go-goja/pkg/jsdoc/extract"
+	"github.com/go-go-golems/go-go-goja/pkg/jsdoc/model"
+	"github.com/go-go-golems/go-go-goja/pkg/jsparse"
+)
+
+// document is one JavaScript file of the workspace together with everything
+// derived from its text. Documents are immutable: an edit replaces the
+// document instead of updating it.
+type document struct {
+	uri  string
+	path string
+	// rel is path relative to the workspace root. jsverbs derives default
+	// command parents from it.
+	rel     string
+	version int
+	text    string
+	// open reports whether the editor owns the text (didOpen) rather than
+	// the file on disk.
+	open       bool
+	lineStarts []int
+
+	analysis *jsparse.AnalysisResult
+	// lastGood is the most recent analysis that parsed. Completion falls
+	// back to it while the user is mid-edit and the text does not parse.
+	lastGood *jsparse.AnalysisResult
+	imports  []importBinding
+	docs     *model.FileDoc
+	verbs    *verbInfo
+}
+
+func newDocument(uri, path, rel, text string, version int, previous *document) *document {
+	d := &document{uri: uri, path: path, rel: rel, version: version, text: text}
+	d.lineStarts = []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			d.lineStarts = append(d.lineStarts, i+1)
+		}
+	}
+	d.analysis = jsparse.Analyze(path, text, nil)
+	switch {
+	case d.parsed() && d.analysis.ParseErr == nil:
+		d.lastGood = d.analysis
+	case previous != nil:
+		d.lastGood = previous.lastGood
+	}
+	if d.parsed() {
+		d.imports = collectImports(d)
+	}
+	if strings.Contains(text, "__doc__") || strings.Contains(text, "doc`") {
+		if fd, err := extract.ParseSource(path, []byte(text)); err == nil {
+			d.docs = fd
+		}
+	}
+	if strings.Contains(text, "__verb__") || strings.Contains(text, "__section__") || strings.Contains(text, "__package__") {
+		d.verbs = scanVerbs(d)
+	}
+	return d
+}
+
+// parsed reports whether the current text produced an AST with scopes.
+func (d *document) parsed() bool {
+	return d.analysis != nil && d.analysis.Index != nil && d.analysis.Resolution != nil
+}
+
+// offsetAt converts an LSP position to a 0-based byte offset, clamping
+// positions past the end of a line or of the document.
+func (d *document) offsetAt(pos Position) int {
+	if pos.Line < 0 {
+		return 0
+	}
+	if pos.Line >= len(d.lineStarts) {
+		return len(d.text)
+	}
+	offset := d.lineStarts[pos.Line]
+	units := 0
+	for offset < len(d.text) && d.text[offset] != '\n' && units < pos.Character {
+		r, size := utf8.DecodeRuneInString(d.text[offset:])
+		units += utf16Len(r)
+		offset += size
+	}
+	return offset
+}
+
+// positionAt converts a 0-based byte offset to an LSP position.
+func (d *document) positionAt(offset int) Position {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(d.text) {
+		offset = len(d.text)
+	}
+	line := sort.Search(len(d.lineStarts), func(i int) bool { return d.lineStarts[i] > offset }) - 1
+	character := 0
+	for _, r := range d.text[d.lineStarts[line]:offset] {
+		character += utf16Len(r)
+	}
+	return Position{Line: line, Character: character}
+}
+
+// nodeRange converts a jsparse node span (1-based, end exclusive) to a range.
+func (d *document) nodeRange(n *jsparse.NodeRecord) Range {
+	return d.spanRange(n.Start-1, n.End-1)
+}
+
+func (d *document) spanRange(start, end int) Range {
+	return Range{Start: d.positionAt(start), End: d.positionAt(end)}
+}
+
+// nodeText returns the source text of a jsparse node.
+func (d *document) nodeText(n *jsparse.NodeRecord) string {
+	start, end := n.Start-1, n.End-1
+	if start < 0 || end > len(d.text) || start > end {
+		return ""
+	}
+	return d.text[start:end]
+}
+
+// linePrefix returns the text of the line containing offset up to offset.
+func (d *document) linePrefix(offset int) string {
+	line := sort.Search(len(d.lineStarts), func(i int) bool { return d.lineStarts[i] > offset }) - 1
+	return d.text[d.lineStarts[line]:offset]
+}
+
+// utf16Len is the number of UTF-16 code units LSP counts for r. Invalid
+// UTF-8 decodes to U+FFFD and counts as one unit.
+func utf16Len(r rune) int {
+	if n := utf16.RuneLen(r); n > 0 {
+		return n
+	}
+	return 1
+}
+
+// uriToPath converts a file:// URI to a local path. Other schemes are
+// returned unchanged so untitled buffers still get a stable name.
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// pathToURI converts an absolute path to a file:// URI.
+func pathToURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
@@ -0,0 +1,204 @@
+package lsp
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/go-go-golems/go-go-goja/pkg/jsparse"
+)
+
+// importBinding is one require() call and the local name it is bound to.
+//
+//	const fs = require("fs")            // alias: binding fs, export ""
+//	const { join } = require("path")    // binding join, export "join"
+//	const { run: go } = require("./x")  // binding go, export "run", keyNode run
+type importBinding struct {
+	module string
+	// specNode is the string literal passed to require().
+	specNode *jsparse.NodeRecord
+	// binding is the local name, nil for a bare require() expression.
+	binding *jsparse.BindingRecord
+	// export is the destructured export name, empty for an alias of the
+	// whole module.
+	export string
+	// keyNode is the key of a { key: local } pattern. It names the export,
+	// so renaming the export renames it while the local name stays.
+	keyNode *jsparse.NodeRecord
+}
+
+// exportSite is a place where a document names one of its exports:
+// exports.name = ..., module.exports.name = ... or a key of
+// module.exports = { ... }.
+type exportSite struct {
+	name string
+	node *jsparse.NodeRecord
+}
+
+// collectImports finds the require() calls of a parsed document.
+func collectImports(d *document) []importBinding {
+	idx := d.analysis.Index
+	res := d.analysis.Resolution
+	var out []importBinding
+	for id := jsparse.NodeID(0); int(id) < len(idx.Nodes); id++ {
+		call := idx.Nodes[id]
+		if call == nil || call.Kind != "CallExpression" || len(call.ChildIDs) != 2 {
+			continue
+		}
+		callee, arg := idx.Nodes[call.ChildIDs[0]], idx.Nodes[call.ChildIDs[1]]
+		if callee == nil || arg == nil || callee.Kind != "Identifier" || arg.Kind != "StringLiteral" {
+			continue
+		}
+		if d.nodeText(callee) != "require" || res.BindingForNode(callee.ID) != nil {
+			continue
+		}
+		module := stringLiteralValue(d.nodeText(arg))
+		parent := idx.Nodes[call.ParentID]
+		if parent == nil || parent.Kind != "Binding" || len(parent.ChildIDs) != 2 || parent.ChildIDs[1] != call.ID {
+			out = append(out, importBinding{module: module, specNode: arg})
+			continue
+		}
+		target := idx.Nodes[parent.ChildIDs[0]]
+		switch target.Kind {
+		case "Identifier":
+			out = append(out, importBinding{module: module, specNode: arg, binding: res.BindingForNode(target.ID)})
+		case "ObjectPattern":
+			for _, propID := range target.ChildIDs {
+				prop := idx.Nodes[propID]
+				switch {
+				case prop.Kind == "PropertyShort" && len(prop.ChildIDs) > 0:
+					local := idx.Nodes[prop.ChildIDs[0]]
+					out = append(out, importBinding{
+						module: module, specNode: arg,
+						binding: res.BindingForNode(local.ID), export: d.nodeText(local),
+					})
+				case prop.Kind == "PropertyKeyed" && len(prop.ChildIDs) == 2:
+					key, local := idx.Nodes[prop.ChildIDs[0]], idx.Nodes[prop.ChildIDs[1]]
+					if local.Kind != "Identifier" {
+						continue
+					}
+					out = append(out, importBinding{
+						module: module, specNode: arg,
+						binding: res.BindingForNode(local.ID), export: propertyKeyName(d, key), keyNode: key,
+					})
+				}
+			}
+		default:
+			out = append(out, importBinding{module: module, specNode: arg})
+		}
+	}
+	return out
+}
+
+// importFor returns the import a binding was declared by.
+func (d *document) importFor(b *jsparse.BindingRecord) (importBinding, bool) {
+	if b == nil {
+		return importBinding{}, false
+	}
+	for _, imp := range d.imports {
+		if imp.binding == b {
+			return imp, true
+		}
+	}
+	return importBinding{}, false
+}
+
+// exportSites lists the places where d names its CommonJS exports.
+func exportSites(d *document) []exportSite {
+	if !d.parsed() {
+		return nil
+	}
+	idx := d.analysis.Index
+	var out []exportSite
+	for id := jsparse.NodeID(0); int(id) < len(idx.Nodes); id++ {
+		n := idx.Nodes[id]
+		if n == nil || n.Kind != "AssignExpression" || len(n.ChildIDs) != 2 {
+			continue
+		}
+		left, right := idx.Nodes[n.ChildIDs[0]], idx.Nodes[n.ChildIDs[1]]
+		if isModuleExports(d, left) && right.Kind == "ObjectLiteral" {
+			for _, propID := range right.ChildIDs {
+				prop := idx.Nodes[propID]
+				if len(prop.ChildIDs) == 0 {
+					continue
+				}
+				key := idx.Nodes[prop.ChildIDs[0]]
+				switch prop.Kind {
+				case "PropertyShort":
+					out = append(out, exportSite{name: d.nodeText(key), node: key})
+				case "PropertyKeyed":
+					out = append(out, exportSite{name: propertyKeyName(d, key), node: key})
+				}
+			}
+			continue
+		}
+		if left.Kind != "DotExpression" || len(left.ChildIDs) != 2 {
+			continue
+		}
+		object, property := idx.Nodes[left.ChildIDs[0]], idx.Nodes[left.ChildIDs[1]]
+		if isExportsObject(d, object) {
+			out = append(out, exportSite{name: d.nodeText(property), node: property})
+		}
+	}
+	return out
+}
+
+// exportNames returns the names a document exports, in source order.
+func exportNames(d *document) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, site := range exportSites(d) {
+		if site.name != "" && !seen[site.name] {
+			seen[site.name] = true
+			names = append(names, site.name)
+		}
+	}
+	return names
+}
+
+// isExportsObject matches `exports` and `module.exports`.
+func isExportsObject(d *document, n *jsparse.NodeRecord) bool {
+	if n == nil {
+		return false
+	}
+	if n.Kind == "Identifier" {
+		return d.nodeText(n) == "exports" && d.analysis.Resolution.BindingForNode(n.ID) == nil
+	}
+	return isModuleExports(d, n)
+}
+
+// isModuleExports matches `module.exports`.
+func isModuleExports(d *document, n *jsparse.NodeRecord) bool {
+	if n == nil || n.Kind != "DotExpression" || len(n.ChildIDs) != 2 {
+		return false
+	}
+	idx := d.analysis.Index
+	object, property := idx.Nodes[n.ChildIDs[0]], idx.Nodes[n.ChildIDs[1]]
+	return object.Kind == "Identifier" && d.nodeText(object) == "module" &&
+		d.analysis.Resolution.BindingForNode(object.ID) == nil && d.nodeText(property) == "exports"
+}
+
+// propertyKeyName returns the name of an object literal or pattern key.
+func propertyKeyName(d *document, key *jsparse.NodeRecord) string {
+	text := d.nodeText(key)
+	if key.Kind == "StringLiteral" {
+		return stringLiteralValue(text)
+	}
+	return text
+}
+
+// stringLiteralValue strips the quotes of a string literal's source text.
+func stringLiteralValue(raw string) string {
+	if unquoted, err := strconv.Unquote(raw); err == nil {
+		return unquoted
+	}
+	if len(raw) >= 2 && (raw[0] == '\'' || raw[0] == '`') && raw[len(raw)-1] == raw[0] {
+		return raw[1 : len(raw)-1]
+	}
+	return strings.Trim(raw, `"'`)
+}
+
+// isRelativeModule reports whether a require() specifier names a workspace
+// file rather than a native module.
+func isRelativeModule(spec string) bool {
+	return strings.HasPrefix(spec, "./") || strings.HasPrefix(spec, "../")
+}
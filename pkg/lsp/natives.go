@@ -0,0 +1,178 @@
+package lsp
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dop251/goja"
+
+	"github.com/go-go-golems/go-go-goja/modules"
+	"github.com/go-go-golems/go-go-goja/pkg/tsgen/render"
+)
+
+// nativeModule is what goja-lsp knows about one native module: its
+// documentation and exports.
+type nativeModule struct {
+	name    string
+	doc     string
+	exports map[string]*nativeExport
+}
+
+// nativeExport is one export of a native module. signature is TypeScript
+// from the module's tsgen descriptor when it has one.
+type nativeExport struct {
+	name        string
+	function    bool
+	signature   string
+	description string
+}
+
+// nativeCatalog indexes the native modules scripts can require().
+type nativeCatalog struct {
+	modules map[string]*nativeModule
+}
+
+var rawDTSExportPattern = regexp.MustCompile(`^export\s+(?:declare\s+)?(function|const|let|var|class)\s+([A-Za-z_$][A-Za-z0-9_$]*)`)
+
+// newNativeCatalog describes mods. Exports come from the tsgen descriptor
+// (modules.TypeScriptDeclarer) first; anything the descriptor does not list
+// is discovered by running the module loader in a scratch runtime.
+func newNativeCatalog(mods []modules.NativeModule) *nativeCatalog {
+	c := &nativeCatalog{modules: map[string]*nativeModule{}}
+	for _, m := range mods {
+		nm := &nativeModule{name: m.Name(), doc: m.Doc(), exports: map[string]*nativeExport{}}
+		if declarer, ok := m.(modules.TypeScriptDeclarer); ok {
+			addDescriptorExports(nm, declarer)
+		}
+		addLoadedExports(nm, m)
+		c.modules[nm.name] = nm
+	}
+	return c
+}
+
+func addDescriptorExports(nm *nativeModule, declarer modules.TypeScriptDeclarer) {
+	desc := declarer.TypeScriptModule()
+	if desc == nil {
+		return
+	}
+	if nm.doc == "" {
+		nm.doc = desc.Description
+	}
+	for _, fn := range desc.Functions {
+		signature, err := render.Function(fn)
+		if err != nil {
+			continue
+		}
+		nm.exports[fn.Name] = &nativeExport{
+			name:        fn.Name,
+			function:    true,
+			signature:   strings.TrimPrefix(signature, "export "),
+			description: fn.Description,
+		}
+	}
+	// RawDTS holds hand-written declarations, one line per entry. Only
+	// top-level export lines describe module exports; nested lines belong
+	// to interfaces and object types.
+	depth := 0
+	for _, line := range desc.RawDTS {
+		line = strings.TrimSpace(line)
+		if depth == 0 {
+			if match := rawDTSExportPattern.FindStringSubmatch(line); match != nil {
+				name := match[2]
+				signature := strings.TrimSuffix(strings.TrimPrefix(line, "export "), ";")
+				if existing, ok := nm.exports[name]; ok {
+					// Overloads are listed one per line.
+					existing.signature += "\n" + signature
+				} else {
+					nm.exports[name] = &nativeExport{name: name, function: match[1] == "function", signature: signature}
+				}
+			}
+		}
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+		if depth < 0 {
+			depth = 0
+		}
+	}
+}
+
+// addLoadedExports runs the module loader against a fresh runtime and adds
+// the exports the descriptor missed. Loaders that need a full engine
+// runtime may panic here; whatever they exported before that is kept.
+func addLoadedExports(nm *nativeModule, m modules.NativeModule) {
+	vm := goja.New()
+	moduleObj := vm.NewObject()
+	exports := vm.NewObject()
+	_ = moduleObj.Set("exports", exports)
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Debug().Str("module", nm.name).Interface("panic", r).Msg("lsp: native module loader failed outside an engine runtime")
+			}
+		}()
+		m.Loader(vm, moduleObj)
+	}()
+	loaded, ok := moduleObj.Get("exports").(*goja.Object)
+	if !ok {
+		return
+	}
+	for _, key := range loaded.Keys() {
+		if _, known := nm.exports[key]; known {
+			continue
+		}
+		_, isFunction := goja.AssertFunction(loaded.Get(key))
+		nm.exports[key] = &nativeExport{name: key, function: isFunction}
+	}
+}
+
+// module returns a native module by require() name. node: prefixed names
+// fall back to the bare module when only that is registered.
+func (c *nativeCatalog) module(name string) *nativeModule {
+	if c == nil {
+		return nil
+	}
+	if m, ok := c.modules[name]; ok {
+		return m
+	}
+	return c.modules[strings.TrimPrefix(name, "node:")]
+}
+
+// names returns the sorted module names.
+func (c *nativeCatalog) names() []string {
+	out := make([]string, 0, len(c.modules))
+	for name := range c.modules {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// sortedExports returns the exports sorted by name.
+func (m *nativeModule) sortedExports() []*nativeExport {
+	out := make([]*nativeExport, 0, len(m.exports))
+	for _, e := range m.exports {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].name < out[j].name })
+	return out
+}
+
+// markdown describes an export for hovers and completion documentation.
+func (e *nativeExport) markdown(module string) string {
+	var sb strings.Builder
+	signature := e.signature
+	if signature == "" {
+		signature = "const " + e.name
+		if e.function {
+			signature = "function " + e.name + "(...args: any[]): any"
+		}
+	}
+	sb.WriteString("```ts\n")
+	sb.WriteString(signature)
+	sb.WriteString("\n```\n")
+	if e.description != "" {
+		sb.WriteString("\n" + e.description + "\n")
+	}
+	sb.WriteString("\nExported by native module `" + module + "`.")
+	return sb.String()
+}
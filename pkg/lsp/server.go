@@ -0,0 +1,356 @@
+// Package lsp implements goja-lsp, a Language Server Protocol server for the
+// JavaScript run by go-go-goja. It is built on jsparse (parsing, scopes and
+// completion), the inspector cross-references, jsdoc __doc__ metadata,
+// jsverbs __verb__ metadata and the native module registry.
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/go-go-golems/go-go-goja/modules"
+)
+
+// Options configures a Server.
+type Options struct {
+	// Modules are the native modules scripts can require(). Nil means
+	// modules.ListDefaultModules().
+	Modules []modules.NativeModule
+	// RootDir is the workspace scanned for scripts. When empty the root sent
+	// by the client in initialize is used.
+	RootDir string
+	// Version is reported in the initialize result.
+	Version string
+}
+
+// ErrExitWithoutShutdown is returned by Serve when the client sends exit
+// without a prior shutdown request. The LSP specification asks servers to
+// exit with status 1 then.
+var ErrExitWithoutShutdown = errors.New("lsp: exit received before shutdown")
+
+// Server answers LSP requests for one client. It handles one message at a
+// time, so it needs no locking.
+type Server struct {
+	opts      Options
+	conn      *conn
+	workspace *workspace
+	natives   *nativeCatalog
+
+	initialized bool
+	shutdown    bool
+}
+
+// NewServer creates a server. The native module catalog is built here, once.
+func NewServer(opts Options) *Server {
+	mods := opts.Modules
+	if mods == nil {
+		mods = modules.ListDefaultModules()
+	}
+	return &Server{
+		opts:      opts,
+		workspace: newWorkspace(),
+		natives:   newNativeCatalog(mods),
+	}
+}
+
+// Serve reads requests from r and writes responses to w until the client
+// sends exit, r reaches EOF or ctx is cancelled.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.conn = newConn(r, w)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		msg, err := s.conn.read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			var rpcErr *responseError
+			if errors.As(err, &rpcErr) {
+				if writeErr := s.conn.replyError(nil, rpcErr); writeErr != nil {
+					return writeErr
+				}
+				continue
+			}
+			return err
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return ErrExitWithoutShutdown
+			}
+			return nil
+		}
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// handle dispatches one message. Only transport errors are returned; request
+// failures are sent to the client as JSON-RPC errors.
+func (s *Server) handle(msg *message) error {
+	if msg.ID == nil {
+		s.notification(msg)
+		return nil
+	}
+	if msg.Method == "" {
+		// A response to a server-to-client request; none are sent.
+		return nil
+	}
+	result, rpcErr := s.request(msg)
+	if rpcErr != nil {
+		return s.conn.replyError(msg.ID, rpcErr)
+	}
+	return s.conn.reply(msg.ID, result)
+}
+
+func (s *Server) request(msg *message) (any, *responseError) {
+	if msg.Method == "initialize" {
+		var params InitializeParams
+		if err := unmarshalParams(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.initialize(params), nil
+	}
+	if !s.initialized {
+		return nil, &responseError{Code: codeServerNotInitialized, Message: "server not initialized"}
+	}
+	if s.shutdown {
+		return nil, &responseError{Code: codeInvalidRequest, Message: "server is shutting down"}
+	}
+	switch msg.Method {
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		if err := unmarshalParams(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.completion(params), nil
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if err := unmarshalParams(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.hoverAt(params), nil
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err := unmarshalParams(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.definitionAt(params), nil
+	case "textDocument/references":
+		var params ReferenceParams
+		if err := unmarshalParams(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.referencesAt(params), nil
+	case "textDocument/rename":
+		var params RenameParams
+		if err := unmarshalParams(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.rename(params)
+	default:
+		return nil, &responseError{Code: codeMethodNotFound, Message: "method not supported: " + msg.Method}
+	}
+}
+
+func (s *Server) notification(msg *message) {
+	if !s.initialized {
+		return
+	}
+	switch msg.Method {
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if unmarshalParams(msg.Params, &params) != nil {
+			return
+		}
+		d := s.workspace.open(params.TextDocument.URI, params.TextDocument.Text, params.TextDocument.Version)
+		s.publishDiagnostics(d)
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if unmarshalParams(msg.Params, &params) != nil || len(params.ContentChanges) == 0 {
+			return
+		}
+		text := params.ContentChanges[len(params.ContentChanges)-1].Text
+		d := s.workspace.open(params.TextDocument.URI, text, params.TextDocument.Version)
+		s.publishDiagnostics(d)
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if unmarshalParams(msg.Params, &params) != nil {
+			return
+		}
+		s.workspace.close(params.TextDocument.URI)
+		// Clear the diagnostics of the closed buffer.
+		_ = s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+			URI: params.TextDocument.URI, Diagnostics: []Diagnostic{},
+		})
+	}
+}
+
+func (s *Server) initialize(params InitializeParams) InitializeResult {
+	root := s.opts.RootDir
+	if root == "" {
+		switch {
+		case params.RootURI != "":
+			root = uriToPath(params.RootURI)
+		case len(params.WorkspaceFolders) > 0:
+			root = uriToPath(params.WorkspaceFolders[0].URI)
+		default:
+			root = params.RootPath
+		}
+	}
+	if root != "" {
+		if abs, err := filepath.Abs(root); err == nil {
+			root = abs
+		}
+	}
+	s.workspace.load(root)
+	s.initialized = true
+	return InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:   textDocumentSyncFull,
+			CompletionProvider: &CompletionOptions{TriggerCharacters: []string{".", "\"", "'", "/"}},
+			HoverProvider:      true,
+			DefinitionProvider: true,
+			ReferencesProvider: true,
+			RenameProvider:     true,
+		},
+		ServerInfo: ServerInfo{Name: "goja-lsp", Version: s.opts.Version},
+	}
+}
+
+func (s *Server) publishDiagnostics(d *document) {
+	version := d.version
+	if err := s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI: d.uri, Version: &version, Diagnostics: s.diagnostics(d),
+	}); err != nil {
+		log.Warn().Err(err).Str("uri", d.uri).Msg("lsp: publish diagnostics")
+	}
+}
+
+func (s *Server) hoverAt(params TextDocumentPositionParams) *Hover {
+	d := s.workspace.get(params.TextDocument.URI)
+	if d == nil {
+		return nil
+	}
+	sym, rng, ok := s.symbolAt(d, d.offsetAt(params.Position))
+	if !ok {
+		return nil
+	}
+	text := s.hover(sym)
+	if text == "" {
+		return nil
+	}
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: text}, Range: &rng}
+}
+
+func (s *Server) definitionAt(params TextDocumentPositionParams) []Location {
+	d := s.workspace.get(params.TextDocument.URI)
+	if d == nil {
+		return nil
+	}
+	sym, _, ok := s.symbolAt(d, d.offsetAt(params.Position))
+	if !ok {
+		return nil
+	}
+	loc, ok := s.definition(sym)
+	if !ok {
+		return nil
+	}
+	return []Location{{URI: loc.doc.uri, Range: loc.rng}}
+}
+
+func (s *Server) referencesAt(params ReferenceParams) []Location {
+	d := s.workspace.get(params.TextDocument.URI)
+	if d == nil {
+		return nil
+	}
+	sym, _, ok := s.symbolAt(d, d.offsetAt(params.Position))
+	if !ok {
+		return nil
+	}
+	out := []Location{}
+	for _, loc := range s.references(sym, params.Context.IncludeDeclaration) {
+		out = append(out, Location{URI: loc.doc.uri, Range: loc.rng})
+	}
+	return out
+}
+
+func (s *Server) rename(params RenameParams) (*WorkspaceEdit, *responseError) {
+	if !isIdentifier(params.NewName) {
+		return nil, &responseError{Code: codeInvalidParams, Message: params.NewName + " is not a valid JavaScript identifier"}
+	}
+	d := s.workspace.get(params.TextDocument.URI)
+	if d == nil {
+		return nil, &responseError{Code: codeRequestFailed, Message: "unknown document " + params.TextDocument.URI}
+	}
+	sym, _, ok := s.symbolAt(d, d.offsetAt(params.Position))
+	if !ok {
+		return nil, &responseError{Code: codeRequestFailed, Message: "no symbol to rename at this position"}
+	}
+	switch sym.kind {
+	case symbolNative:
+		return nil, &responseError{Code: codeRequestFailed, Message: "cannot rename an export of native module " + sym.module}
+	case symbolModule:
+		return nil, &responseError{Code: codeRequestFailed, Message: "cannot rename a module specifier"}
+	case symbolLocal, symbolTop:
+	}
+	edit := &WorkspaceEdit{Changes: map[string][]TextEdit{}}
+	for _, loc := range s.references(sym, true) {
+		edit.Changes[loc.doc.uri] = append(edit.Changes[loc.doc.uri], TextEdit{Range: loc.rng, NewText: params.NewName})
+	}
+	for uri := range edit.Changes {
+		edits := edit.Changes[uri]
+		sort.Slice(edits, func(i, j int) bool { return before(edits[i].Range.Start, edits[j].Range.Start) })
+	}
+	return edit, nil
+}
+
+func before(a, b Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+}
+
+func unmarshalParams(raw json.RawMessage, v any) *responseError {
+	if len(raw) == 0 {
+		return &responseError{Code: codeInvalidParams, Message: "missing params"}
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// isIdentifier reports whether name is a plain JavaScript identifier that
+// is not a reserved word.
+func isIdentifier(name string) bool {
+	if name == "" || reservedWords[name] {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_' || r == '$' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 0x7f:
+		case i > 0 && r >= '0' && r <= '9':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+var reservedWords = map[string]bool{
+	"break": true, "case": true, "catch": true, "class": true, "const": true, "continue": true,
+	"debugger": true, "default": true, "delete": true, "do": true, "else": true, "export": true,
+	"extends": true, "false": true, "finally": true, "for": true, "function": true, "if": true,
+	"import": true, "in": true, "instanceof": true, "let": true, "new": true, "null": true,
+	"return": true, "super": true, "switch": true, "this": true, "throw": true, "true": true,
+	"try": true, "typeof": true, "var": true, "void": true, "while": true, "with": true, "yield": true,
+}
@@ -0,0 +1,338 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-go-golems/go-go-goja/modules"
+	_ "github.com/go-go-golems/go-go-goja/modules/path"
+)
+
+const libSource = `__doc__("greet", {
+  summary: "Greets someone by name.",
+  params: [{ name: "name", type: "string", description: "Who to greet." }],
+  returns: { type: "string" },
+});
+function greet(name) {
+  return "hello " + name;
+}
+
+function helper() {
+  return 1;
+}
+
+__verb__("greet", {
+  short: "Say hello",
+  fields: { name: { argument: true, help: "Who to greet" } },
+});
+
+module.exports = { greet, helper };
+`
+
+const mainSource = `const lib = require("./lib");
+const { helper } = require("./lib");
+const path = require("path");
+
+function run(who) {
+  const joined = path.join("a", who);
+  return lib.greet(joined) + helper();
+}
+
+run("x");
+`
+
+// testClient drives a Server over in-memory pipes with framed JSON-RPC.
+type testClient struct {
+	t      *testing.T
+	out    *conn
+	client *conn
+	nextID int
+	// notifications collects server notifications seen while waiting for
+	// responses.
+	notifications []rawMessage
+	done          chan error
+}
+
+type rawMessage struct {
+	ID     *json.RawMessage `json:"id"`
+	Method string           `json:"method"`
+	Params json.RawMessage  `json:"params"`
+	Result json.RawMessage  `json:"result"`
+	Error  *responseError   `json:"error"`
+}
+
+func newTestClient(t *testing.T, root string) *testClient {
+	t.Helper()
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	server := NewServer(Options{Modules: []modules.NativeModule{modules.GetModule("path")}})
+	c := &testClient{
+		t:      t,
+		out:    newConn(clientIn, io.Discard),
+		client: newConn(strings.NewReader(""), clientOut),
+		done:   make(chan error, 1),
+	}
+	go func() {
+		c.done <- server.Serve(context.Background(), serverIn, serverOut)
+		_ = serverOut.Close()
+	}()
+	t.Cleanup(func() {
+		_ = clientOut.Close()
+		<-c.done
+	})
+	c.call("initialize", InitializeParams{RootURI: pathToURI(root)}, nil)
+	c.notify("initialized", struct{}{})
+	return c
+}
+
+func (c *testClient) notify(method string, params any) {
+	c.t.Helper()
+	require.NoError(c.t, c.client.notify(method, params))
+}
+
+// call sends a request and decodes its result into result. It returns the
+// JSON-RPC error, if any.
+func (c *testClient) call(method string, params any, result any) *responseError {
+	c.t.Helper()
+	c.nextID++
+	id := json.RawMessage(strings.TrimSpace(string(mustJSON(c.t, c.nextID))))
+	require.NoError(c.t, c.client.write(struct {
+		JSONRPC string           `json:"jsonrpc"`
+		ID      *json.RawMessage `json:"id"`
+		Method  string           `json:"method"`
+		Params  any              `json:"params"`
+	}{"2.0", &id, method, params}))
+	for {
+		msg := c.read()
+		if msg.ID == nil {
+			c.notifications = append(c.notifications, msg)
+			continue
+		}
+		require.Equal(c.t, string(id), string(*msg.ID))
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result != nil {
+			require.NoError(c.t, json.Unmarshal(msg.Result, result))
+		}
+		return nil
+	}
+}
+
+func (c *testClient) read() rawMessage {
+	c.t.Helper()
+	body, err := c.out.readBody()
+	require.NoError(c.t, err)
+	var msg rawMessage
+	require.NoError(c.t, json.Unmarshal(body, &msg))
+	return msg
+}
+
+// open sends didOpen for uri and returns the diagnostics published for it.
+func (c *testClient) open(uri, text string) []Diagnostic {
+	c.t.Helper()
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{
+		URI: uri, LanguageID: "javascript", Version: 1, Text: text,
+	}})
+	for {
+		msg := c.read()
+		if msg.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+		var params PublishDiagnosticsParams
+		require.NoError(c.t, json.Unmarshal(msg.Params, &params))
+		if params.URI == uri {
+			return params.Diagnostics
+		}
+	}
+}
+
+func mustJSON(t *testing.T, v any) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return data
+}
+
+func writeWorkspace(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, src := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte(src), 0o644))
+	}
+	return root
+}
+
+// positionOf returns the position of the n-th (0-based) occurrence of needle
+// in text, offset by delta bytes.
+func positionOf(t *testing.T, text, needle string, n, delta int) Position {
+	t.Helper()
+	offset := -1
+	for i := 0; i <= n; i++ {
+		next := strings.Index(text[offset+1:], needle)
+		require.GreaterOrEqual(t, next, 0, "occurrence %d of %q", i, needle)
+		offset += next + 1
+	}
+	offset += delta
+	line := strings.Count(text[:offset], "\n")
+	return Position{Line: line, Character: offset - (strings.LastIndex(text[:offset], "\n") + 1)}
+}
+
+func at(uri string, pos Position) TextDocumentPositionParams {
+	return TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: pos}
+}
+
+func TestDiagnosticsReportSyntaxVerbAndModuleErrors(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{"lib.js": libSource})
+	c := newTestClient(t, root)
+
+	diags := c.open(pathToURI(filepath.Join(root, "broken.js")), "let x = ;\n")
+	require.NotEmpty(t, diags)
+	require.Equal(t, SeverityError, diags[0].Severity)
+	require.Equal(t, Position{Line: 0, Character: 8}, diags[0].Range.Start)
+
+	diags = c.open(pathToURI(filepath.Join(root, "verbs.js")), "function a() {}\n__verb__(\"missing\", { short: \"x\" });\nconst m = require(\"nope\");\n")
+	require.Len(t, diags, 2)
+	require.Equal(t, "jsverbs", diags[0].Source)
+	require.Contains(t, diags[0].Message, `"missing"`)
+	require.Equal(t, 1, diags[0].Range.Start.Line)
+	require.Equal(t, SeverityWarning, diags[1].Severity)
+	require.Contains(t, diags[1].Message, "cannot find module nope")
+
+	require.Empty(t, c.open(pathToURI(filepath.Join(root, "lib.js")), libSource))
+}
+
+func TestCompletionIncludesNativeAndWorkspaceExports(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{"lib.js": libSource, "main.js": mainSource})
+	c := newTestClient(t, root)
+	uri := pathToURI(filepath.Join(root, "main.js"))
+
+	text := mainSource + "path.jo\nru\nlib.\nconst other = require(\"\");\n"
+	c.open(uri, text)
+
+	labels := func(list CompletionList) []string {
+		var out []string
+		for _, item := range list.Items {
+			out = append(out, item.Label)
+		}
+		return out
+	}
+
+	var list CompletionList
+	require.Nil(t, c.call("textDocument/completion", at(uri, positionOf(t, text, "path.jo", 0, len("path.jo"))), &list))
+	require.Equal(t, []string{"join"}, labels(list))
+	require.Contains(t, list.Items[0].Detail, "function join(...parts: string[]): string")
+
+	require.Nil(t, c.call("textDocument/completion", at(uri, positionOf(t, text, "lib.\n", 0, len("lib."))), &list))
+	require.Equal(t, []string{"greet", "helper"}, labels(list))
+	require.Contains(t, list.Items[0].Documentation.Value, "Greets someone by name.")
+
+	require.Nil(t, c.call("textDocument/completion", at(uri, positionOf(t, text, "\nru\n", 0, len("\nru"))), &list))
+	require.Contains(t, labels(list), "run")
+
+	require.Nil(t, c.call("textDocument/completion", at(uri, positionOf(t, text, `require("")`, 0, len(`require("`))), &list))
+	require.Contains(t, labels(list), "path")
+	require.Contains(t, labels(list), "./lib")
+}
+
+func TestHoverShowsJSDocVerbAndNativeSignatures(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{"lib.js": libSource, "main.js": mainSource})
+	c := newTestClient(t, root)
+	mainURI := pathToURI(filepath.Join(root, "main.js"))
+
+	var hover Hover
+	require.Nil(t, c.call("textDocument/hover", at(mainURI, positionOf(t, mainSource, "greet", 0, 1)), &hover))
+	require.Contains(t, hover.Contents.Value, "function greet(name)")
+	require.Contains(t, hover.Contents.Value, "Greets someone by name.")
+	require.Contains(t, hover.Contents.Value, "`name` *string* — Who to greet.")
+	require.Contains(t, hover.Contents.Value, "**jsverbs command** `lib greet` — Say hello")
+	require.Contains(t, hover.Contents.Value, "| `<name>` | string | Who to greet |")
+
+	require.Nil(t, c.call("textDocument/hover", at(mainURI, positionOf(t, mainSource, "path.join", 0, len("path."))), &hover))
+	require.Contains(t, hover.Contents.Value, "function join(...parts: string[]): string")
+	require.Contains(t, hover.Contents.Value, "native module `path`")
+
+	require.Nil(t, c.call("textDocument/hover", at(mainURI, positionOf(t, mainSource, "who", 1, 0)), &hover))
+	require.Contains(t, hover.Contents.Value, "(parameter) who")
+}
+
+func TestDefinitionFollowsRequireAndVerbNames(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{"lib.js": libSource, "main.js": mainSource})
+	c := newTestClient(t, root)
+	libURI := pathToURI(filepath.Join(root, "lib.js"))
+	mainURI := pathToURI(filepath.Join(root, "main.js"))
+
+	var locs []Location
+	require.Nil(t, c.call("textDocument/definition", at(mainURI, positionOf(t, mainSource, "greet", 0, 2)), &locs))
+	require.Equal(t, []Location{{URI: libURI, Range: Range{
+		Start: positionOf(t, libSource, "greet(name)", 0, 0), End: positionOf(t, libSource, "greet(name)", 0, 5),
+	}}}, locs)
+
+	require.Nil(t, c.call("textDocument/definition", at(mainURI, positionOf(t, mainSource, "helper()", 0, 0)), &locs))
+	require.Len(t, locs, 1)
+	require.Equal(t, libURI, locs[0].URI)
+	require.Equal(t, positionOf(t, libSource, "helper()", 0, 0), locs[0].Range.Start)
+
+	require.Nil(t, c.call("textDocument/definition", at(libURI, positionOf(t, libSource, `"greet", {`, 1, 2)), &locs))
+	require.Len(t, locs, 1)
+	require.Equal(t, positionOf(t, libSource, "greet(name)", 0, 0), locs[0].Range.Start)
+
+	require.Nil(t, c.call("textDocument/definition", at(mainURI, positionOf(t, mainSource, `"./lib"`, 0, 1)), &locs))
+	require.Equal(t, []Location{{URI: libURI}}, locs)
+}
+
+func TestReferencesAndRenameSpanTheWorkspace(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{"lib.js": libSource, "main.js": mainSource})
+	c := newTestClient(t, root)
+	libURI := pathToURI(filepath.Join(root, "lib.js"))
+	mainURI := pathToURI(filepath.Join(root, "main.js"))
+
+	var locs []Location
+	params := ReferenceParams{TextDocumentPositionParams: at(libURI, positionOf(t, libSource, "greet(name)", 0, 0))}
+	params.Context.IncludeDeclaration = true
+	require.Nil(t, c.call("textDocument/references", params, &locs))
+	// The declaration, the __verb__ name, the exports entry and lib.greet.
+	require.Len(t, locs, 4)
+
+	var edit WorkspaceEdit
+	rename := RenameParams{TextDocumentPositionParams: at(mainURI, positionOf(t, mainSource, "helper", 1, 0)), NewName: "assist"}
+	require.Nil(t, c.call("textDocument/rename", rename, &edit))
+	require.Len(t, edit.Changes[libURI], 2)
+	require.Len(t, edit.Changes[mainURI], 2)
+	for _, e := range append(edit.Changes[libURI], edit.Changes[mainURI]...) {
+		require.Equal(t, "assist", e.NewText)
+	}
+
+	rename = RenameParams{TextDocumentPositionParams: at(libURI, positionOf(t, libSource, `"greet", {`, 1, 1)), NewName: "welcome"}
+	require.Nil(t, c.call("textDocument/rename", rename, &edit))
+	require.Len(t, edit.Changes[libURI], 3)
+	require.Len(t, edit.Changes[mainURI], 1)
+	verbName := positionOf(t, libSource, `"greet", {`, 1, 1)
+	require.Contains(t, edit.Changes[libURI], TextEdit{
+		Range: Range{Start: verbName, End: Position{Line: verbName.Line, Character: verbName.Character + len("greet")}}, NewText: "welcome",
+	})
+
+	rename = RenameParams{TextDocumentPositionParams: at(mainURI, positionOf(t, mainSource, "path.join", 0, len("path."))), NewName: "concat"}
+	rpcErr := c.call("textDocument/rename", rename, &edit)
+	require.NotNil(t, rpcErr)
+	require.Contains(t, rpcErr.Message, "native module path")
+
+	rename.NewName = "not valid"
+	require.NotNil(t, c.call("textDocument/rename", rename, &edit))
+}
+
+func TestShutdownThenExitEndsServe(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{})
+	c := newTestClient(t, root)
+	require.Nil(t, c.call("shutdown", nil, nil))
+	c.notify("exit", nil)
+	require.NoError(t, <-c.done)
+	c.done <- nil
+}
@@ -0,0 +1,425 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-go-golems/go-go-goja/pkg/inspector/analysis"
+	"github.com/go-go-golems/go-go-goja/pkg/jsdoc/model"
+	"github.com/go-go-golems/go-go-goja/pkg/jsparse"
+)
+
+// symbolKind classifies what a position in a document refers to.
+type symbolKind int
+
+const (
+	// symbolLocal is a binding that cannot be seen from other files.
+	symbolLocal symbolKind = iota
+	// symbolTop is a top-level name of a document, which other documents
+	// may reach through require() when it is exported.
+	symbolTop
+	// symbolNative is an export of a native module.
+	symbolNative
+	// symbolModule is a require() specifier.
+	symbolModule
+)
+
+// symbol is the target of hover, definition, references and rename.
+type symbol struct {
+	kind symbolKind
+	// doc is the document that declares the symbol (symbolLocal, symbolTop).
+	doc     *document
+	name    string
+	binding *jsparse.BindingRecord
+	// imported is set for local bindings introduced by require(): a module
+	// alias or a { key: local } pattern.
+	imported *importBinding
+	// module is the require() specifier (symbolNative, symbolModule).
+	module string
+}
+
+// symbolAt resolves what the identifier or string at offset (0-based bytes)
+// refers to, and returns the range of that token.
+func (s *Server) symbolAt(d *document, offset int) (*symbol, Range, bool) {
+	if !d.parsed() {
+		return nil, Range{}, false
+	}
+	node := tokenAt(d, offset+1)
+	if node == nil {
+		return nil, Range{}, false
+	}
+	tokenRange := d.nodeRange(node)
+	if node.Kind == "StringLiteral" {
+		sym := s.stringSymbol(d, node)
+		return sym, tokenRange, sym != nil
+	}
+	sym := s.identifierSymbol(d, node)
+	return sym, tokenRange, sym != nil
+}
+
+// tokenAt returns the Identifier or StringLiteral at a 1-based offset. A
+// cursor just past the end of a token still selects it.
+func tokenAt(d *document, offset int) *jsparse.NodeRecord {
+	for _, candidate := range []int{offset, offset - 1} {
+		n := d.analysis.Index.NodeAtOffset(candidate)
+		if n != nil && (n.Kind == "Identifier" || n.Kind == "StringLiteral") {
+			return n
+		}
+	}
+	return nil
+}
+
+func (s *Server) identifierSymbol(d *document, node *jsparse.NodeRecord) *symbol {
+	res := d.analysis.Resolution
+	name := d.nodeText(node)
+	if b := res.BindingForNode(node.ID); b != nil {
+		if imp, ok := d.importFor(b); ok {
+			// { name } = require(...) declares a local with the export's
+			// name, so it is the export.
+			if imp.export != "" && imp.keyNode == nil {
+				return s.exportSymbol(d, imp.module, imp.export)
+			}
+			return &symbol{kind: symbolLocal, doc: d, name: name, binding: b, imported: &imp}
+		}
+		if b.ScopeID == res.RootScopeID {
+			return &symbol{kind: symbolTop, doc: d, name: name, binding: b}
+		}
+		return &symbol{kind: symbolLocal, doc: d, name: name, binding: b}
+	}
+
+	parent := d.analysis.Index.Nodes[node.ParentID]
+	if parent == nil || parent.Kind != "DotExpression" || len(parent.ChildIDs) != 2 || parent.ChildIDs[1] != node.ID {
+		return nil
+	}
+	object := d.analysis.Index.Nodes[parent.ChildIDs[0]]
+	if isExportsObject(d, object) {
+		return topSymbol(d, name)
+	}
+	if object.Kind != "Identifier" {
+		return nil
+	}
+	if imp, ok := d.importFor(res.BindingForNode(object.ID)); ok && imp.export == "" {
+		return s.exportSymbol(d, imp.module, name)
+	}
+	return nil
+}
+
+func (s *Server) stringSymbol(d *document, node *jsparse.NodeRecord) *symbol {
+	if site, ok := d.verbs.siteAt(node.Start); ok {
+		return topSymbol(d, site.name)
+	}
+	for _, imp := range d.imports {
+		if imp.specNode == node {
+			return &symbol{kind: symbolModule, module: imp.module, doc: d}
+		}
+		if imp.keyNode == node {
+			return s.exportSymbol(d, imp.module, imp.export)
+		}
+	}
+	for _, site := range exportSites(d) {
+		if site.node == node {
+			return topSymbol(d, site.name)
+		}
+	}
+	return nil
+}
+
+// exportSymbol resolves export name of the module from requires.
+func (s *Server) exportSymbol(from *document, module, name string) *symbol {
+	if target := s.workspace.resolve(from, module); target != nil {
+		return topSymbol(target, name)
+	}
+	if s.natives.module(module) != nil {
+		return &symbol{kind: symbolNative, module: module, name: name}
+	}
+	return nil
+}
+
+// topSymbol returns the top-level name of d. The binding is nil when the
+// name is only assigned to exports.
+func topSymbol(d *document, name string) *symbol {
+	sym := &symbol{kind: symbolTop, doc: d, name: name}
+	if d.parsed() {
+		if root := d.analysis.Resolution.Scopes[d.analysis.Resolution.RootScopeID]; root != nil {
+			sym.binding = root.Bindings[name]
+		}
+	}
+	return sym
+}
+
+// location is a range in a document.
+type location struct {
+	doc *document
+	rng Range
+}
+
+// references lists every place sym is named. Declarations are included when
+// includeDeclaration is set.
+func (s *Server) references(sym *symbol, includeDeclaration bool) []location {
+	var out []location
+	seen := map[string]bool{}
+	add := func(d *document, rng Range) {
+		key := fmt.Sprintf("%s:%d:%d", d.uri, rng.Start.Line, rng.Start.Character)
+		if !seen[key] {
+			seen[key] = true
+			out = append(out, location{doc: d, rng: rng})
+		}
+	}
+	addBinding := func(d *document, b *jsparse.BindingRecord) {
+		for _, xref := range analysis.CrossReferencesForBinding(b, d.analysis.Index) {
+			if xref.NodeID == b.DeclNodeID && !includeDeclaration {
+				continue
+			}
+			add(d, d.nodeRange(d.analysis.Index.Nodes[xref.NodeID]))
+		}
+	}
+	// addImported adds the uses of export name through imports.
+	addImported := func(d *document, imp importBinding, name string) {
+		switch {
+		case imp.export == name && imp.keyNode != nil:
+			add(d, d.nodeRange(imp.keyNode))
+		case imp.export == name && imp.binding != nil:
+			addBinding(d, imp.binding)
+		case imp.export == "" && imp.binding != nil:
+			for _, n := range memberUses(d, imp.binding, name) {
+				add(d, d.nodeRange(n))
+			}
+		}
+	}
+
+	switch sym.kind {
+	case symbolLocal, symbolModule:
+		if sym.binding != nil {
+			addBinding(sym.doc, sym.binding)
+		}
+	case symbolTop:
+		d := sym.doc
+		if sym.binding != nil {
+			addBinding(d, sym.binding)
+		}
+		for _, site := range exportSites(d) {
+			if site.name == sym.name && (includeDeclaration || sym.binding != nil) {
+				add(d, d.nodeRange(site.node))
+			}
+		}
+		if site, ok := d.verbs.site("__verb__", sym.name); ok && site.nameNode != nil {
+			add(d, innerStringRange(d, site.nameNode))
+		}
+		for _, imp := range s.workspace.importersOf(d) {
+			addImported(imp.doc, imp.imp, sym.name)
+		}
+	case symbolNative:
+		target := s.natives.module(sym.module)
+		for _, d := range s.workspace.sorted() {
+			for _, imp := range d.imports {
+				if s.workspace.resolve(d, imp.module) == nil && s.natives.module(imp.module) == target {
+					addImported(d, imp, sym.name)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// memberUses returns the property identifiers of alias.name expressions.
+func memberUses(d *document, alias *jsparse.BindingRecord, name string) []*jsparse.NodeRecord {
+	idx := d.analysis.Index
+	var out []*jsparse.NodeRecord
+	for id := jsparse.NodeID(0); int(id) < len(idx.Nodes); id++ {
+		n := idx.Nodes[id]
+		if n == nil || n.Kind != "DotExpression" || len(n.ChildIDs) != 2 {
+			continue
+		}
+		object, property := idx.Nodes[n.ChildIDs[0]], idx.Nodes[n.ChildIDs[1]]
+		if object.Kind == "Identifier" && d.analysis.Resolution.BindingForNode(object.ID) == alias && d.nodeText(property) == name {
+			out = append(out, property)
+		}
+	}
+	return out
+}
+
+// innerStringRange is the range of a string literal without its quotes.
+func innerStringRange(d *document, n *jsparse.NodeRecord) Range {
+	return d.spanRange(n.Start, n.End-2)
+}
+
+// definition returns where sym is declared.
+func (s *Server) definition(sym *symbol) (location, bool) {
+	switch sym.kind {
+	case symbolLocal:
+		if sym.imported != nil {
+			if sym.imported.export == "" {
+				return s.definition(&symbol{kind: symbolModule, module: sym.imported.module, doc: sym.doc})
+			}
+			if target := s.exportSymbol(sym.doc, sym.imported.module, sym.imported.export); target != nil {
+				return s.definition(target)
+			}
+			return location{}, false
+		}
+		return bindingLocation(sym.doc, sym.binding)
+	case symbolTop:
+		if sym.binding != nil {
+			return bindingLocation(sym.doc, sym.binding)
+		}
+		for _, site := range exportSites(sym.doc) {
+			if site.name == sym.name {
+				return location{doc: sym.doc, rng: sym.doc.nodeRange(site.node)}, true
+			}
+		}
+	case symbolModule:
+		if target := s.workspace.resolve(sym.doc, sym.module); target != nil {
+			return location{doc: target}, true
+		}
+	case symbolNative:
+	}
+	return location{}, false
+}
+
+func bindingLocation(d *document, b *jsparse.BindingRecord) (location, bool) {
+	if b == nil {
+		return location{}, false
+	}
+	n := d.analysis.Index.Nodes[b.DeclNodeID]
+	if n == nil {
+		return location{}, false
+	}
+	return location{doc: d, rng: d.nodeRange(n)}, true
+}
+
+// hover renders Markdown describing sym.
+func (s *Server) hover(sym *symbol) string {
+	switch sym.kind {
+	case symbolNative:
+		if m := s.natives.module(sym.module); m != nil {
+			if e, ok := m.exports[sym.name]; ok {
+				return e.markdown(m.name)
+			}
+			return fmt.Sprintf("`%s` is not a known export of native module `%s`.", sym.name, m.name)
+		}
+	case symbolModule:
+		return s.moduleHover(sym.doc, sym.module)
+	case symbolLocal:
+		if sym.imported != nil {
+			if sym.imported.export == "" {
+				return s.moduleHover(sym.doc, sym.imported.module)
+			}
+			if target := s.exportSymbol(sym.doc, sym.imported.module, sym.imported.export); target != nil {
+				return s.hover(target)
+			}
+		}
+		return declarationHover(sym)
+	case symbolTop:
+		return declarationHover(sym)
+	}
+	return ""
+}
+
+func (s *Server) moduleHover(from *document, module string) string {
+	if target := s.workspace.resolve(from, module); target != nil {
+		text := fmt.Sprintf("```ts\nmodule %q\n```\n\n`%s`", module, target.rel)
+		if names := exportNames(target); len(names) > 0 {
+			text += "\n\nExports: " + strings.Join(names, ", ")
+		}
+		return text
+	}
+	if m := s.natives.module(module); m != nil {
+		text := fmt.Sprintf("```ts\nmodule %q\n```\n\nNative module.", m.name)
+		if m.doc != "" {
+			text += "\n\n" + strings.TrimSpace(m.doc)
+		}
+		return text
+	}
+	return fmt.Sprintf("```ts\nmodule %q\n```\n\nModule not found in the workspace or the native module registry.", module)
+}
+
+// declarationHover shows a declaration's signature, its jsdoc __doc__ entry
+// and, for functions exposed with __verb__, the command metadata.
+func declarationHover(sym *symbol) string {
+	d := sym.doc
+	var sb strings.Builder
+	sb.WriteString("```js\n")
+	sb.WriteString(declarationSignature(d, sym))
+	sb.WriteString("\n```\n")
+	if sym.kind == symbolTop && d.docs != nil {
+		for _, doc := range d.docs.Symbols {
+			if doc.Name == sym.name {
+				sb.WriteString("\n")
+				sb.WriteString(symbolDocMarkdown(doc))
+				break
+			}
+		}
+	}
+	if sym.kind == symbolTop {
+		if verb := d.verbs.verb(sym.name); verb != nil {
+			sb.WriteString("\n")
+			sb.WriteString(verbMarkdown(verb))
+		}
+	}
+	if sym.kind == symbolTop && d.rel != "" {
+		fmt.Fprintf(&sb, "\nDefined in `%s`.", d.rel)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// declarationSignature renders "function name(params)" for functions and
+// "kind name" for other bindings.
+func declarationSignature(d *document, sym *symbol) string {
+	b := sym.binding
+	if b == nil {
+		return "exports." + sym.name
+	}
+	if b.Kind == jsparse.BindingFunction {
+		decl := d.analysis.Index.Nodes[b.DeclNodeID]
+		if fn := d.analysis.Index.Nodes[decl.ParentID]; fn != nil && fn.Kind == "FunctionLiteral" {
+			for _, childID := range fn.ChildIDs {
+				if params := d.analysis.Index.Nodes[childID]; params.Kind == "ParameterList" {
+					return "function " + sym.name + d.nodeText(params)
+				}
+			}
+		}
+		return "function " + sym.name + "()"
+	}
+	kind := b.Kind.String()
+	if b.Kind == jsparse.BindingParameter {
+		kind = "(parameter)"
+	}
+	return kind + " " + sym.name
+}
+
+// symbolDocMarkdown renders a jsdoc __doc__ entry.
+func symbolDocMarkdown(doc *model.SymbolDoc) string {
+	var sb strings.Builder
+	if doc.Summary != "" {
+		sb.WriteString(doc.Summary + "\n")
+	}
+	if len(doc.Params) > 0 {
+		sb.WriteString("\n")
+		for _, p := range doc.Params {
+			line := "- `" + p.Name + "`"
+			if p.Type != "" {
+				line += " *" + p.Type + "*"
+			}
+			if p.Description != "" {
+				line += " — " + p.Description
+			}
+			sb.WriteString(line + "\n")
+		}
+	}
+	if doc.Returns.Type != "" || doc.Returns.Description != "" {
+		line := "\nReturns"
+		if doc.Returns.Type != "" {
+			line += " *" + doc.Returns.Type + "*"
+		}
+		if doc.Returns.Description != "" {
+			line += " — " + doc.Returns.Description
+		}
+		sb.WriteString(line + "\n")
+	}
+	if doc.Prose != "" {
+		sb.WriteString("\n" + strings.TrimSpace(doc.Prose) + "\n")
+	}
+	if len(doc.Tags) > 0 {
+		sb.WriteString("\nTags: " + strings.Join(doc.Tags, ", ") + "\n")
+	}
+	return sb.String()
+}
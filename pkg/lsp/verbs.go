@@ -0,0 +1,198 @@
+package lsp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-go-golems/go-go-goja/pkg/jsparse"
+	"github.com/go-go-golems/go-go-goja/pkg/jsverbs"
+)
+
+// verbInfo is the jsverbs view of a document: the registry scanned from its
+// text and the sentinel calls (__verb__, __section__, __package__) with their
+// positions, which the registry does not record.
+type verbInfo struct {
+	registry *jsverbs.Registry
+	scanErr  error
+	sites    []verbSite
+}
+
+type verbSite struct {
+	sentinel string
+	// name is the first string argument, the function name for __verb__ and
+	// the slug for __section__.
+	name     string
+	call     *jsparse.NodeRecord
+	nameNode *jsparse.NodeRecord
+}
+
+func scanVerbs(d *document) *verbInfo {
+	info := &verbInfo{}
+	options := jsverbs.DefaultScanOptions()
+	options.FailOnErrorDiagnostics = false
+	rel := d.rel
+	if rel == "" {
+		rel = d.path
+	}
+	info.registry, info.scanErr = jsverbs.ScanSource(rel, d.text, options)
+	if !d.parsed() {
+		return info
+	}
+	idx := d.analysis.Index
+	for id := jsparse.NodeID(0); int(id) < len(idx.Nodes); id++ {
+		call := idx.Nodes[id]
+		if call == nil || call.Kind != "CallExpression" || len(call.ChildIDs) == 0 {
+			continue
+		}
+		callee := idx.Nodes[call.ChildIDs[0]]
+		if callee.Kind != "Identifier" {
+			continue
+		}
+		sentinel := d.nodeText(callee)
+		if sentinel != "__verb__" && sentinel != "__section__" && sentinel != "__package__" {
+			continue
+		}
+		site := verbSite{sentinel: sentinel, call: call}
+		if len(call.ChildIDs) > 1 {
+			if arg := idx.Nodes[call.ChildIDs[1]]; arg.Kind == "StringLiteral" {
+				site.name = stringLiteralValue(d.nodeText(arg))
+				site.nameNode = arg
+			}
+		}
+		info.sites = append(info.sites, site)
+	}
+	return info
+}
+
+// verb returns the verb declared for a function name.
+func (v *verbInfo) verb(functionName string) *jsverbs.VerbSpec {
+	if v == nil || v.registry == nil {
+		return nil
+	}
+	for _, verb := range v.registry.Verbs() {
+		if verb.FunctionName == functionName {
+			return verb
+		}
+	}
+	return nil
+}
+
+// site returns the sentinel call whose string argument is name.
+func (v *verbInfo) site(sentinel, name string) (verbSite, bool) {
+	if v == nil {
+		return verbSite{}, false
+	}
+	for _, s := range v.sites {
+		if s.sentinel == sentinel && s.name == name {
+			return s, true
+		}
+	}
+	return verbSite{}, false
+}
+
+// siteAt returns the __verb__ call whose name string contains offset, a
+// 1-based jsparse offset.
+func (v *verbInfo) siteAt(offset int) (verbSite, bool) {
+	if v == nil {
+		return verbSite{}, false
+	}
+	for _, s := range v.sites {
+		if s.sentinel == "__verb__" && s.nameNode != nil && offset >= s.nameNode.Start && offset < s.nameNode.End {
+			return s, true
+		}
+	}
+	return verbSite{}, false
+}
+
+// verbDiagnostics reports jsverbs scan problems at the sentinel call they
+// concern. Problems that cannot be placed are reported on the first line.
+func verbDiagnostics(d *document) []Diagnostic {
+	v := d.verbs
+	if v == nil {
+		return nil
+	}
+	var out []Diagnostic
+	place := func(symbol string) Range {
+		for _, s := range v.sites {
+			if symbol != "" && s.name == symbol {
+				return d.nodeRange(s.call)
+			}
+		}
+		for _, s := range v.sites {
+			if symbol == "" && s.sentinel == "__package__" {
+				return d.nodeRange(s.call)
+			}
+		}
+		return Range{}
+	}
+	if v.registry != nil {
+		for _, diag := range v.registry.Diagnostics {
+			severity := SeverityError
+			if diag.Severity == jsverbs.DiagnosticSeverityWarning {
+				severity = SeverityWarning
+			}
+			out = append(out, Diagnostic{Range: place(diag.Symbol), Severity: severity, Source: "jsverbs", Message: diag.Message})
+		}
+	}
+	if v.scanErr != nil {
+		// Errors returned by the scan (e.g. a __verb__ naming a function that
+		// does not exist) quote the function they are about.
+		symbol := ""
+		for _, s := range v.sites {
+			if s.name != "" && strings.Contains(v.scanErr.Error(), fmt.Sprintf("%q", s.name)) {
+				symbol = s.name
+				break
+			}
+		}
+		out = append(out, Diagnostic{Range: place(symbol), Severity: SeverityError, Source: "jsverbs", Message: v.scanErr.Error()})
+	}
+	return out
+}
+
+// verbMarkdown describes a verb for hovers.
+func verbMarkdown(verb *jsverbs.VerbSpec) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "**jsverbs command** `%s`", verb.FullPath())
+	if verb.Short != "" {
+		fmt.Fprintf(&sb, " — %s", verb.Short)
+	}
+	sb.WriteString("\n")
+	if verb.Long != "" && verb.Long != verb.Short {
+		fmt.Fprintf(&sb, "\n%s\n", verb.Long)
+	}
+	if verb.OutputMode != "" {
+		fmt.Fprintf(&sb, "\nOutput: `%s`\n", verb.OutputMode)
+	}
+	if len(verb.Fields) > 0 {
+		names := make([]string, 0, len(verb.Fields))
+		for name := range verb.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		sb.WriteString("\n| Field | Type | Help |\n|---|---|---|\n")
+		for _, name := range names {
+			field := verb.Fields[name]
+			flag := "--" + name
+			if field.Argument {
+				flag = "<" + name + ">"
+			}
+			typ := field.Type
+			if typ == "" {
+				typ = "string"
+			}
+			help := field.Help
+			if field.Default != nil {
+				help = strings.TrimSpace(fmt.Sprintf("%s (default %v)", help, field.Default))
+			}
+			if len(field.Choices) > 0 {
+				help = strings.TrimSpace(fmt.Sprintf("%s [%s]", help, strings.Join(field.Choices, ", ")))
+			}
+			fmt.Fprintf(&sb, "| `%s` | %s | %s |\n", flag, typ, help)
+		}
+	}
+	if len(verb.UseSections) > 0 {
+		fmt.Fprintf(&sb, "\nSections: %s\n", strings.Join(verb.UseSections, ", "))
+	}
+	return sb.String()
+}
@@ -0,0 +1,171 @@
+package lsp
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// scriptExtensions are the file extensions loaded into the workspace.
+var scriptExtensions = []string{".js", ".cjs", ".mjs"}
+
+// workspace holds every document the server knows: the scripts found under
+// the root directory plus whatever the editor has opened.
+type workspace struct {
+	root string
+	docs map[string]*document
+}
+
+func newWorkspace() *workspace {
+	return &workspace{docs: map[string]*document{}}
+}
+
+// load scans root for scripts. Open documents are kept; their editor text
+// wins over the file on disk.
+func (w *workspace) load(root string) {
+	w.root = root
+	if root == "" {
+		return
+	}
+	_ = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			name := entry.Name()
+			if path != root && (name == "node_modules" || strings.HasPrefix(name, ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isScript(path) {
+			return nil
+		}
+		uri := pathToURI(path)
+		if existing, ok := w.docs[uri]; ok && existing.open {
+			return nil
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		w.docs[uri] = newDocument(uri, path, w.rel(path), string(src), 0, nil)
+		return nil
+	})
+}
+
+// open records editor-owned text for uri.
+func (w *workspace) open(uri, text string, version int) *document {
+	path := uriToPath(uri)
+	d := newDocument(uri, path, w.rel(path), text, version, w.docs[uri])
+	d.open = true
+	w.docs[uri] = d
+	return d
+}
+
+// close hands uri back to the file on disk, or forgets it when it lives
+// outside the workspace root.
+func (w *workspace) close(uri string) {
+	d, ok := w.docs[uri]
+	if !ok {
+		return
+	}
+	if w.root != "" && isScript(d.path) && strings.HasPrefix(d.path, w.root+string(filepath.Separator)) {
+		if src, err := os.ReadFile(d.path); err == nil {
+			w.docs[uri] = newDocument(uri, d.path, d.rel, string(src), 0, nil)
+			return
+		}
+	}
+	delete(w.docs, uri)
+}
+
+func (w *workspace) get(uri string) *document {
+	return w.docs[uri]
+}
+
+// sorted returns the documents in path order so results are deterministic.
+func (w *workspace) sorted() []*document {
+	out := make([]*document, 0, len(w.docs))
+	for _, d := range w.docs {
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].path < out[j].path })
+	return out
+}
+
+// resolve finds the document a relative require() specifier in from names,
+// trying the Node.js extension and index.js fallbacks.
+func (w *workspace) resolve(from *document, spec string) *document {
+	if !isRelativeModule(spec) {
+		return nil
+	}
+	base := filepath.Join(filepath.Dir(from.path), filepath.FromSlash(spec))
+	candidates := []string{base}
+	for _, ext := range scriptExtensions {
+		candidates = append(candidates, base+ext)
+	}
+	for _, ext := range scriptExtensions {
+		candidates = append(candidates, filepath.Join(base, "index"+ext))
+	}
+	for _, candidate := range candidates {
+		if d, ok := w.docs[pathToURI(candidate)]; ok {
+			return d
+		}
+	}
+	return nil
+}
+
+// importer is a require() in doc that names another document.
+type importer struct {
+	doc *document
+	imp importBinding
+}
+
+// importersOf returns the imports of other documents that resolve to target.
+func (w *workspace) importersOf(target *document) []importer {
+	var out []importer
+	for _, d := range w.sorted() {
+		for _, imp := range d.imports {
+			if w.resolve(d, imp.module) == target {
+				out = append(out, importer{doc: d, imp: imp})
+			}
+		}
+	}
+	return out
+}
+
+// relativeSpec returns the require() specifier from one document to another.
+func relativeSpec(from, to *document) string {
+	rel, err := filepath.Rel(filepath.Dir(from.path), to.path)
+	if err != nil {
+		return ""
+	}
+	rel = filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel)))
+	if !strings.HasPrefix(rel, "../") {
+		rel = "./" + rel
+	}
+	return rel
+}
+
+func (w *workspace) rel(path string) string {
+	if w.root == "" {
+		return filepath.Base(path)
+	}
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.Base(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+func isScript(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, candidate := range scriptExtensions {
+		if ext == candidate {
+			return true
+		}
+	}
+	return false
+}
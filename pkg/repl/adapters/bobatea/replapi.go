@@ -9,6 +9,7 @@ import (
 	bobarepl "github.com/go-go-golems/bobatea/pkg/repl"
 	"github.com/go-go-golems/go-go-goja/pkg/docaccess"
 	"github.com/go-go-golems/go-go-goja/pkg/engine"
+	"github.com/go-go-golems/go-go-goja/pkg/jserrors"
 	"github.com/go-go-golems/go-go-goja/pkg/jsparse"
 	js "github.com/go-go-golems/go-go-goja/pkg/repl/evaluators/javascript"
 	"github.com/go-go-golems/go-go-goja/pkg/replapi"
@@ -115,9 +116,10 @@ func (a *REPLAPIAdapter) EvaluateStream(ctx context.Context, code string, emit f
 		}
 	}
 	if resp.Cell.Execution.Error != "" {
+		text := executionErrorText(resp.Cell.Execution)
 		emit(bobarepl.Event{
 			Kind:  bobarepl.EventStderr,
-			Props: map[string]any{"text": resp.Cell.Execution.Error, "append": resp.Cell.Execution.Error, "is_error": true},
+			Props: map[string]any{"text": text, "append": text, "is_error": true},
 		})
 	} else if resp.Cell.Execution.Result != "" {
 		emit(bobarepl.Event{
@@ -134,6 +136,23 @@ func (a *REPLAPIAdapter) EvaluateStream(ctx context.Context, code string, emit f
 	return nil
 }
 
+// executionErrorText formats the error of a cell with its code frame and
+// source-mapped stack.
+func executionErrorText(execution replsession.ExecutionReport) string {
+	var b strings.Builder
+	b.WriteString(execution.Error)
+	if execution.CodeFrame != "" {
+		b.WriteString("\n\n" + execution.CodeFrame)
+	}
+	if len(execution.Stack) > 0 {
+		b.WriteString("\n")
+		for _, frame := range execution.Stack {
+			b.WriteString("\n    at " + jserrors.Frame{Function: frame.Function, File: frame.File, Line: frame.Line, Column: frame.Column}.String())
+		}
+	}
+	return b.String()
+}
+
 func (a *REPLAPIAdapter) GetPrompt() string {
 	return "js>"
 }
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"strings"
 	"sync"
-	"time"
 
 	"github.com/dop251/goja"
 	"github.com/go-go-golems/bobatea/pkg/repl"
@@ -14,6 +13,7 @@ import (
 	ggjengine "github.com/go-go-golems/go-go-goja/pkg/engine"
 	"github.com/go-go-golems/go-go-goja/pkg/hashiplugin/host"
 	"github.com/go-go-golems/go-go-goja/pkg/jsparse"
+	ggjruntimeowner "github.com/go-go-golems/go-go-goja/pkg/runtimeowner"
 	"github.com/pkg/errors"
 )
 
@@ -325,41 +325,14 @@ func (e *Evaluator) waitForPromise(ctx context.Context, promise *goja.Promise) (
 		return promiseString(promise)
 	}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return "", ctx.Err()
-		default:
-		}
-
-		ret, err := e.ownedRuntime.Owner.Call(ctx, "javascript.promise-state", func(_ context.Context, vm *goja.Runtime) (any, error) {
-			return promiseSnapshot{
-				State:  promise.State(),
-				Result: promise.Result(),
-			}, nil
-		})
-		if err != nil {
-			return "", err
-		}
-		snapshot, ok := ret.(promiseSnapshot)
-		if !ok {
-			return "", errors.Errorf("unexpected promise snapshot type %T", ret)
-		}
-		switch snapshot.State {
-		case goja.PromiseStatePending:
-			time.Sleep(5 * time.Millisecond)
-			continue
-		case goja.PromiseStateRejected:
-			return "", errors.Errorf("Promise rejected: %s", valueString(snapshot.Result))
-		case goja.PromiseStateFulfilled:
-			return valueString(snapshot.Result), nil
-		}
+	settlement, err := ggjruntimeowner.AwaitPromise(ctx, e.ownedRuntime.Owner, "javascript.await-promise", promise)
+	if err != nil {
+		return "", err
 	}
-}
-
-type promiseSnapshot struct {
-	State  goja.PromiseState
-	Result goja.Value
+	if settlement.Rejected() {
+		return "", errors.Errorf("Promise rejected: %s", valueString(settlement.Value))
+	}
+	return valueString(settlement.Value), nil
 }
 
 func promiseString(promise *goja.Promise) (string, error) {
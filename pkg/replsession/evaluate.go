@@ -9,7 +9,9 @@ import (
 	"time"
 
 	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/pkg/jserrors"
 	"github.com/go-go-golems/go-go-goja/pkg/jsparse"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimeowner"
 	"github.com/pkg/errors"
 )
 
@@ -21,11 +23,6 @@ type executionOutcome struct {
 	HelperError    bool
 }
 
-type promiseSnapshot struct {
-	State  goja.PromiseState
-	Result goja.Value
-}
-
 // Evaluate runs one cell within an existing session.
 func (s *Service) Evaluate(ctx context.Context, sessionID string, source string) (*EvaluateResponse, error) {
 	state, err := s.getSession(sessionID)
@@ -339,11 +336,14 @@ func (s *Service) evaluateInstrumented(ctx context.Context, state *sessionState,
 		return nil, errors.Wrap(refreshErr, "refresh binding runtime details")
 	}
 
+	stack, codeFrame := exceptionViews(execErr, rewrite.TransformedSource)
 	cell.Execution = ExecutionReport{
 		Status:      executionStatus(execErr, outcome.HelperError),
 		Result:      outcome.LastValue,
 		ResultJSON:  outcome.LastValueJSON,
 		Error:       errorString(execErr),
+		Stack:       stack,
+		CodeFrame:   codeFrame,
 		DurationMS:  duration.Milliseconds(),
 		Awaited:     outcome.Awaited,
 		Console:     consoleEvents,
@@ -425,11 +425,14 @@ func (s *Service) evaluateRaw(ctx context.Context, state *sessionState, cell *Ce
 		}
 	}
 
+	stack, codeFrame := exceptionViews(execErr, rewrite.TransformedSource)
 	cell.Execution = ExecutionReport{
 		Status:     executionStatus(execErr, false),
 		Result:     outcome.LastValue,
 		ResultJSON: outcome.LastValueJSON,
 		Error:      errorString(execErr),
+		Stack:      stack,
+		CodeFrame:  codeFrame,
 		DurationMS: duration.Milliseconds(),
 		Awaited:    outcome.Awaited,
 		Console:    consoleEvents,
@@ -613,42 +616,20 @@ func (s *sessionState) runString(ctx context.Context, source string) (goja.Value
 }
 
 func (s *sessionState) waitPromise(ctx context.Context, promise *goja.Promise) (goja.Value, error) {
-	for {
-		select {
-		case <-ctx.Done():
-			return nil, evaluationContextError(ctx)
-		default:
-		}
-
-		ret, err := s.runtime.Owner.Call(ctx, "replsession.promise-state", func(_ context.Context, vm *goja.Runtime) (any, error) {
-			return promiseSnapshot{State: promise.State(), Result: promise.Result()}, nil
-		})
-		if err != nil {
-			if cause := evaluationContextError(ctx); cause != nil {
-				return nil, cause
-			}
-			return nil, err
-		}
-		snapshot, ok := ret.(promiseSnapshot)
-		if !ok {
-			return nil, fmt.Errorf("unexpected promise snapshot type %T", ret)
+	settlement, err := runtimeowner.AwaitPromise(ctx, s.runtime.Owner, "replsession.await-promise", promise)
+	if err != nil {
+		if cause := evaluationContextError(ctx); cause != nil {
+			return nil, cause
 		}
-		switch snapshot.State {
-		case goja.PromiseStatePending:
-			select {
-			case <-ctx.Done():
-				return nil, evaluationContextError(ctx)
-			case <-time.After(5 * time.Millisecond):
-			}
-			continue
-		case goja.PromiseStateRejected:
-			return nil, fmt.Errorf("promise rejected: %s", rejectionMessage(snapshot.Result, s.runtime.VM))
-		case goja.PromiseStateFulfilled:
-			return snapshot.Result, nil
-		default:
-			time.Sleep(5 * time.Millisecond)
+		return nil, err
+	}
+	if settlement.Rejected() {
+		return nil, &promiseRejection{
+			message:   rejectionMessage(settlement.Value, s.runtime.VM),
+			exception: jserrors.FromValue(settlement.Value),
 		}
 	}
+	return settlement.Value, nil
 }
 
 func (s *sessionState) persistWrappedReturn(ctx context.Context, value goja.Value, bindingsKey string, lastKey string) ([]string, string, string, bool, error) {
@@ -818,3 +799,41 @@ func rejectionMessage(value goja.Value, vm *goja.Runtime) string {
 	}
 	return gojaValuePreview(value, vm)
 }
+
+// promiseRejection is the error of a cell whose promise was rejected. It
+// keeps the rejection value's stack for the execution report.
+type promiseRejection struct {
+	message   string
+	exception *jserrors.Exception
+}
+
+func (e *promiseRejection) Error() string { return "promise rejected: " + e.message }
+
+// exceptionViews returns the source-mapped stack and the code frame of the
+// JavaScript error in err. Frames of the cell itself are named <eval>, so
+// their code frame is cut from the source that ran.
+func exceptionViews(err error, source string) ([]StackFrameView, string) {
+	var exception *jserrors.Exception
+	var rejection *promiseRejection
+	if errors.As(err, &rejection) {
+		exception = rejection.exception
+	} else {
+		exception = jserrors.Find(err)
+	}
+	if exception == nil {
+		return nil, ""
+	}
+	stack := make([]StackFrameView, 0, len(exception.Frames))
+	for _, frame := range exception.Frames {
+		stack = append(stack, StackFrameView{Function: frame.Function, File: frame.File, Line: frame.Line, Column: frame.Column})
+	}
+	if exception.CodeFrame != "" {
+		return stack, exception.CodeFrame
+	}
+	for _, frame := range exception.Frames {
+		if frame.File == "<eval>" && frame.Line > 0 {
+			return stack, jserrors.CodeFrame(source, frame.Line, frame.Column)
+		}
+	}
+	return stack, ""
+}
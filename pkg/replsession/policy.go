@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"strings"
 	"time"
+
+	"github.com/go-go-golems/go-go-goja/pkg/runtimeowner"
 )
 
 // EvalMode controls how source is executed inside a session.
@@ -39,11 +41,23 @@ type PersistPolicy struct {
 	BindingDocs     bool `json:"bindingDocs"`
 }
 
+// BudgetPolicy bounds the resources one runtime call may use. Unlike
+// EvalPolicy.TimeoutMS, which bounds a whole cell, the budget applies to every
+// call the session makes into its runtime. Zero fields are unlimited.
+// MaxHeapGrowthBytes measures the heap of the whole process, so it only
+// applies reliably while the process runs a single session.
+type BudgetPolicy struct {
+	MaxExecutionMS     int64  `json:"maxExecutionMs,omitempty"`
+	MaxHeapGrowthBytes uint64 `json:"maxHeapGrowthBytes,omitempty"`
+	MaxStackDepth      int    `json:"maxStackDepth,omitempty"`
+}
+
 // SessionPolicy is the full behavior policy for one session.
 type SessionPolicy struct {
 	Eval    EvalPolicy    `json:"eval"`
 	Observe ObservePolicy `json:"observe"`
 	Persist PersistPolicy `json:"persist"`
+	Budget  BudgetPolicy  `json:"budget"`
 }
 
 // SessionOptions configures one live REPL session.
@@ -127,6 +141,12 @@ func NormalizeSessionPolicy(policy SessionPolicy) SessionPolicy {
 	if normalized.Eval.TimeoutMS < 0 {
 		normalized.Eval.TimeoutMS = 0
 	}
+	if normalized.Budget.MaxExecutionMS < 0 {
+		normalized.Budget.MaxExecutionMS = 0
+	}
+	if normalized.Budget.MaxStackDepth < 0 {
+		normalized.Budget.MaxStackDepth = 0
+	}
 	return normalized
 }
 
@@ -138,6 +158,18 @@ func (p EvalPolicy) Timeout() time.Duration {
 	return time.Duration(p.TimeoutMS) * time.Millisecond
 }
 
+// RuntimeBudget converts the policy into the runtime owner budget.
+func (p BudgetPolicy) RuntimeBudget() runtimeowner.Budget {
+	budget := runtimeowner.Budget{MaxHeapGrowth: p.MaxHeapGrowthBytes}
+	if p.MaxExecutionMS > 0 {
+		budget.MaxExecutionTime = time.Duration(p.MaxExecutionMS) * time.Millisecond
+	}
+	if p.MaxStackDepth > 0 {
+		budget.MaxStackDepth = p.MaxStackDepth
+	}
+	return budget
+}
+
 // IsZero reports whether no explicit policy fields were set.
 func (p SessionPolicy) IsZero() bool {
 	return p == (SessionPolicy{})
@@ -12,6 +12,7 @@ import (
 	inspectorruntime "github.com/go-go-golems/go-go-goja/pkg/inspector/runtime"
 	"github.com/go-go-golems/go-go-goja/pkg/jsparse"
 	"github.com/go-go-golems/go-go-goja/pkg/repldb"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimeowner"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
@@ -206,7 +207,11 @@ func (s *Service) CreateSessionWithOptions(ctx context.Context, opts SessionOpti
 	}
 
 	sessionCtx, sessionCancel := context.WithCancelCause(s.lifetimeCtx)
-	rt, err := s.factory.NewRuntime(engine.WithStartupContext(ctx), engine.WithLifetimeContext(sessionCtx))
+	rt, err := s.factory.NewRuntime(
+		engine.WithStartupContext(ctx),
+		engine.WithLifetimeContext(sessionCtx),
+		engine.WithBudget(resolved.Policy.Budget.RuntimeBudget()),
+	)
 	if err != nil {
 		sessionCancel(err)
 		return nil, errors.Wrap(err, "create runtime")
@@ -590,6 +595,9 @@ func executionStatus(err error, helperError bool) string {
 	if errors.Is(err, ErrEvaluationTimeout) || errors.Is(err, context.DeadlineExceeded) {
 		return "timeout"
 	}
+	if errors.Is(err, runtimeowner.ErrBudgetExceeded) {
+		return "budget-exceeded"
+	}
 	if err != nil {
 		return "runtime-error"
 	}
@@ -151,6 +151,30 @@ func TestServiceThrowNewErrorPreservesMessage(t *testing.T) {
 	}
 }
 
+func TestServiceThrowReportsStackAndCodeFrame(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	service := NewService(newPersistenceTestFactory(t), zerolog.Nop(), WithDefaultSessionOptions(RawSessionOptions()))
+
+	session, err := service.CreateSession(ctx)
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	resp, err := service.Evaluate(ctx, session.ID, "function fail() {\n  throw new Error('boom');\n}\nfail();")
+	if err != nil {
+		t.Fatalf("evaluate throw: %v", err)
+	}
+	stack := resp.Cell.Execution.Stack
+	if len(stack) != 2 || stack[0] != (StackFrameView{Function: "fail", File: "<eval>", Line: 2, Column: 9}) {
+		t.Fatalf("unexpected stack: %+v", stack)
+	}
+	if !strings.Contains(resp.Cell.Execution.CodeFrame, "> 2 |   throw new Error('boom');\n    |         ^") {
+		t.Fatalf("unexpected code frame:\n%s", resp.Cell.Execution.CodeFrame)
+	}
+}
+
 func TestServiceThrowStringPreservesMessage(t *testing.T) {
 	t.Parallel()
 
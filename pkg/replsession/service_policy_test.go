@@ -208,3 +208,48 @@ func TestServiceInteractiveSyncRunawayTimeoutKeepsSessionUsable(t *testing.T) {
 		t.Fatalf("expected one tracked binding after recovery, got %d", next.Session.BindingCount)
 	}
 }
+
+func TestServiceBudgetPolicyStopsRunawayCell(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	opts := RawSessionOptions()
+	opts.Policy.Budget = BudgetPolicy{MaxExecutionMS: 50, MaxStackDepth: 200}
+
+	service := NewService(newPersistenceTestFactory(t), zerolog.Nop(), WithDefaultSessionOptions(opts))
+
+	session, err := service.CreateSession(ctx)
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	for _, src := range []string{"while (true) {}", "(function f() { return f() })()"} {
+		resp, err := service.Evaluate(ctx, session.ID, src)
+		if err != nil {
+			t.Fatalf("evaluate %q: %v", src, err)
+		}
+		if resp.Cell.Execution.Status != "budget-exceeded" {
+			t.Fatalf("%q: expected budget-exceeded status, got %q (%s)", src, resp.Cell.Execution.Status, resp.Cell.Execution.Error)
+		}
+	}
+
+	next, err := service.Evaluate(ctx, session.ID, "1 + 1")
+	if err != nil {
+		t.Fatalf("evaluate after budget: %v", err)
+	}
+	if next.Cell.Execution.Status != "ok" || next.Cell.Execution.Result != "2" {
+		t.Fatalf("expected session usable after budget, got %q %q", next.Cell.Execution.Status, next.Cell.Execution.Result)
+	}
+}
+
+func TestNormalizeSessionPolicyClampsNegativeBudget(t *testing.T) {
+	t.Parallel()
+
+	policy := NormalizeSessionPolicy(SessionPolicy{Budget: BudgetPolicy{MaxExecutionMS: -1, MaxStackDepth: -5}})
+	if policy.Budget != (BudgetPolicy{}) {
+		t.Fatalf("expected negative budget fields to normalize to zero, got %+v", policy.Budget)
+	}
+	if !policy.Budget.RuntimeBudget().IsZero() {
+		t.Fatalf("expected zero runtime budget")
+	}
+}
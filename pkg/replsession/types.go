@@ -49,15 +49,26 @@ type CellReport struct {
 
 // ExecutionReport describes the actual runtime evaluation outcome.
 type ExecutionReport struct {
-	Status      string         `json:"status"`
-	Result      string         `json:"result"`
-	ResultJSON  string         `json:"resultJson,omitempty"`
-	Error       string         `json:"error,omitempty"`
-	DurationMS  int64          `json:"durationMs"`
-	Awaited     bool           `json:"awaited"`
-	Console     []ConsoleEvent `json:"console"`
-	HadSideFX   bool           `json:"hadSideEffects"`
-	HelperError bool           `json:"helperError"`
+	Status      string           `json:"status"`
+	Result      string           `json:"result"`
+	ResultJSON  string           `json:"resultJson,omitempty"`
+	Error       string           `json:"error,omitempty"`
+	Stack       []StackFrameView `json:"stack,omitempty"`
+	CodeFrame   string           `json:"codeFrame,omitempty"`
+	DurationMS  int64            `json:"durationMs"`
+	Awaited     bool             `json:"awaited"`
+	Console     []ConsoleEvent   `json:"console"`
+	HadSideFX   bool             `json:"hadSideEffects"`
+	HelperError bool             `json:"helperError"`
+}
+
+// StackFrameView is one frame of the stack of a thrown error, with positions
+// in source-mapped code reported in the original file.
+type StackFrameView struct {
+	Function string `json:"function,omitempty"`
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
 }
 
 // ConsoleEvent captures one console.* emission.
@@ -0,0 +1,128 @@
+package runtimebridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/dop251/goja"
+)
+
+// PromiseSettlement is the final state of a JavaScript promise observed from
+// Go. Value is the fulfillment value or the rejection reason.
+type PromiseSettlement struct {
+	State goja.PromiseState
+	Value goja.Value
+}
+
+// Fulfilled reports whether the promise resolved successfully.
+func (s PromiseSettlement) Fulfilled() bool {
+	return s.State == goja.PromiseStateFulfilled
+}
+
+// Rejected reports whether the promise was rejected.
+func (s PromiseSettlement) Rejected() bool {
+	return s.State == goja.PromiseStateRejected
+}
+
+// AwaitPromise blocks until promise settles or ctx is done. It must be called
+// from outside the owner goroutine: it attaches reactions with the intrinsic
+// Promise.prototype.then through owner.Call and then waits on a channel that
+// the reactions signal from the owner loop, so a pending promise costs no
+// owner-loop work while it waits.
+func AwaitPromise(ctx context.Context, owner RuntimeOwner, op string, promise *goja.Promise) (PromiseSettlement, error) {
+	if owner == nil {
+		return PromiseSettlement{}, errors.New("runtimebridge: missing owner")
+	}
+	if promise == nil {
+		return PromiseSettlement{State: goja.PromiseStateFulfilled, Value: goja.Undefined()}, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if op == "" {
+		op = "promise.await"
+	}
+
+	settled := make(chan PromiseSettlement, 1)
+	ret, err := owner.Call(ctx, op, func(_ context.Context, vm *goja.Runtime) (any, error) {
+		if state := promise.State(); state != goja.PromiseStatePending {
+			return PromiseSettlement{State: state, Value: promise.Result()}, nil
+		}
+		if err := attachSettlementReactions(vm, promise, settled); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return PromiseSettlement{}, err
+	}
+	if settlement, ok := ret.(PromiseSettlement); ok {
+		return settlement, nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return PromiseSettlement{}, ctx.Err()
+	case settlement := <-settled:
+		return settlement, nil
+	}
+}
+
+var promiseThenByVM sync.Map
+
+// CaptureIntrinsics records the built-in Promise.prototype.then of vm, which
+// AwaitPromise uses to observe settlement. Store and
+// runtimeowner.NewRuntimeOwner call it when a runtime is initialized, before
+// any script can replace the method; the first capture for a VM wins.
+func CaptureIntrinsics(vm *goja.Runtime) {
+	if vm != nil {
+		intrinsicPromiseThen(vm)
+	}
+}
+
+func intrinsicPromiseThen(vm *goja.Runtime) (goja.Callable, bool) {
+	if value, ok := promiseThenByVM.Load(vm); ok {
+		return value.(goja.Callable), true
+	}
+	ctor, ok := vm.Get("Promise").(*goja.Object)
+	if !ok {
+		return nil, false
+	}
+	proto, ok := ctor.Get("prototype").(*goja.Object)
+	if !ok {
+		return nil, false
+	}
+	then, ok := goja.AssertFunction(proto.Get("then"))
+	if !ok {
+		return nil, false
+	}
+	value, _ := promiseThenByVM.LoadOrStore(vm, then)
+	return value.(goja.Callable), true
+}
+
+// attachSettlementReactions registers the reactions through the intrinsic
+// Promise.prototype.then, so a script that overrides `then` on the promise or
+// its prototype cannot intercept or suppress the settlement.
+func attachSettlementReactions(vm *goja.Runtime, promise *goja.Promise, settled chan<- PromiseSettlement) error {
+	promiseObj := vm.ToValue(promise).ToObject(vm)
+	then, ok := intrinsicPromiseThen(vm)
+	if !ok {
+		return fmt.Errorf("runtimebridge: runtime has no Promise.prototype.then")
+	}
+	notify := func(state goja.PromiseState) func(goja.FunctionCall) goja.Value {
+		return func(call goja.FunctionCall) goja.Value {
+			select {
+			case settled <- PromiseSettlement{State: state, Value: call.Argument(0)}:
+			default:
+			}
+			return goja.Undefined()
+		}
+	}
+	_, err := then(promiseObj,
+		vm.ToValue(notify(goja.PromiseStateFulfilled)),
+		vm.ToValue(notify(goja.PromiseStateRejected)),
+	)
+	return err
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/dop251/goja"
 	"github.com/dop251/goja_nodejs/eventloop"
+	"github.com/go-go-golems/go-go-goja/pkg/sandbox"
 )
 
 // RuntimeOwner is the owner-thread scheduling subset exposed to modules that
@@ -27,6 +28,12 @@ type RuntimeServices struct {
 	LifetimeContext context.Context
 	Loop            *eventloop.EventLoop
 	Owner           RuntimeOwner
+	// Sandbox limits what native modules may do on the script's behalf. Nil
+	// means unrestricted.
+	Sandbox *sandbox.Profile
+	// AddCloser registers cleanup that runs when the runtime is closed. Nil
+	// when the runtime does not support closers.
+	AddCloser func(func(context.Context) error) error
 }
 
 // Lifetime returns the runtime lifetime context, or context.Background when no
@@ -136,12 +143,15 @@ func (svc RuntimeServices) contextLinkedToLifetime(ctx context.Context) linkedCo
 
 var servicesByVM sync.Map
 
-// Store registers runtime services for a concrete VM.
+// Store registers runtime services for a concrete VM. It also captures the
+// intrinsic Promise.prototype.then that AwaitPromise uses, so call it before
+// any script runs on vm.
 func Store(vm *goja.Runtime, services RuntimeServices) {
 	if vm == nil {
 		return
 	}
 	servicesByVM.Store(vm, services)
+	CaptureIntrinsics(vm)
 }
 
 // Lookup returns the services registered for a concrete VM.
@@ -160,6 +170,13 @@ func Lookup(vm *goja.Runtime) (RuntimeServices, bool) {
 	return services, true
 }
 
+// Sandbox returns the sandbox profile registered for vm, or nil when the
+// runtime is unrestricted.
+func Sandbox(vm *goja.Runtime) *sandbox.Profile {
+	services, _ := Lookup(vm)
+	return services.Sandbox
+}
+
 // Delete removes runtime services for a concrete VM.
 func Delete(vm *goja.Runtime) {
 	if vm == nil {
@@ -167,6 +184,7 @@ func Delete(vm *goja.Runtime) {
 	}
 	servicesByVM.Delete(vm)
 	callContextsByVM.Delete(vm)
+	promiseThenByVM.Delete(vm)
 }
 
 type callContextStack struct {
@@ -0,0 +1,222 @@
+package runtimeowner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"runtime/metrics"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// budgetSampleInterval is how often the heap is sampled while a budgeted call
+// runs.
+const budgetSampleInterval = 5 * time.Millisecond
+
+// Budget bounds the resources a single owner call may use. Zero fields are
+// unlimited. Every Call and Post is guarded, including the callbacks modules
+// post when timers fire or I/O completes and the promise jobs those queue.
+// Functions run on the scheduler directly with RunOnLoop bypass the owner and
+// are not guarded.
+type Budget struct {
+	// MaxExecutionTime interrupts a call that keeps the VM busy for longer.
+	MaxExecutionTime time.Duration
+	// MaxHeapGrowth interrupts a call once the Go heap has grown by more than
+	// this many bytes since the call started. The heap is sampled for the
+	// whole process, not per runtime, so the ceiling only holds in a process
+	// that runs one runtime at a time. With a runtime pool, several REPL
+	// sessions or any other concurrent runtimes, one runtime's allocations
+	// count against every budgeted call in flight and can interrupt the wrong
+	// one; bound those with MaxExecutionTime, or run untrusted scripts in a
+	// process of their own.
+	MaxHeapGrowth uint64
+	// MaxStackDepth caps the JavaScript call depth.
+	MaxStackDepth int
+}
+
+// IsZero reports whether the budget sets no limits.
+func (b Budget) IsZero() bool { return b == Budget{} }
+
+// merge returns b with the non-zero fields of override applied.
+func (b Budget) merge(override Budget) Budget {
+	if override.MaxExecutionTime > 0 {
+		b.MaxExecutionTime = override.MaxExecutionTime
+	}
+	if override.MaxHeapGrowth > 0 {
+		b.MaxHeapGrowth = override.MaxHeapGrowth
+	}
+	if override.MaxStackDepth > 0 {
+		b.MaxStackDepth = override.MaxStackDepth
+	}
+	return b
+}
+
+// BudgetResource names the limit a BudgetExceededError refers to.
+type BudgetResource string
+
+const (
+	BudgetExecutionTime BudgetResource = "execution-time"
+	BudgetHeapGrowth    BudgetResource = "heap-growth"
+	BudgetStackDepth    BudgetResource = "stack-depth"
+)
+
+// BudgetExceededError is returned by Call when the call ran over its budget.
+// Limit and Observed are nanoseconds for execution time, bytes for heap
+// growth, and frames for stack depth. It matches ErrBudgetExceeded with
+// errors.Is.
+type BudgetExceededError struct {
+	Op       string
+	Resource BudgetResource
+	Limit    int64
+	Observed int64
+}
+
+func (e *BudgetExceededError) Error() string {
+	switch e.Resource {
+	case BudgetExecutionTime:
+		return fmt.Sprintf("runtimeowner %s: execution time budget of %s exceeded", e.Op, time.Duration(e.Limit))
+	case BudgetHeapGrowth:
+		return fmt.Sprintf("runtimeowner %s: heap growth budget of %d bytes exceeded (grew %d bytes)", e.Op, e.Limit, e.Observed)
+	default:
+		return fmt.Sprintf("runtimeowner %s: %s budget of %d exceeded", e.Op, e.Resource, e.Limit)
+	}
+}
+
+func (e *BudgetExceededError) Is(target error) bool { return target == ErrBudgetExceeded }
+
+type budgetCtxKey struct{}
+
+// WithBudget returns a context whose owner calls use budget. Non-zero fields
+// override the owner's Options.Budget; zero fields keep the owner default.
+func WithBudget(ctx context.Context, budget Budget) context.Context {
+	return context.WithValue(normalizeContext(ctx), budgetCtxKey{}, budget)
+}
+
+func (r *runtimeOwner) budgetFor(ctx context.Context) Budget {
+	budget := r.opts.Budget
+	if override, ok := ctx.Value(budgetCtxKey{}).(Budget); ok {
+		budget = budget.merge(override)
+	}
+	return budget
+}
+
+// guard applies the call budget to the outermost owner invocation and returns
+// the function that ends it. Nested invocations run inside the outer budget.
+func (r *runtimeOwner) guard(ctx context.Context, op string) func(error) error {
+	budget := r.budgetFor(ctx)
+	if r.guarding || budget.IsZero() {
+		return func(err error) error { return err }
+	}
+	r.guarding = true
+	if budget.MaxStackDepth > 0 {
+		r.vm.SetMaxCallStackSize(budget.MaxStackDepth)
+	}
+	var watchdog *budgetWatchdog
+	if budget.MaxExecutionTime > 0 || budget.MaxHeapGrowth > 0 {
+		watchdog = startBudgetWatchdog(r.vm, op, budget)
+	}
+	return func(err error) error {
+		r.guarding = false
+		if budget.MaxStackDepth > 0 {
+			r.vm.SetMaxCallStackSize(r.defaultStackDepth())
+		}
+		if watchdog != nil {
+			if exceeded := watchdog.stop(); exceeded != nil {
+				r.vm.ClearInterrupt()
+				if err != nil {
+					return exceeded
+				}
+			}
+		}
+		var overflow *goja.StackOverflowError
+		if budget.MaxStackDepth > 0 && errors.As(err, &overflow) {
+			return &BudgetExceededError{Op: op, Resource: BudgetStackDepth, Limit: int64(budget.MaxStackDepth), Observed: int64(budget.MaxStackDepth)}
+		}
+		return err
+	}
+}
+
+func (r *runtimeOwner) defaultStackDepth() int {
+	if r.opts.Budget.MaxStackDepth > 0 {
+		return r.opts.Budget.MaxStackDepth
+	}
+	return math.MaxInt32
+}
+
+// budgetWatchdog interrupts the VM when a call runs out of time or heap.
+type budgetWatchdog struct {
+	done     chan struct{}
+	exited   chan struct{}
+	mu       sync.Mutex
+	exceeded *BudgetExceededError
+}
+
+func startBudgetWatchdog(vm *goja.Runtime, op string, budget Budget) *budgetWatchdog {
+	w := &budgetWatchdog{done: make(chan struct{}), exited: make(chan struct{})}
+	baseline := heapBytes()
+	started := time.Now()
+	go func() {
+		defer close(w.exited)
+		var deadline, sample <-chan time.Time
+		if budget.MaxExecutionTime > 0 {
+			timer := time.NewTimer(budget.MaxExecutionTime - time.Since(started))
+			defer timer.Stop()
+			deadline = timer.C
+		}
+		if budget.MaxHeapGrowth > 0 {
+			ticker := time.NewTicker(budgetSampleInterval)
+			defer ticker.Stop()
+			sample = ticker.C
+		}
+		for {
+			select {
+			case <-w.done:
+				return
+			case <-deadline:
+				w.interrupt(vm, &BudgetExceededError{Op: op, Resource: BudgetExecutionTime, Limit: int64(budget.MaxExecutionTime), Observed: int64(time.Since(started))})
+				return
+			case <-sample:
+				if grown := heapBytes() - baseline; grown > int64(budget.MaxHeapGrowth) {
+					w.interrupt(vm, &BudgetExceededError{Op: op, Resource: BudgetHeapGrowth, Limit: int64(budget.MaxHeapGrowth), Observed: grown})
+					return
+				}
+			}
+		}
+	}()
+	return w
+}
+
+func (w *budgetWatchdog) interrupt(vm *goja.Runtime, err *BudgetExceededError) {
+	w.mu.Lock()
+	w.exceeded = err
+	w.mu.Unlock()
+	vm.Interrupt(err)
+}
+
+// stop ends the watchdog and reports the budget it tripped, if any.
+func (w *budgetWatchdog) stop() *BudgetExceededError {
+	close(w.done)
+	<-w.exited
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.exceeded
+}
+
+var heapSample = []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+var heapSampleMu sync.Mutex
+
+// heapBytes reads the live Go heap of the whole process without stopping the
+// world. Go has no per-goroutine heap accounting, hence the single-runtime
+// caveat on Budget.MaxHeapGrowth.
+func heapBytes() int64 {
+	heapSampleMu.Lock()
+	defer heapSampleMu.Unlock()
+	metrics.Read(heapSample)
+	if heapSample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return int64(heapSample[0].Value.Uint64())
+}
@@ -0,0 +1,81 @@
+package runtimeowner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+func newBudgetOwner(t *testing.T, budget Budget) RuntimeOwner {
+	t.Helper()
+	vm := goja.New()
+	s := newQueueScheduler(vm)
+	t.Cleanup(s.Close)
+	return NewRuntimeOwner(vm, s, Options{Name: "budget-test", Budget: budget})
+}
+
+func runScript(owner RuntimeOwner, ctx context.Context, src string) (any, error) {
+	return owner.Call(ctx, "budget.run", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		v, err := vm.RunString(src)
+		if err != nil {
+			return nil, err
+		}
+		return v.Export(), nil
+	})
+}
+
+func requireBudgetExceeded(t *testing.T, err error, resource BudgetResource) {
+	t.Helper()
+	var exceeded *BudgetExceededError
+	if !errors.As(err, &exceeded) || !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected BudgetExceededError, got %T %v", err, err)
+	}
+	if exceeded.Resource != resource {
+		t.Fatalf("resource=%s, want %s", exceeded.Resource, resource)
+	}
+}
+
+func TestBudgetInterruptsLongRunningCall(t *testing.T) {
+	owner := newBudgetOwner(t, Budget{MaxExecutionTime: 20 * time.Millisecond})
+	_, err := runScript(owner, context.Background(), "for (;;) {}")
+	requireBudgetExceeded(t, err, BudgetExecutionTime)
+
+	// The interrupt is cleared, so the runtime stays usable.
+	v, err := runScript(owner, context.Background(), "1 + 1")
+	if err != nil || v != int64(2) {
+		t.Fatalf("runtime unusable after interrupt: %v %v", v, err)
+	}
+}
+
+func TestBudgetLimitsStackDepth(t *testing.T) {
+	owner := newBudgetOwner(t, Budget{MaxStackDepth: 64})
+	_, err := runScript(owner, context.Background(), "function f(n) { return f(n + 1) } f(0)")
+	requireBudgetExceeded(t, err, BudgetStackDepth)
+
+	v, err := runScript(owner, context.Background(), "function g(n) { return n === 0 ? 0 : g(n - 1) } g(10)")
+	if err != nil || v != int64(0) {
+		t.Fatalf("shallow recursion failed: %v %v", v, err)
+	}
+}
+
+func TestBudgetLimitsHeapGrowth(t *testing.T) {
+	owner := newBudgetOwner(t, Budget{MaxHeapGrowth: 8 << 20})
+	_, err := runScript(owner, context.Background(), "const keep = []; for (;;) { keep.push(new Array(1024).fill(keep.length)) }")
+	requireBudgetExceeded(t, err, BudgetHeapGrowth)
+}
+
+func TestWithBudgetOverridesOwnerDefault(t *testing.T) {
+	owner := newBudgetOwner(t, Budget{})
+	ctx := WithBudget(context.Background(), Budget{MaxExecutionTime: 20 * time.Millisecond})
+	_, err := runScript(owner, ctx, "for (;;) {}")
+	requireBudgetExceeded(t, err, BudgetExecutionTime)
+
+	// Nested calls run inside the outer budget instead of starting their own.
+	_, err = owner.Call(ctx, "budget.outer", func(ctx context.Context, vm *goja.Runtime) (any, error) {
+		return runScript(owner, WithBudget(ctx, Budget{MaxExecutionTime: time.Hour}), "for (;;) {}")
+	})
+	requireBudgetExceeded(t, err, BudgetExecutionTime)
+}
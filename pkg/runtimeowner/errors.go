@@ -7,4 +7,5 @@ var (
 	ErrScheduleRejected = errors.New("runtime schedule rejected")
 	ErrCanceled         = errors.New("runtime call canceled")
 	ErrPanicked         = errors.New("runtime call panicked")
+	ErrBudgetExceeded   = errors.New("runtime budget exceeded")
 )
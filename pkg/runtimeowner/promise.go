@@ -0,0 +1,45 @@
+package runtimeowner
+
+import (
+	"context"
+
+	"github.com/dop251/goja"
+	"github.com/go-go-golems/go-go-goja/pkg/runtimebridge"
+)
+
+type bridgeOwner struct {
+	owner RuntimeOwner
+}
+
+func (o bridgeOwner) Call(ctx context.Context, op string, fn func(context.Context, *goja.Runtime) (any, error)) (any, error) {
+	return o.owner.Call(ctx, op, CallFunc(fn))
+}
+
+func (o bridgeOwner) Post(ctx context.Context, op string, fn func(context.Context, *goja.Runtime)) error {
+	return o.owner.Post(ctx, op, PostFunc(fn))
+}
+
+// Bridge adapts owner to the runtimebridge.RuntimeOwner subset used by native
+// modules and shared helpers such as runtimebridge.AwaitPromise.
+func Bridge(owner RuntimeOwner) runtimebridge.RuntimeOwner {
+	if owner == nil {
+		return nil
+	}
+	return bridgeOwner{owner: owner}
+}
+
+// Unbridge returns the owner that Bridge adapted, or nil when owner did not
+// come from Bridge. Code that only has the runtimebridge services of a VM
+// uses it to identify the runtime by the same owner the engine hands out.
+func Unbridge(owner runtimebridge.RuntimeOwner) RuntimeOwner {
+	if o, ok := owner.(bridgeOwner); ok {
+		return o.owner
+	}
+	return nil
+}
+
+// AwaitPromise waits for promise to settle without polling the owner loop.
+// See runtimebridge.AwaitPromise for the calling constraints.
+func AwaitPromise(ctx context.Context, owner RuntimeOwner, op string, promise *goja.Promise) (runtimebridge.PromiseSettlement, error) {
+	return runtimebridge.AwaitPromise(ctx, Bridge(owner), op, promise)
+}
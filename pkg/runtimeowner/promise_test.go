@@ -0,0 +1,135 @@
+package runtimeowner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+func TestAwaitPromiseWakesOnLaterResolution(t *testing.T) {
+	vm := goja.New()
+	s := newQueueScheduler(vm)
+	defer s.Close()
+
+	r := NewRuntimeOwner(vm, s, Options{RecoverPanics: true})
+	var resolve func(any) error
+	ret, err := r.Call(context.Background(), "test.promise.new", func(context.Context, *goja.Runtime) (any, error) {
+		promise, res, _ := vm.NewPromise()
+		resolve = res
+		return promise, nil
+	})
+	if err != nil {
+		t.Fatalf("Call returned error: %v", err)
+	}
+	promise := ret.(*goja.Promise)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = r.Post(context.Background(), "test.promise.resolve", func(context.Context, *goja.Runtime) {
+			_ = resolve("done")
+		})
+	}()
+
+	settlement, err := AwaitPromise(context.Background(), r, "test.promise.await", promise)
+	if err != nil {
+		t.Fatalf("AwaitPromise returned error: %v", err)
+	}
+	if !settlement.Fulfilled() || settlement.Value.String() != "done" {
+		t.Fatalf("settlement = %#v, want fulfilled done", settlement)
+	}
+}
+
+func TestAwaitPromiseReturnsSettledRejection(t *testing.T) {
+	vm := goja.New()
+	s := newQueueScheduler(vm)
+	defer s.Close()
+
+	r := NewRuntimeOwner(vm, s, Options{RecoverPanics: true})
+	ret, err := r.Call(context.Background(), "test.promise.reject", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		value, err := vm.RunString(`Promise.reject(new Error("boom"))`)
+		if err != nil {
+			return nil, err
+		}
+		return value.Export(), nil
+	})
+	if err != nil {
+		t.Fatalf("Call returned error: %v", err)
+	}
+
+	settlement, err := AwaitPromise(context.Background(), r, "test.promise.await", ret.(*goja.Promise))
+	if err != nil {
+		t.Fatalf("AwaitPromise returned error: %v", err)
+	}
+	if !settlement.Rejected() {
+		t.Fatalf("settlement state = %v, want rejected", settlement.State)
+	}
+}
+
+func TestAwaitPromiseHonorsContextCancellation(t *testing.T) {
+	vm := goja.New()
+	s := newQueueScheduler(vm)
+	defer s.Close()
+
+	r := NewRuntimeOwner(vm, s, Options{RecoverPanics: true})
+	ret, err := r.Call(context.Background(), "test.promise.pending", func(context.Context, *goja.Runtime) (any, error) {
+		promise, _, _ := vm.NewPromise()
+		return promise, nil
+	})
+	if err != nil {
+		t.Fatalf("Call returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = AwaitPromise(ctx, r, "test.promise.await", ret.(*goja.Promise))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("AwaitPromise error = %v, want deadline exceeded", err)
+	}
+}
+
+func TestAwaitPromiseIgnoresScriptOverriddenThen(t *testing.T) {
+	vm := goja.New()
+	s := newQueueScheduler(vm)
+	defer s.Close()
+
+	r := NewRuntimeOwner(vm, s, Options{RecoverPanics: true})
+	var resolve func(any) error
+	ret, err := r.Call(context.Background(), "test.promise.override", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		if _, err := vm.RunString(`Promise.prototype.then = function () { return this; }`); err != nil {
+			return nil, err
+		}
+		promise, res, _ := vm.NewPromise()
+		resolve = res
+		if err := vm.Set("pending", promise); err != nil {
+			return nil, err
+		}
+		if _, err := vm.RunString(`pending.then = function () { return pending; }`); err != nil {
+			return nil, err
+		}
+		return promise, nil
+	})
+	if err != nil {
+		t.Fatalf("Call returned error: %v", err)
+	}
+	promise := ret.(*goja.Promise)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = r.Post(context.Background(), "test.promise.resolve", func(context.Context, *goja.Runtime) {
+			_ = resolve("done")
+		})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	settlement, err := AwaitPromise(ctx, r, "test.promise.await", promise)
+	if err != nil {
+		t.Fatalf("AwaitPromise returned error: %v", err)
+	}
+	if !settlement.Fulfilled() || settlement.Value.String() != "done" {
+		t.Fatalf("settlement = %#v, want fulfilled done", settlement)
+	}
+}
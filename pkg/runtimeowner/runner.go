@@ -32,6 +32,7 @@ type runtimeOwner struct {
 	scheduler Scheduler
 	opts      Options
 	closed    atomic.Bool
+	guarding  bool // owner goroutine only
 
 	idleMu sync.Mutex
 	active int
@@ -47,6 +48,10 @@ func NewRuntimeOwner(vm *goja.Runtime, scheduler Scheduler, opts Options) Runtim
 	if opts.Name == "" {
 		opts.Name = "runtime"
 	}
+	if opts.Budget.MaxStackDepth > 0 {
+		vm.SetMaxCallStackSize(opts.Budget.MaxStackDepth)
+	}
+	runtimebridge.CaptureIntrinsics(vm)
 	return &runtimeOwner{vm: vm, scheduler: scheduler, opts: opts}
 }
 
@@ -186,9 +191,11 @@ func (r *runtimeOwner) Post(ctx context.Context, op string, fn PostFunc) error {
 	return nil
 }
 
-func (r *runtimeOwner) invoke(ctx context.Context, op string, fn CallFunc) (any, error) {
+func (r *runtimeOwner) invoke(ctx context.Context, op string, fn CallFunc) (_ any, err error) {
 	r.beginActive()
 	defer r.endActive()
+	endBudget := r.guard(ctx, op)
+	defer func() { err = endBudget(err) }()
 	return runtimebridge.WithCallContext(r.vm, ctx, func() (any, error) {
 		if !r.opts.RecoverPanics {
 			return fn(ctx, r.vm)
@@ -221,6 +228,8 @@ func recoveredPanicError(op string, rec any, includeStack bool) error {
 func (r *runtimeOwner) invokePost(ctx context.Context, op string, fn PostFunc) {
 	r.beginActive()
 	defer r.endActive()
+	endBudget := r.guard(ctx, op)
+	defer func() { _ = endBudget(nil) }()
 	_ = runtimebridge.WithCallContextVoid(r.vm, ctx, func() error {
 		if r.opts.RecoverPanics {
 			defer func() {
@@ -32,4 +32,7 @@ type Options struct {
 	MaxWait           int64 // milliseconds; <=0 disables implicit timeout
 	RecoverPanics     bool
 	IncludePanicStack bool // append runtime/debug.Stack() to recovered panic errors
+	// Budget limits every top-level Call and Post. WithBudget overrides it
+	// per call.
+	Budget Budget
 }
@@ -0,0 +1,67 @@
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dop251/goja"
+)
+
+// Capability names a kind of access a Profile can grant.
+type Capability string
+
+const (
+	CapabilityFSRead   Capability = "fs.read"
+	CapabilityFSWrite  Capability = "fs.write"
+	CapabilityExec     Capability = "exec"
+	CapabilityFetch    Capability = "fetch"
+	CapabilityEnv      Capability = "env"
+	CapabilityDatabase Capability = "database"
+)
+
+// ErrPermissionDenied matches every PermissionError with errors.Is.
+var ErrPermissionDenied = errors.New("sandbox permission denied")
+
+// PermissionError is returned when a profile does not grant a capability.
+// Resource is the path, command, origin, variable or driver that was denied.
+type PermissionError struct {
+	Profile    string
+	Capability Capability
+	Resource   string
+}
+
+func (e *PermissionError) Error() string {
+	if e.Profile != "" {
+		return fmt.Sprintf("sandbox profile %q denies %s access to %q", e.Profile, e.Capability, e.Resource)
+	}
+	return fmt.Sprintf("sandbox denies %s access to %q", e.Capability, e.Resource)
+}
+
+func (e *PermissionError) Is(target error) bool { return target == ErrPermissionDenied }
+
+// JSCode is the error code JavaScript sees on permission errors. It matches
+// Node's permission model.
+const JSCode = "ERR_ACCESS_DENIED"
+
+// JSError converts err into a JavaScript error value. Permission errors get
+// name "PermissionError", code ERR_ACCESS_DENIED and capability/resource
+// properties; other errors become plain Go errors.
+func JSError(vm *goja.Runtime, err error) *goja.Object {
+	obj := vm.NewGoError(err)
+	Decorate(obj, err)
+	return obj
+}
+
+// Decorate adds the permission properties to an existing error object. It
+// reports whether err was a PermissionError.
+func Decorate(obj *goja.Object, err error) bool {
+	var denied *PermissionError
+	if obj == nil || !errors.As(err, &denied) {
+		return false
+	}
+	_ = obj.Set("name", "PermissionError")
+	_ = obj.Set("code", JSCode)
+	_ = obj.Set("capability", string(denied.Capability))
+	_ = obj.Set("resource", denied.Resource)
+	return true
+}
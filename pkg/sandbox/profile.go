@@ -0,0 +1,378 @@
+// Package sandbox describes what a runtime's native modules may touch.
+//
+// A Profile grants capabilities: file system read and write roots, the
+// external commands exec may run, the origins fetch may contact, the
+// environment variables visible through process.env, and the database DSNs
+// the database module may open. A runtime without a profile is unrestricted;
+// a runtime with a profile is denied everything the profile does not grant.
+package sandbox
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Profile is a declarative set of capability grants, usually loaded from YAML:
+//
+//	name: reports
+//	fs:
+//	  read: [./data]
+//	  write: [/tmp/reports]
+//	exec:
+//	  - command: git
+//	    args: [log, "--format=*"]
+//	fetch:
+//	  origins: ["https://api.example.com", "https://*.example.org"]
+//	env: [HOME, "APP_*"]
+//	database:
+//	  - driver: sqlite3
+//	    dsn: "file:data/*.db"
+type Profile struct {
+	Name     string          `yaml:"name,omitempty"`
+	FS       FSGrant         `yaml:"fs,omitempty"`
+	Exec     []ExecGrant     `yaml:"exec,omitempty"`
+	Fetch    FetchGrant      `yaml:"fetch,omitempty"`
+	Env      []string        `yaml:"env,omitempty"`
+	Database []DatabaseGrant `yaml:"database,omitempty"`
+}
+
+// FSGrant lists directory roots. A path is granted when it is a root or lies
+// below one after symlinks are resolved. Write roots are readable as well.
+type FSGrant struct {
+	Read  []string `yaml:"read,omitempty"`
+	Write []string `yaml:"write,omitempty"`
+}
+
+// ExecGrant allows one command. Command is a name looked up on PATH or an
+// absolute path. When Args is set, every argument must match one of its glob
+// patterns; without Args any arguments are allowed.
+type ExecGrant struct {
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args,omitempty"`
+}
+
+// FetchGrant lists origins such as "https://api.example.com". A "*." host
+// prefix matches subdomains, a ":*" suffix matches any port, and "*" alone
+// matches every origin.
+type FetchGrant struct {
+	Origins []string `yaml:"origins,omitempty"`
+}
+
+// DatabaseGrant allows DSNs matching the DSN glob, optionally only for one
+// driver.
+type DatabaseGrant struct {
+	Driver string `yaml:"driver,omitempty"`
+	DSN    string `yaml:"dsn"`
+}
+
+// LoadProfile reads a YAML profile. Relative fs roots are resolved against the
+// directory that contains the file.
+func LoadProfile(path string) (*Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read sandbox profile: %w", err)
+	}
+	profile, err := ParseProfile(data, filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("sandbox profile %s: %w", path, err)
+	}
+	return profile, nil
+}
+
+// ParseProfile decodes a YAML profile. Relative fs roots are resolved against
+// baseDir, or the working directory when baseDir is empty. Unknown keys are
+// rejected so a misspelled grant does not silently deny or allow access.
+func ParseProfile(data []byte, baseDir string) (*Profile, error) {
+	var profile Profile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&profile); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if err := profile.normalize(baseDir); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (p *Profile) normalize(baseDir string) error {
+	if baseDir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		baseDir = wd
+	}
+	var err error
+	if p.FS.Read, err = resolveRoots(baseDir, p.FS.Read); err != nil {
+		return err
+	}
+	if p.FS.Write, err = resolveRoots(baseDir, p.FS.Write); err != nil {
+		return err
+	}
+	for i, grant := range p.Exec {
+		if strings.TrimSpace(grant.Command) == "" {
+			return fmt.Errorf("exec grant %d: command is required", i)
+		}
+	}
+	for i, grant := range p.Database {
+		if strings.TrimSpace(grant.DSN) == "" {
+			return fmt.Errorf("database grant %d: dsn is required", i)
+		}
+	}
+	for _, origin := range p.Fetch.Origins {
+		if origin != "*" && !strings.Contains(origin, "://") {
+			return fmt.Errorf("fetch origin %q must include a scheme", origin)
+		}
+	}
+	return nil
+}
+
+func resolveRoots(baseDir string, roots []string) ([]string, error) {
+	out := make([]string, 0, len(roots))
+	for _, root := range roots {
+		root = strings.TrimSpace(root)
+		if root == "" {
+			continue
+		}
+		if strings.HasPrefix(root, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, err
+			}
+			root = filepath.Join(home, root[2:])
+		}
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(baseDir, root)
+		}
+		out = append(out, resolvePath(root))
+	}
+	return out, nil
+}
+
+// resolvePath makes path absolute and resolves symlinks in the longest prefix
+// that exists, so a link inside a root cannot point outside it.
+func resolvePath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	rest := ""
+	for dir := abs; ; {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(resolved, rest)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return abs
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+		dir = parent
+	}
+}
+
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// CheckRead reports whether the profile grants reading path.
+func (p *Profile) CheckRead(path string) error {
+	if p == nil {
+		return nil
+	}
+	resolved := resolvePath(path)
+	for _, root := range append(append([]string(nil), p.FS.Read...), p.FS.Write...) {
+		if within(root, resolved) {
+			return nil
+		}
+	}
+	return p.deny(CapabilityFSRead, path)
+}
+
+// CheckWrite reports whether the profile grants creating, modifying or
+// removing path.
+func (p *Profile) CheckWrite(path string) error {
+	if p == nil {
+		return nil
+	}
+	resolved := resolvePath(path)
+	for _, root := range p.FS.Write {
+		if within(root, resolved) {
+			return nil
+		}
+	}
+	return p.deny(CapabilityFSWrite, path)
+}
+
+// CheckExec reports whether the profile grants running command with args.
+func (p *Profile) CheckExec(command string, args []string) error {
+	if p == nil {
+		return nil
+	}
+	for _, grant := range p.Exec {
+		if sameCommand(grant.Command, command) && argsAllowed(grant.Args, args) {
+			return nil
+		}
+	}
+	return p.deny(CapabilityExec, strings.TrimSpace(strings.Join(append([]string{command}, args...), " ")))
+}
+
+func sameCommand(granted, command string) bool {
+	if granted == command {
+		return true
+	}
+	grantedPath, err := exec.LookPath(granted)
+	if err != nil {
+		return false
+	}
+	commandPath, err := exec.LookPath(command)
+	if err != nil {
+		return false
+	}
+	return resolvePath(grantedPath) == resolvePath(commandPath)
+}
+
+func argsAllowed(patterns, args []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, arg := range args {
+		if !matchAny(patterns, arg) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckFetch reports whether the profile grants requests to u's origin.
+func (p *Profile) CheckFetch(u *url.URL) error {
+	if p == nil {
+		return nil
+	}
+	for _, pattern := range p.Fetch.Origins {
+		if originMatches(strings.TrimSpace(pattern), u) {
+			return nil
+		}
+	}
+	return p.deny(CapabilityFetch, u.Scheme+"://"+u.Host)
+}
+
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+func originMatches(pattern string, u *url.URL) bool {
+	if pattern == "*" {
+		return true
+	}
+	scheme, host, ok := strings.Cut(pattern, "://")
+	if !ok || !strings.EqualFold(scheme, u.Scheme) {
+		return false
+	}
+	hostPattern, portPattern, hasPort := strings.Cut(host, ":")
+	defaultPort := defaultPorts[strings.ToLower(u.Scheme)]
+	port := u.Port()
+	if port == "" {
+		port = defaultPort
+	}
+	if hasPort {
+		if portPattern != "*" && portPattern != port {
+			return false
+		}
+	} else if port != defaultPort {
+		return false
+	}
+	hostname := strings.ToLower(u.Hostname())
+	hostPattern = strings.ToLower(hostPattern)
+	if suffix, ok := strings.CutPrefix(hostPattern, "*."); ok {
+		return strings.HasSuffix(hostname, "."+suffix)
+	}
+	return hostname == hostPattern
+}
+
+// AllowsEnv reports whether the environment variable name is visible.
+func (p *Profile) AllowsEnv(name string) bool {
+	return p == nil || matchAny(p.Env, name)
+}
+
+// CheckEnv is AllowsEnv as an error, for callers that read one variable on
+// the script's behalf.
+func (p *Profile) CheckEnv(name string) error {
+	if p.AllowsEnv(name) {
+		return nil
+	}
+	return p.deny(CapabilityEnv, name)
+}
+
+// CheckDatabase reports whether the profile grants opening dsn with driver.
+func (p *Profile) CheckDatabase(driver, dsn string) error {
+	if p == nil {
+		return nil
+	}
+	for _, grant := range p.Database {
+		if grant.Driver != "" && grant.Driver != driver {
+			continue
+		}
+		if glob(grant.DSN, dsn) {
+			return nil
+		}
+	}
+	return p.deny(CapabilityDatabase, driver)
+}
+
+func (p *Profile) deny(capability Capability, resource string) error {
+	return &PermissionError{Profile: p.Name, Capability: capability, Resource: resource}
+}
+
+func matchAny(patterns []string, s string) bool {
+	for _, pattern := range patterns {
+		if glob(pattern, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// glob matches s against pattern, where "*" matches any run of characters
+// (including "/") and "?" matches one character.
+func glob(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if pattern == "" {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if glob(pattern, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if s == "" {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		default:
+			if s == "" || pattern[0] != s[0] {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		}
+	}
+	return s == ""
+}
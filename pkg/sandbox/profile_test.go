@@ -0,0 +1,119 @@
+package sandbox
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseProfileResolvesRootsAndRejectsUnknownKeys(t *testing.T) {
+	base := t.TempDir()
+	profile, err := ParseProfile([]byte("name: test\nfs:\n  read: [data]\n  write: [out]\n"), base)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if profile.Name != "test" || len(profile.FS.Read) != 1 || !filepath.IsAbs(profile.FS.Read[0]) {
+		t.Fatalf("unexpected profile: %+v", profile)
+	}
+	if err := profile.CheckRead(filepath.Join(base, "data", "x.csv")); err != nil {
+		t.Fatalf("read below root: %v", err)
+	}
+	if err := profile.CheckRead(filepath.Join(base, "out", "report.txt")); err != nil {
+		t.Fatalf("write roots should be readable: %v", err)
+	}
+	if err := profile.CheckWrite(filepath.Join(base, "data", "x.csv")); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("expected write outside write roots to be denied, got %v", err)
+	}
+	if err := profile.CheckRead(filepath.Join(base, "data", "..", "secret")); err == nil {
+		t.Fatalf("expected .. escape to be denied")
+	}
+
+	if _, err := ParseProfile([]byte("fs:\n  reed: [data]\n"), base); err == nil {
+		t.Fatalf("expected unknown key to fail")
+	}
+	if _, err := ParseProfile([]byte("exec:\n  - args: [x]\n"), base); err == nil {
+		t.Fatalf("expected exec grant without command to fail")
+	}
+	if _, err := ParseProfile([]byte("fetch:\n  origins: [example.com]\n"), base); err == nil {
+		t.Fatalf("expected origin without scheme to fail")
+	}
+}
+
+func TestCheckReadFollowsSymlinks(t *testing.T) {
+	base := t.TempDir()
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret"), []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(base, "data"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(base, "data", "link")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	profile := &Profile{FS: FSGrant{Read: []string{filepath.Join(base, "data")}}}
+	if err := profile.normalize(base); err != nil {
+		t.Fatal(err)
+	}
+	err := profile.CheckRead(filepath.Join(base, "data", "link", "secret"))
+	var denied *PermissionError
+	if !errors.As(err, &denied) || denied.Capability != CapabilityFSRead {
+		t.Fatalf("expected symlink escape to be denied as fs.read, got %v", err)
+	}
+}
+
+func TestProfileChecks(t *testing.T) {
+	profile := &Profile{
+		Exec:     []ExecGrant{{Command: "git", Args: []string{"log", "--format=*"}}, {Command: "echo"}},
+		Fetch:    FetchGrant{Origins: []string{"https://api.example.com", "https://*.example.org", "http://localhost:*"}},
+		Env:      []string{"HOME", "APP_*"},
+		Database: []DatabaseGrant{{Driver: "sqlite3", DSN: "file:data/*.db"}},
+	}
+
+	for _, tc := range []struct {
+		name    string
+		err     error
+		allowed bool
+	}{
+		{"exec git log", profile.CheckExec("git", []string{"log", "--format=%H"}), true},
+		{"exec git push", profile.CheckExec("git", []string{"push"}), false},
+		{"exec echo any", profile.CheckExec("echo", []string{"a", "b"}), true},
+		{"exec rm", profile.CheckExec("rm", []string{"-rf", "/"}), false},
+		{"fetch exact", profile.CheckFetch(mustURL(t, "https://api.example.com/v1")), true},
+		{"fetch explicit default port", profile.CheckFetch(mustURL(t, "https://api.example.com:443/v1")), true},
+		{"fetch other port", profile.CheckFetch(mustURL(t, "https://api.example.com:8443/v1")), false},
+		{"fetch subdomain", profile.CheckFetch(mustURL(t, "https://a.b.example.org/")), true},
+		{"fetch apex of wildcard", profile.CheckFetch(mustURL(t, "https://example.org/")), false},
+		{"fetch any port", profile.CheckFetch(mustURL(t, "http://localhost:8080/")), true},
+		{"fetch scheme", profile.CheckFetch(mustURL(t, "http://api.example.com/")), false},
+		{"env exact", profile.CheckEnv("HOME"), true},
+		{"env glob", profile.CheckEnv("APP_TOKEN"), true},
+		{"env denied", profile.CheckEnv("AWS_SECRET_ACCESS_KEY"), false},
+		{"database", profile.CheckDatabase("sqlite3", "file:data/app.db"), true},
+		{"database driver", profile.CheckDatabase("postgres", "file:data/app.db"), false},
+		{"database dsn", profile.CheckDatabase("sqlite3", "file:/etc/app.db"), false},
+	} {
+		if tc.allowed != (tc.err == nil) {
+			t.Errorf("%s: allowed=%v, err=%v", tc.name, tc.allowed, tc.err)
+		}
+		if tc.err != nil && !errors.Is(tc.err, ErrPermissionDenied) {
+			t.Errorf("%s: expected ErrPermissionDenied, got %v", tc.name, tc.err)
+		}
+	}
+
+	var unrestricted *Profile
+	if err := unrestricted.CheckExec("rm", nil); err != nil || !unrestricted.AllowsEnv("ANY") {
+		t.Fatalf("nil profile should allow everything, got %v", err)
+	}
+}
+
+func mustURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
@@ -84,6 +84,12 @@ func renderModule(module *spec.Module) (string, error) {
 	return sb.String(), nil
 }
 
+// Function renders a single function descriptor as the `export function`
+// declaration used inside a module block.
+func Function(fn spec.Function) (string, error) {
+	return renderFunction(fn)
+}
+
 func renderFunction(fn spec.Function) (string, error) {
 	name := strings.TrimSpace(fn.Name)
 	if name == "" {
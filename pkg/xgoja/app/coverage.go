@@ -0,0 +1,48 @@
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/go-go-goja/pkg/jscover"
+)
+
+// startCoverage returns a collector when --coverage is set, and nil
+// otherwise. finish writes the reports; call it once the runtime is closed.
+func startCoverage(vals *values.Values) (*jscover.Collector, func() error, error) {
+	dir, err := coverageDir(vals)
+	if err != nil || dir == "" {
+		return nil, func() error { return nil }, err
+	}
+	addr, err := debugAddr(vals)
+	if err != nil {
+		return nil, nil, err
+	}
+	if addr != "" {
+		return nil, nil, fmt.Errorf("--debug-addr and --coverage cannot be combined")
+	}
+	collector := jscover.New()
+	finish := func() error {
+		report := collector.Report()
+		if err := report.WriteDir(dir); err != nil {
+			return fmt.Errorf("write coverage: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "xgoja: coverage %s\nxgoja: coverage reports written to %s\n", report.Summary(), dir)
+		return nil
+	}
+	return collector, finish, nil
+}
+
+// rejectCoverage fails commands that evaluate code the collector cannot
+// instrument, instead of ignoring --coverage.
+func rejectCoverage(vals *values.Values, command string) error {
+	dir, err := coverageDir(vals)
+	if err != nil {
+		return err
+	}
+	if dir != "" {
+		return fmt.Errorf("--coverage is not supported by %s; use run, test or a jsverbs command", command)
+	}
+	return nil
+}
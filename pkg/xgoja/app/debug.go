@@ -0,0 +1,51 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/go-go-goja/pkg/jsdebug"
+)
+
+// startDebugger listens for a debug client when --debug-addr is set and
+// waits until it has configured its breakpoints. It returns a nil debugger
+// otherwise. finish reports how the program ended and stops listening.
+func startDebugger(ctx context.Context, vals *values.Values) (*jsdebug.Debugger, func(error), error) {
+	addr, err := debugAddr(vals)
+	if err != nil || addr == "" {
+		return nil, func(error) {}, err
+	}
+	dbg := jsdebug.New()
+	listener, err := jsdebug.Listen(addr, dbg)
+	if err != nil {
+		return nil, nil, err
+	}
+	fmt.Fprintf(os.Stderr, "xgoja: waiting for a debug client on %s\n", listener.Addr())
+	if err := dbg.WaitReady(ctx); err != nil {
+		_ = listener.Close(1)
+		return nil, nil, err
+	}
+	finish := func(runErr error) {
+		exitCode := 0
+		if runErr != nil {
+			exitCode = 1
+		}
+		_ = listener.Close(exitCode)
+	}
+	return dbg, finish, nil
+}
+
+// rejectDebugAddr fails commands that evaluate code the debugger cannot
+// instrument, instead of ignoring --debug-addr.
+func rejectDebugAddr(vals *values.Values, command string) error {
+	addr, err := debugAddr(vals)
+	if err != nil {
+		return err
+	}
+	if addr != "" {
+		return fmt.Errorf("--debug-addr is not supported by %s; use run or a jsverbs command", command)
+	}
+	return nil
+}
@@ -95,6 +95,8 @@ func (h *Host) AttachCommandPlan(root *cobra.Command, command CommandPlan) {
 		h.AttachRun(root)
 	case "builtin.repl":
 		h.AttachRepl(root)
+	case "builtin.test":
+		h.AttachTest(root)
 	case "builtin.jsverbs":
 		h.attachVerbCommandPlan(root, command)
 	case "provider.command-set":
@@ -132,6 +134,23 @@ func (h *Host) AttachRun(root *cobra.Command) {
 	root.AddCommand(cmd)
 }
 
+func (h *Host) AttachTest(root *cobra.Command) {
+	if root == nil || h == nil {
+		return
+	}
+	out := h.Out
+	if out == nil {
+		out = root.OutOrStdout()
+	}
+	cmd, err := buildGlazedCobraCommand(newTestCommand(h.Factory, h.RuntimePlan, out), h.MiddlewaresFunc)
+	if err != nil {
+		command, _ := h.RuntimePlan.commandByType("builtin.test")
+		root.AddCommand(commandErrorStub(commandName(command, "test"), "Run JavaScript and TypeScript test files in generated xgoja runtimes", err))
+		return
+	}
+	root.AddCommand(cmd)
+}
+
 func (h *Host) AttachRepl(root *cobra.Command) {
 	if root == nil || h == nil {
 		return
@@ -103,6 +103,12 @@ func (c *evalCommand) Run(ctx context.Context, vals *values.Values) error {
 	if c.sectionErr != nil {
 		return c.sectionErr
 	}
+	if err := rejectDebugAddr(vals, "eval"); err != nil {
+		return err
+	}
+	if err := rejectCoverage(vals, "eval"); err != nil {
+		return err
+	}
 	settings := evalSettings{}
 	if err := vals.DecodeSectionInto(schema.DefaultSlug, &settings); err != nil {
 		return err
@@ -292,8 +298,29 @@ func buildVerbCommands(sourceRegistry *SourceRegistry, factory *RuntimeFactory,
 		for _, verb := range registry.Verbs() {
 			verb := verb
 			registry := registry
-			cmd, err := registry.CommandForVerbWithInvoker(verb, func(ctx context.Context, _ *jsverbs.Registry, verb *jsverbs.VerbSpec, parsedValues *values.Values) (interface{}, error) {
-				rt, err := factory.NewRuntimeFromSections(ctx, parsedValues, require.WithLoader(registry.RequireLoader()))
+			cmd, err := registry.CommandForVerbWithInvoker(verb, func(ctx context.Context, _ *jsverbs.Registry, verb *jsverbs.VerbSpec, parsedValues *values.Values) (_ interface{}, retErr error) {
+				requireOpt := require.WithLoader(registry.RequireLoader())
+				collector, finishCoverage, err := startCoverage(parsedValues)
+				if err != nil {
+					return nil, err
+				}
+				defer func() {
+					if err := finishCoverage(); err != nil && retErr == nil {
+						retErr = err
+					}
+				}()
+				if collector != nil {
+					requireOpt = collector.RequireOptionWithPaths(registry.RequireLoader(), registry.FilePath)
+				}
+				dbg, finishDebug, err := startDebugger(ctx, parsedValues)
+				if err != nil {
+					return nil, err
+				}
+				defer func() { finishDebug(retErr) }()
+				if dbg != nil {
+					requireOpt = dbg.RequireOptionWithPaths(registry.RequireLoader(), registry.FilePath)
+				}
+				rt, err := factory.NewRuntimeFromSections(ctx, parsedValues, requireOpt)
 				if err != nil {
 					return nil, err
 				}
@@ -9,12 +9,15 @@ import (
 	"syscall"
 
 	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/require"
 	"github.com/evanw/esbuild/pkg/api"
 	"github.com/go-go-golems/glazed/pkg/cmds"
 	"github.com/go-go-golems/glazed/pkg/cmds/fields"
 	"github.com/go-go-golems/glazed/pkg/cmds/schema"
 	"github.com/go-go-golems/glazed/pkg/cmds/values"
 	"github.com/go-go-golems/go-go-goja/pkg/engine"
+	"github.com/go-go-golems/go-go-goja/pkg/jscover"
+	"github.com/go-go-golems/go-go-goja/pkg/jserrors"
 	"github.com/go-go-golems/go-go-goja/pkg/tsscript"
 	"github.com/go-go-golems/go-go-goja/pkg/xgoja/providerapi"
 )
@@ -82,7 +85,7 @@ func (c *runCommand) Run(ctx context.Context, vals *values.Values) error {
 	return runScriptFileWithInitializers(ctx, c.factory, settings.File, vals, selectedModules, settings.KeepAlive)
 }
 
-func runScriptFileWithInitializers(ctx context.Context, factory *RuntimeFactory, file string, vals *values.Values, selectedModules []providerapi.ModuleDescriptor, keepAlive bool) error {
+func runScriptFileWithInitializers(ctx context.Context, factory *RuntimeFactory, file string, vals *values.Values, selectedModules []providerapi.ModuleDescriptor, keepAlive bool) (retErr error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -103,7 +106,30 @@ func runScriptFileWithInitializers(ctx context.Context, factory *RuntimeFactory,
 	if err != nil {
 		return fmt.Errorf("resolve module roots from script %q: %w", scriptPath, err)
 	}
-	rt, err := factory.NewRuntimeFromSections(ctx, vals, requireOpt)
+	requireOpts := []require.Option{requireOpt}
+	collector, finishCoverage, err := startCoverage(vals)
+	if err != nil {
+		return err
+	}
+	// Deferred before the runtime closes, so the reports are written after
+	// the script stopped counting.
+	defer func() {
+		if err := finishCoverage(); err != nil && retErr == nil {
+			retErr = err
+		}
+	}()
+	if collector != nil {
+		requireOpts = append(requireOpts, collector.RequireOption(nil))
+	}
+	dbg, finishDebug, err := startDebugger(ctx, vals)
+	if err != nil {
+		return err
+	}
+	defer func() { finishDebug(retErr) }()
+	if dbg != nil {
+		requireOpts = append(requireOpts, dbg.RequireOption(nil))
+	}
+	rt, err := factory.NewRuntimeFromSections(ctx, vals, requireOpts...)
 	if err != nil {
 		return fmt.Errorf("create runtime: %w", err)
 	}
@@ -115,16 +141,15 @@ func runScriptFileWithInitializers(ctx context.Context, factory *RuntimeFactory,
 	}
 
 	if tsscript.IsTypeScriptPath(scriptPath) {
-		if err := runTypeScriptScript(ctx, rt, scriptPath, selectedModules); err != nil {
+		if err := runTypeScriptScript(ctx, rt, scriptPath, selectedModules, collector); err != nil {
 			return err
 		}
 	} else {
-		_, err = rt.Owner.Call(ctx, "xgoja.run", func(_ context.Context, vm *goja.Runtime) (any, error) {
-			_ = vm
-			return rt.Require.Require(scriptPath)
-		})
+		// ImportModule runs CommonJS and ES module entry points alike and waits
+		// for top-level await to settle.
+		_, err = rt.ImportModule(ctx, scriptPath)
 		if err != nil {
-			return fmt.Errorf("run %s as module: %w", scriptPath, err)
+			return fmt.Errorf("run %s as module: %w", scriptPath, jserrors.Wrap(err))
 		}
 	}
 	if keepAlive {
@@ -134,21 +159,30 @@ func runScriptFileWithInitializers(ctx context.Context, factory *RuntimeFactory,
 	return nil
 }
 
-func runTypeScriptScript(ctx context.Context, rt *engine.Runtime, scriptPath string, selectedModules []providerapi.ModuleDescriptor) error {
-	artifact, err := tsscript.BundleEntry(scriptPath, tsscript.Options{
-		Target:   api.ES2015,
-		Format:   api.FormatIIFE,
-		Platform: api.PlatformNeutral,
-		External: moduleAliases(selectedModules),
-	})
+// runTypeScriptScript bundles and runs a TypeScript entry point. The bundle
+// carries an inline source map, so that errors and coverage are reported
+// against the TypeScript files.
+func runTypeScriptScript(ctx context.Context, rt *engine.Runtime, scriptPath string, selectedModules []providerapi.ModuleDescriptor, collector *jscover.Collector) error {
+	opts := tsscript.Options{
+		Target:    api.ES2015,
+		Format:    api.FormatIIFE,
+		Platform:  api.PlatformNeutral,
+		External:  moduleAliases(selectedModules),
+		Sourcemap: api.SourceMapInline,
+	}
+	artifact, err := tsscript.BundleEntry(scriptPath, opts)
 	if err != nil {
 		return fmt.Errorf("compile TypeScript %s: %w", scriptPath, err)
 	}
+	code := jserrors.Retain(scriptPath, artifact.Code)
+	if collector != nil {
+		code = collector.Instrument(scriptPath, code)
+	}
 	_, err = rt.Owner.Call(ctx, "xgoja.run.typescript", func(_ context.Context, vm *goja.Runtime) (any, error) {
-		return vm.RunScript(scriptPath, string(artifact.Code))
+		return vm.RunScript(scriptPath, string(code))
 	})
 	if err != nil {
-		return fmt.Errorf("run compiled TypeScript %s: %w", scriptPath, err)
+		return fmt.Errorf("run compiled TypeScript %s: %w", scriptPath, jserrors.Wrap(err))
 	}
 	return nil
 }
@@ -4,8 +4,12 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/go-go-goja/pkg/jscover"
 	"github.com/go-go-golems/go-go-goja/pkg/xgoja/providerapi"
 )
 
@@ -28,6 +32,69 @@ func TestRunScriptFileWithInitializersRunsTypeScriptEntry(t *testing.T) {
 	}
 }
 
+func TestRunScriptFileWithInitializersReportsTypeScriptCoverage(t *testing.T) {
+	dir := t.TempDir()
+	helper := filepath.Join(dir, "helper.ts")
+	if err := os.WriteFile(helper, []byte("export function greet(name: string): string {\n  if (!name) {\n    return \"nobody\";\n  }\n  return \"hello \" + name;\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	entry := filepath.Join(dir, "entry.ts")
+	if err := os.WriteFile(entry, []byte("import { greet } from \"./helper\"\nglobalThis.result = greet(\"goja\")\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "coverage")
+	factory := NewRuntimeFactory(providerapi.NewProviderRegistry(), &RuntimePlan{})
+	if err := runScriptFileWithInitializers(context.Background(), factory, entry, xgojaCoverageValues(t, out), nil, false); err != nil {
+		t.Fatalf("runScriptFileWithInitializers() error = %v", err)
+	}
+	lcov, err := os.ReadFile(filepath.Join(out, jscover.LCOVFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"SF:" + helper + "\n", "FNDA:1,greet\n", "DA:3,0\n", "DA:5,1\n", "SF:" + entry + "\n"} {
+		if !strings.Contains(string(lcov), want) {
+			t.Fatalf("lcov report misses %q:\n%s", want, lcov)
+		}
+	}
+}
+
+func TestRunScriptFileWithInitializersReportsTypeScriptErrors(t *testing.T) {
+	dir := t.TempDir()
+	helper := filepath.Join(dir, "helper.ts")
+	if err := os.WriteFile(helper, []byte("export function check(count: number): void {\n  if (count > 1) {\n    throw new Error(\"too many\");\n  }\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	entry := filepath.Join(dir, "entry.ts")
+	if err := os.WriteFile(entry, []byte("import { check } from \"./helper\"\ncheck(2)\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	factory := NewRuntimeFactory(providerapi.NewProviderRegistry(), &RuntimePlan{})
+	err := runScriptFileWithInitializers(context.Background(), factory, entry, nil, nil, false)
+	if err == nil {
+		t.Fatal("runScriptFileWithInitializers() error = nil, want error")
+	}
+	for _, want := range []string{"Error: too many", "> 3 |     throw new Error(\"too many\");", "at check (" + helper + ":3:11)", "at " + entry + ":2:"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error misses %q:\n%v", want, err)
+		}
+	}
+}
+
+func xgojaCoverageValues(t *testing.T, dir string) *values.Values {
+	t.Helper()
+	section, err := xgojaRuntimeSection()
+	if err != nil {
+		t.Fatalf("xgoja section: %v", err)
+	}
+	sectionValues, err := values.NewSectionValues(section, values.WithFieldValue(xgojaCoverageField, dir, fields.WithSource("cobra")))
+	if err != nil {
+		t.Fatalf("section values: %v", err)
+	}
+	return values.New(values.WithSectionValues(xgojaSectionSlug, sectionValues))
+}
+
 func TestModuleAliasesDeduplicatesSelectedModuleAliases(t *testing.T) {
 	aliases := moduleAliases([]providerapi.ModuleDescriptor{
 		{ModuleID: "fs", As: "fs:assets"},
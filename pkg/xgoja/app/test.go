@@ -0,0 +1,175 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/dop251/goja_nodejs/require"
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/go-go-goja/pkg/engine"
+	"github.com/go-go-golems/go-go-goja/pkg/jstest"
+)
+
+type testCommand struct {
+	*cmds.CommandDescription
+	factory     *RuntimeFactory
+	runtimePlan *RuntimePlan
+	out         io.Writer
+	sectionErr  error
+}
+
+var _ cmds.BareCommand = (*testCommand)(nil)
+
+type testSettings struct {
+	Paths           []string `glazed:"paths"`
+	Reporter        string   `glazed:"reporter"`
+	Output          string   `glazed:"output"`
+	Filter          string   `glazed:"filter"`
+	Timeout         string   `glazed:"timeout"`
+	UpdateSnapshots bool     `glazed:"update-snapshots"`
+	CI              bool     `glazed:"ci"`
+}
+
+func newTestCommand(factory *RuntimeFactory, runtimePlan *RuntimePlan, out io.Writer) cmds.Command {
+	moduleSections, _, sectionErr := factory.sectionsForRuntime("test")
+	options := []cmds.CommandDescriptionOption{
+		cmds.WithShort("Run JavaScript and TypeScript test files in generated xgoja runtimes"),
+		cmds.WithLong(`
+Test runs *.test.js, *.test.mjs and *.test.ts files, each in a fresh xgoja
+runtime with the provider modules and module settings of run.
+
+Test files declare tests with describe, it, beforeEach and the other hooks,
+assert with expect, replace members of provider modules with mock, and compare
+values with snapshots stored in __snapshots__ next to the file.
+
+The command fails when a test fails. With --output the report is written to
+the file and a spec report is printed as well.
+`),
+		cmds.WithArguments(
+			fields.New("paths", fields.TypeStringList,
+				fields.WithDefault([]string{"."}),
+				fields.WithHelp("Test files, or directories to find test files in")),
+		),
+		cmds.WithFlags(
+			fields.New("reporter", fields.TypeChoice, fields.WithChoices(jstest.FormatSpec, jstest.FormatTAP, jstest.FormatJUnit), fields.WithDefault(jstest.FormatSpec), fields.WithHelp("Report format")),
+			fields.New("output", fields.TypeString, fields.WithDefault(""), fields.WithHelp("Write the report to this file and print a spec report")),
+			fields.New("filter", fields.TypeString, fields.WithShortFlag("t"), fields.WithDefault(""), fields.WithHelp("Run only tests whose full name matches this regular expression")),
+			fields.New("timeout", fields.TypeString, fields.WithDefault(jstest.DefaultTimeout.String()), fields.WithHelp("Time each test may take")),
+			fields.New("update-snapshots", fields.TypeBool, fields.WithShortFlag("u"), fields.WithDefault(false), fields.WithHelp("Overwrite snapshots that do not match and remove unused ones")),
+			fields.New("ci", fields.TypeBool, fields.WithDefault(false), fields.WithHelp("Fail tests whose snapshots do not exist instead of writing them")),
+		),
+	}
+	if sectionErr == nil && len(moduleSections) > 0 {
+		options = append(options, cmds.WithSections(moduleSections...))
+	}
+	command, _ := runtimePlan.commandByType("builtin.test")
+	return &testCommand{
+		CommandDescription: cmds.NewCommandDescription(commandName(command, "test"), options...),
+		factory:            factory,
+		runtimePlan:        runtimePlan,
+		out:                out,
+		sectionErr:         sectionErr,
+	}
+}
+
+func (c *testCommand) Run(ctx context.Context, vals *values.Values) (retErr error) {
+	if c.sectionErr != nil {
+		return c.sectionErr
+	}
+	settings := testSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, &settings); err != nil {
+		return err
+	}
+	if err := rejectDebugAddr(vals, "test"); err != nil {
+		return err
+	}
+	selectedModules, err := c.factory.selectedModuleDescriptors()
+	if err != nil {
+		return err
+	}
+	timeout, err := time.ParseDuration(settings.Timeout)
+	if err != nil {
+		return fmt.Errorf("parse --timeout: %w", err)
+	}
+	opts := jstest.Options{
+		External:        moduleAliases(selectedModules),
+		Timeout:         timeout,
+		UpdateSnapshots: settings.UpdateSnapshots,
+		CI:              settings.CI,
+	}
+	if settings.Filter != "" {
+		if opts.Filter, err = regexp.Compile(settings.Filter); err != nil {
+			return fmt.Errorf("parse --filter: %w", err)
+		}
+	}
+
+	collector, finishCoverage, err := startCoverage(vals)
+	if err != nil {
+		return err
+	}
+	// Deferred after the runtimes of the files are closed, so the reports
+	// count every test.
+	defer func() {
+		if err := finishCoverage(); err != nil && retErr == nil {
+			retErr = err
+		}
+	}()
+	if collector != nil {
+		opts.Instrument = collector.Instrument
+	}
+	opts.NewRuntime = func(ctx context.Context, file string) (*engine.Runtime, error) {
+		requireOpt, err := engine.RequireOptionWithModuleRootsFromScript(file, engine.DefaultModuleRootsOptions())
+		if err != nil {
+			return nil, fmt.Errorf("resolve module roots from script %q: %w", file, err)
+		}
+		requireOpts := []require.Option{requireOpt}
+		if collector != nil {
+			requireOpts = append(requireOpts, collector.RequireOption(nil))
+		}
+		rt, err := c.factory.NewRuntimeFromSections(ctx, vals, requireOpts...)
+		if err != nil {
+			return nil, err
+		}
+		if vals != nil && len(selectedModules) > 0 {
+			if err := initRuntimeFromSections(ctx, vals, rt, selectedModules); err != nil {
+				_ = rt.Close(ctx)
+				return nil, err
+			}
+		}
+		return rt, nil
+	}
+
+	out := c.out
+	if out == nil {
+		out = os.Stdout
+	}
+	return runTestFiles(ctx, out, settings, opts)
+}
+
+// runTestFiles runs the test files under the paths of settings and writes
+// the report.
+func runTestFiles(ctx context.Context, out io.Writer, settings testSettings, opts jstest.Options) error {
+	files, err := jstest.Discover(settings.Paths)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no test files found in %v", settings.Paths)
+	}
+	report := jstest.Run(ctx, files, opts)
+	dir, _ := os.Getwd()
+	if err := report.WriteOutput(out, settings.Reporter, settings.Output, dir); err != nil {
+		return err
+	}
+	if report.Failed() {
+		return fmt.Errorf("tests failed: %s", report.Summary())
+	}
+	return nil
+}
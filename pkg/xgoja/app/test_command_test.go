@@ -0,0 +1,114 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-go-golems/go-go-goja/pkg/xgoja/providerapi"
+	"github.com/go-go-golems/go-go-goja/pkg/xgoja/testprovider"
+)
+
+const testCommandRuntimePlanJSON = `{
+  "schema": "xgoja/runtime/v2",
+  "name": "fixture",
+  "app": {
+    "name": "fixture"
+  },
+  "target": {
+    "kind": "xgoja",
+    "output": "dist/fixture"
+  },
+  "providers": [
+    {
+      "id": "fixture"
+    }
+  ],
+  "runtime": {
+    "modules": [
+      {
+        "provider": "fixture",
+        "name": "hello",
+        "as": "hello"
+      }
+    ]
+  },
+  "commands": [
+    {
+      "id": "test",
+      "type": "builtin.test",
+      "name": "test"
+    }
+  ]
+}`
+
+func TestGeneratedRootTestCommandRunsTestFilesWithProviderModules(t *testing.T) {
+	registry := providerapi.NewProviderRegistry()
+	if err := testprovider.Register(registry); err != nil {
+		t.Fatalf("register provider: %v", err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "greeter.js"), []byte(`
+const hello = require("hello")
+exports.welcome = (name) => hello.greet(name) + "!"
+`), 0o644); err != nil {
+		t.Fatalf("write greeter: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "greeter.test.js"), []byte(`
+const { welcome } = require("./greeter")
+
+describe("welcome", () => {
+  it("greets through the provider module", () => {
+    expect(welcome("intern")).toBe("hello intern!")
+  })
+
+  it("uses a mocked provider module", () => {
+    const greet = mock("hello", { greet: (name) => "hi " + name }).greet
+    expect(welcome("intern")).toBe("hi intern!")
+    expect(greet).toHaveBeenCalledWith("intern")
+  })
+})
+`), 0o644); err != nil {
+		t.Fatalf("write test: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "typed.test.ts"), []byte(`
+import { greet } from "hello"
+
+it("is compiled", () => {
+  const name: string = "intern"
+  expect(greet(name)).toMatchSnapshot()
+})
+`), 0o644); err != nil {
+		t.Fatalf("write TypeScript test: %v", err)
+	}
+
+	out := &bytes.Buffer{}
+	root, err := NewRootCommand(Options{Providers: registry, RuntimePlanJSON: testCommandRuntimePlanJSON, Out: out})
+	if err != nil {
+		t.Fatalf("new root: %v", err)
+	}
+	junit := filepath.Join(dir, "junit.xml")
+	root.SetArgs([]string{"test", "--reporter", "junit", "--output", junit, dir})
+	if err := root.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("execute test: %v", err)
+	}
+	for _, want := range []string{"✓ greets through the provider module", "✓ uses a mocked provider module", "✓ is compiled", "Tests:      3 passed, 3 total", "Snapshots:  1 added"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("spec report misses %q:\n%s", want, out.String())
+		}
+	}
+	snapshot, err := os.ReadFile(filepath.Join(dir, "__snapshots__", "typed.test.ts.snap"))
+	if err != nil || !strings.Contains(string(snapshot), "exports[`is compiled 1`] = `\"hello intern\"`;") {
+		t.Fatalf("unexpected snapshot file: %v\n%s", err, snapshot)
+	}
+	report, err := os.ReadFile(junit)
+	if err != nil {
+		t.Fatalf("read JUnit report: %v", err)
+	}
+	if !strings.Contains(string(report), `<testsuites name="goja-test" tests="3" failures="0"`) {
+		t.Fatalf("unexpected JUnit report:\n%s", report)
+	}
+}
@@ -68,6 +68,12 @@ func (c *tuiCommand) Run(ctx context.Context, vals *values.Values) error {
 	if c.sectionErr != nil {
 		return c.sectionErr
 	}
+	if err := rejectDebugAddr(vals, "repl"); err != nil {
+		return err
+	}
+	if err := rejectCoverage(vals, "repl"); err != nil {
+		return err
+	}
 	settings := tuiSettings{}
 	if err := vals.DecodeSectionInto(schema.DefaultSlug, &settings); err != nil {
 		return err
@@ -162,16 +162,18 @@ func platformFromString(value string) api.Platform {
 	}
 }
 
+// sourcemapFromString defaults to an inline map, which goja applies to
+// stack traces and jserrors reports against the TypeScript files.
 func sourcemapFromString(value string) api.SourceMap {
 	switch strings.ToLower(strings.TrimSpace(value)) {
-	case "inline":
-		return api.SourceMapInline
+	case "none", "false":
+		return api.SourceMapNone
 	case "external", "linked":
 		return api.SourceMapLinked
 	case "both":
 		return api.SourceMapInlineAndExternal
 	default:
-		return api.SourceMapNone
+		return api.SourceMapInline
 	}
 }
 
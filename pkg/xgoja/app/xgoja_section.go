@@ -11,12 +11,18 @@ import (
 
 const xgojaSectionSlug = "xgoja"
 const xgojaDebugPanicStackField = "debug-panic-stack"
+const xgojaDebugAddrField = "debug-addr"
+const xgojaCoverageField = "coverage"
 
 func xgojaRuntimeSection() (schema.Section, error) {
 	return schema.NewSection(xgojaSectionSlug, "xgoja",
 		schema.WithFields(
 			fields.New(xgojaDebugPanicStackField, fields.TypeBool,
 				fields.WithHelp("Include Go debug stacks in recovered runtime panic errors")),
+			fields.New(xgojaDebugAddrField, fields.TypeString,
+				fields.WithHelp("Wait for a Debug Adapter Protocol client on this address before running JavaScript")),
+			fields.New(xgojaCoverageField, fields.TypeString,
+				fields.WithHelp("Write coverage reports of the JavaScript run to this directory")),
 		),
 	)
 }
@@ -45,3 +51,33 @@ func includeRecoveredPanicStack(vals *values.Values) (bool, error) {
 		return false, fmt.Errorf("invalid xgoja debug panic stack value type %T", field.Value)
 	}
 }
+
+func debugAddr(vals *values.Values) (string, error) {
+	if vals == nil {
+		return "", nil
+	}
+	field, ok := vals.GetField(xgojaSectionSlug, xgojaDebugAddrField)
+	if !ok || field == nil || field.Value == nil {
+		return "", nil
+	}
+	v, ok := field.Value.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid xgoja debug address value type %T", field.Value)
+	}
+	return strings.TrimSpace(v), nil
+}
+
+func coverageDir(vals *values.Values) (string, error) {
+	if vals == nil {
+		return "", nil
+	}
+	field, ok := vals.GetField(xgojaSectionSlug, xgojaCoverageField)
+	if !ok || field == nil || field.Value == nil {
+		return "", nil
+	}
+	v, ok := field.Value.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid xgoja coverage directory value type %T", field.Value)
+	}
+	return strings.TrimSpace(v), nil
+}
@@ -111,6 +111,11 @@ func (m *Manager) Reload(ctx context.Context) (*Snapshot, error) {
 		m.recordFailure(err)
 		return nil, err
 	}
+	if err := candidate.Host.Validate(); err != nil {
+		_ = runtime.Close(ctx)
+		m.recordFailure(err)
+		return nil, err
+	}
 
 	snapshot := &Snapshot{
 		Version:  version,
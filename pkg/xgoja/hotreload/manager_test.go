@@ -9,6 +9,8 @@ import (
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/go-go-golems/go-go-goja/pkg/gojahttp"
 )
 
 type fakeRuntime struct {
@@ -107,6 +109,27 @@ func TestManagerSmokeFailureClosesCandidate(t *testing.T) {
 	}
 }
 
+func TestManagerRouteConflictClosesCandidate(t *testing.T) {
+	candidateRuntime := &fakeRuntime{}
+	manager := MustNewManager(Options{
+		Load: func(_ context.Context, candidate Candidate) (Runtime, error) {
+			candidate.Host.Register("GET", "/users/:id", nil)
+			candidate.Host.Register("GET", "/users/:name", nil)
+			return candidateRuntime, nil
+		},
+	})
+
+	if _, err := manager.Reload(context.Background()); !errors.Is(err, gojahttp.ErrRouteConflict) {
+		t.Fatalf("expected route conflict, got %v", err)
+	}
+	if !candidateRuntime.closed.Load() {
+		t.Fatal("expected conflicting candidate runtime to be closed")
+	}
+	if status := manager.Status(); status.Ready {
+		t.Fatalf("status after route conflict = %#v", status)
+	}
+}
+
 func assertManagerResponse(t *testing.T, manager *Manager, expected string) {
 	t.Helper()
 	rr := httptest.NewRecorder()
@@ -0,0 +1,97 @@
+package host
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	fetchmod "github.com/go-go-golems/go-go-goja/modules/fetch"
+	"github.com/go-go-golems/go-go-goja/pkg/xgoja/providerapi"
+)
+
+// FetchCassetteServiceKey is the host service that carries the
+// *fetchmod.Cassette selected with --fetch-cassette-path. Fetch module
+// instances send their traffic through it instead of the network.
+const FetchCassetteServiceKey = "go-go-goja-host.fetch-cassette"
+
+type fetchCassetteSettings struct {
+	Path  string `glazed:"path"`
+	Mode  string `glazed:"mode"`
+	Match string `glazed:"match"`
+}
+
+// fetchCassetteCapability adds the fetch-cassette flags to run, eval, repl
+// and jsverbs so script suites can record HTTP traffic once and replay it
+// offline.
+type fetchCassetteCapability struct{}
+
+func (fetchCassetteCapability) CapabilityID() string { return "go-go-goja-host.fetch-cassette" }
+
+func (fetchCassetteCapability) GlazedConfigSections(providerapi.SectionRequest) ([]schema.Section, error) {
+	section, err := schema.NewSection("fetch-cassette", "Fetch cassette",
+		schema.WithPrefix("fetch-cassette-"),
+		schema.WithFields(
+			fields.New("path", fields.TypeString, fields.WithDefault(""), fields.WithHelp("Cassette file that serves fetch traffic instead of the network")),
+			fields.New("mode", fields.TypeChoice, fields.WithChoices("replay", "record"), fields.WithDefault("replay"), fields.WithHelp("Replay recorded traffic, or record live traffic into the cassette")),
+			fields.New("match", fields.TypeString, fields.WithDefault("method,url"), fields.WithHelp("Request fields replay compares: method, url, body and header:<name>")),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return []schema.Section{section}, nil
+}
+
+func (fetchCassetteCapability) ContributeHostServices(_ context.Context, req providerapi.HostServiceContributionRequest, sink providerapi.HostServiceSink) error {
+	if req.Values == nil {
+		return nil
+	}
+	if _, ok := req.Values.Get("fetch-cassette"); !ok {
+		return nil
+	}
+	settings := fetchCassetteSettings{}
+	if err := req.Values.DecodeSectionInto("fetch-cassette", &settings); err != nil {
+		return err
+	}
+	if strings.TrimSpace(settings.Path) == "" {
+		return nil
+	}
+	cassette, err := openFetchCassette(settings)
+	if err != nil {
+		return err
+	}
+	return sink.AddHostService(FetchCassetteServiceKey, cassette)
+}
+
+func openFetchCassette(settings fetchCassetteSettings) (*fetchmod.Cassette, error) {
+	mode, err := fetchmod.ParseCassetteMode(settings.Mode)
+	if err != nil {
+		return nil, fmt.Errorf("fetch cassette: %w", err)
+	}
+	match, err := fetchmod.ParseCassetteMatch(settings.Match)
+	if err != nil {
+		return nil, fmt.Errorf("fetch cassette: %w", err)
+	}
+	return fetchmod.OpenCassette(strings.TrimSpace(settings.Path), mode, fetchmod.WithCassetteMatch(match))
+}
+
+func fetchCassetteService(hostServices providerapi.HostServices) (*fetchmod.Cassette, error) {
+	lookup, ok := hostServices.(providerapi.HostServiceLookup)
+	if !ok || lookup == nil {
+		return nil, nil
+	}
+	raw, ok := lookup.HostService(FetchCassetteServiceKey)
+	if !ok {
+		return nil, nil
+	}
+	cassette, ok := raw.(*fetchmod.Cassette)
+	if !ok || cassette == nil {
+		return nil, fmt.Errorf("fetch cassette host service %q must be *fetch.Cassette, got %T", FetchCassetteServiceKey, raw)
+	}
+	return cassette, nil
+}
+
+var _ providerapi.GlazedConfigSectionCapability = fetchCassetteCapability{}
+var _ providerapi.HostServiceContributionCapability = fetchCassetteCapability{}
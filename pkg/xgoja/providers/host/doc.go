@@ -2,7 +2,8 @@
 // provider package.
 //
 // The provider package ID is "go-go-goja-host". It registers fs, node:fs,
-// exec, database, and db. These modules can read/write host files, execute
+// exec, database, and db, plus a "db" command set whose "migrate" command
+// applies a directory of SQL migrations with any linked database/sql driver. These modules can read/write host files, execute
 // host processes, or connect to databases. They are intentionally separate
 // from the core provider package and require explicit runtime-profile config.
 //
@@ -22,6 +23,10 @@
 //     config.driverName and config.dataSourceName. In preconfigured mode,
 //     JavaScript can call query/exec/begin/close immediately and configure() is
 //     rejected even if allowConfigure is also set.
+//   - database/db accept pool settings (maxOpenConns, maxIdleConns,
+//     connMaxLifetime, connMaxIdleTime) and an optional placeholder dialect.
+//     Drivers other than sqlite3 are linked by adding the go-go-goja-postgres
+//     or go-go-goja-mysql provider package to the build.
 //
 // Example preconfigured database module instance:
 //
@@ -33,6 +38,18 @@
 //	      driverName: sqlite3
 //	      dataSourceName: ':memory:'
 //
+// Example Postgres module instance, with the postgres provider listed under
+// providers:
+//
+//	modules:
+//	  - package: go-go-goja-host
+//	    name: database
+//	    config:
+//	      driverName: pgx
+//	      dataSourceName: postgres://app@localhost/app
+//	      maxOpenConns: 10
+//	      connMaxLifetime: 30m
+//
 // Example script-owned demo configuration:
 //
 //	modules:
@@ -65,9 +65,14 @@ type ExecConfig struct {
 }
 
 type DatabaseConfig struct {
-	AllowConfigure bool   `json:"allowConfigure"`
-	DriverName     string `json:"driverName,omitempty"`
-	DataSourceName string `json:"dataSourceName,omitempty"`
+	AllowConfigure  bool   `json:"allowConfigure"`
+	DriverName      string `json:"driverName,omitempty"`
+	DataSourceName  string `json:"dataSourceName,omitempty"`
+	Dialect         string `json:"dialect,omitempty"`
+	MaxOpenConns    int    `json:"maxOpenConns,omitempty"`
+	MaxIdleConns    int    `json:"maxIdleConns,omitempty"`
+	ConnMaxLifetime string `json:"connMaxLifetime,omitempty"`
+	ConnMaxIdleTime string `json:"connMaxIdleTime,omitempty"`
 }
 
 // Register exposes guarded host-capability modules. These modules can touch
@@ -75,12 +80,19 @@ type DatabaseConfig struct {
 // explicit per-module config in xgoja.yaml.
 func Register(registry *providerapi.ProviderRegistry) error {
 	return registry.Package(PackageID,
+		providerapi.WithPackageCapability(fetchCassetteCapability{}),
 		fsModule("fs"),
 		fsModule("node:fs"),
 		fetchModule("fetch"),
 		execModule(),
 		databaseModule("database"),
 		databaseModule("db"),
+		providerapi.CommandSetProvider{
+			Name:          "db",
+			DefaultMount:  "db",
+			Description:   "Apply SQL migration directories to the databases the host uses",
+			NewCommandSet: newDBCommandSet,
+		},
 	)
 }
 
@@ -218,7 +230,15 @@ func fetchModule(name string) providerapi.Module {
 			if strings.TrimSpace(requireName) == "" {
 				requireName = name
 			}
-			return fetchmod.New(fetchmod.WithName(requireName), fetchmod.WithPolicy(policy)).Loader, nil
+			opts := []fetchmod.Option{fetchmod.WithName(requireName), fetchmod.WithPolicy(policy)}
+			cassette, err := fetchCassetteService(ctx.Host)
+			if err != nil {
+				return nil, err
+			}
+			if cassette != nil {
+				opts = append(opts, fetchmod.WithHTTPClient(cassette.Client()))
+			}
+			return fetchmod.New(opts...).Loader, nil
 		},
 	}
 }
@@ -301,7 +321,12 @@ func databaseModule(name string) providerapi.Module {
   "properties": {
     "allowConfigure": {"type": "boolean", "description": "Allow JavaScript to call configure(driverName, dataSourceName). Ignored when driverName/dataSourceName preconfigure the module."},
     "driverName": {"type": "string", "description": "Optional SQL driver name used to preconfigure the module before JavaScript runs."},
-    "dataSourceName": {"type": "string", "description": "Optional SQL data source name used with driverName to preconfigure the module before JavaScript runs."}
+    "dataSourceName": {"type": "string", "description": "Optional SQL data source name used with driverName to preconfigure the module before JavaScript runs."},
+    "dialect": {"type": "string", "enum": ["sqlite", "postgres", "mysql"], "description": "Placeholder dialect. Defaults to the one matching the driver; set it for drivers registered under custom names."},
+    "maxOpenConns": {"type": "integer", "minimum": 0, "description": "Maximum open connections. 0 keeps the database/sql default (unlimited)."},
+    "maxIdleConns": {"type": "integer", "minimum": 0, "description": "Maximum idle connections. 0 keeps the database/sql default."},
+    "connMaxLifetime": {"type": "string", "description": "Maximum connection lifetime as a Go duration such as 30m."},
+    "connMaxIdleTime": {"type": "string", "description": "Maximum connection idle time as a Go duration such as 5m."}
   }
 }`),
 		NewModuleFactory: func(ctx providerapi.ModuleSetupContext) (require.ModuleLoader, error) {
@@ -321,8 +346,20 @@ func databaseModule(name string) providerapi.Module {
 func databaseModuleFromConfig(name string, cfg DatabaseConfig) (*dbm.DBModule, error) {
 	driverName := strings.TrimSpace(cfg.DriverName)
 	dataSourceName := strings.TrimSpace(cfg.DataSourceName)
+	pool, err := databasePoolFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	options := []dbm.Option{dbm.WithName(name), dbm.WithPool(pool)}
+	switch dialect := dbm.Dialect(strings.TrimSpace(cfg.Dialect)); dialect {
+	case "":
+	case dbm.DialectSQLite, dbm.DialectPostgres, dbm.DialectMySQL:
+		options = append(options, dbm.WithDialect(dialect))
+	default:
+		return nil, fmt.Errorf("database config: unknown dialect %q", cfg.Dialect)
+	}
 	if driverName == "" && dataSourceName == "" {
-		return dbm.New(dbm.WithName(name), dbm.WithConfigureEnabled(cfg.AllowConfigure)), nil
+		return dbm.New(append(options, dbm.WithConfigureEnabled(cfg.AllowConfigure))...), nil
 	}
 	if driverName == "" || dataSourceName == "" {
 		return nil, fmt.Errorf("database config requires both driverName and dataSourceName for preconfigured modules")
@@ -331,7 +368,32 @@ func databaseModuleFromConfig(name string, cfg DatabaseConfig) (*dbm.DBModule, e
 	if err != nil {
 		return nil, fmt.Errorf("open preconfigured database %q: %w", driverName, err)
 	}
-	return dbm.New(dbm.WithName(name), dbm.WithPreconfiguredDB(db), dbm.WithCloseFn(db.Close)), nil
+	return dbm.New(append(options, dbm.WithPreconfiguredDB(db), dbm.WithCloseFn(db.Close))...), nil
+}
+
+func databasePoolFromConfig(cfg DatabaseConfig) (dbm.PoolOptions, error) {
+	pool := dbm.PoolOptions{MaxOpenConns: cfg.MaxOpenConns, MaxIdleConns: cfg.MaxIdleConns}
+	if pool.MaxOpenConns < 0 || pool.MaxIdleConns < 0 {
+		return pool, fmt.Errorf("database config: connection limits must not be negative")
+	}
+	for _, field := range []struct {
+		name   string
+		value  string
+		target *time.Duration
+	}{
+		{"connMaxLifetime", cfg.ConnMaxLifetime, &pool.ConnMaxLifetime},
+		{"connMaxIdleTime", cfg.ConnMaxIdleTime, &pool.ConnMaxIdleTime},
+	} {
+		if strings.TrimSpace(field.value) == "" {
+			continue
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(field.value))
+		if err != nil || d < 0 {
+			return pool, fmt.Errorf("database config %s: invalid duration %q", field.name, field.value)
+		}
+		*field.target = d
+	}
+	return pool, nil
 }
 
 func nativeModuleTypeScript(name string) *spec.Module {
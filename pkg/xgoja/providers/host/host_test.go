@@ -2,10 +2,14 @@ package host
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"testing"
@@ -13,6 +17,11 @@ import (
 	"time"
 
 	"github.com/dop251/goja"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
 	"github.com/go-go-golems/go-go-goja/pkg/xgoja/app"
 	"github.com/go-go-golems/go-go-goja/pkg/xgoja/providerapi"
 )
@@ -404,3 +413,184 @@ func TestExecAllowedCommands(t *testing.T) {
 		t.Fatal("expected disallowed command error")
 	}
 }
+
+func TestFetchCassetteSectionReplaysTraffic(t *testing.T) {
+	registry := providerapi.NewProviderRegistry()
+	if err := Register(registry); err != nil {
+		t.Fatalf("register host provider: %v", err)
+	}
+	cassette := filepath.Join(t.TempDir(), "api.yaml")
+	recording := "version: 1\ninteractions:\n  - request: {method: GET, url: 'http://api.example.test/status'}\n    response: {status: 200, body: '{\"ok\":true}'}\n"
+	if err := os.WriteFile(cassette, []byte(recording), 0o600); err != nil {
+		t.Fatalf("write cassette: %v", err)
+	}
+	sections, err := fetchCassetteCapability{}.GlazedConfigSections(providerapi.SectionRequest{})
+	if err != nil {
+		t.Fatalf("sections: %v", err)
+	}
+	sectionValues, err := values.NewSectionValues(sections[0], values.WithFieldValue("path", cassette, fields.WithSource("cobra")))
+	if err != nil {
+		t.Fatalf("section values: %v", err)
+	}
+	runtimePlan := &app.RuntimePlan{Runtime: app.RuntimeSection{Modules: []app.RuntimeModulePlan{{
+		Provider: PackageID,
+		Name:     "fetch",
+		As:       "fetch",
+		Config:   map[string]any{"allow": true, "allowedOrigins": []any{"http://api.example.test"}},
+	}}}}
+	host := app.NewHostWithOptions(registry, runtimePlan, app.HostOptions{})
+	rt, err := host.Factory.NewRuntimeFromSections(context.Background(), values.New(values.WithSectionValues("fetch-cassette", sectionValues)))
+	if err != nil {
+		t.Fatalf("new runtime: %v", err)
+	}
+	defer func() { _ = rt.Close(context.Background()) }()
+
+	_, err = rt.Owner.Call(context.Background(), "host.fetch-cassette.setup", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		_, runErr := vm.RunString(`
+			globalThis.__fetchProviderSmoke = { done: false };
+			(async () => {
+				const fetch = require("fetch");
+				const body = await fetch.client().baseUrl("http://api.example.test").expectJson().get("/status").run();
+				let unmatched = "";
+				try { await fetch.fetch("http://api.example.test/other"); }
+				catch (e) { unmatched = String(e); }
+				globalThis.__fetchProviderSmoke = { done: true, error: "", ok: body.ok, unmatched };
+			})().catch(e => { globalThis.__fetchProviderSmoke = { done: true, error: String(e) }; });
+		`)
+		return nil, runErr
+	})
+	if err != nil {
+		t.Fatalf("run fetch setup: %v", err)
+	}
+	state := ""
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) && !strings.Contains(state, `"done":true`) {
+		ret, err := rt.Owner.Call(context.Background(), "host.fetch-cassette.state", func(_ context.Context, vm *goja.Runtime) (any, error) {
+			return vm.RunString(`JSON.stringify(globalThis.__fetchProviderSmoke || { done: false })`)
+		})
+		if err != nil {
+			t.Fatalf("read fetch state: %v", err)
+		}
+		state = ret.(goja.Value).String()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.Contains(state, `"error":""`) || !strings.Contains(state, `"ok":true`) || !strings.Contains(state, "no unplayed interaction matching GET http://api.example.test/other") {
+		t.Fatalf("fetch cassette state = %s", state)
+	}
+}
+
+func TestDatabasePoolAndDialectConfig(t *testing.T) {
+	cfg := DatabaseConfig{
+		DriverName:      "sqlite3",
+		DataSourceName:  ":memory:",
+		Dialect:         "postgres",
+		MaxOpenConns:    2,
+		ConnMaxLifetime: "30m",
+	}
+	mod, err := databaseModuleFromConfig("db", cfg)
+	if err != nil {
+		t.Fatalf("database module from config: %v", err)
+	}
+	defer func() { _ = mod.Close() }()
+	stats, ok := mod.Stats()
+	if !ok || stats.MaxOpenConnections != 2 {
+		t.Fatalf("stats = %+v, %v", stats, ok)
+	}
+
+	for _, bad := range []DatabaseConfig{
+		{DriverName: "sqlite3", DataSourceName: ":memory:", Dialect: "oracle"},
+		{DriverName: "sqlite3", DataSourceName: ":memory:", ConnMaxIdleTime: "soon"},
+		{AllowConfigure: true, MaxIdleConns: -1},
+	} {
+		if _, err := databaseModuleFromConfig("db", bad); err == nil {
+			t.Fatalf("expected config %+v to be rejected", bad)
+		}
+	}
+}
+
+func TestDBCommandSetAppliesMigrationDirectory(t *testing.T) {
+	registry := providerapi.NewProviderRegistry()
+	if err := Register(registry); err != nil {
+		t.Fatalf("register host provider: %v", err)
+	}
+	provider, ok := registry.ResolveCommandSetProvider(PackageID, "db")
+	if !ok {
+		t.Fatal("db command set is not registered")
+	}
+	set, err := provider.NewCommandSet(providerapi.CommandSetContext{})
+	if err != nil {
+		t.Fatalf("new db command set: %v", err)
+	}
+	command := set.Commands[0].(*dbMigrateCommand)
+	section, ok := command.Description().Schema.Get(schema.DefaultSlug)
+	if !ok {
+		t.Fatal("command default section missing")
+	}
+	driver, ok := section.GetDefinitions().Get("driver")
+	if !ok || *driver.Default != "sqlite3" || !slices.Contains(driver.Choices, "sqlite3") {
+		t.Fatalf("driver flag = %+v", driver)
+	}
+
+	dir := t.TempDir()
+	migrationsDir := filepath.Join(dir, "migrations")
+	if err := os.MkdirAll(migrationsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(migrationsDir, "0001_users.sql"), []byte("CREATE TABLE users (id INTEGER PRIMARY KEY);"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dbPath := filepath.Join(dir, "app.db")
+	var statuses []any
+	for _, dryRun := range []bool{true, false} {
+		updates := map[string]any{"dsn": dbPath, "dir": migrationsDir, "dry-run": dryRun}
+		fieldValues := fields.NewFieldValues()
+		for _, definition := range section.GetDefinitions().ToList() {
+			value := any(nil)
+			if definition.Default != nil {
+				value = *definition.Default
+			}
+			if override, exists := updates[definition.Name]; exists {
+				value = override
+			}
+			if value != nil {
+				fieldValues.Set(definition.Name, &fields.FieldValue{Definition: definition, Value: value})
+			}
+		}
+		sectionValues, err := values.NewSectionValues(section, values.WithFields(fieldValues))
+		if err != nil {
+			t.Fatalf("new section values: %v", err)
+		}
+		rows := &rowCollector{}
+		if err := command.RunIntoGlazeProcessor(context.Background(), values.New(values.WithSectionValues(schema.DefaultSlug, sectionValues)), rows); err != nil {
+			t.Fatalf("migrate (dry-run=%v): %v", dryRun, err)
+		}
+		for _, row := range rows.rows {
+			status, _ := row.Get("status")
+			statuses = append(statuses, status)
+		}
+	}
+	if got := fmt.Sprint(statuses); got != "[pending migrated]" {
+		t.Fatalf("statuses = %s", got)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	var name string
+	if err := db.QueryRow("SELECT name FROM goja_schema_migrations WHERE version = 1").Scan(&name); err != nil || name != "users" {
+		t.Fatalf("recorded migration = %q, %v", name, err)
+	}
+}
+
+type rowCollector struct{ rows []types.Row }
+
+var _ middlewares.Processor = (*rowCollector)(nil)
+
+func (c *rowCollector) AddRow(_ context.Context, row types.Row) error {
+	c.rows = append(c.rows, row)
+	return nil
+}
+
+func (c *rowCollector) Close(context.Context) error { return nil }
@@ -0,0 +1,114 @@
+package host
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/go-go-golems/glazed/pkg/cmds"
+	"github.com/go-go-golems/glazed/pkg/cmds/fields"
+	"github.com/go-go-golems/glazed/pkg/cmds/schema"
+	"github.com/go-go-golems/glazed/pkg/cmds/values"
+	"github.com/go-go-golems/glazed/pkg/middlewares"
+	"github.com/go-go-golems/glazed/pkg/types"
+	dbm "github.com/go-go-golems/go-go-goja/modules/database"
+	"github.com/go-go-golems/go-go-goja/pkg/dbmigrate"
+	"github.com/go-go-golems/go-go-goja/pkg/xgoja/providerapi"
+)
+
+type dbMigrateCommand struct {
+	*cmds.CommandDescription
+}
+
+var _ cmds.GlazeCommand = (*dbMigrateCommand)(nil)
+
+type dbMigrateSettings struct {
+	Driver string `glazed:"driver"`
+	DSN    string `glazed:"dsn"`
+	Dir    string `glazed:"dir"`
+	Table  string `glazed:"table"`
+	DryRun bool   `glazed:"dry-run"`
+}
+
+// newDBCommandSet returns the "migrate" command. --driver offers the
+// database/sql drivers linked into the generated binary: sqlite3 comes with
+// this package, others with the go-go-goja-postgres or go-go-goja-mysql
+// provider packages.
+func newDBCommandSet(providerapi.CommandSetContext) (*providerapi.CommandSet, error) {
+	drivers := sql.Drivers()
+	if len(drivers) == 0 {
+		return nil, fmt.Errorf("db command set: no database/sql drivers are linked into this binary")
+	}
+	driver := drivers[0]
+	if slices.Contains(drivers, "sqlite3") {
+		driver = "sqlite3"
+	}
+	command := &dbMigrateCommand{CommandDescription: cmds.NewCommandDescription("migrate",
+		cmds.WithShort("Apply a directory of SQL migrations to a database"),
+		cmds.WithLong(`
+Migrate applies the NNNN_name.sql files in a directory to a database, the
+same way require("database").migrate(dir) does from a script. Applied
+versions are recorded with a checksum; a changed migration fails with a
+checksum mismatch. Each migration runs in its own transaction.
+
+Examples:
+  my-host db migrate --dsn app.db --dir migrations
+  my-host db migrate --dsn app.db --dir migrations --dry-run --output table
+`),
+		cmds.WithFlags(
+			fields.New("driver", fields.TypeChoice,
+				fields.WithChoices(drivers...),
+				fields.WithDefault(driver),
+				fields.WithHelp("database/sql driver name")),
+			fields.New("dsn", fields.TypeString,
+				fields.WithRequired(true),
+				fields.WithHelp("Data source name passed to the driver")),
+			fields.New("dir", fields.TypeString,
+				fields.WithDefault("migrations"),
+				fields.WithHelp("Directory containing NNNN_name.sql migrations")),
+			fields.New("table", fields.TypeString,
+				fields.WithDefault(dbmigrate.DefaultTable),
+				fields.WithHelp("Table recording applied migrations")),
+			fields.New("dry-run", fields.TypeBool,
+				fields.WithDefault(false),
+				fields.WithHelp("Report pending migrations without applying them")),
+		),
+	)}
+	return &providerapi.CommandSet{Commands: []cmds.Command{command}}, nil
+}
+
+func (c *dbMigrateCommand) RunIntoGlazeProcessor(ctx context.Context, vals *values.Values, gp middlewares.Processor) error {
+	settings := dbMigrateSettings{}
+	if err := vals.DecodeSectionInto(schema.DefaultSlug, &settings); err != nil {
+		return fmt.Errorf("decode migrate settings: %w", err)
+	}
+	migrations, err := dbmigrate.LoadDir(os.DirFS(settings.Dir), ".")
+	if err != nil {
+		return err
+	}
+	db, err := sql.Open(settings.Driver, settings.DSN)
+	if err != nil {
+		return fmt.Errorf("open %s database: %w", settings.Driver, err)
+	}
+	defer func() { _ = db.Close() }()
+
+	results, err := dbmigrate.Apply(ctx, dbmigrate.FromDB(db), migrations, dbmigrate.Options{
+		Table:   settings.Table,
+		DryRun:  settings.DryRun,
+		Dialect: string(dbm.DialectForDriver(settings.Driver)),
+	})
+	for _, result := range results {
+		if addErr := gp.AddRow(ctx, types.NewRow(
+			types.MRP("version", result.Version),
+			types.MRP("name", result.Name),
+			types.MRP("status", result.Status),
+			types.MRP("checksum", result.Checksum),
+			types.MRP("applied_at", result.AppliedAt),
+		)); addErr != nil {
+			return addErr
+		}
+	}
+	return err
+}
@@ -66,6 +66,25 @@ type runtimeEntry struct {
 	settings           settings
 	settingsConfigured bool
 	host               *gojahttp.Host
+	// ownsHost is set when the express loader created host itself rather
+	// than registering into a host service. Nothing serves such a host, so
+	// its routes are validated when the runtime closes.
+	ownsHost bool
+}
+
+// validateOwnedHost reports routes the script registered into a host the
+// loader created that could not be added to its route tree.
+func (e *runtimeEntry) validateOwnedHost() error {
+	e.mu.Lock()
+	host, owned := e.host, e.ownsHost
+	e.mu.Unlock()
+	if !owned {
+		return nil
+	}
+	if err := host.Validate(); err != nil {
+		return fmt.Errorf("http routes: %w", err)
+	}
+	return nil
 }
 
 type capability struct {
@@ -163,8 +182,8 @@ func (c *capability) InitRuntimeFromSections(ctx context.Context, vals *values.V
 	entry.settingsConfigured = true
 	entry.mu.Unlock()
 	return runtime.AddCloser(func(context.Context) error {
-		c.cleanupRuntime(runtime.VM)
-		return nil
+		defer c.cleanupRuntime(runtime.VM)
+		return entry.validateOwnedHost()
 	})
 }
 
@@ -190,6 +209,7 @@ func (c *capability) newExpressLoader(hostServices providerapi.HostServices, cfg
 				entry.host = externalHost.Host
 			} else {
 				entry.host = gojahttp.NewHost(hostOptions(entry.settings))
+				entry.ownsHost = true
 			}
 		}
 		host := entry.host
@@ -2,6 +2,7 @@ package http
 
 import (
 	"context"
+	"errors"
 	"net"
 	stdhttp "net/http"
 	"net/http/httptest"
@@ -405,6 +406,38 @@ func TestExpressRequireDoesNotBindHTTPPort(t *testing.T) {
 	}
 }
 
+func TestOwnedHostReportsConflictingRoutesOnClose(t *testing.T) {
+	capability := newHTTPCapability()
+	factory, err := engine.NewRuntimeFactoryBuilder().WithModules(engine.NativeModuleRegistrar{ModuleName: "express", Loader: capability.NewExpressLoader()}).Build()
+	if err != nil {
+		t.Fatalf("build runtime factory: %v", err)
+	}
+	rt, err := factory.NewRuntime(engine.WithStartupContext(context.Background()), engine.WithLifetimeContext(context.Background()))
+	if err != nil {
+		t.Fatalf("new runtime: %v", err)
+	}
+	if err := capability.InitRuntimeFromSections(context.Background(), httpValues(t, nil), testRuntimeInitializerHandle{rt: rt}); err != nil {
+		_ = rt.Close(context.Background())
+		t.Fatalf("init runtime: %v", err)
+	}
+
+	_, err = rt.Owner.Call(context.Background(), "register conflicting routes", func(_ context.Context, vm *goja.Runtime) (any, error) {
+		_, runErr := vm.RunString(`
+			const app = require("express").app();
+			app.get("/users/:id").public().handle((_ctx, res) => res.json({}));
+			app.get("/users/:name").public().handle((_ctx, res) => res.json({}));
+		`)
+		return nil, runErr
+	})
+	if err != nil {
+		_ = rt.Close(context.Background())
+		t.Fatalf("register routes: %v", err)
+	}
+	if err := rt.Close(context.Background()); !errors.Is(err, gojahttp.ErrRouteConflict) {
+		t.Fatalf("close error = %v, want route conflict", err)
+	}
+}
+
 func httpValues(t *testing.T, overrides map[string]any) *values.Values {
 	t.Helper()
 	capability := newHTTPCapability()
@@ -41,6 +41,11 @@ type serveHotReloadSettings struct {
 	StatusPath string   `glazed:"hot-reload-status-path"`
 }
 
+// serveSettings holds the http-serve fields that are not about hot reload.
+type serveSettings struct {
+	Runtimes int `glazed:"runtimes"`
+}
+
 const serveHotReloadSectionSlug = "http-serve"
 
 func serveCommandJSVerbSources(ctx providerapi.CommandSetContext) (providerapi.JSVerbSourceSet, error) {
@@ -141,7 +146,14 @@ func serveVerb(ctx context.Context, commandCtx providerapi.CommandSetContext, re
 	if err != nil {
 		return nil, err
 	}
+	serveSettings, err := decodeServeSettings(parsedValues)
+	if err != nil {
+		return nil, err
+	}
 	if hotReloadSettings.Enabled {
+		if serveSettings.Runtimes > 1 {
+			return nil, fmt.Errorf("http serve hot reload serves from one runtime; set runtimes to 1")
+		}
 		return serveVerbHotReload(ctx, commandCtx, registry, verb, parsedValues, hotReloadSettings)
 	}
 	httpSettings, err := decodeHTTPServeSettings(parsedValues)
@@ -180,18 +192,40 @@ func serveVerb(ctx context.Context, commandCtx providerapi.CommandSetContext, re
 	if err != nil {
 		return nil, err
 	}
-	rt, err := factory.NewRuntimeFromSectionsWithHostServices(ctx, parsedValues, runtimeServices, require.WithLoader(registry.RequireLoader()))
-	if err != nil {
-		return nil, err
+	// Every runtime runs the verb, which registers its handlers for the
+	// routes into serveHost.
+	newRuntime := func(ctx context.Context) (*engine.Runtime, error) {
+		rt, err := factory.NewRuntimeFromSectionsWithHostServices(ctx, parsedValues, runtimeServices, require.WithLoader(registry.RequireLoader()))
+		if err != nil {
+			return nil, err
+		}
+		if len(commandCtx.SelectedModules) > 0 {
+			if err := providerutil.InitRuntimeFromSections(ctx, parsedValues, runtimeHandle{rt: rt}, commandCtx.SelectedModules); err != nil {
+				_ = rt.Close(ctx)
+				return nil, err
+			}
+		}
+		if _, err := registry.InvokeInRuntime(ctx, rt, verb, parsedValues); err != nil {
+			_ = rt.Close(ctx)
+			return nil, err
+		}
+		return rt, nil
 	}
-	defer func() { _ = rt.Close(context.Background()) }()
-
-	if len(commandCtx.SelectedModules) > 0 {
-		if err := providerutil.InitRuntimeFromSections(ctx, parsedValues, runtimeHandle{rt: rt}, commandCtx.SelectedModules); err != nil {
+	if runtimes := serveSettings.Runtimes; runtimes > 1 {
+		pool, err := engine.NewRuntimePool(ctx, nil, engine.RuntimePoolOptions{Size: runtimes, MaxSize: runtimes, New: newRuntime})
+		if err != nil {
 			return nil, err
 		}
+		defer func() { _ = pool.Close(context.Background()) }()
+		serveHost.SetRuntimePool(pool)
+	} else {
+		rt, err := newRuntime(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = rt.Close(context.Background()) }()
 	}
-	if _, err := registry.InvokeInRuntime(ctx, rt, verb, parsedValues); err != nil {
+	if err := serveHost.Validate(); err != nil {
 		return nil, err
 	}
 
@@ -592,6 +626,7 @@ func serveHotReloadSection() (schema.Section, error) {
 		serveHotReloadSectionSlug,
 		"HTTP serve hot reload",
 		schema.WithFields(
+			fields.New("runtimes", fields.TypeInteger, fields.WithDefault(1), fields.WithHelp("Number of runtimes that serve requests; more than one runs the verb in each and leases one per request from a runtime pool")),
 			fields.New("hot-reload", fields.TypeBool, fields.WithDefault(false), fields.WithHelp("Enable blue/green hot reload for this HTTP serve command")),
 			fields.New("hot-reload-watch-root", fields.TypeStringList, fields.WithHelp("File or directory to poll for reload changes; repeatable")),
 			fields.New("hot-reload-watch-ext", fields.TypeStringList, fields.WithDefault([]string{".js", ".json", ".md", ".yaml", ".yml"}), fields.WithHelp("File extension that triggers hot reload; repeatable")),
@@ -621,6 +656,17 @@ func decodeServeHotReloadSettings(vals *values.Values) (serveHotReloadSettings,
 	return settings, nil
 }
 
+func decodeServeSettings(vals *values.Values) (serveSettings, error) {
+	settings := serveSettings{Runtimes: 1}
+	if vals == nil {
+		return settings, nil
+	}
+	if err := vals.DecodeSectionInto(serveHotReloadSectionSlug, &settings); err != nil {
+		return serveSettings{}, err
+	}
+	return settings, nil
+}
+
 func waitForServeNoListen(ctx context.Context) error {
 	if ctx == nil {
 		ctx = context.Background()
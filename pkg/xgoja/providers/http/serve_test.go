@@ -311,6 +311,89 @@ __verb__("start", { name: "start", short: "Serve empty Express site", output: "t
 	}
 }
 
+func TestServeVerbServesFromRuntimePool(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "site.js"), []byte(`
+__package__({ name: "site" });
+let served = 0;
+function start() {
+  require("express").app().get("/count").public().handle((_ctx, res) => {
+    const until = Date.now() + 200;
+    while (Date.now() < until) {}
+    res.json({ served: ++served });
+  });
+}
+__verb__("start", { name: "start", short: "Serve a counter", output: "text" });
+`), 0o644); err != nil {
+		t.Fatalf("write site.js: %v", err)
+	}
+	registry, err := jsverbs.ScanDir(dir)
+	if err != nil {
+		t.Fatalf("scan dir: %v", err)
+	}
+	providers := providerapi.NewProviderRegistry()
+	if err := Register(providers); err != nil {
+		t.Fatalf("register http provider: %v", err)
+	}
+	capabilities, _ := providers.ResolvePackageCapabilities(PackageID)
+	runtimePlan := &app.RuntimePlan{Runtime: app.RuntimeSection{Modules: []app.RuntimeModulePlan{{Provider: PackageID, Name: "express", As: "express"}}}}
+	factory := app.NewRuntimeFactory(providers, runtimePlan, app.HostServices{})
+	addr := freeServeTestAddr(t)
+	parsedValues := serveHotReloadTestValues(t, addr, map[string]any{"runtimes": 2})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		verb, _ := registry.Verb("site start")
+		_, err := serveVerb(ctx, providerapi.CommandSetContext{
+			RuntimeFactory: factory,
+			SelectedModules: []providerapi.ModuleDescriptor{{
+				PackageID:           PackageID,
+				ModuleID:            "express",
+				As:                  "express",
+				PackageCapabilities: capabilities,
+			}},
+		}, registry, verb, parsedValues)
+		done <- err
+	}()
+
+	body := waitForServeTestBody(t, "http://"+addr+"/count", done)
+	if strings.TrimSpace(body) != `{"served":1}` {
+		t.Fatalf("first body = %s", body)
+	}
+	// Each request holds its runtime for 200ms, so two requests in flight
+	// lease both runtimes, and one of them is the second runtime's first.
+	results := make(chan string, 2)
+	start := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		go func() {
+			<-start
+			resp, err := stdhttp.Get("http://" + addr + "/count")
+			if err != nil {
+				results <- err.Error()
+				return
+			}
+			data, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			results <- strings.TrimSpace(string(data))
+		}()
+	}
+	close(start)
+	got := map[string]bool{<-results: true, <-results: true}
+	if !got[`{"served":1}`] || !got[`{"served":2}`] {
+		t.Fatalf("pooled bodies = %v", got)
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil && !strings.Contains(err.Error(), "context canceled") {
+			t.Fatalf("serve returned error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("serve did not stop after cancel")
+	}
+}
+
 func TestServeVerbUsesHostAuthServiceFactory(t *testing.T) {
 	dir := t.TempDir()
 	if err := os.WriteFile(filepath.Join(dir, "site.js"), []byte(`
@@ -0,0 +1,28 @@
+// Package mysql links the go-sql-driver MySQL driver into xgoja builds.
+//
+// The provider package ID is "go-go-goja-mysql". It registers no modules;
+// listing it under providers makes database/sql driver "mysql" available to
+// the database and db modules of the go-go-goja-host package:
+//
+//	providers:
+//	  - id: mysql
+//	    import: github.com/go-go-golems/go-go-goja/pkg/xgoja/providers/mysql
+//
+// Add multiStatements=true to the DSN if migrations put several statements in
+// one file.
+package mysql
+
+import (
+	"github.com/go-go-golems/go-go-goja/pkg/xgoja/providerapi"
+	_ "github.com/go-sql-driver/mysql" // Registers the "mysql" database/sql driver
+)
+
+const PackageID = "go-go-goja-mysql"
+
+// DriverName is the database/sql driver name registered by this package.
+const DriverName = "mysql"
+
+// Register adds the driver-only provider package.
+func Register(registry *providerapi.ProviderRegistry) error {
+	return registry.Package(PackageID)
+}
@@ -0,0 +1,28 @@
+// Package postgres links the pgx Postgres driver into xgoja builds.
+//
+// The provider package ID is "go-go-goja-postgres". It registers no modules;
+// listing it under providers makes database/sql driver "pgx" available to
+// the database and db modules of the go-go-goja-host package:
+//
+//	providers:
+//	  - id: postgres
+//	    import: github.com/go-go-golems/go-go-goja/pkg/xgoja/providers/postgres
+//
+// Queries keep using "?" and ":name" placeholders; the database module
+// rewrites them to $1, $2, ... for this driver.
+package postgres
+
+import (
+	"github.com/go-go-golems/go-go-goja/pkg/xgoja/providerapi"
+	_ "github.com/jackc/pgx/v5/stdlib" // Registers the "pgx" database/sql driver
+)
+
+const PackageID = "go-go-goja-postgres"
+
+// DriverName is the database/sql driver name registered by this package.
+const DriverName = "pgx"
+
+// Register adds the driver-only provider package.
+func Register(registry *providerapi.ProviderRegistry) error {
+	return registry.Package(PackageID)
+}
@@ -0,0 +1,23 @@
+package postgres
+
+import (
+	"database/sql"
+	"slices"
+	"testing"
+
+	"github.com/go-go-golems/go-go-goja/pkg/xgoja/providerapi"
+)
+
+func TestRegisterLinksDriver(t *testing.T) {
+	registry := providerapi.NewProviderRegistry()
+	if err := Register(registry); err != nil {
+		t.Fatalf("register postgres provider: %v", err)
+	}
+	pkg := registry.Packages()
+	if len(pkg) != 1 || pkg[0].ID != PackageID {
+		t.Fatalf("packages = %+v, want one %q", pkg, PackageID)
+	}
+	if !slices.Contains(sql.Drivers(), DriverName) {
+		t.Fatalf("driver %q is not registered: %v", DriverName, sql.Drivers())
+	}
+}